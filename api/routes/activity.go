@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/activity"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function `activityHandler` returns a page of the authenticated user's activity, newest first:
+// the questions they solved, commented on and shared solutions to.
+//
+//	@Summary	List your activity
+//	@Tags		account
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		limit	query		int	false	"Page size, clamped to MAX_PAGE_SIZE"
+//	@Param		offset	query		int	false	"Number of entries to skip"	default(0)
+//	@Success	200		{object}	activity.Page
+//	@Failure	400		{object}	errorResponse
+//	@Failure	401		{object}	errorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Router		/auth/me/activity [get]
+func activityHandler(feed *activity.Feed) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		page, err := parsePaging(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		list, err := feed.WithContext(c.UserContext()).List(userID, page.Limit, page.Offset)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(list)
+	}
+}
+
+// The function creates the route for reading the authenticated user's activity feed.
+func CreateActivityRoutes(router fiber.Router, feed *activity.Feed) {
+	router.Get("/auth/me/activity", activityHandler(feed))
+}
@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `adminSummary` is the overview shown on the admin dashboard.
+// @property Users - The number of users, in total and by how recently they signed up.
+// @property Questions - The number of questions, in total and per level and status.
+// @property Logins - The number of password and OTP logins in the last 7 and 30 days, from the audit
+// log.
+// @property OTP - The OTP send and verification counters of this instance since it started, summed over
+// every channel.
+type adminSummary struct {
+	Users     auth.SignupCounts           `json:"users"`
+	Questions allquestions.QuestionCounts `json:"questions"`
+	Logins    audit.RecentCounts          `json:"logins"`
+	OTP       otpCounters                 `json:"otp"`
+}
+
+// The function adds up the OTP counters of every channel.
+func otpTotals(stats *OTPStats) otpCounters {
+	var total otpCounters
+	for _, counters := range stats.Snapshot() {
+		total.SendAttempted += counters.SendAttempted
+		total.SendSucceeded += counters.SendSucceeded
+		total.SendFailed += counters.SendFailed
+		total.VerifyAttempted += counters.VerifyAttempted
+		total.VerifyApproved += counters.VerifyApproved
+		total.VerifyRejected += counters.VerifyRejected
+	}
+	return total
+}
+
+// The function `adminSummaryHandler` returns the overview shown on the admin dashboard. Every count
+// takes a single query.
+//
+//	@Summary	Get the admin dashboard summary
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{object}	adminSummary
+//	@Failure	401	{object}	errorResponse
+//	@Failure	403	{object}	errorResponse
+//	@Failure	500	{object}	errorResponse
+//	@Router		/admin/summary [get]
+func adminSummaryHandler(userRepo *auth.Repo, questionRepo allquestions.Repository, auditRepo *audit.Repo, stats *OTPStats) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		now := pkg.NowUTC()
+		users, err := userRepo.WithContext(c.UserContext()).SignupCounts(now)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		questions, err := questionRepo.WithContext(c.UserContext()).Counts()
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		logins, err := auditRepo.WithContext(c.UserContext()).CountRecent([]string{audit.EventLogin, audit.EventOTPLogin}, now)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(adminSummary{Users: users, Questions: questions, Logins: logins, OTP: otpTotals(stats)})
+	}
+}
+
+// The function creates the admin-only route for the dashboard summary.
+func CreateAdminRoutes(router fiber.Router, userRepo *auth.Repo, questionRepo allquestions.Repository, auditRepo *audit.Repo, stats *OTPStats) {
+	router.Get("/admin/summary", RequireRole(userRepo, "admin"), adminSummaryHandler(userRepo, questionRepo, auditRepo, stats))
+}
@@ -0,0 +1,119 @@
+package routes
+
+import (
+	"encoding/json"
+	"reflect"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns an app serving the admin routes to "user-1" from the mocked deployment.
+func adminApp(mt *mtest.T, stats *OTPStats) *fiber.App {
+	app := fiber.New()
+	CreateAdminRoutes(app.Group("", authenticated(jwt.MapClaims{"userid": "user-1"})), auth.NewRepo(mt.DB).(*auth.Repo), allquestions.NewRepo(mt.DB), audit.NewRepo(mt.DB).(*audit.Repo), stats)
+	return app
+}
+
+// The function checks that `date` is `days` days before now, give or take a minute.
+func expectDaysAgo(mt *mtest.T, name string, date interface{}, days int) {
+	mt.Helper()
+	want := time.Now().AddDate(0, 0, -days)
+	got, ok := date.(primitive.DateTime)
+	if !ok || got.Time().Sub(want).Abs() > time.Minute {
+		mt.Errorf("%s = %v, want %d days ago", name, date, days)
+	}
+}
+
+func TestAdminSummary(t *testing.T) {
+	dbtest.Run(t, "every section", func(mt *mtest.T) {
+		stats := NewOTPStats()
+		stats.count("sms", func(c *otpCounters) { c.SendAttempted, c.SendSucceeded, c.VerifyApproved = 3, 2, 1 })
+		stats.count("whatsapp", func(c *otpCounters) { c.SendAttempted, c.SendFailed, c.VerifyRejected = 2, 1, 1 })
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, auth.User{ID: "user-1", UserType: "admin"}),
+			dbtest.Cursor(mt, bson.M{"_id": nil, "total": 12, "last7days": 2, "last30days": 5}),
+			dbtest.Cursor(mt, bson.M{
+				"total":    bson.A{bson.M{"count": 6}},
+				"byLevel":  bson.A{bson.M{"_id": allquestions.LevelEasy, "count": 4}, bson.M{"_id": allquestions.LevelHard, "count": 2}},
+				"byStatus": bson.A{bson.M{"_id": allquestions.StatusPublished, "count": 5}, bson.M{"_id": allquestions.StatusDraft, "count": 1}},
+			}),
+			dbtest.Cursor(mt, bson.M{"_id": nil, "last7days": 4, "last30days": 9}),
+		)
+
+		res, body := send(mt.T, adminApp(mt, stats), fiber.MethodGet, "/admin/summary", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var summary adminSummary
+		if err := json.Unmarshal([]byte(body), &summary); err != nil {
+			mt.Fatal(err)
+		}
+		if want := (auth.SignupCounts{Total: 12, Last7Days: 2, Last30Days: 5}); summary.Users != want {
+			mt.Errorf("users = %+v, want %+v", summary.Users, want)
+		}
+		wantQuestions := allquestions.QuestionCounts{
+			Total:    6,
+			ByLevel:  map[string]int64{allquestions.LevelEasy: 4, allquestions.LevelHard: 2},
+			ByStatus: map[string]int64{allquestions.StatusPublished: 5, allquestions.StatusDraft: 1},
+		}
+		if !reflect.DeepEqual(summary.Questions, wantQuestions) {
+			mt.Errorf("questions = %+v, want %+v", summary.Questions, wantQuestions)
+		}
+		if want := (audit.RecentCounts{Last7Days: 4, Last30Days: 9}); summary.Logins != want {
+			mt.Errorf("logins = %+v, want %+v", summary.Logins, want)
+		}
+		if want := (otpCounters{SendAttempted: 5, SendSucceeded: 2, SendFailed: 1, VerifyApproved: 1, VerifyRejected: 1}); summary.OTP != want {
+			mt.Errorf("otp = %+v, want the counters of both channels added up, %+v", summary.OTP, want)
+		}
+
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": "user-1"})
+		group := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)[0].(bson.M)["$group"].(bson.M)
+		expectDaysAgo(mt, "signup 7 day boundary", group["last7days"].(bson.M)["$sum"].(bson.M)["$cond"].(bson.A)[0].(bson.M)["$gte"].(bson.A)[1], 7)
+		expectDaysAgo(mt, "signup 30 day boundary", group["last30days"].(bson.M)["$sum"].(bson.M)["$cond"].(bson.A)[0].(bson.M)["$gte"].(bson.A)[1], 30)
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "aggregate", "AllQuestion")
+		match := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)[0].(bson.M)["$match"].(bson.M)
+		dbtest.ExpectField(mt, match, "type", bson.M{"$in": bson.A{audit.EventLogin, audit.EventOTPLogin}})
+		expectDaysAgo(mt, "login 30 day boundary", match["timestamp"].(bson.M)["$gte"], 30)
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "empty database", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, auth.User{ID: "user-1", UserType: "admin"}),
+			dbtest.Cursor(mt),
+			dbtest.Cursor(mt, bson.M{"total": bson.A{}, "byLevel": bson.A{}, "byStatus": bson.A{}}),
+			dbtest.Cursor(mt),
+		)
+		res, body := send(mt.T, adminApp(mt, NewOTPStats()), fiber.MethodGet, "/admin/summary", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var summary adminSummary
+		if err := json.Unmarshal([]byte(body), &summary); err != nil {
+			mt.Fatal(err)
+		}
+		if summary.Users.Total != 0 || summary.Questions.Total != 0 || summary.Questions.ByLevel == nil || summary.Logins.Last30Days != 0 {
+			mt.Errorf("summary = %+v, want zero counts", summary)
+		}
+	})
+
+	dbtest.Run(t, "not an admin", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, auth.User{ID: "user-1", UserType: "user"}))
+		res, body := send(mt.T, adminApp(mt, NewOTPStats()), fiber.MethodGet, "/admin/summary", "")
+		if res.StatusCode != fiber.StatusForbidden {
+			mt.Fatalf("status = %d, want 403: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
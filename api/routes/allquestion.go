@@ -1,38 +1,994 @@
 package routes
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sigmacoder/pkg"
 	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/difficulty"
+	"sigmacoder/pkg/logging"
+	"sigmacoder/pkg/submissions"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// The `allquestionsHandler` function is a handler function that retrieves all questions from a
-// repository and returns them as a JSON response.
-func allquestionsHandler(repo *allquestions.Repo) fiber.Handler {
+// `defaultRelatedLimit` is the number of related questions returned when the client doesn't ask for a
+// specific `limit`.
+const defaultRelatedLimit = 5
+
+// The function answers an error returned while reading the question with the ID in the path: an ID
+// that isn't an ObjectID with 400, a missing question with 404 and anything else with 500.
+func sendQuestionError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, pkg.ErrInvalidQuestionID):
+		return sendError(c, fiber.StatusBadRequest, err)
+	case errors.Is(err, mongo.ErrNoDocuments), errors.Is(err, pkg.ErrQuestionNotFound):
+		return sendError(c, fiber.StatusNotFound, pkg.ErrQuestionNotFound)
+	}
+	return sendError(c, fiber.StatusInternalServerError, err)
+}
+
+// The type `questionPage` is a page of a keyset-paginated question listing.
+// @property Questions - The questions on this page, in ascending `Id` order.
+// @property Next - The `after` value that fetches the following page, or null when this is the last
+// page.
+// @property Limit - The effective page size, which is lower than the requested one when it was
+// clamped to the maximum.
+type questionPage struct {
+	Questions []allquestions.AllQuestion `json:"questions"`
+	Next      *int                       `json:"next"`
+	Limit     int                        `json:"limit"`
+}
+
+// The type `questionQueryPage` is a page of a question listing, with how many questions match in total
+// and the filters that were applied.
+type questionQueryPage struct {
+	Questions []allquestions.AllQuestion `json:"questions"`
+	Total     int64                      `json:"total"`
+	Limit     int                        `json:"limit"`
+	Offset    int                        `json:"offset"`
+	Filters   questionFilters            `json:"filters"`
+}
+
+// The type `questionFilters` lists the search, filters and order a question listing was made with.
+// Filters that weren't given are left out.
+type questionFilters struct {
+	Q         string   `json:"q,omitempty"`
+	Category  string   `json:"category,omitempty"`
+	Level     string   `json:"level,omitempty"`
+	Language  string   `json:"language,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Match     string   `json:"match,omitempty"`
+	Companies []string `json:"companies,omitempty"`
+	Sort      string   `json:"sort"`
+}
+
+// The type `questionDetail` is a single question together with the community's opinion of its
+// difficulty, how often it has been viewed and how often the code submitted for it is accepted.
+// @property CommunityDifficulty - How many users voted the question easy, medium and hard.
+// @property Views - How many times the question has been viewed. Views are recorded with
+// `recordViewHandler`, since this response may be served from the cache.
+// @property Acceptance - How many of the judged submissions for the question were accepted.
+type questionDetail struct {
+	allquestions.AllQuestion
+	CommunityDifficulty difficulty.Breakdown   `json:"communityDifficulty"`
+	Views               int64                  `json:"views"`
+	Acceptance          submissions.Acceptance `json:"acceptance"`
+}
+
+// The type `hintsResponse` is the body returned by the hints endpoint.
+// @property Hints - The revealed hints, in order.
+// @property Total - The number of hints the question has, so the client knows whether more can be
+// revealed.
+type hintsResponse struct {
+	Hints []string `json:"hints"`
+	Total int      `json:"total"`
+}
+
+// The type `viewResponse` is the body returned when a view of a question is recorded.
+// @property Views - How many times the question has been viewed, this view included.
+type viewResponse struct {
+	Views int64 `json:"views"`
+}
+
+// The function removes the hints from questions before they are listed, since hints are only revealed
+// one at a time through the hints endpoint.
+func hideHints(questions []allquestions.AllQuestion) []allquestions.AllQuestion {
+	for i := range questions {
+		questions[i].Hints = nil
+	}
+	return questions
+}
+
+// The type `questionErrorResponse` documents the body returned by the question handlers when a request
+// fails.
+type questionErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// The `allquestionsHandler` function is a handler function that lists the published questions. It is
+// the single question listing: `q` searches the question names, `category`, `level`, `language`,
+// `tag` and `company` filter the questions, `sort` orders them, and all of them can be combined. With
+// `limit` or `offset`, it returns one page of the matching questions together with how many match in
+// total and the filters that were applied; otherwise it returns every matching question as an array.
+// With `after`, it instead returns a single keyset-paginated page of questions ordered by `Id`,
+// together with the cursor for the next page, restricted by `language` and `tag` only.
+//
+//	@Summary		List and search questions
+//	@Description	With limit or offset, returns a page object {questions, total, limit, offset, filters} instead of the full list. With after, returns a keyset page object {questions, next, limit}.
+//	@Tags			questions
+//	@Produce		json
+//	@Param			q			query		string	false	"Only return questions whose name contains this, ignoring case"
+//	@Param			category	query		string	false	"Only return questions of this category"
+//	@Param			level		query		string	false	"Only return questions of this level"	Enums(Easy, Medium, Hard)
+//	@Param			language	query		string	false	"Only return questions supporting this language"
+//	@Param			tag			query		string	false	"Only return questions with these tags, comma separated or repeated"
+//	@Param			match		query		string	false	"Whether questions need all of the tags or any of them"	Enums(all, any)	default(all)
+//	@Param			company		query		string	false	"Only return questions asked by any of these companies, ignoring case, comma separated or repeated"
+//	@Param			sort		query		string	false	"Order of the questions, prefixed with - for descending"	Enums(id, -id, name, -name, level, -level)	default(id)
+//	@Param			limit		query		int		false	"Page size, clamped to MAX_PAGE_SIZE"
+//	@Param			offset		query		int		false	"Number of matching questions to skip"
+//	@Param			after		query		int		false	"Return a keyset page of questions with an Id greater than this"
+//	@Success		200		{array}		allquestions.AllQuestion
+//	@Failure		400		{object}	questionErrorResponse
+//	@Failure		500		{object}	questionErrorResponse
+//	@Router			/all/allquestions [get]
+func allquestionsHandler(repo allquestions.Repository) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		allquestions, err := repo.ReadAllQuestion()
+		tags, err := tagFilterFromQuery(c)
+		if err != nil {
+			return sendError(c, 400, err)
+		}
+		if c.Query("after") != "" {
+			return questionPageHandler(c, repo, tags)
+		}
+		params, err := questionQueryFromRequest(c, tags)
 		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			return sendError(c, 400, err)
 		}
-		return c.Status(200).JSON(allquestions)
+		paged := c.Query("limit") != "" || c.Query("offset") != ""
+		if paged {
+			paging, err := parsePaging(c)
+			if err != nil {
+				return sendError(c, 400, err)
+			}
+			params.Limit, params.Offset = paging.Limit, paging.Offset
+		}
+		result, err := repo.WithContext(c.UserContext()).Query(params)
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		if !paged {
+			return c.Status(200).JSON(hideHints(result.Questions))
+		}
+		return c.Status(200).JSON(questionQueryPage{
+			Questions: hideHints(result.Questions),
+			Total:     result.Total,
+			Limit:     params.Limit,
+			Offset:    params.Offset,
+			Filters:   appliedQuestionFilters(params),
+		})
 	}
 }
 
+// The function reads the search, filters and order of a question listing from the query parameters.
+// Unknown categories, levels and orders are rejected rather than matching nothing.
+func questionQueryFromRequest(c *fiber.Ctx, tags allquestions.TagFilter) (allquestions.QueryParams, error) {
+	params := allquestions.QueryParams{
+		Search:    c.Query("q"),
+		Category:  c.Query("category"),
+		Level:     c.Query("level"),
+		Language:  allquestions.NormalizeLanguage(c.Query("language")),
+		Tags:      tags,
+		Companies: companiesFromQuery(c),
+		Sort:      c.Query("sort", allquestions.SortNumber),
+	}
+	if params.Category != "" && !allquestions.ValidCategory(params.Category) {
+		return params, errors.New("category must be one of " + strings.Join(allquestions.CategoryEnum, ", "))
+	}
+	if params.Level != "" && !allquestions.ValidLevel(params.Level) {
+		return params, errors.New("level must be one of " + strings.Join(allquestions.LevelEnum, ", "))
+	}
+	if !allquestions.ValidSort(params.Sort) {
+		return params, errors.New("sort must be one of " + strings.Join(allquestions.SortEnum, ", ") + ", optionally prefixed with -")
+	}
+	return params, nil
+}
+
+// The function returns the filters of a question listing the way they are reported back to clients.
+func appliedQuestionFilters(params allquestions.QueryParams) questionFilters {
+	filters := questionFilters{
+		Q:         strings.TrimSpace(params.Search),
+		Category:  params.Category,
+		Level:     params.Level,
+		Language:  params.Language,
+		Tags:      params.Tags.Tags,
+		Companies: params.Companies,
+		Sort:      params.Sort,
+	}
+	if len(filters.Tags) > 0 {
+		filters.Match = "any"
+		if params.Tags.MatchAll {
+			filters.Match = "all"
+		}
+	}
+	return filters
+}
+
+// The function reads the tag filter of a question listing from the `tag` and `match` query parameters.
+// Tags can be given comma separated, by repeating `tag`, or both.
+func tagFilterFromQuery(c *fiber.Ctx) (allquestions.TagFilter, error) {
+	var tags []string
+	for _, value := range c.Context().QueryArgs().PeekMulti("tag") {
+		tags = append(tags, strings.Split(string(value), ",")...)
+	}
+	filter := allquestions.TagFilter{Tags: allquestions.NormalizeTags(tags)}
+	switch c.Query("match", "all") {
+	case "all":
+		filter.MatchAll = true
+	case "any":
+	default:
+		return filter, errors.New("match must be all or any")
+	}
+	return filter, nil
+}
+
+// The function reads the companies a question listing is restricted to from the `company` query
+// parameter, which can be given comma separated, repeated, or both.
+func companiesFromQuery(c *fiber.Ctx) []string {
+	var companies []string
+	for _, value := range c.Context().QueryArgs().PeekMulti("company") {
+		companies = append(companies, strings.Split(string(value), ",")...)
+	}
+	return allquestions.NormalizeCompanies(companies)
+}
+
+// The function answers a keyset-paginated question listing request.
+func questionPageHandler(c *fiber.Ctx, repo allquestions.Repository, tags allquestions.TagFilter) error {
+	after, err := strconv.Atoi(c.Query("after", "0"))
+	if err != nil {
+		return sendError(c, 400, errors.New("after must be a question Id"))
+	}
+	paging, err := parsePaging(c)
+	if err != nil {
+		return sendError(c, 400, err)
+	}
+	limit := paging.Limit
+	questions, err := repo.WithContext(c.UserContext()).ReadAfter(after, limit, allquestions.NormalizeLanguage(c.Query("language")), tags)
+	if err != nil {
+		return sendError(c, 500, err)
+	}
+	page := questionPage{Questions: hideHints(questions), Limit: limit}
+	if len(questions) == limit {
+		next := questions[len(questions)-1].Id
+		page.Next = &next
+	}
+	return c.Status(200).JSON(page)
+}
+
 // The function `questionByIdHandler` retrieves a question by its ID from a repository and returns it
-// as a JSON response.
-func questionByIdHandler(repo *allquestions.Repo) fiber.Handler {
+// as a JSON response, together with the community's difficulty votes, its view count and its
+// acceptance rate. The response is cacheable, so it doesn't count as a view: clients record one with
+// `recordViewHandler`. Questions that aren't published are answered with 404, like missing ones, and
+// IDs that aren't valid with 400.
+//
+//	@Summary	Get a question by ID
+//	@Tags		questions
+//	@Produce	json
+//	@Param		id	path		string	true	"Question ID"
+//	@Success	200	{object}	questionDetail
+//	@Failure	400	{object}	questionErrorResponse
+//	@Failure	404	{object}	questionErrorResponse
+//	@Failure	500	{object}	questionErrorResponse
+//	@Router		/all/question/{id} [get]
+func questionByIdHandler(repo allquestions.Repository, votes difficulty.Service, views *allquestions.ViewCounter, acceptance *submissions.AcceptanceCache) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		question, err := repo.WithContext(c.UserContext()).ReadByID(id)
+		if err == nil && !question.Published() {
+			err = pkg.ErrQuestionNotFound
+		}
+		if err != nil {
+			return sendQuestionError(c, err)
+		}
+		question.Hints = nil
+		breakdown, err := votes.WithContext(c.UserContext()).Breakdown(id)
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		count, err := views.Views(question.ID)
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		rate, err := acceptance.Get(question.ID)
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		return c.Status(200).JSON(questionDetail{AllQuestion: question, CommunityDifficulty: breakdown, Views: count, Acceptance: rate})
+	}
+}
+
+// The function `recordViewHandler` counts a view of the question with the given ID and returns its
+// view count. It is registered apart from `questionByIdHandler` and never cached, so every view is
+// counted even when the question itself is served from the cache. Questions that aren't published are
+// answered with 404, like missing ones, and IDs that aren't valid with 400.
+//
+//	@Summary	Record a view of a question
+//	@Tags		questions
+//	@Produce	json
+//	@Param		id	path		string	true	"Question ID"
+//	@Success	200	{object}	viewResponse
+//	@Failure	400	{object}	questionErrorResponse
+//	@Failure	404	{object}	questionErrorResponse
+//	@Failure	500	{object}	questionErrorResponse
+//	@Router		/all/question/{id}/view [post]
+func recordViewHandler(repo allquestions.Repository, views *allquestions.ViewCounter) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		id := c.Params("id")
-		question, err := repo.ReadByID(id)
+		question, err := repo.WithContext(c.UserContext()).ReadByID(id)
+		if err == nil && !question.Published() {
+			err = pkg.ErrQuestionNotFound
+		}
+		if err != nil {
+			return sendQuestionError(c, err)
+		}
+		if err := views.Record(question.ID); err != nil {
+			logging.Warnf("allquestions: could not record a view of %s: %v", id, err)
+		}
+		count, err := views.Views(question.ID)
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		return c.Status(200).JSON(viewResponse{Views: count})
+	}
+}
+
+// The function `similarQuestionsHandler` returns the questions whose content is most similar to the
+// question with the given ID, by their embeddings, up to the `limit` query parameter. Questions without
+// an embedding get the same questions as `relatedQuestionsHandler` instead.
+//
+//	@Summary	List questions similar in content to a question
+//	@Tags		questions
+//	@Produce	json
+//	@Param		id		path		string	true	"Question ID"
+//	@Param		limit	query		int		false	"Maximum number of questions"	default(5)
+//	@Success	200		{array}		allquestions.AllQuestion
+//	@Failure	400		{object}	questionErrorResponse
+//	@Failure	404		{object}	questionErrorResponse
+//	@Failure	500		{object}	questionErrorResponse
+//	@Router		/all/question/{id}/similar-by-content [get]
+func similarQuestionsHandler(repo allquestions.Repository, finder allquestions.SimilarFinder) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := clampLimit(c.QueryInt("limit", defaultRelatedLimit), defaultRelatedLimit)
+		similar, err := repo.WithContext(c.UserContext()).SimilarByContent(c.Params("id"), limit, finder)
+		if err != nil {
+			return sendQuestionError(c, err)
+		}
+		return c.Status(200).JSON(hideHints(similar))
+	}
+}
+
+// The type `embeddingRequest` is the body of a request setting the embedding of a question.
+type embeddingRequest struct {
+	Embedding []float32 `json:"embedding"`
+}
+
+// The function `setEmbeddingHandler` stores the precomputed embedding of the question with the given
+// ID, which `similarQuestionsHandler` compares. Every question's embedding must come from the same
+// model, so that their vectors have the same length and can be compared.
+//
+//	@Summary	Set the embedding of a question
+//	@Tags		questions
+//	@Accept		json
+//	@Security	BearerAuth
+//	@Param		id		path	string				true	"Question ID"
+//	@Param		body	body	embeddingRequest	true	"Embedding vector"
+//	@Success	204
+//	@Failure	400	{object}	questionErrorResponse
+//	@Failure	404	{object}	questionErrorResponse
+//	@Failure	500	{object}	questionErrorResponse
+//	@Router		/all/question/{id}/embedding [put]
+func setEmbeddingHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body embeddingRequest
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, 400, err)
+		}
+		if len(body.Embedding) == 0 {
+			return sendError(c, 400, pkg.ErrInvalidEmbedding)
+		}
+		err := repo.WithContext(c.UserContext()).SetEmbedding(c.Params("id"), body.Embedding)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return sendError(c, 404, pkg.ErrQuestionNotFound)
+		}
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// The function `relatedQuestionsHandler` returns other questions from the same category as the
+// question with the given ID, up to the `limit` query parameter.
+//
+//	@Summary	List questions related to a question
+//	@Tags		questions
+//	@Produce	json
+//	@Param		id		path		string	true	"Question ID"
+//	@Param		limit	query		int		false	"Maximum number of questions"	default(5)
+//	@Success	200		{array}		allquestions.AllQuestion
+//	@Failure	400		{object}	questionErrorResponse
+//	@Failure	404		{object}	questionErrorResponse
+//	@Failure	500		{object}	questionErrorResponse
+//	@Router		/all/question/{id}/related [get]
+func relatedQuestionsHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := clampLimit(c.QueryInt("limit", defaultRelatedLimit), defaultRelatedLimit)
+		related, err := repo.WithContext(c.UserContext()).Related(c.Params("id"), limit)
+		if err != nil {
+			return sendQuestionError(c, err)
+		}
+		return c.Status(200).JSON(hideHints(related))
+	}
+}
+
+// The function `neighborsHandler` returns the published questions right before and after the question
+// with the given ID by number, for the previous and next buttons of a problem page. Either is null at
+// the ends of the list. The `sameCategory` and `sameLevel` query parameters skip the questions of other
+// categories and levels.
+//
+//	@Summary	Get the previous and next questions
+//	@Tags		questions
+//	@Produce	json
+//	@Param		id				path		string	true	"Question ID"
+//	@Param		sameCategory	query		bool	false	"Only questions of the same category"	default(false)
+//	@Param		sameLevel		query		bool	false	"Only questions of the same level"		default(false)
+//	@Success	200				{object}	allquestions.Neighbors
+//	@Failure	400				{object}	questionErrorResponse
+//	@Failure	404				{object}	questionErrorResponse
+//	@Failure	500				{object}	questionErrorResponse
+//	@Router		/all/question/{id}/neighbors [get]
+func neighborsHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		neighbors, err := repo.WithContext(c.UserContext()).Neighbors(c.Params("id"), c.QueryBool("sameCategory"), c.QueryBool("sameLevel"))
+		if err != nil {
+			return sendQuestionError(c, err)
+		}
+		for _, question := range []*allquestions.AllQuestion{neighbors.Previous, neighbors.Next} {
+			if question != nil {
+				question.Hints = nil
+			}
+		}
+		return c.Status(200).JSON(neighbors)
+	}
+}
+
+// The function `tagsHandler` returns every tag used by a published question with the number of
+// questions using it, most used first.
+//
+//	@Summary	List question tags
+//	@Tags		questions
+//	@Produce	json
+//	@Success	200	{array}		allquestions.TagCount
+//	@Failure	500	{object}	questionErrorResponse
+//	@Router		/all/tags [get]
+func tagsHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tags, err := repo.WithContext(c.UserContext()).ListTags()
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		return c.Status(200).JSON(tags)
+	}
+}
+
+// The function `companiesHandler` returns every company listed by a published question with the number
+// of questions it asks, most asked first. Companies are counted ignoring case.
+//
+//	@Summary	List the companies asking questions
+//	@Tags		questions
+//	@Produce	json
+//	@Success	200	{array}		allquestions.CompanyCount
+//	@Failure	500	{object}	questionErrorResponse
+//	@Router		/all/companies [get]
+func companiesHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		companies, err := repo.WithContext(c.UserContext()).ListCompanies()
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		return c.Status(200).JSON(companies)
+	}
+}
+
+// The function `popularQuestionsHandler` returns all questions ordered by how many users solved them.
+//
+//	@Summary	List questions by solve count
+//	@Tags		questions
+//	@Produce	json
+//	@Success	200	{array}		allquestions.PopularQuestion
+//	@Failure	500	{object}	questionErrorResponse
+//	@Router		/all/popular [get]
+func popularQuestionsHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		popular, err := repo.WithContext(c.UserContext()).ReadPopular()
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		for i := range popular {
+			popular[i].Hints = nil
+		}
+		return c.Status(200).JSON(popular)
+	}
+}
+
+// The function `questionHintsHandler` returns the first `upto` hints of a question, so the client
+// decides how many are revealed. All hints are returned when `upto` is omitted or larger than the
+// number of hints. Questions that aren't published are answered with 404.
+//
+//	@Summary	Reveal the hints of a question
+//	@Tags		questions
+//	@Produce	json
+//	@Param		id		path		string	true	"Question ID"
+//	@Param		upto	query		int		false	"Number of hints to reveal"
+//	@Success	200		{object}	hintsResponse
+//	@Failure	400		{object}	questionErrorResponse
+//	@Failure	404		{object}	questionErrorResponse
+//	@Failure	500		{object}	questionErrorResponse
+//	@Router		/all/question/{id}/hints [get]
+func questionHintsHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		upto := -1
+		if c.Query("upto") != "" {
+			var err error
+			if upto, err = strconv.Atoi(c.Query("upto")); err != nil || upto < 0 {
+				return sendError(c, 400, errors.New("upto must be a non-negative number"))
+			}
+		}
+		question, err := repo.WithContext(c.UserContext()).ReadByID(c.Params("id"))
+		if err == nil && !question.Published() {
+			err = pkg.ErrQuestionNotFound
+		}
+		if err != nil {
+			return sendQuestionError(c, err)
+		}
+		return c.Status(200).JSON(hintsResponse{Hints: question.HintsUpTo(upto), Total: len(question.Hints)})
+	}
+}
+
+// The function checks that every resource of a question has a known type and a URL.
+func validateResources(question allquestions.AllQuestion) error {
+	for _, resource := range question.Resources {
+		if !resource.Valid() {
+			return errors.New("resources must have a type of problem, editorial or video and a url")
+		}
+	}
+	return nil
+}
+
+// The function normalizes the languages of a question and checks that starter code is only given for
+// languages the question supports.
+func validateLanguages(question *allquestions.AllQuestion) error {
+	supported := map[string]bool{}
+	for i, language := range question.Languages {
+		question.Languages[i] = allquestions.NormalizeLanguage(language)
+		supported[question.Languages[i]] = true
+	}
+	starterCode := make(map[string]string, len(question.StarterCode))
+	for language, code := range question.StarterCode {
+		language = allquestions.NormalizeLanguage(language)
+		if !supported[language] {
+			return errors.New("starterCode can only be given for languages listed in languages")
+		}
+		starterCode[language] = code
+	}
+	if len(starterCode) > 0 {
+		question.StarterCode = starterCode
+	}
+	return nil
+}
+
+// The function runs every check a question payload has to pass before it is stored, and returns one
+// entry per problem, or none when the question is valid. Languages, tags and companies are normalized
+// on the way. It is shared by the create and update handlers and the import dry run, so they accept
+// and reject exactly the same questions.
+func questionErrors(question *allquestions.AllQuestion) ([]fieldError, error) {
+	fields, err := fieldErrors(*question)
+	if err != nil || len(fields) > 0 {
+		return fields, err
+	}
+	if err := validateResources(*question); err != nil {
+		return []fieldError{{Field: "resources", Rule: "resource", Message: err.Error()}}, nil
+	}
+	if err := validateLanguages(question); err != nil {
+		return []fieldError{{Field: "starterCode", Rule: "languages", Message: err.Error()}}, nil
+	}
+	question.Tags = allquestions.NormalizeTags(question.Tags)
+	question.Companies = allquestions.NormalizeCompanies(question.Companies)
+	return nil, nil
+}
+
+// The function validates a question payload. When it is invalid, it answers with 400, the
+// `VALIDATION_FAILED` code and the problems found, and returns false.
+func validateQuestion(c *fiber.Ctx, question *allquestions.AllQuestion) (bool, error) {
+	fields, err := questionErrors(question)
+	if err != nil {
+		return false, sendError(c, 400, err)
+	}
+	if len(fields) > 0 {
+		return false, sendValidationError(c, fields)
+	}
+	return true, nil
+}
+
+// The function `createQuestionHandler` creates a new question from the request body. The ID is always
+// assigned by the server. Questions are created as drafts unless the body gives another `status`, so
+// they can be reviewed before they are published.
+//
+//	@Summary	Create a question
+//	@Tags		questions
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		body	body		allquestions.AllQuestion	true	"Question to create"
+//	@Success	201		{object}	allquestions.AllQuestion
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	500		{object}	questionErrorResponse
+//	@Router		/all/question [post]
+func createQuestionHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var question allquestions.AllQuestion
+		if err := decodeBody(c, &question); err != nil {
+			return sendError(c, 400, err)
+		}
+		if ok, err := validateQuestion(c, &question); !ok {
+			return err
+		}
+		question.ID = primitive.NilObjectID
+		if question.Status == "" {
+			question.Status = allquestions.StatusDraft
+		}
+		created, err := repo.WithContext(c.UserContext()).Create(question)
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		return c.Status(201).JSON(created)
+	}
+}
+
+// The function `updateQuestionHandler` replaces the question with the given ID by the request body.
+// When the body has no `status`, the question keeps its current one. The question always keeps its
+// embedding, which is set through `setEmbeddingHandler`.
+//
+//	@Summary	Replace a question
+//	@Tags		questions
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string						true	"Question ID"
+//	@Param		body	body		allquestions.AllQuestion	true	"New question contents"
+//	@Success	200		{object}	allquestions.AllQuestion
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	404		{object}	questionErrorResponse
+//	@Failure	500		{object}	questionErrorResponse
+//	@Router		/all/question/{id} [put]
+func updateQuestionHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var question allquestions.AllQuestion
+		if err := decodeBody(c, &question); err != nil {
+			return sendError(c, 400, err)
+		}
+		if ok, err := validateQuestion(c, &question); !ok {
+			return err
+		}
+		current, err := repo.WithContext(c.UserContext()).ReadByID(c.Params("id"))
+		if err != nil {
+			return sendQuestionError(c, err)
+		}
+		if question.Status == "" {
+			question.Status = current.Status
+		}
+		question.Embedding = current.Embedding
+		updated, err := repo.WithContext(c.UserContext()).Update(c.Params("id"), question)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return sendError(c, 404, pkg.ErrQuestionNotFound)
+		}
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		return c.Status(200).JSON(updated)
+	}
+}
+
+// The function `patchQuestionHandler` changes only the fields of the question with the given ID that
+// are present in the request body. An omitted field keeps its value, while a field sent as an empty
+// string, list or null is cleared. The result must still be a valid question, so required fields such
+// as `Name` can't be cleared, and neither the ID nor the question number can be changed.
+//
+//	@Summary	Update some fields of a question
+//	@Tags		questions
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string						true	"Question ID"
+//	@Param		body	body		allquestions.AllQuestion	true	"The fields to change"
+//	@Success	200		{object}	allquestions.AllQuestion
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	404		{object}	questionErrorResponse
+//	@Failure	500		{object}	questionErrorResponse
+//	@Router		/all/question/{id} [patch]
+func patchQuestionHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		question, err := repo.WithContext(c.UserContext()).ReadByID(c.Params("id"))
+		if err != nil {
+			return sendQuestionError(c, err)
+		}
+		changed, err := allquestions.ApplyPatch(&question, c.Body())
+		if err != nil {
+			return sendError(c, 400, err)
+		}
+		if ok, err := validateQuestion(c, &question); !ok {
+			return err
+		}
+		if len(changed) == 0 {
+			return c.Status(200).JSON(question)
+		}
+		updated, err := repo.WithContext(c.UserContext()).Patch(c.Params("id"), allquestions.PatchUpdate(question, changed))
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return sendError(c, 404, pkg.ErrQuestionNotFound)
+		}
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		return c.Status(200).JSON(updated)
+	}
+}
+
+// The type `importRowReport` is the outcome of checking one question of an import.
+// @property {int} Row - The position of the question in the request, starting at 0.
+// @property {int} Line - For CSV imports, the line of the file the question was read from, starting at
+// 1 for the header.
+// @property {bool} Valid - Whether the question would be imported.
+// @property Errors - Why the question would be rejected. It is empty for valid questions.
+type importRowReport struct {
+	Row    int          `json:"row"`
+	Line   int          `json:"line,omitempty"`
+	Valid  bool         `json:"valid"`
+	Errors []fieldError `json:"errors"`
+}
+
+// The type `importReport` is the body returned by the import dry run.
+// @property {int} Valid - The number of questions that would be imported.
+// @property {int} Invalid - The number of questions that would be rejected.
+// @property Rows - The outcome of every question, in request order.
+type importReport struct {
+	Valid   int               `json:"valid"`
+	Invalid int               `json:"invalid"`
+	Rows    []importRowReport `json:"rows"`
+}
+
+// The function `validateImportHandler` checks a list of questions with the same rules as creating
+// them, without storing anything, and reports which ones would be rejected and why.
+//
+//	@Summary	Dry-run a question import
+//	@Tags		admin
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		body	body		[]allquestions.AllQuestion	true	"Questions to check"
+//	@Success	200		{object}	importReport
+//	@Failure	400		{object}	questionErrorResponse
+//	@Failure	403		{object}	questionErrorResponse
+//	@Router		/all/import/validate [post]
+func validateImportHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var questions []allquestions.AllQuestion
+		if err := decodeBody(c, &questions); err != nil {
+			return sendError(c, 400, err)
+		}
+		report := importReport{Rows: make([]importRowReport, 0, len(questions))}
+		for i := range questions {
+			report.add(checkImportRow(i, &questions[i]))
+		}
+		return c.Status(200).JSON(report)
+	}
+}
+
+// The function checks one question of an import with the same rules as creating it.
+func checkImportRow(i int, question *allquestions.AllQuestion) importRowReport {
+	fields, err := questionErrors(question)
+	if err != nil {
+		fields = []fieldError{{Field: "body", Rule: "invalid", Message: err.Error()}}
+	}
+	if fields == nil {
+		fields = []fieldError{}
+	}
+	return importRowReport{Row: i, Valid: len(fields) == 0, Errors: fields}
+}
+
+// The function adds the outcome of one question to the report.
+func (r *importReport) add(row importRowReport) {
+	if row.Valid {
+		r.Valid++
+	} else {
+		r.Invalid++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// The type `csvImportReport` is the body returned by the CSV import.
+// @property {int} Imported - The number of questions that were stored, which is `valid`.
+type csvImportReport struct {
+	importReport
+	Imported int `json:"imported"`
+}
+
+// The function returns the CSV file of an import request: the multipart `file` field, or the whole
+// body when it is sent as `text/csv`. The content type is compared directly rather than with `c.Is`,
+// which looks "csv" up in the system's MIME table and doesn't find it on every system.
+func csvUpload(c *fiber.Ctx) (io.ReadCloser, error) {
+	if mediaType, _, _ := mime.ParseMediaType(c.Get(fiber.HeaderContentType)); mediaType == "text/csv" {
+		return io.NopCloser(bytes.NewReader(c.Body())), nil
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("%w: send the file in the multipart field \"file\" or as a text/csv body", pkg.ErrInvalidCSV)
+	}
+	return header.Open()
+}
+
+// The function `importCSVHandler` creates questions from an uploaded CSV file with a header row. Each
+// line is checked with the same rules as creating a question; the valid ones are stored together as
+// drafts, unless they have a `status` column, and the others are reported with their problems. Columns
+// are read into the question field with their name, such as `name`, `level` or `tags`, and the
+// optional `mapping` form field, a JSON object such as `{"Problem Title": "name"}`, reads other
+// columns into a field. List fields such as `tags` separate their values with ";".
+//
+//	@Summary	Import questions from a CSV file
+//	@Tags		admin
+//	@Accept		mpfd
+//	@Accept		text/csv
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		file	formData	file	false	"CSV file with a header row"
+//	@Param		mapping	formData	string	false	"JSON object mapping column names to question fields"
+//	@Success	200		{object}	csvImportReport
+//	@Failure	400		{object}	questionErrorResponse
+//	@Failure	403		{object}	questionErrorResponse
+//	@Failure	500		{object}	questionErrorResponse
+//	@Router		/all/import/csv [post]
+func importCSVHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		mapping := map[string]string{}
+		if raw := c.FormValue("mapping"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+				return sendError(c, 400, fmt.Errorf("%w: mapping must be a JSON object of column names to fields", pkg.ErrInvalidCSV))
+			}
+		}
+		file, err := csvUpload(c)
+		if err != nil {
+			return sendError(c, 400, err)
+		}
+		defer file.Close()
+		rows, err := allquestions.ParseCSV(file, mapping)
+		if err != nil {
+			return sendError(c, 400, err)
+		}
+		report := csvImportReport{importReport: importReport{Rows: make([]importRowReport, 0, len(rows))}}
+		valid := []allquestions.AllQuestion{}
+		for i, parsed := range rows {
+			var row importRowReport
+			if parsed.Err != nil {
+				row = importRowReport{Row: i, Errors: []fieldError{{Field: "row", Rule: "csv", Message: parsed.Err.Error()}}}
+			} else {
+				row = checkImportRow(i, &parsed.Question)
+			}
+			row.Line = parsed.Line
+			if row.Valid {
+				parsed.Question.ID = primitive.NilObjectID
+				if parsed.Question.Status == "" {
+					parsed.Question.Status = allquestions.StatusDraft
+				}
+				valid = append(valid, parsed.Question)
+			}
+			report.add(row)
+		}
+		if _, err := repo.WithContext(c.UserContext()).CreateMany(valid); err != nil {
+			return sendError(c, 500, err)
+		}
+		report.Imported = len(valid)
+		return c.Status(200).JSON(report)
+	}
+}
+
+// The function `adminQuestionsHandler` lists the questions of every status, or only those with the
+// `status` query parameter, in `Id` order. Unlike the public listing, it includes the hints.
+//
+//	@Summary	List questions of any status
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		status	query		string	false	"Only return questions with this status"	Enums(draft, published, archived)
+//	@Success	200		{array}		allquestions.AllQuestion
+//	@Failure	400		{object}	questionErrorResponse
+//	@Failure	403		{object}	questionErrorResponse
+//	@Failure	500		{object}	questionErrorResponse
+//	@Router		/auth/questions [get]
+func adminQuestionsHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		status := c.Query("status")
+		if status != "" && !allquestions.ValidStatus(status) {
+			return sendError(c, 400, errors.New("status must be one of draft, published, archived"))
+		}
+		questions, err := repo.WithContext(c.UserContext()).ReadByStatus(status)
+		if err != nil {
+			return sendError(c, 500, err)
+		}
+		return c.Status(200).JSON(questions)
+	}
+}
+
+// The function `publishQuestionHandler` publishes the question with the given ID, making it visible to
+// users.
+//
+//	@Summary	Publish a question
+//	@Tags		questions
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id	path		string	true	"Question ID"
+//	@Success	200	{object}	allquestions.AllQuestion
+//	@Failure	403	{object}	questionErrorResponse
+//	@Failure	404	{object}	questionErrorResponse
+//	@Failure	500	{object}	questionErrorResponse
+//	@Router		/all/question/{id}/publish [patch]
+func publishQuestionHandler(repo allquestions.Repository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		question, err := repo.WithContext(c.UserContext()).SetStatus(c.Params("id"), allquestions.StatusPublished)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return sendError(c, 404, pkg.ErrQuestionNotFound)
+		}
 		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+			return sendError(c, 500, err)
 		}
 		return c.Status(200).JSON(question)
 	}
 }
 
-// The function creates routes for handling requests related to all questions.
-func CreateAllQuestionRoutes(app *fiber.App, allquestionRepo *allquestions.Repo) {
-	app.Get("/api/all/allquestions", allquestionsHandler(allquestionRepo))
-	app.Get("/api/all/question/:id", questionByIdHandler(allquestionRepo))
+// The function creates routes for handling requests related to all questions. Listing and reading
+// questions is public, so guests can browse them: those routes are registered on `router`, limited by
+// IP with `rateLimit`. Creating, updating and publishing questions, listing the ones that aren't
+// published, setting their embeddings and dry-running imports is restricted to admins and registered
+// on `protected`. `finder` compares the embeddings of questions for the similar questions. `votes`
+// provides the community difficulty shown with a single question, `views` counts its views and
+// `acceptance` provides its acceptance rate. `cache` marks the responses of the read-only routes
+// cacheable; views are recorded on a route of their own, which isn't.
+func CreateAllQuestionRoutes(router fiber.Router, protected fiber.Router, allquestionRepo allquestions.Repository, userRepo *auth.Repo, votes difficulty.Service, views *allquestions.ViewCounter, acceptance *submissions.AcceptanceCache, finder allquestions.SimilarFinder, rateLimit fiber.Handler, cache fiber.Handler) {
+	router.Get("/all/allquestions", rateLimit, cache, allquestionsHandler(allquestionRepo))
+	router.Get("/all/popular", rateLimit, cache, popularQuestionsHandler(allquestionRepo))
+	router.Get("/all/tags", rateLimit, cache, tagsHandler(allquestionRepo))
+	router.Get("/all/companies", rateLimit, cache, companiesHandler(allquestionRepo))
+	router.Get("/all/question/:id", rateLimit, cache, questionByIdHandler(allquestionRepo, votes, views, acceptance))
+	router.Post("/all/question/:id/view", rateLimit, recordViewHandler(allquestionRepo, views))
+	router.Get("/all/question/:id/related", rateLimit, cache, relatedQuestionsHandler(allquestionRepo))
+	router.Get("/all/question/:id/neighbors", rateLimit, cache, neighborsHandler(allquestionRepo))
+	router.Get("/all/question/:id/similar-by-content", rateLimit, cache, similarQuestionsHandler(allquestionRepo, finder))
+	router.Get("/all/question/:id/hints", rateLimit, cache, questionHintsHandler(allquestionRepo))
+	protected.Post("/all/question", RequireRole(userRepo, "admin"), createQuestionHandler(allquestionRepo))
+	protected.Put("/all/question/:id", RequireRole(userRepo, "admin"), updateQuestionHandler(allquestionRepo))
+	protected.Patch("/all/question/:id", RequireRole(userRepo, "admin"), patchQuestionHandler(allquestionRepo))
+	protected.Patch("/all/question/:id/publish", RequireRole(userRepo, "admin"), publishQuestionHandler(allquestionRepo))
+	protected.Put("/all/question/:id/embedding", RequireRole(userRepo, "admin"), setEmbeddingHandler(allquestionRepo))
+	protected.Get("/auth/questions", RequireRole(userRepo, "admin"), adminQuestionsHandler(allquestionRepo))
+	protected.Post("/all/import/validate", RequireRole(userRepo, "admin"), validateImportHandler())
+	protected.Post("/all/import/csv", RequireRole(userRepo, "admin"), importCSVHandler(allquestionRepo))
 }
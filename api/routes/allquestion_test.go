@@ -0,0 +1,858 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/difficulty"
+	"sigmacoder/pkg/submissions"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestQuestionKeysetPages(t *testing.T) {
+	// The numbers have gaps, like a collection some questions were deleted from.
+	var stored []allquestions.AllQuestion
+	for _, number := range []int{1, 2, 5, 7, 8} {
+		stored = append(stored, allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: fmt.Sprint("Question ", number), Id: number})
+	}
+	const limit = 2
+
+	dbtest.Run(t, "continuity", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/all/allquestions", allquestionsHandler(allquestions.NewRepo(mt.DB)))
+
+		var seen []int
+		after := 0
+		for pages := 0; pages < len(stored); pages++ {
+			// The mocked deployment answers with the questions a real one would find for the filter.
+			var matching []interface{}
+			for _, question := range stored {
+				if question.Id > after && len(matching) < limit {
+					matching = append(matching, question)
+				}
+			}
+			mt.AddMockResponses(dbtest.Cursor(mt, matching...))
+
+			res, body := send(mt.T, app, fiber.MethodGet, fmt.Sprintf("/all/allquestions?after=%d&limit=%d", after, limit), "")
+			if res.StatusCode != fiber.StatusOK {
+				mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+			}
+			find := dbtest.NextCommand(mt, "find")
+			dbtest.ExpectField(mt, find, "filter", bson.M{"status": bson.M{"$in": bson.A{allquestions.StatusPublished, nil}}, "id": bson.M{"$gt": after}})
+			dbtest.ExpectField(mt, find, "sort", bson.M{"id": 1})
+			dbtest.ExpectField(mt, find, "limit", int64(limit))
+
+			var page questionPage
+			if err := json.Unmarshal([]byte(body), &page); err != nil {
+				mt.Fatal(err)
+			}
+			for _, question := range page.Questions {
+				seen = append(seen, question.Id)
+			}
+			if page.Next == nil {
+				break
+			}
+			if last := page.Questions[len(page.Questions)-1].Id; *page.Next != last {
+				mt.Fatalf("next = %d, want the last Id on the page, %d", *page.Next, last)
+			}
+			after = *page.Next
+		}
+		if want := []int{1, 2, 5, 7, 8}; fmt.Sprint(seen) != fmt.Sprint(want) {
+			mt.Errorf("the pages returned %v, want every question once in order, %v", seen, want)
+		}
+	})
+}
+
+func TestQuestionLanguageFilter(t *testing.T) {
+	published := bson.M{"$in": bson.A{allquestions.StatusPublished, nil}}
+	goQuestion := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Id: 1, Languages: []string{"go", "python"}}
+
+	dbtest.Run(t, "listing", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/all/allquestions", allquestionsHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Cursor(mt, goQuestion))
+
+		res, body := send(mt.T, app, fiber.MethodGet, "/all/allquestions?language=%20Go", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		// A plain equality on the array field matches the questions whose `languages` contain it.
+		pipeline := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)
+		dbtest.ExpectField(mt, pipeline[0].(bson.M), "$match", bson.M{"status": published, "languages": "go"})
+		var questions []allquestions.AllQuestion
+		if err := json.Unmarshal([]byte(body), &questions); err != nil || len(questions) != 1 || questions[0].ID != goQuestion.ID {
+			mt.Errorf("body = %s, want the Go question", body)
+		}
+	})
+
+	dbtest.Run(t, "keyset page", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/all/allquestions", allquestionsHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Cursor(mt, goQuestion))
+
+		if res, body := send(mt.T, app, fiber.MethodGet, "/all/allquestions?after=0&language=GO", ""); res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"status": published, "id": bson.M{"$gt": 0}, "languages": "go"})
+	})
+
+	dbtest.Run(t, "no language", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/all/allquestions", allquestionsHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Cursor(mt, goQuestion))
+
+		send(mt.T, app, fiber.MethodGet, "/all/allquestions", "")
+		pipeline := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)
+		dbtest.ExpectField(mt, pipeline[0].(bson.M), "$match", bson.M{"status": published})
+	})
+}
+
+func TestValidateLanguages(t *testing.T) {
+	tests := []struct {
+		name        string
+		question    allquestions.AllQuestion
+		languages   []string
+		starterCode map[string]string
+		wantErr     bool
+	}{
+		{
+			name:        "normalized",
+			question:    allquestions.AllQuestion{Languages: []string{" Go", "PYTHON"}, StarterCode: map[string]string{"GO": "package main"}},
+			languages:   []string{"go", "python"},
+			starterCode: map[string]string{"go": "package main"},
+		},
+		{name: "no languages", question: allquestions.AllQuestion{}},
+		{
+			name:     "starter code for another language",
+			question: allquestions.AllQuestion{Languages: []string{"go"}, StarterCode: map[string]string{"rust": "fn main() {}"}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := tt.question
+			err := validateLanguages(&question)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateLanguages() = %v, want an error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if fmt.Sprint(question.Languages) != fmt.Sprint(tt.languages) || fmt.Sprint(question.StarterCode) != fmt.Sprint(tt.starterCode) {
+				t.Errorf("got %v %v, want %v %v", question.Languages, question.StarterCode, tt.languages, tt.starterCode)
+			}
+		})
+	}
+}
+
+func TestQuestionHints(t *testing.T) {
+	question := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "3Sum", Id: 3, Hints: []string{"sort first", "two pointers", "mind duplicates"}}
+	tests := []struct {
+		query  string
+		status int
+		hints  int
+	}{
+		{query: "", status: fiber.StatusOK, hints: 3},
+		{query: "?upto=0", status: fiber.StatusOK, hints: 0},
+		{query: "?upto=2", status: fiber.StatusOK, hints: 2},
+		{query: "?upto=99", status: fiber.StatusOK, hints: 3},
+		{query: "?upto=-1", status: fiber.StatusBadRequest},
+		{query: "?upto=two", status: fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, "upto "+tt.query, func(mt *mtest.T) {
+			app := fiber.New()
+			app.Get("/all/question/:id/hints", questionHintsHandler(allquestions.NewRepo(mt.DB)))
+			mt.AddMockResponses(dbtest.Cursor(mt, question))
+
+			res, body := send(mt.T, app, fiber.MethodGet, "/all/question/"+question.ID.Hex()+"/hints"+tt.query, "")
+			if res.StatusCode != tt.status {
+				mt.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.status, body)
+			}
+			if tt.status != fiber.StatusOK {
+				dbtest.ExpectNoCommand(mt)
+				return
+			}
+			var hints hintsResponse
+			if err := json.Unmarshal([]byte(body), &hints); err != nil {
+				mt.Fatal(err)
+			}
+			if len(hints.Hints) != tt.hints || hints.Total != 3 {
+				mt.Errorf("body = %s, want %d of 3 hints", body, tt.hints)
+			}
+		})
+	}
+
+	dbtest.Run(t, "unpublished question", func(mt *mtest.T) {
+		draft := question
+		draft.Status = allquestions.StatusDraft
+		app := fiber.New()
+		app.Get("/all/question/:id/hints", questionHintsHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Cursor(mt, draft))
+		if res, _ := send(mt.T, app, fiber.MethodGet, "/all/question/"+question.ID.Hex()+"/hints", ""); res.StatusCode != fiber.StatusNotFound {
+			mt.Errorf("status = %d, want 404", res.StatusCode)
+		}
+	})
+}
+
+func TestQuestionByIDErrors(t *testing.T) {
+	draft := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "3Sum", Id: 3, Status: allquestions.StatusDraft}
+	tests := []struct {
+		name   string
+		id     string
+		found  []interface{}
+		status int
+		code   string
+	}{
+		{name: "invalid id", id: "not-an-id", status: fiber.StatusBadRequest, code: "INVALID_QUESTION_ID"},
+		{name: "unknown id", id: primitive.NewObjectID().Hex(), found: []interface{}{}, status: fiber.StatusNotFound, code: "QUESTION_NOT_FOUND"},
+		{name: "unpublished question", id: draft.ID.Hex(), found: []interface{}{draft}, status: fiber.StatusNotFound, code: "QUESTION_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			app := fiber.New()
+			app.Get("/all/question/:id", questionByIdHandler(allquestions.NewRepo(mt.DB), nil, nil, nil))
+			if tt.found != nil {
+				mt.AddMockResponses(dbtest.Cursor(mt, tt.found...))
+			}
+			res, body := send(mt.T, app, fiber.MethodGet, "/all/question/"+tt.id, "")
+			if res.StatusCode != tt.status {
+				mt.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.status, body)
+			}
+			expectCode(mt.T, body, tt.code)
+			if tt.found != nil {
+				dbtest.NextCommand(mt, "find")
+			}
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+}
+
+func TestUnpublishedSourceQuestion(t *testing.T) {
+	draft := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "3Sum", Category: "Arrays", Level: "Medium", Id: 3, Status: allquestions.StatusDraft}
+	routes := map[string]func(repo allquestions.Repository) fiber.Handler{
+		"related":   relatedQuestionsHandler,
+		"neighbors": neighborsHandler,
+	}
+	for name, handler := range routes {
+		dbtest.Run(t, name+" of a draft", func(mt *mtest.T) {
+			app := fiber.New()
+			app.Get("/all/question/:id/"+name, handler(allquestions.NewRepo(mt.DB)))
+			mt.AddMockResponses(dbtest.Cursor(mt, draft))
+			res, body := send(mt.T, app, fiber.MethodGet, "/all/question/"+draft.ID.Hex()+"/"+name, "")
+			if res.StatusCode != fiber.StatusNotFound {
+				mt.Fatalf("status = %d, want 404: %s", res.StatusCode, body)
+			}
+			expectCode(mt.T, body, "QUESTION_NOT_FOUND")
+			dbtest.NextCommand(mt, "find")
+			dbtest.ExpectNoCommand(mt)
+		})
+
+		dbtest.Run(t, name+" of an invalid id", func(mt *mtest.T) {
+			app := fiber.New()
+			app.Get("/all/question/:id/"+name, handler(allquestions.NewRepo(mt.DB)))
+			res, body := send(mt.T, app, fiber.MethodGet, "/all/question/not-an-id/"+name, "")
+			if res.StatusCode != fiber.StatusBadRequest {
+				mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+			}
+			expectCode(mt.T, body, "INVALID_QUESTION_ID")
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+}
+
+func TestQuestionTagFilter(t *testing.T) {
+	published := bson.M{"$in": bson.A{allquestions.StatusPublished, nil}}
+	dpQuestion := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "Climbing Stairs", Id: 1, Tags: []string{"dp"}}
+	tests := []struct {
+		name   string
+		target string
+		filter bson.M
+	}{
+		{
+			name:   "single tag",
+			target: "/all/allquestions?tag=%20DP",
+			filter: bson.M{"status": published, "tags": bson.M{"$all": bson.A{"dp"}}},
+		},
+		{
+			name:   "every tag, comma separated",
+			target: "/all/allquestions?tag=dp,graph",
+			filter: bson.M{"status": published, "tags": bson.M{"$all": bson.A{"dp", "graph"}}},
+		},
+		{
+			name:   "any tag, repeated",
+			target: "/all/allquestions?tag=dp&tag=graph&tag=dp&match=any",
+			filter: bson.M{"status": published, "tags": bson.M{"$in": bson.A{"dp", "graph"}}},
+		},
+		{
+			name:   "tags and language",
+			target: "/all/allquestions?tag=dp&language=go",
+			filter: bson.M{"status": published, "languages": "go", "tags": bson.M{"$all": bson.A{"dp"}}},
+		},
+		{
+			name:   "keyset page",
+			target: "/all/allquestions?after=0&tag=dp,graph",
+			filter: bson.M{"status": published, "id": bson.M{"$gt": 0}, "tags": bson.M{"$all": bson.A{"dp", "graph"}}},
+		},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			app := fiber.New()
+			app.Get("/all/allquestions", allquestionsHandler(allquestions.NewRepo(mt.DB)))
+			mt.AddMockResponses(dbtest.Cursor(mt, dpQuestion))
+
+			res, body := send(mt.T, app, fiber.MethodGet, tt.target, "")
+			if res.StatusCode != fiber.StatusOK {
+				mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+			}
+			if strings.Contains(tt.target, "after=") {
+				dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", tt.filter)
+				return
+			}
+			pipeline := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)
+			dbtest.ExpectField(mt, pipeline[0].(bson.M), "$match", tt.filter)
+		})
+	}
+
+	dbtest.Run(t, "unknown match", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/all/allquestions", allquestionsHandler(allquestions.NewRepo(mt.DB)))
+		if res, body := send(mt.T, app, fiber.MethodGet, "/all/allquestions?tag=dp&match=some", ""); res.StatusCode != fiber.StatusBadRequest {
+			mt.Errorf("status = %d, want 400: %s", res.StatusCode, body)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestQuestionTags(t *testing.T) {
+	dbtest.Run(t, "counts", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/all/tags", tagsHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": "dp", "count": 3}, bson.M{"_id": "graph", "count": 1}))
+
+		res, body := send(mt.T, app, fiber.MethodGet, "/all/tags", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var tags []allquestions.TagCount
+		if err := json.Unmarshal([]byte(body), &tags); err != nil {
+			mt.Fatal(err)
+		}
+		if want := []allquestions.TagCount{{Tag: "dp", Count: 3}, {Tag: "graph", Count: 1}}; fmt.Sprint(tags) != fmt.Sprint(want) {
+			mt.Errorf("tags = %v, want %v", tags, want)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$match": bson.M{"status": bson.M{"$in": bson.A{allquestions.StatusPublished, nil}}}},
+			bson.M{"$unwind": "$tags"},
+			bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		})
+	})
+
+	dbtest.Run(t, "no tags", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/all/tags", tagsHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if _, body := send(mt.T, app, fiber.MethodGet, "/all/tags", ""); body != "[]" {
+			mt.Errorf("body = %s, want an empty array", body)
+		}
+	})
+}
+
+func TestQuestionCompanyFilter(t *testing.T) {
+	published := bson.M{"$in": bson.A{allquestions.StatusPublished, nil}}
+	googleQuestion := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Id: 1, Companies: []string{"Google"}}
+	tests := []struct {
+		name      string
+		target    string
+		companies bson.A
+	}{
+		{
+			name:      "single company, any case",
+			target:    "/all/allquestions?company=%20google",
+			companies: bson.A{primitive.Regex{Pattern: "^google$", Options: "i"}},
+		},
+		{
+			name:   "any company, comma separated and repeated",
+			target: "/all/allquestions?company=Google,Meta&company=GOOGLE",
+			companies: bson.A{
+				primitive.Regex{Pattern: "^Google$", Options: "i"},
+				primitive.Regex{Pattern: "^Meta$", Options: "i"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			app := fiber.New()
+			app.Get("/all/allquestions", allquestionsHandler(allquestions.NewRepo(mt.DB)))
+			mt.AddMockResponses(dbtest.Cursor(mt, googleQuestion))
+
+			res, body := send(mt.T, app, fiber.MethodGet, tt.target, "")
+			if res.StatusCode != fiber.StatusOK {
+				mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+			}
+			if !strings.Contains(body, `"companies":["Google"]`) {
+				mt.Errorf("body = %s, want the companies of the question", body)
+			}
+			pipeline := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)
+			dbtest.ExpectField(mt, pipeline[0].(bson.M), "$match", bson.M{"status": published, "companies": bson.M{"$in": tt.companies}})
+		})
+	}
+
+	dbtest.Run(t, "blank company", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/all/allquestions", allquestionsHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Cursor(mt, googleQuestion))
+		if res, body := send(mt.T, app, fiber.MethodGet, "/all/allquestions?company=%20,", ""); res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		pipeline := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)
+		dbtest.ExpectField(mt, pipeline[0].(bson.M), "$match", bson.M{"status": published})
+	})
+}
+
+func TestQuestionCompanies(t *testing.T) {
+	dbtest.Run(t, "counts", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/all/companies", companiesHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": "google", "company": "Google", "count": 3}, bson.M{"_id": "meta", "company": "Meta", "count": 1}))
+
+		res, body := send(mt.T, app, fiber.MethodGet, "/all/companies", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		if want := `[{"company":"Google","count":3},{"company":"Meta","count":1}]`; body != want {
+			mt.Errorf("body = %s, want %s", body, want)
+		}
+		dbtest.NextCommand(mt, "aggregate")
+	})
+
+	dbtest.Run(t, "created with its companies normalized", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Post("/all/question", createQuestionHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Written(1))
+		question := fmt.Sprintf(`{"Name": "Two Sum", "Link": "https://example.com/two-sum", "Level": %q, "companies": [" Google", "google", "Jane  Street"]}`, allquestions.LevelEasy)
+		if res, body := send(mt.T, app, fiber.MethodPost, "/all/question", question); res.StatusCode != fiber.StatusCreated {
+			mt.Fatalf("status = %d, want 201: %s", res.StatusCode, body)
+		}
+		inserted := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+		dbtest.ExpectField(mt, inserted, "companies", bson.A{"Google", "Jane Street"})
+	})
+
+	dbtest.Run(t, "no companies", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/all/companies", companiesHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if _, body := send(mt.T, app, fiber.MethodGet, "/all/companies", ""); body != "[]" {
+			mt.Errorf("body = %s, want an empty array", body)
+		}
+	})
+}
+
+func TestQuestionDetailAcceptance(t *testing.T) {
+	question := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Id: 1, Status: allquestions.StatusPublished}
+	tests := []struct {
+		name     string
+		judged   []interface{}
+		wantRate interface{}
+	}{
+		{name: "mixed outcomes", judged: []interface{}{bson.M{"accepted": 2, "total": 3}}, wantRate: 0.667},
+		{name: "no judged submissions", wantRate: "N/A"},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			questions := allquestions.NewRepo(mt.DB)
+			app := fiber.New()
+			app.Get("/all/question/:id", questionByIdHandler(
+				questions,
+				difficulty.NewDifficultyService(difficulty.NewRepo(mt.DB).(*difficulty.Repo), questions),
+				allquestions.NewViewCounter(questions, time.Hour),
+				submissions.NewAcceptanceCache(submissions.NewRepo(mt.DB).(*submissions.Repo), time.Minute),
+			))
+			mt.AddMockResponses(dbtest.Cursor(mt, question), dbtest.Cursor(mt), dbtest.Cursor(mt), dbtest.Cursor(mt, tt.judged...))
+
+			res, body := send(mt.T, app, fiber.MethodGet, "/all/question/"+question.ID.Hex(), "")
+			if res.StatusCode != fiber.StatusOK {
+				mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+			}
+			var detail struct {
+				Views      int64
+				Acceptance struct{ Rate interface{} }
+			}
+			if err := json.Unmarshal([]byte(body), &detail); err != nil {
+				mt.Fatal(err)
+			}
+			if detail.Acceptance.Rate != tt.wantRate {
+				mt.Errorf("acceptance rate = %v, want %v", detail.Acceptance.Rate, tt.wantRate)
+			}
+			if detail.Views != 0 {
+				mt.Errorf("views = %d, want the cacheable read not counted as a view", detail.Views)
+			}
+		})
+	}
+}
+
+func TestRecordView(t *testing.T) {
+	published := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Id: 1, Status: allquestions.StatusPublished}
+	draft := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "Three Sum", Id: 2, Status: allquestions.StatusDraft}
+	tests := []struct {
+		name     string
+		question allquestions.AllQuestion
+		stored   []interface{}
+		status   int
+		views    int64
+	}{
+		{name: "first view", question: published, status: fiber.StatusOK, views: 1},
+		{name: "viewed before", question: published, stored: []interface{}{bson.M{"_id": published.ID, "views": int64(41)}}, status: fiber.StatusOK, views: 42},
+		{name: "not published", question: draft, status: fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			questions := allquestions.NewRepo(mt.DB)
+			app := fiber.New()
+			app.Post("/all/question/:id/view", recordViewHandler(questions, allquestions.NewViewCounter(questions, time.Hour)))
+			mt.AddMockResponses(dbtest.Cursor(mt, tt.question), dbtest.Cursor(mt, tt.stored...))
+
+			res, body := send(mt.T, app, fiber.MethodPost, "/all/question/"+tt.question.ID.Hex()+"/view", "")
+			if res.StatusCode != tt.status {
+				mt.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.status, body)
+			}
+			if tt.status != fiber.StatusOK {
+				return
+			}
+			var response viewResponse
+			if err := json.Unmarshal([]byte(body), &response); err != nil {
+				mt.Fatal(err)
+			}
+			if response.Views != tt.views {
+				mt.Errorf("views = %d, want %d", response.Views, tt.views)
+			}
+		})
+	}
+}
+
+// The function returns a multipart body with the CSV file in the "file" field and, when it isn't
+// empty, the column mapping in the "mapping" field, together with its content type.
+func csvForm(t *testing.T, file string, mapping string) (string, string) {
+	t.Helper()
+	var body strings.Builder
+	form := multipart.NewWriter(&body)
+	if mapping != "" {
+		if err := form.WriteField("mapping", mapping); err != nil {
+			t.Fatal(err)
+		}
+	}
+	part, err := form.CreateFormFile("file", "questions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(file))
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return body.String(), form.FormDataContentType()
+}
+
+func TestImportCSV(t *testing.T) {
+	file := "Problem,Link,Level,Category,tags\n" +
+		"Two Sum,https://example.com/two-sum,Easy,Arrays,array;hash-table\n" +
+		",https://example.com/untitled,Easy,Arrays,\n" +
+		"Bad \"quote,https://example.com,Easy,Arrays,\n" +
+		"\"Sum, Again\",https://example.com/sum,Medium,Arrays,\n" +
+		"Unknown Level,https://example.com/level,Impossible,Arrays,\n"
+
+	dbtest.Run(t, "valid rows stored, invalid ones reported", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Post("/all/import/csv", importCSVHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Written(2))
+		body, contentType := csvForm(mt.T, file, `{"Problem": "name"}`)
+		res, resBody := send(mt.T, app, fiber.MethodPost, "/all/import/csv", body, fiber.HeaderContentType, contentType)
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, resBody)
+		}
+		var report csvImportReport
+		if err := json.Unmarshal([]byte(resBody), &report); err != nil {
+			mt.Fatal(err)
+		}
+		if report.Imported != 2 || report.Valid != 2 || report.Invalid != 3 || len(report.Rows) != 5 {
+			mt.Fatalf("report = %+v, want 2 of 5 rows imported", report)
+		}
+		for i, want := range []struct {
+			line  int
+			valid bool
+			field string
+		}{
+			{line: 2, valid: true},
+			{line: 3, field: "Name"},
+			{line: 4, field: "row"},
+			{line: 5, valid: true},
+			{line: 6, field: "Level"},
+		} {
+			row := report.Rows[i]
+			if row.Line != want.line || row.Valid != want.valid {
+				mt.Errorf("row %d = %+v, want line %d, valid %v", i, row, want.line, want.valid)
+			}
+			if want.field != "" && (len(row.Errors) == 0 || row.Errors[0].Field != want.field) {
+				mt.Errorf("row %d errors = %+v, want one on %s", i, row.Errors, want.field)
+			}
+		}
+		documents := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)
+		if len(documents) != 2 {
+			mt.Fatalf("inserted %d questions, want 2", len(documents))
+		}
+		first := documents[0].(bson.M)
+		if first["name"] != "Two Sum" || first["status"] != allquestions.StatusDraft {
+			mt.Errorf("inserted %v, want Two Sum as a draft", first)
+		}
+		dbtest.ExpectField(mt, first, "tags", bson.A{"array", "hash-table"})
+	})
+
+	dbtest.Run(t, "csv body without valid rows", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Post("/all/import/csv", importCSVHandler(allquestions.NewRepo(mt.DB)))
+		res, resBody := send(mt.T, app, fiber.MethodPost, "/all/import/csv", file, fiber.HeaderContentType, "text/csv")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, resBody)
+		}
+		var report csvImportReport
+		if err := json.Unmarshal([]byte(resBody), &report); err != nil {
+			mt.Fatal(err)
+		}
+		// Without the mapping, the "Problem" column isn't read, so no row has a name.
+		if report.Imported != 0 || report.Invalid != 5 {
+			mt.Errorf("report = %+v, want every row refused", report)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	for _, tt := range []struct {
+		name    string
+		file    string
+		mapping string
+	}{
+		{name: "empty file", file: ""},
+		{name: "mapping to an unknown field", file: file, mapping: `{"Problem": "title"}`},
+		{name: "mapping that isn't JSON", file: file, mapping: `Problem=name`},
+	} {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			app := fiber.New()
+			app.Post("/all/import/csv", importCSVHandler(allquestions.NewRepo(mt.DB)))
+			body, contentType := csvForm(mt.T, tt.file, tt.mapping)
+			res, resBody := send(mt.T, app, fiber.MethodPost, "/all/import/csv", body, fiber.HeaderContentType, contentType)
+			if res.StatusCode != fiber.StatusBadRequest {
+				mt.Fatalf("status = %d, want 400: %s", res.StatusCode, resBody)
+			}
+			expectCode(mt.T, resBody, "INVALID_CSV")
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+}
+
+func TestPatchQuestion(t *testing.T) {
+	question := allquestions.AllQuestion{ID: primitive.NewObjectID(), Id: 7, Name: "Two Sum", Link: "https://example.com/two-sum", Videourl: "https://example.com/video", Level: allquestions.LevelEasy, Tags: []string{"hash-table"}}
+	path := "/all/question/" + question.ID.Hex()
+	patchApp := func(mt *mtest.T) *fiber.App {
+		app := fiber.New()
+		app.Patch("/all/question/:id", patchQuestionHandler(allquestions.NewRepo(mt.DB)))
+		return app
+	}
+
+	dbtest.Run(t, "only the given fields change", func(mt *mtest.T) {
+		updated := question
+		updated.Name, updated.Videourl, updated.Tags = "Two Sum II", "", nil
+		mt.AddMockResponses(dbtest.Cursor(mt, question), dbtest.Value(mt, updated))
+		res, body := send(mt.T, patchApp(mt), fiber.MethodPatch, path, `{"Name": "Two Sum II", "videourl": "", "tags": null}`)
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "find")
+		command := dbtest.NextCommand(mt, "findAndModify")
+		dbtest.ExpectField(mt, command, "query", bson.M{"_id": question.ID})
+		dbtest.ExpectField(mt, command, "update", bson.M{
+			"$set":   bson.M{"name": "Two Sum II", "videourl": ""},
+			"$unset": bson.M{"tags": ""},
+		})
+	})
+
+	for _, patch := range []string{`{"Id": 5}`, `{"_id": "65f000000000000000000000"}`, `{"id": "65f000000000000000000000"}`} {
+		dbtest.Run(t, "immutable "+patch, func(mt *mtest.T) {
+			mt.AddMockResponses(dbtest.Cursor(mt, question))
+			res, body := send(mt.T, patchApp(mt), fiber.MethodPatch, path, patch)
+			if res.StatusCode != fiber.StatusBadRequest {
+				mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+			}
+			expectCode(mt.T, body, "QUESTION_ID_IMMUTABLE")
+			dbtest.NextCommand(mt, "find")
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+
+	dbtest.Run(t, "required field cleared", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, question))
+		if res, body := send(mt.T, patchApp(mt), fiber.MethodPatch, path, `{"Name": ""}`); res.StatusCode != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+// The type memoryQuestions is a question repository holding its questions in memory, keyed by their
+// hex ID. It embeds the interface, so calling a method it doesn't implement panics, and records the
+// updates given to `Patch`. `err` is returned by `ListCompanies`.
+type memoryQuestions struct {
+	allquestions.Repository
+	questions map[string]allquestions.AllQuestion
+	patches   []bson.M
+	err       error
+}
+
+func (r *memoryQuestions) WithContext(ctx context.Context) allquestions.Repository {
+	return r
+}
+
+func (r *memoryQuestions) ReadByID(id string) (allquestions.AllQuestion, error) {
+	question, ok := r.questions[id]
+	if !ok {
+		return question, mongo.ErrNoDocuments
+	}
+	return question, nil
+}
+
+func (r *memoryQuestions) Create(question allquestions.AllQuestion) (allquestions.AllQuestion, error) {
+	question.ID = primitive.NewObjectID()
+	r.questions[question.ID.Hex()] = question
+	return question, nil
+}
+
+func (r *memoryQuestions) Update(id string, question allquestions.AllQuestion) (allquestions.AllQuestion, error) {
+	if _, ok := r.questions[id]; !ok {
+		return question, mongo.ErrNoDocuments
+	}
+	question.ID, _ = primitive.ObjectIDFromHex(id)
+	r.questions[id] = question
+	return question, nil
+}
+
+func (r *memoryQuestions) Patch(id string, update bson.M) (allquestions.AllQuestion, error) {
+	r.patches = append(r.patches, update)
+	question, ok := r.questions[id]
+	if !ok {
+		return question, mongo.ErrNoDocuments
+	}
+	if name, ok := update["$set"].(bson.M)["name"].(string); ok {
+		question.Name = name
+	}
+	r.questions[id] = question
+	return question, nil
+}
+
+func (r *memoryQuestions) SetStatus(id string, status string) (allquestions.AllQuestion, error) {
+	question, ok := r.questions[id]
+	if !ok {
+		return question, mongo.ErrNoDocuments
+	}
+	question.Status = status
+	r.questions[id] = question
+	return question, nil
+}
+
+func (r *memoryQuestions) ListCompanies() ([]allquestions.CompanyCount, error) {
+	return []allquestions.CompanyCount{}, r.err
+}
+
+func TestQuestionHandlersWithRepository(t *testing.T) {
+	draft := allquestions.AllQuestion{ID: primitive.NewObjectID(), Id: 1, Name: "Two Sum", Link: "https://example.com/two-sum", Level: allquestions.LevelEasy, Status: allquestions.StatusDraft, Embedding: []float32{1, 0}}
+	newApp := func() (*fiber.App, *memoryQuestions) {
+		repo := &memoryQuestions{questions: map[string]allquestions.AllQuestion{draft.ID.Hex(): draft}}
+		app := fiber.New()
+		app.Post("/all/question", createQuestionHandler(repo))
+		app.Put("/all/question/:id", updateQuestionHandler(repo))
+		app.Patch("/all/question/:id", patchQuestionHandler(repo))
+		app.Patch("/all/question/:id/publish", publishQuestionHandler(repo))
+		app.Get("/all/companies", companiesHandler(repo))
+		return app, repo
+	}
+
+	t.Run("create", func(t *testing.T) {
+		app, repo := newApp()
+		res, body := send(t, app, fiber.MethodPost, "/all/question", `{"Name": "3Sum", "Link": "https://example.com/3sum", "Level": "Medium"}`)
+		if res.StatusCode != fiber.StatusCreated {
+			t.Fatalf("status = %d, want 201: %s", res.StatusCode, body)
+		}
+		var created allquestions.AllQuestion
+		if err := json.Unmarshal([]byte(body), &created); err != nil {
+			t.Fatal(err)
+		}
+		if stored, ok := repo.questions[created.ID.Hex()]; !ok || stored.Status != allquestions.StatusDraft {
+			t.Errorf("stored %+v, want the question created as a draft", stored)
+		}
+	})
+
+	t.Run("update keeps the status and embedding", func(t *testing.T) {
+		app, repo := newApp()
+		res, body := send(t, app, fiber.MethodPut, "/all/question/"+draft.ID.Hex(), `{"Name": "Two Sum II", "Link": "https://example.com/two-sum", "Level": "Easy"}`)
+		if res.StatusCode != fiber.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		if stored := repo.questions[draft.ID.Hex()]; stored.Name != "Two Sum II" || stored.Status != allquestions.StatusDraft || len(stored.Embedding) != 2 {
+			t.Errorf("stored %+v, want the new name with the status and embedding kept", stored)
+		}
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		app, repo := newApp()
+		res, body := send(t, app, fiber.MethodPatch, "/all/question/"+draft.ID.Hex(), `{"Name": "Two Sum II"}`)
+		if res.StatusCode != fiber.StatusOK || !strings.Contains(body, `"Name":"Two Sum II"`) {
+			t.Fatalf("status = %d, body = %s, want the patched question", res.StatusCode, body)
+		}
+		if want := []bson.M{{"$set": bson.M{"name": "Two Sum II"}}}; fmt.Sprint(repo.patches) != fmt.Sprint(want) {
+			t.Errorf("patches = %v, want %v", repo.patches, want)
+		}
+	})
+
+	t.Run("publish", func(t *testing.T) {
+		app, repo := newApp()
+		if res, body := send(t, app, fiber.MethodPatch, "/all/question/"+draft.ID.Hex()+"/publish", ""); res.StatusCode != fiber.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		if status := repo.questions[draft.ID.Hex()].Status; status != allquestions.StatusPublished {
+			t.Errorf("status = %q, want the question published", status)
+		}
+	})
+
+	for _, tt := range []struct{ name, method, suffix, body string }{
+		{name: "update", method: fiber.MethodPut, body: `{"Name": "3Sum", "Link": "https://example.com/3sum", "Level": "Medium"}`},
+		{name: "patch", method: fiber.MethodPatch, body: `{"Name": "3Sum"}`},
+		{name: "publish", method: fiber.MethodPatch, suffix: "/publish"},
+	} {
+		t.Run("unknown question "+tt.name, func(t *testing.T) {
+			app, _ := newApp()
+			res, body := send(t, app, tt.method, "/all/question/"+primitive.NewObjectID().Hex()+tt.suffix, tt.body)
+			if res.StatusCode != fiber.StatusNotFound {
+				t.Fatalf("status = %d, want 404: %s", res.StatusCode, body)
+			}
+			expectCode(t, body, "QUESTION_NOT_FOUND")
+		})
+	}
+
+	t.Run("repository failure", func(t *testing.T) {
+		app, repo := newApp()
+		repo.err = errors.New("connection reset")
+		if res, body := send(t, app, fiber.MethodGet, "/all/companies", ""); res.StatusCode != fiber.StatusInternalServerError {
+			t.Errorf("status = %d, want 500: %s", res.StatusCode, body)
+		}
+	})
+}
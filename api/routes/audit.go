@@ -0,0 +1,109 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/logging"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function records an event caused by the current request in the audit log, filling in the
+// client IP. A failure to record is logged but doesn't fail the request.
+func recordAudit(c *fiber.Ctx, repo *audit.Repo, event audit.Event) {
+	event.IP = clientIP(c)
+	if err := repo.WithContext(c.UserContext()).Record(event); err != nil {
+		logging.Errorf("audit: could not record %s: %v", event.Type, err)
+	}
+}
+
+// The function reads the paging and filtering query parameters of an audit listing. Paging follows
+// `parsePaging`. `from` and `to`
+// are RFC 3339 timestamps and `to` must not be before `from`.
+func auditQuery(c *fiber.Ctx) (audit.Filter, int, int, error) {
+	filter := audit.Filter{Type: c.Query("type")}
+	page, err := parsePaging(c)
+	if err != nil {
+		return filter, 0, 0, err
+	}
+	if from := c.Query("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return filter, 0, 0, errors.New("from must be an RFC 3339 timestamp")
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return filter, 0, 0, errors.New("to must be an RFC 3339 timestamp")
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return filter, 0, 0, errors.New("to must not be before from")
+	}
+	return filter, page.Limit, page.Offset, nil
+}
+
+// The function answers an audit listing, restricted to the given user when `userID` isn't empty.
+func listAudit(c *fiber.Ctx, repo *audit.Repo, userID string) error {
+	filter, limit, offset, err := auditQuery(c)
+	if err != nil {
+		return sendError(c, fiber.StatusBadRequest, err)
+	}
+	filter.UserID = userID
+	page, err := repo.WithContext(c.UserContext()).List(filter, limit, offset)
+	if err != nil {
+		return sendError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+// The function `auditEventsHandler` returns a page of the whole audit log, newest first.
+//
+//	@Summary	List audit events
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		type	query		string	false	"Only events of this type"
+//	@Param		from	query		string	false	"Only events at or after this RFC 3339 time"
+//	@Param		to		query		string	false	"Only events before this RFC 3339 time"
+//	@Param		limit	query		int		false	"Page size, clamped to MAX_PAGE_SIZE"
+//	@Param		offset	query		int		false	"Number of events to skip"	default(0)
+//	@Success	200		{object}	audit.Page
+//	@Failure	400		{object}	errorResponse
+//	@Failure	403		{object}	errorResponse
+//	@Router		/auth/audit [get]
+func auditEventsHandler(repo *audit.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return listAudit(c, repo, "")
+	}
+}
+
+// The function `userAuditEventsHandler` returns a page of the audit events about one user, newest
+// first.
+//
+//	@Summary	List the audit events of a user
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string	true	"User ID"
+//	@Param		type	query		string	false	"Only events of this type"
+//	@Param		from	query		string	false	"Only events at or after this RFC 3339 time"
+//	@Param		to		query		string	false	"Only events before this RFC 3339 time"
+//	@Param		limit	query		int		false	"Page size, clamped to MAX_PAGE_SIZE"
+//	@Param		offset	query		int		false	"Number of events to skip"	default(0)
+//	@Success	200		{object}	audit.Page
+//	@Failure	400		{object}	errorResponse
+//	@Failure	403		{object}	errorResponse
+//	@Router		/auth/users/{id}/audit [get]
+func userAuditEventsHandler(repo *audit.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return listAudit(c, repo, c.Params("id"))
+	}
+}
+
+// The function creates the admin-only routes for reading the audit log.
+func CreateAuditRoutes(router fiber.Router, userRepo *auth.Repo, repo *audit.Repo) {
+	router.Get("/auth/audit", RequireRole(userRepo, "admin"), auditEventsHandler(repo))
+	router.Get("/auth/users/:id/audit", RequireRole(userRepo, "admin"), userAuditEventsHandler(repo))
+}
@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"encoding/json"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns an app serving the audit listings without the admin check, with the repository
+// talking to the mocked deployment.
+func auditApp(mt *mtest.T) *fiber.App {
+	repo := audit.NewRepo(mt.DB).(*audit.Repo)
+	app := fiber.New()
+	app.Get("/auth/audit", auditEventsHandler(repo))
+	app.Get("/auth/users/:id/audit", userAuditEventsHandler(repo))
+	return app
+}
+
+func TestAuditListing(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)
+	login := audit.Event{Type: audit.EventLogin, UserID: "user-1", Timestamp: from.Add(time.Hour)}
+
+	dbtest.Run(t, "filtered page", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, dbtest.Namespace, mtest.FirstBatch, bson.D{{Key: "n", Value: 12}}),
+			dbtest.Cursor(mt, login),
+		)
+		res, body := send(mt.T, auditApp(mt), fiber.MethodGet, "/auth/users/user-1/audit?type=auth.login&from=2024-03-01T00:00:00Z&to=2024-03-08T00:00:00Z&limit=5&offset=10", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		query := bson.M{"userid": "user-1", "type": audit.EventLogin, "timestamp": bson.M{"$gte": from, "$lt": to}}
+		count := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)
+		dbtest.ExpectField(mt, count[0].(bson.M), "$match", query)
+		find := dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, find, "filter", query)
+		dbtest.ExpectField(mt, find, "sort", bson.M{"timestamp": -1})
+		dbtest.ExpectField(mt, find, "skip", int64(10))
+		dbtest.ExpectField(mt, find, "limit", int64(5))
+
+		var page audit.Page
+		if err := json.Unmarshal([]byte(body), &page); err != nil {
+			mt.Fatal(err)
+		}
+		if page.Total != 12 || page.Limit != 5 || page.Offset != 10 || len(page.Events) != 1 || page.Events[0].Type != audit.EventLogin {
+			mt.Errorf("page = %+v, want the login event with a total of 12", page)
+		}
+	})
+
+	dbtest.Run(t, "whole log unfiltered", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, dbtest.Namespace, mtest.FirstBatch), dbtest.Cursor(mt))
+		if res, body := send(mt.T, auditApp(mt), fiber.MethodGet, "/auth/audit", ""); res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "aggregate")
+		find := dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, find, "filter", bson.M{})
+		dbtest.ExpectField(mt, find, "limit", int64(pageSizes.Default))
+	})
+
+	for _, query := range []string{
+		"from=yesterday",
+		"to=2024-03-08",
+		"from=2024-03-08T00:00:00Z&to=2024-03-01T00:00:00Z",
+		"offset=-1",
+	} {
+		dbtest.Run(t, "rejected "+query, func(mt *mtest.T) {
+			if res, _ := send(mt.T, auditApp(mt), fiber.MethodGet, "/auth/audit?"+query, ""); res.StatusCode != fiber.StatusBadRequest {
+				mt.Errorf("status = %d, want 400", res.StatusCode)
+			}
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+}
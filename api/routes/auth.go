@@ -5,53 +5,255 @@ package routes
 // and the `fiber` package from the `github.com/gofiber/fiber/v2` repository. These packages are used
 // in the code to handle HTTP requests and responses, and to interact with the authentication service.
 import (
+	"errors"
 	"net/http"
-	"os"
+	"sigmacoder/pkg"
 	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/captcha"
+	"sigmacoder/pkg/flags"
+	"sigmacoder/pkg/idempotency"
+	"sigmacoder/pkg/logging"
+	"sigmacoder/pkg/sessions"
+	"sigmacoder/pkg/token"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	jwtware "github.com/gofiber/jwt/v3"
 )
 
+// The type `tokenResponse` is the body returned by a successful sign up.
+type tokenResponse struct {
+	Token  string `json:"token"`
+	Status string `json:"status"`
+}
+
+// The type `loginResponse` is the body returned by a successful login. It carries the token, the
+// public projection of the logged in user and the time at which the client should consider the
+// session expired.
+type loginResponse struct {
+	Token   string       `json:"token"`
+	User    auth.OutUser `json:"user"`
+	ExpTime time.Time    `json:"expTime"`
+	Status  string       `json:"status"`
+}
+
+// The type `passwordExpiredResponse` is the body returned when the credentials are right but the
+// password has expired. `Token` can only be used to change the password, until `ExpTime`.
+type passwordExpiredResponse struct {
+	Error   string    `json:"error"`
+	Code    string    `json:"code"`
+	Token   string    `json:"token"`
+	ExpTime time.Time `json:"expTime"`
+}
+
+// The type `errorResponse` documents the body returned by the auth handlers when a request fails.
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
 // The function handles sign up requests by parsing the request body, calling the sign up service, and
-// returning a JSON response with a refresh token.
-func SignUpHandler(repo *auth.Repo, svc auth.Service) fiber.Handler {
+// returning a JSON response with a refresh token. Fields longer than their limits are rejected with 400.
+// The `captchaToken` is checked with `verifier` first, and sign ups whose captcha fails are rejected
+// with 400 before any account is created. An email or username that already has an account is rejected
+// with 409.
+//
+// Clients on unreliable networks can send an `Idempotency-Key` header. Keys are scoped to the email
+// signed up with, so clients can't run into each other's keys. A retry with the same key and body is
+// answered with a fresh token for the account the first attempt created, once its password is checked,
+// instead of failing because the account now exists; it is checked before the captcha, whose tokens can
+// only be used once. Reusing a key for a different body is rejected with 422. Keys are remembered for
+// `IDEMPOTENCY_KEY_TTL`.
+//
+//	@Summary	Register a new user
+//	@Tags		auth
+//	@Accept		json
+//	@Produce	json
+//	@Param		Idempotency-Key	header		string		false	"Key that makes retrying the sign up safe"
+//	@Param		body			body		auth.InUser	true	"User to register"
+//	@Success	200				{object}	tokenResponse
+//	@Failure	400				{object}	validationErrorResponse
+//	@Failure	409				{object}	errorResponse
+//	@Failure	422				{object}	errorResponse
+//	@Failure	503				{object}	errorResponse
+//	@Router		/auth/register [post]
+func SignUpHandler(repo *auth.Repo, svc auth.Service, verifier captcha.SignupVerifier, keys *idempotency.Store) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var in auth.InUser
-		if err := c.BodyParser(&in); err != nil {
-			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "status": "failed1"})
+		if err := decodeBody(c, &in); err != nil {
+			return sendError(c, http.StatusBadRequest, err)
+		}
+		if ok, err := validateRequest(c, in); !ok {
+			return err
+		}
+		key, err := idempotencyKey(c)
+		if err != nil {
+			return sendError(c, http.StatusBadRequest, err)
+		}
+		email := auth.NormalizeEmail(in.Email)
+		scope, fingerprint := signUpIdempotency(in)
+		if key != "" {
+			if resumed, err := resumeSignUp(c, svc, keys, scope, key, fingerprint, in.Password); resumed {
+				return err
+			}
+		}
+		device := deviceFromRequest(c)
+		if err := verifier.Verify(c.UserContext(), in.CaptchaToken, device.IP); errors.Is(err, pkg.ErrCaptchaFailed) {
+			return sendError(c, http.StatusBadRequest, err)
+		} else if err != nil {
+			logging.Errorf("captcha: could not verify a sign up: %v", err)
+			return sendError(c, http.StatusServiceUnavailable, pkg.ErrInternal)
 		}
-		refreshToken, err := svc.SignUp(in)
+		refreshToken, err := svc.WithContext(c.UserContext()).SignUp(in, device)
 		if err != nil {
-			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "status": "failed2"})
+			// A concurrent attempt with the same key may have created the account in the meantime.
+			if key != "" {
+				if resumed, err := resumeSignUp(c, svc, keys, scope, key, fingerprint, in.Password); resumed {
+					return err
+				}
+			}
+			return sendError(c, signUpErrorStatus(err), err)
 		}
-		return c.Status(200).JSON(fiber.Map{"token": refreshToken, "status": "success"})
+		if key != "" {
+			// Only the account is remembered: retries are issued tokens of their own.
+			if user, err := repo.WithContext(c.UserContext()).ReadByEmail(email); err == nil {
+				saveIdempotent(c, keys, scope, key, idempotency.Record{UserID: user.ID, Fingerprint: fingerprint, Status: http.StatusOK})
+			}
+		}
+		return c.Status(200).JSON(tokenResponse{Token: refreshToken, Status: "success"})
+	}
+}
+
+// The function maps an error returned by the sign up service to an HTTP status code.
+func signUpErrorStatus(err error) int {
+	if errors.Is(err, pkg.ErrEmailTaken) || errors.Is(err, pkg.ErrUsernameTaken) {
+		return http.StatusConflict
 	}
+	return http.StatusBadRequest
+}
+
+// `registerScope` prefixes the scopes of the idempotency keys sent with sign ups.
+const registerScope = "register"
+
+// The function returns the idempotency scope of a sign up, which is per email so that different clients
+// can't use each other's keys, and the fingerprint of its body. The fingerprint covers every field of the
+// sign up but the single-use captcha token, the password included, so a retry has to repeat them all.
+func signUpIdempotency(in auth.InUser) (string, string) {
+	in.Normalize()
+	scope := registerScope + "|" + requestFingerprint(strings.ToLower(in.Email))
+	return scope, requestFingerprint(strings.ToLower(in.Email), in.Password, in.Name, in.Username, in.PhoneNumber, in.ProfilePic, in.DateOfBirth, in.Gender, in.UserType)
 }
+
+// The function answers a retried sign up when `key` was already used in `scope`, and reports whether it
+// did. The token issued to the first attempt is not replayed: `svc` issues a fresh one for the account
+// that attempt created, after checking the retry's password against it. When that account is gone, the
+// retry is processed as a new sign up.
+func resumeSignUp(c *fiber.Ctx, svc auth.Service, keys *idempotency.Store, scope string, key string, fingerprint string, password string) (bool, error) {
+	record, found, err := findIdempotent(c, keys, scope, key, fingerprint)
+	if err != nil {
+		return true, sendError(c, idempotencyErrorStatus(err), err)
+	}
+	if !found {
+		return false, nil
+	}
+	refreshToken, err := svc.WithContext(c.UserContext()).ResumeSignUp(record.UserID, password, deviceFromRequest(c))
+	if errors.Is(err, pkg.ErrUserNotFound) {
+		return false, nil
+	}
+	if errors.Is(err, pkg.ErrInvalidCredentials) {
+		return true, sendError(c, http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return true, sendError(c, http.StatusInternalServerError, err)
+	}
+	c.Set(idempotentReplayHeader, "true")
+	return true, c.Status(record.Status).JSON(tokenResponse{Token: refreshToken, Status: "success"})
+}
+
+// The function handles login requests by checking the credentials with the auth service and returning
+// a JSON response with a refresh token and the logged in user. Users log in with their email or their
+// username in `identifier`, or with their email in the older `email` field. When the account is
+// scheduled for deletion, the user carries `deletion_scheduled_at` so the client can offer to cancel it.
+// When the password is older than `PASSWORD_MAX_AGE`, it answers with 403 and a short-lived token that
+// can only be used to change the password.
+//
+//	@Summary	Log in with email or username and password
+//	@Tags		auth
+//	@Accept		json
+//	@Produce	json
+//	@Param		body	body		auth.AuthBody	true	"Credentials"
+//	@Success	200		{object}	loginResponse
+//	@Failure	400		{object}	errorResponse
+//	@Failure	403		{object}	passwordExpiredResponse
+//	@Router		/auth/login [post]
 func LoginHandler(repo *auth.Repo, svc auth.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var in auth.AuthBody
-		if err := c.BodyParser(&in); err != nil {
-			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "status": "failed1"})
+		if err := decodeBody(c, &in); err != nil {
+			return sendError(c, http.StatusBadRequest, err)
+		}
+		if ok, err := validateRequest(c, in); !ok {
+			return err
+		}
+		in.Normalize()
+		refreshToken, ExpTime, err := svc.WithContext(c.UserContext()).Login(in.Identifier, in.Password, deviceFromRequest(c))
+		if errors.Is(err, pkg.ErrPasswordExpired) {
+			return c.Status(fiber.StatusForbidden).JSON(passwordExpiredResponse{Error: err.Error(), Code: pkg.Code(err), Token: refreshToken, ExpTime: ExpTime})
 		}
-		refreshToken, ExpTime, err := svc.Login(in.Email, in.Password)
 		if err != nil {
-			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "status": "failed2"})
+			return sendError(c, http.StatusBadRequest, err)
 		}
-		user, err := repo.ReadByEmail(in.Email)
+		user, err := repo.WithContext(c.UserContext()).ReadByIdentifier(in.Identifier)
 		if err != nil {
-			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error(), "status": "failed3"})
+			return sendError(c, http.StatusBadRequest, err)
 		}
-		return c.Status(200).JSON(fiber.Map{"token": refreshToken, "user": user, "expTime": ExpTime, "status": "success"})
+		return c.Status(200).JSON(loginResponse{Token: refreshToken, User: user.ToOutUser(), ExpTime: ExpTime, Status: "success"})
 	}
 }
 
-// The function handles sign up requests by parsing the request body, calling the sign up service, and
-// returning a JSON response with a refresh token.
-func CreateAuthRoutes(app *fiber.App, userRepo *auth.Repo, svc auth.Service) {
-	app.Post("/api/auth/register", SignUpHandler(userRepo, svc))
-	app.Post("/api/auth/login", LoginHandler(userRepo, svc))
-	app.Use(jwtware.New(jwtware.Config{
-		SigningKey: []byte(os.Getenv("JWT_SECRET")),
-	}))
+// The function `meHandler` returns the authenticated user's account, including when their password
+// was last changed.
+//
+//	@Summary	Get your account
+//	@Tags		account
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{object}	auth.OutUser
+//	@Failure	401	{object}	errorResponse
+//	@Router		/auth/me [get]
+func meHandler(repo *auth.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		user, err := repo.WithContext(c.UserContext()).ReadByID(userID)
+		if err != nil {
+			return sendError(c, fiber.StatusUnauthorized, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(user.ToOutUser())
+	}
+}
+
+// `loginPath` is the login route, relative to `APIPrefix`. It stays reachable in every maintenance mode,
+// otherwise admins whose token expired could never turn maintenance off again.
+const loginPath = "/auth/login"
+
+// The function registers the auth routes. Signing up, logging in, confirming an email change and
+// checking a token are public and registered on `router`, limited by IP with `rateLimit`. Sign ups must
+// pass `verifier`, and retried sign ups are answered from `idempotencyKeys`. Signing up is refused
+// while the `signup` feature flag is off. The routes for managing
+// the logged in user's account are registered on `protected`, which requires a valid token (see
+// `RequireAuth`). `keys` checks the tokens `/auth/check` is asked about.
+func CreateAuthRoutes(router fiber.Router, protected fiber.Router, userRepo *auth.Repo, svc auth.Service, verifier captcha.SignupVerifier, sessionRepo *sessions.Repo, rateLimit fiber.Handler, keys *token.Keys, idempotencyKeys *idempotency.Store, featureFlags *flags.FeatureFlags) {
+	router.Post("/auth/register", rateLimit, RequireFlag(featureFlags, flags.Signup), SignUpHandler(userRepo, svc, verifier, idempotencyKeys))
+	router.Post(loginPath, rateLimit, LoginHandler(userRepo, svc))
+	router.Get("/auth/verify-email-change", rateLimit, confirmEmailChangeHandler(svc))
+	router.Get("/auth/check", authCheckHandler(sessionRepo, userRepo, keys))
+	protected.Get("/auth/me", meHandler(userRepo))
+	protected.Post("/auth/me/email", requestEmailChangeHandler(svc))
+	protected.Post("/auth/me/delete-request", requestDeletionHandler(svc))
+	protected.Post("/auth/me/cancel-deletion", cancelDeletionHandler(svc))
+	protected.Post("/auth/me/password", changePasswordHandler(svc))
+	protected.Put("/auth/me/timezone", setTimezoneHandler(svc))
 }
@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestLoginIdentifier(t *testing.T) {
+	in := auth.InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", Password: "correct horse"}
+	user, err := in.ToUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		body   string
+		filter bson.M
+	}{
+		{name: "email", body: `{"identifier": "ada@example.com", "password": "correct horse"}`, filter: bson.M{"email": "ada@example.com"}},
+		{name: "username", body: `{"identifier": " ada ", "password": "correct horse"}`, filter: bson.M{"username": "ada"}},
+		{name: "older email field", body: `{"email": "ada@example.com", "password": "correct horse"}`, filter: bson.M{"email": "ada@example.com"}},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			repo := auth.NewRepo(mt.DB).(*auth.Repo)
+			app := fiber.New()
+			app.Post("/auth/login", LoginHandler(repo, auth.NewAuthService(repo)))
+			mt.AddMockResponses(dbtest.Cursor(mt, user), dbtest.Cursor(mt, user))
+
+			res, body := send(mt.T, app, fiber.MethodPost, "/auth/login", tt.body)
+			if res.StatusCode != fiber.StatusOK {
+				mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+			}
+			var login loginResponse
+			if err := json.Unmarshal([]byte(body), &login); err != nil || login.Token == "" || login.User.ID != user.ID {
+				mt.Errorf("body = %s, want a token and the user", body)
+			}
+			dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", tt.filter)
+			dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", tt.filter)
+		})
+	}
+
+	for _, tt := range []struct {
+		name  string
+		body  string
+		found []interface{}
+		code  string
+	}{
+		{name: "wrong password by username", body: `{"identifier": "ada", "password": "wrong horse"}`, found: []interface{}{user}, code: "INVALID_CREDENTIALS"},
+		{name: "unknown username", body: `{"identifier": "grace", "password": "correct horse"}`, code: "USER_NOT_FOUND"},
+	} {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			repo := auth.NewRepo(mt.DB).(*auth.Repo)
+			app := fiber.New()
+			app.Post("/auth/login", LoginHandler(repo, auth.NewAuthService(repo)))
+			mt.AddMockResponses(dbtest.Cursor(mt, tt.found...))
+
+			res, body := send(mt.T, app, fiber.MethodPost, "/auth/login", tt.body)
+			if res.StatusCode != fiber.StatusBadRequest {
+				mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+			}
+			expectCode(mt.T, body, tt.code)
+		})
+	}
+}
+
+// The type fixedVerifier is a captcha verifier that answers every check with `err` and remembers the
+// tokens it was asked about.
+type fixedVerifier struct {
+	err    error
+	tokens []string
+}
+
+func (v *fixedVerifier) Verify(ctx context.Context, token string, remoteIP string) error {
+	v.tokens = append(v.tokens, token)
+	return v.err
+}
+
+func TestSignUpCaptcha(t *testing.T) {
+	body := `{"name": "Ada Lovelace", "email": "ada@example.com", "username": "ada", "password": "correct horse", "captchaToken": "solved"}`
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{name: "passed", status: fiber.StatusOK},
+		{name: "failed", err: pkg.ErrCaptchaFailed, status: fiber.StatusBadRequest, code: "CAPTCHA_FAILED"},
+		{name: "service unavailable", err: errors.New("siteverify timed out"), status: fiber.StatusServiceUnavailable, code: "INTERNAL_ERROR"},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			repo := auth.NewRepo(mt.DB).(*auth.Repo)
+			verifier := &fixedVerifier{err: tt.err}
+			app := fiber.New()
+			app.Post("/auth/register", SignUpHandler(repo, auth.NewAuthService(repo), verifier, nil))
+			mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt), dbtest.Written(1))
+
+			res, resBody := send(mt.T, app, fiber.MethodPost, "/auth/register", body)
+			if res.StatusCode != tt.status {
+				mt.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.status, resBody)
+			}
+			if len(verifier.tokens) != 1 || verifier.tokens[0] != "solved" {
+				mt.Errorf("verified %v, want the token of the request", verifier.tokens)
+			}
+			if tt.code == "" {
+				dbtest.NextCommand(mt, "find")
+				dbtest.NextCommand(mt, "find")
+				dbtest.NextCommand(mt, "insert")
+				return
+			}
+			expectCode(mt.T, resBody, tt.code)
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+}
+
+func TestSignUpEmailTaken(t *testing.T) {
+	dbtest.Run(t, "existing email", func(mt *mtest.T) {
+		repo := auth.NewRepo(mt.DB).(*auth.Repo)
+		app := fiber.New()
+		app.Post("/auth/register", SignUpHandler(repo, auth.NewAuthService(repo), &fixedVerifier{}, nil))
+		mt.AddMockResponses(dbtest.Cursor(mt, auth.User{ID: "user-1", Email: "ada@example.com"}))
+
+		res, body := send(mt.T, app, fiber.MethodPost, "/auth/register", `{"name": "Ada Lovelace", "email": "ada@example.com", "username": "ada", "password": "correct horse", "captchaToken": "solved"}`)
+		if res.StatusCode != fiber.StatusConflict {
+			mt.Fatalf("status = %d, want 409: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "EMAIL_TAKEN")
+	})
+}
+
+func TestSignUpUsernameTaken(t *testing.T) {
+	dbtest.Run(t, "existing username", func(mt *mtest.T) {
+		repo := auth.NewRepo(mt.DB).(*auth.Repo)
+		app := fiber.New()
+		app.Post("/auth/register", SignUpHandler(repo, auth.NewAuthService(repo), &fixedVerifier{}, nil))
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt, auth.User{ID: "user-1", Email: "grace@example.com", Username: "ada"}))
+
+		res, body := send(mt.T, app, fiber.MethodPost, "/auth/register", `{"name": "Ada Lovelace", "email": "ada@example.com", "username": "ada", "password": "correct horse", "captchaToken": "solved"}`)
+		if res.StatusCode != fiber.StatusConflict {
+			mt.Fatalf("status = %d, want 409: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "USERNAME_TAKEN")
+	})
+}
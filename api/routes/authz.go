@@ -0,0 +1,127 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// The function returns the `userid` claim of the JWT that the jwtware middleware validated for this
+// request, and false when the request carries no valid token.
+func userIDFromToken(c *fiber.Ctx) (string, bool) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return "", false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", false
+	}
+	userID, ok := claims["userid"].(string)
+	return userID, ok && userID != ""
+}
+
+// The function returns a middleware that only lets the request through when the authenticated user's
+// `UserType` is one of the given roles. It responds with 401 when there is no valid token and 403 when
+// the user doesn't have a permitted role. It must be registered after the jwtware middleware.
+func RequireRole(repo *auth.Repo, roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		user, err := repo.WithContext(c.UserContext()).Read(userID)
+		if err != nil {
+			return sendError(c, fiber.StatusUnauthorized, err)
+		}
+		for _, role := range roles {
+			if user.UserType == role {
+				return c.Next()
+			}
+		}
+		return sendError(c, fiber.StatusForbidden, pkg.ErrForbidden)
+	}
+}
+
+// The function returns the `scope` claim of the JWT validated for this request, or an empty string
+// for unrestricted tokens.
+func scopeFromToken(c *fiber.Ctx) string {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return ""
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	scope, _ := claims["scope"].(string)
+	return scope
+}
+
+// The function returns a middleware that keeps the tokens issued to users whose password has expired
+// from being used for anything but changing it, answering every other request with 403. It must be
+// registered after the jwtware middleware.
+func RestrictScopedTokens() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if scopeFromToken(c) == auth.ScopePasswordChange {
+			if c.Method() != fiber.MethodPost || !strings.HasSuffix(c.Path(), "/auth/me/password") {
+				return sendError(c, fiber.StatusForbidden, pkg.ErrPasswordExpired)
+			}
+		}
+		return c.Next()
+	}
+}
+
+// The function returns when the JWT validated for this request was issued, from its `iat` claim, and
+// false when it has none.
+func issuedAtFromToken(c *fiber.Ctx) (time.Time, bool) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return time.Time{}, false
+	}
+	claims, _ := token.Claims.(jwt.MapClaims)
+	issuedAt, ok := claims["iat"].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(issuedAt), 0), true
+}
+
+// The function reports whether the token validated for this request was issued before its user last
+// changed their password, while `auth.RevokeSessionsOnPasswordChange` is on. Tokens without an `iat`
+// claim can't show they were issued after the change, so they count as issued before it once the user
+// has changed their password. It returns `pkg.ErrUserNotFound` when the token's user doesn't exist.
+func tokenPredatesPassword(c *fiber.Ctx, repo *auth.Repo, userID string) (bool, error) {
+	if !auth.RevokeSessionsOnPasswordChange {
+		return false, nil
+	}
+	user, err := repo.WithContext(c.UserContext()).ReadByID(userID)
+	if err != nil {
+		return false, err
+	}
+	issuedAt, _ := issuedAtFromToken(c)
+	return user.TokenPredatesPassword(issuedAt), nil
+}
+
+// The function returns a middleware that rejects the tokens issued before their user last changed
+// their password with 401, unless `auth.RevokeSessionsOnPasswordChange` is off. Revoking the user's
+// sessions on a change only catches the tokens that carry a session, so this is what logs out the
+// others. It must be registered after the jwtware middleware.
+func RejectTokensBeforePasswordChange(repo *auth.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		stale, err := tokenPredatesPassword(c, repo, userID)
+		if errors.Is(err, pkg.ErrUserNotFound) || stale {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,137 @@
+package routes
+
+import (
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/sessions"
+	"sigmacoder/pkg/token"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRequireAuthAfterPasswordChange(t *testing.T) {
+	keys := token.NewHS256(checkSecret)
+	changedAt := time.Now().Add(-time.Minute)
+	user := auth.User{ID: "user-1", Email: "ada@example.com", PasswordChangedAt: changedAt}
+	expires := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims
+		found  []interface{}
+		status int
+	}{
+		{name: "issued after the change", claims: jwt.MapClaims{"userid": "user-1", "iat": time.Now().Unix(), "exp": expires}, found: []interface{}{user}, status: fiber.StatusOK},
+		{name: "issued before the change", claims: jwt.MapClaims{"userid": "user-1", "iat": changedAt.Add(-time.Hour).Unix(), "exp": expires}, found: []interface{}{user}, status: fiber.StatusUnauthorized},
+		{name: "no issue time", claims: jwt.MapClaims{"userid": "user-1", "exp": expires}, found: []interface{}{user}, status: fiber.StatusUnauthorized},
+		{name: "user deleted", claims: jwt.MapClaims{"userid": "user-1", "iat": time.Now().Unix(), "exp": expires}, found: []interface{}{}, status: fiber.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			next := func(c *fiber.Ctx) error { return c.Next() }
+			app := fiber.New()
+			protected := Protected(app, RequireAuth(keys, sessions.NewRepo(mt.DB).(*sessions.Repo), auth.NewRepo(mt.DB).(*auth.Repo), next)...)
+			protected.Get("/auth/me", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+			mt.AddMockResponses(dbtest.Cursor(mt, tt.found...))
+			res, body := send(mt.T, app, fiber.MethodGet, "/auth/me", "", fiber.HeaderAuthorization, bearer(mt.T, keys, tt.claims))
+			if res.StatusCode != tt.status {
+				mt.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.status, body)
+			}
+			if tt.status == fiber.StatusUnauthorized {
+				expectCode(mt.T, body, "UNAUTHORIZED")
+			}
+		})
+	}
+}
+
+func TestRequireAuthWithoutLogoutOnPasswordChange(t *testing.T) {
+	defer func(revoke bool) { auth.RevokeSessionsOnPasswordChange = revoke }(auth.RevokeSessionsOnPasswordChange)
+	auth.RevokeSessionsOnPasswordChange = false
+	keys := token.NewHS256(checkSecret)
+
+	dbtest.Run(t, "token issued before the change", func(mt *mtest.T) {
+		next := func(c *fiber.Ctx) error { return c.Next() }
+		app := fiber.New()
+		protected := Protected(app, RequireAuth(keys, sessions.NewRepo(mt.DB).(*sessions.Repo), auth.NewRepo(mt.DB).(*auth.Repo), next)...)
+		protected.Get("/auth/me", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+		claims := jwt.MapClaims{"userid": "user-1", "iat": time.Now().Add(-time.Hour).Unix(), "exp": time.Now().Add(time.Hour).Unix()}
+		if res, body := send(mt.T, app, fiber.MethodGet, "/auth/me", "", fiber.HeaderAuthorization, bearer(mt.T, keys, claims)); res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestRequireAuthIssuerAndAudience(t *testing.T) {
+	keys := token.NewHS256(checkSecret)
+	keys.SetClaims("sigmacoder", "sigmacoder-web")
+	other := token.NewHS256(checkSecret)
+	other.SetClaims("sigmacoder", "another-app")
+	tests := []struct {
+		name   string
+		signer *token.Keys
+		status int
+	}{
+		{name: "matching claims", signer: keys, status: fiber.StatusOK},
+		{name: "other audience", signer: other, status: fiber.StatusUnauthorized},
+		{name: "no claims", signer: token.NewHS256(checkSecret), status: fiber.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			next := func(c *fiber.Ctx) error { return c.Next() }
+			app := fiber.New()
+			protected := Protected(app, RequireAuth(keys, sessions.NewRepo(mt.DB).(*sessions.Repo), auth.NewRepo(mt.DB).(*auth.Repo), next)...)
+			protected.Get("/auth/me", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+			mt.AddMockResponses(dbtest.Cursor(mt, auth.User{ID: "user-1"}))
+			claims := jwt.MapClaims{"userid": "user-1", "iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix()}
+			res, body := send(mt.T, app, fiber.MethodGet, "/auth/me", "", fiber.HeaderAuthorization, bearer(mt.T, tt.signer, claims))
+			if res.StatusCode != tt.status {
+				mt.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.status, body)
+			}
+			if tt.status == fiber.StatusUnauthorized {
+				expectCode(mt.T, body, "UNAUTHORIZED")
+				dbtest.ExpectNoCommand(mt)
+			}
+		})
+	}
+}
+
+func TestRestrictScopedTokens(t *testing.T) {
+	keys := token.NewHS256(checkSecret)
+	tests := []struct {
+		name   string
+		scope  string
+		method string
+		path   string
+		status int
+	}{
+		{name: "scoped token changes the password", scope: auth.ScopePasswordChange, method: fiber.MethodPost, path: "/auth/me/password", status: fiber.StatusOK},
+		{name: "scoped token reads the account", scope: auth.ScopePasswordChange, method: fiber.MethodGet, path: "/auth/me", status: fiber.StatusForbidden},
+		{name: "unrestricted token reads the account", method: fiber.MethodGet, path: "/auth/me", status: fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			next := func(c *fiber.Ctx) error { return c.Next() }
+			ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+			app := fiber.New()
+			protected := Protected(app, RequireAuth(keys, sessions.NewRepo(mt.DB).(*sessions.Repo), auth.NewRepo(mt.DB).(*auth.Repo), next)...)
+			protected.Get("/auth/me", ok)
+			protected.Post("/auth/me/password", ok)
+			mt.AddMockResponses(dbtest.Cursor(mt, auth.User{ID: "user-1"}))
+			claims := jwt.MapClaims{"userid": "user-1", "iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix()}
+			if tt.scope != "" {
+				claims["scope"] = tt.scope
+			}
+			res, body := send(mt.T, app, tt.method, tt.path, "", fiber.HeaderAuthorization, bearer(mt.T, keys, claims))
+			if res.StatusCode != tt.status {
+				mt.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.status, body)
+			}
+			if tt.status == fiber.StatusForbidden {
+				expectCode(mt.T, body, "PASSWORD_EXPIRED")
+			}
+		})
+	}
+}
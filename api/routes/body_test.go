@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"encoding/json"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/otp"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// `brokenJSON` is a request body cut off in the middle of a value.
+const brokenJSON = `{"email": "ada@example.com", "password": `
+
+func TestBrokenJSON(t *testing.T) {
+	signedIn := authenticated(jwt.MapClaims{"userid": "user-1"})
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		handler fiber.Handler
+	}{
+		{name: "sign up", method: fiber.MethodPost, path: "/auth/register", handler: SignUpHandler(nil, nil, nil, nil)},
+		{name: "login", method: fiber.MethodPost, path: "/auth/login", handler: LoginHandler(nil, nil)},
+		{name: "change password", method: fiber.MethodPost, path: "/auth/me/password", handler: changePasswordHandler(nil)},
+		{name: "change email", method: fiber.MethodPost, path: "/auth/me/email", handler: requestEmailChangeHandler(nil)},
+		{name: "set timezone", method: fiber.MethodPut, path: "/auth/me/timezone", handler: setTimezoneHandler(nil)},
+		{name: "send otp", method: fiber.MethodPost, path: "/auth/sendotp", handler: sendSMS(&recordingProvider{}, []string{"sms"}, new(otp.DailyQuota), NewOTPStats())},
+		{name: "verify otp", method: fiber.MethodPost, path: "/auth/verifyotp", handler: verifySMS(nil, &recordingProvider{}, NewOTPStats())},
+		{name: "create question", method: fiber.MethodPost, path: "/all/question", handler: createQuestionHandler(nil)},
+		{name: "update question", method: fiber.MethodPut, path: "/all/question/1", handler: updateQuestionHandler(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Add(tt.method, tt.path, signedIn, tt.handler)
+
+			res, body := send(t, app, tt.method, tt.path, brokenJSON)
+			if res.StatusCode != fiber.StatusBadRequest {
+				t.Fatalf("status = %d, want %d: %s", res.StatusCode, fiber.StatusBadRequest, body)
+			}
+			expectCode(t, body, "INVALID_BODY")
+			var envelope errorEnvelope
+			if err := json.Unmarshal([]byte(body), &envelope); err != nil || envelope.Error != pkg.ErrInvalidBody.Error() {
+				t.Errorf("body = %s, want the message %q without the parser's details", body, pkg.ErrInvalidBody)
+			}
+		})
+	}
+
+	dbtest.Run(t, "patch question", func(mt *mtest.T) {
+		question := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Id: 1}
+		mt.AddMockResponses(dbtest.Cursor(mt, question))
+		app := fiber.New()
+		app.Patch("/all/question/:id", patchQuestionHandler(allquestions.NewRepo(mt.DB)))
+
+		res, body := send(t, app, fiber.MethodPatch, "/all/question/"+question.ID.Hex(), brokenJSON)
+		if res.StatusCode != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, want %d: %s", res.StatusCode, fiber.StatusBadRequest, body)
+		}
+		expectCode(t, body, "INVALID_BODY")
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function returns a middleware that forbids caching by default: every response is sent with
+// `Cache-Control: no-store`, so account data and the results of writes are never kept by browsers or
+// shared caches. Routes that are safe to cache override it with `CacheFor`.
+func NoStore() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.Next()
+	}
+}
+
+// The function returns a middleware for read-only routes whose responses may be cached for `maxAge`.
+// Only successful GET and HEAD responses are marked cacheable. Requests carrying an `Authorization`
+// header are answered with `private`, so only the client itself caches them and a CDN never serves
+// one user's response to another; anonymous requests are answered with `public`. A `maxAge` of 0
+// leaves the `no-store` default in place.
+func CacheFor(maxAge time.Duration) fiber.Handler {
+	seconds := strconv.Itoa(int(maxAge.Seconds()))
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if maxAge <= 0 || err != nil || c.Response().StatusCode() != fiber.StatusOK {
+			return err
+		}
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			return err
+		}
+		scope := "public"
+		if c.Get(fiber.HeaderAuthorization) != "" {
+			scope = "private"
+		}
+		c.Set(fiber.HeaderCacheControl, scope+", max-age="+seconds)
+		return err
+	}
+}
@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCacheHeaders(t *testing.T) {
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app := fiber.New()
+	app.Use(NoStore())
+	app.Get("/question", CacheFor(5*time.Minute), ok)
+	app.Post("/question", CacheFor(5*time.Minute), ok)
+	app.Get("/missing", CacheFor(5*time.Minute), func(c *fiber.Ctx) error {
+		return sendError(c, fiber.StatusNotFound, fiber.ErrNotFound)
+	})
+	app.Get("/uncached", CacheFor(0), ok)
+	app.Get("/auth/me", ok)
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		headers []string
+		want    string
+	}{
+		{name: "anonymous read", method: fiber.MethodGet, target: "/question", want: "public, max-age=300"},
+		{name: "authenticated read", method: fiber.MethodGet, target: "/question", headers: []string{fiber.HeaderAuthorization, "Bearer token"}, want: "private, max-age=300"},
+		{name: "write", method: fiber.MethodPost, target: "/question", want: "no-store"},
+		{name: "error", method: fiber.MethodGet, target: "/missing", want: "no-store"},
+		{name: "caching turned off", method: fiber.MethodGet, target: "/uncached", want: "no-store"},
+		{name: "account route", method: fiber.MethodGet, target: "/auth/me", headers: []string{fiber.HeaderAuthorization, "Bearer token"}, want: "no-store"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, _ := send(t, app, tt.method, tt.target, "", tt.headers...)
+			if cache := res.Header.Get(fiber.HeaderCacheControl); cache != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", cache, tt.want)
+			}
+		})
+	}
+
+	dbtest.Run(t, "question listing", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Use(NoStore())
+		app.Get("/all/allquestions", CacheFor(time.Minute), allquestionsHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt))
+
+		if res, _ := send(mt.T, app, fiber.MethodGet, "/all/allquestions", ""); res.Header.Get(fiber.HeaderCacheControl) != "public, max-age=60" {
+			mt.Errorf("anonymous Cache-Control = %q, want public caching", res.Header.Get(fiber.HeaderCacheControl))
+		}
+		if res, _ := send(mt.T, app, fiber.MethodGet, "/all/allquestions", "", fiber.HeaderAuthorization, "Bearer token"); res.Header.Get(fiber.HeaderCacheControl) != "private, max-age=60" {
+			mt.Errorf("authenticated Cache-Control = %q, want private caching", res.Header.Get(fiber.HeaderCacheControl))
+		}
+	})
+}
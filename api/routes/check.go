@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/sessions"
+	"sigmacoder/pkg/token"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// The function parses the bearer token of the request's `Authorization` header and checks its
+// signature with `keys` and its expiry, the same way the jwtware middleware does.
+func bearerToken(c *fiber.Ctx, keys *token.Keys) (*jwt.Token, error) {
+	header := c.Get(fiber.HeaderAuthorization)
+	raw := strings.TrimPrefix(header, "Bearer ")
+	if raw == header || raw == "" {
+		return nil, pkg.ErrUnauthorized
+	}
+	return keys.Parse(raw)
+}
+
+// The function `authCheckHandler` tells an API gateway whether a request's token is valid, for use as
+// an nginx `auth_request`. It answers with an empty 200 and the `X-User-Id` and `X-User-Email` headers
+// for the gateway to forward when the token is valid, its session hasn't been revoked and it wasn't
+// issued before the user last changed their password, and with an empty 401 otherwise. Scoped tokens,
+// such as the ones issued for an expired password, are refused as well, since the gateway can't keep
+// them to the routes their scope allows. It does its own token check instead of going through the
+// jwtware middleware, so it never answers with a body, and it isn't rate limited, since every request
+// passing the gateway hits it from the gateway's IP.
+//
+//	@Summary	Check a token
+//	@Tags		auth
+//	@Security	BearerAuth
+//	@Success	200
+//	@Failure	401
+//	@Router		/auth/check [get]
+func authCheckHandler(sessionRepo *sessions.Repo, userRepo *auth.Repo, keys *token.Keys) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, err := bearerToken(c, keys)
+		if err != nil || !token.Valid {
+			return c.Status(fiber.StatusUnauthorized).Send(nil)
+		}
+		c.Locals("user", token)
+		userID, ok := userIDFromToken(c)
+		if !ok || scopeFromToken(c) != "" {
+			return c.Status(fiber.StatusUnauthorized).Send(nil)
+		}
+		if sessionID, ok := sessionIDFromToken(c); ok {
+			err := sessionRepo.WithContext(c.UserContext()).Touch(sessionID)
+			if errors.Is(err, pkg.ErrSessionRevoked) {
+				return c.Status(fiber.StatusUnauthorized).Send(nil)
+			}
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).Send(nil)
+			}
+		}
+		stale, err := tokenPredatesPassword(c, userRepo, userID)
+		if errors.Is(err, pkg.ErrUserNotFound) || stale {
+			return c.Status(fiber.StatusUnauthorized).Send(nil)
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).Send(nil)
+		}
+		claims := token.Claims.(jwt.MapClaims)
+		email, _ := claims["email"].(string)
+		c.Set("X-User-Id", userID)
+		c.Set("X-User-Email", email)
+		return c.Status(fiber.StatusOK).Send(nil)
+	}
+}
@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/sessions"
+	"sigmacoder/pkg/token"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// `checkSecret` is the secret the tokens of the check tests are signed with.
+const checkSecret = "check-secret"
+
+// The function signs a token with the given claims and returns its `Authorization` header value.
+func bearer(t *testing.T, keys *token.Keys, claims jwt.MapClaims) string {
+	t.Helper()
+	signed, err := keys.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + signed
+}
+
+// The function returns an app serving the check endpoint, with the sessions and users talking to the
+// mocked deployment.
+func checkApp(mt *mtest.T, keys *token.Keys) *fiber.App {
+	app := fiber.New()
+	app.Get("/auth/check", authCheckHandler(sessions.NewRepo(mt.DB).(*sessions.Repo), auth.NewRepo(mt.DB).(*auth.Repo), keys))
+	return app
+}
+
+func TestAuthCheck(t *testing.T) {
+	keys := token.NewHS256(checkSecret)
+	expires := time.Now().Add(time.Hour).Unix()
+	issued := time.Now().Unix()
+	valid := jwt.MapClaims{"userid": "user-1", "email": "ada@example.com", "iat": issued, "exp": expires}
+	user := auth.User{ID: "user-1", Email: "ada@example.com"}
+
+	dbtest.Run(t, "valid token", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, user))
+		res, body := send(mt.T, checkApp(mt, keys), fiber.MethodGet, "/auth/check", "", fiber.HeaderAuthorization, bearer(mt.T, keys, valid))
+		if res.StatusCode != fiber.StatusOK || body != "" {
+			mt.Fatalf("status = %d, body = %q, want an empty 200", res.StatusCode, body)
+		}
+		if res.Header.Get("X-User-Id") != "user-1" || res.Header.Get("X-User-Email") != "ada@example.com" {
+			mt.Errorf("X-User-Id = %q, X-User-Email = %q, want the token's user", res.Header.Get("X-User-Id"), res.Header.Get("X-User-Email"))
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "issued after a password change", func(mt *mtest.T) {
+		changed := user
+		changed.PasswordChangedAt = time.Unix(issued, int64(500*time.Millisecond))
+		mt.AddMockResponses(dbtest.Cursor(mt, changed))
+		if res, _ := send(mt.T, checkApp(mt, keys), fiber.MethodGet, "/auth/check", "", fiber.HeaderAuthorization, bearer(mt.T, keys, valid)); res.StatusCode != fiber.StatusOK {
+			mt.Errorf("status = %d, want 200 for the token issued in the second of the change", res.StatusCode)
+		}
+	})
+
+	dbtest.Run(t, "active session", func(mt *mtest.T) {
+		now := time.Now().UTC()
+		mt.AddMockResponses(dbtest.Cursor(mt, sessions.Session{ID: "session-1", UserID: "user-1", LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}), dbtest.Cursor(mt, user))
+		claims := jwt.MapClaims{"userid": "user-1", "sid": "session-1", "iat": issued, "exp": expires}
+		if res, _ := send(mt.T, checkApp(mt, keys), fiber.MethodGet, "/auth/check", "", fiber.HeaderAuthorization, bearer(mt.T, keys, claims)); res.StatusCode != fiber.StatusOK {
+			mt.Errorf("status = %d, want 200", res.StatusCode)
+		}
+	})
+
+	changed := user
+	changed.PasswordChangedAt = time.Now().Add(time.Minute)
+	tests := []struct {
+		name          string
+		authorization string
+		session       bool
+		found         []interface{}
+	}{
+		{name: "no token"},
+		{name: "not a bearer token", authorization: "Basic dXNlcjpwYXNz"},
+		{name: "malformed token", authorization: "Bearer not-a-token"},
+		{name: "other secret", authorization: bearer(t, token.NewHS256("other-secret"), valid)},
+		{name: "expired", authorization: bearer(t, keys, jwt.MapClaims{"userid": "user-1", "exp": time.Now().Add(-time.Minute).Unix()})},
+		{name: "no user", authorization: bearer(t, keys, jwt.MapClaims{"exp": expires})},
+		{name: "scoped token", authorization: bearer(t, keys, jwt.MapClaims{"userid": "user-1", "scope": auth.ScopePasswordChange, "iat": issued, "exp": expires})},
+		{name: "revoked session", authorization: bearer(t, keys, jwt.MapClaims{"userid": "user-1", "sid": "session-1", "exp": expires}), session: true},
+		{name: "unknown user", authorization: bearer(t, keys, valid), found: []interface{}{}},
+		{name: "issued before a password change", authorization: bearer(t, keys, valid), found: []interface{}{changed}},
+		{name: "no issue time after a password change", authorization: bearer(t, keys, jwt.MapClaims{"userid": "user-1", "exp": expires}), found: []interface{}{changed}},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			if tt.session {
+				mt.AddMockResponses(dbtest.Cursor(mt))
+			}
+			if tt.found != nil {
+				mt.AddMockResponses(dbtest.Cursor(mt, tt.found...))
+			}
+			var headers []string
+			if tt.authorization != "" {
+				headers = []string{fiber.HeaderAuthorization, tt.authorization}
+			}
+			res, body := send(mt.T, checkApp(mt, keys), fiber.MethodGet, "/auth/check", "", headers...)
+			if res.StatusCode != fiber.StatusUnauthorized || body != "" {
+				mt.Errorf("status = %d, body = %q, want an empty 401", res.StatusCode, body)
+			}
+			if res.Header.Get("X-User-Id") != "" {
+				mt.Errorf("X-User-Id = %q, want none", res.Header.Get("X-User-Id"))
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package routes
+
+import "github.com/gofiber/fiber/v2"
+
+// The function returns the address of the client that made the request. The app is configured with
+// `EnableTrustedProxyCheck`, so `X-Forwarded-For` is only honored when the request arrived through one
+// of the `TRUSTED_PROXIES`; otherwise the connecting peer's address is used. A trusted proxy that
+// doesn't send the header is treated as the client, rather than every such request sharing an empty
+// address. Anything that attributes a request to a client (rate limiting, auditing, OTP fraud checks)
+// should go through this helper rather than reading the header directly.
+func clientIP(c *fiber.Ctx) string {
+	if ip := c.IP(); ip != "" {
+		return ip
+	}
+	return c.Context().RemoteIP().String()
+}
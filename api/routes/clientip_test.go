@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function returns an app configured like the server, trusting `X-Forwarded-For` only from the
+// given proxies, that answers with the client IP it resolved.
+func clientIPApp(trustedProxies ...string) *fiber.App {
+	app := fiber.New(fiber.Config{
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          trustedProxies,
+		ProxyHeader:             fiber.HeaderXForwardedFor,
+	})
+	app.Get("/ip", func(c *fiber.Ctx) error {
+		return c.SendString(clientIP(c))
+	})
+	return app
+}
+
+// The test requests arrive from 0.0.0.0, the peer address of Fiber's test connection.
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		forwardedFor   string
+		want           string
+	}{
+		{name: "trusted proxy", trustedProxies: []string{"0.0.0.0"}, forwardedFor: "203.0.113.7", want: "203.0.113.7"},
+		{name: "trusted proxy range", trustedProxies: []string{"0.0.0.0/8"}, forwardedFor: "203.0.113.7", want: "203.0.113.7"},
+		{name: "untrusted peer", trustedProxies: []string{"10.0.0.0/8"}, forwardedFor: "203.0.113.7", want: "0.0.0.0"},
+		{name: "no trusted proxies", forwardedFor: "203.0.113.7", want: "0.0.0.0"},
+		{name: "trusted proxy without header", trustedProxies: []string{"0.0.0.0"}, want: "0.0.0.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var headers []string
+			if test.forwardedFor != "" {
+				headers = []string{fiber.HeaderXForwardedFor, test.forwardedFor}
+			}
+			_, body := send(t, clientIPApp(test.trustedProxies...), fiber.MethodGet, "/ip", "", headers...)
+			if body != test.want {
+				t.Errorf("clientIP() = %q, want %q", body, test.want)
+			}
+		})
+	}
+}
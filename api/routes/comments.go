@@ -0,0 +1,117 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/comments"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function maps an error returned by the comments service to an HTTP status code.
+func commentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pkg.ErrInvalidQuestionID), errors.Is(err, pkg.ErrCommentEmpty),
+		errors.Is(err, pkg.ErrCommentTooLong), errors.Is(err, pkg.ErrCommentProfanity),
+		errors.Is(err, pkg.ErrInvalidParentComment):
+		return fiber.StatusBadRequest
+	case errors.Is(err, pkg.ErrQuestionNotFound), errors.Is(err, pkg.ErrCommentNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, pkg.ErrForbidden):
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusInternalServerError
+}
+
+// The function `postCommentHandler` adds the authenticated user's comment to the discussion of a
+// question. Setting `parentId` posts it as a reply to a top-level comment.
+//
+//	@Summary	Comment on a question
+//	@Tags		comments
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string				true	"Question ID"
+//	@Param		body	body		comments.InComment	true	"Comment to post"
+//	@Success	201		{object}	comments.Comment
+//	@Failure	400		{object}	errorResponse
+//	@Failure	401		{object}	errorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Router		/all/question/{id}/comments [post]
+func postCommentHandler(svc comments.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body comments.InComment
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		comment, err := svc.WithContext(c.UserContext()).Post(userID, c.Params("id"), body)
+		if err != nil {
+			return sendError(c, commentErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(comment)
+	}
+}
+
+// The function `listCommentsHandler` returns a page of the top-level comments on a question, newest
+// first, each with its replies and the public profile of its author.
+//
+//	@Summary	List the comments on a question
+//	@Tags		comments
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string	true	"Question ID"
+//	@Param		limit	query		int		false	"Top-level comments per page"
+//	@Param		offset	query		int		false	"Top-level comments to skip"
+//	@Success	200		{object}	comments.Page
+//	@Failure	400		{object}	errorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Router		/all/question/{id}/comments [get]
+func listCommentsHandler(svc comments.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := parsePaging(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		list, err := svc.WithContext(c.UserContext()).List(c.Params("id"), page.Limit, page.Offset)
+		if err != nil {
+			return sendError(c, commentErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusOK).JSON(list)
+	}
+}
+
+// The function `deleteCommentHandler` deletes one of the authenticated user's comments together with
+// its replies.
+//
+//	@Summary	Delete your comment
+//	@Tags		comments
+//	@Security	BearerAuth
+//	@Param		id	path	string	true	"Comment ID"
+//	@Success	204
+//	@Failure	401	{object}	errorResponse
+//	@Failure	403	{object}	errorResponse
+//	@Failure	404	{object}	errorResponse
+//	@Router		/all/comments/{id} [delete]
+func deleteCommentHandler(svc comments.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		if err := svc.WithContext(c.UserContext()).Delete(userID, c.Params("id")); err != nil {
+			return sendError(c, commentErrorStatus(err), err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// The function creates the routes of the discussion threads on questions.
+func CreateCommentRoutes(router fiber.Router, svc comments.Service) {
+	router.Post("/all/question/:id/comments", postCommentHandler(svc))
+	router.Get("/all/question/:id/comments", listCommentsHandler(svc))
+	router.Delete("/all/comments/:id", deleteCommentHandler(svc))
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+)
+
+// The function returns a middleware that compresses responses with the encoding the client asks for in
+// `Accept-Encoding`, such as gzip or deflate, at the given `compress.Level`. Bodies under 200 bytes are
+// left uncompressed by fasthttp, since the encoding overhead would outweigh the savings.
+func Compress(level int) fiber.Handler {
+	return compress.New(compress.Config{Level: compress.Level(level)})
+}
@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"Name":"Two Sum","Level":"Easy"},`, 100)
+	app := fiber.New()
+	app.Use(Compress(0))
+	app.Get("/large", func(c *fiber.Ctx) error {
+		return c.SendString(large)
+	})
+	app.Get("/small", func(c *fiber.Ctx) error {
+		return c.SendString(`{"ping":"pong"}`)
+	})
+
+	t.Run("large response", func(t *testing.T) {
+		res, body := send(t, app, fiber.MethodGet, "/large", "", fiber.HeaderAcceptEncoding, "gzip")
+		if encoding := res.Header.Get(fiber.HeaderContentEncoding); encoding != "gzip" {
+			t.Fatalf("Content-Encoding = %q, want gzip", encoding)
+		}
+		reader, err := gzip.NewReader(strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := io.ReadAll(reader)
+		if err != nil || string(decoded) != large {
+			t.Errorf("the decompressed body doesn't match the response (%v)", err)
+		}
+	})
+	t.Run("small response", func(t *testing.T) {
+		res, body := send(t, app, fiber.MethodGet, "/small", "", fiber.HeaderAcceptEncoding, "gzip")
+		if encoding := res.Header.Get(fiber.HeaderContentEncoding); encoding != "" || body != `{"ping":"pong"}` {
+			t.Errorf("got Content-Encoding %q and body %q, want the body uncompressed", encoding, body)
+		}
+	})
+	t.Run("client without Accept-Encoding", func(t *testing.T) {
+		res, body := send(t, app, fiber.MethodGet, "/large", "")
+		if encoding := res.Header.Get(fiber.HeaderContentEncoding); encoding != "" || body != large {
+			t.Errorf("got Content-Encoding %q, want the body uncompressed", encoding)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// `publicPath` is the prefix of the public question API, relative to `APIPrefix`.
+const publicPath = "/all"
+
+// The function applies the CORS (Cross-Origin Resource Sharing) policies of the API to the app. Both
+// allow all HTTP methods, specific headers, and credentials to be included in the request. Every route
+// under `APIPrefix` only accepts requests from the stricter `authOrigins`, except the public question
+// API under `/api/v1/all`, which accepts them from `publicOrigins`. New route groups are thus covered
+// by the strict policy until they are made public on purpose. Origins are comma separated, and "*"
+// allows any origin.
+func UseCORS(app *fiber.App, publicOrigins string, authOrigins string) {
+	def := cors.Config{
+		AllowMethods:     "GET,POST,HEAD,PUT,DELETE,PATCH,OPTIONS",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Request-With, " + IdempotencyKeyHeader,
+		AllowCredentials: true,
+	}
+	publicCORS := def
+	publicCORS.AllowOrigins = publicOrigins
+	authCORS := def
+	authCORS.AllowOrigins = authOrigins
+	public, strict := cors.New(publicCORS), cors.New(authCORS)
+	// Each request goes through exactly one of the policies, since a preflight request is answered by
+	// the first CORS middleware it reaches.
+	app.Use(APIPrefix, func(c *fiber.Ctx) error {
+		if c.Path() == APIPrefix+publicPath || strings.HasPrefix(c.Path(), APIPrefix+publicPath+"/") {
+			return public(c)
+		}
+		return strict(c)
+	})
+}
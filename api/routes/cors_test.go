@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestUseCORS(t *testing.T) {
+	app := fiber.New()
+	app.Use(LegacyAPIAlias())
+	UseCORS(app, "*", "https://app.sigmacoder.example")
+	ok := func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	}
+	app.Get(APIPrefix+"/all/allquestions", ok)
+	app.Get(APIPrefix+"/auth/me", ok)
+	app.Get(APIPrefix+"/admin/reports", ok)
+	app.Get(APIPrefix+"/allowed", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		origin string
+		want   string
+	}{
+		{name: "public API from any origin", method: fiber.MethodGet, path: APIPrefix + "/all/allquestions", origin: "https://elsewhere.example", want: "*"},
+		{name: "auth API from the app", method: fiber.MethodGet, path: APIPrefix + "/auth/me", origin: "https://app.sigmacoder.example", want: "https://app.sigmacoder.example"},
+		{name: "auth API from another origin", method: fiber.MethodGet, path: APIPrefix + "/auth/me", origin: "https://elsewhere.example", want: ""},
+		{name: "auth API preflight from another origin", method: fiber.MethodOptions, path: APIPrefix + "/auth/me", origin: "https://elsewhere.example", want: ""},
+		{name: "public API preflight", method: fiber.MethodOptions, path: APIPrefix + "/all/allquestions", origin: "https://elsewhere.example", want: "*"},
+		{name: "other API from another origin", method: fiber.MethodGet, path: APIPrefix + "/admin/reports", origin: "https://elsewhere.example", want: ""},
+		{name: "other API from the app", method: fiber.MethodGet, path: APIPrefix + "/admin/reports", origin: "https://app.sigmacoder.example", want: "https://app.sigmacoder.example"},
+		{name: "other API preflight from another origin", method: fiber.MethodOptions, path: APIPrefix + "/admin/reports", origin: "https://elsewhere.example", want: ""},
+		{name: "path sharing the public prefix", method: fiber.MethodGet, path: APIPrefix + "/allowed", origin: "https://elsewhere.example", want: ""},
+		{name: "legacy auth path", method: fiber.MethodGet, path: "/api/auth/me", origin: "https://elsewhere.example", want: ""},
+		{name: "legacy public path", method: fiber.MethodGet, path: "/api/all/allquestions", origin: "https://elsewhere.example", want: "*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := []string{fiber.HeaderOrigin, tt.origin}
+			if tt.method == fiber.MethodOptions {
+				headers = append(headers, fiber.HeaderAccessControlRequestMethod, fiber.MethodGet)
+			}
+			res, _ := send(t, app, tt.method, tt.path, "", headers...)
+			if got := res.Header.Get(fiber.HeaderAccessControlAllowOrigin); got != tt.want {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
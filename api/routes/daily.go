@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/daily"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function `dailyProblemHandler` returns the problem of the day. Every user gets the same problem
+// for a calendar day, which starts at midnight in the timezone given by the `tz` query parameter, or
+// the user's own timezone, or `defaultTimezone` when they haven't set one. It is selected on the first
+// request of the day if the background job hasn't done so yet.
+//
+//	@Summary	Get the problem of the day
+//	@Tags		questions
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		tz	query		string	false	"IANA timezone, e.g. Asia/Kolkata; defaults to your own"
+//	@Success	200	{object}	daily.Today
+//	@Failure	400	{object}	questionErrorResponse
+//	@Failure	404	{object}	questionErrorResponse
+//	@Failure	500	{object}	questionErrorResponse
+//	@Router		/all/daily [get]
+func dailyProblemHandler(svc daily.Service, userRepo *auth.Repo, defaultTimezone string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		loc, err := userLocation(c, userRepo, userID, defaultTimezone)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		today, err := svc.WithContext(c.UserContext()).Today(pkg.NowUTC(), loc)
+		if errors.Is(err, pkg.ErrQuestionNotFound) {
+			return sendError(c, fiber.StatusNotFound, err)
+		}
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(today)
+	}
+}
+
+// The function creates the route serving the problem of the day. Days are counted in the user's
+// timezone, read from `userRepo`, or `defaultTimezone` when they haven't set one.
+func CreateDailyRoutes(router fiber.Router, svc daily.Service, userRepo *auth.Repo, defaultTimezone string) {
+	router.Get("/all/daily", dailyProblemHandler(svc, userRepo, defaultTimezone))
+}
@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `deletionResponse` is the body returned once an account deletion was scheduled.
+type deletionResponse struct {
+	DeletionScheduledAt time.Time `json:"deletionScheduledAt"`
+}
+
+// The function `requestDeletionHandler` schedules the authenticated user's account to be purged once
+// the deletion grace period has passed. The account stays usable until then, and the deletion can be
+// cancelled with `POST /api/auth/me/cancel-deletion`.
+//
+//	@Summary	Schedule the deletion of your account
+//	@Tags		account
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	202	{object}	deletionResponse
+//	@Failure	401	{object}	errorResponse
+//	@Failure	404	{object}	errorResponse
+//	@Router		/auth/me/delete-request [post]
+func requestDeletionHandler(svc auth.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		scheduledAt, err := svc.WithContext(c.UserContext()).ScheduleDeletion(userID, deviceFromRequest(c))
+		if errors.Is(err, pkg.ErrUserNotFound) {
+			return sendError(c, fiber.StatusNotFound, err)
+		}
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusAccepted).JSON(deletionResponse{DeletionScheduledAt: scheduledAt})
+	}
+}
+
+// The function `cancelDeletionHandler` keeps the authenticated user's account when its deletion was
+// scheduled.
+//
+//	@Summary	Cancel the deletion of your account
+//	@Tags		account
+//	@Security	BearerAuth
+//	@Success	204
+//	@Failure	401	{object}	errorResponse
+//	@Failure	404	{object}	errorResponse
+//	@Failure	409	{object}	errorResponse
+//	@Router		/auth/me/cancel-deletion [post]
+func cancelDeletionHandler(svc auth.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		err := svc.WithContext(c.UserContext()).CancelDeletion(userID, deviceFromRequest(c))
+		switch {
+		case errors.Is(err, pkg.ErrUserNotFound):
+			return sendError(c, fiber.StatusNotFound, err)
+		case errors.Is(err, pkg.ErrDeletionNotScheduled):
+			return sendError(c, fiber.StatusConflict, err)
+		case err != nil:
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/devices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `deviceErrorResponse` documents the body returned by the device handlers when a request
+// fails.
+type deviceErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// The function maps an error returned by the devices service to an HTTP status code.
+func deviceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pkg.ErrInvalidDevice):
+		return fiber.StatusBadRequest
+	case errors.Is(err, pkg.ErrDeviceNotFound):
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusInternalServerError
+}
+
+// The function `registerDeviceHandler` registers a push token for the authenticated user. Registering
+// a token again updates the existing device.
+//
+//	@Summary	Register a device for push notifications
+//	@Tags		devices
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		body	body		devices.InDevice	true	"Platform and push token"
+//	@Success	200		{object}	devices.Device
+//	@Failure	400		{object}	deviceErrorResponse
+//	@Failure	401		{object}	deviceErrorResponse
+//	@Router		/auth/me/devices [post]
+func registerDeviceHandler(svc devices.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body devices.InDevice
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		device, err := svc.WithContext(c.UserContext()).Register(userID, body)
+		if err != nil {
+			return sendError(c, deviceErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusOK).JSON(device)
+	}
+}
+
+// The function `removeDeviceHandler` unregisters one of the authenticated user's devices, so it no
+// longer receives push notifications.
+//
+//	@Summary	Remove a device
+//	@Tags		devices
+//	@Security	BearerAuth
+//	@Param		id	path	string	true	"Device ID"
+//	@Success	204
+//	@Failure	401	{object}	deviceErrorResponse
+//	@Failure	404	{object}	deviceErrorResponse
+//	@Router		/auth/me/devices/{id} [delete]
+func removeDeviceHandler(svc devices.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		if err := svc.WithContext(c.UserContext()).Remove(userID, c.Params("id")); err != nil {
+			return sendError(c, deviceErrorStatus(err), err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// The function creates the routes for registering and removing the authenticated user's push
+// notification devices. Devices are always scoped to the user in the request's token.
+func CreateDeviceRoutes(router fiber.Router, svc devices.Service) {
+	router.Post("/auth/me/devices", registerDeviceHandler(svc))
+	router.Delete("/auth/me/devices/:id", removeDeviceHandler(svc))
+}
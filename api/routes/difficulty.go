@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/difficulty"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function maps an error returned by the difficulty vote service to an HTTP status code.
+func difficultyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pkg.ErrInvalidQuestionID), errors.Is(err, pkg.ErrInvalidDifficulty):
+		return fiber.StatusBadRequest
+	case errors.Is(err, pkg.ErrQuestionNotFound):
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusInternalServerError
+}
+
+// The function `voteDifficultyHandler` records how difficult the authenticated user finds a question.
+// Voting again replaces the user's previous vote. It returns the updated community breakdown.
+//
+//	@Summary	Vote on the difficulty of a question
+//	@Tags		questions
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string				true	"Question ID"
+//	@Param		body	body		difficulty.InVote	true	"easy, medium or hard"
+//	@Success	200		{object}	difficulty.Breakdown
+//	@Failure	400		{object}	questionErrorResponse
+//	@Failure	401		{object}	questionErrorResponse
+//	@Failure	404		{object}	questionErrorResponse
+//	@Router		/all/question/{id}/difficulty-vote [post]
+func voteDifficultyHandler(svc difficulty.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body difficulty.InVote
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		breakdown, err := svc.WithContext(c.UserContext()).Vote(userID, c.Params("id"), body.Level)
+		if err != nil {
+			return sendError(c, difficultyErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusOK).JSON(breakdown)
+	}
+}
+
+// The function creates the route for voting on the difficulty of questions.
+func CreateDifficultyRoutes(router fiber.Router, svc difficulty.Service) {
+	router.Post("/all/question/:id/difficulty-vote", voteDifficultyHandler(svc))
+}
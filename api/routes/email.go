@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `emailChangeBody` is the request body for changing the authenticated user's email.
+type emailChangeBody struct {
+	Email string `json:"email"`
+}
+
+// The type `emailChangeResponse` is the body returned once an email change was requested.
+type emailChangeResponse struct {
+	PendingEmail string `json:"pendingEmail"`
+}
+
+// The function maps an error of the email change flow to an HTTP status code.
+func emailChangeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pkg.ErrInvalidEmail), errors.Is(err, pkg.ErrInvalidEmailToken):
+		return fiber.StatusBadRequest
+	case errors.Is(err, pkg.ErrEmailTaken):
+		return fiber.StatusConflict
+	}
+	return fiber.StatusInternalServerError
+}
+
+// The function `requestEmailChangeHandler` starts changing the authenticated user's email. The new
+// address only takes effect once the link sent to it is followed.
+//
+//	@Summary	Change your email address
+//	@Tags		account
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		body	body		emailChangeBody	true	"New email address"
+//	@Success	202		{object}	emailChangeResponse
+//	@Failure	400		{object}	errorResponse
+//	@Failure	401		{object}	errorResponse
+//	@Failure	409		{object}	errorResponse
+//	@Router		/auth/me/email [post]
+func requestEmailChangeHandler(svc auth.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body emailChangeBody
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		if err := svc.WithContext(c.UserContext()).RequestEmailChange(userID, body.Email); err != nil {
+			return sendError(c, emailChangeErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusAccepted).JSON(emailChangeResponse{PendingEmail: auth.NormalizeEmail(body.Email)})
+	}
+}
+
+// The function `confirmEmailChangeHandler` completes an email change from the link sent to the new
+// address. It is public, since the link is opened from the email rather than from the app.
+//
+//	@Summary	Confirm an email change
+//	@Tags		account
+//	@Produce	json
+//	@Param		token	query		string	true	"Token from the verification link"
+//	@Success	200		{object}	auth.OutUser
+//	@Failure	400		{object}	errorResponse
+//	@Failure	409		{object}	errorResponse
+//	@Router		/auth/verify-email-change [get]
+func confirmEmailChangeHandler(svc auth.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, err := svc.WithContext(c.UserContext()).ConfirmEmailChange(c.Query("token"))
+		if err != nil {
+			return sendError(c, emailChangeErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusOK).JSON(user.ToOutUser())
+	}
+}
@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/logging"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// `statusCodes` holds the generic codes used for errors that aren't one of the sentinels in `pkg`,
+// such as malformed request bodies, keyed by the HTTP status they are answered with.
+var statusCodes = map[int]string{
+	fiber.StatusBadRequest:            "BAD_REQUEST",
+	fiber.StatusUnauthorized:          "UNAUTHORIZED",
+	fiber.StatusForbidden:             "FORBIDDEN",
+	fiber.StatusNotFound:              "NOT_FOUND",
+	fiber.StatusMethodNotAllowed:      "METHOD_NOT_ALLOWED",
+	fiber.StatusConflict:              "CONFLICT",
+	fiber.StatusRequestEntityTooLarge: "PAYLOAD_TOO_LARGE",
+	fiber.StatusTooManyRequests:       "TOO_MANY_REQUESTS",
+	fiber.StatusServiceUnavailable:    "SERVICE_UNAVAILABLE",
+	fiber.StatusGatewayTimeout:        "REQUEST_TIMEOUT",
+}
+
+// The function returns the machine-readable code for an error answered with the given status: the
+// sentinel's own code when there is one, otherwise the generic code of the status.
+func errorCode(status int, err error) string {
+	if code := pkg.Code(err); code != "" {
+		return code
+	}
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return "INTERNAL_ERROR"
+}
+
+// The function writes the error envelope, `{"error": <message>, "code": <code>}`, with the given
+// status. Errors from parsers and the database can quote the request, so sensitive values in the
+// message, such as passwords and OTP codes, are masked by `logging.Redact`.
+func sendError(c *fiber.Ctx, status int, err error) error {
+	return c.Status(status).JSON(fiber.Map{"error": logging.Redact(err.Error()), "code": errorCode(status, err)})
+}
+
+// The function parses the request body into `out`. Any parser failure, such as malformed JSON or an
+// unsupported content type, is reported as `pkg.ErrInvalidBody`, so clients get a clean message and
+// code instead of the parser's internal details.
+func decodeBody(c *fiber.Ctx, out interface{}) error {
+	if err := c.BodyParser(out); err != nil {
+		return pkg.ErrInvalidBody
+	}
+	return nil
+}
+
+// The function is the app-wide Fiber error handler. It answers errors that handlers return instead of
+// writing a response themselves, as well as Fiber's own errors such as unknown routes, with the same
+// envelope as the handlers.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		status = fiberErr.Code
+	}
+	return sendError(c, status, err)
+}
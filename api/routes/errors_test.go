@@ -0,0 +1,103 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/notes"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// The type errorEnvelope is the body of error responses.
+type errorEnvelope struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// The function decodes an error response and checks its code.
+func expectCode(t *testing.T, body string, code string) {
+	t.Helper()
+	var envelope errorEnvelope
+	if err := json.Unmarshal([]byte(body), &envelope); err != nil {
+		t.Fatalf("body = %s, want an error envelope: %v", body, err)
+	}
+	if envelope.Code != code || envelope.Error == "" {
+		t.Errorf("body = %s, want a message and the code %s", body, code)
+	}
+}
+
+// The type failingNotes is a notes service whose every call fails with `err`.
+type failingNotes struct {
+	err error
+}
+
+func (s failingNotes) Save(userID string, questionID string, content string) (notes.Note, error) {
+	return notes.Note{}, s.err
+}
+
+func (s failingNotes) Get(userID string, questionID string) (notes.Note, error) {
+	return notes.Note{}, s.err
+}
+
+func (s failingNotes) WithContext(ctx context.Context) notes.Service {
+	return s
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+		code   string
+	}{
+		{name: "sentinel", status: fiber.StatusNotFound, err: pkg.ErrUserNotFound, code: "USER_NOT_FOUND"},
+		{name: "wrapped sentinel", status: fiber.StatusUnauthorized, err: fmt.Errorf("login: %w", pkg.ErrInvalidCredentials), code: "INVALID_CREDENTIALS"},
+		{name: "other error", status: fiber.StatusBadRequest, err: errors.New("limit must be a number"), code: "BAD_REQUEST"},
+		{name: "other error with an unlisted status", status: fiber.StatusInternalServerError, err: errors.New("connection reset"), code: "INTERNAL_ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				return sendError(c, tt.status, tt.err)
+			})
+			res, body := send(t, app, fiber.MethodGet, "/", "")
+			if res.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.status)
+			}
+			expectCode(t, body, tt.code)
+		})
+	}
+
+	t.Run("central error handler", func(t *testing.T) {
+		app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		app.Get("/fails", func(c *fiber.Ctx) error {
+			return pkg.ErrMaintenance
+		})
+		res, body := send(t, app, fiber.MethodGet, "/missing", "")
+		if res.StatusCode != fiber.StatusNotFound {
+			t.Errorf("status = %d, want 404", res.StatusCode)
+		}
+		expectCode(t, body, "NOT_FOUND")
+		res, body = send(t, app, fiber.MethodGet, "/fails", "")
+		if res.StatusCode != fiber.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", res.StatusCode)
+		}
+		expectCode(t, body, "MAINTENANCE")
+	})
+
+	t.Run("note handlers", func(t *testing.T) {
+		app := fiber.New()
+		CreateNoteRoutes(app.Group("", authenticated(jwt.MapClaims{"userid": "user-1"})), failingNotes{err: pkg.ErrNoteNotFound})
+		res, body := send(t, app, fiber.MethodGet, "/all/question/abc/notes", "")
+		if res.StatusCode != fiber.StatusNotFound {
+			t.Errorf("status = %d, want 404", res.StatusCode)
+		}
+		expectCode(t, body, "NOTE_NOT_FOUND")
+	})
+}
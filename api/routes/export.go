@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/notes"
+	"sigmacoder/pkg/progress"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `accountExport` is the document a user downloads to take their data elsewhere. The profile
+// is the `OutUser` projection, so internal fields such as the password hash are never included.
+type accountExport struct {
+	ExportedAt time.Time           `json:"exportedAt"`
+	Profile    auth.OutUser        `json:"profile"`
+	Progress   []progress.Progress `json:"progress"`
+	Notes      []notes.Note        `json:"notes"`
+}
+
+// The function `exportHandler` assembles everything stored about the authenticated user into a single
+// JSON document and returns it as a downloadable attachment.
+//
+//	@Summary	Export your data
+//	@Tags		account
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{object}	accountExport
+//	@Failure	401	{object}	errorResponse
+//	@Failure	500	{object}	errorResponse
+//	@Router		/auth/me/export [get]
+func exportHandler(userRepo *auth.Repo, progressRepo *progress.Repo, noteRepo *notes.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		ctx := c.UserContext()
+		user, err := userRepo.WithContext(ctx).Read(userID)
+		if err != nil {
+			return sendError(c, fiber.StatusUnauthorized, err)
+		}
+		solved, err := progressRepo.WithContext(ctx).ListByUser(userID)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		userNotes, err := noteRepo.WithContext(ctx).ListByUser(userID)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		c.Attachment("sigmacoder-export.json")
+		return c.Status(fiber.StatusOK).JSON(accountExport{
+			ExportedAt: pkg.NowUTC(),
+			Profile:    user.ToOutUser(),
+			Progress:   solved,
+			Notes:      userNotes,
+		})
+	}
+}
+
+// The function creates the route that lets the authenticated user download their data.
+func CreateExportRoutes(router fiber.Router, userRepo *auth.Repo, progressRepo *progress.Repo, noteRepo *notes.Repo) {
+	router.Get("/auth/me/export", exportHandler(userRepo, progressRepo, noteRepo))
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"encoding/json"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/notes"
+	"sigmacoder/pkg/progress"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestExport(t *testing.T) {
+	user := auth.User{
+		ID:                 "user-1",
+		Name:               "Ada",
+		Email:              "ada@example.com",
+		Password:           "$2a$10$hashhashhashhashhashhashhashhashhashhashhashhashhash",
+		PendingEmail:       "new@example.com",
+		EmailChangeToken:   "change-token",
+		EmailChangeExpires: time.Now().Add(time.Hour),
+	}
+	question := primitive.NewObjectID()
+	solved := progress.Progress{ID: primitive.NewObjectID(), UserID: user.ID, QuestionID: question, SolvedAt: time.Now()}
+	note := notes.Note{ID: primitive.NewObjectID(), UserID: user.ID, QuestionID: question, Content: "use a hash map"}
+
+	dbtest.Run(t, "sections", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/auth/me/export", authenticated(jwt.MapClaims{"userid": user.ID}), exportHandler(
+			auth.NewRepo(mt.DB).(*auth.Repo), progress.NewRepo(mt.DB).(*progress.Repo), notes.NewRepo(mt.DB).(*notes.Repo)))
+		// The stored user also has the token version, which the `User` type doesn't know about.
+		stored := dbtest.Document(mt, user)
+		stored = append(stored, bson.E{Key: "tokenversion", Value: 3})
+		mt.AddMockResponses(dbtest.Cursor(mt, stored), dbtest.Cursor(mt, solved), dbtest.Cursor(mt, note))
+
+		res, body := send(mt.T, app, fiber.MethodGet, "/auth/me/export", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		if disposition := res.Header.Get(fiber.HeaderContentDisposition); !strings.HasPrefix(disposition, "attachment") {
+			mt.Errorf("Content-Disposition = %q, want an attachment", disposition)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": user.ID})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"userid": user.ID})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"userid": user.ID})
+
+		var export map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(body), &export); err != nil {
+			mt.Fatal(err)
+		}
+		for _, section := range []string{"exportedAt", "profile", "progress", "notes"} {
+			if _, ok := export[section]; !ok {
+				mt.Errorf("the export has no %q section: %s", section, body)
+			}
+		}
+		var profile map[string]interface{}
+		if err := json.Unmarshal(export["profile"], &profile); err != nil {
+			mt.Fatal(err)
+		}
+		if profile["id"] != user.ID || profile["email"] != user.Email {
+			mt.Errorf("profile = %v, want the user's", profile)
+		}
+		for _, field := range []string{"password", "tokenversion", "token_version", "pendingemail", "emailchangetoken"} {
+			if _, ok := profile[field]; ok {
+				mt.Errorf("the profile has the %q field: %s", field, export["profile"])
+			}
+		}
+		for _, value := range []string{user.Password, user.EmailChangeToken, user.PendingEmail} {
+			if strings.Contains(body, value) {
+				mt.Errorf("the export contains %q: %s", value, body)
+			}
+		}
+		if !strings.Contains(string(export["progress"]), question.Hex()) || !strings.Contains(string(export["notes"]), note.Content) {
+			mt.Errorf("the export is missing the progress or the notes: %s", body)
+		}
+	})
+
+	dbtest.Run(t, "unauthenticated", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/auth/me/export", exportHandler(
+			auth.NewRepo(mt.DB).(*auth.Repo), progress.NewRepo(mt.DB).(*progress.Repo), notes.NewRepo(mt.DB).(*notes.Repo)))
+		if res, _ := send(mt.T, app, fiber.MethodGet, "/auth/me/export", ""); res.StatusCode != fiber.StatusUnauthorized {
+			mt.Errorf("status = %d, want 401", res.StatusCode)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
@@ -0,0 +1,121 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/flags"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// `flagsPath` is the prefix of the admin routes for the feature flags, relative to `APIPrefix`.
+const flagsPath = "/auth/flags"
+
+// The type `setFlagBody` is the request body for overriding a feature flag.
+type setFlagBody struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
+
+// The function returns a middleware that answers with 403 Forbidden while the feature flag `name` is
+// off, and lets the request through otherwise.
+func RequireFlag(featureFlags *flags.FeatureFlags, name string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !featureFlags.Enabled(name) {
+			return sendError(c, fiber.StatusForbidden, pkg.ErrFeatureDisabled)
+		}
+		return c.Next()
+	}
+}
+
+// The function maps an error returned by the feature flags to an HTTP status code.
+func flagErrorStatus(err error) int {
+	if errors.Is(err, pkg.ErrUnknownFlag) {
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusInternalServerError
+}
+
+// The function `listFlagsHandler` returns every feature flag with its value and where the value comes
+// from: an admin's override, the configuration or the default.
+//
+//	@Summary	List the feature flags
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{array}		flags.State
+//	@Failure	403	{object}	errorResponse
+//	@Router		/auth/flags [get]
+func listFlagsHandler(featureFlags *flags.FeatureFlags) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(featureFlags.States())
+	}
+}
+
+// The function `setFlagHandler` overrides a feature flag on every instance, whatever the configuration
+// says. The other instances pick it up within `FEATURE_FLAGS_REFRESH`.
+//
+//	@Summary	Override a feature flag
+//	@Tags		admin
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		name	path		string		true	"Flag name"
+//	@Param		body	body		setFlagBody	true	"New value"
+//	@Success	200		{object}	flags.State
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	403		{object}	errorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Router		/auth/flags/{name} [put]
+func setFlagHandler(featureFlags *flags.FeatureFlags, auditLog *audit.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body setFlagBody
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		if ok, err := validateRequest(c, body); !ok {
+			return err
+		}
+		actorID, _ := userIDFromToken(c)
+		state, err := featureFlags.Set(c.Params("name"), *body.Enabled, actorID)
+		if err != nil {
+			return sendError(c, flagErrorStatus(err), err)
+		}
+		recordAudit(c, auditLog, audit.Event{Type: audit.EventFlagChanged, ActorID: actorID, Metadata: map[string]string{"flag": state.Name, "enabled": strconv.FormatBool(state.Enabled)}})
+		return c.Status(fiber.StatusOK).JSON(state)
+	}
+}
+
+// The function `clearFlagHandler` removes the override of a feature flag, so it goes back to the value
+// from the configuration.
+//
+//	@Summary	Remove the override of a feature flag
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		name	path		string	true	"Flag name"
+//	@Success	200		{object}	flags.State
+//	@Failure	403		{object}	errorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Router		/auth/flags/{name} [delete]
+func clearFlagHandler(featureFlags *flags.FeatureFlags, auditLog *audit.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		state, err := featureFlags.Clear(c.Params("name"))
+		if err != nil {
+			return sendError(c, flagErrorStatus(err), err)
+		}
+		actorID, _ := userIDFromToken(c)
+		recordAudit(c, auditLog, audit.Event{Type: audit.EventFlagChanged, ActorID: actorID, Metadata: map[string]string{"flag": state.Name, "enabled": strconv.FormatBool(state.Enabled), "source": state.Source}})
+		return c.Status(fiber.StatusOK).JSON(state)
+	}
+}
+
+// The function creates the admin-only routes for reading and overriding the feature flags. Changes are
+// recorded in the audit log.
+func CreateFlagRoutes(router fiber.Router, userRepo *auth.Repo, featureFlags *flags.FeatureFlags, auditLog *audit.Repo) {
+	router.Get(flagsPath, RequireRole(userRepo, "admin"), listFlagsHandler(featureFlags))
+	router.Put(flagsPath+"/:name", RequireRole(userRepo, "admin"), setFlagHandler(featureFlags, auditLog))
+	router.Delete(flagsPath+"/:name", RequireRole(userRepo, "admin"), clearFlagHandler(featureFlags, auditLog))
+}
@@ -0,0 +1,20 @@
+package routes
+
+import "github.com/gofiber/fiber/v2"
+
+// The function returns a middleware that redirects plain HTTP requests to HTTPS when the server runs
+// behind a TLS terminating proxy. The proxy reports the original scheme in `X-Forwarded-Proto`; when it
+// says "http" the client is sent a 308 Permanent Redirect to the same URL over `https://`, which keeps
+// the method and body of the request. Requests without the header, such as local development hitting
+// the server directly, pass through untouched, and the header is only believed when it comes from one
+// of the `TRUSTED_PROXIES`.
+func HTTPSRedirect() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !c.IsProxyTrusted() || c.Get(fiber.HeaderXForwardedProto) != "http" {
+			return c.Next()
+		}
+		// The request target can also be an absolute URL, so the path and query are taken from the
+		// parsed URI rather than from the raw request line.
+		return c.Redirect("https://"+c.Hostname()+string(c.Request().URI().RequestURI()), fiber.StatusPermanentRedirect)
+	}
+}
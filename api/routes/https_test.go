@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		proto          string
+		absolute       bool
+		status         int
+		location       string
+	}{
+		{name: "plain HTTP through the proxy", trustedProxies: []string{"0.0.0.0"}, proto: "http", status: fiber.StatusPermanentRedirect, location: "https://example.com/api/all/allquestions?limit=5"},
+		{name: "absolute request target", trustedProxies: []string{"0.0.0.0"}, proto: "http", absolute: true, status: fiber.StatusPermanentRedirect, location: "https://example.com/api/all/allquestions?limit=5"},
+		{name: "HTTPS through the proxy", trustedProxies: []string{"0.0.0.0"}, proto: "https", status: fiber.StatusOK},
+		{name: "no header", trustedProxies: []string{"0.0.0.0"}, status: fiber.StatusOK},
+		{name: "header from an untrusted client", proto: "http", status: fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{EnableTrustedProxyCheck: true, TrustedProxies: tt.trustedProxies})
+			app.Use(HTTPSRedirect())
+			app.Post("/api/all/allquestions", func(c *fiber.Ctx) error {
+				return c.SendString("ok")
+			})
+			var headers []string
+			if tt.proto != "" {
+				headers = []string{fiber.HeaderXForwardedProto, tt.proto}
+			}
+			// httptest requests are for example.com whether or not the target names the host.
+			target := "/api/all/allquestions?limit=5"
+			if tt.absolute {
+				target = "http://example.com" + target
+			}
+			res, _ := send(t, app, fiber.MethodPost, target, "", headers...)
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.status)
+			}
+			if location := res.Header.Get(fiber.HeaderLocation); location != tt.location {
+				t.Errorf("Location = %q, want %q", location, tt.location)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package routes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/idempotency"
+	"sigmacoder/pkg/logging"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// `IdempotencyKeyHeader` is the header clients send a key in to make retrying a request safe. Requests
+// with the same key get the same response, and only the first one is processed.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// `idempotentReplayHeader` is set on the responses to a retried request.
+const idempotentReplayHeader = "Idempotent-Replayed"
+
+// `maxIdempotencyKeyLength` is the length of the longest idempotency key accepted.
+const maxIdempotencyKeyLength = 255
+
+// The function returns the request's idempotency key, or an empty string when it has none. Keys must be
+// at most `maxIdempotencyKeyLength` characters of printable ASCII without spaces, such as a UUID.
+func idempotencyKey(c *fiber.Ctx) (string, error) {
+	key := c.Get(IdempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		return "", pkg.ErrInvalidIdemKey
+	}
+	for _, r := range key {
+		if r <= ' ' || r > '~' {
+			return "", pkg.ErrInvalidIdemKey
+		}
+	}
+	return key, nil
+}
+
+// The function returns a digest of the fields that identify a request, so a retry can be told apart
+// from a different request sent with the same key without storing the fields themselves.
+func requestFingerprint(fields ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// The function returns the record stored for `key` in `scope`, and whether there is one. A key used
+// for a request with a different fingerprint yields `pkg.ErrIdemKeyReused`.
+func findIdempotent(c *fiber.Ctx, store *idempotency.Store, scope string, key string, fingerprint string) (idempotency.Record, bool, error) {
+	record, found, err := store.WithContext(c.UserContext()).Find(scope, key, pkg.NowUTC())
+	if err != nil || !found {
+		return record, false, err
+	}
+	if record.Fingerprint != fingerprint {
+		return record, true, pkg.ErrIdemKeyReused
+	}
+	return record, true, nil
+}
+
+// The function maps an error returned by `findIdempotent` to an HTTP status code.
+func idempotencyErrorStatus(err error) int {
+	if errors.Is(err, pkg.ErrIdemKeyReused) {
+		return fiber.StatusUnprocessableEntity
+	}
+	return fiber.StatusInternalServerError
+}
+
+// The function stores the outcome of the request sent with `key` in `scope`, to answer its retries
+// with. The response has been built already, so a failure is only logged; a retry is then
+// processed again as if it had no key.
+func saveIdempotent(c *fiber.Ctx, store *idempotency.Store, scope string, key string, record idempotency.Record) {
+	if err := store.WithContext(c.UserContext()).Save(scope, key, record, pkg.NowUTC()); err != nil {
+		logging.Warnf("idempotency: could not store the response for a %s request: %v", scope, err)
+	}
+}
@@ -0,0 +1,165 @@
+package routes
+
+import (
+	"encoding/json"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/idempotency"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns an app serving POST /auth/register with idempotency keys, whose repositories
+// talk to the mocked deployment, and the verifier its captchas are checked with.
+func idempotentSignUpApp(mt *mtest.T) (*fiber.App, *fixedVerifier) {
+	repo := auth.NewRepo(mt.DB).(*auth.Repo)
+	verifier := &fixedVerifier{}
+	app := fiber.New()
+	app.Post("/auth/register", SignUpHandler(repo, auth.NewAuthService(repo), verifier, idempotency.NewStore(mt.DB, time.Hour)))
+	return app, verifier
+}
+
+// The function returns the user stored for Ada, whose password is `password`.
+func storedAda(t *testing.T, password string) auth.User {
+	t.Helper()
+	in := auth.InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", Password: password}
+	ada, err := in.ToUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ada.ID = "user-1"
+	return ada
+}
+
+func TestIdempotentSignUp(t *testing.T) {
+	const signUp = `{"name": "Ada Lovelace", "email": " ada@example.com ", "username": "ada", "password": "correct horse", "captchaToken": "solved"}`
+	ada := storedAda(t, "correct horse")
+	scope, fingerprint := signUpIdempotency(auth.InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", Password: "correct horse"})
+	// `original` is the record stored by the first attempt of the sign up above.
+	original := idempotency.Record{
+		ID:          scope + "|key-1",
+		UserID:      ada.ID,
+		Fingerprint: fingerprint,
+		Status:      fiber.StatusOK,
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	dbtest.Run(t, "new key creates a user", func(mt *mtest.T) {
+		app, verifier := idempotentSignUpApp(mt)
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt), dbtest.Cursor(mt), dbtest.Written(1), dbtest.Cursor(mt, ada), dbtest.Written(1))
+		res, body := send(mt.T, app, fiber.MethodPost, "/auth/register", signUp, IdempotencyKeyHeader, "key-2")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var response tokenResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			mt.Fatal(err)
+		}
+		if response.Token == "" || res.Header.Get(idempotentReplayHeader) != "" {
+			mt.Errorf("body = %s, want a new token that isn't replayed", body)
+		}
+		if len(verifier.tokens) != 1 {
+			mt.Errorf("verified %v, want the captcha checked once", verifier.tokens)
+		}
+		if filter := dbtest.NextCommand(mt, "find")["filter"].(bson.M); filter["_id"] != scope+"|key-2" {
+			mt.Errorf("looked up %v, want the key in the scope of the email", filter)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "insert"), "insert", "users")
+		dbtest.NextCommand(mt, "find")
+		saved := dbtest.NextCommand(mt, "insert")
+		dbtest.ExpectField(mt, saved, "insert", "idempotency_keys")
+		record := saved["documents"].(bson.A)[0].(bson.M)
+		if record["_id"] != scope+"|key-2" || record["userid"] != ada.ID || record["fingerprint"] != fingerprint {
+			mt.Errorf("stored %v, want the account of the sign up under the key", record)
+		}
+		if _, ok := record["body"].(primitive.Binary); ok {
+			mt.Errorf("stored %v, want the token left out", record)
+		}
+	})
+
+	dbtest.Run(t, "duplicate key issues a fresh token", func(mt *mtest.T) {
+		app, verifier := idempotentSignUpApp(mt)
+		mt.AddMockResponses(dbtest.Cursor(mt, original), dbtest.Cursor(mt, ada))
+		res, body := send(mt.T, app, fiber.MethodPost, "/auth/register", signUp, IdempotencyKeyHeader, "key-1")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var response tokenResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			mt.Fatal(err)
+		}
+		if response.Token == "" || res.Header.Get(idempotentReplayHeader) != "true" {
+			mt.Errorf("body = %s, want a token issued for the retry", body)
+		}
+		if len(verifier.tokens) != 0 {
+			mt.Errorf("verified %v, want the captcha of a retry left alone", verifier.tokens)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": ada.ID})
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "password no longer matches the account", func(mt *mtest.T) {
+		app, _ := idempotentSignUpApp(mt)
+		mt.AddMockResponses(dbtest.Cursor(mt, original), dbtest.Cursor(mt, storedAda(mt.T, "changed since")))
+		res, body := send(mt.T, app, fiber.MethodPost, "/auth/register", signUp, IdempotencyKeyHeader, "key-1")
+		if res.StatusCode != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "INVALID_CREDENTIALS")
+	})
+
+	dbtest.Run(t, "retry racing the first attempt", func(mt *mtest.T) {
+		app, _ := idempotentSignUpApp(mt)
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt, ada), dbtest.Cursor(mt, original), dbtest.Cursor(mt, ada))
+		res, body := send(mt.T, app, fiber.MethodPost, "/auth/register", signUp, IdempotencyKeyHeader, "key-1")
+		if res.StatusCode != fiber.StatusOK || res.Header.Get(idempotentReplayHeader) != "true" {
+			mt.Errorf("status = %d, body = %s, want a token for the account once it exists", res.StatusCode, body)
+		}
+	})
+
+	dbtest.Run(t, "key reused for another password", func(mt *mtest.T) {
+		app, _ := idempotentSignUpApp(mt)
+		mt.AddMockResponses(dbtest.Cursor(mt, original))
+		other := strings.Replace(signUp, "correct horse", "battery staple", 1)
+		res, body := send(mt.T, app, fiber.MethodPost, "/auth/register", other, IdempotencyKeyHeader, "key-1")
+		if res.StatusCode != fiber.StatusUnprocessableEntity {
+			mt.Fatalf("status = %d, want 422: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "IDEMPOTENCY_KEY_REUSED")
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "same key for another email", func(mt *mtest.T) {
+		app, _ := idempotentSignUpApp(mt)
+		grace := auth.User{ID: "user-2", Email: "grace@example.com"}
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt), dbtest.Cursor(mt), dbtest.Written(1), dbtest.Cursor(mt, grace), dbtest.Written(1))
+		other := strings.Replace(signUp, "ada@example.com", "grace@example.com", 1)
+		res, body := send(mt.T, app, fiber.MethodPost, "/auth/register", other, IdempotencyKeyHeader, "key-1")
+		if res.StatusCode != fiber.StatusOK || res.Header.Get(idempotentReplayHeader) != "" {
+			mt.Fatalf("status = %d, want the sign up processed: %s", res.StatusCode, body)
+		}
+		if filter := dbtest.NextCommand(mt, "find")["filter"].(bson.M); filter["_id"] == original.ID {
+			mt.Errorf("looked up %v, want the key in the scope of the other email", filter)
+		}
+	})
+
+	dbtest.Run(t, "invalid key", func(mt *mtest.T) {
+		app, _ := idempotentSignUpApp(mt)
+		res, body := send(mt.T, app, fiber.MethodPost, "/auth/register", signUp, IdempotencyKeyHeader, "key with spaces")
+		if res.StatusCode != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "INVALID_IDEMPOTENCY_KEY")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"sigmacoder/pkg/token"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function `jwksHandler` publishes the public keys the API's tokens can be verified with, so other
+// services can check them without being able to issue any. The set is empty when tokens are signed
+// with HS256.
+func jwksHandler(keys *token.Keys) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(keys.JWKS())
+	}
+}
+
+// The function creates the JWKS route. It is served at the root of the app rather than under
+// `APIPrefix`, where clients look for it.
+func CreateJWKSRoutes(app fiber.Router, keys *token.Keys) {
+	app.Get("/.well-known/jwks.json", jwksHandler(keys))
+}
@@ -0,0 +1,110 @@
+package routes
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"sigmacoder/pkg/token"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// The function returns RS256 keys with a new private key.
+func rs256Keys(t *testing.T) *token.Keys {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := token.NewRS256(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// The function fetches the JWKS served for `keys`.
+func fetchJWKS(t *testing.T, keys *token.Keys) token.JWKSet {
+	t.Helper()
+	app := fiber.New()
+	CreateJWKSRoutes(app, keys)
+	res, body := send(t, app, fiber.MethodGet, "/.well-known/jwks.json", "")
+	if res.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+	}
+	var set token.JWKSet
+	if err := json.Unmarshal([]byte(body), &set); err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+// The function rebuilds the RSA public key a JWK describes, the way a third party verifying our tokens
+// would.
+func publicKeyOf(t *testing.T, key token.JWK) *rsa.PublicKey {
+	t.Helper()
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+}
+
+func TestJWKS(t *testing.T) {
+	t.Run("minted token verifies with the served key", func(t *testing.T) {
+		keys := rs256Keys(t)
+		raw, err := keys.Sign(jwt.MapClaims{"userid": "user-1", "exp": time.Now().Add(time.Hour).Unix()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		set := fetchJWKS(t, keys)
+		// A third party picks the key by the token's kid and checks the signature with it alone.
+		parsed, err := jwt.Parse(raw, func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			for _, key := range set.Keys {
+				if key.Kid == kid && key.Kty == "RSA" && key.Alg == "RS256" && key.Use == "sig" {
+					return publicKeyOf(t, key), nil
+				}
+			}
+			t.Fatalf("kid %q is not in the JWKS %+v", kid, set)
+			return nil, nil
+		}, jwt.WithValidMethods([]string{"RS256"}))
+		if err != nil || !parsed.Valid {
+			t.Errorf("verifying with the served key = %v, want the token accepted", err)
+		}
+	})
+
+	t.Run("rotated key", func(t *testing.T) {
+		old := rs256Keys(t)
+		current := rs256Keys(t)
+		// The previous key is only needed as a public key.
+		der, err := x509.MarshalPKIXPublicKey(publicKeyOf(t, fetchJWKS(t, old).Keys[0]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := current.AddPrevious(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})); err != nil {
+			t.Fatal(err)
+		}
+		set := fetchJWKS(t, current)
+		if len(set.Keys) != 2 || set.Keys[1].Kid != fetchJWKS(t, old).Keys[0].Kid {
+			t.Errorf("keys = %+v, want the current key and the previous one", set.Keys)
+		}
+	})
+
+	t.Run("HS256", func(t *testing.T) {
+		if set := fetchJWKS(t, token.NewHS256("secret")); len(set.Keys) != 0 {
+			t.Errorf("keys = %+v, want none", set.Keys)
+		}
+	})
+}
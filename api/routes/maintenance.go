@@ -0,0 +1,187 @@
+package routes
+
+import (
+	"fmt"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/flags"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The maintenance modes the API can be in.
+const (
+	MaintenanceOff      = "off"
+	MaintenanceReadOnly = "read-only"
+	MaintenanceFull     = "full"
+)
+
+// `maintenancePath` is the admin route that reads and switches the maintenance mode, relative to
+// `APIPrefix`. It stays reachable in every mode, otherwise maintenance could never be turned off again.
+const maintenancePath = "/auth/maintenance"
+
+// The Maintenance type switches the API in and out of maintenance. The mode is held by the
+// `flags.ReadOnly` and `flags.Maintenance` feature flags, so it starts in the mode from
+// `MAINTENANCE_MODE` and a switch made by an admin reaches every instance within
+// `FEATURE_FLAGS_REFRESH`, without a redeploy.
+// @property featureFlags - The feature flags holding the mode.
+// @property {time.Duration} retryAfter - The delay suggested to blocked clients in `Retry-After`.
+type Maintenance struct {
+	featureFlags *flags.FeatureFlags
+	retryAfter   time.Duration
+}
+
+// The type `maintenanceBody` is the request and response body of the maintenance routes.
+type maintenanceBody struct {
+	Mode string `json:"mode"`
+}
+
+// The function reports whether the mode is one of the known maintenance modes.
+func validMaintenanceMode(mode string) bool {
+	switch mode {
+	case MaintenanceOff, MaintenanceReadOnly, MaintenanceFull:
+		return true
+	}
+	return false
+}
+
+// The function returns the error for a maintenance mode that isn't one of the known modes.
+func unknownMaintenanceMode(mode string) error {
+	return fmt.Errorf("unknown maintenance mode %q, expected off, read-only or full", mode)
+}
+
+// The function returns the values of the maintenance flags in the given mode, to be passed to
+// `flags.New` as their configuration. An empty mode is off.
+func MaintenanceFlags(mode string) (map[string]bool, error) {
+	if mode == "" {
+		mode = MaintenanceOff
+	}
+	if !validMaintenanceMode(mode) {
+		return nil, unknownMaintenanceMode(mode)
+	}
+	return map[string]bool{flags.ReadOnly: mode == MaintenanceReadOnly, flags.Maintenance: mode == MaintenanceFull}, nil
+}
+
+// The function returns the current maintenance mode. Full maintenance wins when both flags are on.
+func (m *Maintenance) Mode() string {
+	switch {
+	case m.featureFlags.Maintenance():
+		return MaintenanceFull
+	case m.featureFlags.ReadOnly():
+		return MaintenanceReadOnly
+	}
+	return MaintenanceOff
+}
+
+// The function switches to the given maintenance mode by overriding the maintenance flags on behalf of
+// the admin with the given ID. The flag being turned on is overridden first, so the API is never
+// briefly out of maintenance while switching between read-only and full.
+func (m *Maintenance) Set(mode, actorID string) error {
+	values, err := MaintenanceFlags(mode)
+	if err != nil {
+		return err
+	}
+	names := []string{flags.ReadOnly, flags.Maintenance}
+	if values[flags.Maintenance] {
+		names[0], names[1] = names[1], names[0]
+	}
+	for _, name := range names {
+		if _, err := m.featureFlags.Set(name, values[name], actorID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The function returns a middleware that answers with 503 Service Unavailable and a `Retry-After`
+// header while the API is in maintenance: in read-only mode only requests that change data (POST,
+// PUT, PATCH and DELETE) are refused, in full mode every request is. The health check at `/`, the login
+// route, the maintenance route and the feature flag routes are always let through, so admins can log
+// in and turn it off. It
+// must be registered after `LegacyAPIAlias`, so those routes are recognized under their legacy paths
+// as well.
+func (m *Maintenance) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Path() == "/" || c.Path() == APIPrefix+loginPath || c.Path() == APIPrefix+maintenancePath || c.Path() == APIPrefix+flagsPath || strings.HasPrefix(c.Path(), APIPrefix+flagsPath+"/") {
+			return c.Next()
+		}
+		switch m.Mode() {
+		case MaintenanceFull:
+		case MaintenanceReadOnly:
+			switch c.Method() {
+			case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+			default:
+				return c.Next()
+			}
+		default:
+			return c.Next()
+		}
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(m.retryAfter.Seconds())))
+		return sendError(c, fiber.StatusServiceUnavailable, pkg.ErrMaintenance)
+	}
+}
+
+// The function `getMaintenanceHandler` returns the current maintenance mode.
+//
+//	@Summary	Get the maintenance mode
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{object}	maintenanceBody
+//	@Failure	403	{object}	errorResponse
+//	@Router		/auth/maintenance [get]
+func getMaintenanceHandler(m *Maintenance) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(maintenanceBody{Mode: m.Mode()})
+	}
+}
+
+// The function `setMaintenanceHandler` switches the maintenance mode on every instance. The other
+// instances pick it up within `FEATURE_FLAGS_REFRESH`.
+//
+//	@Summary	Switch the maintenance mode
+//	@Tags		admin
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		body	body		maintenanceBody	true	"off, read-only or full"
+//	@Success	200		{object}	maintenanceBody
+//	@Failure	400		{object}	errorResponse
+//	@Failure	403		{object}	errorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Router		/auth/maintenance [put]
+func setMaintenanceHandler(m *Maintenance, auditLog *audit.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body maintenanceBody
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		if !validMaintenanceMode(body.Mode) {
+			return sendError(c, fiber.StatusBadRequest, unknownMaintenanceMode(body.Mode))
+		}
+		previous := m.Mode()
+		actorID, _ := userIDFromToken(c)
+		if err := m.Set(body.Mode, actorID); err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		recordAudit(c, auditLog, audit.Event{Type: audit.EventMaintenanceChanged, ActorID: actorID, Metadata: map[string]string{"from": previous, "to": body.Mode}})
+		return c.Status(fiber.StatusOK).JSON(maintenanceBody{Mode: m.Mode()})
+	}
+}
+
+// The function creates the admin-only routes for reading and switching the maintenance mode. Switches
+// are recorded in the audit log.
+func CreateMaintenanceRoutes(router fiber.Router, userRepo *auth.Repo, m *Maintenance, auditLog *audit.Repo) {
+	router.Get(maintenancePath, RequireRole(userRepo, "admin"), getMaintenanceHandler(m))
+	router.Put(maintenancePath, RequireRole(userRepo, "admin"), setMaintenanceHandler(m, auditLog))
+}
+
+// The function creates the maintenance switch over the given feature flags. `retryAfter` is the delay
+// suggested to the clients that are turned away.
+func NewMaintenance(featureFlags *flags.FeatureFlags, retryAfter time.Duration) *Maintenance {
+	return &Maintenance{featureFlags: featureFlags, retryAfter: retryAfter}
+}
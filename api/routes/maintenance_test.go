@@ -0,0 +1,110 @@
+package routes
+
+import (
+	"sigmacoder/pkg/flags"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function returns the maintenance switch over feature flags configured in the given mode, without
+// overrides.
+func newMaintenance(t *testing.T, mode string) (*Maintenance, *flags.FeatureFlags) {
+	t.Helper()
+	config, err := MaintenanceFlags(mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	featureFlags := flags.New(config, nil, time.Minute)
+	return NewMaintenance(featureFlags, 30*time.Second), featureFlags
+}
+
+func TestMaintenance(t *testing.T) {
+	tests := []struct {
+		mode   string
+		method string
+		path   string
+		status int
+	}{
+		{mode: MaintenanceOff, method: fiber.MethodGet, path: APIPrefix + "/all/allquestions", status: fiber.StatusOK},
+		{mode: MaintenanceOff, method: fiber.MethodPost, path: APIPrefix + "/auth/signup", status: fiber.StatusOK},
+		{mode: MaintenanceReadOnly, method: fiber.MethodGet, path: APIPrefix + "/all/allquestions", status: fiber.StatusOK},
+		{mode: MaintenanceReadOnly, method: fiber.MethodHead, path: APIPrefix + "/all/allquestions", status: fiber.StatusOK},
+		{mode: MaintenanceReadOnly, method: fiber.MethodPost, path: APIPrefix + "/auth/signup", status: fiber.StatusServiceUnavailable},
+		{mode: MaintenanceReadOnly, method: fiber.MethodPut, path: APIPrefix + "/all/question/1", status: fiber.StatusServiceUnavailable},
+		{mode: MaintenanceReadOnly, method: fiber.MethodPatch, path: APIPrefix + "/all/question/1", status: fiber.StatusServiceUnavailable},
+		{mode: MaintenanceReadOnly, method: fiber.MethodDelete, path: APIPrefix + "/auth/me/sessions/1", status: fiber.StatusServiceUnavailable},
+		{mode: MaintenanceFull, method: fiber.MethodGet, path: APIPrefix + "/all/allquestions", status: fiber.StatusServiceUnavailable},
+		{mode: MaintenanceFull, method: fiber.MethodPost, path: APIPrefix + "/auth/signup", status: fiber.StatusServiceUnavailable},
+		{mode: MaintenanceFull, method: fiber.MethodGet, path: "/", status: fiber.StatusOK},
+		{mode: MaintenanceReadOnly, method: fiber.MethodPost, path: APIPrefix + loginPath, status: fiber.StatusOK},
+		{mode: MaintenanceFull, method: fiber.MethodPost, path: APIPrefix + loginPath, status: fiber.StatusOK},
+		{mode: MaintenanceFull, method: fiber.MethodPut, path: APIPrefix + maintenancePath, status: fiber.StatusOK},
+		{mode: MaintenanceFull, method: fiber.MethodGet, path: APIPrefix + flagsPath, status: fiber.StatusOK},
+		{mode: MaintenanceFull, method: fiber.MethodDelete, path: APIPrefix + flagsPath + "/maintenance", status: fiber.StatusOK},
+		{mode: MaintenanceFull, method: fiber.MethodGet, path: APIPrefix + flagsPath + "-export", status: fiber.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+" "+tt.method+" "+tt.path, func(t *testing.T) {
+			maintenance, _ := newMaintenance(t, tt.mode)
+			app := fiber.New()
+			app.Use(maintenance.Middleware())
+			app.All("/*", func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+			res, body := send(t, app, tt.method, tt.path, "")
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", res.StatusCode, tt.status)
+			}
+			if tt.status != fiber.StatusServiceUnavailable {
+				return
+			}
+			if retryAfter := res.Header.Get(fiber.HeaderRetryAfter); retryAfter != "30" {
+				t.Errorf("Retry-After = %q, want 30", retryAfter)
+			}
+			expectCode(t, body, "MAINTENANCE")
+		})
+	}
+}
+
+func TestMaintenanceModes(t *testing.T) {
+	if _, err := MaintenanceFlags("sometimes"); err == nil {
+		t.Error("MaintenanceFlags() of an unknown mode = nil, want an error")
+	}
+	maintenance, featureFlags := newMaintenance(t, "")
+	if mode := maintenance.Mode(); mode != MaintenanceOff {
+		t.Errorf("Mode() = %q, want off by default", mode)
+	}
+	if err := maintenance.Set(MaintenanceReadOnly, "admin-1"); err != nil || maintenance.Mode() != MaintenanceReadOnly {
+		t.Errorf("Set(read-only) = %v, mode %q", err, maintenance.Mode())
+	}
+	if err := maintenance.Set("on", "admin-1"); err == nil || maintenance.Mode() != MaintenanceReadOnly {
+		t.Errorf("Set(on) = %v, mode %q, want an error and the mode unchanged", err, maintenance.Mode())
+	}
+	if err := maintenance.Set(MaintenanceFull, "admin-1"); err != nil || !featureFlags.Maintenance() || featureFlags.ReadOnly() {
+		t.Errorf("Set(full) = %v, want only the maintenance flag on", err)
+	}
+	if err := maintenance.Set(MaintenanceOff, "admin-1"); err != nil || featureFlags.Maintenance() || featureFlags.ReadOnly() {
+		t.Errorf("Set(off) = %v, want both flags off", err)
+	}
+}
+
+func TestMaintenanceFlags(t *testing.T) {
+	maintenance, featureFlags := newMaintenance(t, MaintenanceReadOnly)
+	if _, err := featureFlags.Set(flags.Maintenance, true, "admin-1"); err != nil {
+		t.Fatal(err)
+	}
+	if mode := maintenance.Mode(); mode != MaintenanceFull {
+		t.Errorf("Mode() = %q, want the maintenance flag override to win over the configuration", mode)
+	}
+	if _, err := featureFlags.Set(flags.Maintenance, false, "admin-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := featureFlags.Set(flags.ReadOnly, false, "admin-1"); err != nil {
+		t.Fatal(err)
+	}
+	if mode := maintenance.Mode(); mode != MaintenanceOff {
+		t.Errorf("Mode() = %q, want off once both flags are overridden", mode)
+	}
+}
@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/notes"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `noteBody` is the request body for saving a note.
+type noteBody struct {
+	Content string `json:"content"`
+}
+
+// The type `noteErrorResponse` documents the body returned by the note handlers when a request fails.
+type noteErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// The function maps an error returned by the notes service to an HTTP status code.
+func noteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pkg.ErrInvalidQuestionID), errors.Is(err, pkg.ErrNoteTooLong):
+		return fiber.StatusBadRequest
+	case errors.Is(err, pkg.ErrNoteNotFound):
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusInternalServerError
+}
+
+// The function `saveNoteHandler` creates or replaces the authenticated user's note on a question.
+//
+//	@Summary	Save your note on a question
+//	@Tags		notes
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string		true	"Question ID"
+//	@Param		body	body		noteBody	true	"Note contents"
+//	@Success	200		{object}	notes.Note
+//	@Failure	400		{object}	noteErrorResponse
+//	@Failure	401		{object}	noteErrorResponse
+//	@Router		/all/question/{id}/notes [put]
+func saveNoteHandler(svc notes.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body noteBody
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		note, err := svc.WithContext(c.UserContext()).Save(userID, c.Params("id"), body.Content)
+		if err != nil {
+			return sendError(c, noteErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusOK).JSON(note)
+	}
+}
+
+// The function `getNoteHandler` returns the authenticated user's note on a question.
+//
+//	@Summary	Get your note on a question
+//	@Tags		notes
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id	path		string	true	"Question ID"
+//	@Success	200	{object}	notes.Note
+//	@Failure	401	{object}	noteErrorResponse
+//	@Failure	404	{object}	noteErrorResponse
+//	@Router		/all/question/{id}/notes [get]
+func getNoteHandler(svc notes.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		note, err := svc.WithContext(c.UserContext()).Get(userID, c.Params("id"))
+		if err != nil {
+			return sendError(c, noteErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusOK).JSON(note)
+	}
+}
+
+// The function creates the routes for reading and saving a user's private notes on questions. Notes
+// are always scoped to the user in the request's token.
+func CreateNoteRoutes(router fiber.Router, svc notes.Service) {
+	router.Put("/all/question/:id/notes", saveNoteHandler(svc))
+	router.Get("/all/question/:id/notes", getNoteHandler(svc))
+}
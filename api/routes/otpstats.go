@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"sigmacoder/pkg/auth"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `otpCounters` counts the OTP requests of a single channel.
+// @property {int64} SendAttempted - The number of codes requested.
+// @property {int64} SendSucceeded - The number of codes the provider accepted to send.
+// @property {int64} SendFailed - The number of codes the provider failed to send.
+// @property {int64} VerifyAttempted - The number of codes submitted for verification.
+// @property {int64} VerifyApproved - The number of codes the provider approved.
+// @property {int64} VerifyRejected - The number of codes the provider rejected, for example because
+// they were wrong or had expired. Attempts that are neither approved nor rejected failed to reach the
+// provider.
+type otpCounters struct {
+	SendAttempted   int64 `json:"sendAttempted"`
+	SendSucceeded   int64 `json:"sendSucceeded"`
+	SendFailed      int64 `json:"sendFailed"`
+	VerifyAttempted int64 `json:"verifyAttempted"`
+	VerifyApproved  int64 `json:"verifyApproved"`
+	VerifyRejected  int64 `json:"verifyRejected"`
+}
+
+// The OTPStats type counts OTP sends and verifications per channel, to spot provider outages or abuse of
+// the SMS pipeline. The counters are kept in memory, so they start from zero on every restart and
+// every instance counts only its own requests.
+// @property mu - Guards `channels`.
+// @property channels - The counters, keyed by channel.
+type OTPStats struct {
+	mu       sync.Mutex
+	channels map[string]*otpCounters
+}
+
+// The function applies `update` to the counters of the channel.
+func (s *OTPStats) count(channel string, update func(*otpCounters)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counters, ok := s.channels[channel]
+	if !ok {
+		counters = &otpCounters{}
+		s.channels[channel] = counters
+	}
+	update(counters)
+}
+
+// The function returns a copy of the counters of every channel.
+func (s *OTPStats) Snapshot() map[string]otpCounters {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := make(map[string]otpCounters, len(s.channels))
+	for channel, counters := range s.channels {
+		snapshot[channel] = *counters
+	}
+	return snapshot
+}
+
+// The function creates empty OTP counters.
+func NewOTPStats() *OTPStats {
+	return &OTPStats{channels: map[string]*otpCounters{}}
+}
+
+// The function `otpStatsHandler` returns the OTP send and verification counters of this instance since
+// it started, per channel.
+//
+//	@Summary	Get OTP statistics
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{object}	map[string]otpCounters
+//	@Failure	401	{object}	errorResponse
+//	@Failure	403	{object}	errorResponse
+//	@Router		/auth/otp-stats [get]
+func otpStatsHandler(stats *OTPStats) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(stats.Snapshot())
+	}
+}
+
+// The function creates the admin-only route for reading the OTP statistics.
+func CreateOTPStatsRoutes(router fiber.Router, userRepo *auth.Repo, stats *OTPStats) {
+	router.Get("/auth/otp-stats", RequireRole(userRepo, "admin"), otpStatsHandler(stats))
+}
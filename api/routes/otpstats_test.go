@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/otp"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The type scriptedProvider is an OTP provider whose sends fail with `sendErr` and whose verifications
+// report `channel` and fail with `verifyErr`.
+type scriptedProvider struct {
+	sendErr   error
+	channel   string
+	verifyErr error
+}
+
+func (p scriptedProvider) Send(ctx context.Context, phoneNumber string, channel string, deviceIP string) error {
+	return p.sendErr
+}
+
+func (p scriptedProvider) Verify(ctx context.Context, phoneNumber string, code string) (string, error) {
+	return p.channel, p.verifyErr
+}
+
+// The function returns an app serving the OTP routes with `provider`, counting into `stats`, with the
+// users talking to the mocked deployment.
+func otpApp(mt *mtest.T, provider otp.Provider, stats *OTPStats) *fiber.App {
+	app := fiber.New()
+	svc := auth.NewAuthService(auth.NewRepo(mt.DB).(*auth.Repo))
+	app.Post("/auth/sendotp", sendSMS(provider, []string{"sms", "whatsapp"}, new(otp.DailyQuota), stats))
+	app.Post("/auth/verifyotp", verifySMS(svc, provider, stats))
+	return app
+}
+
+func TestOTPStats(t *testing.T) {
+	verifyBody := `{"user": {"phoneNumber": "+15551234567"}, "code": "123456"}`
+	tests := []struct {
+		name     string
+		provider scriptedProvider
+		path     string
+		body     string
+		users    bool
+		channel  string
+		want     otpCounters
+	}{
+		{name: "send succeeded", path: "/auth/sendotp", body: `{"phoneNumber": "+15551234567", "channel": "whatsapp"}`, channel: "whatsapp",
+			want: otpCounters{SendAttempted: 1, SendSucceeded: 1}},
+		{name: "send failed", provider: scriptedProvider{sendErr: errors.New("twilio unavailable")}, path: "/auth/sendotp", body: `{"phoneNumber": "+15551234567"}`, channel: "sms",
+			want: otpCounters{SendAttempted: 1, SendFailed: 1}},
+		{name: "verify approved", provider: scriptedProvider{channel: "sms"}, path: "/auth/verifyotp", body: verifyBody, users: true, channel: "sms",
+			want: otpCounters{VerifyAttempted: 1, VerifyApproved: 1}},
+		{name: "verify rejected", provider: scriptedProvider{channel: "whatsapp", verifyErr: pkg.ErrOTPNotApproved}, path: "/auth/verifyotp", body: verifyBody, channel: "whatsapp",
+			want: otpCounters{VerifyAttempted: 1, VerifyRejected: 1}},
+		{name: "verify failed to reach the provider", provider: scriptedProvider{channel: otp.UnknownChannel, verifyErr: errors.New("twilio unavailable")}, path: "/auth/verifyotp", body: verifyBody, channel: otp.UnknownChannel,
+			want: otpCounters{VerifyAttempted: 1}},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			if tt.users {
+				mt.AddMockResponses(dbtest.Cursor(mt, auth.User{ID: "user-1", PhoneNumber: "+15551234567"}))
+			}
+			stats := NewOTPStats()
+			send(mt.T, otpApp(mt, tt.provider, stats), fiber.MethodPost, tt.path, tt.body)
+			snapshot := stats.Snapshot()
+			if len(snapshot) != 1 || snapshot[tt.channel] != tt.want {
+				mt.Errorf("counters = %+v, want %+v for %s only", snapshot, tt.want, tt.channel)
+			}
+		})
+	}
+
+	dbtest.Run(t, "requests refused before the provider aren't counted", func(mt *mtest.T) {
+		stats := NewOTPStats()
+		app := otpApp(mt, scriptedProvider{}, stats)
+		send(mt.T, app, fiber.MethodPost, "/auth/sendotp", `{"phoneNumber": "+15551234567", "channel": "pigeon"}`)
+		send(mt.T, app, fiber.MethodPost, "/auth/verifyotp", `{"code": "123456"}`)
+		if snapshot := stats.Snapshot(); len(snapshot) != 0 {
+			mt.Errorf("counters = %+v, want none", snapshot)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// `pageSizes` holds the page size used when a list request doesn't ask for a `limit`, and the largest
+// `limit` any list request is allowed. They are set from `DEFAULT_PAGE_SIZE` and `MAX_PAGE_SIZE` at
+// startup through `SetPageSizes`.
+var pageSizes = struct {
+	Default int
+	Max     int
+}{Default: 20, Max: 100}
+
+// The type `paging` is the effective page requested by a list request.
+// @property Limit - The number of items per page, after the default and the maximum were applied.
+// @property Offset - The number of items to skip, for the endpoints that page by offset.
+type paging struct {
+	Limit  int
+	Offset int
+}
+
+// The function sets the default and maximum page sizes of the list endpoints. A maximum below the
+// default is raised to the default.
+func SetPageSizes(defaultSize int, maxSize int) {
+	if defaultSize <= 0 {
+		defaultSize = 20
+	}
+	if maxSize < defaultSize {
+		maxSize = defaultSize
+	}
+	pageSizes.Default = defaultSize
+	pageSizes.Max = maxSize
+}
+
+// The function clamps a requested limit to the maximum page size, using `fallback` when the limit is
+// absent or not positive.
+func clampLimit(limit int, fallback int) int {
+	if limit <= 0 {
+		limit = fallback
+	}
+	if limit > pageSizes.Max {
+		limit = pageSizes.Max
+	}
+	return limit
+}
+
+// The function reads the `limit` and `offset` query parameters shared by the list endpoints. A
+// missing limit falls back to the default page size and a larger one than allowed is clamped to the
+// maximum rather than rejected, so responses report the effective limit back to the client.
+func parsePaging(c *fiber.Ctx) (paging, error) {
+	page := paging{
+		Limit:  clampLimit(c.QueryInt("limit", pageSizes.Default), pageSizes.Default),
+		Offset: c.QueryInt("offset", 0),
+	}
+	if page.Offset < 0 {
+		return page, errors.New("offset must not be negative")
+	}
+	return page, nil
+}
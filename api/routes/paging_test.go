@@ -0,0 +1,98 @@
+package routes
+
+import (
+	"encoding/json"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function sets the page sizes for the rest of the test.
+func usePageSizes(t *testing.T, defaultSize int, maxSize int) {
+	t.Helper()
+	previous := pageSizes
+	t.Cleanup(func() { pageSizes = previous })
+	SetPageSizes(defaultSize, maxSize)
+}
+
+func TestSetPageSizes(t *testing.T) {
+	tests := []struct {
+		defaultSize, maxSize int
+		wantDefault, wantMax int
+	}{
+		{defaultSize: 10, maxSize: 50, wantDefault: 10, wantMax: 50},
+		{defaultSize: 0, maxSize: 50, wantDefault: 20, wantMax: 50},
+		{defaultSize: 30, maxSize: 10, wantDefault: 30, wantMax: 30},
+	}
+	for _, tt := range tests {
+		usePageSizes(t, tt.defaultSize, tt.maxSize)
+		if pageSizes.Default != tt.wantDefault || pageSizes.Max != tt.wantMax {
+			t.Errorf("SetPageSizes(%d, %d) = %+v, want default %d and max %d", tt.defaultSize, tt.maxSize, pageSizes, tt.wantDefault, tt.wantMax)
+		}
+	}
+}
+
+func TestParsePaging(t *testing.T) {
+	usePageSizes(t, 20, 100)
+	tests := []struct {
+		query   string
+		want    paging
+		wantErr bool
+	}{
+		{query: "", want: paging{Limit: 20}},
+		{query: "?limit=5&offset=40", want: paging{Limit: 5, Offset: 40}},
+		{query: "?limit=1000000", want: paging{Limit: 100}},
+		{query: "?limit=0", want: paging{Limit: 20}},
+		{query: "?limit=-3", want: paging{Limit: 20}},
+		{query: "?limit=lots", want: paging{Limit: 20}},
+		{query: "?offset=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				page, err := parsePaging(c)
+				if err != nil {
+					return sendError(c, fiber.StatusBadRequest, err)
+				}
+				return c.JSON(page)
+			})
+			res, body := send(t, app, fiber.MethodGet, "/"+tt.query, "")
+			if tt.wantErr {
+				if res.StatusCode != fiber.StatusBadRequest {
+					t.Errorf("status = %d, want 400", res.StatusCode)
+				}
+				return
+			}
+			var got paging
+			if err := json.Unmarshal([]byte(body), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("parsePaging() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEffectiveLimitInResponse(t *testing.T) {
+	usePageSizes(t, 20, 100)
+	dbtest.Run(t, "clamped", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Get("/all/allquestions", allquestionsHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Cursor(mt))
+
+		_, body := send(mt.T, app, fiber.MethodGet, "/all/allquestions?after=0&limit=1000000", "")
+		var page questionPage
+		if err := json.Unmarshal([]byte(body), &page); err != nil {
+			mt.Fatal(err)
+		}
+		if page.Limit != 100 {
+			mt.Errorf("limit = %d, want the maximum of 100", page.Limit)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "limit", int64(100))
+	})
+}
@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `changePasswordBody` is the request body for changing a password.
+type changePasswordBody struct {
+	CurrentPassword string `json:"currentPassword" validate:"max=256"`
+	NewPassword     string `json:"newPassword" validate:"max=256"`
+}
+
+// The function `changePasswordHandler` replaces the authenticated user's password. Unless
+// `PASSWORD_CHANGE_REVOKES_SESSIONS` is off, every existing token of the user stops working; the
+// response carries a fresh token so the client that made the change stays logged in.
+//
+//	@Summary	Change your password
+//	@Tags		account
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		body	body		changePasswordBody	true	"Current and new password"
+//	@Success	200		{object}	tokenResponse
+//	@Failure	400		{object}	errorResponse
+//	@Failure	401		{object}	errorResponse
+//	@Router		/auth/me/password [post]
+func changePasswordHandler(svc auth.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body changePasswordBody
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		if ok, err := validateRequest(c, body); !ok {
+			return err
+		}
+		token, err := svc.WithContext(c.UserContext()).ChangePassword(userID, body.CurrentPassword, body.NewPassword, deviceFromRequest(c))
+		switch {
+		case errors.Is(err, pkg.ErrInvalidPassword):
+			return sendError(c, fiber.StatusBadRequest, err)
+		case errors.Is(err, pkg.ErrInvalidCredentials), errors.Is(err, pkg.ErrUserNotFound):
+			return sendError(c, fiber.StatusUnauthorized, err)
+		case err != nil:
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(tokenResponse{Token: token, Status: "success"})
+	}
+}
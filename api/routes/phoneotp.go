@@ -3,35 +3,36 @@ package routes
 // The `import` statement is importing various packages that are needed for the implementation of the
 // phone OTP routes in a Fiber app. These packages include:
 import (
-	"context"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
-	"os"
+	"sigmacoder/pkg"
 	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/flags"
+	"sigmacoder/pkg/otp"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/joho/godotenv"
-	"github.com/twilio/twilio-go"
-	twilioApi "github.com/twilio/twilio-go/rest/verify/v2"
 )
 
-// `const appTimeout = time.Second * 10` is defining a constant variable `appTimeout` with a value of
-// 10 seconds, which is the maximum amount of time allowed for a request to be processed before timing
-// out. This is used in the `context.WithTimeout` function to set a timeout for the request context.
-const appTimeout = time.Second * 10
-
 // The OTPData type represents data for a phone number used in one-time password authentication, with
 // the phone number being a required field.
 // @property {string} PhoneNumber - PhoneNumber is a property of the OTPData struct that represents the
 // phone number of the user for whom the OTP (One-Time Password) is being generated. It is of type
 // string and has a JSON tag "phoneNumber" which is used for marshaling and unmarshaling JSON data. The
 // "omitempty"
+// @property {string} Channel - The channel the code is delivered over, such as "sms", "call" or
+// "whatsapp". It defaults to "sms" and must be one of the configured `OTP_CHANNELS`.
 type OTPData struct {
 	PhoneNumber string `json:"phoneNumber,omitempty" validate:"required"`
+	Channel     string `json:"channel,omitempty"`
 }
 
+// `defaultOTPChannel` is the channel used when a send request doesn't name one.
+const defaultOTPChannel = "sms"
+
 // The VerifyData type contains a user's OTPData and a code to be validated, both of which are
 // required.
 // @property User - User is a pointer to an OTPData struct. It is marked as omitempty, which means that
@@ -45,7 +46,7 @@ type VerifyData struct {
 	Code string   `json:"code,omitempty" validate:"required"`
 }
 
-// The type `jsonResponse` represents a JSON response with a status code, message, and data.
+// The type `jsonResponse` represents a successful JSON response with a status code, message, and data.
 // @property {int} Status - Status is an integer property that represents the status of the response.
 // It is typically used to indicate whether the request was successful or not. For example, a status
 // code of 200 typically indicates success, while a status code of 404 indicates that the requested
@@ -62,169 +63,157 @@ type jsonResponse struct {
 	Data    any    `json:"data"`
 }
 
-// `var validate = validator.New()` is creating a new instance of the `validator` struct from the
+// `var validate = newValidator()` is creating a new instance of the `validator` struct from the
 // `github.com/go-playground/validator/v10` package. This instance is used to validate the request body
-// data in the `validateBody` function.
-var validate = validator.New()
-
-// The function validates the request body using a given struct and returns an error if validation
-// fails.
-func validateBody(c *fiber.Ctx, data interface{}) error {
-	if err := c.BodyParser(&data); err != nil {
-		return err
-	}
-	if err := validate.Struct(data); err != nil {
-		return err
-	}
-	return nil
-}
-
-// The function writes a JSON response with a success message and data to a Fiber context.
-func writeJSON(c *fiber.Ctx, status int, data interface{}) {
-	c.JSON(jsonResponse{Status: status, Message: "success", Data: data})
-}
+// data in the `validateRequest` function.
+var validate = newValidator()
 
-// The function returns a JSON response with an error message and status code.
-func errorJSON(c *fiber.Ctx, err error, status ...int) {
-	statusCode := fiber.StatusBadRequest
-	if len(status) > 0 {
-		statusCode = status[0]
-	}
-	c.Status(statusCode).JSON(jsonResponse{Status: statusCode, Message: err.Error()})
+// The function writes a JSON response with a success message and data to a Fiber context, with the
+// given status as both the HTTP status and the `status` field.
+func writeJSON(c *fiber.Ctx, status int, data interface{}) error {
+	return c.Status(status).JSON(jsonResponse{Status: status, Message: "success", Data: data})
 }
 
-// The function loads the .env file and returns the value of the TWILIO_ACCOUNT_SID environment
-// variable.
-func envACCOUNTSID() string {
-	println(godotenv.Unmarshal(".env"))
-	err := godotenv.Load(".env")
-	if err != nil {
-		log.Fatalln(err)
-		log.Fatal("Error loading .env file")
-	}
-	return os.Getenv("TWILIO_ACCOUNT_SID")
-}
-
-// This function loads the .env file and returns the value of the TWILIO_AUTHTOKEN environment
-// variable.
-func envAUTHTOKEN() string {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-	return os.Getenv("TWILIO_AUTHTOKEN")
-}
-
-// This function loads the environment variables from a .env file and returns the value of the
-// TWILIO_SERVICES_ID variable.
-func envSERVICESID() string {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-	return os.Getenv("TWILIO_SERVICES_ID")
-}
-
-// This line of code is creating a new instance of the `twilio.RestClient` struct and assigning it to
-// the `client` variable. The `twilio.NewRestClientWithParams()` function is used to create the new
-// instance, and it takes a `twilio.ClientParams` struct as an argument. The `twilio.ClientParams`
-// struct contains the `Username` and `Password` fields, which are set to the values of the
-// `envACCOUNTSID()` and `envAUTHTOKEN()` functions, respectively. These functions load the values of
-// the `TWILIO_ACCOUNT_SID` and `TWILIO_AUTHTOKEN` environment variables from a `.env` file and return
-// them. The `client` variable is then used to make requests to Twilio's API.
-var client *twilio.RestClient = twilio.NewRestClientWithParams(twilio.ClientParams{
-	Username: envACCOUNTSID(),
-	Password: envAUTHTOKEN(),
-})
-
-// The function sends an OTP (one-time password) to a phone number using Twilio's API.
-func twilioSendOTP(phoneNumber string) (string, error) {
-	params := &twilioApi.CreateVerificationParams{}
-	params.SetTo(phoneNumber)
-	params.SetChannel("sms")
-
-	resp, err := client.VerifyV2.CreateVerification(envSERVICESID(), params)
-	if err != nil {
-		return "", err
-	}
-
-	return *resp.Sid, nil
-}
-
-// The function verifies an OTP code sent to a phone number using Twilio API.
-func twilioVerifyOTP(phoneNumber string, code string) error {
-	params := &twilioApi.CreateVerificationCheckParams{}
-	params.SetTo(phoneNumber)
-	params.SetCode(code)
-
-	resp, err := client.VerifyV2.CreateVerificationCheck(envSERVICESID(), params)
-	if err != nil {
-		return err
-	} else if *resp.Status == "approved" {
-		return nil
-	}
-
-	return nil
-}
-
-// The function sends an OTP SMS message using Twilio API and returns a success message.
-func sendSMS() fiber.Handler {
+// The function sends an OTP message through the configured provider and returns a success message. The
+// phone number is normalized to E.164 and the requested channel must be one of `allowedChannels`;
+// malformed numbers and other channels are rejected with 400 before the provider is called. Every
+// phone number may only be sent as many codes per UTC day as `quota` allows; past that the request is
+// answered with 429, a `Retry-After` header and the time the quota resets.
+// Sends that reach the provider are counted in `stats` per channel. Failures are answered with the
+// error envelope, `{"error": <message>, "code": <code>}`, like every other handler.
+//
+//	@Summary	Send a one-time password to a phone number
+//	@Tags		otp
+//	@Accept		json
+//	@Produce	json
+//	@Param		body	body		OTPData	true	"Phone number to send the code to"
+//	@Success	202		{object}	jsonResponse
+//	@Failure	400		{object}	errorResponse
+//	@Failure	429		{object}	errorResponse
+//	@Router		/auth/sendotp [post]
+func sendSMS(provider otp.Provider, allowedChannels []string, quota *otp.DailyQuota, stats *OTPStats) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		_, cancel := context.WithTimeout(context.Background(), appTimeout)
-		defer cancel()
 		var payload OTPData
-		if err := c.BodyParser(&payload); err != nil {
-			return err
+		if err := decodeBody(c, &payload); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		phone, err := auth.NormalizePhone(payload.PhoneNumber)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
 		}
 		newData := OTPData{
-			PhoneNumber: payload.PhoneNumber,
+			PhoneNumber: phone,
+			Channel:     strings.TrimSpace(payload.Channel),
+		}
+		if newData.Channel == "" {
+			newData.Channel = defaultOTPChannel
+		}
+		if !channelAllowed(newData.Channel, allowedChannels) {
+			return sendError(c, fiber.StatusBadRequest, fmt.Errorf("%w: %q", pkg.ErrOTPChannel, newData.Channel))
+		}
+		now := pkg.NowUTC()
+		resetAt, err := quota.WithContext(c.UserContext()).Take(newData.PhoneNumber, now)
+		if errors.Is(err, pkg.ErrOTPDailyLimit) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(resetAt.Sub(now).Seconds())+1))
+			return sendError(c, fiber.StatusTooManyRequests, fmt.Errorf("%w, try again after %s", err, resetAt.Format(time.RFC3339)))
 		}
-		_, err := twilioSendOTP(newData.PhoneNumber)
 		if err != nil {
-			errorJSON(c, err)
-			return err
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		stats.count(newData.Channel, func(counters *otpCounters) { counters.SendAttempted++ })
+		if err := provider.Send(c.UserContext(), newData.PhoneNumber, newData.Channel, clientIP(c)); err != nil {
+			stats.count(newData.Channel, func(counters *otpCounters) { counters.SendFailed++ })
+			return sendError(c, fiber.StatusBadRequest, err)
 		}
-		writeJSON(c, http.StatusAccepted, "OTP sent successfully")
-		return nil
+		stats.count(newData.Channel, func(counters *otpCounters) { counters.SendSucceeded++ })
+		return writeJSON(c, http.StatusAccepted, "OTP sent successfully")
 	}
 }
 
-// The function verifies an SMS OTP code using Twilio API and returns a success message.
-func verifySMS(svc auth.Service) fiber.Handler {
+// The type `otpVerifyResponse` is the body returned when an OTP code was verified successfully.
+type otpVerifyResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Token   string `json:"token"`
+}
+
+// The function verifies an SMS OTP code with the configured provider and returns a success message.
+// The phone number is normalized the same way as when the code was sent. Verifications are counted in
+// `stats` per channel.
+//
+//	@Summary	Verify a one-time password and log in
+//	@Tags		otp
+//	@Accept		json
+//	@Produce	json
+//	@Param		body	body		VerifyData	true	"Phone number and the received code"
+//	@Success	200		{object}	otpVerifyResponse
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	401		{object}	errorResponse
+//	@Router		/auth/verifyotp [post]
+func verifySMS(svc auth.Service, provider otp.Provider, stats *OTPStats) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		_, cancel := context.WithTimeout(c.Context(), appTimeout)
-		defer cancel()
 		var payload VerifyData
-
-		if err := c.BodyParser(&payload); err != nil {
+		if err := decodeBody(c, &payload); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		// `validateRequest` enforces the `required` tags, so a body without `user` is answered with 400
+		// before `User` is dereferenced below.
+		if ok, err := validateRequest(c, &payload); !ok {
 			return err
 		}
 		newData := VerifyData{
 			User: payload.User,
-			Code: payload.Code,
+			Code: strings.TrimSpace(payload.Code),
 		}
-		token, err := svc.LoginPhoneOtp(newData.User.PhoneNumber)
+		phone, err := auth.NormalizePhone(newData.User.PhoneNumber)
 		if err != nil {
-			errorJSON(c, err)
-			return err
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		newData.User.PhoneNumber = phone
+		channel, err := provider.Verify(c.UserContext(), newData.User.PhoneNumber, newData.Code)
+		stats.count(channel, func(counters *otpCounters) {
+			counters.VerifyAttempted++
+			switch {
+			case err == nil:
+				counters.VerifyApproved++
+			case errors.Is(err, pkg.ErrOTPNotApproved):
+				counters.VerifyRejected++
+			}
+		})
+		if errors.Is(err, pkg.ErrOTPNotApproved) {
+			return sendError(c, fiber.StatusUnauthorized, err)
 		}
-
-		err = twilioVerifyOTP(newData.User.PhoneNumber, newData.Code)
 		if err != nil {
-			errorJSON(c, err)
-			return err
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		token, err := svc.WithContext(c.UserContext()).LoginPhoneOtp(newData.User.PhoneNumber, deviceFromRequest(c))
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
 		}
-		return c.JSON(fiber.Map{
-			"status":  http.StatusOK,
-			"message": "OTP verified successfully",
-			"token":   token,
+		return c.JSON(otpVerifyResponse{
+			Status:  http.StatusOK,
+			Message: "OTP verified successfully",
+			Token:   token,
 		})
+	}
+}
 
+// The function reports whether the channel is one of the allowed ones.
+func channelAllowed(channel string, allowedChannels []string) bool {
+	for _, allowed := range allowedChannels {
+		if channel == allowed {
+			return true
+		}
 	}
+	return false
 }
 
-// The function creates two routes for sending and verifying phone OTPs in a Fiber app.
-func CreatePhoneOtpRoutes(app *fiber.App, svc auth.Service) {
-	app.Post("/api/auth/sendotp", sendSMS())
-	app.Post("/api/auth/verifyotp", verifySMS(svc))
+// The function creates two routes for sending and verifying phone OTPs in a Fiber app. Codes are sent
+// and checked by `provider` and can only be sent over the channels in `allowedChannels`. Both routes are public, so `rateLimit` limits them
+// by IP, and `quota` caps the codes sent to each phone number per day. Sends and verifications are
+// counted in `stats`. Both are refused while the `phone_otp` feature flag is off.
+func CreatePhoneOtpRoutes(router fiber.Router, svc auth.Service, provider otp.Provider, allowedChannels []string, rateLimit fiber.Handler, quota *otp.DailyQuota, stats *OTPStats, featureFlags *flags.FeatureFlags) {
+	enabled := RequireFlag(featureFlags, flags.PhoneOTP)
+	router.Post("/auth/sendotp", rateLimit, enabled, sendSMS(provider, allowedChannels, quota, stats))
+	router.Post("/auth/verifyotp", rateLimit, enabled, verifySMS(svc, provider, stats))
 }
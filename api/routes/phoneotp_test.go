@@ -0,0 +1,199 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/otp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The type recordingProvider is an OTP provider that accepts every send and remembers the phone
+// numbers and channels codes were sent to, and the number of codes it was asked to verify.
+type recordingProvider struct {
+	phones   []string
+	channels []string
+	verified int
+}
+
+func (p *recordingProvider) Send(ctx context.Context, phoneNumber string, channel string, deviceIP string) error {
+	p.phones = append(p.phones, phoneNumber)
+	p.channels = append(p.channels, channel)
+	return nil
+}
+
+func (p *recordingProvider) Verify(ctx context.Context, phoneNumber string, code string) (string, error) {
+	p.verified++
+	return otp.UnknownChannel, nil
+}
+
+func TestSendSMSChannels(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		channel string
+	}{
+		{name: "default channel", body: `{"phoneNumber": "+15551234567"}`, status: fiber.StatusAccepted, channel: "sms"},
+		{name: "allowed channel", body: `{"phoneNumber": "+15551234567", "channel": "whatsapp"}`, status: fiber.StatusAccepted, channel: "whatsapp"},
+		{name: "disallowed channel", body: `{"phoneNumber": "+15551234567", "channel": "call"}`, status: fiber.StatusBadRequest},
+		{name: "unknown channel", body: `{"phoneNumber": "+15551234567", "channel": "pigeon"}`, status: fiber.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &recordingProvider{}
+			app := fiber.New()
+			app.Post("/auth/sendotp", sendSMS(provider, []string{"sms", "whatsapp"}, new(otp.DailyQuota), NewOTPStats()))
+
+			res, body := send(t, app, fiber.MethodPost, "/auth/sendotp", tt.body)
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.status, body)
+			}
+			if tt.channel == "" {
+				expectCode(t, body, "OTP_CHANNEL_NOT_ALLOWED")
+			}
+			switch {
+			case tt.channel == "" && len(provider.channels) != 0:
+				t.Errorf("a code was sent over %v, want the request refused before the provider", provider.channels)
+			case tt.channel != "" && (len(provider.channels) != 1 || provider.channels[0] != tt.channel):
+				t.Errorf("codes were sent over %v, want [%s]", provider.channels, tt.channel)
+			}
+		})
+	}
+}
+
+func TestSendSMSPhoneFormat(t *testing.T) {
+	defer func(code string) { auth.DefaultCountryCode = code }(auth.DefaultCountryCode)
+	auth.DefaultCountryCode = "91"
+	tests := []struct {
+		name  string
+		phone string
+		want  string
+	}{
+		{name: "e164", phone: "+14155552671", want: "+14155552671"},
+		{name: "local format", phone: "098765 43210", want: "+919876543210"},
+		{name: "not a number", phone: "call me maybe"},
+		{name: "too short", phone: "+1415"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &recordingProvider{}
+			app := fiber.New()
+			app.Post("/auth/sendotp", sendSMS(provider, []string{"sms"}, new(otp.DailyQuota), NewOTPStats()))
+
+			res, body := send(t, app, fiber.MethodPost, "/auth/sendotp", `{"phoneNumber": "`+tt.phone+`"}`)
+			if tt.want == "" {
+				if res.StatusCode != fiber.StatusBadRequest {
+					t.Fatalf("status = %d, want %d: %s", res.StatusCode, fiber.StatusBadRequest, body)
+				}
+				expectCode(t, body, "INVALID_PHONE")
+				if len(provider.phones) != 0 {
+					t.Errorf("a code was sent to %v, want the number refused before the provider", provider.phones)
+				}
+				return
+			}
+			if res.StatusCode != fiber.StatusAccepted {
+				t.Fatalf("status = %d, want %d: %s", res.StatusCode, fiber.StatusAccepted, body)
+			}
+			if len(provider.phones) != 1 || provider.phones[0] != tt.want {
+				t.Errorf("codes were sent to %v, want [%s]", provider.phones, tt.want)
+			}
+		})
+	}
+}
+
+func TestMockProviderLogin(t *testing.T) {
+	dbtest.Run(t, "send, verify and log in", func(mt *mtest.T) {
+		provider, err := otp.NewFixedCodeProvider("424242")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		app := otpApp(mt, provider, NewOTPStats())
+		if res, body := send(mt.T, app, fiber.MethodPost, "/auth/sendotp", `{"phoneNumber": "+1 555 123 4567"}`); res.StatusCode != fiber.StatusAccepted {
+			mt.Fatalf("send status = %d, want 202: %s", res.StatusCode, body)
+		}
+		res, body := send(mt.T, app, fiber.MethodPost, "/auth/verifyotp", `{"user": {"phoneNumber": "+15551234567"}, "code": "000000"}`)
+		if res.StatusCode != fiber.StatusUnauthorized {
+			mt.Fatalf("verify of a wrong code status = %d, want 401: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "OTP_NOT_APPROVED")
+
+		mt.AddMockResponses(dbtest.Cursor(mt, auth.User{ID: "user-1", PhoneNumber: "+15551234567"}))
+		res, body = send(mt.T, app, fiber.MethodPost, "/auth/verifyotp", `{"user": {"phoneNumber": "+15551234567"}, "code": " 424242 "}`)
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("verify status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var verified otpVerifyResponse
+		if err := json.Unmarshal([]byte(body), &verified); err != nil || verified.Token == "" {
+			mt.Errorf("body = %s, want a token", body)
+		}
+
+		if res, _ := send(mt.T, app, fiber.MethodPost, "/auth/verifyotp", `{"user": {"phoneNumber": "+15551234567"}, "code": "424242"}`); res.StatusCode != fiber.StatusUnauthorized {
+			mt.Errorf("verify of a used code status = %d, want 401", res.StatusCode)
+		}
+	})
+}
+
+func TestVerifySMSBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{name: "no user", body: `{"code": "123456"}`, code: "VALIDATION_FAILED"},
+		{name: "null user", body: `{"user": null, "code": "123456"}`, code: "VALIDATION_FAILED"},
+		{name: "no code", body: `{"user": {"phoneNumber": "+15551234567"}}`, code: "VALIDATION_FAILED"},
+		{name: "not json", body: `{"user":`, code: "INVALID_BODY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &recordingProvider{}
+			app := fiber.New()
+			app.Post("/auth/verifyotp", verifySMS(nil, provider, NewOTPStats()))
+
+			res, body := send(t, app, fiber.MethodPost, "/auth/verifyotp", tt.body)
+			if res.StatusCode != fiber.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+			}
+			expectCode(t, body, tt.code)
+			if provider.verified != 0 {
+				t.Errorf("the provider verified %d codes, want the request refused before it", provider.verified)
+			}
+		})
+	}
+}
+
+func TestSendSMSDailyLimit(t *testing.T) {
+	dbtest.Run(t, "over the limit", func(mt *mtest.T) {
+		provider := &recordingProvider{}
+		app := fiber.New()
+		app.Post("/auth/sendotp", sendSMS(provider, []string{"sms"}, otp.NewDailyQuota(mt.DB, 3), NewOTPStats()))
+		mt.AddMockResponses(dbtest.Value(mt, bson.M{"count": 3}), dbtest.Value(mt, bson.M{"count": 4}))
+
+		if res, body := send(mt.T, app, fiber.MethodPost, "/auth/sendotp", `{"phoneNumber": "+15551234567"}`); res.StatusCode != fiber.StatusAccepted {
+			mt.Fatalf("status of the last allowed code = %d, want 202: %s", res.StatusCode, body)
+		}
+		res, body := send(mt.T, app, fiber.MethodPost, "/auth/sendotp", `{"phoneNumber": "+15551234567"}`)
+		if res.StatusCode != fiber.StatusTooManyRequests {
+			mt.Fatalf("status = %d, want 429: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "OTP_DAILY_LIMIT")
+		retryAfter, err := strconv.Atoi(res.Header.Get(fiber.HeaderRetryAfter))
+		if err != nil || retryAfter <= 0 || retryAfter > 24*60*60+1 {
+			mt.Errorf("Retry-After = %q, want the seconds until UTC midnight", res.Header.Get(fiber.HeaderRetryAfter))
+		}
+		if !strings.Contains(body, "try again after") {
+			mt.Errorf("body = %s, want when the limit resets", body)
+		}
+		if len(provider.channels) != 1 {
+			mt.Errorf("sent %d codes, want only the allowed one", len(provider.channels))
+		}
+	})
+}
@@ -0,0 +1,313 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/progress"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `batchSolvedBody` is the request body for marking several questions solved at once.
+type batchSolvedBody struct {
+	IDs []string `json:"ids"`
+}
+
+// The type `progressErrorResponse` documents the body returned by the progress handlers when a request
+// fails.
+type progressErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// The function `markSolvedBatchHandler` marks every question in the request as solved by the
+// authenticated user. Unknown, malformed and already solved IDs are skipped and counted in the
+// response.
+//
+//	@Summary	Mark several questions solved
+//	@Tags		progress
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		body	body		batchSolvedBody	true	"IDs of the solved questions"
+//	@Success	200		{object}	progress.BatchResult
+//	@Failure	400		{object}	progressErrorResponse
+//	@Failure	401		{object}	progressErrorResponse
+//	@Router		/auth/me/progress/batch [post]
+func markSolvedBatchHandler(svc progress.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body batchSolvedBody
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		result, err := svc.WithContext(c.UserContext()).MarkSolvedBatch(userID, body.IDs)
+		if errors.Is(err, pkg.ErrEmptyBatch) || errors.Is(err, pkg.ErrBatchTooLarge) {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(result)
+	}
+}
+
+// The function `streakHandler` returns the authenticated user's daily solving streak. Days are
+// counted in the timezone given by the `tz` query parameter, or the user's own timezone when it is
+// omitted, or `defaultTimezone` when they haven't set one.
+//
+//	@Summary	Get your solving streak
+//	@Tags		progress
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		tz	query		string	false	"IANA timezone, e.g. Asia/Kolkata; defaults to your own"
+//	@Success	200	{object}	progress.Streak
+//	@Failure	400	{object}	progressErrorResponse
+//	@Failure	401	{object}	progressErrorResponse
+//	@Router		/auth/me/streak [get]
+func streakHandler(svc progress.Service, userRepo *auth.Repo, defaultTimezone string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		loc, err := userLocation(c, userRepo, userID, defaultTimezone)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		streak, err := svc.WithContext(c.UserContext()).Streak(userID, loc)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(streak)
+	}
+}
+
+// The function `statsHandler` returns a summary of the authenticated user's progress for their profile
+// page: the solved questions per level, the streak and the rank. Days are counted in the timezone given
+// by the `tz` query parameter, or the user's own timezone when it is omitted, or `defaultTimezone` when
+// they haven't set one.
+//
+//	@Summary	Get your progress summary
+//	@Tags		progress
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		tz	query		string	false	"IANA timezone, e.g. Asia/Kolkata; defaults to your own"
+//	@Success	200	{object}	progress.Stats
+//	@Failure	400	{object}	progressErrorResponse
+//	@Failure	401	{object}	progressErrorResponse
+//	@Failure	500	{object}	progressErrorResponse
+//	@Router		/auth/me/stats [get]
+func statsHandler(svc progress.Service, userRepo *auth.Repo, defaultTimezone string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		loc, err := userLocation(c, userRepo, userID, defaultTimezone)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		stats, err := svc.WithContext(c.UserContext()).Stats(userID, loc)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(stats)
+	}
+}
+
+// The function `toggleSolvedHandler` flips whether the authenticated user has solved a question, for
+// UIs with a "solved" checkbox, and returns the new state.
+//
+//	@Summary	Toggle whether a question is solved
+//	@Tags		progress
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id	path		string	true	"Question ID"
+//	@Success	200	{object}	progress.SolvedState
+//	@Failure	400	{object}	progressErrorResponse
+//	@Failure	401	{object}	progressErrorResponse
+//	@Failure	404	{object}	progressErrorResponse
+//	@Router		/all/question/{id}/toggle-solved [post]
+func toggleSolvedHandler(svc progress.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		state, err := svc.WithContext(c.UserContext()).ToggleSolved(userID, c.Params("id"))
+		switch {
+		case errors.Is(err, pkg.ErrInvalidQuestionID):
+			return sendError(c, fiber.StatusBadRequest, err)
+		case errors.Is(err, pkg.ErrQuestionNotFound):
+			return sendError(c, fiber.StatusNotFound, err)
+		case err != nil:
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(state)
+	}
+}
+
+// The function `listProgressHandler` returns a page of the questions the authenticated user solved,
+// most recently solved first unless `order` is `asc`, together with the number of solved questions
+// matching the filter.
+//
+//	@Summary	List your solved questions
+//	@Tags		progress
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		level	query		string	false	"Only questions of this level"	Enums(Easy, Medium, Hard)
+//	@Param		order	query		string	false	"Order by solve time"			Enums(desc, asc)	default(desc)
+//	@Param		limit	query		int		false	"Page size, clamped to MAX_PAGE_SIZE"
+//	@Param		offset	query		int		false	"Number of entries to skip"	default(0)
+//	@Success	200		{object}	progress.Page
+//	@Failure	400		{object}	progressErrorResponse
+//	@Failure	401		{object}	progressErrorResponse
+//	@Router		/auth/me/progress [get]
+func listProgressHandler(svc progress.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		page, err := parsePaging(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		level := c.Query("level")
+		if level != "" && !allquestions.ValidLevel(level) {
+			return sendError(c, fiber.StatusBadRequest, errors.New("level must be one of "+strings.Join(allquestions.LevelEnum, ", ")))
+		}
+		order := c.Query("order", "desc")
+		if order != "asc" && order != "desc" {
+			return sendError(c, fiber.StatusBadRequest, errors.New("order must be asc or desc"))
+		}
+		list, err := svc.WithContext(c.UserContext()).List(userID, level, order == "asc", page.Limit, page.Offset)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(list)
+	}
+}
+
+// `defaultLeaderboardLimit` is the number of users on the leaderboard when the client doesn't ask for a
+// specific `limit`.
+const defaultLeaderboardLimit = 10
+
+// The function `rankHandler` returns the authenticated user's position on the leaderboard by number of
+// solved questions.
+//
+//	@Summary	Get your leaderboard rank
+//	@Tags		progress
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{object}	progress.Rank
+//	@Failure	401	{object}	progressErrorResponse
+//	@Failure	500	{object}	progressErrorResponse
+//	@Router		/auth/me/rank [get]
+func rankHandler(svc progress.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		rank, err := svc.WithContext(c.UserContext()).Rank(userID)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(rank)
+	}
+}
+
+// The function `leaderboardHandler` returns the users that solved the most questions, up to the
+// `limit` query parameter.
+//
+//	@Summary	Get the leaderboard
+//	@Tags		progress
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		limit	query		int	false	"Number of users, clamped to MAX_PAGE_SIZE"	default(10)
+//	@Success	200		{array}		progress.LeaderboardEntry
+//	@Failure	500		{object}	progressErrorResponse
+//	@Router		/all/leaderboard [get]
+func leaderboardHandler(svc progress.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := clampLimit(c.QueryInt("limit", defaultLeaderboardLimit), defaultLeaderboardLimit)
+		entries, err := svc.WithContext(c.UserContext()).Leaderboard(limit)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(entries)
+	}
+}
+
+// The function `recountHandler` recomputes the number of questions a user has solved from their
+// progress and stores it on the user.
+//
+//	@Summary	Recount a user's solved questions
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id	path		string	true	"User ID"
+//	@Success	200	{object}	progress.Recount
+//	@Failure	404	{object}	errorResponse
+//	@Failure	500	{object}	errorResponse
+//	@Router		/auth/users/{id}/recount [post]
+func recountHandler(svc progress.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		recount, err := svc.WithContext(c.UserContext()).Recount(c.Params("id"))
+		switch {
+		case errors.Is(err, pkg.ErrUserNotFound):
+			return sendError(c, fiber.StatusNotFound, err)
+		case err != nil:
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(recount)
+	}
+}
+
+// The function `recountAllHandler` recomputes the number of questions every user has solved and
+// stores it on the users.
+//
+//	@Summary	Recount every user's solved questions
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{object}	progress.RecountAllResult
+//	@Failure	500	{object}	errorResponse
+//	@Router		/auth/recount-all [post]
+func recountAllHandler(svc progress.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		result, err := svc.WithContext(c.UserContext()).RecountAll()
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(result)
+	}
+}
+
+// The function creates the admin-only routes that bring the solve counts stored on the users back in
+// line with the progress collection.
+func CreateRecountRoutes(router fiber.Router, userRepo *auth.Repo, svc progress.Service) {
+	router.Post("/auth/users/:id/recount", RequireRole(userRepo, "admin"), recountHandler(svc))
+	router.Post("/auth/recount-all", RequireRole(userRepo, "admin"), recountAllHandler(svc))
+}
+
+// The function creates the routes for recording and summarizing the authenticated user's progress.
+// `defaultTimezone` is used for day boundaries when neither the request nor the user in `userRepo`
+// specifies one.
+func CreateProgressRoutes(router fiber.Router, svc progress.Service, userRepo *auth.Repo, defaultTimezone string) {
+	router.Get("/auth/me/progress", listProgressHandler(svc))
+	router.Post("/auth/me/progress/batch", markSolvedBatchHandler(svc))
+	router.Get("/auth/me/streak", streakHandler(svc, userRepo, defaultTimezone))
+	router.Get("/auth/me/stats", statsHandler(svc, userRepo, defaultTimezone))
+	router.Post("/all/question/:id/toggle-solved", toggleSolvedHandler(svc))
+	router.Get("/auth/me/rank", rankHandler(svc))
+	router.Get("/all/leaderboard", leaderboardHandler(svc))
+}
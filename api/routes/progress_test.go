@@ -0,0 +1,115 @@
+package routes
+
+import (
+	"encoding/json"
+	"reflect"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/progress"
+	"sigmacoder/pkg/timelogs"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns an app serving GET /auth/me/stats for "user-1" from the mocked deployment.
+func statsApp(mt *mtest.T) *fiber.App {
+	users := auth.NewRepo(mt.DB).(*auth.Repo)
+	svc := progress.NewProgressService(progress.NewRepo(mt.DB).(*progress.Repo), allquestions.NewRepo(mt.DB), users, timelogs.NewRepo(mt.DB).(*timelogs.Repo), 10)
+	app := fiber.New()
+	app.Get("/auth/me/stats", authenticated(jwt.MapClaims{"userid": "user-1"}), statsHandler(svc, users, "UTC"))
+	return app
+}
+
+func TestMeStats(t *testing.T) {
+	dbtest.Run(t, "every section", func(mt *mtest.T) {
+		now := time.Now().UTC()
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, bson.M{
+				"byLevel": bson.A{bson.M{"_id": allquestions.LevelEasy, "count": 2}, bson.M{"_id": allquestions.LevelHard, "count": 1}},
+				"solvedAt": bson.A{
+					bson.M{"solvedAt": now},
+					bson.M{"solvedAt": now.AddDate(0, 0, -1)},
+					bson.M{"solvedAt": now.AddDate(0, 0, -3)},
+				},
+			}),
+			dbtest.Cursor(mt, bson.M{"above": bson.A{bson.M{"count": 4}}, "ranked": bson.A{bson.M{"count": 9}}}),
+		)
+
+		res, body := send(mt.T, statsApp(mt), fiber.MethodGet, "/auth/me/stats?tz=UTC", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var stats progress.Stats
+		if err := json.Unmarshal([]byte(body), &stats); err != nil {
+			mt.Fatal(err)
+		}
+		if stats.Solved != 3 {
+			mt.Errorf("solved = %d, want 3", stats.Solved)
+		}
+		if want := map[string]int64{allquestions.LevelEasy: 2, allquestions.LevelMedium: 0, allquestions.LevelHard: 1}; !reflect.DeepEqual(stats.ByLevel, want) {
+			mt.Errorf("byLevel = %v, want %v", stats.ByLevel, want)
+		}
+		if want := (progress.Streak{Current: 2, Longest: 2, SolvedToday: true, Timezone: "UTC"}); stats.Streak != want {
+			mt.Errorf("streak = %+v, want %+v", stats.Streak, want)
+		}
+		if want := (progress.Rank{Rank: 5, Solved: 3, Ranked: 9}); stats.Rank != want {
+			mt.Errorf("rank = %+v, want %+v", stats.Rank, want)
+		}
+		pipeline := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)
+		dbtest.ExpectField(mt, pipeline[0].(bson.M), "$match", bson.M{"userid": "user-1"})
+		dbtest.NextCommand(mt, "aggregate")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "nothing solved", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, bson.M{"byLevel": bson.A{}, "solvedAt": bson.A{}}),
+			dbtest.Cursor(mt, bson.M{"above": bson.A{bson.M{"count": 9}}, "ranked": bson.A{bson.M{"count": 9}}}),
+		)
+		res, body := send(mt.T, statsApp(mt), fiber.MethodGet, "/auth/me/stats?tz=UTC", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var stats progress.Stats
+		if err := json.Unmarshal([]byte(body), &stats); err != nil {
+			mt.Fatal(err)
+		}
+		if stats.Solved != 0 || len(stats.ByLevel) != len(allquestions.LevelEnum) || stats.Streak.Current != 0 || stats.Rank.Rank != 10 {
+			mt.Errorf("stats = %+v, want zero counts for every level and the last rank", stats)
+		}
+	})
+
+	dbtest.Run(t, "stored timezone", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, auth.User{ID: "user-1", Timezone: "Asia/Kolkata"}),
+			dbtest.Cursor(mt, bson.M{"byLevel": bson.A{}, "solvedAt": bson.A{}}),
+			dbtest.Cursor(mt, bson.M{"above": bson.A{bson.M{"count": 9}}, "ranked": bson.A{bson.M{"count": 9}}}),
+		)
+		res, body := send(mt.T, statsApp(mt), fiber.MethodGet, "/auth/me/stats", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var stats progress.Stats
+		if err := json.Unmarshal([]byte(body), &stats); err != nil {
+			mt.Fatal(err)
+		}
+		if stats.Streak.Timezone != "Asia/Kolkata" {
+			mt.Errorf("streak = %+v, want it counted in the user's timezone", stats.Streak)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": "user-1"})
+	})
+
+	dbtest.Run(t, "unknown timezone", func(mt *mtest.T) {
+		res, body := send(mt.T, statsApp(mt), fiber.MethodGet, "/auth/me/stats?tz=Mars/Olympus", "")
+		if res.StatusCode != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
@@ -0,0 +1,113 @@
+package routes
+
+import (
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/sessions"
+	"sigmacoder/pkg/token"
+
+	"github.com/gofiber/fiber/v2"
+	jwtware "github.com/gofiber/jwt/v3"
+)
+
+// The type `protectedRouter` registers every route with the authentication middleware in front of its
+// handlers. Since the middleware is part of each route instead of a `Use` on the whole API, a route is
+// protected because it was registered on this router, not because of where it was registered.
+// @property middleware - The handlers that run before the route's own, such as those of `RequireAuth`.
+type protectedRouter struct {
+	fiber.Router
+	middleware []fiber.Handler
+}
+
+// The function returns a router that registers its routes on `router`, each of them running
+// `middleware` first. The public routes are registered on `router` itself, and the ones that need a
+// logged in user on the returned router. `Use` isn't wrapped, so it must not be called on it.
+func Protected(router fiber.Router, middleware ...fiber.Handler) fiber.Router {
+	return &protectedRouter{Router: router, middleware: middleware}
+}
+
+// The function prepends the router's middleware to the handlers of a route.
+func (r *protectedRouter) with(handlers []fiber.Handler) []fiber.Handler {
+	return append(append([]fiber.Handler{}, r.middleware...), handlers...)
+}
+
+// The methods below register a route like those of `fiber.Router`, with the middleware prepended.
+func (r *protectedRouter) Get(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Get(path, r.with(handlers)...)
+	return r
+}
+
+func (r *protectedRouter) Head(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Head(path, r.with(handlers)...)
+	return r
+}
+
+func (r *protectedRouter) Post(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Post(path, r.with(handlers)...)
+	return r
+}
+
+func (r *protectedRouter) Put(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Put(path, r.with(handlers)...)
+	return r
+}
+
+func (r *protectedRouter) Delete(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Delete(path, r.with(handlers)...)
+	return r
+}
+
+func (r *protectedRouter) Connect(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Connect(path, r.with(handlers)...)
+	return r
+}
+
+func (r *protectedRouter) Options(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Options(path, r.with(handlers)...)
+	return r
+}
+
+func (r *protectedRouter) Trace(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Trace(path, r.with(handlers)...)
+	return r
+}
+
+func (r *protectedRouter) Patch(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Patch(path, r.with(handlers)...)
+	return r
+}
+
+func (r *protectedRouter) Add(method, path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.Add(method, path, r.with(handlers)...)
+	return r
+}
+
+func (r *protectedRouter) All(path string, handlers ...fiber.Handler) fiber.Router {
+	r.Router.All(path, r.with(handlers)...)
+	return r
+}
+
+// The function returns a protected router for the routes under `prefix`. Handlers given for the group
+// itself run for every route under the prefix, protected or not, like those of `fiber.Router.Group`.
+func (r *protectedRouter) Group(prefix string, handlers ...fiber.Handler) fiber.Router {
+	return &protectedRouter{Router: r.Router.Group(prefix, handlers...), middleware: r.middleware}
+}
+
+// The function returns the middleware every protected route runs: it requires a valid token, checked
+// with `keys`, whose session hasn't been revoked and that wasn't issued before its user's password was
+// last changed, keeps tokens issued for an expired password to the password change, and then applies
+// `rateLimit` per user. Requests without a valid token are answered with 401.
+func RequireAuth(keys *token.Keys, sessionRepo *sessions.Repo, userRepo *auth.Repo, rateLimit fiber.Handler) []fiber.Handler {
+	return []fiber.Handler{
+		jwtware.New(jwtware.Config{
+			KeyFunc: keys.Keyfunc,
+			ErrorHandler: func(c *fiber.Ctx, err error) error {
+				return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+			},
+		}),
+		RequireActiveSession(sessionRepo),
+		RejectTokensBeforePasswordChange(userRepo),
+		RestrictScopedTokens(),
+		rateLimit,
+	}
+}
@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/sessions"
+	"sigmacoder/pkg/token"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns an app with the auth and question routes registered the way main does, with
+// the protected ones behind `RequireAuth`. The auth routes, which include protected ones, are
+// registered first, so the public question routes come after them.
+func protectedApp(mt *mtest.T, keys *token.Keys) *fiber.App {
+	next := func(c *fiber.Ctx) error { return c.Next() }
+	userRepo := auth.NewRepo(mt.DB).(*auth.Repo)
+	sessionRepo := sessions.NewRepo(mt.DB).(*sessions.Repo)
+	app := fiber.New()
+	protected := Protected(app, RequireAuth(keys, sessionRepo, userRepo, next)...)
+	CreateAuthRoutes(app, protected, userRepo, auth.NewAuthService(userRepo), nil, sessionRepo, next, keys, nil, nil)
+	CreateAllQuestionRoutes(app, protected, allquestions.NewRepo(mt.DB), userRepo, nil, nil, nil, nil, next, next)
+	return app
+}
+
+func TestProtectedRoutes(t *testing.T) {
+	keys := token.NewHS256(checkSecret)
+
+	dbtest.Run(t, "public route without a token", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if res, body := send(mt.T, protectedApp(mt, keys), fiber.MethodGet, "/all/allquestions", ""); res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "aggregate")
+	})
+
+	for _, route := range []struct {
+		method string
+		path   string
+	}{
+		{method: fiber.MethodGet, path: "/auth/me"},
+		{method: fiber.MethodPost, path: "/auth/me/password"},
+		{method: fiber.MethodPost, path: "/all/question"},
+		{method: fiber.MethodGet, path: "/auth/questions"},
+	} {
+		dbtest.Run(t, route.method+" "+route.path+" without a token", func(mt *mtest.T) {
+			res, body := send(mt.T, protectedApp(mt, keys), route.method, route.path, "")
+			if res.StatusCode != fiber.StatusUnauthorized {
+				mt.Fatalf("status = %d, want 401: %s", res.StatusCode, body)
+			}
+			expectCode(mt.T, body, "UNAUTHORIZED")
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+
+	dbtest.Run(t, "protected route with a token", func(mt *mtest.T) {
+		user := auth.User{ID: "user-1", Email: "ada@example.com"}
+		mt.AddMockResponses(dbtest.Cursor(mt, user), dbtest.Cursor(mt, user))
+		claims := jwt.MapClaims{"userid": "user-1", "iat": time.Now().Unix(), "exp": time.Now().Add(time.Hour).Unix()}
+		if res, body := send(mt.T, protectedApp(mt, keys), fiber.MethodGet, "/auth/me", "", fiber.HeaderAuthorization, bearer(mt.T, keys, claims)); res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+	})
+}
+
+func TestProtectedRegistrationOrder(t *testing.T) {
+	keys := token.NewHS256(checkSecret)
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) }
+
+	dbtest.Run(t, "interleaved routes", func(mt *mtest.T) {
+		next := func(c *fiber.Ctx) error { return c.Next() }
+		app := fiber.New()
+		protected := Protected(app, RequireAuth(keys, sessions.NewRepo(mt.DB).(*sessions.Repo), auth.NewRepo(mt.DB).(*auth.Repo), next)...)
+		protected.Get("/before", ok)
+		app.Get("/public", ok)
+		protected.Get("/after", ok)
+		group := protected.Group("/group")
+		group.Get("/nested", ok)
+
+		for _, path := range []string{"/before", "/after", "/group/nested"} {
+			res, body := send(mt.T, app, fiber.MethodGet, path, "")
+			if res.StatusCode != fiber.StatusUnauthorized {
+				mt.Errorf("GET %s status = %d, want 401: %s", path, res.StatusCode, body)
+			}
+		}
+		if res, body := send(mt.T, app, fiber.MethodGet, "/public", ""); res.StatusCode != fiber.StatusOK {
+			mt.Errorf("GET /public status = %d, want 200: %s", res.StatusCode, body)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"sigmacoder/pkg"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// The function returns the key a request is rate limited by: the `userid` claim of its token when the
+// jwtware middleware already validated one, otherwise the client IP. Keying authenticated requests on
+// the user keeps users behind a shared NAT from using up each other's budget, and keeps a single user
+// from multiplying theirs by switching proxies.
+func rateLimitKey(c *fiber.Ctx) string {
+	if userID, ok := userIDFromToken(c); ok {
+		return "user:" + userID
+	}
+	return "ip:" + clientIP(c)
+}
+
+// The function returns a middleware that allows at most `max` requests per `window` for every key
+// from `rateLimitKey`. Requests over the limit are answered with 429 Too Many Requests and a
+// `Retry-After` header. To key authenticated requests on the user, it has to run after the jwtware
+// middleware; on public routes it falls back to the IP. A `max` of 0 disables rate limiting.
+func RateLimit(max int, window time.Duration) fiber.Handler {
+	if max <= 0 {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+	return limiter.New(limiter.Config{
+		Max:          max,
+		Expiration:   window,
+		KeyGenerator: rateLimitKey,
+		LimitReached: func(c *fiber.Ctx) error {
+			return sendError(c, fiber.StatusTooManyRequests, pkg.ErrRateLimited)
+		},
+	})
+}
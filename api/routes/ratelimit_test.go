@@ -0,0 +1,71 @@
+package routes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// `testUserHeader` names the user a test request is authenticated as. It stands in for the token the
+// jwtware middleware would validate.
+const testUserHeader = "X-Test-User"
+
+// The function returns an app whose routes allow `max` requests per minute. Requests carrying
+// `testUserHeader` are authenticated as that user.
+func rateLimitedApp(max int) *fiber.App {
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if userID := c.Get(testUserHeader); userID != "" {
+			c.Locals("user", &jwt.Token{Claims: jwt.MapClaims{"userid": userID}, Valid: true})
+		}
+		return c.Next()
+	})
+	app.Use(RateLimit(max, time.Minute))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Run("users on the same IP are limited separately", func(t *testing.T) {
+		app := rateLimitedApp(2)
+		for _, user := range []string{"user-1", "user-1", "user-2", "user-2"} {
+			if res, body := send(t, app, fiber.MethodGet, "/", "", testUserHeader, user); res.StatusCode != fiber.StatusOK {
+				t.Fatalf("request of %s: status = %d, want 200: %s", user, res.StatusCode, body)
+			}
+		}
+		res, body := send(t, app, fiber.MethodGet, "/", "", testUserHeader, "user-1")
+		if res.StatusCode != fiber.StatusTooManyRequests {
+			t.Fatalf("status = %d, want 429 once user-1 used up its budget", res.StatusCode)
+		}
+		expectCode(t, body, "RATE_LIMITED")
+		if res.Header.Get(fiber.HeaderRetryAfter) == "" {
+			t.Error("Retry-After is missing")
+		}
+	})
+
+	t.Run("public requests fall back to the IP", func(t *testing.T) {
+		app := rateLimitedApp(1)
+		if res, _ := send(t, app, fiber.MethodGet, "/", ""); res.StatusCode != fiber.StatusOK {
+			t.Fatalf("status = %d, want 200", res.StatusCode)
+		}
+		if res, _ := send(t, app, fiber.MethodGet, "/", ""); res.StatusCode != fiber.StatusTooManyRequests {
+			t.Errorf("status = %d, want 429 for the second request from the IP", res.StatusCode)
+		}
+		if res, _ := send(t, app, fiber.MethodGet, "/", "", testUserHeader, "user-1"); res.StatusCode != fiber.StatusOK {
+			t.Errorf("status = %d, want 200 for a user on the same IP", res.StatusCode)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		app := rateLimitedApp(0)
+		for i := 0; i < 5; i++ {
+			if res, _ := send(t, app, fiber.MethodGet, "/", ""); res.StatusCode != fiber.StatusOK {
+				t.Fatalf("request %d: status = %d, want 200", i+1, res.StatusCode)
+			}
+		}
+	})
+}
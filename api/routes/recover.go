@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"runtime/debug"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/logging"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+)
+
+// `panickedLocal` is the key under which `Recover` marks requests whose handler panicked.
+const panickedLocal = "panicked"
+
+// The function logs a recovered panic with the ID of the request it happened in and the stack trace,
+// and marks the request so `Recover` can answer it.
+func logPanic(c *fiber.Ctx, e interface{}) {
+	logging.Errorf("panic: request %s %s %s: %v\n%s", c.GetRespHeader(fiber.HeaderXRequestID), c.Method(), c.Path(), e, debug.Stack())
+	c.Locals(panickedLocal, true)
+}
+
+// The function returns a middleware built on Fiber's recover middleware that turns a panic in any
+// later handler into a 500 with the usual error envelope. The panic value and stack trace are only
+// logged, never sent to the client. It should run after the request ID middleware, so the logged ID
+// matches the `X-Request-ID` of the response.
+func Recover() fiber.Handler {
+	recoverer := recover.New(recover.Config{EnableStackTrace: true, StackTraceHandler: logPanic})
+	return func(c *fiber.Ctx) error {
+		err := recoverer(c)
+		if panicked, _ := c.Locals(panickedLocal).(bool); panicked {
+			return sendError(c, fiber.StatusInternalServerError, pkg.ErrInternal)
+		}
+		return err
+	}
+}
@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"sigmacoder/pkg/logging"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+func TestRecover(t *testing.T) {
+	logged := captureLogs(t, logging.LevelInfo)
+
+	app := fiber.New()
+	app.Use(requestid.New())
+	app.Use(Recover())
+	app.Get("/panic", func(c *fiber.Ctx) error {
+		var user *struct{ PhoneNumber string }
+		return c.SendString(user.PhoneNumber)
+	})
+	app.Get("/teapot", func(c *fiber.Ctx) error {
+		return fiber.ErrTeapot
+	})
+
+	t.Run("panicking handler", func(t *testing.T) {
+		res, body := send(t, app, fiber.MethodGet, "/panic", "")
+		if res.StatusCode != fiber.StatusInternalServerError {
+			t.Fatalf("status = %d, want 500: %s", res.StatusCode, body)
+		}
+		expectCode(t, body, "INTERNAL_ERROR")
+		if strings.Contains(body, "nil pointer") || strings.Contains(body, "goroutine") {
+			t.Errorf("body = %s, want the panic and stack trace kept out of the response", body)
+		}
+		id := res.Header.Get(fiber.HeaderXRequestID)
+		if id == "" || !strings.Contains(logged.String(), "request "+id) || !strings.Contains(logged.String(), "nil pointer") {
+			t.Errorf("logged %q, want the panic logged with the request ID %q", logged.String(), id)
+		}
+	})
+
+	t.Run("server keeps serving", func(t *testing.T) {
+		if res, body := send(t, app, fiber.MethodGet, "/panic", ""); res.StatusCode != fiber.StatusInternalServerError {
+			t.Errorf("second status = %d, want 500: %s", res.StatusCode, body)
+		}
+	})
+
+	t.Run("errors pass through", func(t *testing.T) {
+		if res, body := send(t, app, fiber.MethodGet, "/teapot", ""); res.StatusCode != fiber.StatusTeapot {
+			t.Errorf("status = %d, want 418: %s", res.StatusCode, body)
+		}
+	})
+}
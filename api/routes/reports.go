@@ -0,0 +1,130 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/reports"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function maps an error returned by the reports service to an HTTP status code.
+func reportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pkg.ErrInvalidQuestionID), errors.Is(err, pkg.ErrInvalidReportStatus):
+		return fiber.StatusBadRequest
+	case errors.Is(err, pkg.ErrQuestionNotFound), errors.Is(err, pkg.ErrReportNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, pkg.ErrDuplicateReport):
+		return fiber.StatusConflict
+	}
+	return fiber.StatusInternalServerError
+}
+
+// The function `reportQuestionHandler` flags a question as broken, for example because its link is
+// dead or the problem is incorrect, so admins can look into it. A user can only have one open report
+// per question.
+//
+//	@Summary	Report a broken question
+//	@Tags		questions
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string				true	"Question ID"
+//	@Param		body	body		reports.InReport	true	"What is wrong with the question"
+//	@Success	201		{object}	reports.Report
+//	@Failure	400		{object}	validationErrorResponse
+//	@Failure	401		{object}	errorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Failure	409		{object}	errorResponse
+//	@Router		/all/question/{id}/report [post]
+func reportQuestionHandler(svc reports.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body reports.InReport
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		if ok, err := validateRequest(c, &body); !ok {
+			return err
+		}
+		report, err := svc.WithContext(c.UserContext()).Report(userID, c.Params("id"), body.Reason)
+		if err != nil {
+			return sendError(c, reportErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(report)
+	}
+}
+
+// The function `listReportsHandler` returns a page of the question reports with a status, oldest
+// first.
+//
+//	@Summary	List question reports
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		status	query		string	false	"open or resolved"	default(open)
+//	@Param		limit	query		int		false	"Page size, clamped to MAX_PAGE_SIZE"
+//	@Param		offset	query		int		false	"Number of reports to skip"	default(0)
+//	@Success	200		{object}	reports.Page
+//	@Failure	400		{object}	errorResponse
+//	@Failure	403		{object}	errorResponse
+//	@Router		/admin/reports [get]
+func listReportsHandler(svc reports.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := parsePaging(c)
+		if err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		list, err := svc.WithContext(c.UserContext()).List(c.Query("status", reports.StatusOpen), page.Limit, page.Offset)
+		if err != nil {
+			return sendError(c, reportErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusOK).JSON(list)
+	}
+}
+
+// The function `setReportStatusHandler` resolves a question report, or reopens a resolved one.
+//
+//	@Summary	Resolve or reopen a question report
+//	@Tags		admin
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string				true	"Report ID"
+//	@Param		body	body		reports.InStatus	true	"open or resolved"
+//	@Success	200		{object}	reports.Report
+//	@Failure	400		{object}	errorResponse
+//	@Failure	403		{object}	errorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Failure	409		{object}	errorResponse
+//	@Router		/admin/reports/{id} [patch]
+func setReportStatusHandler(svc reports.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		adminID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body reports.InStatus
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		report, err := svc.WithContext(c.UserContext()).SetStatus(c.Params("id"), body.Status, adminID)
+		if err != nil {
+			return sendError(c, reportErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusOK).JSON(report)
+	}
+}
+
+// The function creates the route for reporting questions, and the admin-only routes for handling the
+// reports.
+func CreateReportRoutes(router fiber.Router, userRepo *auth.Repo, svc reports.Service) {
+	router.Post("/all/question/:id/report", reportQuestionHandler(svc))
+	router.Get("/admin/reports", RequireRole(userRepo, "admin"), listReportsHandler(svc))
+	router.Patch("/admin/reports/:id", RequireRole(userRepo, "admin"), setReportStatusHandler(svc))
+}
@@ -0,0 +1,163 @@
+package routes
+
+import (
+	"encoding/json"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/reports"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns an app serving the report routes to "user-1" from the mocked deployment.
+func reportsApp(mt *mtest.T) *fiber.App {
+	svc := reports.NewReportsService(reports.NewRepo(mt.DB).(*reports.Repo), allquestions.NewRepo(mt.DB))
+	app := fiber.New()
+	CreateReportRoutes(app.Group("", authenticated(jwt.MapClaims{"userid": "user-1"})), auth.NewRepo(mt.DB).(*auth.Repo), svc)
+	return app
+}
+
+func TestReportQuestion(t *testing.T) {
+	question := primitive.NewObjectID()
+
+	dbtest.Run(t, "reported", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": question}), dbtest.Written(1))
+		res, body := send(mt.T, reportsApp(mt), fiber.MethodPost, "/all/question/"+question.Hex()+"/report", `{"reason": "  the link is dead "}`)
+		if res.StatusCode != fiber.StatusCreated {
+			mt.Fatalf("status = %d, want 201: %s", res.StatusCode, body)
+		}
+		var report reports.Report
+		if err := json.Unmarshal([]byte(body), &report); err != nil {
+			mt.Fatal(err)
+		}
+		if report.QuestionID != question || report.UserID != "user-1" || report.Reason != "the link is dead" || report.Status != reports.StatusOpen {
+			mt.Errorf("report = %+v, want an open report by user-1 with the trimmed reason", report)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": bson.M{"$in": bson.A{question}}})
+		stored := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+		for field, want := range map[string]interface{}{"questionid": question, "userid": "user-1", "status": reports.StatusOpen} {
+			if stored[field] != want {
+				mt.Errorf("stored %s = %v, want %v", field, stored[field], want)
+			}
+		}
+	})
+
+	dbtest.Run(t, "already reported", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, bson.M{"_id": question}),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}),
+		)
+		res, body := send(mt.T, reportsApp(mt), fiber.MethodPost, "/all/question/"+question.Hex()+"/report", `{"reason": "the link is dead"}`)
+		if res.StatusCode != fiber.StatusConflict {
+			mt.Fatalf("status = %d, want 409: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "DUPLICATE_REPORT")
+	})
+
+	dbtest.Run(t, "unknown question", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		res, body := send(mt.T, reportsApp(mt), fiber.MethodPost, "/all/question/"+question.Hex()+"/report", `{"reason": "the link is dead"}`)
+		if res.StatusCode != fiber.StatusNotFound {
+			mt.Fatalf("status = %d, want 404: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "no reason", func(mt *mtest.T) {
+		res, body := send(mt.T, reportsApp(mt), fiber.MethodPost, "/all/question/"+question.Hex()+"/report", `{}`)
+		if res.StatusCode != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestAdminReports(t *testing.T) {
+	admin := auth.User{ID: "user-1", UserType: "admin"}
+	open := reports.Report{ID: primitive.NewObjectID(), QuestionID: primitive.NewObjectID(), UserID: "user-2", Reason: "the link is dead", Status: reports.StatusOpen, CreatedAt: time.Now()}
+
+	dbtest.Run(t, "open reports listed", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, admin), dbtest.Cursor(mt, bson.M{"n": 1}), dbtest.Cursor(mt, open))
+		res, body := send(mt.T, reportsApp(mt), fiber.MethodGet, "/admin/reports", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var page reports.Page
+		if err := json.Unmarshal([]byte(body), &page); err != nil {
+			mt.Fatal(err)
+		}
+		if page.Total != 1 || len(page.Reports) != 1 || page.Reports[0].ID != open.ID {
+			mt.Errorf("page = %+v, want the open report", page)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "aggregate")
+		find := dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, find, "filter", bson.M{"status": reports.StatusOpen})
+		dbtest.ExpectField(mt, find, "sort", bson.M{"createdAt": 1})
+	})
+
+	dbtest.Run(t, "resolved", func(mt *mtest.T) {
+		resolvedAt := time.Now()
+		resolved := open
+		resolved.Status, resolved.ResolvedAt, resolved.ResolvedBy = reports.StatusResolved, &resolvedAt, "user-1"
+		mt.AddMockResponses(dbtest.Cursor(mt, admin), dbtest.Value(mt, resolved))
+		res, body := send(mt.T, reportsApp(mt), fiber.MethodPatch, "/admin/reports/"+open.ID.Hex(), `{"status": " Resolved "}`)
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "find")
+		command := dbtest.NextCommand(mt, "findAndModify")
+		dbtest.ExpectField(mt, command, "query", bson.M{"_id": open.ID})
+		set := command["update"].(bson.M)["$set"].(bson.M)
+		if set["status"] != reports.StatusResolved || set["resolvedBy"] != "user-1" || set["resolvedAt"] == nil {
+			mt.Errorf("$set = %v, want the report resolved by user-1", set)
+		}
+	})
+
+	dbtest.Run(t, "reopened", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, admin), dbtest.Value(mt, open))
+		if res, body := send(mt.T, reportsApp(mt), fiber.MethodPatch, "/admin/reports/"+open.ID.Hex(), `{"status": "open"}`); res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "findAndModify"), "update", bson.M{
+			"$set":   bson.M{"status": reports.StatusOpen},
+			"$unset": bson.M{"resolvedAt": "", "resolvedBy": ""},
+		})
+	})
+
+	dbtest.Run(t, "reopened while another is open", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, admin), dbtest.Failed(11000, "duplicate key"))
+		res, body := send(mt.T, reportsApp(mt), fiber.MethodPatch, "/admin/reports/"+open.ID.Hex(), `{"status": "open"}`)
+		if res.StatusCode != fiber.StatusConflict {
+			mt.Fatalf("status = %d, want 409: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "DUPLICATE_REPORT")
+	})
+
+	dbtest.Run(t, "unknown status", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, admin))
+		if res, body := send(mt.T, reportsApp(mt), fiber.MethodPatch, "/admin/reports/"+open.ID.Hex(), `{"status": "ignored"}`); res.StatusCode != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "not an admin", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, auth.User{ID: "user-1", UserType: "user"}))
+		if res, body := send(mt.T, reportsApp(mt), fiber.MethodGet, "/admin/reports", ""); res.StatusCode != fiber.StatusForbidden {
+			mt.Fatalf("status = %d, want 403: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
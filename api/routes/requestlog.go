@@ -0,0 +1,41 @@
+package routes
+
+import (
+	"sigmacoder/pkg/logging"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// `maxLoggedBody` is the number of bytes of a request body logged at debug level. Longer bodies are
+// cut off.
+const maxLoggedBody = 4096
+
+// The function returns a middleware that logs every request at info level with its ID, method, URL,
+// status and duration. At debug level it also logs JSON and form bodies, up to `maxLoggedBody` bytes.
+// Errors returned by later handlers are answered by the app's error handler first, so the logged status
+// is the one sent. Everything is logged through `logging`, which masks passwords, OTP codes, tokens and
+// credentials in the URL and the body. It should run after the request ID middleware, so the logged ID
+// matches the `X-Request-ID` of the response.
+func RequestLogger() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		contentType := c.Get(fiber.HeaderContentType)
+		if logging.Enabled(logging.LevelDebug) && (strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) || strings.HasPrefix(contentType, fiber.MIMEApplicationForm)) {
+			body := c.Body()
+			if len(body) > maxLoggedBody {
+				body = body[:maxLoggedBody]
+			}
+			logging.Debugf("http: request %s body: %s", c.GetRespHeader(fiber.HeaderXRequestID), body)
+		}
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		logging.Infof("http: %s %s %s %d %s", c.GetRespHeader(fiber.HeaderXRequestID), c.Method(), c.OriginalURL(),
+			c.Response().StatusCode(), time.Since(start))
+		return nil
+	}
+}
@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sigmacoder/pkg/logging"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+// The function makes the default logger write records of `level` and above to the returned buffer,
+// until the test ends.
+func captureLogs(t *testing.T, level logging.Level) *bytes.Buffer {
+	t.Helper()
+	var logged bytes.Buffer
+	logger, err := logging.New(&logged, level, logging.FormatConsole)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logging.SetDefault(logger)
+	t.Cleanup(func() {
+		logger, _ := logging.New(os.Stderr, logging.LevelInfo, logging.FormatConsole)
+		logging.SetDefault(logger)
+	})
+	return &logged
+}
+
+func TestRequestLogger(t *testing.T) {
+	newApp := func() *fiber.App {
+		app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		app.Use(requestid.New())
+		app.Use(RequestLogger())
+		app.Post("/auth/login", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+		app.Post("/auth/verify", func(c *fiber.Ctx) error {
+			return fiber.NewError(fiber.StatusBadRequest, `invalid body {"code":"123456"}`)
+		})
+		return app
+	}
+	const login = `{"email":"ada@example.com","password":"hunter22"}`
+
+	t.Run("password in a body masked at debug", func(t *testing.T) {
+		logged := captureLogs(t, logging.LevelDebug)
+		res, body := send(t, newApp(), fiber.MethodPost, "/auth/login", login)
+		if res.StatusCode != fiber.StatusNoContent {
+			t.Fatalf("status = %d, want 204: %s", res.StatusCode, body)
+		}
+		id := res.Header.Get(fiber.HeaderXRequestID)
+		if !strings.Contains(logged.String(), `request `+id+` body: {"email":"ada@example.com","password":"[REDACTED]"}`) {
+			t.Errorf("logged %q, want the body logged with the password masked", logged.String())
+		}
+		if strings.Contains(logged.String(), "hunter22") {
+			t.Errorf("logged %q, want the password kept out", logged.String())
+		}
+	})
+
+	t.Run("body not logged at info", func(t *testing.T) {
+		logged := captureLogs(t, logging.LevelInfo)
+		res, _ := send(t, newApp(), fiber.MethodPost, "/auth/login?token=abc123", login)
+		id := res.Header.Get(fiber.HeaderXRequestID)
+		if got := logged.String(); strings.Contains(got, "body:") || !strings.Contains(got, "http: "+id+" POST /auth/login?token=[REDACTED] 204") {
+			t.Errorf("logged %q, want only the request line, with the token masked", got)
+		}
+	})
+
+	t.Run("error answered before logging", func(t *testing.T) {
+		logged := captureLogs(t, logging.LevelInfo)
+		res, body := send(t, newApp(), fiber.MethodPost, "/auth/verify", `{"code":"123456"}`)
+		if res.StatusCode != fiber.StatusBadRequest {
+			t.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+		}
+		if strings.Contains(body, "123456") {
+			t.Errorf("body = %s, want the code masked in the error", body)
+		}
+		if !strings.Contains(logged.String(), "/auth/verify 400") {
+			t.Errorf("logged %q, want the status sent by the error handler", logged.String())
+		}
+	})
+}
+
+func TestErrorHandlerRedacts(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Post("/auth/reset", func(c *fiber.Ctx) error {
+		return errors.New(`write failed: {"newPassword":"hunter22","email":"ada@example.com"}`)
+	})
+	res, body := send(t, app, fiber.MethodPost, "/auth/reset", `{}`)
+	if res.StatusCode != fiber.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500: %s", res.StatusCode, body)
+	}
+	expectCode(t, body, "INTERNAL_ERROR")
+	if strings.Contains(body, "hunter22") || !strings.Contains(body, "ada@example.com") {
+		t.Errorf("body = %s, want the password masked and the rest kept", body)
+	}
+}
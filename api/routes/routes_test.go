@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// The function sends a request to the app and returns the response together with its body. A non-empty
+// `body` is sent as JSON. Each pair of `headers` is a header name followed by its value.
+func send(t *testing.T, app *fiber.App, method string, target string, body string, headers ...string) (*http.Response, string) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	res, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, target, err)
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("%s %s: reading the body: %v", method, target, err)
+	}
+	return res, string(data)
+}
+
+// The function returns a middleware that stands in for jwtware: it stores a validated token with the
+// given claims, as if the request had carried it.
+func authenticated(claims jwt.MapClaims) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals("user", &jwt.Token{Claims: claims, Valid: true})
+		return c.Next()
+	}
+}
@@ -0,0 +1,132 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/sessions"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// The type `sessionView` is a session as it is listed to its owner.
+// @property Current - Whether this is the session the listing request was made with.
+type sessionView struct {
+	sessions.Session
+	Current bool `json:"current"`
+}
+
+// The type `sessionErrorResponse` documents the body returned by the session handlers when a request
+// fails.
+type sessionErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// The function describes the client making the request, for the session its token is issued under.
+func deviceFromRequest(c *fiber.Ctx) sessions.Device {
+	return sessions.Device{UserAgent: c.Get(fiber.HeaderUserAgent), IP: clientIP(c)}
+}
+
+// The function returns the `sid` claim of the request's token, and false for tokens issued without a
+// session.
+func sessionIDFromToken(c *fiber.Ctx) (string, bool) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return "", false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", false
+	}
+	sessionID, ok := claims["sid"].(string)
+	return sessionID, ok && sessionID != ""
+}
+
+// The function returns a middleware that rejects tokens whose session has been revoked or has expired,
+// and records when each session was last used. Tokens issued before sessions existed carry no `sid`
+// and are let through until they expire. It must be registered after the jwtware middleware.
+func RequireActiveSession(repo *sessions.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sessionID, ok := sessionIDFromToken(c)
+		if !ok {
+			return c.Next()
+		}
+		err := repo.WithContext(c.UserContext()).Touch(sessionID)
+		if errors.Is(err, pkg.ErrSessionRevoked) {
+			return sendError(c, fiber.StatusUnauthorized, err)
+		}
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Next()
+	}
+}
+
+// The function `listSessionsHandler` returns the authenticated user's active sessions.
+//
+//	@Summary	List your active sessions
+//	@Tags		sessions
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Success	200	{array}		sessionView
+//	@Failure	401	{object}	sessionErrorResponse
+//	@Failure	500	{object}	sessionErrorResponse
+//	@Router		/auth/me/sessions [get]
+func listSessionsHandler(repo *sessions.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		list, err := repo.WithContext(c.UserContext()).ListActive(userID)
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		current, _ := sessionIDFromToken(c)
+		views := make([]sessionView, 0, len(list))
+		for _, session := range list {
+			views = append(views, sessionView{Session: session, Current: session.ID == current})
+		}
+		return c.Status(fiber.StatusOK).JSON(views)
+	}
+}
+
+// The function `revokeSessionHandler` revokes one of the authenticated user's sessions. Requests made
+// with the revoked session's token are rejected from then on.
+//
+//	@Summary	Revoke one of your sessions
+//	@Tags		sessions
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id	path	string	true	"Session ID"
+//	@Success	204
+//	@Failure	401	{object}	sessionErrorResponse
+//	@Failure	404	{object}	sessionErrorResponse
+//	@Failure	500	{object}	sessionErrorResponse
+//	@Router		/auth/me/sessions/{id} [delete]
+func revokeSessionHandler(repo *sessions.Repo, auditLog *audit.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		err := repo.WithContext(c.UserContext()).Revoke(userID, c.Params("id"))
+		if errors.Is(err, pkg.ErrSessionNotFound) {
+			return sendError(c, fiber.StatusNotFound, err)
+		}
+		if err != nil {
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		recordAudit(c, auditLog, audit.Event{Type: audit.EventSessionRevoked, UserID: userID, Metadata: map[string]string{"session": c.Params("id")}})
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// The function creates the routes for listing and revoking the authenticated user's sessions.
+// Revocations are recorded in the audit log.
+func CreateSessionRoutes(router fiber.Router, repo *sessions.Repo, auditLog *audit.Repo) {
+	router.Get("/auth/me/sessions", listSessionsHandler(repo))
+	router.Delete("/auth/me/sessions/:id", revokeSessionHandler(repo, auditLog))
+}
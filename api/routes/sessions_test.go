@@ -0,0 +1,96 @@
+package routes
+
+import (
+	"encoding/json"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/sessions"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns an app serving the session routes to the user of the given token claims, with
+// the repositories talking to the mocked deployment.
+func sessionsApp(mt *mtest.T, claims jwt.MapClaims) *fiber.App {
+	app := fiber.New()
+	repo := sessions.NewRepo(mt.DB).(*sessions.Repo)
+	api := app.Group("/api", authenticated(claims), RequireActiveSession(repo))
+	CreateSessionRoutes(api, repo, audit.NewRepo(mt.DB).(*audit.Repo))
+	return app
+}
+
+func TestSessions(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	phone := sessions.Session{ID: "session-1", UserID: "user-1", UserAgent: "phone", IP: "203.0.113.7", LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+	laptop := sessions.Session{ID: "session-2", UserID: "user-1", UserAgent: "laptop", IP: "198.51.100.2", LastUsedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}
+	claims := jwt.MapClaims{"userid": "user-1", "sid": phone.ID}
+
+	dbtest.Run(t, "listing", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, phone), dbtest.Cursor(mt, phone, laptop))
+		res, body := send(mt.T, sessionsApp(mt, claims), fiber.MethodGet, "/api/auth/me/sessions", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": phone.ID})
+		find := dbtest.NextCommand(mt, "find")
+		if filter := find["filter"].(bson.M); filter["userid"] != "user-1" || filter["revokedAt"] == nil || filter["expiresAt"] == nil {
+			mt.Errorf("filter = %v, want the user's sessions that are neither revoked nor expired", filter)
+		}
+		dbtest.ExpectField(mt, find, "sort", bson.M{"lastUsedAt": -1})
+
+		var views []map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &views); err != nil {
+			mt.Fatal(err)
+		}
+		if len(views) != 2 || views[0]["id"] != phone.ID || views[0]["current"] != true || views[1]["current"] != false {
+			mt.Errorf("body = %s, want both sessions with the phone marked as current", body)
+		}
+		if views[0]["userAgent"] != "phone" || views[0]["ip"] != phone.IP {
+			mt.Errorf("body = %s, want the device of each session", body)
+		}
+	})
+
+	dbtest.Run(t, "revoking another session", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, phone), dbtest.Written(1), dbtest.Written(1))
+		if res, body := send(mt.T, sessionsApp(mt, claims), fiber.MethodDelete, "/api/auth/me/sessions/"+laptop.ID, ""); res.StatusCode != fiber.StatusNoContent {
+			mt.Fatalf("status = %d, want 204: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "find")
+		update := dbtest.NextCommand(mt, "update")["updates"].(bson.A)[0].(bson.M)
+		dbtest.ExpectField(mt, update, "q", bson.M{"_id": laptop.ID, "userid": "user-1", "revokedAt": bson.M{"$exists": false}})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "insert"), "insert", "audit")
+	})
+
+	dbtest.Run(t, "revoking an unknown session", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, phone), dbtest.Written(0))
+		if res, _ := send(mt.T, sessionsApp(mt, claims), fiber.MethodDelete, "/api/auth/me/sessions/someone-elses", ""); res.StatusCode != fiber.StatusNotFound {
+			mt.Errorf("status = %d, want 404", res.StatusCode)
+		}
+	})
+
+	dbtest.Run(t, "revoked session", func(mt *mtest.T) {
+		revokedAt := now.Add(-time.Minute)
+		revoked := phone
+		revoked.RevokedAt = &revokedAt
+		mt.AddMockResponses(dbtest.Cursor(mt, revoked))
+		if res, _ := send(mt.T, sessionsApp(mt, claims), fiber.MethodGet, "/api/auth/me/sessions", ""); res.StatusCode != fiber.StatusUnauthorized {
+			mt.Errorf("status = %d, want 401", res.StatusCode)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "expired session", func(mt *mtest.T) {
+		expired := phone
+		expired.ExpiresAt = now.Add(-time.Minute)
+		mt.AddMockResponses(dbtest.Cursor(mt, expired))
+		if res, _ := send(mt.T, sessionsApp(mt, claims), fiber.MethodGet, "/api/auth/me/sessions", ""); res.StatusCode != fiber.StatusUnauthorized {
+			mt.Errorf("status = %d, want 401", res.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"errors"
+	"net/url"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The type `shareLinkResponse` is the body returned when a share link is created.
+// @property {string} URL - The link that shows the question without logging in.
+// @property ExpiresAt - When the link stops working.
+type shareLinkResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// The function returns the HTTP status of an error returned while resolving a share link.
+func shareErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pkg.ErrInvalidQuestionID):
+		return fiber.StatusBadRequest
+	case errors.Is(err, pkg.ErrInvalidShareToken):
+		return fiber.StatusUnauthorized
+	case errors.Is(err, pkg.ErrShareLinkExpired):
+		return fiber.StatusGone
+	case errors.Is(err, pkg.ErrQuestionNotFound):
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusInternalServerError
+}
+
+// The function reads a published question, answering drafts and archived questions as not found.
+func readPublishedQuestion(c *fiber.Ctx, repo allquestions.Repository, id string) (allquestions.AllQuestion, error) {
+	question, err := repo.WithContext(c.UserContext()).ReadByID(id)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && !question.Published()) {
+		return question, pkg.ErrQuestionNotFound
+	}
+	return question, err
+}
+
+// The function `shareQuestionHandler` creates a signed link to a question that can be opened without
+// logging in until it expires. `baseURL` is the public URL of the API the link points to.
+//
+//	@Summary	Create a share link for a question
+//	@Tags		questions
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id	path		string	true	"Question ID"
+//	@Success	201	{object}	shareLinkResponse
+//	@Failure	400	{object}	questionErrorResponse
+//	@Failure	404	{object}	questionErrorResponse
+//	@Failure	500	{object}	questionErrorResponse
+//	@Router		/all/question/{id}/share [post]
+func shareQuestionHandler(repo allquestions.Repository, signer *allquestions.ShareSigner, baseURL string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		question, err := readPublishedQuestion(c, repo, c.Params("id"))
+		if err != nil {
+			return sendError(c, shareErrorStatus(err), err)
+		}
+		token, expiresAt := signer.Sign(question.ID.Hex(), pkg.NowUTC())
+		link := strings.TrimSuffix(baseURL, "/") + APIPrefix + "/all/shared?token=" + url.QueryEscape(token)
+		return c.Status(fiber.StatusCreated).JSON(shareLinkResponse{URL: link, ExpiresAt: expiresAt})
+	}
+}
+
+// The function `sharedQuestionHandler` returns the question a share link points to. It doesn't require
+// a login; the token's signature is what authorizes the request. Tampered tokens are answered with 401
+// and expired ones with 410.
+//
+//	@Summary	Open a shared question
+//	@Tags		questions
+//	@Produce	json
+//	@Param		token	query		string	true	"Share token"
+//	@Success	200		{object}	allquestions.AllQuestion
+//	@Failure	401		{object}	questionErrorResponse
+//	@Failure	404		{object}	questionErrorResponse
+//	@Failure	410		{object}	questionErrorResponse
+//	@Failure	500		{object}	questionErrorResponse
+//	@Router		/all/shared [get]
+func sharedQuestionHandler(repo allquestions.Repository, signer *allquestions.ShareSigner) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := signer.Verify(c.Query("token"), pkg.NowUTC())
+		if err != nil {
+			return sendError(c, shareErrorStatus(err), err)
+		}
+		question, err := readPublishedQuestion(c, repo, id)
+		if err != nil {
+			return sendError(c, shareErrorStatus(err), err)
+		}
+		question.Hints = nil
+		return c.Status(fiber.StatusOK).JSON(question)
+	}
+}
+
+// The function creates the public route that opens shared questions. It has to be registered before
+// the auth routes, so it isn't behind the JWT middleware, and is rate limited per IP. `cache` marks its
+// responses cacheable.
+func CreateSharedQuestionRoutes(router fiber.Router, repo allquestions.Repository, signer *allquestions.ShareSigner, rateLimit fiber.Handler, cache fiber.Handler) {
+	router.Get("/all/shared", rateLimit, cache, sharedQuestionHandler(repo, signer))
+}
+
+// The function creates the route for sharing questions, which requires a login.
+func CreateShareRoutes(router fiber.Router, repo allquestions.Repository, signer *allquestions.ShareSigner, baseURL string) {
+	router.Post("/all/question/:id/share", shareQuestionHandler(repo, signer, baseURL))
+}
@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/url"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/database/dbtest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns an app serving the share routes, with the link creation route open to anyone.
+func shareApp(mt *mtest.T, signer *allquestions.ShareSigner) *fiber.App {
+	next := func(c *fiber.Ctx) error { return c.Next() }
+	repo := allquestions.NewRepo(mt.DB)
+	app := fiber.New()
+	CreateSharedQuestionRoutes(app, repo, signer, next, next)
+	CreateShareRoutes(app, repo, signer, "https://sigmacoder.example/")
+	return app
+}
+
+func TestShareLinks(t *testing.T) {
+	signer := allquestions.NewShareSigner("share-secret", time.Hour)
+	question := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Id: 1, Hints: []string{"use a map"}}
+
+	dbtest.Run(t, "create and open", func(mt *mtest.T) {
+		app := shareApp(mt, signer)
+		mt.AddMockResponses(dbtest.Cursor(mt, question), dbtest.Cursor(mt, question))
+		res, body := send(mt.T, app, fiber.MethodPost, "/all/question/"+question.ID.Hex()+"/share", "")
+		if res.StatusCode != fiber.StatusCreated {
+			mt.Fatalf("status = %d, want 201: %s", res.StatusCode, body)
+		}
+		var link shareLinkResponse
+		if err := json.Unmarshal([]byte(body), &link); err != nil {
+			mt.Fatal(err)
+		}
+		if !strings.HasPrefix(link.URL, "https://sigmacoder.example"+APIPrefix+"/all/shared?token=") {
+			mt.Fatalf("url = %q, want a link to the shared question route", link.URL)
+		}
+		shared, err := url.Parse(link.URL)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		res, body = send(mt.T, app, fiber.MethodGet, "/all/shared?"+shared.RawQuery, "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var opened allquestions.AllQuestion
+		if err := json.Unmarshal([]byte(body), &opened); err != nil {
+			mt.Fatal(err)
+		}
+		if opened.ID != question.ID || len(opened.Hints) != 0 {
+			mt.Errorf("body = %s, want the question without its hints", body)
+		}
+	})
+
+	dbtest.Run(t, "unpublished question", func(mt *mtest.T) {
+		draft := question
+		draft.Status = allquestions.StatusDraft
+		mt.AddMockResponses(dbtest.Cursor(mt, draft))
+		res, body := send(mt.T, shareApp(mt, signer), fiber.MethodPost, "/all/question/"+question.ID.Hex()+"/share", "")
+		if res.StatusCode != fiber.StatusNotFound {
+			mt.Fatalf("status = %d, want 404: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "QUESTION_NOT_FOUND")
+	})
+
+	valid, _ := signer.Sign(question.ID.Hex(), time.Now())
+	expired, _ := signer.Sign(question.ID.Hex(), time.Now().Add(-2*time.Hour))
+	tests := []struct {
+		name   string
+		token  string
+		status int
+		code   string
+	}{
+		{name: "expired token", token: expired, status: fiber.StatusGone, code: "SHARE_LINK_EXPIRED"},
+		{name: "tampered token", token: primitive.NewObjectID().Hex() + valid[strings.Index(valid, "."):], status: fiber.StatusUnauthorized, code: "INVALID_SHARE_TOKEN"},
+		{name: "missing token", token: "", status: fiber.StatusUnauthorized, code: "INVALID_SHARE_TOKEN"},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			res, body := send(mt.T, shareApp(mt, signer), fiber.MethodGet, "/all/shared?token="+url.QueryEscape(tt.token), "")
+			if res.StatusCode != tt.status {
+				mt.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.status, body)
+			}
+			expectCode(mt.T, body, tt.code)
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/solutions"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `solutionErrorResponse` documents the body returned by the solution handlers when a request
+// fails.
+type solutionErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// The function maps an error returned by the solutions service to an HTTP status code.
+func solutionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pkg.ErrInvalidQuestionID), errors.Is(err, pkg.ErrInvalidSolution):
+		return fiber.StatusBadRequest
+	case errors.Is(err, pkg.ErrQuestionNotFound), errors.Is(err, pkg.ErrSolutionNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, pkg.ErrAlreadyUpvoted):
+		return fiber.StatusConflict
+	}
+	return fiber.StatusInternalServerError
+}
+
+// The function `submitSolutionHandler` shares the authenticated user's solution to a question.
+//
+//	@Summary	Submit a solution to a question
+//	@Tags		solutions
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string					true	"Question ID"
+//	@Param		body	body		solutions.InSolution	true	"Solution to share"
+//	@Success	201		{object}	solutions.Solution
+//	@Failure	400		{object}	solutionErrorResponse
+//	@Failure	401		{object}	solutionErrorResponse
+//	@Failure	404		{object}	solutionErrorResponse
+//	@Router		/all/question/{id}/solutions [post]
+func submitSolutionHandler(svc solutions.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body solutions.InSolution
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		solution, err := svc.WithContext(c.UserContext()).Submit(userID, c.Params("id"), body)
+		if err != nil {
+			return sendError(c, solutionErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(solution)
+	}
+}
+
+// The function `listSolutionsHandler` returns the solutions shared for a question, most upvoted first,
+// each with the public profile of its author.
+//
+//	@Summary	List the solutions to a question
+//	@Tags		solutions
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id	path		string	true	"Question ID"
+//	@Success	200	{array}		solutions.SolutionView
+//	@Failure	400	{object}	solutionErrorResponse
+//	@Failure	404	{object}	solutionErrorResponse
+//	@Router		/all/question/{id}/solutions [get]
+func listSolutionsHandler(svc solutions.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		list, err := svc.WithContext(c.UserContext()).List(c.Params("id"))
+		if err != nil {
+			return sendError(c, solutionErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusOK).JSON(list)
+	}
+}
+
+// The function `upvoteSolutionHandler` records the authenticated user's upvote on a solution. Every
+// user can upvote a solution only once.
+//
+//	@Summary	Upvote a solution
+//	@Tags		solutions
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id	path	string	true	"Solution ID"
+//	@Success	204
+//	@Failure	401	{object}	solutionErrorResponse
+//	@Failure	404	{object}	solutionErrorResponse
+//	@Failure	409	{object}	solutionErrorResponse
+//	@Router		/all/solutions/{id}/upvote [post]
+func upvoteSolutionHandler(svc solutions.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		if err := svc.WithContext(c.UserContext()).Upvote(userID, c.Params("id")); err != nil {
+			return sendError(c, solutionErrorStatus(err), err)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// The function creates the routes for sharing, listing and upvoting user-submitted solutions.
+func CreateSolutionRoutes(router fiber.Router, svc solutions.Service) {
+	router.Post("/all/question/:id/solutions", submitSolutionHandler(svc))
+	router.Get("/all/question/:id/solutions", listSolutionsHandler(svc))
+	router.Post("/all/solutions/:id/upvote", upvoteSolutionHandler(svc))
+}
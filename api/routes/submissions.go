@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/submissions"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function maps an error returned by the submissions service to an HTTP status code.
+func submissionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pkg.ErrInvalidQuestionID), errors.Is(err, pkg.ErrInvalidSubmission):
+		return fiber.StatusBadRequest
+	case errors.Is(err, pkg.ErrQuestionNotFound), errors.Is(err, pkg.ErrSubmissionNotFound):
+		return fiber.StatusNotFound
+	}
+	return fiber.StatusInternalServerError
+}
+
+// The function `submitCodeHandler` submits the authenticated user's code to be run against a
+// question's tests. It answers right away with the pending submission; poll
+// `GET /api/v1/all/submissions/{id}` for the verdict.
+//
+//	@Summary	Submit code to be judged
+//	@Tags		submissions
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string						true	"Question ID"
+//	@Param		body	body		submissions.InSubmission	true	"Code to run"
+//	@Success	202		{object}	submissions.Submission
+//	@Failure	400		{object}	errorResponse
+//	@Failure	401		{object}	errorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Router		/all/question/{id}/submit [post]
+func submitCodeHandler(svc submissions.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body submissions.InSubmission
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		submission, err := svc.WithContext(c.UserContext()).Submit(userID, c.Params("id"), body)
+		if err != nil {
+			return sendError(c, submissionErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusAccepted).JSON(submission)
+	}
+}
+
+// The function `submissionHandler` returns one of the authenticated user's submissions with its
+// current status and, once judged, its result.
+//
+//	@Summary	Get a submission
+//	@Tags		submissions
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id	path		string	true	"Submission ID"
+//	@Success	200	{object}	submissions.Submission
+//	@Failure	401	{object}	errorResponse
+//	@Failure	404	{object}	errorResponse
+//	@Router		/all/submissions/{id} [get]
+func submissionHandler(svc submissions.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		submission, err := svc.WithContext(c.UserContext()).Get(userID, c.Params("id"))
+		if err != nil {
+			return sendError(c, submissionErrorStatus(err), err)
+		}
+		return c.Status(fiber.StatusOK).JSON(submission)
+	}
+}
+
+// The function creates the routes for submitting code to be judged and polling the result.
+func CreateSubmissionRoutes(router fiber.Router, svc submissions.Service) {
+	router.Post("/all/question/:id/submit", submitCodeHandler(svc))
+	router.Get("/all/submissions/:id", submissionHandler(svc))
+}
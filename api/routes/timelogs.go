@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/timelogs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `timeLogErrorResponse` documents the body returned by the time tracking handlers when a
+// request fails.
+type timeLogErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// The function `logTimeHandler` records a session the authenticated user spent on a question, given
+// either as its length in `seconds` or as the `startedAt` and `endedAt` times of the client's timer,
+// and returns the time spent on the question so far. Sessions add up, so a question worked on over
+// several sittings is logged once per sitting.
+//
+//	@Summary	Log time spent on a question
+//	@Tags		progress
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id		path		string				true	"Question ID"
+//	@Param		body	body		timelogs.Session	true	"Length, or start and stop times, of the session"
+//	@Success	201		{object}	timelogs.Logged
+//	@Failure	400		{object}	timeLogErrorResponse
+//	@Failure	401		{object}	timeLogErrorResponse
+//	@Failure	404		{object}	timeLogErrorResponse
+//	@Router		/all/question/{id}/time [post]
+func logTimeHandler(svc timelogs.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body timelogs.Session
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		logged, err := svc.WithContext(c.UserContext()).Log(userID, c.Params("id"), body)
+		switch {
+		case errors.Is(err, pkg.ErrInvalidQuestionID), errors.Is(err, pkg.ErrInvalidDuration):
+			return sendError(c, fiber.StatusBadRequest, err)
+		case errors.Is(err, pkg.ErrQuestionNotFound):
+			return sendError(c, fiber.StatusNotFound, err)
+		case err != nil:
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusCreated).JSON(logged)
+	}
+}
+
+// The function creates the route for logging the time users spend on questions. The totals are
+// returned with the user's progress.
+func CreateTimeLogRoutes(router fiber.Router, svc timelogs.Service) {
+	router.Post("/all/question/:id/time", logTimeHandler(svc))
+}
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The function returns a middleware that bounds every request to the given timeout. It derives a
+// context with the deadline from `c.UserContext()` and installs it back on the request, so handlers
+// that pass `c.UserContext()` to the repositories and to outside services, such as the OTP provider,
+// have those calls cancelled once the deadline passes. If the deadline was exceeded by the time the
+// handler returns and the handler didn't complete its response, the response is replaced with a 504
+// Gateway Timeout, which must not be cached even if the route allows caching. A response the handler
+// finished just before the deadline is kept. The handler itself runs on the request's goroutine, since a Fiber
+// context can't be used once the middleware has returned, so a handler has to pass the context on to
+// be answered at the deadline.
+func RequestTimeout(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+		err := c.Next()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !completed(c, err) {
+			c.Set(fiber.HeaderCacheControl, "no-store")
+			return sendError(c, fiber.StatusGatewayTimeout, pkg.ErrRequestTimeout)
+		}
+		return err
+	}
+}
+
+// The function reports whether the handler completed the response: it returned no error and set a
+// successful status, with a body unless the status isn't the default 200. An error status is taken as
+// the handler failing because its calls were cancelled at the deadline.
+func completed(c *fiber.Ctx, err error) bool {
+	if err != nil {
+		return false
+	}
+	status := c.Response().StatusCode()
+	if status >= fiber.StatusBadRequest {
+		return false
+	}
+	return status != fiber.StatusOK || len(c.Response().Body()) > 0
+}
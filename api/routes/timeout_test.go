@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"sigmacoder/pkg/otp"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type blockingProvider is an OTP provider that hangs until its context is done, like a provider
+// that doesn't answer.
+type blockingProvider struct{}
+
+func (blockingProvider) Send(ctx context.Context, phoneNumber string, channel string, deviceIP string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingProvider) Verify(ctx context.Context, phoneNumber string, code string) (string, error) {
+	<-ctx.Done()
+	return otp.UnknownChannel, ctx.Err()
+}
+
+// The function checks that the response is the 504 the timeout middleware answers with, and that it
+// was sent at the deadline rather than whenever the handler would have finished on its own.
+func expectTimeout(t *testing.T, app *fiber.App, method string, target string, body string) {
+	t.Helper()
+	started := time.Now()
+	res, data := send(t, app, method, target, body)
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("the request took %s, want it to end at the 50ms deadline", elapsed)
+	}
+	if res.StatusCode != fiber.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", res.StatusCode)
+	}
+	var envelope struct{ Code string }
+	if err := json.Unmarshal([]byte(data), &envelope); err != nil || envelope.Code != "REQUEST_TIMEOUT" {
+		t.Errorf("body = %s, want the REQUEST_TIMEOUT error", data)
+	}
+	if cache := res.Header.Get(fiber.HeaderCacheControl); cache != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", cache)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	app := fiber.New()
+	app.Use(RequestTimeout(50 * time.Millisecond))
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-c.UserContext().Done():
+			return c.UserContext().Err()
+		}
+		return c.SendString("done")
+	})
+	app.Get("/silent", func(c *fiber.Ctx) error {
+		<-c.UserContext().Done()
+		return nil
+	})
+	app.Get("/finished", func(c *fiber.Ctx) error {
+		err := c.SendString("done")
+		<-c.UserContext().Done()
+		return err
+	})
+	app.Get("/fast", func(c *fiber.Ctx) error {
+		return c.SendString("done")
+	})
+	app.Post("/auth/sendotp", sendSMS(blockingProvider{}, []string{"sms"}, new(otp.DailyQuota), NewOTPStats()))
+
+	t.Run("slow handler", func(t *testing.T) {
+		expectTimeout(t, app, fiber.MethodGet, "/slow", "")
+	})
+	t.Run("provider that doesn't answer", func(t *testing.T) {
+		expectTimeout(t, app, fiber.MethodPost, "/auth/sendotp", `{"phoneNumber": "+15551234567"}`)
+	})
+	t.Run("handler that sets no response", func(t *testing.T) {
+		expectTimeout(t, app, fiber.MethodGet, "/silent", "")
+	})
+	t.Run("response finished before the deadline", func(t *testing.T) {
+		res, body := send(t, app, fiber.MethodGet, "/finished", "")
+		if res.StatusCode != fiber.StatusOK || body != "done" {
+			t.Errorf("got %d %q, want the finished 200 %q kept", res.StatusCode, body, "done")
+		}
+	})
+	t.Run("fast handler", func(t *testing.T) {
+		res, body := send(t, app, fiber.MethodGet, "/fast", "")
+		if res.StatusCode != fiber.StatusOK || body != "done" {
+			t.Errorf("got %d %q, want 200 %q", res.StatusCode, body, "done")
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/auth"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `timezoneBody` is the request body for setting your timezone.
+type timezoneBody struct {
+	Timezone string `json:"timezone" validate:"max=64"`
+}
+
+// The function `setTimezoneHandler` stores the authenticated user's timezone, an IANA name such as
+// "Asia/Kolkata", which their streak, stats and problem of the day are counted in. An empty timezone
+// clears it, so the server's default is used again.
+//
+//	@Summary	Set your timezone
+//	@Tags		account
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		body	body		timezoneBody	true	"IANA timezone, e.g. Asia/Kolkata"
+//	@Success	200		{object}	auth.OutUser
+//	@Failure	400		{object}	errorResponse
+//	@Failure	401		{object}	errorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Router		/auth/me/timezone [put]
+func setTimezoneHandler(svc auth.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := userIDFromToken(c)
+		if !ok {
+			return sendError(c, fiber.StatusUnauthorized, pkg.ErrUnauthorized)
+		}
+		var body timezoneBody
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		if ok, err := validateRequest(c, body); !ok {
+			return err
+		}
+		user, err := svc.WithContext(c.UserContext()).SetTimezone(userID, body.Timezone)
+		switch {
+		case errors.Is(err, pkg.ErrUnknownTimezone):
+			return sendError(c, fiber.StatusBadRequest, err)
+		case errors.Is(err, pkg.ErrUserNotFound):
+			return sendError(c, fiber.StatusNotFound, err)
+		case err != nil:
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(user.ToOutUser())
+	}
+}
+
+// The function returns the timezone calendar days are counted in for the authenticated user: the one
+// given by the `tz` query parameter, else the one stored with `PUT /auth/me/timezone`, else
+// `defaultTimezone`. It returns `pkg.ErrUnknownTimezone` when `tz` isn't a known timezone.
+func userLocation(c *fiber.Ctx, userRepo *auth.Repo, userID string, defaultTimezone string) (*time.Location, error) {
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, pkg.ErrUnknownTimezone
+		}
+		return loc, nil
+	}
+	fallback, err := time.LoadLocation(defaultTimezone)
+	if err != nil {
+		return nil, pkg.ErrUnknownTimezone
+	}
+	user, err := userRepo.WithContext(c.UserContext()).ReadByID(userID)
+	if err != nil {
+		return fallback, nil
+	}
+	return user.Location(fallback), nil
+}
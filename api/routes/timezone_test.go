@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"encoding/json"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns an app serving PUT /auth/me/timezone for "user-1" from the mocked deployment.
+func timezoneApp(mt *mtest.T) *fiber.App {
+	app := fiber.New()
+	app.Put("/auth/me/timezone", authenticated(jwt.MapClaims{"userid": "user-1"}), setTimezoneHandler(auth.NewAuthService(auth.NewRepo(mt.DB).(*auth.Repo))))
+	return app
+}
+
+func TestSetTimezone(t *testing.T) {
+	dbtest.Run(t, "valid timezone", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Value(mt, auth.User{ID: "user-1", Email: "ada@example.com"}))
+		res, body := send(mt.T, timezoneApp(mt), fiber.MethodPut, "/auth/me/timezone", `{"timezone":"Asia/Kolkata"}`)
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var user auth.OutUser
+		if err := json.Unmarshal([]byte(body), &user); err != nil {
+			mt.Fatal(err)
+		}
+		if user.ID != "user-1" || user.Timezone != "Asia/Kolkata" {
+			mt.Errorf("user = %+v, want user-1 in Asia/Kolkata", user)
+		}
+	})
+
+	for _, timezone := range []string{"Mars/Olympus", "Local"} {
+		dbtest.Run(t, "invalid "+timezone, func(mt *mtest.T) {
+			res, body := send(mt.T, timezoneApp(mt), fiber.MethodPut, "/auth/me/timezone", `{"timezone":"`+timezone+`"}`)
+			if res.StatusCode != fiber.StatusBadRequest {
+				mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+			}
+			expectCode(mt.T, body, "UNKNOWN_TIMEZONE")
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+
+	dbtest.Run(t, "unknown user", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Value(mt, nil))
+		res, body := send(mt.T, timezoneApp(mt), fiber.MethodPut, "/auth/me/timezone", `{"timezone":"UTC"}`)
+		if res.StatusCode != fiber.StatusNotFound {
+			mt.Fatalf("status = %d, want 404: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "USER_NOT_FOUND")
+	})
+}
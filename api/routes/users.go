@@ -0,0 +1,133 @@
+package routes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/account"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/auth"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `purgeResponse` reports how many documents were removed from each collection when a user
+// was purged, and under `solutions.upvoters` how many solutions their upvote was withdrawn from.
+type purgeResponse struct {
+	Removed map[string]int64 `json:"removed"`
+}
+
+// The function `purgeUserHandler` permanently deletes a user and all of their data.
+//
+//	@Summary	Purge a user and all of their data
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		id	path		string	true	"User ID"
+//	@Success	200	{object}	purgeResponse
+//	@Failure	404	{object}	errorResponse
+//	@Failure	409	{object}	errorResponse
+//	@Failure	500	{object}	errorResponse
+//	@Router		/auth/users/{id}/purge [delete]
+func purgeUserHandler(purger *account.Purger, auditLog *audit.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		removed, err := purger.WithContext(c.UserContext()).Purge(c.Params("id"))
+		switch {
+		case errors.Is(err, pkg.ErrUserNotFound):
+			return sendError(c, fiber.StatusNotFound, err)
+		case errors.Is(err, pkg.ErrLastAdmin):
+			return sendError(c, fiber.StatusConflict, err)
+		case err != nil:
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		actorID, _ := userIDFromToken(c)
+		recordAudit(c, auditLog, audit.Event{Type: audit.EventUserPurged, UserID: c.Params("id"), ActorID: actorID})
+		return c.Status(fiber.StatusOK).JSON(purgeResponse{Removed: removed})
+	}
+}
+
+// The function `userByEmailHandler` looks up a user by email address for support staff. The email is
+// normalized the same way as at sign up and login and matched ignoring case. Only the `OutUser`
+// projection is returned, never the password hash.
+//
+//	@Summary	Look up a user by email
+//	@Tags		admin
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		email	query		string	true	"Email address"
+//	@Success	200		{object}	auth.OutUser
+//	@Failure	400		{object}	errorResponse
+//	@Failure	404		{object}	errorResponse
+//	@Router		/auth/users/by-email [get]
+func userByEmailHandler(repo *auth.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		email := auth.NormalizeEmail(c.Query("email"))
+		if email == "" {
+			return sendError(c, fiber.StatusBadRequest, errors.New("email is required"))
+		}
+		user, err := repo.WithContext(c.UserContext()).ReadByEmail(email)
+		if err != nil {
+			return sendError(c, fiber.StatusNotFound, err)
+		}
+		return c.Status(fiber.StatusOK).JSON(user.ToOutUser())
+	}
+}
+
+// The type `setRolesBody` is the request body for assigning a role to many users.
+type setRolesBody struct {
+	UserIDs []string `json:"user_ids" validate:"required,min=1,max=1000,dive,required,max=64"`
+	Role    string   `json:"role" validate:"required,max=20"`
+}
+
+// The type `setRolesResponse` reports how many users got a new role.
+type setRolesResponse struct {
+	Modified int `json:"modified"`
+}
+
+// The function `setRolesHandler` gives every listed user the role, for example to promote a cohort of
+// moderators at once. Users that already have the role and unknown IDs are skipped. Demoting the last
+// remaining admin is refused. Every change is recorded in the audit log.
+//
+//	@Summary	Assign a role to many users
+//	@Tags		admin
+//	@Accept		json
+//	@Produce	json
+//	@Security	BearerAuth
+//	@Param		body	body		setRolesBody	true	"Users and the role to give them"
+//	@Success	200		{object}	setRolesResponse
+//	@Failure	400		{object}	errorResponse
+//	@Failure	409		{object}	errorResponse
+//	@Failure	500		{object}	errorResponse
+//	@Router		/auth/users/roles [post]
+func setRolesHandler(userRepo *auth.Repo, auditLog *audit.Repo) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body setRolesBody
+		if err := decodeBody(c, &body); err != nil {
+			return sendError(c, fiber.StatusBadRequest, err)
+		}
+		if ok, err := validateRequest(c, body); !ok {
+			return err
+		}
+		changed, err := userRepo.WithContext(c.UserContext()).SetRoles(body.UserIDs, body.Role)
+		switch {
+		case errors.Is(err, pkg.ErrInvalidRole):
+			return sendError(c, fiber.StatusBadRequest, err)
+		case errors.Is(err, pkg.ErrLastAdmin):
+			return sendError(c, fiber.StatusConflict, err)
+		case err != nil:
+			return sendError(c, fiber.StatusInternalServerError, err)
+		}
+		actorID, _ := userIDFromToken(c)
+		for _, userID := range changed {
+			recordAudit(c, auditLog, audit.Event{Type: audit.EventRoleChanged, UserID: userID, ActorID: actorID, Metadata: map[string]string{"role": body.Role}})
+		}
+		return c.Status(fiber.StatusOK).JSON(setRolesResponse{Modified: len(changed)})
+	}
+}
+
+// The function creates the admin-only routes for managing user accounts. Purges and role changes are
+// recorded in the audit log.
+func CreateUserAdminRoutes(router fiber.Router, userRepo *auth.Repo, purger *account.Purger, auditLog *audit.Repo) {
+	router.Get("/auth/users/by-email", RequireRole(userRepo, "admin"), userByEmailHandler(userRepo))
+	router.Delete("/auth/users/:id/purge", RequireRole(userRepo, "admin"), purgeUserHandler(purger, auditLog))
+	router.Post("/auth/users/roles", RequireRole(userRepo, "admin"), setRolesHandler(userRepo, auditLog))
+}
@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"encoding/json"
+	"sigmacoder/pkg/account"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns an app serving the admin user routes to the user of the given ID, with the
+// repositories talking to the mocked deployment.
+func userAdminApp(mt *mtest.T, userID string) *fiber.App {
+	app := fiber.New()
+	userRepo := auth.NewRepo(mt.DB).(*auth.Repo)
+	CreateUserAdminRoutes(app.Group("/api", authenticated(jwt.MapClaims{"userid": userID})), userRepo, account.NewPurger(mt.DB), audit.NewRepo(mt.DB).(*audit.Repo))
+	return app
+}
+
+func TestUserByEmail(t *testing.T) {
+	admin := auth.User{ID: "admin-1", UserType: "admin"}
+	ada := auth.User{ID: "user-1", Name: "Ada", Email: "ada@example.com", Password: "$2a$10$secrethash", UserType: "user"}
+
+	dbtest.Run(t, "found ignoring case", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, admin), dbtest.Cursor(mt, ada))
+		res, body := send(mt.T, userAdminApp(mt, admin.ID), fiber.MethodGet, "/api/auth/users/by-email?email=%20Ada@Example.COM%20", "")
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": admin.ID})
+		find := dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, find, "filter", bson.M{"email": "Ada@Example.COM"})
+		dbtest.ExpectField(mt, find, "collation", bson.M{"locale": "en", "strength": int32(2)})
+
+		var user map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &user); err != nil {
+			mt.Fatal(err)
+		}
+		if user["id"] != ada.ID || user["email"] != ada.Email {
+			mt.Errorf("body = %s, want Ada", body)
+		}
+		if _, ok := user["password"]; ok || strings.Contains(body, ada.Password) {
+			mt.Errorf("body = %s, want no password hash", body)
+		}
+	})
+
+	dbtest.Run(t, "not found", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, admin), dbtest.Cursor(mt))
+		res, body := send(mt.T, userAdminApp(mt, admin.ID), fiber.MethodGet, "/api/auth/users/by-email?email=nobody@example.com", "")
+		if res.StatusCode != fiber.StatusNotFound {
+			mt.Errorf("status = %d, want 404", res.StatusCode)
+		}
+		expectCode(mt.T, body, "USER_NOT_FOUND")
+	})
+
+	dbtest.Run(t, "no email", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, admin))
+		if res, _ := send(mt.T, userAdminApp(mt, admin.ID), fiber.MethodGet, "/api/auth/users/by-email?email=%20", ""); res.StatusCode != fiber.StatusBadRequest {
+			mt.Errorf("status = %d, want 400", res.StatusCode)
+		}
+	})
+
+	dbtest.Run(t, "not an admin", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, ada))
+		if res, _ := send(mt.T, userAdminApp(mt, ada.ID), fiber.MethodGet, "/api/auth/users/by-email?email=ada@example.com", ""); res.StatusCode != fiber.StatusForbidden {
+			mt.Errorf("status = %d, want 403", res.StatusCode)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestSetRoles(t *testing.T) {
+	admin := auth.User{ID: "admin-1", UserType: "admin"}
+
+	dbtest.Run(t, "bulk promotion", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, admin),
+			dbtest.Cursor(mt),
+			dbtest.Cursor(mt, bson.M{"_id": "user-1"}, bson.M{"_id": "user-2"}),
+			dbtest.Written(2),
+			mtest.CreateSuccessResponse(),
+			dbtest.Written(1),
+			dbtest.Written(1),
+		)
+		res, body := send(mt.T, userAdminApp(mt, admin.ID), fiber.MethodPost, "/api/auth/users/roles", `{"user_ids":["user-1","user-2","user-3"],"role":"moderator"}`)
+		if res.StatusCode != fiber.StatusOK {
+			mt.Fatalf("status = %d, want 200: %s", res.StatusCode, body)
+		}
+		var modified setRolesResponse
+		if err := json.Unmarshal([]byte(body), &modified); err != nil {
+			mt.Fatal(err)
+		}
+		if modified.Modified != 2 {
+			mt.Errorf("modified = %d, want 2", modified.Modified)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "aggregate")
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "update")["updates"].(bson.A)[0].(bson.M), "u", bson.M{"$set": bson.M{"usertype": "moderator"}})
+		dbtest.NextCommand(mt, "commitTransaction")
+		for _, userID := range []string{"user-1", "user-2"} {
+			event := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+			if event["userid"] != userID || event["actorid"] != admin.ID {
+				mt.Errorf("recorded %v, want the role change of %s by the admin", event, userID)
+			}
+		}
+	})
+
+	dbtest.Run(t, "invalid role", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, admin))
+		res, body := send(mt.T, userAdminApp(mt, admin.ID), fiber.MethodPost, "/api/auth/users/roles", `{"user_ids":["user-1"],"role":"owner"}`)
+		if res.StatusCode != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "INVALID_ROLE")
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "no users", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, admin))
+		if res, body := send(mt.T, userAdminApp(mt, admin.ID), fiber.MethodPost, "/api/auth/users/roles", `{"user_ids":[],"role":"moderator"}`); res.StatusCode != fiber.StatusBadRequest {
+			mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "last admin", func(mt *mtest.T) {
+		counted := mtest.CreateCursorResponse(0, dbtest.Namespace, mtest.FirstBatch, bson.D{{Key: "n", Value: 1}})
+		mt.AddMockResponses(dbtest.Cursor(mt, admin), counted, counted, mtest.CreateSuccessResponse())
+		res, body := send(mt.T, userAdminApp(mt, admin.ID), fiber.MethodPost, "/api/auth/users/roles", `{"user_ids":["admin-1"],"role":"user"}`)
+		if res.StatusCode != fiber.StatusConflict {
+			mt.Fatalf("status = %d, want 409: %s", res.StatusCode, body)
+		}
+		expectCode(mt.T, body, "LAST_ADMIN")
+	})
+
+	dbtest.Run(t, "not an admin", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, auth.User{ID: "user-1", UserType: "moderator"}))
+		if res, _ := send(mt.T, userAdminApp(mt, "user-1"), fiber.MethodPost, "/api/auth/users/roles", `{"user_ids":["user-1"],"role":"admin"}`); res.StatusCode != fiber.StatusForbidden {
+			mt.Errorf("status = %d, want 403", res.StatusCode)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
@@ -0,0 +1,109 @@
+package routes
+
+import (
+	"errors"
+	"reflect"
+	"sigmacoder/pkg/allquestions"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// The type `fieldError` describes a single field of a request body that failed validation.
+// @property Field - The JSON name of the field.
+// @property Rule - The validation rule it broke, such as "required" or "questionlevel".
+// @property Message - A human-readable explanation.
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// The type `validationErrorResponse` documents the body returned when a request body fails
+// validation.
+type validationErrorResponse struct {
+	Error  string       `json:"error"`
+	Code   string       `json:"code"`
+	Fields []fieldError `json:"fields"`
+}
+
+// The function creates the validator used for request bodies. Field errors are reported under the
+// fields' JSON names, and the question enums are available as the `questionlevel`, `questioncategory`
+// and `questionstatus` rules.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" || name == "" {
+			return field.Name
+		}
+		return name
+	})
+	v.RegisterValidation("questionlevel", func(fl validator.FieldLevel) bool {
+		return allquestions.ValidLevel(fl.Field().String())
+	})
+	v.RegisterValidation("questioncategory", func(fl validator.FieldLevel) bool {
+		return allquestions.ValidCategory(fl.Field().String())
+	})
+	v.RegisterValidation("questionstatus", func(fl validator.FieldLevel) bool {
+		return allquestions.ValidStatus(fl.Field().String())
+	})
+	return v
+}
+
+// The function returns the message explaining a failed validation rule.
+func fieldErrorMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return err.Field() + " is required"
+	case "max":
+		return err.Field() + " must be at most " + err.Param() + " characters long"
+	case "excludes":
+		return err.Field() + " must not contain " + err.Param()
+	case "questionlevel":
+		return err.Field() + " must be one of " + strings.Join(allquestions.LevelEnum, ", ")
+	case "questioncategory":
+		return err.Field() + " must be one of " + strings.Join(allquestions.CategoryEnum, ", ")
+	case "questionstatus":
+		return err.Field() + " must be one of " + strings.Join(allquestions.StatusEnum, ", ")
+	}
+	return err.Field() + " is invalid"
+}
+
+// The function checks a parsed request body against its `validate` tags and returns one entry per
+// invalid field, or none when it is valid. The error is only set when the body couldn't be validated
+// at all.
+func fieldErrors(body interface{}) ([]fieldError, error) {
+	err := validate.Struct(body)
+	if err == nil {
+		return nil, nil
+	}
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return nil, err
+	}
+	fields := make([]fieldError, 0, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		fields = append(fields, fieldError{Field: fieldErr.Field(), Rule: fieldErr.Tag(), Message: fieldErrorMessage(fieldErr)})
+	}
+	return fields, nil
+}
+
+// The function answers with 400, the `VALIDATION_FAILED` code and the invalid fields.
+func sendValidationError(c *fiber.Ctx, fields []fieldError) error {
+	return c.Status(fiber.StatusBadRequest).JSON(validationErrorResponse{Error: "request body is invalid", Code: "VALIDATION_FAILED", Fields: fields})
+}
+
+// The function validates a parsed request body. When it is invalid, it answers with 400, the
+// `VALIDATION_FAILED` code and one entry per invalid field, and returns false.
+func validateRequest(c *fiber.Ctx, body interface{}) (bool, error) {
+	fields, err := fieldErrors(body)
+	if err != nil {
+		return false, sendError(c, fiber.StatusBadRequest, err)
+	}
+	if len(fields) > 0 {
+		return false, sendValidationError(c, fields)
+	}
+	return true, nil
+}
@@ -0,0 +1,180 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestQuestionValidation(t *testing.T) {
+	valid := map[string]interface{}{"Name": "Two Sum", "Link": "https://leetcode.com/problems/two-sum", "Level": "Easy", "Category": "Arrays"}
+	tests := []struct {
+		name  string
+		field string
+		value interface{}
+		rule  string
+	}{
+		{name: "missing name", field: "Name", value: "", rule: "required"},
+		{name: "name too long", field: "Name", value: strings.Repeat("a", 201), rule: "max"},
+		{name: "missing link", field: "Link", value: "", rule: "required"},
+		{name: "missing level", field: "Level", value: "", rule: "required"},
+		{name: "unknown level", field: "Level", value: "Impossible", rule: "questionlevel"},
+		{name: "level in the wrong case", field: "Level", value: "easy", rule: "questionlevel"},
+		{name: "unknown category", field: "Category", value: "Astrology", rule: "questioncategory"},
+		{name: "unknown status", field: "status", value: "live", rule: "questionstatus"},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			app := fiber.New()
+			app.Post("/all/question", createQuestionHandler(allquestions.NewRepo(mt.DB)))
+			payload := map[string]interface{}{}
+			for key, value := range valid {
+				payload[key] = value
+			}
+			payload[tt.field] = tt.value
+			data, _ := json.Marshal(payload)
+
+			res, body := send(mt.T, app, fiber.MethodPost, "/all/question", string(data))
+			if res.StatusCode != fiber.StatusBadRequest {
+				mt.Fatalf("status = %d, want 400: %s", res.StatusCode, body)
+			}
+			var response validationErrorResponse
+			if err := json.Unmarshal([]byte(body), &response); err != nil {
+				mt.Fatal(err)
+			}
+			if response.Code != "VALIDATION_FAILED" || len(response.Fields) != 1 {
+				mt.Fatalf("body = %s, want a single field error", body)
+			}
+			if field := response.Fields[0]; field.Field != tt.field || field.Rule != tt.rule || field.Message == "" {
+				mt.Errorf("field error = %+v, want %s to fail %s", field, tt.field, tt.rule)
+			}
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+
+	dbtest.Run(t, "valid", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Post("/all/question", createQuestionHandler(allquestions.NewRepo(mt.DB)))
+		mt.AddMockResponses(dbtest.Written(1))
+		data, _ := json.Marshal(valid)
+		if res, body := send(mt.T, app, fiber.MethodPost, "/all/question", string(data)); res.StatusCode != fiber.StatusCreated {
+			mt.Fatalf("status = %d, want 201: %s", res.StatusCode, body)
+		}
+		dbtest.NextCommand(mt, "insert")
+	})
+}
+
+func TestQuestionUpdateValidation(t *testing.T) {
+	dbtest.Run(t, "invalid level", func(mt *mtest.T) {
+		app := fiber.New()
+		app.Put("/all/question/:id", updateQuestionHandler(allquestions.NewRepo(mt.DB)))
+		res, body := send(mt.T, app, fiber.MethodPut, "/all/question/5f1d7f4e2a1b3c4d5e6f7a8b", `{"Name": "Two Sum", "Link": "https://leetcode.com/problems/two-sum", "Level": "Trivial"}`)
+		if res.StatusCode != fiber.StatusBadRequest || !strings.Contains(body, `"field":"Level"`) {
+			mt.Errorf("got %d %s, want 400 for the Level field", res.StatusCode, body)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+// The type lengthLimit is a capped field of a request body: `set` puts a value in the field of a valid
+// body, and `max` is the longest value it accepts.
+type lengthLimit struct {
+	field string
+	max   int
+	set   func(body map[string]interface{}, value string)
+}
+
+// The function returns a length limit on a top level string field.
+func stringLimit(field string, max int) lengthLimit {
+	return lengthLimit{field: field, max: max, set: func(body map[string]interface{}, value string) { body[field] = value }}
+}
+
+// The function checks every limit at its maximum length and one character over, decoding `valid` with
+// the value set into a fresh `out()`.
+func checkLengthLimits(t *testing.T, valid map[string]interface{}, out func() interface{}, limits []lengthLimit) {
+	t.Helper()
+	for _, limit := range limits {
+		for _, length := range []int{limit.max, limit.max + 1} {
+			t.Run(fmt.Sprintf("%s of %d", limit.field, length), func(t *testing.T) {
+				payload := map[string]interface{}{}
+				for key, value := range valid {
+					payload[key] = value
+				}
+				// A multi-byte character, so the limits are checked to count characters rather than bytes.
+				limit.set(payload, strings.Repeat("é", length))
+				data, _ := json.Marshal(payload)
+				body := out()
+				if err := json.Unmarshal(data, body); err != nil {
+					t.Fatal(err)
+				}
+				fields, err := fieldErrors(body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if length == limit.max && len(fields) != 0 {
+					t.Errorf("field errors = %+v, want a value of the maximum length accepted", fields)
+				}
+				if length > limit.max && (len(fields) != 1 || fields[0].Rule != "max") {
+					t.Errorf("field errors = %+v, want %s to fail max", fields, limit.field)
+				}
+			})
+		}
+	}
+}
+
+func TestLengthLimits(t *testing.T) {
+	t.Run("sign up", func(t *testing.T) {
+		checkLengthLimits(t, map[string]interface{}{}, func() interface{} { return &auth.InUser{} }, []lengthLimit{
+			stringLimit("name", 100),
+			stringLimit("password", 256),
+			stringLimit("phonenumber", 20),
+			stringLimit("profilepic", 2048),
+			stringLimit("email", 254),
+			stringLimit("username", 40),
+			stringLimit("dob", 10),
+			stringLimit("gender", 32),
+			stringLimit("usertype", 20),
+			stringLimit("captchaToken", 4096),
+		})
+	})
+
+	t.Run("login", func(t *testing.T) {
+		checkLengthLimits(t, map[string]interface{}{}, func() interface{} { return &auth.AuthBody{} }, []lengthLimit{
+			stringLimit("identifier", 254),
+			stringLimit("email", 254),
+			stringLimit("password", 256),
+		})
+	})
+
+	t.Run("question", func(t *testing.T) {
+		valid := map[string]interface{}{"Name": "Two Sum", "Link": "https://leetcode.com/problems/two-sum", "Level": "Easy"}
+		list := func(field string, max int) lengthLimit {
+			return lengthLimit{field: field, max: max, set: func(body map[string]interface{}, value string) { body[field] = []string{value} }}
+		}
+		resource := func(field string, max int) lengthLimit {
+			return lengthLimit{field: "resources." + field, max: max, set: func(body map[string]interface{}, value string) {
+				entry := map[string]string{"type": allquestions.ResourceProblem, "url": "https://leetcode.com/problems/two-sum"}
+				entry[field] = value
+				body["resources"] = []map[string]string{entry}
+			}}
+		}
+		checkLengthLimits(t, valid, func() interface{} { return &allquestions.AllQuestion{} }, []lengthLimit{
+			stringLimit("Name", 200),
+			stringLimit("Link", 2048),
+			stringLimit("videourl", 2048),
+			list("languages", 32),
+			list("hints", 1000),
+			list("tags", 32),
+			list("companies", 64),
+			resource("url", 2048),
+			{field: "starterCode", max: 20000, set: func(body map[string]interface{}, value string) { body["starterCode"] = map[string]string{"go": value} }},
+		})
+	})
+}
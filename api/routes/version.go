@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// `APIPrefix` is the prefix of the current version of the API. The `Create*Routes` functions register
+// their routes relative to it, on the router returned by `app.Group(APIPrefix)`, so a `/api/v2` can be
+// added next to it later without breaking existing clients.
+const APIPrefix = "/api/v1"
+
+// `versionSegment` matches the version segment of a versioned API path, such as `v1`.
+var versionSegment = regexp.MustCompile(`^v[0-9]+$`)
+
+// The function returns a middleware that keeps the unversioned paths clients used before the API was
+// versioned, such as `/api/auth/login`, working as aliases of the same paths under `APIPrefix`. The
+// request is rewritten before it is routed, and the response carries a `Deprecation` header so
+// clients can notice they should move to the versioned path. It has to be registered before any
+// middleware that looks at the path.
+func LegacyAPIAlias() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path := c.Path()
+		if !strings.HasPrefix(path, "/api/") {
+			return c.Next()
+		}
+		segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/api/"), "/")
+		if versionSegment.MatchString(segment) {
+			return c.Next()
+		}
+		c.Path(APIPrefix + strings.TrimPrefix(path, "/api"))
+		c.Set("Deprecation", "true")
+		return c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLegacyAPIAlias(t *testing.T) {
+	app := fiber.New()
+	app.Use(LegacyAPIAlias())
+	api := app.Group(APIPrefix)
+	api.Get("/all/allquestions", func(c *fiber.Ctx) error {
+		return c.SendString(c.Path())
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		status     int
+		deprecated bool
+	}{
+		{name: "versioned path", path: APIPrefix + "/all/allquestions", status: fiber.StatusOK},
+		{name: "legacy path", path: "/api/all/allquestions", status: fiber.StatusOK, deprecated: true},
+		{name: "unknown version", path: "/api/v9/all/allquestions", status: fiber.StatusNotFound},
+		{name: "outside the api", path: "/all/allquestions", status: fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := send(t, app, fiber.MethodGet, tt.path, "")
+			if res.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d: %s", res.StatusCode, tt.status, body)
+			}
+			if tt.status == fiber.StatusOK && body != APIPrefix+"/all/allquestions" {
+				t.Errorf("routed to %s, want %s", body, APIPrefix+"/all/allquestions")
+			}
+			if deprecated := res.Header.Get("Deprecation") == "true"; deprecated != tt.deprecated {
+				t.Errorf("Deprecation header set = %v, want %v", deprecated, tt.deprecated)
+			}
+		})
+	}
+}
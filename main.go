@@ -7,42 +7,175 @@ import (
 	"log"
 	"os"
 	"sigmacoder/api/routes"
+	_ "sigmacoder/docs"
+	"sigmacoder/pkg/account"
+	"sigmacoder/pkg/activity"
 	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/audit"
 	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/captcha"
+	"sigmacoder/pkg/comments"
 	"sigmacoder/pkg/configuration"
+	"sigmacoder/pkg/daily"
+	"sigmacoder/pkg/database"
+	"sigmacoder/pkg/devices"
+	"sigmacoder/pkg/difficulty"
+	"sigmacoder/pkg/flags"
+	"sigmacoder/pkg/idempotency"
+	"sigmacoder/pkg/logging"
+	"sigmacoder/pkg/mail"
+	"sigmacoder/pkg/notes"
+	"sigmacoder/pkg/otp"
+	"sigmacoder/pkg/progress"
+	"sigmacoder/pkg/reports"
+	"sigmacoder/pkg/retry"
+	"sigmacoder/pkg/seed"
+	"sigmacoder/pkg/sessions"
+	"sigmacoder/pkg/solutions"
+	"sigmacoder/pkg/submissions"
+	"sigmacoder/pkg/timelogs"
+	"sigmacoder/pkg/token"
+	"sigmacoder/pkg/webhook"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/gofiber/swagger"
 	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// The general API information below is read by `swag init` to generate the OpenAPI spec in `docs/`,
+// which is served at `/swagger`. Regenerate it after changing any handler annotations.
+//
+//	@title						SigmaCoder API
+//	@version					1.0
+//	@description				Authentication, OTP and question endpoints of the SigmaCoder backend.
+//	@BasePath					/api/v1
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
+//	@description				JWT issued by register, login or verifyotp, sent as "Bearer <token>".
 func main() {
-	// `app := fiber.New()` is creating a new instance of the Fiber web framework, which will be used to
-	// define and handle HTTP routes for the application.
-	app := fiber.New()
-	// `def` is a variable that holds a CORS (Cross-Origin Resource Sharing) configuration. It specifies
-	// the allowed origins, methods, headers, and credentials for cross-origin requests. In this case, it
-	// allows any origin, all HTTP methods, specific headers, and credentials to be included in the
-	// request. This configuration is used by the `cors.New()` middleware to enable CORS for all routes in
-	// the Fiber application.
-	def := cors.Config{
-		AllowOrigins:     "*",
-		AllowMethods:     "GET,POST,HEAD,PUT,DELETE,PATCH,OPTIONS",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, X-Request-With",
-		AllowCredentials: true,
-	}
-	// `app.Use(cors.New(def))` is adding a CORS middleware to the Fiber application, which allows
-	// cross-origin requests from any origin. `godotenv.Load()` is loading environment variables from a
-	// `.env` file into the application's environment.
-	app.Use(cors.New(def))
+	// `godotenv.Load()` is loading environment variables from a `.env` file into the application's
+	// environment, and `configuration.Load()` reads the application configuration from them, falling
+	// back to the file named by `CONFIG_FILE` for the variables that aren't set. This has to happen
+	// before the Fiber app is created, since the app itself is configured from it.
 	godotenv.Load()
-	// `config := configuration.FromEnv()` is loading the application configuration from environment
-	// variables using the `FromEnv()` method of the `configuration` package. This allows the application
-	// to read configuration values such as the MongoDB URI and the application port from environment
-	// variables, which can be set differently depending on the deployment environment.
-	config := configuration.FromEnv()
+	config, err := configuration.Load()
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	// `logging.SetDefault(...)` logs records of `LOG_LEVEL` and above in `LOG_FORMAT`, typically info in
+	// JSON in production and debug on the console locally. The standard logger, which libraries write to,
+	// is routed through it at info level.
+	logLevel, err := logging.ParseLevel(config.LogLevel)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	logger, err := logging.New(os.Stderr, logLevel, config.LogFormat)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	logging.SetDefault(logger)
+	// The credentials below are masked wherever they would show up in a log record or an error message.
+	logging.AddSecrets(config.TwilioAccountSID, config.TwilioAuthToken, config.JwtSecret, config.SMTPPassword,
+		config.PasswordPepper, config.CaptchaSecret, config.SignupWebhookSecret, config.ShareLinkSecret)
+	log.SetFlags(0)
+	log.SetOutput(logger.Writer(logging.LevelInfo))
+	// `APP_ENV` names the environment; settings meant for testing, such as `OTP_TEST_CODE`, are only
+	// accepted in development and test.
+	if config.AppEnv != "production" && config.AppEnv != "development" && config.AppEnv != "test" {
+		logging.Fatalf("APP_ENV must be %q, %q or %q, got %q", "production", "development", "test", config.AppEnv)
+	}
+	// `auth.PasswordPepper` is mixed into every password before hashing. Rotating `PASSWORD_PEPPER`
+	// invalidates all existing passwords.
+	auth.PasswordPepper = config.PasswordPepper
+	// `auth.PasswordHash` is the algorithm new password hashes are created with. Users whose password
+	// was hashed with the other one are migrated when they next log in.
+	if config.PasswordHash != auth.HashArgon2id && config.PasswordHash != auth.HashBcrypt {
+		logging.Fatalf("PASSWORD_HASH must be %q or %q, got %q", auth.HashArgon2id, auth.HashBcrypt, config.PasswordHash)
+	}
+	auth.PasswordHash = config.PasswordHash
+	// `auth.DefaultCountryCode` completes the phone numbers OTPs are sent to when they are given in their
+	// local format.
+	auth.DefaultCountryCode = config.PhoneCountryCode
+	// New users always get `DEFAULT_USER_TYPE`, so it must not hand out admin rights.
+	if config.DefaultUserType == "" || config.DefaultUserType == "admin" {
+		logging.Fatalf("DEFAULT_USER_TYPE must be a role other than %q, got %q", "admin", config.DefaultUserType)
+	}
+	// `auth.Roles` are the roles admins can assign, which include `DEFAULT_USER_TYPE`.
+	if !auth.ValidRole(config.DefaultUserType) {
+		auth.Roles = append(auth.Roles, config.DefaultUserType)
+	}
+	// `auth.DeletionGracePeriod` is how long accounts stay restorable after their user asked for them to
+	// be deleted.
+	auth.DeletionGracePeriod = config.AccountDeletionGrace
+	// `auth.PasswordMaxAge` is how long a password lasts before logging in with it only allows changing
+	// it.
+	auth.PasswordMaxAge = config.PasswordMaxAge
+	// `auth.RevokeSessionsOnPasswordChange` is whether a password change logs the user out everywhere.
+	auth.RevokeSessionsOnPasswordChange = config.PasswordChangeLogout
+	// `tokenKeys` sign and check the API's tokens with `JWT_ALG`: HS256 with `JWT_SECRET`, or RS256 with
+	// the private key in `JWT_PRIVATE_KEY_FILE`, whose public key is published at
+	// `/.well-known/jwks.json` together with the rotated out keys in `JWT_PREVIOUS_KEY_FILES`. A missing
+	// or unreadable key stops startup. Tokens carry `JWT_ISSUER` and `JWT_AUDIENCE`, when set, and are
+	// only accepted with them.
+	tokenKeys, err := token.Load(config.JwtAlgorithm, config.JwtSecret, config.JwtPrivateKeyFile, config.JwtPreviousKeyFiles)
+	if err != nil {
+		logging.Fatalf("JWT_ALG: %v", err)
+	}
+	tokenKeys.SetClaims(config.JwtIssuer, config.JwtAudience)
+	// `routes.SetPageSizes(...)` sets the default and maximum page sizes shared by the list endpoints.
+	routes.SetPageSizes(config.DefaultPageSize, config.MaxPageSize)
+	// `app := fiber.New(...)` is creating a new instance of the Fiber web framework, which will be used to
+	// define and handle HTTP routes for the application. `X-Forwarded-For` is only honored when the
+	// request comes from one of the `TRUSTED_PROXIES`, so `c.IP()` can't be spoofed by clients that
+	// connect directly. The read, write and idle timeouts keep slow or idle clients from holding
+	// connections open indefinitely. `routes.ErrorHandler` answers errors that reach Fiber with the same
+	// JSON envelope, including the error `code`, as the handlers use.
+	app := fiber.New(fiber.Config{
+		AppName:                 config.AppName,
+		DisableStartupMessage:   config.DisableStartupMessage,
+		ReadTimeout:             config.ReadTimeout,
+		WriteTimeout:            config.WriteTimeout,
+		IdleTimeout:             config.IdleTimeout,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          config.TrustedProxies,
+		ProxyHeader:             fiber.HeaderXForwardedFor,
+		ErrorHandler:            routes.ErrorHandler,
+	})
+	// `requestid.New()` tags every request with an `X-Request-ID`, `routes.RequestLogger()` logs each
+	// request under that ID, and `routes.Recover()` answers a panicking handler with a 500 instead of
+	// crashing, logging the panic under the same ID.
+	app.Use(requestid.New())
+	app.Use(routes.RequestLogger())
+	app.Use(routes.Recover())
+	// With `HTTPS_REDIRECT` enabled, requests that reached the proxy over plain HTTP are redirected to
+	// HTTPS before anything else runs.
+	if config.HTTPSRedirect {
+		app.Use(routes.HTTPSRedirect())
+	}
+	// `routes.LegacyAPIAlias()` serves the unversioned `/api/...` paths clients used before the API was
+	// versioned as aliases of `/api/v1/...`. It rewrites the path, so it runs before every middleware
+	// that looks at it.
+	app.Use(routes.LegacyAPIAlias())
+	// `routes.UseCORS(...)` lets the public question API be called from `CORS_PUBLIC_ORIGINS`, and the
+	// rest of the API only from the stricter `CORS_AUTH_ORIGINS`.
+	routes.UseCORS(app, config.CORSPublicOrigins, config.CORSAuthOrigins)
+	// `routes.Compress(...)` encodes responses for clients that send `Accept-Encoding`, which mostly
+	// matters for the question lists.
+	if config.CompressEnabled {
+		app.Use(routes.Compress(config.CompressLevel))
+	}
+	// `app.Use(routes.RequestTimeout(...))` bounds every request to `REQUEST_TIMEOUT`, answering with 504
+	// when a handler runs past it.
+	app.Use(routes.RequestTimeout(config.RequestTimeout))
+	// `app.Use(routes.NoStore())` keeps every response out of caches unless its route allows caching.
+	// The read-only question routes do, for `QUESTION_CACHE_MAX_AGE`, through `questionCache`.
+	app.Use(routes.NoStore())
+	questionCache := routes.CacheFor(config.QuestionCacheMaxAge)
 	// This code is establishing a connection to a MongoDB database using the MongoDB Go driver. It creates
 	// a new client instance using the `mongo.Connect()` method, passing in a context and options for the
 	// client. The `config.MongoURI` value is used to specify the URI for the MongoDB database. If an error
@@ -55,6 +188,37 @@ func main() {
 	// MongoDB client connection. This allows the application to interact with the "sigmacoder" database using
 	// the methods provided by the MongoDB Go driver.
 	db := client.Database("sigmacoder")
+	// `retry.Default` is the policy the repositories use to retry operations that fail with transient
+	// errors such as a primary stepdown or a network blip.
+	retry.Default = retry.Policy{Attempts: config.MongoRetryAttempts, Backoff: config.MongoRetryBackoff}
+	// `collectionOpts` sets the read preference and write concern of the repositories. Everything reads
+	// from `MONGO_READ_PREFERENCE` except the question catalogue, which uses
+	// `QUESTIONS_READ_PREFERENCE` so its heavy, staleness tolerant reads can be sent to secondaries.
+	collectionOpts, err := database.CollectionOptions(config.MongoReadPreference, config.MongoWriteConcern)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	questionOpts, err := database.CollectionOptions(config.QuestionsReadPreference, config.MongoWriteConcern)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	// `featureFlags` centralizes the features that can be switched on and off: each flag takes the value
+	// an admin stored in the `feature_flags` collection, falling back to its environment variable.
+	// Overrides are re-read every `FEATURE_FLAGS_REFRESH`.
+	flagConfig, err := routes.MaintenanceFlags(config.MaintenanceMode)
+	if err != nil {
+		logging.Fatalf("%v", err)
+	}
+	flagConfig[flags.Signup] = config.SignupEnabled
+	flagConfig[flags.PhoneOTP] = config.PhoneOTPEnabled
+	flagConfig[flags.WelcomeEmail] = config.WelcomeEmail
+	flagRepo := flags.NewRepo(db, collectionOpts)
+	featureFlags := flags.New(flagConfig, flagRepo, config.FlagsRefresh)
+	// `maintenance` refuses writes, or every request, with 503 while the API is in maintenance. It starts
+	// in `MAINTENANCE_MODE` and can be switched by admins at runtime, through its own route or the
+	// `read_only` and `maintenance` flags. It is registered before any route, so it covers all of them.
+	maintenance := routes.NewMaintenance(featureFlags, config.MaintenanceRetryAfter)
+	app.Use(maintenance.Middleware())
 
 	// This code is creating a route for the root URL ("/") of the application using the HTTP GET method.
 	// When a user makes a GET request to the root URL, the function passed as the second argument to
@@ -65,45 +229,353 @@ func main() {
 			"ping": "pong",
 		})
 	})
+	// `app.Get("/swagger/*", swagger.HandlerDefault)` serves the generated OpenAPI spec and the Swagger
+	// UI at `/swagger/index.html`. It is registered before the auth routes so the docs stay public.
+	app.Get("/swagger/*", swagger.HandlerDefault)
+	// `routes.CreateJWKSRoutes(app, tokenKeys)` publishes the token verification keys.
+	routes.CreateJWKSRoutes(app, tokenKeys)
 	// `userRepo := auth.NewRepo(db)` is creating a new instance of the `auth.Repo` struct, which is used
 	// to interact with the MongoDB database and perform CRUD (Create, Read, Update, Delete) operations on
 	// user data. The `db` variable is passed as an argument to the `NewRepo()` function to establish a
 	// connection to the MongoDB database. The resulting `userRepo` variable is then used to pass the user
 	// data to the authentication routes defined in the `routes` package.
-	userRepo := auth.NewRepo(db)
-	// The line `userSvc := auth.NewAuthService(userRepo.(*auth.Repo))` is creating a new instance of the
-	// `auth.AuthService` struct, which is used to handle the logic and operations related to user
-	// authentication.
-	userSvc := auth.NewAuthService(userRepo.(*auth.Repo))
+	userRepo := auth.NewRepo(db, collectionOpts)
+	if err := userRepo.EnsureIndexes(); err != nil {
+		logging.Warnf("auth: could not ensure indexes: %v", err)
+	}
+	// `sessionRepo` records a session for every token issued at sign up and login, so users can list
+	// their logged in devices and revoke them individually.
+	sessionRepo := sessions.NewRepo(db, collectionOpts)
+	// `auditRepo` is the audit log of security relevant events such as logins, session revocations and
+	// purges. Its indexes back the admin listings filtered by user or type.
+	auditRepo := audit.NewRepo(db, collectionOpts)
+	if err := auditRepo.EnsureIndexes(); err != nil {
+		logging.Warnf("audit: could not ensure indexes: %v", err)
+	}
+	// The line `userSvc := auth.NewAuthService(userRepo.(*auth.Repo), ...)` is creating a new instance of
+	// the `auth.AuthService` struct, which is used to handle the logic and operations related to user
+	// authentication. When `SIGNUP_WEBHOOK_URL` is set, every sign up is also pushed to it in the
+	// background as a signed event. Emails, such as email change verification links and the welcome
+	// email sent with `WELCOME_EMAIL_ENABLED`, go through the SMTP server at `SMTP_HOST`, or are written
+	// to the log when none is configured.
+	signupWebhook := webhook.NewNotifier(config.SignupWebhookURL, config.SignupWebhookSecret, config.SignupWebhookAttempts, config.SignupWebhookBackoff)
+	var mailSender mail.Sender = mail.LogSender{}
+	if config.SMTPHost != "" {
+		mailSender = mail.NewSMTPSender(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, config.SMTPFrom)
+	}
+	authOpts := []auth.Option{
+		auth.WithSessions(sessionRepo.(*sessions.Repo)),
+		auth.WithSignupWebhook(signupWebhook),
+		auth.WithAudit(auditRepo.(*audit.Repo)),
+		auth.WithMailer(mailSender, config.PublicBaseURL),
+		auth.WithTokenKeys(tokenKeys),
+		auth.WithDefaultUserType(config.DefaultUserType),
+	}
+	authOpts = append(authOpts, auth.WithWelcomeEmail(featureFlags.WelcomeEmail))
+	userSvc := auth.NewAuthService(userRepo.(*auth.Repo), authOpts...)
 	// The line `allquestionRepo := allquestions.NewRepo(db)` is creating a new instance of the
 	// `allquestions.Repo` struct, which is used to interact with the MongoDB database and perform CRUD
 	// (Create, Read, Update, Delete) operations on all question data. The `db` variable, which represents
 	// the MongoDB database connection, is passed as an argument to the `NewRepo()` function to establish a
 	// connection to the database. The resulting `allquestionRepo` variable is then used to pass the all
 	// question data to the routes defined in the `routes` package.
-	allquestionRepo := allquestions.NewRepo(db)
+	allquestionRepo := allquestions.NewRepo(db, questionOpts)
+	if err := allquestionRepo.EnsureIndexes(); err != nil {
+		logging.Warnf("allquestions: could not ensure indexes: %v", err)
+	}
+	// `questionViews` counts how often each question is viewed, batching the writes every
+	// `VIEW_FLUSH_INTERVAL`.
+	questionViews := allquestions.NewViewCounter(allquestionRepo, config.ViewFlushInterval)
+	// `noteSvc` handles users' private notes on questions, capped at `NOTE_MAX_LENGTH` characters.
+	noteRepo := notes.NewRepo(db, collectionOpts)
+	noteSvc := notes.NewNotesService(noteRepo.(*notes.Repo), config.NoteMaxLength)
+	// `solutionSvc` handles user-submitted solutions to questions and their upvotes.
+	solutionRepo := solutions.NewRepo(db, collectionOpts)
+	solutionSvc := solutions.NewSolutionsService(solutionRepo.(*solutions.Repo), allquestionRepo, userRepo.(*auth.Repo))
+	// `commentSvc` handles the discussion threads on questions, with comments capped at
+	// `COMMENT_MAX_LENGTH` characters.
+	commentRepo := comments.NewRepo(db, collectionOpts)
+	if err := commentRepo.EnsureIndexes(); err != nil {
+		logging.Warnf("comments: could not ensure indexes: %v", err)
+	}
+	commentSvc := comments.NewCommentsService(commentRepo.(*comments.Repo), allquestionRepo, userRepo.(*auth.Repo), config.CommentMaxLength)
+	// `submissionSvc` stores code submitted to be run and hands it to the judge picked by `JUDGE`. No
+	// real judge is integrated yet, so by default submissions stay pending.
+	var judge submissions.Judge
+	if config.Judge == "fake" {
+		logging.Warnf("submissions: using the fake judge, which accepts code without running it")
+		judge = submissions.FakeJudge{Delay: 2 * time.Second}
+	}
+	submissionRepo := submissions.NewRepo(db, collectionOpts)
+	if err := submissionRepo.EnsureIndexes(); err != nil {
+		logging.Warnf("submissions: could not ensure indexes: %v", err)
+	}
+	// `acceptance` shows how often submissions for a question are accepted, recomputed at most every
+	// `ACCEPTANCE_CACHE_TTL`.
+	acceptance := submissions.NewAcceptanceCache(submissionRepo.(*submissions.Repo), config.AcceptanceCacheTTL)
+	submissionSvc := submissions.NewSubmissionsService(submissionRepo.(*submissions.Repo), allquestionRepo, judge)
+	// `progressRepo` stores which questions each user has solved. Its unique index is what keeps marking
+	// a question solved idempotent, so a failure to create it is logged loudly.
+	progressRepo := progress.NewRepo(db, collectionOpts)
+	if err := progressRepo.EnsureIndexes(); err != nil {
+		logging.Warnf("progress: could not ensure indexes: %v", err)
+	}
+	// `timeLogSvc` records the sessions users spend on questions, each at most `TIME_LOG_MAX_SESSION`
+	// long. Their totals are listed with the user's progress.
+	timeLogRepo := timelogs.NewRepo(db, collectionOpts)
+	if err := timeLogRepo.EnsureIndexes(); err != nil {
+		logging.Warnf("timelogs: could not ensure indexes: %v", err)
+	}
+	timeLogSvc := timelogs.NewTimeLogsService(timeLogRepo.(*timelogs.Repo), allquestionRepo, config.TimeLogMaxSession)
+	progressSvc := progress.NewProgressService(progressRepo.(*progress.Repo), allquestionRepo, userRepo.(*auth.Repo),
+		timeLogRepo.(*timelogs.Repo), config.ProgressBatchMax)
+	// `deviceSvc` stores the push tokens of the devices users receive notifications on. Its unique index
+	// on the token is what deduplicates registrations.
+	deviceRepo := devices.NewRepo(db, collectionOpts)
+	if err := deviceRepo.EnsureIndexes(); err != nil {
+		logging.Warnf("devices: could not ensure indexes: %v", err)
+	}
+	deviceSvc := devices.NewDevicesService(deviceRepo.(*devices.Repo))
+	// `dailySvc` serves the problem of the day, picked with `DAILY_PROBLEM_STRATEGY` and stored so every
+	// user gets the same one. It is selected in the background every `DAILY_PROBLEM_INTERVAL`, or by the
+	// first request of the day when that comes earlier.
+	selector, ok := daily.NewSelector(config.DailyProblemStrategy)
+	if !ok {
+		logging.Fatalf("DAILY_PROBLEM_STRATEGY must be %q or %q, got %q", daily.StrategyRandom, daily.StrategySequential, config.DailyProblemStrategy)
+	}
+	dailyRepo := daily.NewRepo(db, collectionOpts)
+	dailySvc := daily.NewDailyService(dailyRepo.(*daily.Repo), allquestionRepo, selector)
+	// `difficultySvc` records how difficult users find each question. Its unique index keeps every user
+	// to one vote per question.
+	difficultyRepo := difficulty.NewRepo(db, collectionOpts)
+	if err := difficultyRepo.EnsureIndexes(); err != nil {
+		logging.Warnf("difficulty: could not ensure indexes: %v", err)
+	}
+	difficultySvc := difficulty.NewDifficultyService(difficultyRepo.(*difficulty.Repo), allquestionRepo)
+	// `reportSvc` records users' reports of broken questions. Its partial unique index keeps every user
+	// to one open report per question.
+	reportRepo := reports.NewRepo(db, collectionOpts)
+	if err := reportRepo.EnsureIndexes(); err != nil {
+		logging.Warnf("reports: could not ensure indexes: %v", err)
+	}
+	reportSvc := reports.NewReportsService(reportRepo.(*reports.Repo), allquestionRepo)
+	// `go run . seed` populates the database with the sample users and questions from `pkg/seed` and
+	// exits instead of starting the server. Records that already exist are skipped, so it can be
+	// re-run safely.
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		result, err := seed.Run(userRepo.(*auth.Repo), allquestionRepo)
+		if err != nil {
+			log.Panic(err)
+		}
+		logging.Infof("seed: created %d users (%d already present), %d questions (%d already present)",
+			result.UsersCreated, result.UsersSkipped, result.QuestionsCreated, result.QuestionsSkipped)
+		return
+	}
+	// `go run . backfill-resources` fills in the `resources` of questions created before they existed
+	// from their legacy `Link` and `Videourl` fields, then exits. Already migrated questions are skipped.
+	if len(os.Args) > 1 && os.Args[1] == "backfill-resources" {
+		updated, err := allquestionRepo.BackfillResources()
+		if err != nil {
+			log.Panic(err)
+		}
+		logging.Infof("backfill-resources: updated %d questions", updated)
+		return
+	}
+	// `go run . migrate` runs the one-time data migrations, then exits. It moves the phone numbers of
+	// users from the `phonenumber` key to `phone_number` and rebuilds the indexes on it. Run it before
+	// deploying a version that reads `phone_number`; already migrated users are skipped, so it can be
+	// re-run safely.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migration, err := userRepo.(*auth.Repo).MigratePhoneNumberField()
+		if err != nil {
+			log.Panic(err)
+		}
+		logging.Infof("migrate: moved the phone number of %d users to phone_number, rebuilt indexes %v",
+			migration.UsersUpdated, migration.IndexesRebuilt)
+		return
+	}
+	dailySvc.(*daily.Svc).StartScheduler(config.DailyProblemInterval)
+	// `api` is the router of the current API version. Every route below is registered on it, under
+	// `/api/v1`.
+	api := app.Group(routes.APIPrefix)
+	// `rateLimit` allows `RATE_LIMIT_MAX` requests per `RATE_LIMIT_WINDOW`, counted per user on the
+	// authenticated routes and per IP on the public ones.
+	rateLimit := routes.RateLimit(config.RateLimitMax, config.RateLimitWindow)
 	// `routes.CreatePhoneOtpRoutes(app, userSvc)` is creating and registering HTTP routes related to phone
 	// OTP (One-Time Password) verification in the Fiber application. It is passing the `app` instance of
 	// the Fiber application and a pointer to the `auth.AuthService` struct instance `userSvc` to the
 	// `CreatePhoneOtpRoutes` function, which will define and register the necessary routes for phone OTP
 	// verification. The `userSvc` instance is used to handle the logic and operations related to phone OTP
-	// verification, such as sending OTPs and verifying OTPs.
-	routes.CreatePhoneOtpRoutes(app, userSvc)
+	// verification, such as sending OTPs and verifying OTPs. Codes are only sent over `OTP_CHANNELS`.
+	// `OTP_PROVIDER` picks what sends and checks the codes; the mock provider only logs them, so the
+	// phone flow works offline. With `OTP_CODE_LENGTH` set, the Twilio Verify service is switched to codes
+	// of that many digits before any are sent. A length outside Twilio's range is a configuration mistake
+	// and stops startup.
+	var otpProvider otp.Provider
+	switch config.OTPProvider {
+	case "mock":
+		logging.Warnf("otp: using the mock provider, which logs codes at debug level instead of sending them")
+		otpProvider = otp.NewMockProvider(config.OTPCodeLength)
+	case "twilio":
+		twilioProvider := otp.NewTwilioProvider(config.TwilioAccountSID, config.TwilioAuthToken, config.TwilioServiceSID)
+		if config.OTPCodeLength != 0 {
+			if err := twilioProvider.SetCodeLength(config.OTPCodeLength); err != nil {
+				logging.Fatalf("otp: %v", err)
+			}
+		}
+		otpProvider = twilioProvider
+	default:
+		logging.Fatalf("OTP_PROVIDER must be %q or %q, got %q", "twilio", "mock", config.OTPProvider)
+	}
+	// With `OTP_TEST_CODE` set, the numbers starting with `OTP_TEST_PHONE_PREFIX` are "sent" that fixed
+	// code instead of a real OTP, so end-to-end tests can log in by phone. It is off unless configured
+	// and refused unless `APP_ENV` is development or test.
+	if config.OTPTestCode != "" {
+		if !otp.FixedCodeAllowed(config.AppEnv) {
+			logging.Fatalf("OTP_TEST_CODE can only be set when APP_ENV is %q or %q, got %q", "development", "test", config.AppEnv)
+		}
+		testProvider, err := otp.NewTestNumberProvider(config.OTPTestPhonePrefix, config.OTPTestCode, otpProvider)
+		if err != nil {
+			logging.Fatalf("OTP_TEST_CODE: %v", err)
+		}
+		logging.Warnf("otp: numbers starting with %q get the fixed test code instead of an OTP", config.OTPTestPhonePrefix)
+		otpProvider = testProvider
+	}
+	// `shareSigner` signs the links that share a question with people who aren't logged in. Opening them
+	// is public, so that route is registered on `api`.
+	shareSigner := allquestions.NewShareSigner(config.ShareLinkSecret, config.ShareLinkTTL)
+	routes.CreateSharedQuestionRoutes(api, allquestionRepo, shareSigner, rateLimit, questionCache)
+	// `otpQuota` caps the OTPs sent to a phone number at `OTP_DAILY_LIMIT` per UTC day.
+	otpQuota := otp.NewDailyQuota(db, config.OTPDailyLimit, collectionOpts)
+	if err := otpQuota.EnsureIndexes(); err != nil {
+		logging.Warnf("otp: could not ensure indexes: %v", err)
+	}
+	otpStats := routes.NewOTPStats()
+	routes.CreatePhoneOtpRoutes(api, userSvc, otpProvider, config.OTPChannels, rateLimit, otpQuota, otpStats, featureFlags)
 	// `routes.CreateAuthRoutes(app, userRepo.(*auth.Repo))` is creating and registering HTTP routes
 	// related to user authentication in the Fiber application. It is passing the `app` instance of the
 	// Fiber application and a pointer to the `auth.Repo` struct instance `userRepo` to the
 	// `CreateAuthRoutes` function, which will define and register the necessary routes for user
 	// authentication. The `userRepo.(*auth.Repo)` syntax is used to convert the `userRepo` variable to a
 	// pointer to the `auth.Repo` struct type, which is required by the `CreateAuthRoutes` function.
-	routes.CreateAuthRoutes(app, userRepo.(*auth.Repo), userSvc)
-	// `routes.CreateAllQuestionRoutes(app, allquestionRepo.(*allquestions.Repo))` is creating and
-	// registering HTTP routes related to all question data in the Fiber application. It is passing the
-	// `app` instance of the Fiber application and a pointer to the `allquestions.Repo` struct instance
-	// `allquestionRepo` to the `CreateAllQuestionRoutes` function, which will define and register the
-	// necessary routes for all question data. The `allquestionRepo.(*allquestions.Repo)` syntax is used
-	// to convert the `allquestionRepo` variable to a pointer to the `allquestions.Repo` struct type,
-	// which is required by the `CreateAllQuestionRoutes` function.
-	routes.CreateAllQuestionRoutes(app, allquestionRepo.(*allquestions.Repo))
+	// `sessionRepo` lets the middleware reject tokens whose session was revoked, and `userRepo` the ones
+	// issued before their user last changed their password.
+	//
+	// Authentication is explicit per route: the public routes are registered on `api`, and the ones that
+	// need a logged in user on `protected`, which runs `routes.RequireAuth` in front of each of them and
+	// answers requests without a valid token with 401. The order the groups are registered in doesn't
+	// matter. The public groups are the shared questions, the phone OTP routes, signing up and logging
+	// in, and listing and reading questions. Every other group is protected, and the admin-only routes
+	// additionally check the caller's role.
+	protected := routes.Protected(api, routes.RequireAuth(tokenKeys, sessionRepo.(*sessions.Repo), userRepo.(*auth.Repo), rateLimit)...)
+	// `signupVerifier` checks the captcha of every sign up with `CAPTCHA_PROVIDER`. With "none" every sign
+	// up is let through, which is meant for development.
+	var signupVerifier captcha.SignupVerifier
+	switch config.CaptchaProvider {
+	case "none":
+		signupVerifier = captcha.NoopVerifier{}
+	case "recaptcha":
+		signupVerifier = captcha.NewSiteVerifier(captcha.RecaptchaURL, config.CaptchaSecret)
+	case "hcaptcha":
+		signupVerifier = captcha.NewSiteVerifier(captcha.HCaptchaURL, config.CaptchaSecret)
+	default:
+		logging.Fatalf("CAPTCHA_PROVIDER must be %q, %q or %q, got %q", "recaptcha", "hcaptcha", "none", config.CaptchaProvider)
+	}
+	if config.CaptchaProvider != "none" && config.CaptchaSecret == "" {
+		logging.Fatalf("CAPTCHA_SECRET must be set when CAPTCHA_PROVIDER is %q", config.CaptchaProvider)
+	}
+	// `idempotencyKeys` remembers the responses to sign ups sent with an `Idempotency-Key` header for
+	// `IDEMPOTENCY_KEY_TTL`, so a retried sign up gets the original token.
+	idempotencyKeys := idempotency.NewStore(db, config.IdempotencyKeyTTL, collectionOpts)
+	if err := idempotencyKeys.EnsureIndexes(); err != nil {
+		logging.Warnf("idempotency: could not ensure indexes: %v", err)
+	}
+	routes.CreateAuthRoutes(api, protected, userRepo.(*auth.Repo), userSvc, signupVerifier, sessionRepo.(*sessions.Repo), rateLimit, tokenKeys,
+		idempotencyKeys, featureFlags)
+	// `routes.CreateSessionRoutes(app, ...)` registers the routes for listing and revoking the
+	// authenticated user's sessions.
+	routes.CreateSessionRoutes(protected, sessionRepo.(*sessions.Repo), auditRepo.(*audit.Repo))
+	// `routes.CreateAllQuestionRoutes(app, allquestionRepo)` is creating and registering HTTP routes
+	// related to all question data in the Fiber application. It is passing the `app` instance of the
+	// Fiber application and the `allquestions.Repository` `allquestionRepo` to the
+	// `CreateAllQuestionRoutes` function, which will define and register the necessary routes for all
+	// question data. Reading questions is public, and `userRepo` is passed along so the admin-only
+	// routes can look up the caller's role.
+	// `similarFinder` compares the question embeddings for the similar questions, in memory or with
+	// Atlas Vector Search depending on `SIMILARITY_SEARCH`.
+	var similarFinder allquestions.SimilarFinder
+	switch config.SimilaritySearch {
+	case "memory":
+		similarFinder = allquestions.NewMemoryFinder(allquestionRepo)
+	case "atlas":
+		similarFinder = allquestions.NewAtlasFinder(db, config.AtlasVectorIndex, questionOpts)
+	default:
+		logging.Fatalf("SIMILARITY_SEARCH must be %q or %q, got %q", "memory", "atlas", config.SimilaritySearch)
+	}
+	routes.CreateAllQuestionRoutes(api, protected, allquestionRepo, userRepo.(*auth.Repo), difficultySvc, questionViews, acceptance, similarFinder, rateLimit, questionCache)
+	// `routes.CreateDifficultyRoutes(api, difficultySvc)` registers community difficulty voting.
+	routes.CreateDifficultyRoutes(protected, difficultySvc)
+	// `routes.CreateReportRoutes(...)` registers reporting broken questions and the admin-only routes
+	// for handling the reports.
+	routes.CreateReportRoutes(protected, userRepo.(*auth.Repo), reportSvc)
+	// `routes.CreateShareRoutes(...)` registers the route that creates share links, valid for
+	// `SHARE_LINK_TTL`.
+	routes.CreateShareRoutes(protected, allquestionRepo, shareSigner, config.PublicBaseURL)
+	// `routes.CreateDailyRoutes(api, dailySvc, ...)` registers the problem of the day, which changes at
+	// midnight in each user's timezone.
+	routes.CreateDailyRoutes(protected, dailySvc, userRepo.(*auth.Repo), config.DefaultTimezone)
+	// `routes.CreateNoteRoutes(app, noteSvc)` registers the note routes, which are all protected.
+	routes.CreateNoteRoutes(protected, noteSvc)
+	// `routes.CreateSolutionRoutes(app, solutionSvc)` registers the routes for sharing, listing and
+	// upvoting solutions. Listings only ever expose the authors' public profiles.
+	routes.CreateSolutionRoutes(protected, solutionSvc)
+	// `routes.CreateCommentRoutes(api, commentSvc)` registers the routes for posting, listing and
+	// deleting comments in the discussion of a question.
+	routes.CreateCommentRoutes(protected, commentSvc)
+	// `routes.CreateSubmissionRoutes(api, submissionSvc)` registers the routes for submitting code to be
+	// judged and polling its status.
+	routes.CreateSubmissionRoutes(protected, submissionSvc)
+	// `routes.CreateProgressRoutes(app, progressSvc, ...)` registers the routes that record and summarize
+	// the authenticated user's solved questions.
+	routes.CreateProgressRoutes(protected, progressSvc, userRepo.(*auth.Repo), config.DefaultTimezone)
+	// `routes.CreateTimeLogRoutes(api, timeLogSvc)` registers the route for logging the time spent on a
+	// question.
+	routes.CreateTimeLogRoutes(protected, timeLogSvc)
+	// `routes.CreateActivityRoutes(...)` registers the authenticated user's activity feed, merged from
+	// their solves, comments and solutions.
+	activityFeed := activity.NewFeed(db)
+	if err := activityFeed.EnsureIndexes(); err != nil {
+		logging.Warnf("activity: could not ensure indexes: %v", err)
+	}
+	routes.CreateActivityRoutes(protected, activityFeed)
+	// `routes.CreateRecountRoutes(...)` registers the admin-only routes that recompute the solve counts
+	// stored on the users from the progress collection.
+	routes.CreateRecountRoutes(protected, userRepo.(*auth.Repo), progressSvc)
+	// `routes.CreateDeviceRoutes(api, deviceSvc)` registers the routes for registering and removing push
+	// notification devices.
+	routes.CreateDeviceRoutes(protected, deviceSvc)
+	// `routes.CreateExportRoutes(...)` registers the data portability export, which reads from every
+	// collection that holds data about the user.
+	routes.CreateExportRoutes(protected, userRepo.(*auth.Repo), progressRepo.(*progress.Repo), noteRepo.(*notes.Repo))
+	// `routes.CreateUserAdminRoutes(...)` registers the admin-only account management routes, such as
+	// purging a user together with all of their data. The same purger removes, every
+	// `ACCOUNT_PURGE_INTERVAL`, the accounts whose deletion grace period has run out.
+	purger := account.NewPurger(db)
+	purger.StartDeletionSweeper(config.AccountPurgeInterval, auditRepo.(*audit.Repo))
+	routes.CreateUserAdminRoutes(protected, userRepo.(*auth.Repo), purger, auditRepo.(*audit.Repo))
+	// `routes.CreateMaintenanceRoutes(...)` registers the admin-only routes for switching the maintenance
+	// mode.
+	routes.CreateMaintenanceRoutes(protected, userRepo.(*auth.Repo), maintenance, auditRepo.(*audit.Repo))
+	// `routes.CreateFlagRoutes(...)` registers the admin-only routes for listing and overriding the
+	// feature flags.
+	routes.CreateFlagRoutes(protected, userRepo.(*auth.Repo), featureFlags, auditRepo.(*audit.Repo))
+	// `routes.CreateAuditRoutes(...)` registers the admin-only, paginated listings of the audit log.
+	routes.CreateAuditRoutes(protected, userRepo.(*auth.Repo), auditRepo.(*audit.Repo))
+	// `routes.CreateOTPStatsRoutes(...)` registers the admin-only OTP send and verification counters.
+	routes.CreateOTPStatsRoutes(protected, userRepo.(*auth.Repo), otpStats)
+	// `routes.CreateAdminRoutes(...)` registers the admin dashboard summary, which counts users,
+	// questions, logins and OTPs.
+	routes.CreateAdminRoutes(protected, userRepo.(*auth.Repo), allquestionRepo, auditRepo.(*audit.Repo), otpStats)
 	// `log.Panic(app.Listen(":" + os.Getenv("PORT")))` is a line of code that starts the Fiber
 	// application and listens for incoming HTTP requests on the specified port.
 	log.Panic(app.Listen(":" + os.Getenv("PORT")))
@@ -0,0 +1,68 @@
+package account
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/logging"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The `PurgeDue` function purges every user whose scheduled deletion is at or before `now` and returns
+// the IDs of the purged users. A user that can't be purged, such as the only remaining admin, is
+// logged and left for the next run rather than stopping the others.
+func (p *Purger) PurgeDue(now time.Time) ([]string, error) {
+	cursor, err := p.db.Collection("users").Find(p.context,
+		bson.M{"deletionscheduledat": bson.M{"$lte": now}},
+		options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var due []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(p.context, &due); err != nil {
+		return nil, err
+	}
+	purged := []string{}
+	for _, user := range due {
+		_, err := p.Purge(user.ID)
+		if errors.Is(err, pkg.ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			logging.Errorf("account: could not purge %s after its deletion grace period: %v", user.ID, err)
+			continue
+		}
+		purged = append(purged, user.ID)
+	}
+	return purged, nil
+}
+
+// The function purges the accounts whose deletion grace period has run out every `interval`, in the
+// background, and records each purge in the audit log when one is given. It returns immediately.
+func (p *Purger) StartDeletionSweeper(interval time.Duration, auditLog *audit.Repo) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := p.PurgeDue(time.Now())
+			if err != nil {
+				logging.Errorf("account: scheduled deletions failed: %v", err)
+			}
+			for _, userID := range purged {
+				if auditLog == nil {
+					continue
+				}
+				event := audit.Event{Type: audit.EventUserPurged, UserID: userID, Metadata: map[string]string{"reason": "scheduled"}}
+				if err := auditLog.Record(event); err != nil {
+					logging.Errorf("audit: could not record %s: %v", audit.EventUserPurged, err)
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
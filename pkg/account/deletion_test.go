@@ -0,0 +1,51 @@
+package account
+
+import (
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestPurgeDue(t *testing.T) {
+	dbtest.Run(t, "purges the users past their grace period", func(mt *mtest.T) {
+		now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+		responses := []bson.D{
+			dbtest.Cursor(mt, bson.M{"_id": "user-1"}, bson.M{"_id": "gone"}),
+			dbtest.Cursor(mt, bson.M{"_id": "user-1", "usertype": "user"}),
+			dbtest.Written(1),
+		}
+		for range ownedCollections {
+			responses = append(responses, dbtest.Written(0))
+		}
+		// The second user was purged in the meantime, so it is skipped.
+		responses = append(responses, mtest.CreateSuccessResponse(), dbtest.Cursor(mt), mtest.CreateSuccessResponse())
+		mt.AddMockResponses(responses...)
+
+		purged, err := NewPurger(mt.DB).PurgeDue(now)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(purged) != 1 || purged[0] != "user-1" {
+			mt.Errorf("PurgeDue() = %v, want [user-1]", purged)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"deletionscheduledat": bson.M{"$lte": now}})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": "user-1"})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "delete"), "delete", "users")
+	})
+
+	dbtest.Run(t, "nothing due", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		purged, err := NewPurger(mt.DB).PurgeDue(time.Now())
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if purged == nil || len(purged) != 0 {
+			mt.Errorf("PurgeDue() = %#v, want an empty slice", purged)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
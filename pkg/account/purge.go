@@ -0,0 +1,118 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// `ownedCollections` lists every collection holding documents that belong to a user, keyed by the
+// field that references the user's ID. Collections added for new per-user features must be listed
+// here so that purging a user keeps removing all of their data.
+var ownedCollections = []struct {
+	Name  string
+	Field string
+}{
+	{Name: "progress", Field: "userid"},
+	{Name: "notes", Field: "userid"},
+	{Name: "solutions", Field: "userid"},
+	{Name: "sessions", Field: "userid"},
+	{Name: "comments", Field: "userid"},
+	{Name: "submissions", Field: "userid"},
+	{Name: "devices", Field: "userid"},
+	{Name: "difficulty_votes", Field: "userid"},
+	{Name: "reports", Field: "userid"},
+	{Name: "audit", Field: "userid"},
+	{Name: "idempotency_keys", Field: "userid"},
+	{Name: "time_logs", Field: "userid"},
+}
+
+// `upvotesKey` is the key under which `Purge` reports the number of other users' solutions the user's
+// upvote was withdrawn from.
+const upvotesKey = "solutions.upvoters"
+
+// The Purger type removes a user and all of their data across collections.
+// @property db - The database holding the user collections.
+// @property context - The context the purge runs with.
+type Purger struct {
+	db      *mongo.Database
+	context context.Context
+}
+
+// The `Purge` function deletes the user with the given ID together with every document that belongs
+// to them, all within one transaction so a failure leaves nothing half deleted. It returns the number
+// of documents removed per collection. The user's upvotes on other users' solutions are withdrawn in the
+// same transaction, so their ID isn't kept in `upvoters` and the upvote counts stay right; the number
+// of solutions changed is reported under `upvotesKey`. The audit events about the user are deleted too; the only one
+// left behind is the `user.purged` event recorded after the purge, which names the user by their
+// opaque ID alone. Purging the only remaining admin is refused with `pkg.ErrLastAdmin`, and an
+// unknown user yields `pkg.ErrUserNotFound`.
+func (p *Purger) Purge(userID string) (map[string]int64, error) {
+	session, err := p.db.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(p.context)
+	removed, err := session.WithTransaction(p.context, func(sc mongo.SessionContext) (interface{}, error) {
+		users := p.db.Collection("users")
+		var user struct {
+			UserType string `bson:"usertype"`
+		}
+		err := users.FindOne(sc, bson.M{"_id": userID}).Decode(&user)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, pkg.ErrUserNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if user.UserType == "admin" {
+			admins, err := users.CountDocuments(sc, bson.M{"usertype": "admin"})
+			if err != nil {
+				return nil, err
+			}
+			if admins <= 1 {
+				return nil, pkg.ErrLastAdmin
+			}
+		}
+		removed := map[string]int64{}
+		result, err := users.DeleteOne(sc, bson.M{"_id": userID})
+		if err != nil {
+			return nil, err
+		}
+		removed["users"] = result.DeletedCount
+		for _, owned := range ownedCollections {
+			result, err := p.db.Collection(owned.Name).DeleteMany(sc, bson.M{owned.Field: userID})
+			if err != nil {
+				return nil, err
+			}
+			removed[owned.Name] = result.DeletedCount
+		}
+		upvoted, err := p.db.Collection("solutions").UpdateMany(sc,
+			bson.M{"upvoters": userID},
+			bson.M{"$pull": bson.M{"upvoters": userID}, "$inc": bson.M{"upvotes": -1}})
+		if err != nil {
+			return nil, err
+		}
+		removed[upvotesKey] = upvoted.ModifiedCount
+		return removed, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed.(map[string]int64), nil
+}
+
+// The `WithContext` function returns a copy of the purger that runs with the given context.
+func (p *Purger) WithContext(ctx context.Context) *Purger {
+	clone := *p
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new Purger for the given database.
+func NewPurger(db *mongo.Database) *Purger {
+	return &Purger{db: db, context: context.TODO()}
+}
@@ -0,0 +1,91 @@
+package account
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestPurge(t *testing.T) {
+	dbtest.Run(t, "removes the data in every collection", func(mt *mtest.T) {
+		// Every collection holds a few documents of the user.
+		responses := []bson.D{dbtest.Cursor(mt, bson.M{"_id": "user-1", "usertype": "user"}), dbtest.Written(1)}
+		for i := range ownedCollections {
+			responses = append(responses, dbtest.Written(i+1))
+		}
+		// The user upvoted two solutions of other users.
+		responses = append(responses, dbtest.Written(2))
+		mt.AddMockResponses(append(responses, mtest.CreateSuccessResponse())...)
+
+		removed, err := NewPurger(mt.DB).Purge("user-1")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": "user-1"})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "delete"), "delete", "users")
+		if removed["users"] != 1 {
+			mt.Errorf("removed %d users, want 1", removed["users"])
+		}
+		for i, owned := range ownedCollections {
+			remove := dbtest.NextCommand(mt, "delete")
+			dbtest.ExpectField(mt, remove, "delete", owned.Name)
+			deletes := remove["deletes"].(bson.A)
+			if len(deletes) != 1 {
+				mt.Fatalf("deletes = %v, want a single delete", deletes)
+			}
+			dbtest.ExpectField(mt, deletes[0].(bson.M), "q", bson.M{owned.Field: "user-1"})
+			if removed[owned.Name] != int64(i+1) {
+				mt.Errorf("removed %d documents from %s, want %d", removed[owned.Name], owned.Name, i+1)
+			}
+		}
+		upvotes := dbtest.NextCommand(mt, "update")
+		dbtest.ExpectField(mt, upvotes, "update", "solutions")
+		dbtest.ExpectField(mt, upvotes, "updates", bson.A{bson.M{
+			"q":     bson.M{"upvoters": "user-1"},
+			"u":     bson.M{"$pull": bson.M{"upvoters": "user-1"}, "$inc": bson.M{"upvotes": -1}},
+			"multi": true,
+		}})
+		if removed[upvotesKey] != 2 {
+			mt.Errorf("withdrew %d upvotes, want 2", removed[upvotesKey])
+		}
+		dbtest.NextCommand(mt, "commitTransaction")
+	})
+
+	dbtest.Run(t, "every user collection is purged", func(mt *mtest.T) {
+		purged := map[string]bool{}
+		for _, owned := range ownedCollections {
+			purged[owned.Name] = true
+		}
+		for _, name := range []string{"progress", "notes", "solutions", "sessions", "comments", "submissions", "devices", "difficulty_votes", "reports", "audit", "idempotency_keys", "time_logs"} {
+			if !purged[name] {
+				mt.Errorf("%s isn't purged", name)
+			}
+		}
+	})
+
+	dbtest.Run(t, "last admin", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, bson.M{"_id": "admin-1", "usertype": "admin"}),
+			mtest.CreateCursorResponse(0, dbtest.Namespace, mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateSuccessResponse(),
+		)
+		if _, err := NewPurger(mt.DB).Purge("admin-1"); !errors.Is(err, pkg.ErrLastAdmin) {
+			mt.Errorf("Purge() = %v, want %v", err, pkg.ErrLastAdmin)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "aggregate")
+		dbtest.NextCommand(mt, "abortTransaction")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "unknown user", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt), mtest.CreateSuccessResponse())
+		if _, err := NewPurger(mt.DB).Purge("nobody"); !errors.Is(err, pkg.ErrUserNotFound) {
+			mt.Errorf("Purge() = %v, want %v", err, pkg.ErrUserNotFound)
+		}
+	})
+}
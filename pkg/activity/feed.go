@@ -0,0 +1,149 @@
+package activity
+
+import (
+	"context"
+	"sigmacoder/pkg/retry"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The types of activity in a feed.
+const (
+	TypeSolved    = "solved"
+	TypeCommented = "commented"
+	TypeSolution  = "shared_solution"
+)
+
+// `sources` lists the collections a feed is merged from, with the type of their entries and the field
+// holding when each entry happened. Collections added for new user actions can be listed here to show
+// up in the feed.
+var sources = []struct {
+	Collection string
+	Type       string
+	TimeField  string
+}{
+	{Collection: "progress", Type: TypeSolved, TimeField: "solvedAt"},
+	{Collection: "comments", Type: TypeCommented, TimeField: "createdAt"},
+	{Collection: "solutions", Type: TypeSolution, TimeField: "createdAt"},
+}
+
+// The Activity type is a single entry of a user's activity feed.
+// @property {string} Type - What the user did, one of the `Type*` constants.
+// @property QuestionID - The ObjectID of the question the user acted on.
+// @property {string} QuestionName - The name of that question, empty if it has been deleted since.
+// @property ItemID - The ObjectID of the comment or solution the user posted. It is left out for solves.
+// @property At - When the user did it.
+type Activity struct {
+	Type         string              `json:"type" bson:"type"`
+	QuestionID   primitive.ObjectID  `json:"questionId" bson:"questionid"`
+	QuestionName string              `json:"questionName,omitempty" bson:"questionname,omitempty"`
+	ItemID       *primitive.ObjectID `json:"itemId,omitempty" bson:"itemid,omitempty"`
+	At           time.Time           `json:"at" bson:"at"`
+}
+
+// The Page type is a page of a user's activity, newest first, together with the total number of
+// entries in the feed.
+type Page struct {
+	Activities []Activity `json:"activities"`
+	Total      int64      `json:"total"`
+	Limit      int        `json:"limit"`
+	Offset     int        `json:"offset"`
+}
+
+// The Feed type reads users' activity feeds. A feed isn't stored anywhere: it is merged on every read
+// from the collections in `sources`, so it never drifts from them and needs no backfill.
+// @property db - The database holding the source collections.
+// @property context - The context the reads run with.
+type Feed struct {
+	db      *mongo.Database
+	context context.Context
+}
+
+// The function returns the pipeline stages that turn the documents of a source into activities.
+func sourceStages(userID string, activityType string, timeField string) bson.A {
+	project := bson.M{"_id": 0, "type": activityType, "questionid": 1, "at": "$" + timeField}
+	if activityType != TypeSolved {
+		project["itemid"] = "$_id"
+	}
+	return bson.A{
+		bson.M{"$match": bson.M{"userid": userID}},
+		bson.M{"$project": project},
+	}
+}
+
+// The function returns a page of the user's activity, newest first, merged from every source with
+// `$unionWith` in a single aggregation, which needs MongoDB 4.4 or later.
+func (f *Feed) List(userID string, limit int, offset int) (Page, error) {
+	page := Page{Activities: []Activity{}, Limit: limit, Offset: offset}
+	first := sources[0]
+	pipeline := sourceStages(userID, first.Type, first.TimeField)
+	for _, source := range sources[1:] {
+		pipeline = append(pipeline, bson.M{"$unionWith": bson.M{
+			"coll":     source.Collection,
+			"pipeline": sourceStages(userID, source.Type, source.TimeField),
+		}})
+	}
+	pipeline = append(pipeline,
+		bson.M{"$sort": bson.D{{Key: "at", Value: -1}, {Key: "itemid", Value: -1}}},
+		bson.M{"$facet": bson.M{
+			"total": bson.A{bson.M{"$count": "count"}},
+			"activities": bson.A{
+				bson.M{"$skip": offset},
+				bson.M{"$limit": limit},
+				bson.M{"$lookup": bson.M{"from": "AllQuestion", "localField": "questionid", "foreignField": "_id", "as": "question"}},
+				bson.M{"$set": bson.M{"questionname": bson.M{"$first": "$question.name"}}},
+				bson.M{"$unset": "question"},
+			},
+		}},
+	)
+	var result []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Activities []Activity `bson:"activities"`
+	}
+	err := retry.Default.Do(f.context, func() error {
+		cursor, err := f.db.Collection(first.Collection).Aggregate(f.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(f.context, &result)
+	})
+	if err != nil || len(result) == 0 {
+		return page, err
+	}
+	if len(result[0].Total) > 0 {
+		page.Total = result[0].Total[0].Count
+	}
+	page.Activities = append(page.Activities, result[0].Activities...)
+	return page, nil
+}
+
+// The function creates the indexes that let every source be matched by user and read newest first.
+func (f *Feed) EnsureIndexes() error {
+	for _, source := range sources {
+		_, err := f.db.Collection(source.Collection).Indexes().CreateOne(f.context, mongo.IndexModel{
+			Keys: bson.D{{Key: "userid", Value: 1}, {Key: source.TimeField, Value: -1}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The `WithContext` function returns a copy of the feed whose reads run with the given context, so a
+// request's deadline and cancellation reach the database calls.
+func (f *Feed) WithContext(ctx context.Context) *Feed {
+	clone := *f
+	clone.context = ctx
+	return &clone
+}
+
+// The function creates a feed reading from the source collections of the given database.
+func NewFeed(db *mongo.Database) *Feed {
+	return &Feed{db: db, context: context.TODO()}
+}
@@ -0,0 +1,126 @@
+package activity
+
+import (
+	"sigmacoder/pkg/database/dbtest"
+	"sort"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The type seededEntry is a document of one of the feed's source collections.
+type seededEntry struct {
+	source string
+	userID string
+	id     primitive.ObjectID
+	at     time.Time
+}
+
+// The function answers the feed aggregation the way a real deployment would for the seeded
+// documents: the user's entries of every source, newest first, paged by `limit` and `offset`.
+func feedOf(mt *mtest.T, userID string, seeded []seededEntry, limit int, offset int) bson.D {
+	var activities []Activity
+	for _, entry := range seeded {
+		if entry.userID != userID {
+			continue
+		}
+		activity := Activity{QuestionID: primitive.NewObjectID(), At: entry.at}
+		for _, source := range sources {
+			if source.Collection == entry.source {
+				activity.Type = source.Type
+			}
+		}
+		if activity.Type != TypeSolved {
+			id := entry.id
+			activity.ItemID = &id
+		}
+		activities = append(activities, activity)
+	}
+	sort.SliceStable(activities, func(i, j int) bool { return activities[i].At.After(activities[j].At) })
+	total := bson.A{}
+	if len(activities) > 0 {
+		total = bson.A{bson.M{"count": len(activities)}}
+	}
+	page := bson.A{}
+	for i := offset; i < len(activities) && i < offset+limit; i++ {
+		page = append(page, activities[i])
+	}
+	return dbtest.Cursor(mt, bson.M{"total": total, "activities": page})
+}
+
+func TestList(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	seeded := []seededEntry{
+		{source: "progress", userID: "ada", id: primitive.NewObjectID(), at: start},
+		{source: "comments", userID: "ada", id: primitive.NewObjectID(), at: start.Add(3 * time.Hour)},
+		{source: "solutions", userID: "ada", id: primitive.NewObjectID(), at: start.Add(time.Hour)},
+		{source: "progress", userID: "ada", id: primitive.NewObjectID(), at: start.Add(4 * time.Hour)},
+		{source: "comments", userID: "ada", id: primitive.NewObjectID(), at: start.Add(2 * time.Hour)},
+		{source: "solutions", userID: "grace", id: primitive.NewObjectID(), at: start.Add(5 * time.Hour)},
+	}
+	tests := []struct {
+		name   string
+		limit  int
+		offset int
+		types  []string
+		hours  []int
+	}{
+		{name: "newest first across sources", limit: 10, types: []string{TypeSolved, TypeCommented, TypeCommented, TypeSolution, TypeSolved}, hours: []int{4, 3, 2, 1, 0}},
+		{name: "first page", limit: 2, types: []string{TypeSolved, TypeCommented}, hours: []int{4, 3}},
+		{name: "second page", limit: 2, offset: 2, types: []string{TypeCommented, TypeSolution}, hours: []int{2, 1}},
+		{name: "past the end", limit: 2, offset: 6},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(feedOf(mt, "ada", seeded, tt.limit, tt.offset))
+			page, err := NewFeed(mt.DB).List("ada", tt.limit, tt.offset)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if page.Total != 5 || page.Limit != tt.limit || page.Offset != tt.offset {
+				mt.Errorf("total = %d, limit = %d, offset = %d, want 5, %d, %d", page.Total, page.Limit, page.Offset, tt.limit, tt.offset)
+			}
+			if len(page.Activities) != len(tt.hours) {
+				mt.Fatalf("got %d activities, want %d", len(page.Activities), len(tt.hours))
+			}
+			for i, activity := range page.Activities {
+				if activity.Type != tt.types[i] || !activity.At.Equal(start.Add(time.Duration(tt.hours[i])*time.Hour)) {
+					mt.Errorf("activity %d = %s at %s, want %s %d hours after the start", i, activity.Type, activity.At, tt.types[i], tt.hours[i])
+				}
+				if (activity.ItemID == nil) != (activity.Type == TypeSolved) {
+					mt.Errorf("activity %d of type %s has item %v, want one only for comments and solutions", i, activity.Type, activity.ItemID)
+				}
+			}
+
+			command := dbtest.NextCommand(mt, "aggregate")
+			dbtest.ExpectField(mt, command, "aggregate", "progress")
+			pipeline := command["pipeline"].(bson.A)
+			dbtest.ExpectField(mt, pipeline[0].(bson.M), "$match", bson.M{"userid": "ada"})
+			for i, collection := range []string{"comments", "solutions"} {
+				union := pipeline[2+i].(bson.M)["$unionWith"].(bson.M)
+				dbtest.ExpectField(mt, union, "coll", collection)
+				dbtest.ExpectField(mt, union["pipeline"].(bson.A)[0].(bson.M), "$match", bson.M{"userid": "ada"})
+			}
+			// The entries are sorted once they are merged, before the page is cut, so a page is in order
+			// across the sources rather than within each of them.
+			dbtest.ExpectField(mt, pipeline[4].(bson.M), "$sort", bson.D{{Key: "at", Value: int32(-1)}, {Key: "itemid", Value: int32(-1)}})
+			activities := pipeline[5].(bson.M)["$facet"].(bson.M)["activities"].(bson.A)
+			dbtest.ExpectField(mt, activities[0].(bson.M), "$skip", int32(tt.offset))
+			dbtest.ExpectField(mt, activities[1].(bson.M), "$limit", int32(tt.limit))
+		})
+	}
+
+	dbtest.Run(t, "no activity", func(mt *mtest.T) {
+		mt.AddMockResponses(feedOf(mt, "edsger", seeded, 10, 0))
+		page, err := NewFeed(mt.DB).List("edsger", 10, 0)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if page.Total != 0 || page.Activities == nil || len(page.Activities) != 0 {
+			mt.Errorf("List() = %+v, want an empty page", page)
+		}
+	})
+}
@@ -1,13 +1,135 @@
 package allquestions
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"sigmacoder/pkg/filter"
+	"strings"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The resource types a question can link to.
+const (
+	ResourceProblem   = "problem"
+	ResourceEditorial = "editorial"
+	ResourceVideo     = "video"
+)
+
+// The Resource type is a single link attached to a question.
+// @property {string} Type - The kind of resource: "problem", "editorial" or "video".
+// @property {string} URL - The address of the resource.
+type Resource struct {
+	Type string `json:"type" bson:"type" validate:"max=20"`
+	URL  string `json:"url" bson:"url" validate:"max=2048"`
+}
+
+// The function reports whether the resource has a known type and a URL.
+func (r Resource) Valid() bool {
+	switch r.Type {
+	case ResourceProblem, ResourceEditorial, ResourceVideo:
+		return r.URL != ""
+	}
+	return false
+}
+
+// The AllQuestion type is a single problem. `Link` and `Videourl` are the legacy single-link
+// fields; they are kept while documents are migrated to `Resources`, which can hold any number of
+// problem, editorial and video links. `Languages` lists the programming languages a question can be
+// solved in and `StarterCode` holds the code the editor starts from, keyed by language. Both are
+// optional, so questions stored before they existed still decode with empty values. `Hints` are
+// revealed one at a time through the hints endpoint and are left out of the regular question
+// listings. `Tags` label the techniques a question exercises, such as "dp" or "two-pointers",
+// beyond its single `Category`, and `Companies` name the companies known to ask it in interviews.
+// The `validate` tags are checked on the admin create and update payloads: `Level` and `Category`
+// must be one of `LevelEnum` and `CategoryEnum`, and every string is capped to a maximum length.
+// `Status` is one of `StatusEnum`; questions stored before it
+// existed don't have one and count as published. `Embedding` is a precomputed vector of the
+// question's content, compared to find similar questions. It is set through its own admin route and
+// never sent to clients.
 type AllQuestion struct {
-	ID       primitive.ObjectID `json:"id" bson:"_id"`
-	Videourl string             `json:"videourl"`
-	Category string             `json:"Category"`
-	Name     string             `json:"Name"`
-	Link     string             `json:"Link"`
-	Id       int                `json:"Id"`
-	Level    string             `json:"Level"`
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	Videourl    string             `json:"videourl" validate:"max=2048"`
+	Category    string             `json:"Category" validate:"omitempty,questioncategory"`
+	Name        string             `json:"Name" validate:"required,max=200"`
+	Link        string             `json:"Link" validate:"required,max=2048"`
+	Id          int                `json:"Id"`
+	Level       string             `json:"Level" validate:"required,questionlevel"`
+	Resources   []Resource         `json:"resources,omitempty" bson:"resources,omitempty" validate:"dive"`
+	Languages   []string           `json:"languages,omitempty" bson:"languages,omitempty" validate:"dive,max=32"`
+	StarterCode map[string]string  `json:"starterCode,omitempty" bson:"starterCode,omitempty" validate:"dive,keys,max=32,endkeys,max=20000"`
+	Hints       []string           `json:"hints,omitempty" bson:"hints,omitempty" validate:"dive,max=1000"`
+	Status      string             `json:"status,omitempty" bson:"status,omitempty" validate:"omitempty,questionstatus"`
+	Tags        []string           `json:"tags,omitempty" bson:"tags,omitempty" validate:"dive,max=32"`
+	Companies   []string           `json:"companies,omitempty" bson:"companies,omitempty" validate:"max=50,dive,max=64"`
+	Embedding   []float32          `json:"-" bson:"embedding,omitempty"`
+}
+
+// The fields of stored questions that the repository filters on.
+const (
+	fieldID        filter.Field = "_id"
+	fieldNumber    filter.Field = "id"
+	fieldName      filter.Field = "name"
+	fieldCategory  filter.Field = "category"
+	fieldLevel     filter.Field = "level"
+	fieldLanguages filter.Field = "languages"
+	fieldStatus    filter.Field = "status"
+	fieldTags      filter.Field = "tags"
+	fieldResources filter.Field = "resources"
+	fieldEmbedding filter.Field = "embedding"
+	fieldCompanies filter.Field = "companies"
+)
+
+// The function returns a filter builder matching the questions users may see: the published ones and
+// the ones stored before questions had a status.
+func published() *filter.Builder {
+	return filter.New().In(fieldStatus, bson.A{StatusPublished, nil})
+}
+
+// The function returns the status of the question, which is published for questions stored before
+// questions had a status.
+func (q *AllQuestion) EffectiveStatus() string {
+	if q.Status == "" {
+		return StatusPublished
+	}
+	return q.Status
+}
+
+// The function reports whether the question is shown to users.
+func (q *AllQuestion) Published() bool {
+	return q.EffectiveStatus() == StatusPublished
+}
+
+// The function builds the `Resources` of a question from its legacy `Link` and `Videourl` fields.
+func (q *AllQuestion) LegacyResources() []Resource {
+	resources := []Resource{}
+	if q.Link != "" {
+		resources = append(resources, Resource{Type: ResourceProblem, URL: q.Link})
+	}
+	if q.Videourl != "" {
+		resources = append(resources, Resource{Type: ResourceVideo, URL: q.Videourl})
+	}
+	return resources
+}
+
+// The function returns the canonical form of a language name, so "Go" and " go" are stored and matched
+// as "go".
+func NormalizeLanguage(language string) string {
+	return strings.ToLower(strings.TrimSpace(language))
+}
+
+// The function returns the first `upto` hints of the question, or all of them when `upto` is negative
+// or larger than the number of hints.
+func (q *AllQuestion) HintsUpTo(upto int) []string {
+	if upto < 0 || upto > len(q.Hints) {
+		upto = len(q.Hints)
+	}
+	return append([]string{}, q.Hints[:upto]...)
+}
+
+// The PopularQuestion type is an AllQuestion together with the number of users that have solved it.
+// @property {int64} SolveCount - The number of solve entries recorded for the question in the progress
+// collection. Questions nobody has solved yet have a count of 0.
+type PopularQuestion struct {
+	AllQuestion `bson:",inline"`
+	SolveCount  int64 `json:"solveCount" bson:"solveCount"`
 }
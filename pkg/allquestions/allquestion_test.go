@@ -0,0 +1,144 @@
+package allquestions
+
+import (
+	"reflect"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function decodes a stored document into a question.
+func decodeQuestion(t *testing.T, document bson.M) AllQuestion {
+	t.Helper()
+	data, err := bson.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var question AllQuestion
+	if err := bson.Unmarshal(data, &question); err != nil {
+		t.Fatalf("decoding %v: %v", document, err)
+	}
+	return question
+}
+
+func TestDecodeLegacyQuestion(t *testing.T) {
+	question := decodeQuestion(t, bson.M{
+		"_id":      primitive.NewObjectID(),
+		"name":     "Two Sum",
+		"link":     "https://leetcode.com/problems/two-sum",
+		"videourl": "https://youtu.be/two-sum",
+		"id":       1,
+		"level":    "Easy",
+		"category": "Arrays",
+	})
+	if question.Link != "https://leetcode.com/problems/two-sum" || question.Videourl != "https://youtu.be/two-sum" {
+		t.Errorf("legacy links = %q, %q", question.Link, question.Videourl)
+	}
+	if question.Resources != nil || question.Languages != nil || question.Hints != nil || question.Status != "" {
+		t.Errorf("fields added after the document was stored should be empty, got %+v", question)
+	}
+	want := []Resource{
+		{Type: ResourceProblem, URL: "https://leetcode.com/problems/two-sum"},
+		{Type: ResourceVideo, URL: "https://youtu.be/two-sum"},
+	}
+	if got := question.LegacyResources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LegacyResources() = %v, want %v", got, want)
+	}
+	if !question.Published() {
+		t.Error("a question stored before questions had a status should count as published")
+	}
+}
+
+func TestDecodeQuestionWithResources(t *testing.T) {
+	question := decodeQuestion(t, bson.M{
+		"_id":  primitive.NewObjectID(),
+		"name": "Two Sum",
+		"link": "https://leetcode.com/problems/two-sum",
+		"resources": bson.A{
+			bson.M{"type": ResourceProblem, "url": "https://leetcode.com/problems/two-sum"},
+			bson.M{"type": ResourceEditorial, "url": "https://leetcode.com/problems/two-sum/editorial"},
+			bson.M{"type": ResourceVideo, "url": "https://youtu.be/a"},
+			bson.M{"type": ResourceVideo, "url": "https://youtu.be/b"},
+		},
+	})
+	if len(question.Resources) != 4 || question.Resources[1].Type != ResourceEditorial || question.Resources[3].URL != "https://youtu.be/b" {
+		t.Errorf("Resources = %v", question.Resources)
+	}
+}
+
+func TestLegacyResourcesWithoutLinks(t *testing.T) {
+	question := AllQuestion{Name: "Two Sum"}
+	if got := question.LegacyResources(); got == nil || len(got) != 0 {
+		t.Errorf("LegacyResources() = %#v, want an empty slice", got)
+	}
+}
+
+func TestResourceValid(t *testing.T) {
+	tests := []struct {
+		resource Resource
+		want     bool
+	}{
+		{Resource{Type: ResourceProblem, URL: "https://a"}, true},
+		{Resource{Type: ResourceEditorial, URL: "https://a"}, true},
+		{Resource{Type: ResourceVideo, URL: "https://a"}, true},
+		{Resource{Type: ResourceVideo}, false},
+		{Resource{Type: "podcast", URL: "https://a"}, false},
+	}
+	for _, test := range tests {
+		if got := test.resource.Valid(); got != test.want {
+			t.Errorf("%+v.Valid() = %v, want %v", test.resource, got, test.want)
+		}
+	}
+}
+
+func TestBackfillResources(t *testing.T) {
+	dbtest.Run(t, "backfill", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		legacy := AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Link: "https://leetcode.com/problems/two-sum"}
+		mt.AddMockResponses(dbtest.Cursor(mt, legacy), dbtest.Written(1))
+		updated, err := repo.BackfillResources()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if updated != 1 {
+			mt.Errorf("BackfillResources() = %d, want 1", updated)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"resources": bson.M{"$exists": false}})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "update"), "updates", bson.A{bson.M{
+			"q": bson.M{"_id": legacy.ID},
+			"u": bson.M{"$set": bson.M{"resources": bson.A{bson.M{"type": ResourceProblem, "url": legacy.Link}}}},
+		}})
+	})
+}
+
+func TestHintsUpTo(t *testing.T) {
+	question := AllQuestion{Hints: []string{"sort first", "two pointers", "mind duplicates"}}
+	tests := []struct {
+		upto int
+		want []string
+	}{
+		{upto: 0, want: []string{}},
+		{upto: 2, want: []string{"sort first", "two pointers"}},
+		{upto: 3, want: question.Hints},
+		{upto: 10, want: question.Hints},
+		{upto: -1, want: question.Hints},
+	}
+	for _, tt := range tests {
+		got := question.HintsUpTo(tt.upto)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("HintsUpTo(%d) = %q, want %q", tt.upto, got, tt.want)
+		}
+	}
+
+	if got := (&AllQuestion{}).HintsUpTo(2); got == nil || len(got) != 0 {
+		t.Errorf("HintsUpTo() of a question without hints = %#v, want an empty list", got)
+	}
+	got := question.HintsUpTo(1)
+	got[0] = "changed"
+	if question.Hints[0] != "sort first" {
+		t.Error("changing the returned hints changed the question's")
+	}
+}
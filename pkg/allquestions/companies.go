@@ -0,0 +1,82 @@
+package allquestions
+
+import (
+	"regexp"
+	"sigmacoder/pkg/filter"
+	"sigmacoder/pkg/retry"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The CompanyCount type is a company together with the number of published questions it asks.
+// @property {string} Company - The name of the company. When questions spell it differently, such as
+// "Google" and "google", the spelling that sorts first is returned.
+// @property {int64} Count - The number of published questions that list the company.
+type CompanyCount struct {
+	Company string `json:"company" bson:"company"`
+	Count   int64  `json:"count" bson:"count"`
+}
+
+// The function returns the canonical form of a company name: trimmed, with runs of whitespace
+// collapsed to one space. Unlike tags, the case is kept for display and ignored when matching.
+func NormalizeCompany(company string) string {
+	return strings.Join(strings.Fields(company), " ")
+}
+
+// The function normalizes a list of companies, dropping empty ones and ones repeated in a different
+// case, of which the first spelling is kept.
+func NormalizeCompanies(companies []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, company := range companies {
+		company = NormalizeCompany(company)
+		key := strings.ToLower(company)
+		if company == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, company)
+	}
+	return normalized
+}
+
+// The function adds the condition that a question lists at least one of the companies, ignoring case,
+// to a filter builder. An empty list adds no condition.
+func companyFilter(builder *filter.Builder, companies []string) {
+	if len(companies) == 0 {
+		return
+	}
+	patterns := make([]primitive.Regex, 0, len(companies))
+	for _, company := range companies {
+		patterns = append(patterns, primitive.Regex{Pattern: "^" + regexp.QuoteMeta(company) + "$", Options: "i"})
+	}
+	builder.In(fieldCompanies, patterns)
+}
+
+// The `ListCompanies` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns every company listed by a published question, ignoring case, with the number
+// of published questions listing it, most asked first and ties in alphabetical order.
+func (s *Repo) ListCompanies() ([]CompanyCount, error) {
+	counts := []CompanyCount{}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: published().Build()}},
+		{{Key: "$unwind", Value: "$companies"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     bson.M{"$toLower": "$companies"},
+			"company": bson.M{"$min": "$companies"},
+			"count":   bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &counts)
+	})
+	return counts, err
+}
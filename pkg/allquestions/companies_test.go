@@ -0,0 +1,90 @@
+package allquestions
+
+import (
+	"reflect"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/filter"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestNormalizeCompanies(t *testing.T) {
+	tests := []struct {
+		companies []string
+		want      []string
+	}{
+		{companies: nil, want: []string{}},
+		{companies: []string{" Google", "Jane  Street "}, want: []string{"Google", "Jane Street"}},
+		{companies: []string{"Google", "", "  ", "google", "GOOGLE", "Meta"}, want: []string{"Google", "Meta"}},
+	}
+	for _, tt := range tests {
+		if got := NormalizeCompanies(tt.companies); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NormalizeCompanies(%q) = %q, want %q", tt.companies, got, tt.want)
+		}
+	}
+}
+
+func TestCompanyFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		companies []string
+		want      bson.M
+	}{
+		{name: "no companies", want: bson.M{}},
+		{
+			name:      "any company, ignoring case",
+			companies: []string{"Google", "Jane Street"},
+			want: bson.M{"companies": bson.M{"$in": []primitive.Regex{
+				{Pattern: "^Google$", Options: "i"},
+				{Pattern: "^Jane Street$", Options: "i"},
+			}}},
+		},
+		{
+			name:      "names are matched literally",
+			companies: []string{"C++ Labs"},
+			want:      bson.M{"companies": bson.M{"$in": []primitive.Regex{{Pattern: `^C\+\+ Labs$`, Options: "i"}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := filter.New()
+			companyFilter(builder, tt.companies)
+			if got := builder.Build(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("companyFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListCompanies(t *testing.T) {
+	dbtest.Run(t, "counted ignoring case", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt,
+			bson.M{"_id": "google", "company": "Google", "count": 3},
+			bson.M{"_id": "meta", "company": "Meta", "count": 1},
+		))
+		companies, err := NewRepo(mt.DB).(*Repo).ListCompanies()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if want := []CompanyCount{{Company: "Google", Count: 3}, {Company: "Meta", Count: 1}}; !reflect.DeepEqual(companies, want) {
+			mt.Errorf("ListCompanies() = %v, want %v", companies, want)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$match": bson.M{"status": publishedStatus}},
+			bson.M{"$unwind": "$companies"},
+			bson.M{"$group": bson.M{"_id": bson.M{"$toLower": "$companies"}, "company": bson.M{"$min": "$companies"}, "count": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		})
+	})
+
+	dbtest.Run(t, "no companies", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		companies, err := NewRepo(mt.DB).(*Repo).ListCompanies()
+		if err != nil || companies == nil || len(companies) != 0 {
+			mt.Errorf("ListCompanies() = %v, %v, want an empty list", companies, err)
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package allquestions
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sigmacoder/pkg"
+	"strconv"
+	"strings"
+)
+
+// The fields of a question a CSV column can be mapped to. List fields hold several values in one cell,
+// separated by `CSVListSeparator`.
+const (
+	CSVFieldName      = "name"
+	CSVFieldLink      = "link"
+	CSVFieldLevel     = "level"
+	CSVFieldCategory  = "category"
+	CSVFieldNumber    = "id"
+	CSVFieldVideoURL  = "videourl"
+	CSVFieldStatus    = "status"
+	CSVFieldLanguages = "languages"
+	CSVFieldTags      = "tags"
+	CSVFieldHints     = "hints"
+	CSVFieldCompanies = "companies"
+)
+
+// `CSVFields` lists every field a CSV column can be mapped to.
+var CSVFields = []string{
+	CSVFieldName, CSVFieldLink, CSVFieldLevel, CSVFieldCategory, CSVFieldNumber, CSVFieldVideoURL,
+	CSVFieldStatus, CSVFieldLanguages, CSVFieldTags, CSVFieldHints, CSVFieldCompanies,
+}
+
+// `CSVListSeparator` separates the values of a list field, such as the tags, in a single cell.
+const CSVListSeparator = ";"
+
+// The CSVRow type is a question read from one line of a CSV file.
+// @property {int} Line - The line of the file the question was read from, starting at 1 for the header.
+// @property Question - The question, with the cells of the mapped columns.
+// @property Err - Why the line couldn't be read as a question, such as a malformed quote or a number
+// that isn't one. When it is set, `Question` is incomplete.
+type CSVRow struct {
+	Line     int
+	Question AllQuestion
+	Err      error
+}
+
+// The function returns the field every column of the header is read into, or an empty string for
+// columns that are ignored. Columns named in `mapping` are read into the field it gives; the other
+// columns are read into the field with their name, ignoring case and surrounding spaces, if there is
+// one.
+func csvColumns(header []string, mapping map[string]string) ([]string, error) {
+	normalized := map[string]string{}
+	for column, field := range mapping {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !contains(CSVFields, field) {
+			return nil, fmt.Errorf("%w: column %q is mapped to unknown field %q", pkg.ErrInvalidCSV, column, field)
+		}
+		normalized[strings.ToLower(strings.TrimSpace(column))] = field
+	}
+	columns := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		field, ok := normalized[column]
+		if !ok && contains(CSVFields, column) {
+			field = column
+		}
+		if field == "" {
+			continue
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("%w: more than one column is read into %q", pkg.ErrInvalidCSV, field)
+		}
+		seen[field] = true
+		columns[i] = field
+	}
+	return columns, nil
+}
+
+// The function splits a list cell into its values, dropping empty ones.
+func csvList(cell string) []string {
+	values := []string{}
+	for _, value := range strings.Split(cell, CSVListSeparator) {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// The function sets the field of the question a cell is mapped to. Empty cells leave the field unset.
+func setCSVField(question *AllQuestion, field string, cell string) error {
+	cell = strings.TrimSpace(cell)
+	if cell == "" {
+		return nil
+	}
+	switch field {
+	case CSVFieldName:
+		question.Name = cell
+	case CSVFieldLink:
+		question.Link = cell
+	case CSVFieldLevel:
+		question.Level = cell
+	case CSVFieldCategory:
+		question.Category = cell
+	case CSVFieldVideoURL:
+		question.Videourl = cell
+	case CSVFieldStatus:
+		question.Status = cell
+	case CSVFieldNumber:
+		number, err := strconv.Atoi(cell)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", field, cell)
+		}
+		question.Id = number
+	case CSVFieldLanguages:
+		question.Languages = csvList(cell)
+	case CSVFieldTags:
+		question.Tags = csvList(cell)
+	case CSVFieldHints:
+		question.Hints = csvList(cell)
+	case CSVFieldCompanies:
+		question.Companies = csvList(cell)
+	}
+	return nil
+}
+
+// The function reads questions from a CSV file whose first line is a header naming the columns, one
+// question per following line. `mapping` maps column names, such as "Problem Title", to the
+// `CSVFields` they hold; columns that aren't mapped are read into the field with their name if there
+// is one, and ignored otherwise. Quoted cells may contain commas, quotes and line breaks, and a UTF-8
+// byte order mark before the header, as written by spreadsheet programs, is skipped. A line that
+// can't be read is returned with its error and the following lines are still read. It returns
+// `pkg.ErrInvalidCSV` when the file has no header or the mapping is invalid.
+func ParseCSV(r io.Reader, mapping map[string]string) ([]CSVRow, error) {
+	buffered := bufio.NewReader(r)
+	if bom, _, err := buffered.ReadRune(); err == nil && bom != '\ufeff' {
+		buffered.UnreadRune()
+	}
+	reader := csv.NewReader(buffered)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: the file is empty", pkg.ErrInvalidCSV)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", pkg.ErrInvalidCSV, err)
+	}
+	columns, err := csvColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+	rows := []CSVRow{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, CSVRow{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return rows, err
+		}
+		line, _ := reader.FieldPos(0)
+		row := CSVRow{Line: line}
+		for i, cell := range record {
+			if columns[i] == "" {
+				continue
+			}
+			if err := setCSVField(&row.Question, columns[i], cell); err != nil {
+				row.Err = err
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,82 @@
+package allquestions
+
+import (
+	"errors"
+	"reflect"
+	"sigmacoder/pkg"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	t.Run("well formed", func(t *testing.T) {
+		file := "\ufeffName,Link,Level,Category,id,tags,Notes\n" +
+			"Two Sum,https://example.com/two-sum,Easy,Arrays,1,array; hash-table ;,ignored\n" +
+			"\"Sum, Again\",https://example.com/sum,Medium,,2,,\n" +
+			"\"The \"\"Quoted\"\" One\",\"https://example.com/\nquoted\",Hard,Strings,3,dp,\n"
+		rows, err := ParseCSV(strings.NewReader(file), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []CSVRow{
+			{Line: 2, Question: AllQuestion{Name: "Two Sum", Link: "https://example.com/two-sum", Level: LevelEasy, Category: "Arrays", Id: 1, Tags: []string{"array", "hash-table"}}},
+			{Line: 3, Question: AllQuestion{Name: "Sum, Again", Link: "https://example.com/sum", Level: LevelMedium, Id: 2}},
+			{Line: 4, Question: AllQuestion{Name: `The "Quoted" One`, Link: "https://example.com/\nquoted", Level: LevelHard, Category: "Strings", Id: 3, Tags: []string{"dp"}}},
+		}
+		if !reflect.DeepEqual(rows, want) {
+			t.Errorf("ParseCSV() = %+v, want %+v", rows, want)
+		}
+	})
+
+	t.Run("header mapping", func(t *testing.T) {
+		file := "Problem Title,URL,Difficulty,name\nTwo Sum,https://example.com/two-sum,Easy,\n"
+		rows, err := ParseCSV(strings.NewReader(file), map[string]string{"problem title": "name", " URL ": "Link", "Difficulty": "level", "name": "category"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := AllQuestion{Name: "Two Sum", Link: "https://example.com/two-sum", Level: LevelEasy}
+		if len(rows) != 1 || !reflect.DeepEqual(rows[0].Question, want) {
+			t.Errorf("ParseCSV() = %+v, want %+v", rows, want)
+		}
+	})
+
+	t.Run("malformed lines", func(t *testing.T) {
+		file := "name,link,level,id\n" +
+			"Two Sum,https://example.com/two-sum,Easy,one\n" +
+			"Bad \"quote,https://example.com,Easy,2\n" +
+			"Valid Parentheses,https://example.com/parens,Easy,3\n"
+		rows, err := ParseCSV(strings.NewReader(file), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 3 {
+			t.Fatalf("ParseCSV() returned %d rows, want 3: %+v", len(rows), rows)
+		}
+		if rows[0].Line != 2 || rows[0].Err == nil || !strings.Contains(rows[0].Err.Error(), "must be a number") {
+			t.Errorf("row 0 = %+v, want line 2 refused for its id", rows[0])
+		}
+		if rows[1].Line != 3 || rows[1].Err == nil {
+			t.Errorf("row 1 = %+v, want line 3 refused for its quote", rows[1])
+		}
+		if rows[2].Err != nil || rows[2].Question.Name != "Valid Parentheses" {
+			t.Errorf("row 2 = %+v, want the line after the malformed one read", rows[2])
+		}
+	})
+
+	for _, tt := range []struct {
+		name    string
+		file    string
+		mapping map[string]string
+	}{
+		{name: "empty file", file: ""},
+		{name: "only a byte order mark", file: "\ufeff"},
+		{name: "unknown field", file: "title\nTwo Sum\n", mapping: map[string]string{"title": "headline"}},
+		{name: "two columns for one field", file: "name,title\nTwo Sum,Two Sum\n", mapping: map[string]string{"title": "name"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCSV(strings.NewReader(tt.file), tt.mapping); !errors.Is(err, pkg.ErrInvalidCSV) {
+				t.Errorf("ParseCSV() = %v, want %v", err, pkg.ErrInvalidCSV)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package allquestions
+
+// The difficulty levels a question can have.
+const (
+	LevelEasy   = "Easy"
+	LevelMedium = "Medium"
+	LevelHard   = "Hard"
+)
+
+// `LevelEnum` lists every known difficulty level, easiest first.
+var LevelEnum = []string{LevelEasy, LevelMedium, LevelHard}
+
+// The publication statuses a question can have. Only published questions are shown to users; drafts are
+// staged by admins before they go public and archived questions are retired ones.
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// `StatusEnum` lists every known publication status.
+var StatusEnum = []string{StatusDraft, StatusPublished, StatusArchived}
+
+// `CategoryEnum` lists every known question category. Questions can only be created in, or moved to,
+// one of these; add a category here before using it.
+var CategoryEnum = []string{
+	"Arrays",
+	"Strings",
+	"Hashing",
+	"Two Pointers",
+	"Sliding Window",
+	"Stack",
+	"Queue",
+	"Linked List",
+	"Binary Search",
+	"Sorting",
+	"Recursion",
+	"Backtracking",
+	"Trees",
+	"Heap",
+	"Trie",
+	"Graphs",
+	"Greedy",
+	"Dynamic Programming",
+	"Bit Manipulation",
+	"Math",
+}
+
+// The function reports whether the level is one of `LevelEnum`.
+func ValidLevel(level string) bool {
+	return contains(LevelEnum, level)
+}
+
+// The function reports whether the category is one of `CategoryEnum`.
+func ValidCategory(category string) bool {
+	return contains(CategoryEnum, category)
+}
+
+// The function reports whether the status is one of `StatusEnum`.
+func ValidStatus(status string) bool {
+	return contains(StatusEnum, status)
+}
+
+// The function reports whether the list contains the value.
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package allquestions
+
+import (
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The Neighbors type is the questions before and after a question in ascending `Id` order, for the
+// previous and next buttons of a problem page.
+// @property Previous - The published question with the closest lower `Id`, or nil for the first one.
+// @property Next - The published question with the closest higher `Id`, or nil for the last one.
+type Neighbors struct {
+	Previous *AllQuestion `json:"previous"`
+	Next     *AllQuestion `json:"next"`
+}
+
+// The function returns the published question closest to `number`, the one before it when `before` is
+// true and the one after it otherwise, matching the category and level in `same` when they aren't
+// empty, or nil when there is none.
+func (s *Repo) adjacent(number int, before bool, same AllQuestion) (*AllQuestion, error) {
+	builder := published().EqIfSet(fieldCategory, same.Category).EqIfSet(fieldLevel, same.Level)
+	order := 1
+	if before {
+		builder.Lt(fieldNumber, number)
+		order = -1
+	} else {
+		builder.Gt(fieldNumber, number)
+	}
+	query := builder.Build()
+	var question AllQuestion
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, query, options.FindOne().SetSort(bson.M{"id": order})).Decode(&question)
+	})
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+// The `Neighbors` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the published questions right before and after the given question by `Id`,
+// each found with a single indexed query. `sameCategory` and `sameLevel` skip the questions of other
+// categories and levels. It returns `mongo.ErrNoDocuments` when the question doesn't exist or isn't
+// published.
+func (s *Repo) Neighbors(id string, sameCategory bool, sameLevel bool) (Neighbors, error) {
+	var neighbors Neighbors
+	question, err := s.ReadByID(id)
+	if err != nil {
+		return neighbors, err
+	}
+	if !question.Published() {
+		return neighbors, mongo.ErrNoDocuments
+	}
+	var same AllQuestion
+	if sameCategory {
+		same.Category = question.Category
+	}
+	if sameLevel {
+		same.Level = question.Level
+	}
+	if neighbors.Previous, err = s.adjacent(question.Id, true, same); err != nil {
+		return neighbors, err
+	}
+	neighbors.Next, err = s.adjacent(question.Id, false, same)
+	return neighbors, err
+}
@@ -0,0 +1,110 @@
+package allquestions
+
+import (
+	"errors"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function checks that the next command is the lookup of the question before or after `number`
+// with `filter` on top of the published condition.
+func expectAdjacent(mt *mtest.T, number int, before bool, filter bson.M) {
+	mt.Helper()
+	want := bson.M{"status": publishedStatus}
+	for key, value := range filter {
+		want[key] = value
+	}
+	order, operator := int32(1), "$gt"
+	if before {
+		order, operator = -1, "$lt"
+	}
+	want["id"] = bson.M{operator: number}
+	find := dbtest.NextCommand(mt, "find")
+	dbtest.ExpectField(mt, find, "filter", want)
+	dbtest.ExpectField(mt, find, "sort", bson.M{"id": order})
+	dbtest.ExpectField(mt, find, "limit", int64(1))
+}
+
+func TestNeighbors(t *testing.T) {
+	first := AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Category: "Arrays", Level: "Easy", Id: 1}
+	middle := AllQuestion{ID: primitive.NewObjectID(), Name: "Contains Duplicate", Category: "Arrays", Level: "Easy", Id: 2}
+	last := AllQuestion{ID: primitive.NewObjectID(), Name: "3Sum", Category: "Arrays", Level: "Medium", Id: 3}
+
+	dbtest.Run(t, "middle question", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, middle), dbtest.Cursor(mt, first), dbtest.Cursor(mt, last))
+		neighbors, err := repo.Neighbors(middle.ID.Hex(), false, false)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if neighbors.Previous == nil || neighbors.Previous.ID != first.ID || neighbors.Next == nil || neighbors.Next.ID != last.ID {
+			mt.Errorf("Neighbors() = %+v, want the first question before and the last one after", neighbors)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": middle.ID})
+		expectAdjacent(mt, middle.Id, true, nil)
+		expectAdjacent(mt, middle.Id, false, nil)
+	})
+
+	dbtest.Run(t, "first question", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, first), dbtest.Cursor(mt), dbtest.Cursor(mt, middle))
+		neighbors, err := repo.Neighbors(first.ID.Hex(), false, false)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if neighbors.Previous != nil || neighbors.Next == nil || neighbors.Next.ID != middle.ID {
+			mt.Errorf("Neighbors() = %+v, want no previous question and the middle one after", neighbors)
+		}
+	})
+
+	dbtest.Run(t, "last question", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, last), dbtest.Cursor(mt, middle), dbtest.Cursor(mt))
+		neighbors, err := repo.Neighbors(last.ID.Hex(), false, false)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if neighbors.Previous == nil || neighbors.Previous.ID != middle.ID || neighbors.Next != nil {
+			mt.Errorf("Neighbors() = %+v, want the middle question before and no next one", neighbors)
+		}
+	})
+
+	dbtest.Run(t, "same category and level", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, middle), dbtest.Cursor(mt, first), dbtest.Cursor(mt))
+		if _, err := repo.Neighbors(middle.ID.Hex(), true, true); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.NextCommand(mt, "find")
+		expectAdjacent(mt, middle.Id, true, bson.M{"category": "Arrays", "level": "Easy"})
+		expectAdjacent(mt, middle.Id, false, bson.M{"category": "Arrays", "level": "Easy"})
+	})
+
+	dbtest.Run(t, "same category only", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, middle), dbtest.Cursor(mt), dbtest.Cursor(mt))
+		if _, err := repo.Neighbors(middle.ID.Hex(), true, false); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.NextCommand(mt, "find")
+		expectAdjacent(mt, middle.Id, true, bson.M{"category": "Arrays"})
+		expectAdjacent(mt, middle.Id, false, bson.M{"category": "Arrays"})
+	})
+
+	dbtest.Run(t, "unpublished question", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		draft := middle
+		draft.Status = StatusDraft
+		mt.AddMockResponses(dbtest.Cursor(mt, draft))
+		if _, err := repo.Neighbors(middle.ID.Hex(), false, false); !errors.Is(err, mongo.ErrNoDocuments) {
+			mt.Fatalf("Neighbors() = %v, want %v", err, mongo.ErrNoDocuments)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
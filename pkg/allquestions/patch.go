@@ -0,0 +1,122 @@
+package allquestions
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sigmacoder/pkg"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The type patchField is a field of a question that a partial update can change.
+// @property {int} index - The index of the field in `AllQuestion`.
+// @property {string} stored - The name the field is stored under.
+// @property {bool} omitEmpty - Whether the field is left out of stored questions when it is empty.
+type patchField struct {
+	index     int
+	stored    string
+	omitEmpty bool
+}
+
+// `patchFields` are the fields a partial update can change, keyed by their JSON name. The ObjectID,
+// the question number and the fields that aren't part of the JSON, such as the embedding, can't be
+// changed this way.
+var patchFields = func() map[string]patchField {
+	fields := map[string]patchField{}
+	questionType := reflect.TypeOf(AllQuestion{})
+	for i := 0; i < questionType.NumField(); i++ {
+		field := questionType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || isIDKey(name) {
+			continue
+		}
+		stored := strings.Split(field.Tag.Get("bson"), ",")
+		patch := patchField{index: i, stored: stored[0], omitEmpty: len(stored) > 1 && stored[1] == "omitempty"}
+		if patch.stored == "" {
+			patch.stored = strings.ToLower(field.Name)
+		}
+		fields[name] = patch
+	}
+	return fields
+}()
+
+// The function reports whether the JSON key names the question's ObjectID or its number, which
+// identify the question and can't be changed by a partial update: "_id", or "id" in any case, such as
+// the "Id" of the number.
+func isIDKey(key string) bool {
+	return key == "_id" || strings.EqualFold(key, "id")
+}
+
+// The function reports whether the value is one that `omitempty` leaves out of stored documents: a
+// zero value, or an empty list or map.
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// The function applies a partial update, a JSON object holding some of the question's fields, to the
+// question and returns the JSON names of the fields it changed. Only the fields present in the object
+// are changed: an omitted field is left alone, while a field given as an empty string, list or null is
+// cleared. The status is the exception: it can't be cleared, since questions without one count as
+// published. Keys must be spelled exactly as in the question's JSON. It fails with
+// `pkg.ErrQuestionIDImmutable` when the object tries to change the ObjectID or the question number, and
+// with `pkg.ErrInvalidBody` when it isn't an object, has an unknown key or a value of the wrong type.
+// The caller must still validate the updated question before passing it to `PatchUpdate`.
+func ApplyPatch(question *AllQuestion, body []byte) ([]string, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(body, &values); err != nil || values == nil {
+		return nil, fmt.Errorf("%w: the body must be a JSON object", pkg.ErrInvalidBody)
+	}
+	changed := make([]string, 0, len(values))
+	target := reflect.ValueOf(question).Elem()
+	for key, data := range values {
+		if isIDKey(key) {
+			return nil, pkg.ErrQuestionIDImmutable
+		}
+		field, ok := patchFields[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", pkg.ErrInvalidBody, key)
+		}
+		value := reflect.New(target.Field(field.index).Type())
+		if err := json.Unmarshal(data, value.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %s has the wrong type", pkg.ErrInvalidBody, key)
+		}
+		if field.stored == string(fieldStatus) && isEmpty(value.Elem()) {
+			return nil, fmt.Errorf("%w: status can't be cleared", pkg.ErrInvalidBody)
+		}
+		target.Field(field.index).Set(value.Elem())
+		changed = append(changed, key)
+	}
+	return changed, nil
+}
+
+// The function returns the update document that stores the given fields of the question, named as
+// returned by `ApplyPatch`, and leaves every other field alone. The values are read from the question
+// itself, so they include whatever normalization happened while it was validated. Fields that are left
+// out of stored questions when empty are removed with `$unset` rather than set to an empty value.
+func PatchUpdate(question AllQuestion, changed []string) bson.M {
+	set, unset := bson.M{}, bson.M{}
+	source := reflect.ValueOf(question)
+	for _, key := range changed {
+		field := patchFields[key]
+		value := source.Field(field.index)
+		if field.omitEmpty && isEmpty(value) {
+			unset[field.stored] = ""
+		} else {
+			set[field.stored] = value.Interface()
+		}
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
@@ -0,0 +1,132 @@
+package allquestions
+
+import (
+	"errors"
+	"reflect"
+	"sigmacoder/pkg"
+	"sort"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestApplyPatch(t *testing.T) {
+	id := primitive.NewObjectID()
+	// The function returns a fully populated question, so every cleared field shows.
+	original := func() AllQuestion {
+		return AllQuestion{
+			ID:        id,
+			Id:        7,
+			Name:      "Two Sum",
+			Link:      "https://example.com/two-sum",
+			Videourl:  "https://example.com/video",
+			Category:  "Arrays",
+			Level:     LevelEasy,
+			Status:    StatusPublished,
+			Tags:      []string{"hash-table"},
+			Hints:     []string{"use a map"},
+			Companies: []string{"Acme"},
+			Embedding: []float32{1, 0},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		changed []string
+		want    func(q *AllQuestion)
+	}{
+		{
+			name:    "omitted fields are untouched",
+			body:    `{"Name": "Two Sum II"}`,
+			changed: []string{"Name"},
+			want:    func(q *AllQuestion) { q.Name = "Two Sum II" },
+		},
+		{
+			name:    "empty string clears",
+			body:    `{"videourl": "", "Category": ""}`,
+			changed: []string{"Category", "videourl"},
+			want:    func(q *AllQuestion) { q.Videourl, q.Category = "", "" },
+		},
+		{
+			name:    "null and empty lists clear",
+			body:    `{"tags": null, "hints": [], "companies": null}`,
+			changed: []string{"companies", "hints", "tags"},
+			want:    func(q *AllQuestion) { q.Tags, q.Hints, q.Companies = nil, []string{}, nil },
+		},
+		{
+			name:    "list replaced",
+			body:    `{"tags": ["dp", "array"]}`,
+			changed: []string{"tags"},
+			want:    func(q *AllQuestion) { q.Tags = []string{"dp", "array"} },
+		},
+		{
+			name:    "empty object",
+			body:    `{}`,
+			changed: []string{},
+			want:    func(q *AllQuestion) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question := original()
+			changed, err := ApplyPatch(&question, []byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(changed)
+			if !reflect.DeepEqual(changed, tt.changed) {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			want := original()
+			tt.want(&want)
+			if !reflect.DeepEqual(question, want) {
+				t.Errorf("question = %+v, want %+v", question, want)
+			}
+		})
+	}
+
+	for _, body := range []string{`{"_id": "65f000000000000000000000"}`, `{"id": "65f000000000000000000000"}`, `{"ID": "x"}`, `{"Id": 5}`, `{"iD": 5, "Name": "Two Sum II"}`} {
+		t.Run("immutable "+body, func(t *testing.T) {
+			question := original()
+			if _, err := ApplyPatch(&question, []byte(body)); !errors.Is(err, pkg.ErrQuestionIDImmutable) {
+				t.Errorf("ApplyPatch() = %v, want %v", err, pkg.ErrQuestionIDImmutable)
+			}
+			if question.ID != id || question.Id != 7 {
+				t.Errorf("question = %+v, want its IDs kept", question)
+			}
+		})
+	}
+
+	for _, tt := range []struct{ name, body string }{
+		{name: "not an object", body: `["Name"]`},
+		{name: "null body", body: `null`},
+		{name: "unknown field", body: `{"name": "two sum"}`},
+		{name: "embedding", body: `{"embedding": [1, 2]}`},
+		{name: "wrong type", body: `{"tags": "dp"}`},
+		{name: "status cleared", body: `{"status": ""}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			question := original()
+			if _, err := ApplyPatch(&question, []byte(tt.body)); !errors.Is(err, pkg.ErrInvalidBody) {
+				t.Errorf("ApplyPatch() = %v, want %v", err, pkg.ErrInvalidBody)
+			}
+		})
+	}
+}
+
+func TestPatchUpdate(t *testing.T) {
+	question := AllQuestion{Name: "Two Sum II", Videourl: "", Category: "", Tags: nil, Hints: []string{"sort first"}}
+	got := PatchUpdate(question, []string{"Name", "videourl", "Category", "tags", "hints"})
+	want := bson.M{
+		"$set":   bson.M{"name": "Two Sum II", "videourl": "", "category": "", "hints": []string{"sort first"}},
+		"$unset": bson.M{"tags": ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PatchUpdate() = %v, want %v", got, want)
+	}
+	if got := PatchUpdate(question, nil); len(got) != 0 {
+		t.Errorf("PatchUpdate() of nothing = %v, want an empty update", got)
+	}
+}
@@ -0,0 +1,147 @@
+package allquestions
+
+import (
+	"sigmacoder/pkg/retry"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The orders a question listing can be sorted in. Prefixing one with "-" reverses it. Levels sort
+// from easiest to hardest, following `LevelEnum`.
+const (
+	SortNumber = "id"
+	SortName   = "name"
+	SortLevel  = "level"
+)
+
+// `SortEnum` lists every order a question listing can be sorted in, ascending.
+var SortEnum = []string{SortNumber, SortName, SortLevel}
+
+// The function reports whether the sort is one of `SortEnum`, optionally prefixed with "-".
+func ValidSort(sort string) bool {
+	return contains(SortEnum, strings.TrimPrefix(sort, "-"))
+}
+
+// The QueryParams type describes a question listing: which published questions to list, in which
+// order and which page of them. Every filter left empty matches every question.
+// @property {string} Search - Only list questions whose name contains it, ignoring case.
+// @property {string} Category - Only list questions of this category.
+// @property {string} Level - Only list questions of this level.
+// @property {string} Language - Only list questions supporting this language.
+// @property Tags - Only list questions with these tags.
+// @property {[]string} Companies - Only list questions asked by at least one of these companies,
+// ignoring case.
+// @property {string} Sort - One of `SortEnum`, optionally prefixed with "-" for descending order. It
+// defaults to `SortNumber`. Ties are broken by the question number.
+// @property {int} Limit - The page size. 0 lists every matching question.
+// @property {int} Offset - How many matching questions to skip. It is ignored without a limit.
+type QueryParams struct {
+	Search    string
+	Category  string
+	Level     string
+	Language  string
+	Tags      TagFilter
+	Companies []string
+	Sort      string
+	Limit     int
+	Offset    int
+}
+
+// The QueryResult type is a page of a question listing.
+// @property Questions - The questions on the page.
+// @property {int64} Total - How many questions match the filters, on every page.
+type QueryResult struct {
+	Questions []AllQuestion
+	Total     int64
+}
+
+// The function builds the MongoDB filter of the listing, which only ever matches published questions.
+func (p QueryParams) filter() bson.M {
+	builder := published().
+		Contains(fieldName, strings.TrimSpace(p.Search)).
+		EqIfSet(fieldCategory, p.Category).
+		EqIfSet(fieldLevel, p.Level).
+		EqIfSet(fieldLanguages, p.Language)
+	p.Tags.apply(builder)
+	companyFilter(builder, p.Companies)
+	return builder.Build()
+}
+
+// The function builds the pipeline stages that sort the listing. Sorting by level sorts on the level's
+// position in `LevelEnum`, added to every question as `levelrank`.
+func (p QueryParams) sortStages() bson.A {
+	direction := 1
+	sort := p.Sort
+	if strings.HasPrefix(sort, "-") {
+		direction = -1
+		sort = strings.TrimPrefix(sort, "-")
+	}
+	switch sort {
+	case SortName:
+		return bson.A{bson.M{"$sort": bson.D{{Key: "name", Value: direction}, {Key: "id", Value: 1}}}}
+	case SortLevel:
+		return bson.A{
+			bson.M{"$addFields": bson.M{"levelrank": bson.M{"$indexOfArray": bson.A{LevelEnum, "$level"}}}},
+			bson.M{"$sort": bson.D{{Key: "levelrank", Value: direction}, {Key: "id", Value: 1}}},
+		}
+	default:
+		return bson.A{bson.M{"$sort": bson.D{{Key: "id", Value: direction}}}}
+	}
+}
+
+// The `Query` function is a method of the `Repo` struct that implements the `Repository` interface. It
+// is the single entry point of question listings: it searches, filters, sorts and pages the published
+// questions as described by `params`, and counts every matching question. A page and its total are
+// read in a single aggregation; without a limit, every matching question is read and counted as it is,
+// since they may not fit in the single document a `$facet` produces.
+func (s *Repo) Query(params QueryParams) (QueryResult, error) {
+	listing := QueryResult{Questions: []AllQuestion{}}
+	page := params.sortStages()
+	match := bson.M{"$match": params.filter()}
+	if params.Limit <= 0 {
+		err := retry.Default.Do(s.context, func() error {
+			cursor, err := s.db.Aggregate(s.context, append(bson.A{match}, page...))
+			if err != nil {
+				return err
+			}
+			return cursor.All(s.context, &listing.Questions)
+		})
+		listing.Total = int64(len(listing.Questions))
+		return listing, err
+	}
+	if params.Offset > 0 {
+		page = append(page, bson.M{"$skip": params.Offset})
+	}
+	page = append(page, bson.M{"$limit": params.Limit})
+	pipeline := bson.A{
+		match,
+		bson.M{"$facet": bson.M{
+			"questions": page,
+			"total":     bson.A{bson.M{"$count": "count"}},
+		}},
+	}
+	var result []struct {
+		Questions []AllQuestion `bson:"questions"`
+		Total     []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &result)
+	})
+	if err != nil || len(result) == 0 {
+		return listing, err
+	}
+	if result[0].Questions != nil {
+		listing.Questions = result[0].Questions
+	}
+	if len(result[0].Total) > 0 {
+		listing.Total = result[0].Total[0].Count
+	}
+	return listing, nil
+}
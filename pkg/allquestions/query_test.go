@@ -0,0 +1,176 @@
+package allquestions
+
+import (
+	"reflect"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestQueryParamsFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		params QueryParams
+		want   bson.M
+	}{
+		{
+			name:   "no filters",
+			params: QueryParams{},
+			want:   bson.M{"status": publishedStatus},
+		},
+		{
+			name:   "search is matched literally",
+			params: QueryParams{Search: "  a+b (easy) "},
+			want:   bson.M{"status": publishedStatus, "name": bson.M{"$regex": `a\+b \(easy\)`, "$options": "i"}},
+		},
+		{
+			name:   "category and level",
+			params: QueryParams{Category: "Arrays", Level: LevelEasy},
+			want:   bson.M{"status": publishedStatus, "category": "Arrays", "level": LevelEasy},
+		},
+		{
+			name:   "any tag",
+			params: QueryParams{Tags: TagFilter{Tags: []string{"dp", "graph"}}},
+			want:   bson.M{"status": publishedStatus, "tags": bson.M{"$in": []string{"dp", "graph"}}},
+		},
+		{
+			name: "every filter combined",
+			params: QueryParams{
+				Search:    "sum",
+				Category:  "Arrays",
+				Level:     LevelMedium,
+				Language:  "go",
+				Tags:      TagFilter{Tags: []string{"dp", "hash-table"}, MatchAll: true},
+				Companies: []string{"Acme"},
+				Sort:      "-" + SortName,
+				Limit:     10,
+				Offset:    20,
+			},
+			want: bson.M{
+				"status":    publishedStatus,
+				"name":      bson.M{"$regex": "sum", "$options": "i"},
+				"category":  "Arrays",
+				"level":     LevelMedium,
+				"languages": "go",
+				"tags":      bson.M{"$all": []string{"dp", "hash-table"}},
+				"companies": bson.M{"$in": []primitive.Regex{{Pattern: "^Acme$", Options: "i"}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.params.filter(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryParamsSortStages(t *testing.T) {
+	tests := []struct {
+		sort string
+		want bson.A
+	}{
+		{sort: "", want: bson.A{bson.M{"$sort": bson.D{{Key: "id", Value: 1}}}}},
+		{sort: "-" + SortNumber, want: bson.A{bson.M{"$sort": bson.D{{Key: "id", Value: -1}}}}},
+		{sort: SortName, want: bson.A{bson.M{"$sort": bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}}}}},
+		{sort: "-" + SortLevel, want: bson.A{
+			bson.M{"$addFields": bson.M{"levelrank": bson.M{"$indexOfArray": bson.A{LevelEnum, "$level"}}}},
+			bson.M{"$sort": bson.D{{Key: "levelrank", Value: -1}, {Key: "id", Value: 1}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run("sort "+tt.sort, func(t *testing.T) {
+			if got := (QueryParams{Sort: tt.sort}).sortStages(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortStages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidSort(t *testing.T) {
+	for sort, want := range map[string]bool{SortNumber: true, "-" + SortLevel: true, SortName: true, "--name": false, "difficulty": false, "": false} {
+		if got := ValidSort(sort); got != want {
+			t.Errorf("ValidSort(%q) = %v, want %v", sort, got, want)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	twoSum := AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Category: "Arrays", Level: LevelEasy, Id: 1}
+	threeSum := AllQuestion{ID: primitive.NewObjectID(), Name: "3Sum", Category: "Arrays", Level: LevelMedium, Id: 15}
+
+	dbtest.Run(t, "combined filters on a page", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"questions": bson.A{threeSum, twoSum}, "total": bson.A{bson.M{"count": 7}}}))
+		params := QueryParams{Search: "sum", Category: "Arrays", Tags: TagFilter{Tags: []string{"dp"}, MatchAll: true}, Sort: "-" + SortLevel, Limit: 2, Offset: 4}
+		listing, err := repo.Query(params)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if listing.Total != 7 || len(listing.Questions) != 2 || listing.Questions[0].ID != threeSum.ID || listing.Questions[1].ID != twoSum.ID {
+			mt.Errorf("Query() = %+v, want both questions in order out of 7", listing)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$match": bson.M{
+				"status":   publishedStatus,
+				"name":     bson.M{"$regex": "sum", "$options": "i"},
+				"category": "Arrays",
+				"tags":     bson.M{"$all": bson.A{"dp"}},
+			}},
+			bson.M{"$facet": bson.M{
+				"questions": bson.A{
+					bson.M{"$addFields": bson.M{"levelrank": bson.M{"$indexOfArray": bson.A{bson.A{LevelEasy, LevelMedium, LevelHard}, "$level"}}}},
+					bson.M{"$sort": bson.D{{Key: "levelrank", Value: int32(-1)}, {Key: "id", Value: int32(1)}}},
+					bson.M{"$skip": int32(4)},
+					bson.M{"$limit": int32(2)},
+				},
+				"total": bson.A{bson.M{"$count": "count"}},
+			}},
+		})
+	})
+
+	dbtest.Run(t, "first page", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"questions": bson.A{twoSum}, "total": bson.A{bson.M{"count": 1}}}))
+		if _, err := repo.Query(QueryParams{Level: LevelEasy, Limit: 5}); err != nil {
+			mt.Fatal(err)
+		}
+		pipeline := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)
+		dbtest.ExpectField(mt, pipeline[1].(bson.M)["$facet"].(bson.M), "questions", bson.A{
+			bson.M{"$sort": bson.D{{Key: "id", Value: int32(1)}}},
+			bson.M{"$limit": int32(5)},
+		})
+	})
+
+	dbtest.Run(t, "no match on a page", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"questions": bson.A{}, "total": bson.A{}}))
+		listing, err := repo.Query(QueryParams{Category: "Graphs", Limit: 5})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if listing.Total != 0 || listing.Questions == nil || len(listing.Questions) != 0 {
+			mt.Errorf("Query() = %#v, want an empty listing", listing)
+		}
+	})
+
+	dbtest.Run(t, "without a limit", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, twoSum, threeSum))
+		listing, err := repo.Query(QueryParams{Category: "Arrays", Sort: SortName, Offset: 10})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if listing.Total != 2 || len(listing.Questions) != 2 {
+			mt.Errorf("Query() = %+v, want both questions, counted", listing)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$match": bson.M{"status": publishedStatus, "category": "Arrays"}},
+			bson.M{"$sort": bson.D{{Key: "name", Value: int32(1)}, {Key: "id", Value: int32(1)}}},
+		})
+	})
+}
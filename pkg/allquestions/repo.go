@@ -2,51 +2,471 @@ package allquestions
 
 import (
 	"context"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/filter"
+	"sigmacoder/pkg/retry"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Repository interface {
 	ReadAllQuestion() ([]AllQuestion, error)
+	ReadByLanguage(language string) ([]AllQuestion, error)
 	ReadByID(id string) (AllQuestion, error)
+	Related(id string, limit int) ([]AllQuestion, error)
+	ReadEmbedded(exclude primitive.ObjectID) ([]AllQuestion, error)
+	SetEmbedding(id string, embedding []float32) error
+	SimilarByContent(id string, limit int, finder SimilarFinder) ([]AllQuestion, error)
+	Neighbors(id string, sameCategory bool, sameLevel bool) (Neighbors, error)
+	ReadPopular() ([]PopularQuestion, error)
+	ReadByNumber(number int) (AllQuestion, error)
+	Create(question AllQuestion) (AllQuestion, error)
+	CreateMany(questions []AllQuestion) ([]AllQuestion, error)
+	Update(id string, question AllQuestion) (AllQuestion, error)
+	Patch(id string, update bson.M) (AllQuestion, error)
+	BackfillResources() (int64, error)
+	ExistingIDs(ids []primitive.ObjectID) ([]primitive.ObjectID, error)
+	ReadAfter(after int, limit int, language string, tags TagFilter) ([]AllQuestion, error)
+	ReadByTags(tags TagFilter, language string) ([]AllQuestion, error)
+	Query(params QueryParams) (QueryResult, error)
+	ListTags() ([]TagCount, error)
+	ListCompanies() ([]CompanyCount, error)
+	AddViews(counts map[primitive.ObjectID]int64) error
+	Views(id primitive.ObjectID) (int64, error)
+	Counts() (QuestionCounts, error)
+	ReadByStatus(status string) ([]AllQuestion, error)
+	SetStatus(id string, status string) (AllQuestion, error)
+	RandomPublished() (AllQuestion, error)
+	NextPublished(after int) (AllQuestion, error)
+	EnsureIndexes() error
+	WithContext(ctx context.Context) Repository
 }
 
+// `progressCollection` is the collection holding per-user solve entries, each referencing the solved
+// question's `_id` in its `questionid` field.
+const progressCollection = "progress"
+
 type Repo struct {
 	db      *mongo.Collection
+	views   *mongo.Collection
 	context context.Context
 }
 
 // The `ReadByID` function is a method of the `Repo` struct that implements the `Repository` interface.
-// It is used to retrieve a single question from the MongoDB collection based on its ID.
+// It is used to retrieve a single question from the MongoDB collection based on its ID. An ID that
+// isn't a valid ObjectID is answered with `pkg.ErrInvalidQuestionID` without querying the database.
 func (s *Repo) ReadByID(id string) (AllQuestion, error) {
-	oid, _ := primitive.ObjectIDFromHex(id)
 	var user AllQuestion
-	err := s.db.FindOne(s.context, bson.M{"_id": oid}).Decode(&user)
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return user, pkg.ErrInvalidQuestionID
+	}
+	err = retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, filter.New().Eq(fieldID, oid).Build()).Decode(&user)
+	})
 	if err != nil {
 		return user, err
 	}
 	return user, nil
 }
 
+// The `ReadByNumber` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It retrieves a single question by its numeric `Id` rather than its ObjectID.
+func (s *Repo) ReadByNumber(number int) (AllQuestion, error) {
+	var question AllQuestion
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, filter.New().Eq(fieldNumber, number).Build()).Decode(&question)
+	})
+	if err != nil {
+		return question, err
+	}
+	return question, nil
+}
+
+// The `Create` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It inserts a new question, assigning it a fresh ObjectID when it doesn't have one yet, and returns
+// the stored question.
+func (s *Repo) Create(question AllQuestion) (AllQuestion, error) {
+	if question.ID.IsZero() {
+		question.ID = primitive.NewObjectID()
+	}
+	err := retry.Default.Do(s.context, func() error {
+		_, err := s.db.InsertOne(s.context, question)
+		return err
+	})
+	if err != nil {
+		return question, err
+	}
+	return question, nil
+}
+
+// The `CreateMany` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It inserts the questions in a single batch, assigning a fresh ObjectID to those that don't
+// have one yet, and returns the stored questions.
+func (s *Repo) CreateMany(questions []AllQuestion) ([]AllQuestion, error) {
+	if len(questions) == 0 {
+		return questions, nil
+	}
+	documents := make([]interface{}, len(questions))
+	for i := range questions {
+		if questions[i].ID.IsZero() {
+			questions[i].ID = primitive.NewObjectID()
+		}
+		documents[i] = questions[i]
+	}
+	err := retry.Default.Do(s.context, func() error {
+		_, err := s.db.InsertMany(s.context, documents)
+		return err
+	})
+	if err != nil {
+		return questions, err
+	}
+	return questions, nil
+}
+
+// The `Update` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It replaces the question with the given ID by the given question, keeping its ID, and returns
+// `mongo.ErrNoDocuments` when there is no such question.
+func (s *Repo) Update(id string, question AllQuestion) (AllQuestion, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return question, mongo.ErrNoDocuments
+	}
+	question.ID = oid
+	var result *mongo.UpdateResult
+	err = retry.Default.Do(s.context, func() error {
+		result, err = s.db.ReplaceOne(s.context, filter.New().Eq(fieldID, oid).Build(), question)
+		return err
+	})
+	if err != nil {
+		return question, err
+	}
+	if result.MatchedCount == 0 {
+		return question, mongo.ErrNoDocuments
+	}
+	return question, nil
+}
+
+// The `Patch` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It applies an update document built by `ApplyPatch` to the question with the given ID, so only the
+// fields it names change, and returns the updated question, or `mongo.ErrNoDocuments` when there is no
+// such question.
+func (s *Repo) Patch(id string, update bson.M) (AllQuestion, error) {
+	var question AllQuestion
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return question, mongo.ErrNoDocuments
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = retry.Default.Do(s.context, func() error {
+		return s.db.FindOneAndUpdate(s.context, filter.New().Eq(fieldID, oid).Build(), update, opts).Decode(&question)
+	})
+	return question, err
+}
+
+// The `BackfillResources` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It is the migration helper for `Resources`: every question that doesn't have them yet gets
+// them built from its legacy `Link` and `Videourl` fields. Questions that already have resources are
+// left alone, so it can be run repeatedly. It returns the number of questions updated.
+func (s *Repo) BackfillResources() (int64, error) {
+	var updated int64
+	cursor, err := s.db.Find(s.context, filter.New().Exists(fieldResources, false).Build())
+	if err != nil {
+		return updated, err
+	}
+	defer cursor.Close(s.context)
+	for cursor.Next(s.context) {
+		var question AllQuestion
+		if err := cursor.Decode(&question); err != nil {
+			return updated, err
+		}
+		update := bson.M{"$set": bson.M{"resources": question.LegacyResources()}}
+		if _, err := s.db.UpdateByID(s.context, question.ID, update); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, cursor.Err()
+}
+
+// The `ExistingIDs` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the subset of the given IDs that belong to an existing question.
+func (s *Repo) ExistingIDs(ids []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	existing := []primitive.ObjectID{}
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	var found []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, filter.New().In(fieldID, ids).Build(), opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &found)
+	})
+	if err != nil {
+		return existing, err
+	}
+	for _, question := range found {
+		existing = append(existing, question.ID)
+	}
+	return existing, nil
+}
+
 // The `ReadAllQuestion` function is a method of the `Repo` struct that implements the `Repository`
-// interface. It is used to retrieve all the questions from the MongoDB collection.
+// interface. It is used to retrieve all the published questions from the MongoDB collection.
 func (s *Repo) ReadAllQuestion() ([]AllQuestion, error) {
 	var allquestions []AllQuestion
-	cursor, err := s.db.Find(s.context, bson.M{})
+	err := retry.Default.Do(s.context, func() error {
+		allquestions = nil
+		cursor, err := s.db.Find(s.context, published().Build())
+		if err != nil {
+			return err
+		}
+		for cursor.Next(s.context) {
+			var allquestion AllQuestion
+			cursor.Decode(&allquestion)
+			allquestions = append(allquestions, allquestion)
+		}
+		return cursor.Err()
+	})
+	return allquestions, err
+}
+
+// The `ReadByLanguage` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the published questions whose `languages` array contains the given language.
+func (s *Repo) ReadByLanguage(language string) ([]AllQuestion, error) {
+	questions := []AllQuestion{}
+	query := published().Eq(fieldLanguages, language).Build()
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, query)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &questions)
+	})
+	return questions, err
+}
+
+// The `ReadAfter` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It is the keyset-paginated variant of `ReadAllQuestion`: it returns up to `limit` questions whose
+// numeric `Id` is greater than `after`, in ascending `Id` order. Unlike skipping an offset, this stays
+// cheap however deep the client pages, since the index on `id` takes it straight to the first match.
+// Question numbers start at 1, so an `after` of 0 returns the first page. A non-empty `language`
+// restricts the page to questions that support it, and `tags` to the questions with the given tags.
+// Only published questions are returned.
+func (s *Repo) ReadAfter(after int, limit int, language string, tags TagFilter) ([]AllQuestion, error) {
+	page := []AllQuestion{}
+	builder := published().Gt(fieldNumber, after).EqIfSet(fieldLanguages, language)
+	tags.apply(builder)
+	opts := options.Find().SetSort(bson.M{"id": 1}).SetLimit(int64(limit))
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, builder.Build(), opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &page)
+	})
+	return page, err
+}
+
+// The `ReadByStatus` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It is the admin listing: it returns the questions with the given status, or every question
+// when `status` is empty, in ascending `Id` order. Questions stored before questions had a status are
+// returned as published.
+func (s *Repo) ReadByStatus(status string) ([]AllQuestion, error) {
+	questions := []AllQuestion{}
+	builder := filter.New()
+	switch status {
+	case "":
+	case StatusPublished:
+		builder = published()
+	default:
+		builder.Eq(fieldStatus, status)
+	}
+	opts := options.Find().SetSort(bson.M{"id": 1})
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, builder.Build(), opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &questions)
+	})
+	for i := range questions {
+		questions[i].Status = questions[i].EffectiveStatus()
+	}
+	return questions, err
+}
+
+// The `SetStatus` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It changes the status of the question with the given ID and returns the updated question, or
+// `mongo.ErrNoDocuments` when there is no such question.
+func (s *Repo) SetStatus(id string, status string) (AllQuestion, error) {
+	var question AllQuestion
+	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return allquestions, err
+		return question, mongo.ErrNoDocuments
 	}
-	for cursor.Next(s.context) {
-		var allquestion AllQuestion
-		cursor.Decode(&allquestion)
-		allquestions = append(allquestions, allquestion)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = retry.Default.Do(s.context, func() error {
+		return s.db.FindOneAndUpdate(s.context, filter.New().Eq(fieldID, oid).Build(), bson.M{"$set": bson.M{"status": status}}, opts).Decode(&question)
+	})
+	return question, err
+}
+
+// The `RandomPublished` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns a random published question, or `mongo.ErrNoDocuments` when there is none.
+func (s *Repo) RandomPublished() (AllQuestion, error) {
+	var sample []AllQuestion
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: published().Build()}},
+		{{Key: "$sample", Value: bson.M{"size": 1}}},
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &sample)
+	})
+	if err != nil {
+		return AllQuestion{}, err
+	}
+	if len(sample) == 0 {
+		return AllQuestion{}, mongo.ErrNoDocuments
+	}
+	return sample[0], nil
+}
+
+// The `NextPublished` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the published question with the lowest numeric `Id` greater than `after`,
+// starting over from the first one when there is none. It returns `mongo.ErrNoDocuments` only when
+// there are no published questions at all.
+func (s *Repo) NextPublished(after int) (AllQuestion, error) {
+	page, err := s.ReadAfter(after, 1, "", TagFilter{})
+	if err == nil && len(page) == 0 && after > 0 {
+		page, err = s.ReadAfter(0, 1, "", TagFilter{})
+	}
+	if err != nil {
+		return AllQuestion{}, err
+	}
+	if len(page) == 0 {
+		return AllQuestion{}, mongo.ErrNoDocuments
+	}
+	return page[0], nil
+}
+
+// The `EnsureIndexes` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It creates the index on the numeric `id` that keyset pagination relies on, and the
+// multikey indexes on `tags` and `companies` used by the tag and company filters.
+func (s *Repo) EnsureIndexes() error {
+	_, err := s.db.Indexes().CreateMany(s.context, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}}},
+		{Keys: bson.D{{Key: "companies", Value: 1}}},
+	})
+	return err
+}
+
+// The `Related` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It returns up to `limit` other questions from the same category as the given question, preferring
+// the ones with the same level. Only published questions are returned, and an unpublished question is
+// answered with `mongo.ErrNoDocuments` like a missing one. A question with no siblings yields an empty
+// slice, not an error.
+func (s *Repo) Related(id string, limit int) ([]AllQuestion, error) {
+	related := []AllQuestion{}
+	question, err := s.ReadByID(id)
+	if err != nil {
+		return related, err
+	}
+	if !question.Published() {
+		return related, mongo.ErrNoDocuments
+	}
+	queries := []bson.M{
+		published().Eq(fieldCategory, question.Category).Eq(fieldLevel, question.Level).Ne(fieldID, question.ID).Build(),
+		published().Eq(fieldCategory, question.Category).Ne(fieldLevel, question.Level).Build(),
 	}
-	return allquestions, nil
+	for _, query := range queries {
+		remaining := limit - len(related)
+		if remaining <= 0 {
+			break
+		}
+		var batch []AllQuestion
+		err := retry.Default.Do(s.context, func() error {
+			cursor, err := s.db.Find(s.context, query, options.Find().SetLimit(int64(remaining)))
+			if err != nil {
+				return err
+			}
+			return cursor.All(s.context, &batch)
+		})
+		if err != nil {
+			return related, err
+		}
+		related = append(related, batch...)
+	}
+	return related, nil
+}
+
+// The `ReadPopular` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It counts the solve entries of every published question in the progress collection and
+// returns the questions ordered by solve count, most solved first. Questions without solves sort last with a
+// count of 0, so when no progress has been recorded the questions come back in their default order.
+// The entries are counted inside the `$lookup`, so only their number is joined however often a
+// question was solved, keeping the documents far below the 16MB limit.
+func (s *Repo) ReadPopular() ([]PopularQuestion, error) {
+	popular := []PopularQuestion{}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: published().Build()}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": progressCollection,
+			"let":  bson.M{"question": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$questionid", "$$question"}}}},
+				bson.M{"$count": "n"},
+			},
+			"as": "solves",
+		}}},
+		{{Key: "$addFields", Value: bson.M{"solveCount": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$solves.n", 0}}, 0}}}}},
+		{{Key: "$project", Value: bson.M{"solves": 0}}},
+		{{Key: "$sort", Value: bson.D{{Key: "solveCount", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &popular)
+	})
+	return popular, err
+}
+
+// The function returns a copy of the repo whose operations run with the given context.
+func (s *Repo) withContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The `WithContext` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns a copy of the repo whose operations run with the given context, so a request's
+// deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) Repository {
+	return s.withContext(ctx)
 }
 
-func NewRepo(db *mongo.Database) Repository {
+// The function opens the `AllQuestion` collection, with the view counts in `question_views`, with the
+// given options.
+func newRepo(db *mongo.Database, opts ...*options.CollectionOptions) *Repo {
 	ctx := context.TODO()
-	return &Repo{db: db.Collection("AllQuestion"), context: ctx}
+	return &Repo{db: db.Collection("AllQuestion", opts...), views: db.Collection(viewsCollection, opts...), context: ctx}
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `AllQuestion` collection, with the view counts in `question_views`. The collections are opened with
+// the given options, which set their read preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	return newRepo(db, opts...)
 }
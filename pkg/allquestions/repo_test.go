@@ -0,0 +1,204 @@
+package allquestions
+
+import (
+	"errors"
+	"reflect"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/retry"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// `publishedStatus` is the status condition every query for published questions has.
+var publishedStatus = bson.M{"$in": bson.A{StatusPublished, nil}}
+
+func TestRelated(t *testing.T) {
+	question := AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Category: "Arrays", Level: "Easy", Id: 1}
+	sameLevel := AllQuestion{ID: primitive.NewObjectID(), Name: "Contains Duplicate", Category: "Arrays", Level: "Easy", Id: 2}
+	otherLevel := AllQuestion{ID: primitive.NewObjectID(), Name: "3Sum", Category: "Arrays", Level: "Medium", Id: 3}
+
+	dbtest.Run(t, "same level first", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, question), dbtest.Cursor(mt, sameLevel), dbtest.Cursor(mt, otherLevel))
+		related, err := repo.Related(question.ID.Hex(), 5)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(related) != 2 || related[0].ID != sameLevel.ID || related[1].ID != otherLevel.ID {
+			mt.Errorf("Related() = %v, want the same level question, then the other level one", related)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": question.ID})
+		find := dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, find, "filter", bson.M{"status": publishedStatus, "category": "Arrays", "level": "Easy", "_id": bson.M{"$ne": question.ID}})
+		dbtest.ExpectField(mt, find, "limit", int64(5))
+		find = dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, find, "filter", bson.M{"status": publishedStatus, "category": "Arrays", "level": bson.M{"$ne": "Easy"}})
+		dbtest.ExpectField(mt, find, "limit", int64(4))
+	})
+
+	dbtest.Run(t, "limit reached by the same level", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, question), dbtest.Cursor(mt, sameLevel))
+		related, err := repo.Related(question.ID.Hex(), 1)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(related) != 1 {
+			mt.Errorf("Related() returned %d questions, want 1", len(related))
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "limit", int64(1))
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "no siblings", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, question), dbtest.Cursor(mt), dbtest.Cursor(mt))
+		related, err := repo.Related(question.ID.Hex(), 5)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if related == nil || len(related) != 0 {
+			mt.Errorf("Related() = %#v, want an empty slice", related)
+		}
+	})
+
+	dbtest.Run(t, "unpublished question", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		draft := question
+		draft.Status = StatusDraft
+		mt.AddMockResponses(dbtest.Cursor(mt, draft))
+		if _, err := repo.Related(question.ID.Hex(), 5); !errors.Is(err, mongo.ErrNoDocuments) {
+			mt.Fatalf("Related() = %v, want %v", err, mongo.ErrNoDocuments)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestReadByIDInvalid(t *testing.T) {
+	dbtest.Run(t, "not an object id", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		if _, err := repo.ReadByID("not-an-id"); !errors.Is(err, pkg.ErrInvalidQuestionID) {
+			mt.Fatalf("ReadByID() = %v, want %v", err, pkg.ErrInvalidQuestionID)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestReadPopular(t *testing.T) {
+	dbtest.Run(t, "ordered by solve count", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mostSolved := PopularQuestion{AllQuestion: AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Id: 1}, SolveCount: 7}
+		lessSolved := PopularQuestion{AllQuestion: AllQuestion{ID: primitive.NewObjectID(), Name: "3Sum", Id: 2}, SolveCount: 2}
+		unsolved := AllQuestion{ID: primitive.NewObjectID(), Name: "4Sum", Id: 3}
+		mt.AddMockResponses(dbtest.Cursor(mt, mostSolved, lessSolved, bson.M{"_id": unsolved.ID, "name": unsolved.Name, "id": 3, "solveCount": 0}))
+		popular, err := repo.ReadPopular()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		var counts []int64
+		for _, question := range popular {
+			counts = append(counts, question.SolveCount)
+		}
+		if !reflect.DeepEqual(counts, []int64{7, 2, 0}) || popular[2].ID != unsolved.ID {
+			mt.Errorf("ReadPopular() solve counts = %v, want [7 2 0] with the unsolved question last", counts)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$match": bson.M{"status": publishedStatus}},
+			bson.M{"$lookup": bson.M{
+				"from": "progress",
+				"let":  bson.M{"question": "$_id"},
+				"pipeline": bson.A{
+					bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$questionid", "$$question"}}}},
+					bson.M{"$count": "n"},
+				},
+				"as": "solves",
+			}},
+			bson.M{"$addFields": bson.M{"solveCount": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$solves.n", int32(0)}}, int32(0)}}}},
+			bson.M{"$project": bson.M{"solves": 0}},
+			bson.M{"$sort": bson.D{{Key: "solveCount", Value: -1}, {Key: "_id", Value: 1}}},
+		})
+	})
+
+	dbtest.Run(t, "no questions", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		popular, err := repo.ReadPopular()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if popular == nil || len(popular) != 0 {
+			mt.Errorf("ReadPopular() = %#v, want an empty slice", popular)
+		}
+	})
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	question := AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Id: 1}
+	defer func(policy retry.Policy) { retry.Default = policy }(retry.Default)
+	retry.Default = retry.Policy{Attempts: 3, Backoff: time.Millisecond}
+
+	dbtest.Run(t, "fails twice then succeeds", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Failed(11600, "interrupted at shutdown"), dbtest.Failed(189, "primary stepped down"), dbtest.Cursor(mt, question))
+		found, err := repo.ReadByNumber(1)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if found.ID != question.ID {
+			mt.Errorf("ReadByNumber() = %v, want %v", found, question)
+		}
+		for i := 0; i < 3; i++ {
+			dbtest.NextCommand(mt, "find")
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "duplicate key is not retried", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}))
+		if _, err := repo.Create(question); !mongo.IsDuplicateKeyError(err) {
+			mt.Fatalf("Create() = %v, want a duplicate key error", err)
+		}
+		dbtest.NextCommand(mt, "insert")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestNewRepoOptions(t *testing.T) {
+	dbtest.Run(t, "configured read preference and write concern", func(mt *mtest.T) {
+		opts, err := database.CollectionOptions("secondaryPreferred", "1")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		repo := NewRepo(mt.DB, opts).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Written(1))
+		repo.ReadByNumber(1)
+		if _, err := repo.Create(AllQuestion{Name: "Two Sum", Id: 1}); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "$readPreference", bson.M{"mode": "secondaryPreferred"})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "insert"), "writeConcern", bson.M{"w": 1})
+	})
+
+	dbtest.Run(t, "defaults", func(mt *mtest.T) {
+		opts, err := database.CollectionOptions("", "")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		repo := NewRepo(mt.DB, opts).(*Repo)
+		mt.AddMockResponses(dbtest.Written(1))
+		if _, err := repo.Create(AllQuestion{Name: "Two Sum", Id: 1}); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "insert"), "writeConcern", bson.M{"w": "majority"})
+	})
+}
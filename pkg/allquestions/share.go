@@ -0,0 +1,63 @@
+package allquestions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"sigmacoder/pkg"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The ShareSigner type signs and checks the tokens of question share links. A token is
+// `<question id>.<expiry as unix seconds>.<signature>`, where the signature is the base64url encoded
+// HMAC-SHA256 of the first two parts, so the link carries everything needed to check it and nothing
+// has to be stored.
+// @property secret - The key the tokens are signed with.
+// @property ttl - How long a share link stays valid.
+type ShareSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// The function signs the payload of a token.
+func (s *ShareSigner) signature(payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// The function returns a token sharing the question with the given ID, and when it expires.
+func (s *ShareSigner) Sign(questionID string, now time.Time) (string, time.Time) {
+	expiresAt := now.Add(s.ttl).Truncate(time.Second)
+	payload := questionID + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + s.signature(payload), expiresAt
+}
+
+// The function checks a token and returns the ID of the question it shares. It returns
+// `pkg.ErrInvalidShareToken` when the token is malformed or its signature doesn't match, and
+// `pkg.ErrShareLinkExpired` when it is genuine but has expired.
+func (s *ShareSigner) Verify(token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", pkg.ErrInvalidShareToken
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.signature(payload))) {
+		return "", pkg.ErrInvalidShareToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", pkg.ErrInvalidShareToken
+	}
+	if !now.Before(time.Unix(expiresAt, 0)) {
+		return "", pkg.ErrShareLinkExpired
+	}
+	return parts[0], nil
+}
+
+// The function creates a signer for share links that are valid for `ttl`.
+func NewShareSigner(secret string, ttl time.Duration) *ShareSigner {
+	return &ShareSigner{secret: []byte(secret), ttl: ttl}
+}
@@ -0,0 +1,45 @@
+package allquestions
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareSigner(t *testing.T) {
+	signer := NewShareSigner("share-secret", time.Hour)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	token, expiresAt := signer.Sign("65e1c0ffee0000000000abcd", now)
+	if !expiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("expiresAt = %s, want an hour after signing", expiresAt)
+	}
+	parts := strings.Split(token, ".")
+
+	tests := []struct {
+		name  string
+		token string
+		now   time.Time
+		want  error
+	}{
+		{name: "valid", token: token, now: now.Add(59 * time.Minute)},
+		{name: "expired", token: token, now: expiresAt, want: pkg.ErrShareLinkExpired},
+		{name: "other question", token: "65e1c0ffee0000000000dcba." + parts[1] + "." + parts[2], now: now, want: pkg.ErrInvalidShareToken},
+		{name: "extended expiry", token: parts[0] + ".9999999999." + parts[2], now: now, want: pkg.ErrInvalidShareToken},
+		{name: "other secret", token: func() string { token, _ := NewShareSigner("other-secret", time.Hour).Sign(parts[0], now); return token }(), now: now, want: pkg.ErrInvalidShareToken},
+		{name: "malformed", token: "not-a-token", now: now, want: pkg.ErrInvalidShareToken},
+		{name: "empty", token: "", now: now, want: pkg.ErrInvalidShareToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := signer.Verify(tt.token, tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && id != parts[0] {
+				t.Errorf("Verify() = %q, want %q", id, parts[0])
+			}
+		})
+	}
+}
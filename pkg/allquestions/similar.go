@@ -0,0 +1,216 @@
+package allquestions
+
+import (
+	"context"
+	"math"
+	"sigmacoder/pkg/filter"
+	"sigmacoder/pkg/retry"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The SimilarFinder interface finds the questions whose content is most similar to a question's, by
+// their precomputed `Embedding`. Implementations decide where the vectors are compared, so the search
+// can move to a vector database without changing its callers.
+// @property Similar - Similar returns up to `limit` published questions other than `question`, most
+// similar first. `question` must have an embedding.
+// @property WithContext - WithContext returns a copy of the finder whose database calls run with the
+// given context, typically the request's `c.UserContext()`.
+type SimilarFinder interface {
+	Similar(question AllQuestion, limit int) ([]AllQuestion, error)
+	WithContext(ctx context.Context) SimilarFinder
+}
+
+// The function returns the cosine similarity of two vectors, from -1 for opposite directions to 1 for
+// the same direction. Vectors of different lengths or without a direction aren't comparable and have
+// a similarity of 0.
+func CosineSimilarity(a []float32, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// The function orders the candidates by the cosine similarity of their embeddings to `vector`, most
+// similar first, and returns up to `limit` of them. Candidates whose embedding has another length, such
+// as the ones computed by another model, aren't comparable and are left out. Candidates that are
+// equally similar keep their order.
+func RankBySimilarity(vector []float32, candidates []AllQuestion, limit int) []AllQuestion {
+	comparable := []AllQuestion{}
+	scores := []float64{}
+	for _, candidate := range candidates {
+		if len(candidate.Embedding) != len(vector) {
+			continue
+		}
+		comparable = append(comparable, candidate)
+		scores = append(scores, CosineSimilarity(vector, candidate.Embedding))
+	}
+	order := make([]int, len(comparable))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	ranked := []AllQuestion{}
+	for _, i := range order {
+		if len(ranked) == limit {
+			break
+		}
+		ranked = append(ranked, comparable[i])
+	}
+	return ranked
+}
+
+// The MemoryFinder type is a SimilarFinder that reads every published question with an embedding and
+// compares the vectors in memory. It needs nothing beyond MongoDB and suits catalogues of a few
+// thousand questions.
+// @property repo - The question repository the candidates are read from.
+type MemoryFinder struct {
+	repo Repository
+}
+
+// The `Similar` function is a method of the `MemoryFinder` struct that implements the `SimilarFinder`
+// interface.
+func (f *MemoryFinder) Similar(question AllQuestion, limit int) ([]AllQuestion, error) {
+	candidates, err := f.repo.ReadEmbedded(question.ID)
+	if err != nil {
+		return nil, err
+	}
+	return RankBySimilarity(question.Embedding, candidates, limit), nil
+}
+
+// The `WithContext` function is a method of the `MemoryFinder` struct that implements the
+// `SimilarFinder` interface.
+func (f *MemoryFinder) WithContext(ctx context.Context) SimilarFinder {
+	return &MemoryFinder{repo: f.repo.WithContext(ctx)}
+}
+
+// The function creates a finder that compares the embeddings of the questions in `repo` in memory.
+func NewMemoryFinder(repo Repository) *MemoryFinder {
+	return &MemoryFinder{repo: repo}
+}
+
+// The AtlasFinder type is a SimilarFinder that searches the embeddings with MongoDB Atlas Vector
+// Search, so only the closest questions are read.
+// @property repo - The question collection that is searched.
+// @property {string} index - The name of the Atlas Vector Search index on `embedding`, with cosine
+// similarity.
+type AtlasFinder struct {
+	repo  *Repo
+	index string
+}
+
+// `atlasCandidatesPerResult` is how many nearest neighbours Atlas considers for every question
+// returned, trading latency for accuracy.
+const atlasCandidatesPerResult = 10
+
+// The `Similar` function is a method of the `AtlasFinder` struct that implements the `SimilarFinder`
+// interface. Unpublished questions and the question itself are dropped after the search, so it asks
+// for one more question than `limit` and tolerates a shorter result.
+func (f *AtlasFinder) Similar(question AllQuestion, limit int) ([]AllQuestion, error) {
+	similar := []AllQuestion{}
+	pipeline := bson.A{
+		bson.M{"$vectorSearch": bson.M{
+			"index":         f.index,
+			"path":          string(fieldEmbedding),
+			"queryVector":   question.Embedding,
+			"numCandidates": (limit + 1) * atlasCandidatesPerResult,
+			"limit":         limit + 1,
+		}},
+		bson.M{"$match": published().Ne(fieldID, question.ID).Build()},
+		bson.M{"$limit": limit},
+	}
+	err := retry.Default.Do(f.repo.context, func() error {
+		cursor, err := f.repo.db.Aggregate(f.repo.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(f.repo.context, &similar)
+	})
+	return similar, err
+}
+
+// The `WithContext` function is a method of the `AtlasFinder` struct that implements the
+// `SimilarFinder` interface.
+func (f *AtlasFinder) WithContext(ctx context.Context) SimilarFinder {
+	return &AtlasFinder{repo: f.repo.withContext(ctx), index: f.index}
+}
+
+// The function creates a finder that searches the embeddings of the questions in `db` with the Atlas
+// Vector Search index named `index`. The collection is opened with the given options, like the one of
+// `NewRepo`.
+func NewAtlasFinder(db *mongo.Database, index string, opts ...*options.CollectionOptions) *AtlasFinder {
+	return &AtlasFinder{repo: newRepo(db, opts...), index: index}
+}
+
+// The `ReadEmbedded` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns every published question that has an embedding, except the one with the ID
+// `exclude`.
+func (s *Repo) ReadEmbedded(exclude primitive.ObjectID) ([]AllQuestion, error) {
+	questions := []AllQuestion{}
+	query := published().Exists(fieldEmbedding, true).Ne(fieldID, exclude).Build()
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, query)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &questions)
+	})
+	return questions, err
+}
+
+// The `SetEmbedding` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It stores the precomputed embedding of the question with the given ID, and returns
+// `mongo.ErrNoDocuments` when there is no such question.
+func (s *Repo) SetEmbedding(id string, embedding []float32) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return mongo.ErrNoDocuments
+	}
+	var result *mongo.UpdateResult
+	err = retry.Default.Do(s.context, func() error {
+		result, err = s.db.UpdateOne(s.context, filter.New().Eq(fieldID, oid).Build(), bson.M{"$set": bson.M{string(fieldEmbedding): embedding}})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// The `SimilarByContent` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns up to `limit` questions similar in content to the question with the given ID,
+// found by `finder`. When the question has no embedding, or no other question is comparable, it falls
+// back to the questions of the same category returned by `Related`. It returns `mongo.ErrNoDocuments`
+// when the question doesn't exist or isn't published.
+func (s *Repo) SimilarByContent(id string, limit int, finder SimilarFinder) ([]AllQuestion, error) {
+	question, err := s.ReadByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !question.Published() {
+		return nil, mongo.ErrNoDocuments
+	}
+	if len(question.Embedding) > 0 {
+		similar, err := finder.WithContext(s.context).Similar(question, limit)
+		if err != nil || len(similar) > 0 {
+			return similar, err
+		}
+	}
+	return s.Related(id, limit)
+}
@@ -0,0 +1,162 @@
+package allquestions
+
+import (
+	"context"
+	"math"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The type fixedFinder is a SimilarFinder that answers with `similar` and remembers the questions it
+// was asked about.
+type fixedFinder struct {
+	similar []AllQuestion
+	asked   []primitive.ObjectID
+}
+
+func (f *fixedFinder) Similar(question AllQuestion, limit int) ([]AllQuestion, error) {
+	f.asked = append(f.asked, question.ID)
+	return f.similar, nil
+}
+
+func (f *fixedFinder) WithContext(ctx context.Context) SimilarFinder {
+	return f
+}
+
+// The function returns the names of the questions, in order.
+func names(questions []AllQuestion) []string {
+	list := []string{}
+	for _, question := range questions {
+		list = append(list, question.Name)
+	}
+	return list
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{name: "same direction", a: []float32{1, 2, 3}, b: []float32{2, 4, 6}, want: 1},
+		{name: "orthogonal", a: []float32{1, 0}, b: []float32{0, 1}, want: 0},
+		{name: "opposite", a: []float32{1, -1}, b: []float32{-1, 1}, want: -1},
+		{name: "different lengths", a: []float32{1, 0}, b: []float32{1, 0, 0}, want: 0},
+		{name: "no direction", a: []float32{0, 0}, b: []float32{1, 1}, want: 0},
+		{name: "empty", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("CosineSimilarity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankBySimilarity(t *testing.T) {
+	vector := []float32{1, 0, 0}
+	candidates := []AllQuestion{
+		{Name: "orthogonal", Embedding: []float32{0, 1, 0}},
+		{Name: "near", Embedding: []float32{0.9, 0.1, 0}},
+		{Name: "other model", Embedding: []float32{1, 0}},
+		{Name: "identical", Embedding: []float32{2, 0, 0}},
+		{Name: "opposite", Embedding: []float32{-1, 0, 0}},
+		{Name: "also orthogonal", Embedding: []float32{0, 0, 1}},
+	}
+	tests := []struct {
+		name  string
+		limit int
+		want  []string
+	}{
+		{name: "every comparable candidate", limit: 10, want: []string{"identical", "near", "orthogonal", "also orthogonal", "opposite"}},
+		{name: "limited", limit: 2, want: []string{"identical", "near"}},
+		{name: "nothing", limit: 0, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(RankBySimilarity(vector, candidates, tt.limit))
+			if len(got) != len(tt.want) {
+				t.Fatalf("RankBySimilarity() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("RankBySimilarity() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSimilarByContent(t *testing.T) {
+	question := AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Category: "Arrays", Level: LevelEasy, Id: 1, Embedding: []float32{1, 0}}
+	near := AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum II", Category: "Arrays", Id: 2, Embedding: []float32{0.8, 0.2}}
+	far := AllQuestion{ID: primitive.NewObjectID(), Name: "Valid Parentheses", Category: "Stacks", Id: 3, Embedding: []float32{0, 1}}
+
+	dbtest.Run(t, "ranked in memory", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, question), dbtest.Cursor(mt, far, near))
+		similar, err := repo.SimilarByContent(question.ID.Hex(), 5, NewMemoryFinder(repo))
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if got := names(similar); len(got) != 2 || got[0] != near.Name || got[1] != far.Name {
+			mt.Errorf("SimilarByContent() = %v, want the closest question first", got)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{
+			"status":    publishedStatus,
+			"embedding": bson.M{"$exists": true},
+			"_id":       bson.M{"$ne": question.ID},
+		})
+	})
+
+	dbtest.Run(t, "swapped finder", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		finder := &fixedFinder{similar: []AllQuestion{far}}
+		mt.AddMockResponses(dbtest.Cursor(mt, question))
+		similar, err := repo.SimilarByContent(question.ID.Hex(), 5, finder)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(finder.asked) != 1 || finder.asked[0] != question.ID || len(similar) != 1 || similar[0].ID != far.ID {
+			mt.Errorf("SimilarByContent() = %v after asking about %v, want the finder's answer", names(similar), finder.asked)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "no embedding falls back to related", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		finder := &fixedFinder{}
+		plain := question
+		plain.Embedding = nil
+		mt.AddMockResponses(dbtest.Cursor(mt, plain), dbtest.Cursor(mt, plain), dbtest.Cursor(mt, near), dbtest.Cursor(mt))
+		similar, err := repo.SimilarByContent(question.ID.Hex(), 5, finder)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(finder.asked) != 0 {
+			mt.Errorf("asked the finder about %v, want it skipped without an embedding", finder.asked)
+		}
+		if got := names(similar); len(got) != 1 || got[0] != near.Name {
+			mt.Errorf("SimilarByContent() = %v, want the related question of the same category", got)
+		}
+	})
+
+	dbtest.Run(t, "nothing comparable falls back to related", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Cursor(mt, question), dbtest.Cursor(mt), dbtest.Cursor(mt, question), dbtest.Cursor(mt, near), dbtest.Cursor(mt))
+		similar, err := repo.SimilarByContent(question.ID.Hex(), 5, NewMemoryFinder(repo))
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if got := names(similar); len(got) != 1 || got[0] != near.Name {
+			mt.Errorf("SimilarByContent() = %v, want the related question of the same category", got)
+		}
+	})
+}
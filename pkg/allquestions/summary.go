@@ -0,0 +1,60 @@
+package allquestions
+
+import (
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The QuestionCounts type counts the questions, in total and per level and status. Questions stored
+// before questions had a status count as published.
+// @property {int64} Total - The number of questions.
+// @property ByLevel - The number of questions of every level.
+// @property ByStatus - The number of questions with every status.
+type QuestionCounts struct {
+	Total    int64            `json:"total"`
+	ByLevel  map[string]int64 `json:"byLevel"`
+	ByStatus map[string]int64 `json:"byStatus"`
+}
+
+// The `Counts` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It counts the questions, whatever their status, in a single aggregation.
+func (s *Repo) Counts() (QuestionCounts, error) {
+	pipeline := bson.A{
+		bson.M{"$facet": bson.M{
+			"total":    bson.A{bson.M{"$count": "count"}},
+			"byLevel":  bson.A{bson.M{"$group": bson.M{"_id": "$level", "count": bson.M{"$sum": 1}}}},
+			"byStatus": bson.A{bson.M{"$group": bson.M{"_id": bson.M{"$ifNull": bson.A{"$status", StatusPublished}}, "count": bson.M{"$sum": 1}}}},
+		}},
+	}
+	type group struct {
+		ID    string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	var result []struct {
+		Total    []group `bson:"total"`
+		ByLevel  []group `bson:"byLevel"`
+		ByStatus []group `bson:"byStatus"`
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &result)
+	})
+	counts := QuestionCounts{ByLevel: map[string]int64{}, ByStatus: map[string]int64{}}
+	if err != nil || len(result) == 0 {
+		return counts, err
+	}
+	if len(result[0].Total) > 0 {
+		counts.Total = result[0].Total[0].Count
+	}
+	for _, level := range result[0].ByLevel {
+		counts.ByLevel[level.ID] = level.Count
+	}
+	for _, status := range result[0].ByStatus {
+		counts.ByStatus[status.ID] = status.Count
+	}
+	return counts, nil
+}
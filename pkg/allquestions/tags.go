@@ -0,0 +1,96 @@
+package allquestions
+
+import (
+	"sigmacoder/pkg/filter"
+	"sigmacoder/pkg/retry"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The TagFilter type narrows a question listing down to questions with certain tags. A filter without
+// tags matches every question.
+// @property {[]string} Tags - The normalized tags to look for.
+// @property {bool} MatchAll - Whether a question needs every tag, rather than at least one of them.
+type TagFilter struct {
+	Tags     []string
+	MatchAll bool
+}
+
+// The function adds the tag condition to a filter builder. Matching a single tag against the `tags`
+// array finds the questions that contain it, so both forms can use the multikey index on `tags`.
+func (f TagFilter) apply(builder *filter.Builder) {
+	if len(f.Tags) == 0 {
+		return
+	}
+	if f.MatchAll {
+		builder.All(fieldTags, f.Tags)
+	} else {
+		builder.In(fieldTags, f.Tags)
+	}
+}
+
+// The TagCount type is a tag together with the number of published questions that have it.
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int64  `json:"count" bson:"count"`
+}
+
+// The function returns the canonical form of a tag, so "DP" and " dp" are stored and matched as "dp".
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// The function normalizes a list of tags, dropping empty and repeated ones.
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// The `ReadByTags` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the published questions matching the tag filter, restricted to the ones
+// supporting `language` when it isn't empty.
+func (s *Repo) ReadByTags(tags TagFilter, language string) ([]AllQuestion, error) {
+	questions := []AllQuestion{}
+	builder := published().EqIfSet(fieldLanguages, language)
+	tags.apply(builder)
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, builder.Build())
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &questions)
+	})
+	return questions, err
+}
+
+// The `ListTags` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It returns every tag used by a published question with the number of published questions using it,
+// most used first and ties in alphabetical order.
+func (s *Repo) ListTags() ([]TagCount, error) {
+	counts := []TagCount{}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: published().Build()}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &counts)
+	})
+	return counts, err
+}
@@ -0,0 +1,22 @@
+package allquestions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		tags []string
+		want []string
+	}{
+		{tags: nil, want: []string{}},
+		{tags: []string{" DP", "Graph "}, want: []string{"dp", "graph"}},
+		{tags: []string{"dp", "", "  ", "DP", "two-pointers", "dp"}, want: []string{"dp", "two-pointers"}},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTags(tt.tags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NormalizeTags(%q) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
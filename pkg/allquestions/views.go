@@ -0,0 +1,128 @@
+package allquestions
+
+import (
+	"sigmacoder/pkg/logging"
+	"sigmacoder/pkg/retry"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// `viewsCollection` holds the view count of every question that has been viewed, with the question's
+// `_id` as its own. The counts live apart from the questions so replacing a question doesn't reset
+// them, and so counting views doesn't write to the hot question documents.
+const viewsCollection = "question_views"
+
+// The `AddViews` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It adds the given number of views to each question in a single bulk write.
+func (s *Repo) AddViews(counts map[primitive.ObjectID]int64) error {
+	if len(counts) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(counts))
+	for id, count := range counts {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$inc": bson.M{"views": count}}).
+			SetUpsert(true))
+	}
+	return retry.Default.Do(s.context, func() error {
+		_, err := s.views.BulkWrite(s.context, models, options.BulkWrite().SetOrdered(false))
+		return err
+	})
+}
+
+// The `Views` function is a method of the `Repo` struct that implements the `Repository` interface. It
+// returns the stored number of views of the question, 0 when it has never been viewed.
+func (s *Repo) Views(id primitive.ObjectID) (int64, error) {
+	var result struct {
+		Views int64 `bson:"views"`
+	}
+	err := retry.Default.Do(s.context, func() error {
+		return s.views.FindOne(s.context, bson.M{"_id": id}).Decode(&result)
+	})
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return result.Views, err
+}
+
+// The ViewCounter type counts question views. When batching, views are counted in memory and added
+// to the database on every flush, so a popular question costs one write per flush rather than one per
+// view; views that haven't been flushed yet are lost if the process stops. Otherwise every view is
+// written as it happens. Either way the database only ever increments the counts, so several instances
+// can count views of the same question concurrently.
+// @property mu - Guards `pending`.
+// @property pending - The views counted since the last flush, keyed by question.
+// @property batch - Whether views are batched in memory.
+// @property repo - The repository the views are written to.
+type ViewCounter struct {
+	mu      sync.Mutex
+	pending map[primitive.ObjectID]int64
+	batch   bool
+	repo    Repository
+}
+
+// The function counts a view of the question. The error is only ever set when views aren't batched.
+func (v *ViewCounter) Record(id primitive.ObjectID) error {
+	if !v.batch {
+		return v.repo.AddViews(map[primitive.ObjectID]int64{id: 1})
+	}
+	v.mu.Lock()
+	v.pending[id]++
+	v.mu.Unlock()
+	return nil
+}
+
+// The function returns the number of views of the question: the stored ones plus the ones that haven't
+// been flushed yet.
+func (v *ViewCounter) Views(id primitive.ObjectID) (int64, error) {
+	stored, err := v.repo.Views(id)
+	if err != nil {
+		return 0, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return stored + v.pending[id], nil
+}
+
+// The function writes the views counted since the last flush to the database. When the write fails,
+// the views are kept for the next flush.
+func (v *ViewCounter) Flush() error {
+	v.mu.Lock()
+	batch := v.pending
+	v.pending = map[primitive.ObjectID]int64{}
+	v.mu.Unlock()
+	if err := v.repo.AddViews(batch); err != nil {
+		v.mu.Lock()
+		for id, count := range batch {
+			v.pending[id] += count
+		}
+		v.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// The function creates a view counter that writes to the given repository. With a positive
+// `flushInterval`, views are batched and flushed that often in the background; otherwise every view is
+// written as it happens.
+func NewViewCounter(repo Repository, flushInterval time.Duration) *ViewCounter {
+	v := &ViewCounter{pending: map[primitive.ObjectID]int64{}, batch: flushInterval > 0, repo: repo}
+	if v.batch {
+		go func() {
+			ticker := time.NewTicker(flushInterval)
+			for {
+				<-ticker.C
+				if err := v.Flush(); err != nil {
+					logging.Errorf("allquestions: could not flush question views: %v", err)
+				}
+			}
+		}()
+	}
+	return v
+}
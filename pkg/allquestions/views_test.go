@@ -0,0 +1,116 @@
+package allquestions
+
+import (
+	"sigmacoder/pkg/database/dbtest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns the `$inc` of every update in a bulk write to the views collection, keyed by
+// question.
+func viewIncrements(mt *mtest.T, command bson.M) map[primitive.ObjectID]int64 {
+	mt.Helper()
+	increments := map[primitive.ObjectID]int64{}
+	for _, update := range command["updates"].(bson.A) {
+		update := update.(bson.M)
+		if update["upsert"] != true {
+			mt.Errorf("update = %v, want an upsert", update)
+		}
+		increments[update["q"].(bson.M)["_id"].(primitive.ObjectID)] = update["u"].(bson.M)["$inc"].(bson.M)["views"].(int64)
+	}
+	return increments
+}
+
+func TestViewCounter(t *testing.T) {
+	popular, quiet := primitive.NewObjectID(), primitive.NewObjectID()
+
+	dbtest.Run(t, "every view written", func(mt *mtest.T) {
+		views := NewViewCounter(NewRepo(mt.DB).(*Repo), 0)
+		mt.AddMockResponses(dbtest.Written(1))
+		if err := views.Record(popular); err != nil {
+			mt.Fatal(err)
+		}
+		command := dbtest.NextCommand(mt, "update")
+		if collection := command["update"]; collection != viewsCollection {
+			mt.Errorf("wrote to %v, want %s", collection, viewsCollection)
+		}
+		if increments := viewIncrements(mt, command); len(increments) != 1 || increments[popular] != 1 {
+			mt.Errorf("increments = %v, want one view of the question", increments)
+		}
+	})
+
+	dbtest.Run(t, "batched views flushed", func(mt *mtest.T) {
+		views := NewViewCounter(NewRepo(mt.DB).(*Repo), time.Hour)
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				views.Record(popular)
+				if i%10 == 0 {
+					views.Record(quiet)
+				}
+			}(i)
+		}
+		wg.Wait()
+		dbtest.ExpectNoCommand(mt)
+
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": popular, "views": 7}))
+		if count, err := views.Views(popular); err != nil || count != 57 {
+			mt.Errorf("Views() before the flush = %d, %v, want the 7 stored plus the 50 pending", count, err)
+		}
+		dbtest.NextCommand(mt, "find")
+
+		mt.AddMockResponses(dbtest.Written(2))
+		if err := views.Flush(); err != nil {
+			mt.Fatal(err)
+		}
+		if increments := viewIncrements(mt, dbtest.NextCommand(mt, "update")); len(increments) != 2 || increments[popular] != 50 || increments[quiet] != 5 {
+			mt.Errorf("increments = %v, want 50 and 5 views", increments)
+		}
+
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": popular, "views": 57}))
+		if count, err := views.Views(popular); err != nil || count != 57 {
+			mt.Errorf("Views() after the flush = %d, %v, want the 57 stored and nothing pending", count, err)
+		}
+		dbtest.NextCommand(mt, "find")
+
+		if err := views.Flush(); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "failed flush kept", func(mt *mtest.T) {
+		views := NewViewCounter(NewRepo(mt.DB).(*Repo), time.Hour)
+		views.Record(popular)
+		views.Record(popular)
+		mt.AddMockResponses(dbtest.Failed(2, "bad value"))
+		if err := views.Flush(); err == nil {
+			mt.Fatal("Flush() = nil, want the write error")
+		}
+		dbtest.NextCommand(mt, "update")
+		views.Record(popular)
+
+		mt.AddMockResponses(dbtest.Written(1))
+		if err := views.Flush(); err != nil {
+			mt.Fatal(err)
+		}
+		if increments := viewIncrements(mt, dbtest.NextCommand(mt, "update")); increments[popular] != 3 {
+			mt.Errorf("increments = %v, want the 2 views of the failed flush and the new one", increments)
+		}
+	})
+
+	dbtest.Run(t, "never viewed", func(mt *mtest.T) {
+		views := NewViewCounter(NewRepo(mt.DB).(*Repo), 0)
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if count, err := views.Views(quiet); err != nil || count != 0 {
+			mt.Errorf("Views() = %d, %v, want 0", count, err)
+		}
+	})
+}
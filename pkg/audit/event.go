@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The event types recorded in the audit log.
+const (
+	EventSignup             = "auth.signup"
+	EventLogin              = "auth.login"
+	EventOTPLogin           = "auth.otp_login"
+	EventSessionRevoked     = "session.revoked"
+	EventUserPurged         = "user.purged"
+	EventMaintenanceChanged = "maintenance.changed"
+	EventDeletionRequested  = "account.deletion_requested"
+	EventDeletionCancelled  = "account.deletion_cancelled"
+	EventPasswordChanged    = "auth.password_changed"
+	EventRoleChanged        = "user.role_changed"
+	EventFlagChanged        = "flag.changed"
+)
+
+// The Event type is a single entry of the audit log.
+// @property ID - The ObjectID of the entry.
+// @property {string} Type - What happened, one of the `Event*` constants.
+// @property {string} UserID - The user the event is about.
+// @property {string} ActorID - The user that caused the event, when it isn't the user it is about, such
+// as the admin that purged an account.
+// @property {string} IP - The client IP address of the request that caused the event.
+// @property Timestamp - When the event happened.
+// @property Metadata - Additional details that depend on the type of the event.
+type Event struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Type      string             `json:"type" bson:"type"`
+	UserID    string             `json:"userId,omitempty" bson:"userid,omitempty"`
+	ActorID   string             `json:"actorId,omitempty" bson:"actorid,omitempty"`
+	IP        string             `json:"ip,omitempty" bson:"ip,omitempty"`
+	Timestamp time.Time          `json:"timestamp" bson:"timestamp"`
+	Metadata  map[string]string  `json:"metadata,omitempty" bson:"metadata,omitempty"`
+}
+
+// The Filter type narrows down a listing of audit events. Zero values don't filter.
+// @property {string} UserID - Only events about this user.
+// @property {string} Type - Only events of this type.
+// @property From - Only events at or after this time.
+// @property To - Only events before this time.
+type Filter struct {
+	UserID string
+	Type   string
+	From   time.Time
+	To     time.Time
+}
+
+// The Page type is a page of audit events together with the total number of events matching the
+// filter.
+type Page struct {
+	Events []Event `json:"events"`
+	Total  int64   `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
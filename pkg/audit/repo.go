@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/retry"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the audit log.
+type Repository interface {
+	Record(event Event) error
+	List(filter Filter, limit int, offset int) (Page, error)
+	CountRecent(types []string, now time.Time) (RecentCounts, error)
+	EnsureIndexes() error
+}
+
+// Repo is the struct that implements the Repository interface on top of the `audit` collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `Record` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It appends an event to the audit log, stamping it with the current time when it has none.
+func (s *Repo) Record(event Event) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = pkg.NowUTC()
+	}
+	return retry.Default.Do(s.context, func() error {
+		_, err := s.db.InsertOne(s.context, event)
+		return err
+	})
+}
+
+// The `List` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It returns a page of the events matching the filter, newest first, together with the number of
+// matching events.
+func (s *Repo) List(filter Filter, limit int, offset int) (Page, error) {
+	page := Page{Events: []Event{}, Limit: limit, Offset: offset}
+	query := bson.M{}
+	if filter.UserID != "" {
+		query["userid"] = filter.UserID
+	}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	timestamp := bson.M{}
+	if !filter.From.IsZero() {
+		timestamp["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		timestamp["$lt"] = filter.To
+	}
+	if len(timestamp) > 0 {
+		query["timestamp"] = timestamp
+	}
+	opts := options.Find().SetSort(bson.M{"timestamp": -1}).SetSkip(int64(offset)).SetLimit(int64(limit))
+	err := retry.Default.Do(s.context, func() error {
+		total, err := s.db.CountDocuments(s.context, query)
+		if err != nil {
+			return err
+		}
+		page.Total = total
+		cursor, err := s.db.Find(s.context, query, opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &page.Events)
+	})
+	return page, err
+}
+
+// The `EnsureIndexes` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It creates the indexes behind the per-user and per-type listings, both ordered by time.
+func (s *Repo) EnsureIndexes() error {
+	_, err := s.db.Indexes().CreateMany(s.context, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userid", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "timestamp", Value: -1}}},
+	})
+	return err
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `audit` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("audit", opts...), context: ctx}
+}
@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"sigmacoder/pkg/retry"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The RecentCounts type counts audit events by how recently they happened.
+// @property {int64} Last7Days - The number of events in the last 7 days.
+// @property {int64} Last30Days - The number of events in the last 30 days.
+type RecentCounts struct {
+	Last7Days  int64 `json:"last7Days" bson:"last7days"`
+	Last30Days int64 `json:"last30Days" bson:"last30days"`
+}
+
+// The `CountRecent` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It counts the events of the given types in the 7 and 30 days before `now`, in a single
+// aggregation.
+func (s *Repo) CountRecent(types []string, now time.Time) (RecentCounts, error) {
+	weekAgo := now.AddDate(0, 0, -7)
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"type": bson.M{"$in": types}, "timestamp": bson.M{"$gte": now.AddDate(0, 0, -30)}}},
+		bson.M{"$group": bson.M{
+			"_id":        nil,
+			"last7days":  bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$timestamp", weekAgo}}, 1, 0}}},
+			"last30days": bson.M{"$sum": 1},
+		}},
+	}
+	var result []RecentCounts
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &result)
+	})
+	if err != nil || len(result) == 0 {
+		return RecentCounts{}, err
+	}
+	return result[0], nil
+}
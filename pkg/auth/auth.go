@@ -6,15 +6,22 @@ package auth
 // in the code to handle HTTP requests and responses, and to implement user authentication
 // functionality.
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"sigmacoder/pkg"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// The AuthBody type is the request body for logging in. `Identifier` is either an email or a username;
+// `Email` is its older name, still accepted when `Identifier` is empty. The `validate` tags cap the
+// length of the fields, so oversized credentials are rejected before the password is hashed.
 type AuthBody struct {
-	Email string `json:"email"`
-	Password    string `json:"password"`
+	Identifier string `json:"identifier" validate:"max=254"`
+	Email      string `json:"email" validate:"max=254"`
+	Password   string `json:"password" validate:"max=256"`
 }
 
 // The above type defines a user with various properties such as ID, name, password, phone number,
@@ -41,23 +48,56 @@ type AuthBody struct {
 // @property CreatedAt - CreatedAt is a property of the User struct that represents the date and time
 // when the user was created. It is of type time.Time and is formatted as "YYYY-MM-DD HH:MM:SS". This
 // property can be used to track when a user was added to a system or database.
-
+// @property {string} PendingEmail - The new email address the user asked to switch to. It only replaces
+// `Email` once the user follows the verification link sent to it.
+// @property {string} EmailChangeToken - The SHA-256 hash of the token in the verification link. The
+// token itself is only ever sent to the new address.
+// @property EmailChangeExpires - When the verification link stops working.
+// @property DeletionScheduledAt - When the account is going to be purged, if the user asked for it to
+// be deleted. The account stays usable until then and the deletion can still be cancelled.
+// @property {int64} SolvedCount - The number of questions the user has solved, copied from the progress
+// collection so it can be read without counting. It is only as fresh as the last recount.
+// @property PasswordChangedAt - When the password was last set, at sign up or by changing it. It is
+// zero for users that signed up before it was recorded.
+// @property {string} Timezone - The IANA name of the user's timezone, such as "Asia/Kolkata", which
+// calendar days like streaks and the problem of the day are counted in. It is empty until the user
+// sets it, and the server's default timezone is used instead.
+// Every field has an explicit `bson` tag, which is its key in the users collection and may differ from
+// its JSON name. The keys are the ones the driver derived from the field names before the tags were
+// added, except for the phone number, stored as `phone_number` like its JSON name. Users stored with
+// the old `phonenumber` key are moved over by `go run . migrate`. Queries name the keys through the
+// `field...` constants in repo.go.
 type User struct {
 	ID          string    `json:"id" bson:"_id"`
-	Name        string    `json:"name"`
-	Password    string    `json:"password"`
-	PhoneNumber string    `json:"phone_number"`
-	ProfilePic  string    `json:"profile_pic"`
-	Email       string    `json:"email"`
-	Username    string    `json:"username"`
-	UserType    string    `json:"usertype"`
-	DateOfBirth string    `json:"dob"`
-	Gender      string    `json:"gender"`
-	CreatedAt   time.Time `json:"created_at"`
+	Name        string    `json:"name" bson:"name"`
+	Password    string    `json:"password" bson:"password"`
+	PhoneNumber string    `json:"phone_number" bson:"phone_number"`
+	ProfilePic  string    `json:"profile_pic" bson:"profilepic"`
+	Email       string    `json:"email" bson:"email"`
+	Username    string    `json:"username" bson:"username"`
+	UserType    string    `json:"usertype" bson:"usertype"`
+	DateOfBirth string    `json:"dob" bson:"dateofbirth"`
+	Gender      string    `json:"gender" bson:"gender"`
+	CreatedAt   time.Time `json:"created_at" bson:"createdat"`
+
+	PendingEmail       string    `json:"-" bson:"pendingemail,omitempty"`
+	EmailChangeToken   string    `json:"-" bson:"emailchangetoken,omitempty"`
+	EmailChangeExpires time.Time `json:"-" bson:"emailchangeexpires,omitempty"`
+
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty" bson:"deletionscheduledat,omitempty"`
+
+	SolvedCount int64 `json:"solved_count" bson:"solvedcount"`
+
+	PasswordChangedAt time.Time `json:"password_changed_at" bson:"passwordchangedat,omitempty"`
+
+	Timezone string `json:"timezone" bson:"timezone,omitempty"`
 }
 
 // The above type defines the structure of an input user object in Go, with various fields such as
 // name, password, phone number, email, and gender.
+// The `validate` tags cap the length of every field, in characters, so a client can't store oversized
+// documents or strings that break the UI. Usernames can't contain "@", so a login identifier is never
+// both an email and a username. `UserType` is ignored on sign up, where every user gets the default.
 // @property {string} Name - The name of the user.
 // @property {string} Password - The "Password" property is a string that represents the user's
 // password. It is likely used for authentication purposes to ensure that only authorized users can
@@ -84,16 +124,20 @@ type User struct {
 // of user. It can be used to differentiate between different types of users, such as regular users,
 // administrators, or moderators. The value of UserType can be set to any string that represents the
 // type of user.
+// @property {string} CaptchaToken - The token of the captcha the client solved, checked by the sign up
+// verifier. It isn't stored.
 type InUser struct {
-	Name        string `json:"name"`
-	Password    string `json:"password"`
-	PhoneNumber string `json:"phonenumber"`
-	ProfilePic  string `json:"profilepic"`
-	Email       string `json:"email"`
-	Username    string `json:"username"`
-	DateOfBirth string `json:"dob"`
-	Gender      string `json:"gender"`
-	UserType    string `json:"usertype"`
+	Name        string `json:"name" validate:"max=100"`
+	Password    string `json:"password" validate:"max=256"`
+	PhoneNumber string `json:"phonenumber" validate:"max=20"`
+	ProfilePic  string `json:"profilepic" validate:"max=2048"`
+	Email       string `json:"email" validate:"max=254"`
+	Username    string `json:"username" validate:"max=40,excludes=@"`
+	DateOfBirth string `json:"dob" validate:"max=10"`
+	Gender      string `json:"gender" validate:"max=32"`
+	UserType    string `json:"usertype" validate:"max=20"`
+
+	CaptchaToken string `json:"captchaToken" validate:"max=4096"`
 }
 
 // The above type defines the structure of an output user object in Go, including various user details
@@ -115,6 +159,11 @@ type InUser struct {
 // gender identity.
 // @property CreatedAt - CreatedAt is a property of the OutUser struct that represents the date and
 // time when the user was created. It is of type time.Time and is formatted as "YYYY-MM-DD HH:MM:SS".
+// @property DeletionScheduledAt - When the account is going to be purged, if its deletion was
+// requested. Clients use it to offer cancelling the deletion.
+// @property PasswordChangedAt - When the password was last set. Users that signed up before it was
+// recorded report when they signed up.
+// @property {string} Timezone - The user's timezone, or an empty string when they haven't set one.
 type OutUser struct {
 	ID          string    `json:"id" bson:"_id"`
 	Name        string    `json:"name"`
@@ -126,28 +175,41 @@ type OutUser struct {
 	DateOfBirth string    `json:"dob"`
 	Gender      string    `json:"gender"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	PasswordChangedAt   time.Time  `json:"password_changed_at"`
+	Timezone            string     `json:"timezone"`
 }
 
 // The `ToUser()` function is a method of the `InUser` struct that converts an input user object of
 // type `InUser` to an output user object of type `User`. It generates a new UUID for the user ID,
 // hashes the user's password using the `hashPassword()` function, and sets the remaining user
 // properties based on the input `InUser` object. The function returns a new `User` object with the
-// generated UUID and hashed password, along with the other user properties.
-func (in *InUser) ToUser() User {
+// generated UUID and hashed password, along with the other user properties, or the error hashing the
+// password failed with. The input is normalized first, so whitespace never reaches the database.
+func (in *InUser) ToUser() (User, error) {
+	in.Normalize()
+	hash, err := hashPassword(in.Password)
+	if err != nil {
+		return User{}, err
+	}
 	uuid := uuid.New().String()
+	now := pkg.NowUTC()
 	return User{
 		ID:          uuid,
 		Name:        in.Name,
 		ProfilePic:  in.ProfilePic,
 		PhoneNumber: in.PhoneNumber,
-		Password:    hashPassword(in.Password),
+		Password:    hash,
 		Email:       in.Email,
 		UserType:    in.UserType,
 		Username:    in.Username,
 		DateOfBirth: in.DateOfBirth,
 		Gender:      in.Gender,
-		CreatedAt:   time.Now(),
-	}
+		CreatedAt:   now,
+
+		PasswordChangedAt: now,
+	}, nil
 }
 
 // The `func (u *User) ToOutUser() OutUser` method is a function that takes a `User` object as a
@@ -167,12 +229,48 @@ func (u *User) ToOutUser() OutUser {
 		DateOfBirth: u.DateOfBirth,
 		Gender:      u.Gender,
 		CreatedAt:   u.CreatedAt,
+
+		DeletionScheduledAt: u.DeletionScheduledAt,
+		PasswordChangedAt:   u.passwordSetAt(),
+		Timezone:            u.Timezone,
 	}
 }
 
-// The function takes a password string, generates a hash using bcrypt algorithm with minimum cost, and
-// returns the hash as a string.
-func hashPassword(password string) string {
-	bytes, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
-	return string(bytes)
+// The PublicUser type is the part of a user that may be shown to other users, for example next to a
+// solution they shared. It deliberately leaves out contact details such as the email and phone number.
+// @property {string} Username - The username of the user.
+// @property {string} Name - The display name of the user.
+// @property {string} ProfilePic - The URL of the user's profile picture.
+type PublicUser struct {
+	Username   string `json:"username"`
+	Name       string `json:"name"`
+	ProfilePic string `json:"profile_pic"`
+}
+
+// The `ToPublicUser` method converts a `User` to the `PublicUser` projection that is safe to show to
+// other users.
+func (u *User) ToPublicUser() PublicUser {
+	return PublicUser{
+		Username:   u.Username,
+		Name:       u.Name,
+		ProfilePic: u.ProfilePic,
+	}
+}
+
+// `PasswordPepper` is an application-wide secret mixed into every password before it is hashed, so a
+// leaked database alone isn't enough to brute-force the hashes offline. It is set from
+// `PASSWORD_PEPPER` at startup. Changing it invalidates every existing password, which then have to be
+// reset. When it is empty, passwords are hashed as they are.
+var PasswordPepper string
+
+// The function returns the bytes that are actually hashed for a password. With a pepper, the password
+// is first run through HMAC-SHA256 keyed with it rather than having the pepper appended, since bcrypt
+// ignores everything past 72 bytes and would silently drop the pepper from long passwords.
+func pepperedPassword(password string) []byte {
+	if PasswordPepper == "" {
+		return []byte(password)
+	}
+	mac := hmac.New(sha256.New, []byte(PasswordPepper))
+	mac.Write([]byte(password))
+	return []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
 }
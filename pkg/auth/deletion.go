@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/sessions"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// `DeletionGracePeriod` is how long an account stays around after its user asked for it to be
+// deleted. It is set from `ACCOUNT_DELETION_GRACE` at startup.
+var DeletionGracePeriod = 30 * 24 * time.Hour
+
+// The function reports whether the user's grace period has run out, in which case the account is
+// treated as deleted even if it hasn't been purged yet.
+func (u *User) deletionDue(now time.Time) bool {
+	return u.DeletionScheduledAt != nil && !now.Before(*u.DeletionScheduledAt)
+}
+
+// The `ScheduleDeletion` function is a method of the `Svc` struct that implements the
+// `ScheduleDeletion` method of the `Service` interface. It schedules the user's account to be purged
+// once `DeletionGracePeriod` has passed and returns when that will happen. The account stays usable in
+// the meantime. Asking again keeps the original date, so the grace period can't be extended by
+// repeating the request.
+func (s *Svc) ScheduleDeletion(userID string, device sessions.Device) (time.Time, error) {
+	user, err := s.repo.ReadByID(userID)
+	if err != nil {
+		return time.Time{}, pkg.ErrUserNotFound
+	}
+	if user.DeletionScheduledAt != nil {
+		return *user.DeletionScheduledAt, nil
+	}
+	scheduledAt := pkg.NowUTC().Add(DeletionGracePeriod)
+	if _, err := s.repo.Update(userID, bson.M{"$set": bson.M{"deletionscheduledat": scheduledAt}}); err != nil {
+		return time.Time{}, err
+	}
+	s.audit(audit.EventDeletionRequested, user, device)
+	return scheduledAt, nil
+}
+
+// The `CancelDeletion` function is a method of the `Svc` struct that implements the `CancelDeletion`
+// method of the `Service` interface. It keeps the user's account by clearing its scheduled deletion,
+// and returns `pkg.ErrDeletionNotScheduled` when there is none.
+func (s *Svc) CancelDeletion(userID string, device sessions.Device) error {
+	user, err := s.repo.ReadByID(userID)
+	if err != nil {
+		return pkg.ErrUserNotFound
+	}
+	if user.DeletionScheduledAt == nil {
+		return pkg.ErrDeletionNotScheduled
+	}
+	if _, err := s.repo.Update(userID, bson.M{"$unset": bson.M{"deletionscheduledat": ""}}); err != nil {
+		return err
+	}
+	s.audit(audit.EventDeletionCancelled, user, device)
+	return nil
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/sessions"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestScheduleDeletion(t *testing.T) {
+	dbtest.Run(t, "scheduled after the grace period", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo)).(*Svc)
+		mt.AddMockResponses(dbtest.Cursor(mt, User{ID: "user-1"}), dbtest.Value(mt, User{ID: "user-1"}))
+		before := pkg.NowUTC()
+		scheduledAt, err := svc.ScheduleDeletion("user-1", sessions.Device{})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if want := before.Add(DeletionGracePeriod); scheduledAt.Before(want) || scheduledAt.After(want.Add(time.Minute)) {
+			mt.Errorf("ScheduleDeletion() = %s, want %s from now", scheduledAt, DeletionGracePeriod)
+		}
+		dbtest.NextCommand(mt, "find")
+		command := dbtest.NextCommand(mt, "findAndModify")
+		dbtest.ExpectField(mt, command, "query", bson.M{"_id": "user-1"})
+		if _, ok := command["update"].(bson.M)["$set"].(bson.M)["deletionscheduledat"]; !ok {
+			mt.Errorf("update = %v, want deletionscheduledat set", command["update"])
+		}
+	})
+
+	dbtest.Run(t, "asking again keeps the date", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo)).(*Svc)
+		original := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+		mt.AddMockResponses(dbtest.Cursor(mt, User{ID: "user-1", DeletionScheduledAt: &original}))
+		scheduledAt, err := svc.ScheduleDeletion("user-1", sessions.Device{})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if !scheduledAt.Equal(original) {
+			mt.Errorf("ScheduleDeletion() = %s, want the original %s", scheduledAt, original)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "unknown user", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo)).(*Svc)
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if _, err := svc.ScheduleDeletion("nobody", sessions.Device{}); !errors.Is(err, pkg.ErrUserNotFound) {
+			mt.Errorf("ScheduleDeletion() = %v, want %v", err, pkg.ErrUserNotFound)
+		}
+	})
+}
+
+func TestCancelDeletion(t *testing.T) {
+	dbtest.Run(t, "scheduled", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo)).(*Svc)
+		scheduledAt := pkg.NowUTC().Add(DeletionGracePeriod)
+		mt.AddMockResponses(dbtest.Cursor(mt, User{ID: "user-1", DeletionScheduledAt: &scheduledAt}), dbtest.Value(mt, User{ID: "user-1"}))
+		if err := svc.CancelDeletion("user-1", sessions.Device{}); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "findAndModify"), "update", bson.M{"$unset": bson.M{"deletionscheduledat": ""}})
+	})
+
+	dbtest.Run(t, "not scheduled", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo)).(*Svc)
+		mt.AddMockResponses(dbtest.Cursor(mt, User{ID: "user-1"}))
+		if err := svc.CancelDeletion("user-1", sessions.Device{}); !errors.Is(err, pkg.ErrDeletionNotScheduled) {
+			mt.Errorf("CancelDeletion() = %v, want %v", err, pkg.ErrDeletionNotScheduled)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestDeletionDue(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		scheduledAt := now.Add(d)
+		return &scheduledAt
+	}
+	tests := []struct {
+		name        string
+		scheduledAt *time.Time
+		want        bool
+	}{
+		{name: "not scheduled", want: false},
+		{name: "within the grace period", scheduledAt: at(time.Second), want: false},
+		{name: "at the end of the grace period", scheduledAt: at(0), want: true},
+		{name: "after the grace period", scheduledAt: at(-time.Hour), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := User{DeletionScheduledAt: tt.scheduledAt}
+			if got := user.deletionDue(now); got != tt.want {
+				t.Errorf("deletionDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginDuringDeletion(t *testing.T) {
+	dbtest.Run(t, "within the grace period", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo)).(*Svc)
+		scheduledAt := pkg.NowUTC().Add(time.Hour)
+		mt.AddMockResponses(dbtest.Cursor(mt, User{ID: "user-1", Email: "ada@example.com", Password: mustHashPassword(mt.T, "correct horse"), DeletionScheduledAt: &scheduledAt}))
+		if _, _, err := svc.Login("ada@example.com", "correct horse", sessions.Device{}); err != nil {
+			mt.Errorf("Login() = %v, want the account usable until it is purged", err)
+		}
+	})
+
+	dbtest.Run(t, "after the grace period", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo)).(*Svc)
+		scheduledAt := pkg.NowUTC().Add(-time.Hour)
+		mt.AddMockResponses(dbtest.Cursor(mt, User{ID: "user-1", Email: "ada@example.com", Password: mustHashPassword(mt.T, "correct horse"), DeletionScheduledAt: &scheduledAt}))
+		if _, _, err := svc.Login("ada@example.com", "correct horse", sessions.Device{}); !errors.Is(err, pkg.ErrUserNotFound) {
+			mt.Errorf("Login() = %v, want %v before the account is purged", err, pkg.ErrUserNotFound)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/mail"
+	"sigmacoder/pkg"
+	mailer "sigmacoder/pkg/mail"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// `emailChangeTTL` is how long the verification link of an email change stays valid.
+const emailChangeTTL = 24 * time.Hour
+
+// The function returns the hex encoded SHA-256 hash of a verification token. Only the hash is stored,
+// so the tokens can't be read back from the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// The `RequestEmailChange` function is a method of the `Svc` struct that implements the
+// `RequestEmailChange` method of the `Service` interface. It checks that the new address is valid and
+// not used by another account, stores it as the user's pending email and sends a verification link to
+// it. The current email stays in place until the link is followed, so a mistyped address can't lock
+// the user out. Requesting another change replaces the pending one.
+func (s *Svc) RequestEmailChange(userID string, newEmail string) error {
+	newEmail = NormalizeEmail(newEmail)
+	if _, err := mail.ParseAddress(newEmail); err != nil {
+		return pkg.ErrInvalidEmail
+	}
+	if err := s.checkEmailFree(userID, newEmail); err != nil {
+		return err
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(raw)
+	_, err := s.repo.Update(userID, bson.M{"$set": bson.M{
+		"pendingemail":       newEmail,
+		"emailchangetoken":   hashToken(token),
+		"emailchangeexpires": pkg.NowUTC().Add(emailChangeTTL),
+	}})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: "Follow this link within 24 hours to start using this address for your SigmaCoder account:\n\n" +
+			s.baseURL + "/api/v1/auth/verify-email-change?token=" + token + "\n\n" +
+			"If you didn't ask for this, you can ignore this email.",
+	})
+}
+
+// The `ConfirmEmailChange` function is a method of the `Svc` struct that implements the
+// `ConfirmEmailChange` method of the `Service` interface. It swaps the pending email belonging to the
+// token into the user's email and clears the pending change. The address is checked again, since
+// another account may have claimed it in the meantime.
+func (s *Svc) ConfirmEmailChange(token string) (User, error) {
+	if token == "" {
+		return User{}, pkg.ErrInvalidEmailToken
+	}
+	user, err := s.repo.ReadByEmailChangeToken(hashToken(token))
+	if errors.Is(err, pkg.ErrUserNotFound) || (err == nil && time.Now().After(user.EmailChangeExpires)) {
+		return User{}, pkg.ErrInvalidEmailToken
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if err := s.checkEmailFree(user.ID, user.PendingEmail); err != nil {
+		return User{}, err
+	}
+	if _, err := s.repo.Update(user.ID, bson.M{
+		"$set":   bson.M{"email": user.PendingEmail},
+		"$unset": bson.M{"pendingemail": "", "emailchangetoken": "", "emailchangeexpires": ""},
+	}); err != nil {
+		return User{}, err
+	}
+	user.Email = user.PendingEmail
+	user.PendingEmail = ""
+	user.EmailChangeToken = ""
+	user.EmailChangeExpires = time.Time{}
+	return user, nil
+}
+
+// The function returns `pkg.ErrEmailTaken` when the address belongs to an account other than the
+// given user's.
+func (s *Svc) checkEmailFree(userID string, email string) error {
+	existing, err := s.repo.ReadByEmail(email)
+	if errors.Is(err, pkg.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != userID {
+		return pkg.ErrEmailTaken
+	}
+	return nil
+}
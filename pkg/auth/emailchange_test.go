@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	mailer "sigmacoder/pkg/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The type recordingMailer is a mail sender that keeps the messages it is asked to send.
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// The function returns the verification token in the link of an email change message.
+func linkToken(t *mtest.T, msg mailer.Message) string {
+	t.Helper()
+	_, after, found := strings.Cut(msg.Body, "/api/v1/auth/verify-email-change?token=")
+	if !found {
+		t.Fatalf("message body %q has no verification link", msg.Body)
+	}
+	token, _, _ := strings.Cut(after, "\n")
+	return token
+}
+
+func TestRequestEmailChange(t *testing.T) {
+	dbtest.Run(t, "pending until confirmed", func(mt *mtest.T) {
+		sent := &recordingMailer{}
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo), WithMailer(sent, "https://sigmacoder.example")).(*Svc)
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Value(mt, User{ID: "user-1", Email: "ada@example.com"}))
+		if err := svc.RequestEmailChange("user-1", " ada@example.net "); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"email": "ada@example.net"})
+		command := dbtest.NextCommand(mt, "findAndModify")
+		dbtest.ExpectField(mt, command, "query", bson.M{"_id": "user-1"})
+		set := command["update"].(bson.M)["$set"].(bson.M)
+		if _, ok := set["email"]; ok {
+			mt.Errorf("$set = %v, want the current email left in place", set)
+		}
+		if set["pendingemail"] != "ada@example.net" {
+			mt.Errorf("pendingemail = %v, want the trimmed new address", set["pendingemail"])
+		}
+		if len(sent.sent) != 1 || sent.sent[0].To != "ada@example.net" {
+			mt.Fatalf("sent %+v, want one message to the new address", sent.sent)
+		}
+		token := linkToken(mt, sent.sent[0])
+		if set["emailchangetoken"] != hashToken(token) {
+			mt.Errorf("emailchangetoken = %v, want the hash of the token in the link", set["emailchangetoken"])
+		}
+		if set["emailchangetoken"] == token {
+			mt.Error("stored the raw token, want only its hash")
+		}
+	})
+
+	dbtest.Run(t, "email taken", func(mt *mtest.T) {
+		sent := &recordingMailer{}
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo), WithMailer(sent, "")).(*Svc)
+		mt.AddMockResponses(dbtest.Cursor(mt, User{ID: "user-2", Email: "grace@example.com"}))
+		if err := svc.RequestEmailChange("user-1", "grace@example.com"); !errors.Is(err, pkg.ErrEmailTaken) {
+			mt.Fatalf("RequestEmailChange() = %v, want %v", err, pkg.ErrEmailTaken)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+		if len(sent.sent) != 0 {
+			mt.Errorf("sent %+v, want no message", sent.sent)
+		}
+	})
+
+	dbtest.Run(t, "invalid address", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo)).(*Svc)
+		if err := svc.RequestEmailChange("user-1", "not an address"); !errors.Is(err, pkg.ErrInvalidEmail) {
+			mt.Fatalf("RequestEmailChange() = %v, want %v", err, pkg.ErrInvalidEmail)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	pending := User{
+		ID:                 "user-1",
+		Email:              "ada@example.com",
+		PendingEmail:       "ada@example.net",
+		EmailChangeToken:   hashToken("token"),
+		EmailChangeExpires: time.Now().Add(time.Hour),
+	}
+
+	dbtest.Run(t, "swaps the email", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo)).(*Svc)
+		mt.AddMockResponses(dbtest.Cursor(mt, pending), dbtest.Cursor(mt), dbtest.Value(mt, pending))
+		user, err := svc.ConfirmEmailChange("token")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if user.Email != "ada@example.net" || user.PendingEmail != "" || user.EmailChangeToken != "" {
+			mt.Errorf("ConfirmEmailChange() = %+v, want the new email and no pending change", user)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"emailchangetoken": hashToken("token")})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"email": "ada@example.net"})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "findAndModify"), "update", bson.M{
+			"$set":   bson.M{"email": "ada@example.net"},
+			"$unset": bson.M{"pendingemail": "", "emailchangetoken": "", "emailchangeexpires": ""},
+		})
+	})
+
+	dbtest.Run(t, "email taken in the meantime", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo)).(*Svc)
+		mt.AddMockResponses(dbtest.Cursor(mt, pending), dbtest.Cursor(mt, User{ID: "user-2", Email: "ada@example.net"}))
+		if _, err := svc.ConfirmEmailChange("token"); !errors.Is(err, pkg.ErrEmailTaken) {
+			mt.Fatalf("ConfirmEmailChange() = %v, want %v", err, pkg.ErrEmailTaken)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	expired := pending
+	expired.EmailChangeExpires = time.Now().Add(-time.Minute)
+	for _, test := range []struct {
+		name  string
+		token string
+		found []interface{}
+	}{
+		{name: "no token", token: ""},
+		{name: "unknown token", token: "other", found: []interface{}{}},
+		{name: "expired token", token: "token", found: []interface{}{expired}},
+	} {
+		dbtest.Run(t, test.name, func(mt *mtest.T) {
+			svc := NewAuthService(NewRepo(mt.DB).(*Repo)).(*Svc)
+			if test.found != nil {
+				mt.AddMockResponses(dbtest.Cursor(mt, test.found...))
+			}
+			if _, err := svc.ConfirmEmailChange(test.token); !errors.Is(err, pkg.ErrInvalidEmailToken) {
+				mt.Fatalf("ConfirmEmailChange() = %v, want %v", err, pkg.ErrInvalidEmailToken)
+			}
+			if test.found != nil {
+				dbtest.NextCommand(mt, "find")
+			}
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+}
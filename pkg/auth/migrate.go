@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// `legacyPhoneNumberField` is the key phone numbers were stored under before `User` had explicit
+// `bson` tags, derived by the driver from the `PhoneNumber` field name.
+const legacyPhoneNumberField = "phonenumber"
+
+// The PhoneNumberMigration type reports what `MigratePhoneNumberField` changed.
+// @property {int64} UsersUpdated - How many users had their phone number moved to the new key.
+// @property {[]string} IndexesRebuilt - The names of the indexes on the old key that were replaced by
+// the same index on the new key.
+type PhoneNumberMigration struct {
+	UsersUpdated   int64
+	IndexesRebuilt []string
+}
+
+// The type storedIndex is the part of an index description that is carried over when an index is
+// rebuilt on the new key.
+type storedIndex struct {
+	Name    string `bson:"name"`
+	Key     bson.D `bson:"key"`
+	Unique  bool   `bson:"unique"`
+	Sparse  bool   `bson:"sparse"`
+	Partial bson.M `bson:"partialFilterExpression"`
+}
+
+// The `MigratePhoneNumberField` function is a method of the `Repo` struct. It moves the phone number of
+// every user from the legacy `phonenumber` key to `phone_number` with `$rename`, and replaces every
+// index on the old key by the same index on the new one. Users and indexes that were already migrated
+// are left alone, so it can be run repeatedly.
+func (s *Repo) MigratePhoneNumberField() (PhoneNumberMigration, error) {
+	var migration PhoneNumberMigration
+	var result *mongo.UpdateResult
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		result, err = s.db.UpdateMany(s.context,
+			bson.M{legacyPhoneNumberField: bson.M{"$exists": true}},
+			bson.M{"$rename": bson.M{legacyPhoneNumberField: string(fieldPhoneNumber)}})
+		return err
+	})
+	if err != nil {
+		return migration, err
+	}
+	migration.UsersUpdated = result.ModifiedCount
+	migration.IndexesRebuilt, err = s.rebuildPhoneNumberIndexes()
+	return migration, err
+}
+
+// The function replaces every index whose key includes the legacy phone number key by the same index
+// on `phone_number`, and returns the names of the replaced indexes. The new index is created before
+// the old one is dropped, so lookups by phone number stay indexed throughout.
+func (s *Repo) rebuildPhoneNumberIndexes() ([]string, error) {
+	rebuilt := []string{}
+	cursor, err := s.db.Indexes().List(s.context)
+	if err != nil {
+		return rebuilt, err
+	}
+	var indexes []storedIndex
+	if err := cursor.All(s.context, &indexes); err != nil {
+		return rebuilt, err
+	}
+	for _, index := range indexes {
+		key, changed := renameIndexKey(index.Key)
+		if !changed {
+			continue
+		}
+		opts := options.Index()
+		if index.Unique {
+			opts.SetUnique(true)
+		}
+		if index.Sparse {
+			opts.SetSparse(true)
+		}
+		if index.Partial != nil {
+			opts.SetPartialFilterExpression(index.Partial)
+		}
+		if _, err := s.db.Indexes().CreateOne(s.context, mongo.IndexModel{Keys: key, Options: opts}); err != nil {
+			return rebuilt, err
+		}
+		if _, err := s.db.Indexes().DropOne(s.context, index.Name); err != nil {
+			return rebuilt, err
+		}
+		rebuilt = append(rebuilt, index.Name)
+	}
+	return rebuilt, nil
+}
+
+// The function returns the index key with the legacy phone number key replaced by `phone_number`, and
+// whether it contained it.
+func renameIndexKey(key bson.D) (bson.D, bool) {
+	renamed := make(bson.D, len(key))
+	changed := false
+	for i, element := range key {
+		if element.Key == legacyPhoneNumberField {
+			element.Key = string(fieldPhoneNumber)
+			changed = true
+		}
+		renamed[i] = element
+	}
+	return renamed, changed
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"reflect"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMigratePhoneNumberField(t *testing.T) {
+	idIndex := bson.M{"v": 2, "name": "_id_", "key": bson.D{{Key: "_id", Value: 1}}}
+
+	dbtest.Run(t, "old field renamed", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(
+			dbtest.Written(2),
+			dbtest.Cursor(mt, idIndex, bson.M{"v": 2, "name": "phonenumber_1", "key": bson.D{{Key: "phonenumber", Value: 1}}, "unique": true, "sparse": true}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+		)
+		migration, err := repo.MigratePhoneNumberField()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if migration.UsersUpdated != 2 || !reflect.DeepEqual(migration.IndexesRebuilt, []string{"phonenumber_1"}) {
+			mt.Errorf("MigratePhoneNumberField() = %+v, want 2 users and the phonenumber_1 index", migration)
+		}
+		update := dbtest.NextCommand(mt, "update")["updates"].(bson.A)[0].(bson.M)
+		dbtest.ExpectField(mt, update, "q", bson.M{"phonenumber": bson.M{"$exists": true}})
+		dbtest.ExpectField(mt, update, "u", bson.M{"$rename": bson.M{"phonenumber": "phone_number"}})
+		dbtest.ExpectField(mt, update, "multi", true)
+		dbtest.NextCommand(mt, "listIndexes")
+		created := dbtest.NextCommand(mt, "createIndexes")["indexes"].(bson.A)[0].(bson.M)
+		dbtest.ExpectField(mt, created, "key", bson.M{"phone_number": int32(1)})
+		if created["unique"] != true || created["sparse"] != true {
+			mt.Errorf("created index %v, want it unique and sparse like the old one", created)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "dropIndexes"), "index", "phonenumber_1")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "already migrated", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(
+			dbtest.Written(0),
+			dbtest.Cursor(mt, idIndex, bson.M{"v": 2, "name": "phone_number_1", "key": bson.D{{Key: "phone_number", Value: 1}}, "unique": true}),
+		)
+		migration, err := repo.MigratePhoneNumberField()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if migration.UsersUpdated != 0 || migration.IndexesRebuilt == nil || len(migration.IndexesRebuilt) != 0 {
+			mt.Errorf("MigratePhoneNumberField() = %#v, want nothing changed", migration)
+		}
+		dbtest.NextCommand(mt, "update")
+		dbtest.NextCommand(mt, "listIndexes")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestRenameIndexKey(t *testing.T) {
+	key, changed := renameIndexKey(bson.D{{Key: "usertype", Value: 1}, {Key: "phonenumber", Value: -1}})
+	if want := (bson.D{{Key: "usertype", Value: 1}, {Key: "phone_number", Value: -1}}); !changed || !reflect.DeepEqual(key, want) {
+		t.Errorf("renameIndexKey() = %v, %v, want %v, true", key, changed, want)
+	}
+	if _, changed := renameIndexKey(bson.D{{Key: "email", Value: 1}}); changed {
+		t.Error("renameIndexKey() of an index without the phone number = true, want false")
+	}
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"sigmacoder/pkg"
+	"strings"
+)
+
+// The function trims leading and trailing whitespace and collapses every run of internal whitespace to a
+// single space, so "  Ada   Lovelace " becomes "Ada Lovelace".
+func CollapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// The function returns the form of an email address used for storage and lookups. Lookups by email
+// additionally ignore case, see `Repo.ReadByEmail`.
+func NormalizeEmail(email string) string {
+	return strings.TrimSpace(email)
+}
+
+// The `Normalize` method trims the whitespace around every field of the input user and collapses the
+// internal whitespace of the name, so padded input can't create accounts that look like duplicates or
+// fail later lookups. The password is left exactly as typed, since the whitespace in it is part of the
+// secret.
+func (in *InUser) Normalize() {
+	in.Name = CollapseSpaces(in.Name)
+	in.PhoneNumber = strings.TrimSpace(in.PhoneNumber)
+	in.ProfilePic = strings.TrimSpace(in.ProfilePic)
+	in.Email = NormalizeEmail(in.Email)
+	in.Username = strings.TrimSpace(in.Username)
+	in.DateOfBirth = strings.TrimSpace(in.DateOfBirth)
+	in.Gender = strings.TrimSpace(in.Gender)
+	in.UserType = strings.TrimSpace(in.UserType)
+}
+
+// The `Normalize` method trims the whitespace around the identifier and email of the login credentials,
+// and falls back to the email when no identifier was given. As with `InUser`, the password is left
+// untouched.
+func (b *AuthBody) Normalize() {
+	b.Email = NormalizeEmail(b.Email)
+	b.Identifier = strings.TrimSpace(b.Identifier)
+	if b.Identifier == "" {
+		b.Identifier = b.Email
+	}
+}
+
+// `DefaultCountryCode` is the country calling code, without the "+", assumed for phone numbers given in
+// their local format, such as "098765 43210". It is set from `PHONE_DEFAULT_COUNTRY_CODE` at startup.
+// When it is empty, only numbers in the international format are accepted.
+var DefaultCountryCode string
+
+// The function returns the E.164 form of a phone number, such as "+14155552671", the form OTP providers
+// expect. Spaces, dashes, dots and parentheses are dropped and a leading "00" is read as "+". A number
+// without either is taken to be local: its leading trunk "0" is dropped and `DefaultCountryCode` is
+// prepended. It returns `pkg.ErrInvalidPhone` when the result isn't a "+" followed by 8 to 15 digits,
+// the first of which isn't 0.
+func NormalizePhone(phone string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+	switch {
+	case strings.HasPrefix(digits, "+"):
+		digits = digits[1:]
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case DefaultCountryCode != "":
+		digits = DefaultCountryCode + strings.TrimPrefix(digits, "0")
+	default:
+		return "", pkg.ErrInvalidPhone
+	}
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", pkg.ErrInvalidPhone
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", pkg.ErrInvalidPhone
+		}
+	}
+	return "+" + digits, nil
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/sessions"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestInUserNormalize(t *testing.T) {
+	in := InUser{
+		Name:        "  Ada \t  Lovelace\n",
+		Password:    " secret with spaces ",
+		PhoneNumber: " +15551234567 ",
+		ProfilePic:  " https://example.com/ada.png ",
+		Email:       "  ada@example.com ",
+		Username:    " ada ",
+		DateOfBirth: " 1815-12-10 ",
+		Gender:      " female ",
+		UserType:    " user ",
+	}
+	in.Normalize()
+	want := InUser{
+		Name:        "Ada Lovelace",
+		Password:    " secret with spaces ",
+		PhoneNumber: "+15551234567",
+		ProfilePic:  "https://example.com/ada.png",
+		Email:       "ada@example.com",
+		Username:    "ada",
+		DateOfBirth: "1815-12-10",
+		Gender:      "female",
+		UserType:    "user",
+	}
+	if in != want {
+		t.Errorf("Normalize() = %+v, want %+v", in, want)
+	}
+}
+
+func TestAuthBodyNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		body AuthBody
+		want string
+	}{
+		{name: "identifier", body: AuthBody{Identifier: "  ada  "}, want: "ada"},
+		{name: "email fallback", body: AuthBody{Email: " ada@example.com\t"}, want: "ada@example.com"},
+		{name: "identifier first", body: AuthBody{Identifier: " ada ", Email: "other@example.com"}, want: "ada"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body
+			body.Normalize()
+			if body.Identifier != tt.want {
+				t.Errorf("Identifier = %q, want %q", body.Identifier, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaddedSignUpAndLogin(t *testing.T) {
+	dbtest.Run(t, "stored normalized and found again", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt), dbtest.Written(1))
+		in := InUser{Name: " Ada   Lovelace ", Email: " ada@example.com ", Username: " ada ", PhoneNumber: " +15551234567 ", Password: "correct horse"}
+		if _, err := svc.SignUp(in, sessions.Device{}); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"email": "ada@example.com"})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"username": "ada"})
+		stored := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+		for field, want := range map[string]string{"name": "Ada Lovelace", "email": "ada@example.com", "username": "ada", "phone_number": "+15551234567"} {
+			if stored[field] != want {
+				mt.Errorf("stored %s = %q, want %q", field, stored[field], want)
+			}
+		}
+
+		// Logging in with the same padding finds the stored user.
+		body := AuthBody{Identifier: "  ada@example.com ", Password: "correct horse"}
+		body.Normalize()
+		mt.AddMockResponses(dbtest.Cursor(mt, stored))
+		if _, _, err := svc.Login(body.Identifier, body.Password, sessions.Device{}); err != nil {
+			mt.Fatalf("Login() = %v, want the padded login to succeed", err)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"email": "ada@example.com"})
+	})
+}
+
+func TestNormalizePhone(t *testing.T) {
+	defer func(code string) { DefaultCountryCode = code }(DefaultCountryCode)
+	tests := []struct {
+		name    string
+		country string
+		phone   string
+		want    string
+		err     error
+	}{
+		{name: "e164", phone: "+14155552671", want: "+14155552671"},
+		{name: "e164 with separators", phone: " +1 (415) 555-2671 ", want: "+14155552671"},
+		{name: "international prefix", phone: "0044 20 7946 0958", want: "+442079460958"},
+		{name: "local with trunk zero", country: "91", phone: "098765 43210", want: "+919876543210"},
+		{name: "local without trunk zero", country: "91", phone: "98765.43210", want: "+919876543210"},
+		{name: "local without a default country", phone: "098765 43210", err: pkg.ErrInvalidPhone},
+		{name: "letters", phone: "+1415555CALL", err: pkg.ErrInvalidPhone},
+		{name: "too short", phone: "+1415", err: pkg.ErrInvalidPhone},
+		{name: "too long", phone: "+1415555267112345", err: pkg.ErrInvalidPhone},
+		{name: "country code zero", phone: "+04155552671", err: pkg.ErrInvalidPhone},
+		{name: "empty", phone: "  ", err: pkg.ErrInvalidPhone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			DefaultCountryCode = tt.country
+			got, err := NormalizePhone(tt.phone)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("NormalizePhone(%q) error = %v, want %v", tt.phone, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizePhone(%q) = %q, want %q", tt.phone, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The password hashing algorithms. New hashes are created with `PasswordHash`.
+const (
+	HashArgon2id = "argon2id"
+	HashBcrypt   = "bcrypt"
+)
+
+// `PasswordHash` is the algorithm new password hashes are created with. It is set from
+// `PASSWORD_HASH` at startup and defaults to argon2id. Hashes created with the other algorithm keep
+// working, and are replaced with one of this algorithm the next time their user logs in.
+var PasswordHash = HashArgon2id
+
+// The argon2id parameters, following the second recommendation of RFC 9106. They are stored in every
+// hash, so they can be raised later without breaking existing ones.
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// `errMalformedHash` is returned for a stored hash that isn't in any known format.
+var errMalformedHash = errors.New("malformed password hash")
+
+// The function hashes a password with `PasswordHash`. Argon2id hashes are encoded in the PHC string
+// format, `$argon2id$v=19$m=...,t=...,p=...$<salt>$<hash>`, and bcrypt ones start with `$2`, which is
+// how `checkPassword` tells them apart. Bcrypt hashes use `bcrypt.DefaultCost`. It fails when no
+// random salt could be read, or bcrypt refuses the password, rather than storing a weaker hash.
+func hashPassword(password string) (string, error) {
+	if PasswordHash == HashBcrypt {
+		bytes, err := bcrypt.GenerateFromPassword(pepperedPassword(password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes), nil
+	}
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey(pepperedPassword(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// The function reports whether the password matches the stored hash, detecting the algorithm from the
+// hash's prefix. When it matches but the hash wasn't made with `PasswordHash`, `rehash` is true and the
+// caller should store a fresh hash of the password.
+func checkPassword(hash string, password string) (rehash bool, err error) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return PasswordHash != HashArgon2id, checkArgon2id(hash, password)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), pepperedPassword(password)); err != nil {
+		return false, err
+	}
+	return PasswordHash != HashBcrypt, nil
+}
+
+// The function checks a password against an argon2id hash in the PHC string format, using the
+// parameters stored in the hash.
+func checkArgon2id(hash string, password string) error {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return errMalformedHash
+	}
+	var version int
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return errMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return errMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return errMalformedHash
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return errMalformedHash
+	}
+	got := argon2.IDKey(pepperedPassword(password), salt, time, memory, threads, uint32(len(want)))
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return bcrypt.ErrMismatchedHashAndPassword
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/sessions"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The function returns the hash of a password with the current settings, failing the test when it can't
+// be hashed.
+func mustHashPassword(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := hashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// The function sets the password hashing algorithm and pepper for the rest of the test.
+func usePasswordSettings(t *testing.T, algorithm string, pepper string) {
+	t.Helper()
+	previousHash, previousPepper := PasswordHash, PasswordPepper
+	t.Cleanup(func() { PasswordHash, PasswordPepper = previousHash, previousPepper })
+	PasswordHash, PasswordPepper = algorithm, pepper
+}
+
+func TestPasswordPepper(t *testing.T) {
+	for _, algorithm := range []string{HashArgon2id, HashBcrypt} {
+		t.Run(algorithm+" without a pepper", func(t *testing.T) {
+			usePasswordSettings(t, algorithm, "")
+			hash := mustHashPassword(t, "correct horse")
+			if _, err := checkPassword(hash, "correct horse"); err != nil {
+				t.Errorf("checkPassword() of the right password = %v", err)
+			}
+			if _, err := checkPassword(hash, "wrong horse"); err == nil {
+				t.Error("checkPassword() of a wrong password = nil, want an error")
+			}
+		})
+
+		t.Run(algorithm+" with a pepper", func(t *testing.T) {
+			usePasswordSettings(t, algorithm, "pepper-1")
+			hash := mustHashPassword(t, "correct horse")
+			if _, err := checkPassword(hash, "correct horse"); err != nil {
+				t.Errorf("checkPassword() of the right password = %v", err)
+			}
+			if _, err := checkPassword(hash, "wrong horse"); err == nil {
+				t.Error("checkPassword() of a wrong password = nil, want an error")
+			}
+
+			// Rotating the pepper invalidates the hashes made with the old one, and hashes made
+			// without a pepper don't verify once one is set.
+			PasswordPepper = "pepper-2"
+			if _, err := checkPassword(hash, "correct horse"); err == nil {
+				t.Error("checkPassword() after rotating the pepper = nil, want an error")
+			}
+			PasswordPepper = ""
+			if _, err := checkPassword(hash, "correct horse"); err == nil {
+				t.Error("checkPassword() of a peppered hash without the pepper = nil, want an error")
+			}
+		})
+	}
+
+	t.Run("long passwords stay distinct under bcrypt", func(t *testing.T) {
+		// bcrypt only reads the first 72 bytes; with a pepper the password is reduced to its HMAC
+		// first, so passwords that only differ after 72 bytes don't collide.
+		usePasswordSettings(t, HashBcrypt, "pepper-1")
+		prefix := string(make([]byte, 72))
+		hash := mustHashPassword(t, prefix+"a")
+		if _, err := checkPassword(hash, prefix+"b"); err == nil {
+			t.Error("checkPassword() of a password differing after 72 bytes = nil, want an error")
+		}
+	})
+}
+
+func TestHashPasswordBcryptCost(t *testing.T) {
+	usePasswordSettings(t, HashBcrypt, "")
+	hash := mustHashPassword(t, "correct horse")
+	if cost, err := bcrypt.Cost([]byte(hash)); err != nil || cost != bcrypt.DefaultCost {
+		t.Errorf("bcrypt.Cost() = %d, %v, want %d", cost, err, bcrypt.DefaultCost)
+	}
+}
+
+func TestCheckPasswordRehash(t *testing.T) {
+	usePasswordSettings(t, HashBcrypt, "")
+	bcryptHash := mustHashPassword(t, "correct horse")
+	PasswordHash = HashArgon2id
+	argon2Hash := mustHashPassword(t, "correct horse")
+
+	tests := []struct {
+		name      string
+		algorithm string
+		hash      string
+		rehash    bool
+	}{
+		{name: "bcrypt hash, argon2id configured", algorithm: HashArgon2id, hash: bcryptHash, rehash: true},
+		{name: "argon2id hash, argon2id configured", algorithm: HashArgon2id, hash: argon2Hash, rehash: false},
+		{name: "argon2id hash, bcrypt configured", algorithm: HashBcrypt, hash: argon2Hash, rehash: true},
+		{name: "bcrypt hash, bcrypt configured", algorithm: HashBcrypt, hash: bcryptHash, rehash: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PasswordHash = tt.algorithm
+			rehash, err := checkPassword(tt.hash, "correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rehash != tt.rehash {
+				t.Errorf("checkPassword() rehash = %v, want %v", rehash, tt.rehash)
+			}
+		})
+	}
+
+	t.Run("wrong password", func(t *testing.T) {
+		PasswordHash = HashArgon2id
+		if rehash, err := checkPassword(bcryptHash, "wrong horse"); err == nil || rehash {
+			t.Errorf("checkPassword() = %v, %v, want an error and no rehash", rehash, err)
+		}
+	})
+}
+
+func TestLoginUpgradesBcrypt(t *testing.T) {
+	usePasswordSettings(t, HashBcrypt, "")
+	bcryptUser := User{ID: "user-1", Email: "ada@example.com", Password: mustHashPassword(t, "correct horse")}
+	PasswordHash = HashArgon2id
+
+	dbtest.Run(t, "bcrypt user upgraded", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+		mt.AddMockResponses(dbtest.Cursor(mt, bcryptUser), dbtest.Value(mt, bcryptUser))
+		if _, _, err := svc.Login("ada@example.com", "correct horse", sessions.Device{}); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.NextCommand(mt, "find")
+		command := dbtest.NextCommand(mt, "findAndModify")
+		dbtest.ExpectField(mt, command, "query", bson.M{"_id": "user-1"})
+		upgraded, _ := command["update"].(bson.M)["$set"].(bson.M)["password"].(string)
+		if !strings.HasPrefix(upgraded, "$argon2id$") {
+			mt.Fatalf("stored password = %q, want an argon2id hash", upgraded)
+		}
+		if rehash, err := checkPassword(upgraded, "correct horse"); err != nil || rehash {
+			mt.Errorf("checkPassword() of the upgraded hash = %v, %v, want it to verify without another rehash", rehash, err)
+		}
+	})
+
+	dbtest.Run(t, "argon2id user left alone", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+		argon2User := bcryptUser
+		argon2User.Password = mustHashPassword(mt.T, "correct horse")
+		mt.AddMockResponses(dbtest.Cursor(mt, argon2User))
+		if _, _, err := svc.Login("ada@example.com", "correct horse", sessions.Device{}); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "wrong password not upgraded", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+		mt.AddMockResponses(dbtest.Cursor(mt, bcryptUser))
+		if _, _, err := svc.Login("ada@example.com", "wrong horse", sessions.Device{}); err == nil {
+			mt.Fatal("Login() with a wrong password = nil, want an error")
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
@@ -0,0 +1,41 @@
+package auth
+
+import "time"
+
+// `PasswordMaxAge` is how long a password may be used before it has to be changed. It is set from
+// `PASSWORD_MAX_AGE` at startup. When it is 0, passwords never expire.
+var PasswordMaxAge time.Duration
+
+// `ScopePasswordChange` is the `scope` claim of the tokens issued to users whose password has expired.
+// Such a token can only be used to change the password.
+const ScopePasswordChange = "password_change"
+
+// `passwordChangeTokenTTL` is how long a user whose password has expired has to change it with the
+// token they got when logging in.
+const passwordChangeTokenTTL = 15 * time.Minute
+
+// The function returns when the user's password was last set. Users that signed up before it was
+// recorded have had their password since they signed up.
+func (u *User) passwordSetAt() time.Time {
+	if u.PasswordChangedAt.IsZero() {
+		return u.CreatedAt
+	}
+	return u.PasswordChangedAt
+}
+
+// The function reports whether the user's password is older than `PasswordMaxAge` at `now`.
+func (u *User) passwordExpired(now time.Time) bool {
+	return PasswordMaxAge > 0 && now.Sub(u.passwordSetAt()) > PasswordMaxAge
+}
+
+// The function reports whether a token issued at `issuedAt` was issued before the user's password was
+// last set, so it must no longer be accepted. Tokens carry their issue time in whole seconds, so the
+// change time is truncated the same way, and the token issued by the change itself stays valid. Users
+// that signed up before the change time was recorded have never changed their password, so none of
+// their tokens predate a change.
+func (u *User) TokenPredatesPassword(issuedAt time.Time) bool {
+	if u.PasswordChangedAt.IsZero() {
+		return false
+	}
+	return issuedAt.Before(u.PasswordChangedAt.Truncate(time.Second))
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/sessions"
+	"sigmacoder/pkg/token"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestLoginPasswordExpiry(t *testing.T) {
+	defer func(maxAge time.Duration) { PasswordMaxAge = maxAge }(PasswordMaxAge)
+	keys := token.NewHS256("expiry-secret")
+	hashed := mustHashPassword(t, "correct horse")
+	now := time.Now().UTC()
+	tests := []struct {
+		name    string
+		maxAge  time.Duration
+		user    User
+		expired bool
+	}{
+		{name: "changed recently", maxAge: 90 * 24 * time.Hour, user: User{PasswordChangedAt: now.AddDate(0, 0, -10)}},
+		{name: "changed too long ago", maxAge: 90 * 24 * time.Hour, user: User{PasswordChangedAt: now.AddDate(0, 0, -100)}, expired: true},
+		{name: "never changed since an old sign up", maxAge: 90 * 24 * time.Hour, user: User{CreatedAt: now.AddDate(-1, 0, 0)}, expired: true},
+		{name: "never changed since a recent sign up", maxAge: 90 * 24 * time.Hour, user: User{CreatedAt: now.AddDate(0, 0, -1)}},
+		{name: "expiry disabled", user: User{PasswordChangedAt: now.AddDate(-5, 0, 0)}},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			PasswordMaxAge = tt.maxAge
+			user := tt.user
+			user.ID, user.Email, user.Password = "user-1", "ada@example.com", hashed
+			svc := NewAuthService(NewRepo(mt.DB).(*Repo), WithTokenKeys(keys))
+			mt.AddMockResponses(dbtest.Cursor(mt, user))
+			signed, expiresAt, err := svc.Login("ada@example.com", "correct horse", sessions.Device{})
+			if tt.expired != errors.Is(err, pkg.ErrPasswordExpired) || (!tt.expired && err != nil) {
+				mt.Fatalf("Login() = %v, want expired: %v", err, tt.expired)
+			}
+			parsed, err := keys.Parse(signed)
+			if err != nil {
+				mt.Fatalf("Parse() = %v, want a token either way", err)
+			}
+			scope, _ := parsed.Claims.(jwt.MapClaims)["scope"].(string)
+			if !tt.expired {
+				if scope != "" {
+					mt.Errorf("scope = %q, want an unrestricted token", scope)
+				}
+				return
+			}
+			if scope != ScopePasswordChange {
+				mt.Errorf("scope = %q, want %q", scope, ScopePasswordChange)
+			}
+			if lifetime := time.Until(expiresAt); lifetime > passwordChangeTokenTTL || lifetime < passwordChangeTokenTTL-time.Minute {
+				mt.Errorf("token expires in %s, want %s", lifetime, passwordChangeTokenTTL)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/sessions"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// `RevokeSessionsOnPasswordChange` is whether changing a password logs the user out everywhere, by
+// revoking every session issued before the change and rejecting every token issued before it. It is
+// set from `PASSWORD_CHANGE_REVOKES_SESSIONS` at startup.
+var RevokeSessionsOnPasswordChange = true
+
+// The `ChangePassword` function is a method of the `Svc` struct that implements the `ChangePassword`
+// method of the `Service` interface. It checks the current password, stores the hash of the new one
+// together with when it was changed and, unless `RevokeSessionsOnPasswordChange` is off, revokes every
+// session of the user. The change time is what rejects the tokens issued before it, including those
+// without a session. It returns a fresh token for the device that made the change, so it stays logged
+// in.
+func (s *Svc) ChangePassword(userID string, current string, next string, device sessions.Device) (string, error) {
+	if next == "" {
+		return "", pkg.ErrInvalidPassword
+	}
+	user, err := s.repo.ReadByID(userID)
+	if err != nil {
+		return "", pkg.ErrUserNotFound
+	}
+	if _, err := checkPassword(user.Password, current); err != nil {
+		return "", pkg.ErrInvalidCredentials
+	}
+	hash, err := hashPassword(next)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.repo.Update(userID, bson.M{"$set": bson.M{"password": hash, "passwordchangedat": pkg.NowUTC()}}); err != nil {
+		return "", err
+	}
+	if RevokeSessionsOnPasswordChange && s.sessions != nil {
+		if _, err := s.sessions.RevokeAll(userID); err != nil {
+			return "", err
+		}
+	}
+	s.audit(audit.EventPasswordChanged, user, device)
+	return s.issueToken(user, time.Hour*720, device)
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/sessions"
+	"sigmacoder/pkg/token"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestChangePassword(t *testing.T) {
+	keys := token.NewHS256("change-secret")
+	user := User{ID: "user-1", Email: "ada@example.com", Password: mustHashPassword(t, "correct horse")}
+
+	dbtest.Run(t, "old tokens predate the change", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo), WithTokenKeys(keys), WithSessions(sessions.NewRepo(mt.DB).(*sessions.Repo)))
+		mt.AddMockResponses(dbtest.Cursor(mt, user), dbtest.Value(mt, user), dbtest.Written(2), dbtest.Written(1))
+		before := time.Now().Add(-time.Second)
+		signed, err := svc.ChangePassword("user-1", "correct horse", "battery staple", sessions.Device{})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.NextCommand(mt, "find")
+		set := dbtest.NextCommand(mt, "findAndModify")["update"].(bson.M)["$set"].(bson.M)
+		stored, ok := set["passwordchangedat"].(primitive.DateTime)
+		changedAt := stored.Time()
+		if !ok || changedAt.Before(before) {
+			mt.Fatalf("$set = %v, want passwordchangedat set to the time of the change", set)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "update")["updates"].(bson.A)[0].(bson.M), "q", bson.M{"userid": "user-1", "revokedAt": bson.M{"$exists": false}})
+		dbtest.NextCommand(mt, "insert")
+
+		changed := user
+		changed.PasswordChangedAt = changedAt
+		if !changed.TokenPredatesPassword(before) {
+			mt.Errorf("TokenPredatesPassword() of a token issued before the change = false, want true")
+		}
+		parsed, err := keys.Parse(signed)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		issuedAt, ok := parsed.Claims.(jwt.MapClaims)["iat"].(float64)
+		if !ok {
+			mt.Fatalf("claims = %v, want an iat claim", parsed.Claims)
+		}
+		if changed.TokenPredatesPassword(time.Unix(int64(issuedAt), 0)) {
+			mt.Errorf("TokenPredatesPassword() of the token issued by the change = true, want it to stay valid")
+		}
+	})
+
+	dbtest.Run(t, "wrong current password", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo), WithTokenKeys(keys))
+		mt.AddMockResponses(dbtest.Cursor(mt, user))
+		if _, err := svc.ChangePassword("user-1", "wrong horse", "battery staple", sessions.Device{}); !errors.Is(err, pkg.ErrInvalidCredentials) {
+			mt.Fatalf("ChangePassword() = %v, want %v", err, pkg.ErrInvalidCredentials)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestTokenPredatesPassword(t *testing.T) {
+	changedAt := time.Date(2024, 3, 1, 12, 0, 0, int(700*time.Millisecond), time.UTC)
+	tests := []struct {
+		name      string
+		changedAt time.Time
+		issuedAt  time.Time
+		want      bool
+	}{
+		{name: "issued before the change", changedAt: changedAt, issuedAt: changedAt.Add(-time.Hour), want: true},
+		{name: "issued in the second of the change", changedAt: changedAt, issuedAt: changedAt.Truncate(time.Second)},
+		{name: "issued after the change", changedAt: changedAt, issuedAt: changedAt.Add(time.Minute)},
+		{name: "no issue time", changedAt: changedAt, issuedAt: time.Time{}, want: true},
+		{name: "password never changed", issuedAt: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := User{PasswordChangedAt: tt.changedAt}
+			if got := user.TokenPredatesPassword(tt.issuedAt); got != tt.want {
+				t.Errorf("TokenPredatesPassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
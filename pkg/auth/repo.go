@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"sigmacoder/pkg"
+	"sigmacoder/pkg/filter"
+	"sigmacoder/pkg/retry"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // Repository is an interfaces that defines the schema of
@@ -23,6 +26,10 @@ type Repository interface {
 	ReadByEmail(email string) (User, error)
 	ReadByPhoneNumber(phone string) (User, error)
 	ReadByUsernanme(username string) (User, error)
+	ReadByIdentifier(identifier string) (User, error)
+	ReadMany(ids []string) ([]User, error)
+	ReadByEmailChangeToken(tokenHash string) (User, error)
+	EnsureIndexes() error
 }
 
 // Repo is the struct that Implements the Repository Interface.
@@ -32,14 +39,76 @@ type Repo struct {
 	context context.Context
 }
 
+// The fields of stored users that the repository filters on. They must match the `bson` tags of
+// `User`.
+const (
+	fieldID               filter.Field = "_id"
+	fieldEmail            filter.Field = "email"
+	fieldPhoneNumber      filter.Field = "phone_number"
+	fieldUsername         filter.Field = "username"
+	fieldUserType         filter.Field = "usertype"
+	fieldEmailChangeToken filter.Field = "emailchangetoken"
+	fieldSolvedCount      filter.Field = "solvedcount"
+)
+
+// The function returns a filter matching the user with the given ID.
+func byID(id interface{}) bson.M {
+	return filter.New().Eq(fieldID, id).Build()
+}
+
+// `emailCollation` compares strings ignoring case, so "Ada@Example.com" and "ada@example.com" find
+// the same user.
+var emailCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// The names of the unique indexes on `email` and `username`.
+const (
+	emailIndex    = "email_unique"
+	usernameIndex = "username_unique"
+)
+
+// The function maps a duplicate key error to the sentinel error of the unique index that refused the
+// write, `pkg.ErrUsernameTaken` or `pkg.ErrEmailTaken`. Other errors are returned as they are.
+func duplicateKeyError(err error) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	if strings.Contains(err.Error(), usernameIndex) {
+		return pkg.ErrUsernameTaken
+	}
+	return pkg.ErrEmailTaken
+}
+
+// The `EnsureIndexes` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It creates a unique index on the non-empty emails with `emailCollation`, so two accounts
+// can't share an email in any case, and one on the non-empty usernames, so they can't share a username
+// either, even when they sign up at the same time. It fails when existing accounts already do, which
+// has to be resolved by hand.
+func (s *Repo) EnsureIndexes() error {
+	_, err := s.db.Indexes().CreateMany(s.context, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: string(fieldEmail), Value: 1}},
+			Options: options.Index().SetName(emailIndex).SetUnique(true).SetCollation(emailCollation).
+				SetPartialFilterExpression(bson.M{string(fieldEmail): bson.M{"$gt": ""}}),
+		},
+		{
+			Keys: bson.D{{Key: string(fieldUsername), Value: 1}},
+			Options: options.Index().SetName(usernameIndex).SetUnique(true).
+				SetPartialFilterExpression(bson.M{string(fieldUsername): bson.M{"$gt": ""}}),
+		},
+	})
+	return err
+}
+
 // This function is used to fetch a user from the database with their email. It takes in an email
 // string as a parameter and returns a User object and an error. It searches for a user in the database
 // with the given email using the FindOne method of the MongoDB collection. If a user is found, it
 // decodes the result into a User object and returns it. If no user is found, it returns an error
-// indicating that the user was not found.
+// indicating that the user was not found. The email is matched ignoring case.
 func (s *Repo) ReadByEmail(email string) (User, error) {
 	var user User
-	err := s.db.FindOne(s.context, bson.M{"email": email}).Decode(&user)
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, filter.New().Eq(fieldEmail, email).Build(), options.FindOne().SetCollation(emailCollation)).Decode(&user)
+	})
 	if err != nil {
 		return user, pkg.ErrUserNotFound
 	}
@@ -54,7 +123,9 @@ func (s *Repo) ReadByEmail(email string) (User, error) {
 
 func (s *Repo) ReadByPhoneNumber(phone string) (User, error) {
 	var user User
-	err := s.db.FindOne(s.context, bson.M{"phonenumber": phone}).Decode(&user)
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, filter.New().Eq(fieldPhoneNumber, phone).Build()).Decode(&user)
+	})
 	if err != nil {
 		return user, pkg.ErrUserNotFound
 	}
@@ -68,23 +139,38 @@ func (s *Repo) ReadByPhoneNumber(phone string) (User, error) {
 // indicating that the user was not found.
 func (s *Repo) ReadByUsernanme(username string) (User, error) {
 	var user User
-	err := s.db.FindOne(s.context, bson.M{"username": username}).Decode(&user)
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, filter.New().Eq(fieldUsername, username).Build()).Decode(&user)
+	})
 	if err != nil {
-		return user, errors.New("user not found with this email")
+		return user, pkg.ErrUserNotFound
 	}
 	return user, nil
 }
 
+// The `ReadByIdentifier` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the user a login identifier belongs to: identifiers containing "@" are looked
+// up as emails, everything else as usernames. Usernames can't contain "@" for this to be unambiguous.
+func (s *Repo) ReadByIdentifier(identifier string) (User, error) {
+	if strings.Contains(identifier, "@") {
+		return s.ReadByEmail(identifier)
+	}
+	return s.ReadByUsernanme(identifier)
+}
+
 // This function is used to fetch a user from the database with their ID. It takes in an ID string as a
-// parameter and returns a User object and an error. It converts the ID string to a MongoDB ObjectID
-// using the `primitive.ObjectIDFromHex` function, and then searches for a user in the database with
-// the given ID using the `FindOne` method of the MongoDB collection. If a user is found, it decodes
-// the result into a User object and returns it. If no user is found, it returns an error indicating
-// that the user was not found.
+// parameter and returns a User object and an error. User IDs are the UUID strings generated by
+// `ToUser`, stored as they are in `_id`, so the ID is matched as a string. If a user is found, it
+// decodes the result into a User object and returns it. If no user is found, it returns
+// `pkg.ErrUserNotFound`.
 func (s *Repo) ReadByID(id string) (User, error) {
-	oid, _ := primitive.ObjectIDFromHex(id)
 	var user User
-	err := s.db.FindOne(s.context, bson.M{"_id": oid}).Decode(&user)
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, byID(id)).Decode(&user)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return user, pkg.ErrUserNotFound
+	}
 	if err != nil {
 		return user, err
 	}
@@ -95,13 +181,20 @@ func (s *Repo) ReadByID(id string) (User, error) {
 // a struct that contains the necessary information to create a new user. It converts this `InUser`
 // object to a `User` object using the `ToUser()` method, and then inserts this `User` object into the
 // MongoDB collection using the `InsertOne()` method. If there is an error during the insertion, it
-// returns the error. Otherwise, it returns the newly created `User` object.
+// returns the error, `pkg.ErrEmailTaken` or `pkg.ErrUsernameTaken` when a unique index refused it.
+// Otherwise, it returns the newly created `User` object.
 func (s *Repo) Create(in InUser) (User, error) {
-	user := in.ToUser()
-	_, err := s.db.InsertOne(s.context, user)
+	user, err := in.ToUser()
 	if err != nil {
 		return user, err
 	}
+	err = retry.Default.Do(s.context, func() error {
+		_, err := s.db.InsertOne(s.context, user)
+		return err
+	})
+	if err != nil {
+		return user, duplicateKeyError(err)
+	}
 	return user, nil
 
 }
@@ -113,7 +206,9 @@ func (s *Repo) Create(in InUser) (User, error) {
 // it. If no user is found, it returns an error indicating that the user was not found.
 func (s *Repo) Read(id string) (User, error) {
 	var user User
-	err := s.db.FindOne(s.context, bson.M{"_id": id}).Decode(&user)
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, byID(id)).Decode(&user)
+	})
 	if err != nil {
 		return user, errors.New("user not found with this id")
 	}
@@ -127,8 +222,11 @@ func (s *Repo) Read(id string) (User, error) {
 // the update, it returns the error.
 func (s *Repo) Update(id string, upd map[string]interface{}) (User, error) {
 	var u User
-	if err := s.db.FindOneAndUpdate(s.context, bson.M{"_id": id}, upd).Decode(&u); err != nil {
-		return u, err
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOneAndUpdate(s.context, byID(id), upd).Decode(&u)
+	})
+	if err != nil {
+		return u, duplicateKeyError(err)
 	}
 	return u, nil
 }
@@ -136,16 +234,104 @@ func (s *Repo) Update(id string, upd map[string]interface{}) (User, error) {
 // `func (s *Repo) Delete(id int) bool` is a method of the `Repo` struct that implements the
 // `Repository` interface. It takes an `id` of type `int` as input and returns a `bool`.
 func (s *Repo) Delete(id int) bool {
-	delete, err := s.db.DeleteOne(s.context, bson.M{"_id": id})
+	var delete *mongo.DeleteResult
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		delete, err = s.db.DeleteOne(s.context, byID(id))
+		return err
+	})
 	if err != nil {
 		return false
 	}
 	return delete.DeletedCount == 1
 }
 
-// The function returns a new instance of a Repository interface implementation with a MongoDB database
-// connection.
-func NewRepo(db *mongo.Database) Repository {
+// The `ReadMany` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It returns the users with the given IDs in a single query. IDs that don't belong to a user are
+// skipped.
+func (s *Repo) ReadMany(ids []string) ([]User, error) {
+	users := []User{}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, filter.New().In(fieldID, ids).Build())
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &users)
+	})
+	return users, err
+}
+
+// The `ReadByEmailChangeToken` function is a method of the `Repo` struct that implements the
+// `Repository` interface. It returns the user with a pending email change whose token hashes to the
+// given value.
+func (s *Repo) ReadByEmailChangeToken(tokenHash string) (User, error) {
+	var user User
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, filter.New().Eq(fieldEmailChangeToken, tokenHash).Build()).Decode(&user)
+	})
+	if err != nil {
+		return user, pkg.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// The `SetSolvedCount` function is a method of the `Repo` struct. It stores the number of questions the
+// user has solved, and returns `pkg.ErrUserNotFound` when there is no such user.
+func (s *Repo) SetSolvedCount(id string, solved int64) error {
+	var result *mongo.UpdateResult
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		result, err = s.db.UpdateOne(s.context, byID(id), bson.M{"$set": bson.M{"solvedcount": solved}})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return pkg.ErrUserNotFound
+	}
+	return nil
+}
+
+// The `SetSolvedCounts` function is a method of the `Repo` struct. It stores the solve counts of every
+// user at once: users in `counts` get their count, everyone else gets 0. It returns the number of
+// users whose stored count changed.
+func (s *Repo) SetSolvedCounts(counts map[string]int64) (int64, error) {
+	ids := make([]string, 0, len(counts))
+	models := make([]mongo.WriteModel, 0, len(counts)+1)
+	for id, solved := range counts {
+		ids = append(ids, id)
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(filter.New().Eq(fieldID, id).Ne(fieldSolvedCount, solved).Build()).
+			SetUpdate(bson.M{"$set": bson.M{"solvedcount": solved}}))
+	}
+	models = append(models, mongo.NewUpdateManyModel().
+		SetFilter(filter.New().Nin(fieldID, ids).Ne(fieldSolvedCount, 0).Build()).
+		SetUpdate(bson.M{"$set": bson.M{"solvedcount": 0}}))
+	var result *mongo.BulkWriteResult
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		result, err = s.db.BulkWrite(s.context, models, options.BulkWrite().SetOrdered(false))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation with a MongoDB
+// database connection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
 	ctx := context.TODO()
-	return &Repo{db: db.Collection("users"), context: ctx}
+	return &Repo{db: db.Collection("users", opts...), context: ctx}
 }
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/filter"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCreateThenReadByID(t *testing.T) {
+	dbtest.Run(t, "found by the generated ID", func(mt *mtest.T) {
+		repo := NewRepo(mt.DB).(*Repo)
+		mt.AddMockResponses(dbtest.Written(1))
+		created, err := repo.Create(InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", Password: "correct horse"})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if _, err := uuid.Parse(created.ID); err != nil {
+			mt.Errorf("ID = %q, want a UUID", created.ID)
+		}
+		stored := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+		if stored["_id"] != created.ID {
+			mt.Fatalf("stored _id = %#v, want the ID as a string, %q", stored["_id"], created.ID)
+		}
+
+		// The mocked deployment answers with the stored document, as a real one would for the filter.
+		mt.AddMockResponses(dbtest.Cursor(mt, stored))
+		found, err := repo.ReadByID(created.ID)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if found.ID != created.ID || found.Email != created.Email {
+			mt.Errorf("ReadByID() = %+v, want the created user", found)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": created.ID})
+	})
+
+	dbtest.Run(t, "unknown ID", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if _, err := NewRepo(mt.DB).(*Repo).ReadByID(uuid.NewString()); !errors.Is(err, pkg.ErrUserNotFound) {
+			mt.Errorf("ReadByID() = %v, want %v", err, pkg.ErrUserNotFound)
+		}
+	})
+}
+
+func TestStoredKeys(t *testing.T) {
+	t.Run("every field tagged", func(t *testing.T) {
+		fields := reflect.TypeOf(User{})
+		for i := 0; i < fields.NumField(); i++ {
+			field := fields.Field(i)
+			if key, _, _ := strings.Cut(field.Tag.Get("bson"), ","); key == "" {
+				t.Errorf("User.%s has no bson key", field.Name)
+			}
+		}
+	})
+
+	t.Run("queried keys are stored", func(t *testing.T) {
+		scheduled := time.Now()
+		data, err := bson.Marshal(User{
+			ID: "user-1", Email: "ada@example.com", PhoneNumber: "+14155552671", Username: "ada", UserType: "user",
+			EmailChangeToken: "hash", SolvedCount: 1, DeletionScheduledAt: &scheduled,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var stored bson.M
+		if err := bson.Unmarshal(data, &stored); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range []filter.Field{fieldID, fieldEmail, fieldPhoneNumber, fieldUsername, fieldUserType, fieldEmailChangeToken, fieldSolvedCount} {
+			if _, ok := stored[string(field)]; !ok {
+				t.Errorf("queries use %q, which a stored user doesn't have", field)
+			}
+		}
+	})
+
+	in := InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", PhoneNumber: "+14155552671", Password: "correct horse"}
+	for _, tt := range []struct {
+		name string
+		read func(repo *Repo, created User) (User, error)
+	}{
+		{name: "by email", read: func(repo *Repo, created User) (User, error) { return repo.ReadByEmail(created.Email) }},
+		{name: "by phone number", read: func(repo *Repo, created User) (User, error) { return repo.ReadByPhoneNumber(created.PhoneNumber) }},
+		{name: "by username", read: func(repo *Repo, created User) (User, error) { return repo.ReadByUsernanme(created.Username) }},
+		{name: "by id", read: func(repo *Repo, created User) (User, error) { return repo.ReadByID(created.ID) }},
+	} {
+		dbtest.Run(t, "created user found "+tt.name, func(mt *mtest.T) {
+			repo := NewRepo(mt.DB).(*Repo)
+			mt.AddMockResponses(dbtest.Written(1))
+			created, err := repo.Create(in)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			stored := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+
+			mt.AddMockResponses(dbtest.Cursor(mt, stored))
+			found, err := tt.read(repo, created)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if found.ID != created.ID || found.PhoneNumber != created.PhoneNumber || found.Username != created.Username {
+				mt.Errorf("read %+v, want the created user", found)
+			}
+			// The filter must name a key of the stored document with the value stored under it, or it
+			// would match nothing on a real deployment.
+			for key, value := range dbtest.NextCommand(mt, "find")["filter"].(bson.M) {
+				if !reflect.DeepEqual(stored[key], value) {
+					mt.Errorf("filter %s = %#v, want the stored value %#v", key, value, stored[key])
+				}
+			}
+		})
+	}
+}
+
+func TestEnsureIndexes(t *testing.T) {
+	dbtest.Run(t, "unique emails and usernames", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		if err := NewRepo(mt.DB).(*Repo).EnsureIndexes(); err != nil {
+			mt.Fatal(err)
+		}
+		indexes := dbtest.NextCommand(mt, "createIndexes")["indexes"].(bson.A)
+		email := indexes[0].(bson.M)
+		if email["name"] != emailIndex || email["unique"] != true {
+			mt.Errorf("created %v, want the unique email index", email)
+		}
+		dbtest.ExpectField(mt, email, "key", bson.M{"email": 1})
+		dbtest.ExpectField(mt, email, "collation", bson.M{"locale": "en", "strength": 2})
+		dbtest.ExpectField(mt, email, "partialFilterExpression", bson.M{"email": bson.M{"$gt": ""}})
+		username := indexes[1].(bson.M)
+		if username["name"] != usernameIndex || username["unique"] != true {
+			mt.Errorf("created %v, want the unique username index", username)
+		}
+		dbtest.ExpectField(mt, username, "key", bson.M{"username": 1})
+		dbtest.ExpectField(mt, username, "partialFilterExpression", bson.M{"username": bson.M{"$gt": ""}})
+	})
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		index string
+		want  error
+	}{
+		{index: emailIndex, want: pkg.ErrEmailTaken},
+		{index: usernameIndex, want: pkg.ErrUsernameTaken},
+	}
+	for _, tt := range tests {
+		message := "E11000 duplicate key error collection: sigmacoder.users index: " + tt.index
+		dbtest.Run(t, "create refused by "+tt.index, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: message}))
+			if _, err := NewRepo(mt.DB).(*Repo).Create(InUser{Name: "Ada Lovelace", Email: "Ada@example.com", Username: "ada", Password: "correct horse"}); !errors.Is(err, tt.want) {
+				mt.Errorf("Create() = %v, want %v", err, tt.want)
+			}
+		})
+		dbtest.Run(t, "update refused by "+tt.index, func(mt *mtest.T) {
+			mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Message: message}))
+			if _, err := NewRepo(mt.DB).(*Repo).Update("user-1", bson.M{"$set": bson.M{"username": "ada"}}); !errors.Is(err, tt.want) {
+				mt.Errorf("Update() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
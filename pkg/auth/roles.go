@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/filter"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The roles a user can have, stored in `UserType`.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// `Roles` lists the roles admins can assign. `DEFAULT_USER_TYPE` is added at startup when it is
+// another one, so every user can be given back the role they signed up with.
+var Roles = []string{RoleUser, RoleModerator, RoleAdmin}
+
+// The function reports whether the role is one of `Roles`.
+func ValidRole(role string) bool {
+	for _, known := range Roles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
+
+// The `SetRoles` function is a method of the `Repo` struct. It gives every user in `ids` the role, in
+// a single transaction, and returns the IDs of the users whose role changed. IDs that don't belong to a
+// user, and users that already have the role, are skipped. Demoting every remaining admin is refused
+// with `pkg.ErrLastAdmin`, and a role that isn't one of `Roles` with `pkg.ErrInvalidRole`.
+func (s *Repo) SetRoles(ids []string, role string) ([]string, error) {
+	if !ValidRole(role) {
+		return nil, pkg.ErrInvalidRole
+	}
+	session, err := s.db.Database().Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(s.context)
+	changed, err := session.WithTransaction(s.context, func(sc mongo.SessionContext) (interface{}, error) {
+		if role != RoleAdmin {
+			demoted, err := s.db.CountDocuments(sc, filter.New().In(fieldID, ids).Eq(fieldUserType, RoleAdmin).Build())
+			if err != nil {
+				return nil, err
+			}
+			if demoted > 0 {
+				admins, err := s.db.CountDocuments(sc, filter.New().Eq(fieldUserType, RoleAdmin).Build())
+				if err != nil {
+					return nil, err
+				}
+				if admins-demoted < 1 {
+					return nil, pkg.ErrLastAdmin
+				}
+			}
+		}
+		matching := filter.New().In(fieldID, ids).Ne(fieldUserType, role).Build()
+		var users []struct {
+			ID string `bson:"_id"`
+		}
+		cursor, err := s.db.Find(sc, matching, options.Find().SetProjection(bson.M{string(fieldID): 1}))
+		if err != nil {
+			return nil, err
+		}
+		if err := cursor.All(sc, &users); err != nil {
+			return nil, err
+		}
+		changed := make([]string, 0, len(users))
+		for _, user := range users {
+			changed = append(changed, user.ID)
+		}
+		if len(changed) == 0 {
+			return changed, nil
+		}
+		_, err = s.db.UpdateMany(sc, filter.New().In(fieldID, changed).Build(), bson.M{"$set": bson.M{string(fieldUserType): role}})
+		return changed, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed.([]string), nil
+}
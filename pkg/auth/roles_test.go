@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns the response to a `CountDocuments` call that counted `n` documents.
+func counted(n int) bson.D {
+	return mtest.CreateCursorResponse(0, dbtest.Namespace, mtest.FirstBatch, bson.D{{Key: "n", Value: n}})
+}
+
+func TestValidRole(t *testing.T) {
+	for role, want := range map[string]bool{RoleUser: true, RoleModerator: true, RoleAdmin: true, "Moderator": false, "owner": false, "": false} {
+		if got := ValidRole(role); got != want {
+			t.Errorf("ValidRole(%q) = %v, want %v", role, got, want)
+		}
+	}
+}
+
+func TestSetRoles(t *testing.T) {
+	ids := []string{"user-1", "user-2", "user-3"}
+
+	dbtest.Run(t, "bulk promotion", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			dbtest.Cursor(mt),
+			dbtest.Cursor(mt, bson.M{"_id": "user-1"}, bson.M{"_id": "user-3"}),
+			dbtest.Written(2),
+			mtest.CreateSuccessResponse(),
+		)
+		changed, err := NewRepo(mt.DB).(*Repo).SetRoles(ids, RoleModerator)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if want := []string{"user-1", "user-3"}; !reflect.DeepEqual(changed, want) {
+			mt.Errorf("SetRoles() = %v, want %v", changed, want)
+		}
+		pipeline := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)
+		dbtest.ExpectField(mt, pipeline[0].(bson.M), "$match", bson.M{"_id": bson.M{"$in": ids}, "usertype": RoleAdmin})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": bson.M{"$in": ids}, "usertype": bson.M{"$ne": RoleModerator}})
+		update := dbtest.NextCommand(mt, "update")["updates"].(bson.A)[0].(bson.M)
+		dbtest.ExpectField(mt, update, "q", bson.M{"_id": bson.M{"$in": changed}})
+		dbtest.ExpectField(mt, update, "u", bson.M{"$set": bson.M{"usertype": RoleModerator}})
+		dbtest.NextCommand(mt, "commitTransaction")
+	})
+
+	dbtest.Run(t, "promotion to admin skips the guard", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": "user-2"}), dbtest.Written(1), mtest.CreateSuccessResponse())
+		if _, err := NewRepo(mt.DB).(*Repo).SetRoles(ids, RoleAdmin); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "update")
+		dbtest.NextCommand(mt, "commitTransaction")
+	})
+
+	dbtest.Run(t, "everyone already has the role", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt), mtest.CreateSuccessResponse())
+		changed, err := NewRepo(mt.DB).(*Repo).SetRoles(ids, RoleUser)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if changed == nil || len(changed) != 0 {
+			mt.Errorf("SetRoles() = %#v, want no change", changed)
+		}
+		dbtest.NextCommand(mt, "aggregate")
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "commitTransaction")
+	})
+
+	dbtest.Run(t, "invalid role", func(mt *mtest.T) {
+		for _, role := range []string{"owner", "Admin", ""} {
+			if _, err := NewRepo(mt.DB).(*Repo).SetRoles(ids, role); !errors.Is(err, pkg.ErrInvalidRole) {
+				mt.Errorf("SetRoles(%q) = %v, want %v", role, err, pkg.ErrInvalidRole)
+			}
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "last admin", func(mt *mtest.T) {
+		mt.AddMockResponses(counted(2), counted(2), mtest.CreateSuccessResponse())
+		if _, err := NewRepo(mt.DB).(*Repo).SetRoles(ids, RoleUser); !errors.Is(err, pkg.ErrLastAdmin) {
+			mt.Errorf("SetRoles() = %v, want %v", err, pkg.ErrLastAdmin)
+		}
+		dbtest.NextCommand(mt, "aggregate")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)[0].(bson.M), "$match", bson.M{"usertype": RoleAdmin})
+		dbtest.NextCommand(mt, "abortTransaction")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "demoting some admins", func(mt *mtest.T) {
+		mt.AddMockResponses(counted(1), counted(3), dbtest.Cursor(mt, bson.M{"_id": "user-1"}), dbtest.Written(1), mtest.CreateSuccessResponse())
+		changed, err := NewRepo(mt.DB).(*Repo).SetRoles(ids, RoleUser)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(changed) != 1 || changed[0] != "user-1" {
+			mt.Errorf("SetRoles() = %v, want user-1 demoted", changed)
+		}
+	})
+}
@@ -1,20 +1,27 @@
 package auth
 
 import (
-	"errors"
+	"context"
 	"os"
 	"sigmacoder/pkg"
+	"sigmacoder/pkg/audit"
+	"sigmacoder/pkg/logging"
+	"sigmacoder/pkg/mail"
+	"sigmacoder/pkg/sessions"
+	"sigmacoder/pkg/token"
+	"sigmacoder/pkg/webhook"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
-	"golang.org/x/crypto/bcrypt"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 // The above type defines a service interface with methods for login, phone OTP login, and user sign
 // up.
-// @property Login - The Login method takes an email and password as parameters and returns a string
-// (presumably a token or session ID) and an error. It is used to authenticate a user with their email
-// and password.
+// @property Login - The Login method takes an identifier, either an email or a username, and a password
+// as parameters and returns a string (presumably a token or session ID) and an error. It is used to
+// authenticate a user with their email or username and password.
 // @property LoginPhoneOtp - This method is used to log in a user using their phone number and a
 // one-time password (OTP). It takes the phone number as input and returns a token string and an error
 // if any.
@@ -22,100 +29,319 @@ import (
 // parameter of type InUser, which represents the user information such as email, password, and phone
 // number. It returns a string representing the user ID and an error if any error occurs during the
 // signup process.
+// @property ResumeSignUp - ResumeSignUp issues a fresh token for an account a retried sign up already
+// created, once the password is checked.
+// @property RequestEmailChange - RequestEmailChange starts switching a user's email to a new address by
+// sending a verification link to it.
+// @property ConfirmEmailChange - ConfirmEmailChange completes an email change from the token in the
+// verification link.
+// @property ScheduleDeletion - ScheduleDeletion schedules a user's account to be purged after the
+// deletion grace period.
+// @property CancelDeletion - CancelDeletion keeps an account whose deletion was scheduled.
+// @property ChangePassword - ChangePassword replaces a user's password after checking the current one,
+// and returns a fresh token.
+// @property SetTimezone - SetTimezone stores the timezone a user's calendar days are counted in.
+// @property WithContext - WithContext returns a copy of the service whose repository calls run with the
+// given context, typically the request's `c.UserContext()`.
 type Service interface {
-	Login(email string, password string) (string, time.Time, error)
-	LoginPhoneOtp(phone string) (string, error)
-	SignUp(in InUser) (string, error)
+	Login(identifier string, password string, device sessions.Device) (string, time.Time, error)
+	LoginPhoneOtp(phone string, device sessions.Device) (string, error)
+	SignUp(in InUser, device sessions.Device) (string, error)
+	ResumeSignUp(userID string, password string, device sessions.Device) (string, error)
+	RequestEmailChange(userID string, newEmail string) error
+	ConfirmEmailChange(token string) (User, error)
+	ScheduleDeletion(userID string, device sessions.Device) (time.Time, error)
+	CancelDeletion(userID string, device sessions.Device) error
+	ChangePassword(userID string, current string, next string, device sessions.Device) (string, error)
+	SetTimezone(userID string, timezone string) (User, error)
+	WithContext(ctx context.Context) Service
 }
 
 // The type Svc represents a service that has a dependency on a Repo.
 // @property repo - The `repo` property is a pointer to an instance of the `Repo` struct. It is used to
 // access and manipulate data in the repository.
+// @property sessions - The session store every issued token is recorded in, so it can be listed and
+// revoked. When it is nil, tokens are issued without a session.
+// @property signupWebhook - The webhook notified of every successful sign up. When it is nil, no
+// notification is sent.
+// @property auditLog - The audit log sign ups and logins are recorded in. When it is nil, nothing is
+// recorded.
+// @property mailer - The sender of the emails the service sends, such as email change verification
+// links. It defaults to logging them.
+// @property {string} baseURL - The public URL of the API, used to build the links in those emails.
+// @property tokens - The keys issued tokens are signed with. They default to HS256 with `JWT_SECRET`.
+// @property welcomeEmail - Reports whether new users are sent a welcome email. When it is nil, none is
+// sent.
+// @property {string} defaultUserType - The `UserType` every new user gets. It defaults to "user".
 type Svc struct {
-	repo *Repo
+	repo          *Repo
+	sessions      *sessions.Repo
+	signupWebhook *webhook.Notifier
+	auditLog      *audit.Repo
+	mailer        mail.Sender
+	baseURL       string
+	tokens        *token.Keys
+	welcomeEmail  func() bool
+
+	defaultUserType string
+}
+
+// The SignupEvent type is the payload sent to the signup webhook.
+// @property {string} Event - Always "user.signup".
+// @property {string} UserID - The ID of the new user.
+// @property {string} Email - The email address of the new user.
+// @property CreatedAt - When the user signed up.
+type SignupEvent struct {
+	Event     string    `json:"event"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// The Option type configures optional dependencies of the auth service.
+type Option func(*Svc)
+
+// The function returns an Option that records a session for every token the service issues.
+func WithSessions(store *sessions.Repo) Option {
+	return func(s *Svc) {
+		s.sessions = store
+	}
+}
+
+// The function returns an Option that notifies the given webhook of every successful sign up.
+func WithSignupWebhook(notifier *webhook.Notifier) Option {
+	return func(s *Svc) {
+		s.signupWebhook = notifier
+	}
+}
+
+// The function returns an Option that sends the service's emails with the given sender. `baseURL` is
+// the public URL of the API that links in the emails point to.
+func WithMailer(sender mail.Sender, baseURL string) Option {
+	return func(s *Svc) {
+		s.mailer = sender
+		s.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// The function returns an Option that sends new users a welcome email through the service's mailer
+// whenever `enabled` reports true at the time they sign up, so it can be switched at runtime.
+func WithWelcomeEmail(enabled func() bool) Option {
+	return func(s *Svc) {
+		s.welcomeEmail = enabled
+	}
+}
+
+// The function returns an Option that gives every new user the given `UserType` instead of "user".
+func WithDefaultUserType(userType string) Option {
+	return func(s *Svc) {
+		s.defaultUserType = userType
+	}
+}
+
+// The function returns an Option that signs the issued tokens with the given keys.
+func WithTokenKeys(keys *token.Keys) Option {
+	return func(s *Svc) {
+		s.tokens = keys
+	}
+}
+
+// The function returns an Option that records sign ups and logins in the given audit log.
+func WithAudit(log *audit.Repo) Option {
+	return func(s *Svc) {
+		s.auditLog = log
+	}
+}
+
+// The function records an event in the audit log, if one is configured. A failure to record is logged
+// but doesn't fail the operation that is being audited.
+func (s *Svc) audit(eventType string, user User, device sessions.Device) {
+	if s.auditLog == nil {
+		return
+	}
+	event := audit.Event{Type: eventType, UserID: user.ID, IP: device.IP}
+	if err := s.auditLog.Record(event); err != nil {
+		logging.Errorf("audit: could not record %s: %v", eventType, err)
+	}
+}
+
+// The function signs a token for the user that is valid for `ttl`. When a session store is configured,
+// it first records a session for the device and embeds its ID in the `sid` claim. The `iat` claim
+// records when the token was issued, so tokens issued before a password change can be rejected.
+func (s *Svc) issueToken(user User, ttl time.Duration, device sessions.Device) (string, error) {
+	return s.issueScopedToken(user, ttl, device, "")
 }
 
+// The function signs a token like `issueToken`, restricted to what `scope` allows. An empty scope
+// issues an unrestricted token.
+func (s *Svc) issueScopedToken(user User, ttl time.Duration, device sessions.Device, scope string) (string, error) {
+	issuedAt := pkg.NowUTC()
+	expiresAt := issuedAt.Add(ttl)
+	claims := jwt.MapClaims{
+		"userid": user.ID,
+		"email":  user.Email,
+		"iat":    issuedAt.Unix(),
+		"exp":    expiresAt.Unix(),
+	}
+	if scope != "" {
+		claims["scope"] = scope
+	}
+	if s.sessions != nil {
+		session, err := s.sessions.Create(user.ID, device, expiresAt)
+		if err != nil {
+			return "", err
+		}
+		claims["sid"] = session.ID
+	}
+	return s.tokens.Sign(claims)
+}
 
 // The `SignUp` function is a method of the `Svc` struct that implements the `SignUp` method of the
-// `Service` interface. It is responsible for handling user sign up functionality.
-func (s *Svc) SignUp(in InUser) (string, error) {
-	user, err := s.repo.ReadByEmail(in.Email)
-	if !(err == pkg.ErrUserNotFound) && err != nil {
+// `Service` interface. It is responsible for handling user sign up functionality. Any `UserType` in
+// the request is replaced with the service's default, so clients can't make themselves admins. New
+// users are sent a welcome email when that is turned on. An email that already has an account, in any
+// case, yields `pkg.ErrEmailTaken`, and a username that already has one `pkg.ErrUsernameTaken`.
+func (s *Svc) SignUp(in InUser, device sessions.Device) (string, error) {
+	in.Normalize()
+	in.UserType = s.defaultUserType
+	// Emails are matched ignoring case, so any user found has the email, whatever its case. The unique
+	// indexes refuse the insert of a concurrent sign up that got past these checks.
+	_, err := s.repo.ReadByEmail(in.Email)
+	if err == nil {
+		return "", pkg.ErrEmailTaken
+	}
+	if err != pkg.ErrUserNotFound {
 		return "", err
 	}
-	if user.Email == in.Email {
-		return "", errors.New("user with id already exists")
+	if in.Username != "" {
+		_, err = s.repo.ReadByUsernanme(in.Username)
+		if err == nil {
+			return "", pkg.ErrUsernameTaken
+		}
+		if err != pkg.ErrUserNotFound {
+			return "", err
+		}
 	}
 	create, err := s.repo.Create(in)
 	if err != nil {
 		return "", err
 	}
-	claims := jwt.MapClaims{
-		"userid": create.ID,
-		"email":  create.Email,
-		"exp":    time.Now().Add(time.Hour * 72).Unix(),
-	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	refresh, err := refreshToken.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	s.signupWebhook.Send(SignupEvent{Event: "user.signup", UserID: create.ID, Email: create.Email, CreatedAt: create.CreatedAt})
+	s.audit(audit.EventSignup, create, device)
+	s.sendWelcome(create)
+	return s.issueToken(create, time.Hour*72, device)
+}
+
+// The `ResumeSignUp` function is a method of the `Svc` struct that implements the `ResumeSignUp` method
+// of the `Service` interface. It answers a retried sign up with a fresh token for the account the first
+// attempt created, so the token issued to that attempt never has to be stored or handed out again. The
+// password of the retry is checked against the account first, yielding `pkg.ErrInvalidCredentials`
+// when it doesn't match. Like `Login`, it refuses accounts whose deletion grace period has run out.
+func (s *Svc) ResumeSignUp(userID string, password string, device sessions.Device) (string, error) {
+	user, err := s.repo.ReadByID(userID)
 	if err != nil {
 		return "", err
 	}
-	return refresh , nil
+	if user.deletionDue(time.Now()) {
+		return "", pkg.ErrUserNotFound
+	}
+	if _, err := checkPassword(user.Password, password); err != nil {
+		return "", pkg.ErrInvalidCredentials
+	}
+	return s.issueToken(user, time.Hour*72, device)
 }
 
-
 // The `Login` function is a method of the `Svc` struct that implements the `Login` method of the
-// `Service` interface. It takes an `email` and `password` as input parameters and returns a string and
-// an error.
-func (s *Svc) Login(email string, password string) (string,  time.Time, error) {
-	user, err := s.repo.ReadByEmail(email)
+// `Service` interface. It takes an `identifier` and `password` as input parameters and returns a string
+// and an error. The identifier is looked up as an email when it contains "@" and as a username
+// otherwise. Accounts whose deletion grace period has run out can no longer log in, even before they
+// are purged. A password still hashed with an outdated algorithm is re-hashed with the current one
+// after it was checked, so users migrate without having to reset their passwords. When the password is
+// older than `PasswordMaxAge`, it returns `pkg.ErrPasswordExpired` together with a short-lived token
+// that can only be used to change the password.
+func (s *Svc) Login(identifier string, password string, device sessions.Device) (string, time.Time, error) {
+	user, err := s.repo.ReadByIdentifier(identifier)
 	if err != nil {
 		return "", time.Time{}, err
 	}
-	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", time.Time{}, err
+	if user.deletionDue(time.Now()) {
+		return "", time.Time{}, pkg.ErrUserNotFound
 	}
-	claims := jwt.MapClaims{
-		"userid": user.ID,
-		"email":  user.Email,
-		"exp":    time.Now().Add(time.Hour * 720).Unix(),
+	rehash, err := checkPassword(user.Password, password)
+	if err != nil {
+		return "", time.Time{}, pkg.ErrInvalidCredentials
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	refresh, err := refreshToken.SignedString([]byte(os.Getenv("JWT_SECRET")))
-	expirationTime := time.Now().Add(time.Hour * 168)
+	if rehash {
+		hash, err := hashPassword(password)
+		if err == nil {
+			_, err = s.repo.Update(user.ID, bson.M{"$set": bson.M{"password": hash}})
+		}
+		if err != nil {
+			logging.Warnf("auth: could not upgrade the password hash of %s: %v", user.ID, err)
+		}
+	}
+	if user.passwordExpired(pkg.NowUTC()) {
+		scoped, err := s.issueScopedToken(user, passwordChangeTokenTTL, device, ScopePasswordChange)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		return scoped, pkg.NowUTC().Add(passwordChangeTokenTTL), pkg.ErrPasswordExpired
+	}
+	refresh, err := s.issueToken(user, time.Hour*720, device)
+	expirationTime := pkg.NowUTC().Add(time.Hour * 168)
 	if err != nil {
 		return "", time.Time{}, err
 	}
+	s.audit(audit.EventLogin, user, device)
 	return refresh, expirationTime, nil
 
 }
 
-
 // The `LoginPhoneOtp` function is a method of the `Svc` struct that implements the `LoginPhoneOtp`
 // method of the `Service` interface. It takes a `phone` number as an input parameter and returns a
-// string and an error.
-func (s *Svc) LoginPhoneOtp(phone string) (string, error) {
+// string and an error. Like `Login`, it refuses accounts whose deletion grace period has run out.
+func (s *Svc) LoginPhoneOtp(phone string, device sessions.Device) (string, error) {
 	user, err := s.repo.ReadByPhoneNumber(phone)
 	if err != nil {
 		return "", err
 	}
-	claims := jwt.MapClaims{
-		"userid": user.ID,
-		"email":  user.Email,
-		"exp":    time.Now().Add(time.Hour * 72).Unix(),
+	if user.deletionDue(time.Now()) {
+		return "", pkg.ErrUserNotFound
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	refresh, err := refreshToken.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	token, err := s.issueToken(user, time.Hour*72, device)
 	if err != nil {
 		return "", err
 	}
-	return refresh, nil
+	s.audit(audit.EventOTPLogin, user, device)
+	return token, nil
+}
 
+// The `WithContext` function is a method of the `Svc` struct that implements the `WithContext` method
+// of the `Service` interface. It returns a copy of the service bound to the given context.
+func (s *Svc) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.repo = s.repo.WithContext(ctx)
+	if s.sessions != nil {
+		clone.sessions = s.sessions.WithContext(ctx)
+	}
+	if s.auditLog != nil {
+		clone.auditLog = s.auditLog.WithContext(ctx)
+	}
+	return &clone
 }
 
-// The function creates a new instance of a service with a given repository.
-func NewAuthService(repo *Repo) Service {
-	return &Svc{
-		repo: repo,
+// The function creates a new instance of a service with a given repository, configured by the given
+// options.
+func NewAuthService(repo *Repo, opts ...Option) Service {
+	svc := &Svc{
+		repo:   repo,
+		mailer: mail.LogSender{},
+		tokens: token.NewHS256(os.Getenv("JWT_SECRET")),
+
+		defaultUserType: "user",
+	}
+	for _, opt := range opts {
+		opt(svc)
 	}
+	return svc
 }
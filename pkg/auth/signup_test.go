@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"sigmacoder/pkg/sessions"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSignUpUserType(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		requested string
+		want      string
+	}{
+		{name: "admin requested", requested: "admin", want: "user"},
+		{name: "nothing requested", want: "user"},
+		{name: "configured default", opts: []Option{WithDefaultUserType("member")}, requested: "admin", want: "member"},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			svc := NewAuthService(NewRepo(mt.DB).(*Repo), tt.opts...)
+			mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt), dbtest.Written(1))
+			in := InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", Password: "correct horse", UserType: tt.requested}
+			if _, err := svc.SignUp(in, sessions.Device{}); err != nil {
+				mt.Fatal(err)
+			}
+			dbtest.NextCommand(mt, "find")
+			dbtest.NextCommand(mt, "find")
+			stored := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+			if stored["usertype"] != tt.want {
+				mt.Errorf("stored usertype = %v, want %q", stored["usertype"], tt.want)
+			}
+		})
+	}
+}
+
+func TestSignUpEmailTaken(t *testing.T) {
+	// The stored email is matched ignoring case, so it may differ in case from the requested one.
+	for _, stored := range []string{"ada@example.com", "Ada@Example.com"} {
+		dbtest.Run(t, "existing email "+stored, func(mt *mtest.T) {
+			svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+			mt.AddMockResponses(dbtest.Cursor(mt, User{ID: "user-1", Email: stored}))
+			in := InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", Password: "correct horse"}
+			if _, err := svc.SignUp(in, sessions.Device{}); !errors.Is(err, pkg.ErrEmailTaken) {
+				mt.Errorf("SignUp() = %v, want %v", err, pkg.ErrEmailTaken)
+			}
+			dbtest.NextCommand(mt, "find")
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+
+	dbtest.Run(t, "concurrent sign up", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt), mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error index: email_unique"}))
+		in := InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", Password: "correct horse"}
+		if _, err := svc.SignUp(in, sessions.Device{}); !errors.Is(err, pkg.ErrEmailTaken) {
+			mt.Errorf("SignUp() = %v, want %v", err, pkg.ErrEmailTaken)
+		}
+	})
+}
+
+func TestSignUpUsernameTaken(t *testing.T) {
+	dbtest.Run(t, "existing username", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt, User{ID: "user-1", Email: "grace@example.com", Username: "ada"}))
+		in := InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", Password: "correct horse"}
+		if _, err := svc.SignUp(in, sessions.Device{}); !errors.Is(err, pkg.ErrUsernameTaken) {
+			mt.Errorf("SignUp() = %v, want %v", err, pkg.ErrUsernameTaken)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"username": "ada"})
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "no username", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Written(1))
+		in := InUser{Name: "Ada Lovelace", Email: "ada@example.com", Password: "correct horse"}
+		if _, err := svc.SignUp(in, sessions.Device{}); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "insert")
+	})
+
+	dbtest.Run(t, "concurrent sign up", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt), mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error index: username_unique"}))
+		in := InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", Password: "correct horse"}
+		if _, err := svc.SignUp(in, sessions.Device{}); !errors.Is(err, pkg.ErrUsernameTaken) {
+			mt.Errorf("SignUp() = %v, want %v", err, pkg.ErrUsernameTaken)
+		}
+	})
+}
+
+func TestResumeSignUp(t *testing.T) {
+	in := InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", Password: "correct horse"}
+	ada, err := in.ToUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		stored   []interface{}
+		password string
+		want     error
+	}{
+		{name: "password checked", stored: []interface{}{ada}, password: "correct horse"},
+		{name: "wrong password", stored: []interface{}{ada}, password: "battery staple", want: pkg.ErrInvalidCredentials},
+		{name: "account gone", password: "correct horse", want: pkg.ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+			mt.AddMockResponses(dbtest.Cursor(mt, tt.stored...))
+			token, err := svc.ResumeSignUp(ada.ID, tt.password, sessions.Device{})
+			if !errors.Is(err, tt.want) || (tt.want == nil) != (token != "") {
+				mt.Errorf("ResumeSignUp() = %q, %v, want a token or %v", token, err, tt.want)
+			}
+			dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": ada.ID})
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"sigmacoder/pkg/retry"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The SignupCounts type counts the users, in total and by how recently they signed up.
+// @property {int64} Total - The number of users.
+// @property {int64} Last7Days - The number of users that signed up in the last 7 days.
+// @property {int64} Last30Days - The number of users that signed up in the last 30 days.
+type SignupCounts struct {
+	Total      int64 `json:"total" bson:"total"`
+	Last7Days  int64 `json:"last7Days" bson:"last7days"`
+	Last30Days int64 `json:"last30Days" bson:"last30days"`
+}
+
+// The `SignupCounts` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It counts the users and the ones that signed up in the 7 and 30 days before `now`, in a
+// single aggregation.
+func (s *Repo) SignupCounts(now time.Time) (SignupCounts, error) {
+	since := func(days int) bson.M {
+		return bson.M{"$cond": bson.A{bson.M{"$gte": bson.A{"$createdat", now.AddDate(0, 0, -days)}}, 1, 0}}
+	}
+	pipeline := bson.A{
+		bson.M{"$group": bson.M{
+			"_id":        nil,
+			"total":      bson.M{"$sum": 1},
+			"last7days":  bson.M{"$sum": since(7)},
+			"last30days": bson.M{"$sum": since(30)},
+		}},
+	}
+	var result []SignupCounts
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &result)
+	})
+	if err != nil || len(result) == 0 {
+		return SignupCounts{}, err
+	}
+	return result[0], nil
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The function returns the user's timezone, or `fallback` when they haven't set one or it no longer
+// loads.
+func (u *User) Location(fallback *time.Location) *time.Location {
+	if u.Timezone == "" {
+		return fallback
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// The `SetTimezone` function is a method of the `Svc` struct that implements the `SetTimezone` method
+// of the `Service` interface. It stores the IANA timezone the user's calendar days are counted in and
+// returns the updated user. An empty timezone clears it, so the server's default is used again. It
+// returns `pkg.ErrUnknownTimezone` for names `time.LoadLocation` doesn't know.
+func (s *Svc) SetTimezone(userID string, timezone string) (User, error) {
+	timezone = strings.TrimSpace(timezone)
+	update := bson.M{"$unset": bson.M{"timezone": ""}}
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil || strings.EqualFold(timezone, "Local") {
+			return User{}, pkg.ErrUnknownTimezone
+		}
+		timezone = loc.String()
+		update = bson.M{"$set": bson.M{"timezone": timezone}}
+	}
+	user, err := s.repo.Update(userID, update)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return User{}, pkg.ErrUserNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+	user.Timezone = timezone
+	return user, nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSetTimezone(t *testing.T) {
+	user := User{ID: "user-1", Email: "ada@example.com"}
+
+	for _, tt := range []struct {
+		name     string
+		timezone string
+		want     string
+	}{
+		{name: "iana name", timezone: "Asia/Kolkata", want: "Asia/Kolkata"},
+		{name: "surrounding spaces", timezone: "  America/New_York ", want: "America/New_York"},
+		{name: "utc", timezone: "UTC", want: "UTC"},
+	} {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+			mt.AddMockResponses(dbtest.Value(mt, user))
+			updated, err := svc.SetTimezone("user-1", tt.timezone)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if updated.Timezone != tt.want {
+				mt.Errorf("SetTimezone() = %+v, want the timezone %q", updated, tt.want)
+			}
+			command := dbtest.NextCommand(mt, "findAndModify")
+			dbtest.ExpectField(mt, command, "query", bson.M{"_id": "user-1"})
+			dbtest.ExpectField(mt, command, "update", bson.M{"$set": bson.M{"timezone": tt.want}})
+		})
+	}
+
+	dbtest.Run(t, "cleared", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+		mt.AddMockResponses(dbtest.Value(mt, user))
+		updated, err := svc.SetTimezone("user-1", " ")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if updated.Timezone != "" {
+			mt.Errorf("SetTimezone() = %+v, want no timezone", updated)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "findAndModify"), "update", bson.M{"$unset": bson.M{"timezone": ""}})
+	})
+
+	for _, timezone := range []string{"Mars/Olympus", "Local", "local", "+05:30", "../etc/passwd"} {
+		dbtest.Run(t, "invalid "+timezone, func(mt *mtest.T) {
+			svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+			if _, err := svc.SetTimezone("user-1", timezone); !errors.Is(err, pkg.ErrUnknownTimezone) {
+				mt.Errorf("SetTimezone(%q) = %v, want %v", timezone, err, pkg.ErrUnknownTimezone)
+			}
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+
+	dbtest.Run(t, "unknown user", func(mt *mtest.T) {
+		svc := NewAuthService(NewRepo(mt.DB).(*Repo))
+		mt.AddMockResponses(dbtest.Value(mt, nil))
+		if _, err := svc.SetTimezone("nobody", "Asia/Kolkata"); !errors.Is(err, pkg.ErrUserNotFound) {
+			mt.Errorf("SetTimezone() = %v, want %v", err, pkg.ErrUserNotFound)
+		}
+	})
+}
+
+func TestUserLocation(t *testing.T) {
+	fallback := time.FixedZone("fallback", 3600)
+	for _, tt := range []struct {
+		timezone string
+		want     string
+	}{
+		{timezone: "", want: "fallback"},
+		{timezone: "Asia/Kolkata", want: "Asia/Kolkata"},
+		{timezone: "Mars/Olympus", want: "fallback"},
+	} {
+		user := User{Timezone: tt.timezone}
+		if got := user.Location(fallback).String(); got != tt.want {
+			t.Errorf("Location() with %q = %s, want %s", tt.timezone, got, tt.want)
+		}
+	}
+}
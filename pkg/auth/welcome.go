@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"bytes"
+	"sigmacoder/pkg/logging"
+	mailer "sigmacoder/pkg/mail"
+	"text/template"
+)
+
+// `welcomeTemplate` is the body of the email sent to new users.
+var welcomeTemplate = template.Must(template.New("welcome").Parse(`Hi {{.Name}},
+
+Welcome to SigmaCoder! Your account is ready, so you can start solving questions right away:
+
+{{.BaseURL}}
+
+Happy coding!
+`))
+
+// The function sends the welcome email to a new user in the background, so a slow or failing mail
+// server never delays or fails the sign up. Failures are only logged.
+func (s *Svc) sendWelcome(user User) {
+	if s.welcomeEmail == nil || !s.welcomeEmail() || user.Email == "" {
+		return
+	}
+	var body bytes.Buffer
+	data := struct{ Name, BaseURL string }{Name: user.Name, BaseURL: s.baseURL}
+	if data.Name == "" {
+		data.Name = "there"
+	}
+	if err := welcomeTemplate.Execute(&body, data); err != nil {
+		logging.Errorf("auth: could not render the welcome email of %s: %v", user.ID, err)
+		return
+	}
+	msg := mailer.Message{To: user.Email, Subject: "Welcome to SigmaCoder", Body: body.String()}
+	go func() {
+		if err := s.mailer.Send(msg); err != nil {
+			logging.Errorf("auth: could not send the welcome email of %s: %v", user.ID, err)
+		}
+	}()
+}
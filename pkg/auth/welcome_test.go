@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"errors"
+	"sigmacoder/pkg/database/dbtest"
+	mailer "sigmacoder/pkg/mail"
+	"sigmacoder/pkg/sessions"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The type channelMailer is a mail sender that hands every message it is asked to send to a channel,
+// so tests can wait for emails sent in the background. It waits for `release`, when set, before
+// answering with `err`.
+type channelMailer struct {
+	sent    chan mailer.Message
+	release chan struct{}
+	err     error
+}
+
+func (m *channelMailer) Send(msg mailer.Message) error {
+	if m.release != nil {
+		<-m.release
+	}
+	m.sent <- msg
+	return m.err
+}
+
+// The function signs up Ada with a service that sends welcome emails through `sender` when `enabled`.
+func signUpWithWelcome(mt *mtest.T, sender mailer.Sender, enabled bool) {
+	svc := NewAuthService(NewRepo(mt.DB).(*Repo), WithMailer(sender, "https://sigmacoder.example/"), WithWelcomeEmail(func() bool { return enabled }))
+	mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt), dbtest.Written(1))
+	in := InUser{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada", Password: "correct horse"}
+	if _, err := svc.SignUp(in, sessions.Device{}); err != nil {
+		mt.Fatalf("SignUp() = %v, want the sign up to succeed", err)
+	}
+}
+
+func TestWelcomeEmail(t *testing.T) {
+	dbtest.Run(t, "sent to the new user", func(mt *mtest.T) {
+		sender := &channelMailer{sent: make(chan mailer.Message, 1)}
+		signUpWithWelcome(mt, sender, true)
+		select {
+		case msg := <-sender.sent:
+			if msg.To != "ada@example.com" {
+				mt.Errorf("sent to %q, want the new user's email", msg.To)
+			}
+			if !strings.Contains(msg.Body, "Hi Ada Lovelace,") || !strings.Contains(msg.Body, "https://sigmacoder.example\n") {
+				mt.Errorf("body = %q, want the user's name and the site link", msg.Body)
+			}
+		case <-time.After(time.Second):
+			mt.Fatal("no welcome email was sent")
+		}
+	})
+
+	dbtest.Run(t, "slow mail server", func(mt *mtest.T) {
+		sender := &channelMailer{sent: make(chan mailer.Message, 1), release: make(chan struct{})}
+		started := time.Now()
+		signUpWithWelcome(mt, sender, true)
+		if elapsed := time.Since(started); elapsed > time.Second {
+			mt.Errorf("SignUp() took %s, want it not to wait for the mail server", elapsed)
+		}
+		close(sender.release)
+		<-sender.sent
+	})
+
+	dbtest.Run(t, "failing mail server", func(mt *mtest.T) {
+		sender := &channelMailer{sent: make(chan mailer.Message, 1), err: errors.New("connection refused")}
+		signUpWithWelcome(mt, sender, true)
+		<-sender.sent
+	})
+
+	dbtest.Run(t, "turned off", func(mt *mtest.T) {
+		sender := &channelMailer{sent: make(chan mailer.Message, 1)}
+		signUpWithWelcome(mt, sender, false)
+		select {
+		case msg := <-sender.sent:
+			mt.Errorf("sent %+v, want no email", msg)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sigmacoder/pkg"
+	"strings"
+	"time"
+)
+
+// The siteverify endpoints of the supported captcha services. Both take the same form fields and
+// answer with the same `success` field.
+const (
+	RecaptchaURL = "https://www.google.com/recaptcha/api/siteverify"
+	HCaptchaURL  = "https://api.hcaptcha.com/siteverify"
+)
+
+// The SignupVerifier interface checks that a sign up comes from a person rather than a bot, before the
+// account is created.
+// @property Verify - Verify checks the captcha token the client solved. `remoteIP` is the client's IP,
+// which the captcha service may use in its checks; it may be empty. It returns `pkg.ErrCaptchaFailed`
+// when the token is missing or wasn't accepted, and another error when the service couldn't be asked
+// before `ctx` was done.
+type SignupVerifier interface {
+	Verify(ctx context.Context, token string, remoteIP string) error
+}
+
+// The NoopVerifier type is a SignupVerifier that accepts every sign up. It is used when no captcha is
+// configured, such as in development.
+type NoopVerifier struct{}
+
+// The `Verify` method accepts any token, including an empty one.
+func (NoopVerifier) Verify(ctx context.Context, token string, remoteIP string) error {
+	return nil
+}
+
+// The SiteVerifier type checks tokens with a captcha service's siteverify endpoint, such as reCAPTCHA's
+// or hCaptcha's.
+// @property {string} url - The siteverify endpoint tokens are checked with.
+// @property {string} secret - The secret key of the site, shared with the captcha service.
+// @property client - The HTTP client used for the checks. Its timeout bounds each of them.
+type SiteVerifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// The type siteverifyResponse is the part of the siteverify response the verifier reads.
+type siteverifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// The `Verify` function is a method of the `SiteVerifier` struct that implements the `SignupVerifier`
+// interface. An empty token is rejected without asking the service.
+func (v *SiteVerifier) Verify(ctx context.Context, token string, remoteIP string) error {
+	if token == "" {
+		return pkg.ErrCaptchaFailed
+	}
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("captcha: siteverify answered %s", resp.Status)
+	}
+	var result siteverifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("captcha: could not decode the siteverify response: %w", err)
+	}
+	if !result.Success {
+		return pkg.ErrCaptchaFailed
+	}
+	return nil
+}
+
+// The function creates a verifier that checks tokens with the siteverify endpoint at `endpoint`, using
+// the site's secret key.
+func NewSiteVerifier(endpoint string, secret string) *SiteVerifier {
+	return &SiteVerifier{url: endpoint, secret: secret, client: &http.Client{Timeout: 5 * time.Second}}
+}
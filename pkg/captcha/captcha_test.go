@@ -0,0 +1,74 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sigmacoder/pkg"
+	"testing"
+)
+
+// The function starts a siteverify endpoint that answers every check with `status` and `body`, and
+// returns a verifier using it together with the forms it was sent.
+func siteverify(t *testing.T, status int, body string) (*SiteVerifier, *[]url.Values) {
+	t.Helper()
+	var forms []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		forms = append(forms, r.PostForm)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return NewSiteVerifier(server.URL, "site-secret"), &forms
+}
+
+func TestSiteVerifier(t *testing.T) {
+	t.Run("accepted token", func(t *testing.T) {
+		verifier, forms := siteverify(t, http.StatusOK, `{"success": true}`)
+		if err := verifier.Verify(context.Background(), "solved", "203.0.113.7"); err != nil {
+			t.Fatalf("Verify() = %v, want nil", err)
+		}
+		if len(*forms) != 1 {
+			t.Fatalf("sent %d checks, want 1", len(*forms))
+		}
+		form := (*forms)[0]
+		if form.Get("secret") != "site-secret" || form.Get("response") != "solved" || form.Get("remoteip") != "203.0.113.7" {
+			t.Errorf("form = %v, want the secret, the token and the client IP", form)
+		}
+	})
+
+	t.Run("rejected token", func(t *testing.T) {
+		verifier, _ := siteverify(t, http.StatusOK, `{"success": false, "error-codes": ["invalid-input-response"]}`)
+		if err := verifier.Verify(context.Background(), "forged", ""); !errors.Is(err, pkg.ErrCaptchaFailed) {
+			t.Errorf("Verify() = %v, want %v", err, pkg.ErrCaptchaFailed)
+		}
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		verifier, forms := siteverify(t, http.StatusOK, `{"success": true}`)
+		if err := verifier.Verify(context.Background(), "", ""); !errors.Is(err, pkg.ErrCaptchaFailed) {
+			t.Errorf("Verify() = %v, want %v", err, pkg.ErrCaptchaFailed)
+		}
+		if len(*forms) != 0 {
+			t.Errorf("sent %v, want the token refused before asking the service", *forms)
+		}
+	})
+
+	t.Run("service unavailable", func(t *testing.T) {
+		verifier, _ := siteverify(t, http.StatusServiceUnavailable, "")
+		if err := verifier.Verify(context.Background(), "solved", ""); err == nil || errors.Is(err, pkg.ErrCaptchaFailed) {
+			t.Errorf("Verify() = %v, want an error other than %v", err, pkg.ErrCaptchaFailed)
+		}
+	})
+}
+
+func TestNoopVerifier(t *testing.T) {
+	if err := (NoopVerifier{}).Verify(context.Background(), "", ""); err != nil {
+		t.Errorf("Verify() = %v, want every sign up accepted", err)
+	}
+}
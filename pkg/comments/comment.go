@@ -0,0 +1,51 @@
+package comments
+
+import (
+	"sigmacoder/pkg/auth"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Comment type is a message in the discussion of a question.
+// @property ID - The ObjectID of the comment.
+// @property QuestionID - The ObjectID of the question it is about.
+// @property ParentID - The comment it replies to. Only top-level comments can be replied to, so
+// discussions are at most one level deep. It is nil for top-level comments.
+// @property {string} UserID - The ID of the author. It is not exposed directly; listings carry the
+// author's public profile instead.
+// @property {string} Body - The text of the comment.
+// @property CreatedAt - When the comment was posted.
+type Comment struct {
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	QuestionID primitive.ObjectID  `json:"questionId" bson:"questionid"`
+	ParentID   *primitive.ObjectID `json:"parentId,omitempty" bson:"parentid,omitempty"`
+	UserID     string              `json:"-" bson:"userid"`
+	Body       string              `json:"body" bson:"body"`
+	CreatedAt  time.Time           `json:"createdAt" bson:"createdAt"`
+}
+
+// The InComment type is the data a user submits to post a comment.
+// @property {string} Body - The text of the comment.
+// @property {string} ParentID - The ID of the top-level comment to reply to, if any.
+type InComment struct {
+	Body     string `json:"body"`
+	ParentID string `json:"parentId,omitempty"`
+}
+
+// The CommentView type is a comment as it is listed, together with the public profile of its author
+// and, for top-level comments, its replies oldest first.
+type CommentView struct {
+	Comment
+	Author  auth.PublicUser `json:"author"`
+	Replies []CommentView   `json:"replies,omitempty"`
+}
+
+// The Page type is a page of top-level comments together with the total number of top-level comments
+// on the question.
+type Page struct {
+	Comments []CommentView `json:"comments"`
+	Total    int64         `json:"total"`
+	Limit    int           `json:"limit"`
+	Offset   int           `json:"offset"`
+}
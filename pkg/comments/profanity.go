@@ -0,0 +1,38 @@
+package comments
+
+import (
+	"strings"
+	"unicode"
+)
+
+// `blockedWords` are the words comments may not contain. The check is deliberately basic: it matches
+// whole words ignoring case, so it catches the obvious cases without flagging words that merely
+// contain one of them.
+var blockedWords = map[string]bool{
+	"asshole":      true,
+	"bastard":      true,
+	"bitch":        true,
+	"bullshit":     true,
+	"cunt":         true,
+	"dickhead":     true,
+	"fuck":         true,
+	"fucker":       true,
+	"fucking":      true,
+	"motherfucker": true,
+	"shit":         true,
+	"slut":         true,
+	"whore":        true,
+}
+
+// The function reports whether the text contains one of the `blockedWords`.
+func containsProfanity(text string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range words {
+		if blockedWords[word] {
+			return true
+		}
+	}
+	return false
+}
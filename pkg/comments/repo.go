@@ -0,0 +1,130 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the Comment entity.
+type Repository interface {
+	Create(comment Comment) (Comment, error)
+	Read(id primitive.ObjectID) (Comment, error)
+	ListTopLevel(questionID primitive.ObjectID, limit int, offset int) ([]Comment, int64, error)
+	ListReplies(parentIDs []primitive.ObjectID) ([]Comment, error)
+	Delete(id primitive.ObjectID) error
+	EnsureIndexes() error
+}
+
+// Repo is the struct that implements the Repository interface on top of the `comments` collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `Create` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It stores a new comment and returns it with its assigned ID.
+func (s *Repo) Create(comment Comment) (Comment, error) {
+	comment.ID = primitive.NewObjectID()
+	err := retry.Default.Do(s.context, func() error {
+		_, err := s.db.InsertOne(s.context, comment)
+		return err
+	})
+	return comment, err
+}
+
+// The `Read` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It returns the comment with the given ID, or `pkg.ErrCommentNotFound`.
+func (s *Repo) Read(id primitive.ObjectID) (Comment, error) {
+	var comment Comment
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, bson.M{"_id": id}).Decode(&comment)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return comment, pkg.ErrCommentNotFound
+	}
+	return comment, err
+}
+
+// The `ListTopLevel` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns a page of the top-level comments on a question, newest first, together with
+// the number of top-level comments on it.
+func (s *Repo) ListTopLevel(questionID primitive.ObjectID, limit int, offset int) ([]Comment, int64, error) {
+	list := []Comment{}
+	var total int64
+	query := bson.M{"questionid": questionID, "parentid": nil}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).SetSkip(int64(offset)).SetLimit(int64(limit))
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		total, err = s.db.CountDocuments(s.context, query)
+		if err != nil {
+			return err
+		}
+		cursor, err := s.db.Find(s.context, query, opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &list)
+	})
+	return list, total, err
+}
+
+// The `ListReplies` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the replies to all of the given comments in a single query, oldest first.
+func (s *Repo) ListReplies(parentIDs []primitive.ObjectID) ([]Comment, error) {
+	list := []Comment{}
+	if len(parentIDs) == 0 {
+		return list, nil
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, bson.M{"parentid": bson.M{"$in": parentIDs}}, opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &list)
+	})
+	return list, err
+}
+
+// The `Delete` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It deletes a comment together with its replies, so no reply is left without the comment it answers.
+func (s *Repo) Delete(id primitive.ObjectID) error {
+	return retry.Default.Do(s.context, func() error {
+		_, err := s.db.DeleteMany(s.context, bson.M{"$or": bson.A{bson.M{"_id": id}, bson.M{"parentid": id}}})
+		return err
+	})
+}
+
+// The `EnsureIndexes` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It creates the indexes behind the paginated listing of a question's comments and the
+// lookup of their replies.
+func (s *Repo) EnsureIndexes() error {
+	_, err := s.db.Indexes().CreateMany(s.context, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "questionid", Value: 1}, {Key: "parentid", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "parentid", Value: 1}, {Key: "createdAt", Value: 1}}},
+	})
+	return err
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `comments` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("comments", opts...), context: ctx}
+}
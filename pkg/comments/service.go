@@ -0,0 +1,173 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"strings"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Service interface defines the discussion operations available to the HTTP handlers.
+// @property Post - Post adds a user's comment, or reply to a comment, to the discussion of a question.
+// @property List - List returns a page of a question's top-level comments with their replies and the
+// public profiles of their authors.
+// @property Delete - Delete removes a comment, which only its author may do.
+// @property WithContext - WithContext returns a copy of the service whose repository calls run with the
+// given context.
+type Service interface {
+	Post(userID string, questionID string, in InComment) (Comment, error)
+	List(questionID string, limit int, offset int) (Page, error)
+	Delete(userID string, commentID string) error
+	WithContext(ctx context.Context) Service
+}
+
+// The type Svc implements the Service interface.
+// @property repo - The repository comments are stored in.
+// @property questions - The question repository, used to check that a question exists.
+// @property users - The user repository, used to look up the authors of comments.
+// @property maxLength - The maximum number of characters a comment may contain.
+type Svc struct {
+	repo      *Repo
+	questions allquestions.Repository
+	users     *auth.Repo
+	maxLength int
+}
+
+// The `Post` function is a method of the `Svc` struct that implements the `Post` method of the
+// `Service` interface. The body must not be blank, longer than the maximum length or contain
+// profanity. A reply must answer a top-level comment on the same question.
+func (s *Svc) Post(userID string, questionID string, in InComment) (Comment, error) {
+	oid, err := s.questionID(questionID)
+	if err != nil {
+		return Comment{}, err
+	}
+	body := strings.TrimSpace(in.Body)
+	switch {
+	case body == "":
+		return Comment{}, pkg.ErrCommentEmpty
+	case utf8.RuneCountInString(body) > s.maxLength:
+		return Comment{}, pkg.ErrCommentTooLong
+	case containsProfanity(body):
+		return Comment{}, pkg.ErrCommentProfanity
+	}
+	comment := Comment{QuestionID: oid, UserID: userID, Body: body, CreatedAt: pkg.NowUTC()}
+	if in.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(in.ParentID)
+		if err != nil {
+			return Comment{}, pkg.ErrInvalidParentComment
+		}
+		parent, err := s.repo.Read(parentID)
+		if errors.Is(err, pkg.ErrCommentNotFound) || (err == nil && (parent.QuestionID != oid || parent.ParentID != nil)) {
+			return Comment{}, pkg.ErrInvalidParentComment
+		}
+		if err != nil {
+			return Comment{}, err
+		}
+		comment.ParentID = &parentID
+	}
+	return s.repo.Create(comment)
+}
+
+// The `List` function is a method of the `Svc` struct that implements the `List` method of the
+// `Service` interface. The replies and the authors of the whole page are each looked up in a single
+// query.
+func (s *Svc) List(questionID string, limit int, offset int) (Page, error) {
+	page := Page{Comments: []CommentView{}, Limit: limit, Offset: offset}
+	oid, err := s.questionID(questionID)
+	if err != nil {
+		return page, err
+	}
+	list, total, err := s.repo.ListTopLevel(oid, limit, offset)
+	page.Total = total
+	if err != nil || len(list) == 0 {
+		return page, err
+	}
+	parentIDs := make([]primitive.ObjectID, 0, len(list))
+	for _, comment := range list {
+		parentIDs = append(parentIDs, comment.ID)
+	}
+	replies, err := s.repo.ListReplies(parentIDs)
+	if err != nil {
+		return page, err
+	}
+	var authorIDs []string
+	for _, comment := range append(list, replies...) {
+		authorIDs = append(authorIDs, comment.UserID)
+	}
+	authors, err := s.users.ReadMany(authorIDs)
+	if err != nil {
+		return page, err
+	}
+	byID := make(map[string]auth.PublicUser, len(authors))
+	for _, author := range authors {
+		byID[author.ID] = author.ToPublicUser()
+	}
+	repliesByParent := map[primitive.ObjectID][]CommentView{}
+	for _, reply := range replies {
+		repliesByParent[*reply.ParentID] = append(repliesByParent[*reply.ParentID], CommentView{Comment: reply, Author: byID[reply.UserID]})
+	}
+	for _, comment := range list {
+		page.Comments = append(page.Comments, CommentView{Comment: comment, Author: byID[comment.UserID], Replies: repliesByParent[comment.ID]})
+	}
+	return page, nil
+}
+
+// The `Delete` function is a method of the `Svc` struct that implements the `Delete` method of the
+// `Service` interface. Only the author may delete a comment; anybody else gets `pkg.ErrForbidden`.
+// Deleting a top-level comment also deletes its replies.
+func (s *Svc) Delete(userID string, commentID string) error {
+	oid, err := primitive.ObjectIDFromHex(commentID)
+	if err != nil {
+		return pkg.ErrCommentNotFound
+	}
+	comment, err := s.repo.Read(oid)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != userID {
+		return pkg.ErrForbidden
+	}
+	return s.repo.Delete(oid)
+}
+
+// The function parses a question ID and checks that the question exists.
+func (s *Svc) questionID(questionID string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(questionID)
+	if err != nil {
+		return oid, pkg.ErrInvalidQuestionID
+	}
+	existing, err := s.questions.ExistingIDs([]primitive.ObjectID{oid})
+	if err != nil {
+		return oid, err
+	}
+	if len(existing) == 0 {
+		return oid, pkg.ErrQuestionNotFound
+	}
+	return oid, nil
+}
+
+// The `WithContext` function is a method of the `Svc` struct that implements the `WithContext` method
+// of the `Service` interface. It returns a copy of the service bound to the given context.
+func (s *Svc) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.repo = s.repo.WithContext(ctx)
+	clone.questions = s.questions.WithContext(ctx)
+	clone.users = s.users.WithContext(ctx)
+	return &clone
+}
+
+// The function creates a new instance of the comments service. Comments may be at most `maxLength`
+// characters long.
+func NewCommentsService(repo *Repo, questions allquestions.Repository, users *auth.Repo, maxLength int) Service {
+	return &Svc{
+		repo:      repo,
+		questions: questions,
+		users:     users,
+		maxLength: maxLength,
+	}
+}
@@ -0,0 +1,188 @@
+package comments
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns a comments service, capping comments at `maxLength` characters, whose
+// repositories talk to the mocked deployment.
+func newService(mt *mtest.T, maxLength int) Service {
+	return NewCommentsService(NewRepo(mt.DB).(*Repo), allquestions.NewRepo(mt.DB), auth.NewRepo(mt.DB).(*auth.Repo), maxLength)
+}
+
+// The function returns the response to the lookup of a question that exists.
+func questionFound(mt *mtest.T, id primitive.ObjectID) bson.D {
+	return dbtest.Cursor(mt, bson.M{"_id": id})
+}
+
+func TestPost(t *testing.T) {
+	questionID := primitive.NewObjectID()
+
+	dbtest.Run(t, "top-level comment", func(mt *mtest.T) {
+		mt.AddMockResponses(questionFound(mt, questionID), dbtest.Written(1))
+		comment, err := newService(mt, 100).Post("user-1", questionID.Hex(), InComment{Body: "  Try two pointers.  "})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if comment.Body != "Try two pointers." || comment.ParentID != nil || comment.ID.IsZero() {
+			mt.Errorf("Post() = %+v, want a trimmed top-level comment with an ID", comment)
+		}
+		dbtest.NextCommand(mt, "find")
+		stored := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+		if _, ok := stored["parentid"]; ok || stored["userid"] != "user-1" || stored["questionid"] != questionID {
+			mt.Errorf("stored %v, want a top-level comment by user-1 on the question", stored)
+		}
+	})
+
+	dbtest.Run(t, "reply", func(mt *mtest.T) {
+		parent := Comment{ID: primitive.NewObjectID(), QuestionID: questionID, UserID: "user-2", Body: "How?"}
+		mt.AddMockResponses(questionFound(mt, questionID), dbtest.Cursor(mt, parent), dbtest.Written(1))
+		comment, err := newService(mt, 100).Post("user-1", questionID.Hex(), InComment{Body: "Sort first.", ParentID: parent.ID.Hex()})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if comment.ParentID == nil || *comment.ParentID != parent.ID {
+			mt.Errorf("Post() = %+v, want a reply to %s", comment, parent.ID.Hex())
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": parent.ID})
+		stored := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+		if stored["parentid"] != parent.ID {
+			mt.Errorf("stored parentid = %v, want %s", stored["parentid"], parent.ID.Hex())
+		}
+	})
+
+	grandparentID := primitive.NewObjectID()
+	for _, test := range []struct {
+		name   string
+		parent Comment
+	}{
+		{name: "reply to a reply", parent: Comment{ID: primitive.NewObjectID(), QuestionID: questionID, ParentID: &grandparentID}},
+		{name: "reply to a comment on another question", parent: Comment{ID: primitive.NewObjectID(), QuestionID: primitive.NewObjectID()}},
+	} {
+		dbtest.Run(t, test.name, func(mt *mtest.T) {
+			mt.AddMockResponses(questionFound(mt, questionID), dbtest.Cursor(mt, test.parent))
+			if _, err := newService(mt, 100).Post("user-1", questionID.Hex(), InComment{Body: "Sort first.", ParentID: test.parent.ID.Hex()}); !errors.Is(err, pkg.ErrInvalidParentComment) {
+				mt.Fatalf("Post() = %v, want %v", err, pkg.ErrInvalidParentComment)
+			}
+			dbtest.NextCommand(mt, "find")
+			dbtest.NextCommand(mt, "find")
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+
+	for _, test := range []struct {
+		name string
+		body string
+		want error
+	}{
+		{name: "empty", body: " \n\t ", want: pkg.ErrCommentEmpty},
+		{name: "too long", body: strings.Repeat("é", 31), want: pkg.ErrCommentTooLong},
+		{name: "profanity", body: "What the Fuck, this is hard", want: pkg.ErrCommentProfanity},
+	} {
+		dbtest.Run(t, test.name, func(mt *mtest.T) {
+			mt.AddMockResponses(questionFound(mt, questionID))
+			if _, err := newService(mt, 30).Post("user-1", questionID.Hex(), InComment{Body: test.body}); !errors.Is(err, test.want) {
+				mt.Fatalf("Post() = %v, want %v", err, test.want)
+			}
+			dbtest.NextCommand(mt, "find")
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+
+	dbtest.Run(t, "unknown question", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if _, err := newService(mt, 100).Post("user-1", questionID.Hex(), InComment{Body: "Hello"}); !errors.Is(err, pkg.ErrQuestionNotFound) {
+			mt.Fatalf("Post() = %v, want %v", err, pkg.ErrQuestionNotFound)
+		}
+	})
+}
+
+func TestList(t *testing.T) {
+	dbtest.Run(t, "replies and authors", func(mt *mtest.T) {
+		questionID := primitive.NewObjectID()
+		top := Comment{ID: primitive.NewObjectID(), QuestionID: questionID, UserID: "user-1", Body: "How?", CreatedAt: time.Now()}
+		reply := Comment{ID: primitive.NewObjectID(), QuestionID: questionID, ParentID: &top.ID, UserID: "user-2", Body: "Sort first.", CreatedAt: time.Now()}
+		mt.AddMockResponses(
+			questionFound(mt, questionID),
+			mtest.CreateCursorResponse(0, dbtest.Namespace, mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			dbtest.Cursor(mt, top),
+			dbtest.Cursor(mt, reply),
+			dbtest.Cursor(mt, auth.User{ID: "user-1", Username: "ada", Email: "ada@example.com"}, auth.User{ID: "user-2", Username: "grace", Email: "grace@example.com"}),
+		)
+		page, err := newService(mt, 100).List(questionID.Hex(), 10, 0)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if page.Total != 1 || len(page.Comments) != 1 {
+			mt.Fatalf("List() = %+v, want one top-level comment", page)
+		}
+		listed := page.Comments[0]
+		if listed.Author.Username != "ada" || len(listed.Replies) != 1 || listed.Replies[0].Author.Username != "grace" {
+			mt.Errorf("List() = %+v, want the comment by ada with the reply by grace", listed)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "aggregate")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"questionid": questionID, "parentid": nil})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"parentid": bson.M{"$in": bson.A{top.ID}}})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": bson.M{"$in": bson.A{"user-1", "user-2"}}})
+	})
+}
+
+func TestDelete(t *testing.T) {
+	comment := Comment{ID: primitive.NewObjectID(), QuestionID: primitive.NewObjectID(), UserID: "user-1", Body: "How?"}
+
+	dbtest.Run(t, "by the author", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, comment), dbtest.Written(2))
+		if err := newService(mt, 100).Delete("user-1", comment.ID.Hex()); err != nil {
+			mt.Fatal(err)
+		}
+		dbtest.NextCommand(mt, "find")
+		deletes := dbtest.NextCommand(mt, "delete")["deletes"].(bson.A)
+		dbtest.ExpectField(mt, deletes[0].(bson.M), "q", bson.M{"$or": bson.A{bson.M{"_id": comment.ID}, bson.M{"parentid": comment.ID}}})
+	})
+
+	dbtest.Run(t, "by somebody else", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, comment))
+		if err := newService(mt, 100).Delete("user-2", comment.ID.Hex()); !errors.Is(err, pkg.ErrForbidden) {
+			mt.Fatalf("Delete() = %v, want %v", err, pkg.ErrForbidden)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "unknown comment", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if err := newService(mt, 100).Delete("user-1", comment.ID.Hex()); !errors.Is(err, pkg.ErrCommentNotFound) {
+			mt.Fatalf("Delete() = %v, want %v", err, pkg.ErrCommentNotFound)
+		}
+	})
+}
+
+func TestContainsProfanity(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{text: "Use a hash map.", want: false},
+		{text: "This is SHIT.", want: true},
+		{text: "Scunthorpe and cocktails", want: false},
+		{text: "shitake mushrooms", want: false},
+	}
+	for _, tt := range tests {
+		if got := containsProfanity(tt.text); got != tt.want {
+			t.Errorf("containsProfanity(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
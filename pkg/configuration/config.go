@@ -1,9 +1,14 @@
 package configuration
 
-// `import "os"` is importing the `os` package, which provides a way to interact with the operating
-// system. In this specific code, it is used to retrieve environment variables using the `os.Getenv()`
-// function.
-import "os"
+// The `import` block is importing the `os` package, which provides a way to interact with the
+// operating system, and the `strconv`, `strings` and `time` packages, which are used to parse
+// numeric, boolean, list and duration valued environment variables.
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // `var config Config` is declaring a variable named `config` of type `Config`. This variable will be
 // used to store the configuration values retrieved from environment variables.
@@ -19,19 +24,395 @@ var config Config
 // secret key used for JSON Web Token (JWT) authentication. JWT is a popular method for securely
 // transmitting information between parties as a JSON object. The secret key is used to sign and verify
 // the authenticity of the token.
+// @property {[]string} TrustedProxies - TrustedProxies is the list of proxy IPs or CIDR ranges whose
+// `X-Forwarded-For` header is honored when resolving the client IP. Requests from any other address
+// are attributed to the connecting peer.
+// @property {time.Duration} RequestTimeout - The maximum time a single request may take before the
+// server gives up on it and answers with 504 Gateway Timeout.
+// @property {bool} CompressEnabled - Whether responses are gzip/deflate/brotli encoded for clients that
+// send a matching `Accept-Encoding` header.
+// @property {int} CompressLevel - The compression level passed to Fiber's compress middleware: 0 for
+// the default, 1 for best speed and 2 for best compression.
+// @property {int} NoteMaxLength - The maximum number of characters a user's note on a question may
+// contain.
+// @property {string} AppName - The name the Fiber app reports in its startup message and `Server`
+// header.
+// @property {time.Duration} ReadTimeout - The maximum time allowed to read a full request, including
+// the body. Together with the other timeouts it keeps slow clients from holding connections open.
+// @property {time.Duration} WriteTimeout - The maximum time allowed to write the response.
+// @property {time.Duration} IdleTimeout - How long a keep-alive connection may sit idle before it is
+// closed.
+// @property {bool} DisableStartupMessage - Whether to suppress Fiber's startup banner, which is
+// usually wanted in production.
+// @property {int} ProgressBatchMax - The maximum number of question IDs accepted by a single batch
+// "mark solved" request.
+// @property {time.Duration} TimeLogMaxSession - The longest session a user may log as time spent on a
+// question. Longer ones are rejected as a timer that was left running.
+// @property {bool} SignupEnabled - Whether new users can register with an email and password. It is the
+// configured value of the `signup` feature flag.
+// @property {bool} PhoneOTPEnabled - Whether phone OTPs can be requested and verified. It is the
+// configured value of the `phone_otp` feature flag.
+// @property {time.Duration} FlagsRefresh - How often each instance reads the feature flag overrides set
+// by admins, and so how long an override takes to reach every instance.
+// @property {time.Duration} IdempotencyKeyTTL - How long the response to a sign up sent with an
+// `Idempotency-Key` header is kept to answer its retries. It holds the new user's token, so keep it
+// short.
+// @property {string} DefaultTimezone - The IANA timezone used for calendar days, such as streaks and the
+// problem of the day, when neither the request nor the user's own setting specifies one.
+// @property {[]string} OTPChannels - The Twilio Verify channels OTPs may be sent over, e.g. "sms",
+// "call" or "whatsapp". Requests for any other channel are rejected.
+// @property {int} MongoRetryAttempts - How many times a MongoDB operation is tried in total when it
+// fails with a transient error such as a primary stepdown.
+// @property {time.Duration} MongoRetryBackoff - The wait before the first retry, doubled after each
+// further attempt.
+// @property {bool} HTTPSRedirect - Whether plain HTTP requests arriving through a TLS terminating proxy
+// are redirected to HTTPS. Leave it off for local development.
+// @property {string} SignupWebhookURL - The URL a signed event is POSTed to whenever a user signs up.
+// Leave it empty to turn the webhook off.
+// @property {string} SignupWebhookSecret - The shared secret the signup webhook payloads are signed
+// with, so the receiver can verify they came from us.
+// @property {int} SignupWebhookAttempts - How many times a signup webhook delivery is tried in total.
+// @property {time.Duration} SignupWebhookBackoff - The wait before the first retry of a failed signup
+// webhook delivery, doubled after each further attempt.
+// @property {int} OTPCodeLength - The number of digits in OTP codes, between 4 and 10. It is applied to
+// the Twilio Verify service at startup; 0 leaves the service's own setting, 6 digits unless changed,
+// untouched. The mock provider honours it too. Codes stay valid for Twilio Verify's fixed window of 10 minutes.
+// @property {string} MaintenanceMode - The maintenance mode the API starts in: "off", "read-only", which
+// refuses requests that change data, or "full", which refuses everything but the health check. It is
+// the configured value of the `read_only` and `maintenance` feature flags, so admins can switch it at
+// runtime.
+// @property {time.Duration} MaintenanceRetryAfter - The delay suggested in `Retry-After` to the clients
+// refused during maintenance.
+// @property {string} PasswordPepper - An application-wide secret mixed into passwords before hashing.
+// Keep it out of the database. Changing it invalidates every existing password; leaving it empty hashes
+// passwords as they are.
+// @property {int} DefaultPageSize - The page size of list endpoints when a request doesn't ask for a
+// `limit`.
+// @property {int} MaxPageSize - The largest `limit` a list request may ask for. Larger ones are clamped
+// to it.
+// @property {string} PublicBaseURL - The URL the API is reachable at from the outside, used to build
+// the links in the emails it sends.
+// @property {string} MongoReadPreference - The read preference of the repositories, such as "primary"
+// or "secondaryPreferred". It defaults to "primary" so reads always see the latest writes.
+// @property {string} MongoWriteConcern - The write concern of the repositories, either "majority" or
+// the number of nodes that have to acknowledge a write. It defaults to "majority".
+// @property {string} QuestionsReadPreference - The read preference of the question catalogue, which is
+// read far more than it is written and can tolerate slightly stale reads. It defaults to
+// `MongoReadPreference`.
+// @property AccountDeletionGrace - How long an account whose user asked for it to be deleted stays
+// around, and can be restored, before it is purged. It defaults to 30 days.
+// @property AccountPurgeInterval - How often accounts whose deletion grace period has run out are
+// purged.
+// @property {string} CORSPublicOrigins - The comma separated origins allowed to call the public
+// question API under `/api/all`. It defaults to "*".
+// @property {string} CORSAuthOrigins - The comma separated origins allowed to call the rest of the
+// API, such as the account and admin routes. It defaults to `CORSPublicOrigins`, and should be narrowed down to the
+// app's own origins in production.
+// @property {int} CommentMaxLength - The maximum number of characters a comment in a question's
+// discussion may contain.
+// @property {int} RateLimitMax - The number of requests a user, or an IP on the public routes, may
+// make per `RateLimitWindow`. 0 disables rate limiting.
+// @property RateLimitWindow - The window `RateLimitMax` applies to.
+// @property {string} Judge - The judge that runs submitted code: "none", which leaves submissions
+// pending, or "fake", which accepts them without running anything and is only meant for local
+// development. It defaults to "none".
+// @property {string} PasswordHash - The algorithm new password hashes are created with, "argon2id" or
+// "bcrypt". Passwords hashed with the other one are re-hashed on their user's next login. It defaults
+// to "argon2id".
+// @property {string} ShareLinkSecret - The key question share links are signed with. It defaults to
+// `JwtSecret`; setting it separately allows revoking every share link without logging everyone out.
+// @property ShareLinkTTL - How long a question share link stays valid. It defaults to 7 days.
+// @property {bool} PasswordChangeLogout - Whether changing a password logs the user out of every
+// other device. It defaults to true.
+// @property {string} DailyProblemStrategy - How the problem of the day is picked: "random", or
+// "sequential", which goes through the questions in order. It defaults to "random".
+// @property DailyProblemInterval - How often the problem of the current day is selected in the
+// background, if it wasn't yet.
+// @property {string} OTPProvider - What sends and checks phone OTPs: "twilio", or "mock", which logs
+// the codes instead of sending them and is only meant for local development. It defaults to "twilio".
+// @property {string} TwilioAccountSID - The SID of the Twilio account OTPs are sent from.
+// @property {string} TwilioAuthToken - The auth token of the Twilio account.
+// @property {string} TwilioServiceSID - The SID of the Twilio Verify service OTPs are sent from.
+// @property QuestionCacheMaxAge - How long clients may cache the responses of the read-only question
+// routes. Responses to logged-in users are only cached privately. 0 turns caching off.
+// @property {string} JwtAlgorithm - The algorithm tokens are signed with: "HS256", with `JwtSecret`, or
+// "RS256", with the private key in `JwtPrivateKeyFile`. It defaults to "HS256".
+// @property {string} JwtPrivateKeyFile - The path of the PEM encoded RSA private key RS256 tokens are
+// signed with.
+// @property {[]string} JwtPreviousKeyFiles - The paths of RS256 keys that were rotated out. Tokens they
+// signed are still accepted, and their public keys still published, until they are removed from the
+// list.
+// @property {string} SMTPHost - The SMTP server emails are sent through. Leave it empty to write emails
+// to the log instead, for local development.
+// @property {int} SMTPPort - The port of the SMTP server. It defaults to 587.
+// @property {string} SMTPUsername - The user to log into the SMTP server as. Leave it empty for servers
+// that don't require a login.
+// @property {string} SMTPPassword - The password of the SMTP user.
+// @property {string} SMTPFrom - The address emails are sent from.
+// @property {bool} WelcomeEmail - Whether new users are sent a welcome email. It defaults to true. It is
+// the configured value of the `welcome_email` feature flag, which admins can override at runtime.
+// @property ViewFlushInterval - How often question views counted in memory are written to the
+// database. 0 writes every view as it happens.
+// @property {int} OTPDailyLimit - How many OTPs a phone number may be sent per UTC day, across every
+// channel. 0 removes the cap. It defaults to 10.
+// @property {string} DefaultUserType - The `UserType` new users sign up with, whatever they ask for. It
+// defaults to "user" and can't be "admin".
+// @property AcceptanceCacheTTL - How long the acceptance rate of a question is reused before it is
+// recomputed from the submissions. 0 recomputes it on every request.
+// @property {string} PhoneCountryCode - The country calling code, such as "91", assumed for phone
+// numbers given without one. When it is empty, numbers must include their country code.
+// @property {string} JwtIssuer - The `iss` claim of issued tokens, which received tokens must match.
+// When it is empty, the claim isn't checked.
+// @property {string} JwtAudience - The `aud` claim of issued tokens, which received tokens must match.
+// When it is empty, the claim isn't checked.
+// @property PasswordMaxAge - How long a password may be used before the user has to change it. 0, the
+// default, lets passwords be used forever.
+// @property {string} CaptchaProvider - What checks the captcha of sign ups: "recaptcha", "hcaptcha" or
+// "none", the default, which lets every sign up through.
+// @property {string} CaptchaSecret - The secret key of the site at the captcha provider.
+// @property {string} SimilaritySearch - Where the embeddings of questions are compared to find similar
+// ones: "memory", the default, or "atlas" for MongoDB Atlas Vector Search.
+// @property {string} AtlasVectorIndex - The name of the Atlas Vector Search index on the question
+// embeddings. It defaults to "question_embeddings".
+// @property {string} LogLevel - The lowest level that is logged: "debug", "info", the default, "warn" or
+// "error".
+// @property {string} LogFormat - How log records are written: "json", the default, with one object per
+// line for log collectors, or "console" for reading in a terminal.
+// @property {string} AppEnv - The environment the server runs in: "production", the default,
+// "development" or "test", lowercased. Settings that weaken security for testing are only accepted in
+// development and test.
+// @property {string} OTPTestCode - A fixed code the phone numbers starting with `OTPTestPhonePrefix`
+// are "sent" instead of a real OTP, so automated end-to-end tests can log in by phone. Empty, the
+// default, turns it off, and it can only be set when `AppEnv` is "development" or "test".
+// @property {string} OTPTestPhonePrefix - The start of the test phone numbers, in international format
+// such as "+1555". It is required with `OTPTestCode`.
 type Config struct {
-	MongoURI  string
-	Port      string
-	JwtSecret string
+	MongoURI                string
+	Port                    string
+	JwtSecret               string
+	TrustedProxies          []string
+	RequestTimeout          time.Duration
+	CompressEnabled         bool
+	CompressLevel           int
+	NoteMaxLength           int
+	AppName                 string
+	ReadTimeout             time.Duration
+	WriteTimeout            time.Duration
+	IdleTimeout             time.Duration
+	DisableStartupMessage   bool
+	ProgressBatchMax        int
+	TimeLogMaxSession       time.Duration
+	SignupEnabled           bool
+	PhoneOTPEnabled         bool
+	FlagsRefresh            time.Duration
+	IdempotencyKeyTTL       time.Duration
+	DefaultTimezone         string
+	OTPChannels             []string
+	MongoRetryAttempts      int
+	MongoRetryBackoff       time.Duration
+	HTTPSRedirect           bool
+	SignupWebhookURL        string
+	SignupWebhookSecret     string
+	SignupWebhookAttempts   int
+	SignupWebhookBackoff    time.Duration
+	OTPCodeLength           int
+	MaintenanceMode         string
+	MaintenanceRetryAfter   time.Duration
+	PasswordPepper          string
+	DefaultPageSize         int
+	MaxPageSize             int
+	PublicBaseURL           string
+	MongoReadPreference     string
+	MongoWriteConcern       string
+	QuestionsReadPreference string
+	AccountDeletionGrace    time.Duration
+	AccountPurgeInterval    time.Duration
+	CORSPublicOrigins       string
+	CORSAuthOrigins         string
+	CommentMaxLength        int
+	RateLimitMax            int
+	RateLimitWindow         time.Duration
+	Judge                   string
+	PasswordHash            string
+	ShareLinkSecret         string
+	ShareLinkTTL            time.Duration
+	DailyProblemStrategy    string
+	DailyProblemInterval    time.Duration
+	PasswordChangeLogout    bool
+	OTPProvider             string
+	TwilioAccountSID        string
+	TwilioAuthToken         string
+	TwilioServiceSID        string
+	QuestionCacheMaxAge     time.Duration
+	JwtAlgorithm            string
+	JwtPrivateKeyFile       string
+	JwtPreviousKeyFiles     []string
+	SMTPHost                string
+	SMTPPort                int
+	SMTPUsername            string
+	SMTPPassword            string
+	SMTPFrom                string
+	WelcomeEmail            bool
+	ViewFlushInterval       time.Duration
+	OTPDailyLimit           int
+	DefaultUserType         string
+	AcceptanceCacheTTL      time.Duration
+	PhoneCountryCode        string
+	JwtIssuer               string
+	JwtAudience             string
+	PasswordMaxAge          time.Duration
+	CaptchaProvider         string
+	CaptchaSecret           string
+	SimilaritySearch        string
+	AtlasVectorIndex        string
+	LogLevel                string
+	LogFormat               string
+	AppEnv                  string
+	OTPTestCode             string
+	OTPTestPhonePrefix      string
 }
 
 // The function retrieves configuration values from environment variables and returns them as a Config
 // struct.
 func FromEnv() Config {
 	config := Config{
-		MongoURI:  os.Getenv("MONGO_URI"),
-		Port:      os.Getenv("PORT"),
-		JwtSecret: os.Getenv("JWT_SECRET"),
+		MongoURI:              os.Getenv("MONGO_URI"),
+		Port:                  os.Getenv("PORT"),
+		JwtSecret:             os.Getenv("JWT_SECRET"),
+		TrustedProxies:        envList("TRUSTED_PROXIES"),
+		RequestTimeout:        envDuration("REQUEST_TIMEOUT", 10*time.Second),
+		CompressEnabled:       envBool("COMPRESS_ENABLED", true),
+		CompressLevel:         envInt("COMPRESS_LEVEL", 0),
+		NoteMaxLength:         envInt("NOTE_MAX_LENGTH", 5000),
+		AppName:               envString("APP_NAME", "sigmacoder"),
+		ReadTimeout:           envDuration("READ_TIMEOUT", 10*time.Second),
+		WriteTimeout:          envDuration("WRITE_TIMEOUT", 15*time.Second),
+		IdleTimeout:           envDuration("IDLE_TIMEOUT", 60*time.Second),
+		DisableStartupMessage: envBool("DISABLE_STARTUP_MESSAGE", false),
+		ProgressBatchMax:      envInt("PROGRESS_BATCH_MAX", 500),
+		TimeLogMaxSession:     envDuration("TIME_LOG_MAX_SESSION", 12*time.Hour),
+		SignupEnabled:         envBool("SIGNUP_ENABLED", true),
+		PhoneOTPEnabled:       envBool("PHONE_OTP_ENABLED", true),
+		FlagsRefresh:          envDuration("FEATURE_FLAGS_REFRESH", 30*time.Second),
+		IdempotencyKeyTTL:     envDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		DefaultTimezone:       envString("DEFAULT_TIMEZONE", "UTC"),
+		OTPChannels:           envList("OTP_CHANNELS"),
+		MongoRetryAttempts:    envInt("MONGO_RETRY_ATTEMPTS", 3),
+		MongoRetryBackoff:     envDuration("MONGO_RETRY_BACKOFF", 100*time.Millisecond),
+		HTTPSRedirect:         envBool("HTTPS_REDIRECT", false),
+		SignupWebhookURL:      os.Getenv("SIGNUP_WEBHOOK_URL"),
+		SignupWebhookSecret:   os.Getenv("SIGNUP_WEBHOOK_SECRET"),
+		SignupWebhookAttempts: envInt("SIGNUP_WEBHOOK_ATTEMPTS", 5),
+		SignupWebhookBackoff:  envDuration("SIGNUP_WEBHOOK_BACKOFF", time.Second),
+		OTPCodeLength:         envInt("OTP_CODE_LENGTH", 0),
+		MaintenanceMode:       envString("MAINTENANCE_MODE", "off"),
+		MaintenanceRetryAfter: envDuration("MAINTENANCE_RETRY_AFTER", 5*time.Minute),
+		PasswordPepper:        os.Getenv("PASSWORD_PEPPER"),
+		DefaultPageSize:       envInt("DEFAULT_PAGE_SIZE", 20),
+		MaxPageSize:           envInt("MAX_PAGE_SIZE", 100),
+		PublicBaseURL:         envString("PUBLIC_BASE_URL", "http://localhost:8080"),
+		MongoReadPreference:   envString("MONGO_READ_PREFERENCE", "primary"),
+		MongoWriteConcern:     envString("MONGO_WRITE_CONCERN", "majority"),
+		AccountDeletionGrace:  envDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour),
+		AccountPurgeInterval:  envDuration("ACCOUNT_PURGE_INTERVAL", time.Hour),
+		CORSPublicOrigins:     envString("CORS_PUBLIC_ORIGINS", "*"),
+		CommentMaxLength:      envInt("COMMENT_MAX_LENGTH", 2000),
+		RateLimitMax:          envInt("RATE_LIMIT_MAX", 120),
+		RateLimitWindow:       envDuration("RATE_LIMIT_WINDOW", time.Minute),
+		Judge:                 envString("JUDGE", "none"),
+		PasswordHash:          envString("PASSWORD_HASH", "argon2id"),
+		ShareLinkTTL:          envDuration("SHARE_LINK_TTL", 7*24*time.Hour),
+		DailyProblemStrategy:  envString("DAILY_PROBLEM_STRATEGY", "random"),
+		DailyProblemInterval:  envDuration("DAILY_PROBLEM_INTERVAL", time.Hour),
+		PasswordChangeLogout:  envBool("PASSWORD_CHANGE_REVOKES_SESSIONS", true),
+		OTPProvider:           envString("OTP_PROVIDER", "twilio"),
+		TwilioAccountSID:      os.Getenv("TWILIO_ACCOUNT_SID"),
+		TwilioAuthToken:       os.Getenv("TWILIO_AUTHTOKEN"),
+		TwilioServiceSID:      os.Getenv("TWILIO_SERVICES_ID"),
+		QuestionCacheMaxAge:   envDuration("QUESTION_CACHE_MAX_AGE", 5*time.Minute),
+		JwtAlgorithm:          envString("JWT_ALG", "HS256"),
+		JwtPrivateKeyFile:     os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		JwtPreviousKeyFiles:   envList("JWT_PREVIOUS_KEY_FILES"),
+		SMTPHost:              os.Getenv("SMTP_HOST"),
+		SMTPPort:              envInt("SMTP_PORT", 587),
+		SMTPUsername:          os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:          os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:              envString("SMTP_FROM", "no-reply@sigmacoder.dev"),
+		WelcomeEmail:          envBool("WELCOME_EMAIL_ENABLED", true),
+		ViewFlushInterval:     envDuration("VIEW_FLUSH_INTERVAL", 10*time.Second),
+		OTPDailyLimit:         envInt("OTP_DAILY_LIMIT", 10),
+		DefaultUserType:       envString("DEFAULT_USER_TYPE", "user"),
+		AcceptanceCacheTTL:    envDuration("ACCEPTANCE_CACHE_TTL", time.Minute),
+		PhoneCountryCode:      strings.TrimPrefix(os.Getenv("PHONE_DEFAULT_COUNTRY_CODE"), "+"),
+		JwtIssuer:             os.Getenv("JWT_ISSUER"),
+		JwtAudience:           os.Getenv("JWT_AUDIENCE"),
+		PasswordMaxAge:        envDuration("PASSWORD_MAX_AGE", 0),
+		CaptchaProvider:       envString("CAPTCHA_PROVIDER", "none"),
+		CaptchaSecret:         os.Getenv("CAPTCHA_SECRET"),
+		SimilaritySearch:      envString("SIMILARITY_SEARCH", "memory"),
+		AtlasVectorIndex:      envString("ATLAS_VECTOR_INDEX", "question_embeddings"),
+		LogLevel:              envString("LOG_LEVEL", "info"),
+		LogFormat:             envString("LOG_FORMAT", "json"),
+		AppEnv:                strings.ToLower(strings.TrimSpace(envString("APP_ENV", "production"))),
+		OTPTestCode:           os.Getenv("OTP_TEST_CODE"),
+		OTPTestPhonePrefix:    os.Getenv("OTP_TEST_PHONE_PREFIX"),
+	}
+	config.QuestionsReadPreference = envString("QUESTIONS_READ_PREFERENCE", config.MongoReadPreference)
+	config.CORSAuthOrigins = envString("CORS_AUTH_ORIGINS", config.CORSPublicOrigins)
+	config.ShareLinkSecret = envString("SHARE_LINK_SECRET", config.JwtSecret)
+	if len(config.OTPChannels) == 0 {
+		config.OTPChannels = []string{"sms"}
 	}
 	return config
 }
+
+// The function reads a string from an environment variable, falling back to the given default when
+// the variable is unset or empty.
+func envString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// The function reads a comma separated environment variable and returns its non-empty, trimmed
+// entries.
+func envList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// The function reads a duration such as "10s" or "1m30s" from an environment variable, falling back
+// to the given default when the variable is unset or can't be parsed.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// The function reads a boolean such as "true" or "0" from an environment variable, falling back to
+// the given default when the variable is unset or can't be parsed.
+func envBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// The function reads an integer from an environment variable, falling back to the given default when
+// the variable is unset or can't be parsed.
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
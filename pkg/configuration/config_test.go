@@ -0,0 +1,56 @@
+package configuration
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServerTimeouts(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		t.Setenv("READ_TIMEOUT", "")
+		t.Setenv("WRITE_TIMEOUT", "")
+		t.Setenv("IDLE_TIMEOUT", "")
+		t.Setenv("APP_NAME", "")
+		t.Setenv("DISABLE_STARTUP_MESSAGE", "")
+		config := FromEnv()
+		if config.ReadTimeout != 10*time.Second || config.WriteTimeout != 15*time.Second || config.IdleTimeout != 60*time.Second {
+			t.Errorf("timeouts = %s, %s, %s, want 10s, 15s, 1m0s", config.ReadTimeout, config.WriteTimeout, config.IdleTimeout)
+		}
+		if config.AppName != "sigmacoder" || config.DisableStartupMessage {
+			t.Errorf("AppName = %q, DisableStartupMessage = %v, want sigmacoder and false", config.AppName, config.DisableStartupMessage)
+		}
+	})
+
+	t.Run("from the environment", func(t *testing.T) {
+		t.Setenv("READ_TIMEOUT", "5s")
+		t.Setenv("WRITE_TIMEOUT", "1m30s")
+		t.Setenv("IDLE_TIMEOUT", "250ms")
+		t.Setenv("APP_NAME", "sigmacoder-staging")
+		t.Setenv("DISABLE_STARTUP_MESSAGE", "true")
+		config := FromEnv()
+		if config.ReadTimeout != 5*time.Second || config.WriteTimeout != 90*time.Second || config.IdleTimeout != 250*time.Millisecond {
+			t.Errorf("timeouts = %s, %s, %s, want 5s, 1m30s, 250ms", config.ReadTimeout, config.WriteTimeout, config.IdleTimeout)
+		}
+		if config.AppName != "sigmacoder-staging" || !config.DisableStartupMessage {
+			t.Errorf("AppName = %q, DisableStartupMessage = %v", config.AppName, config.DisableStartupMessage)
+		}
+	})
+
+	t.Run("unparsable values fall back to the defaults", func(t *testing.T) {
+		t.Setenv("READ_TIMEOUT", "ten seconds")
+		t.Setenv("WRITE_TIMEOUT", "15")
+		config := FromEnv()
+		if config.ReadTimeout != 10*time.Second || config.WriteTimeout != 15*time.Second {
+			t.Errorf("timeouts = %s, %s, want the defaults 10s and 15s", config.ReadTimeout, config.WriteTimeout)
+		}
+	})
+}
+
+func TestAppEnv(t *testing.T) {
+	for value, want := range map[string]string{"": "production", "test": "test", " Development ": "development", "PRODUCTION": "production"} {
+		t.Setenv("APP_ENV", value)
+		if got := FromEnv().AppEnv; got != want {
+			t.Errorf("AppEnv from %q = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The function reads the application configuration. When `CONFIG_FILE` names a YAML or JSON file, its
+// values are applied first, and environment variables that are set win over them. Without
+// `CONFIG_FILE` it is the same as `FromEnv`.
+func Load() (Config, error) {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := applyFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	return FromEnv(), nil
+}
+
+// The function reads a configuration file and exports each of its values as the environment variable
+// of the same name, unless that variable is already set. The file is a flat mapping of the variable
+// names used in `.env.example` to their values, for example `REQUEST_TIMEOUT: 10s`; lists such as
+// `OTP_CHANNELS` may be given as a YAML or JSON list. Going through the environment means the code
+// that reads variables directly, such as the Twilio credentials, sees the file's values as well.
+func applyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file: %s is neither .yaml, .yml nor .json", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	for key, value := range values {
+		text, err := fileValue(value)
+		if err != nil {
+			return fmt.Errorf("config file: %s: %w", key, err)
+		}
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The function formats a value from the configuration file the way it would be written in an
+// environment variable. Lists are joined with commas; nested mappings aren't supported.
+func fileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			text, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, text)
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %v, expected a string, number, boolean or list", value)
+}
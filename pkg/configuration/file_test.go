@@ -0,0 +1,114 @@
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// The function unsets the environment variables for the duration of the test and restores them
+// afterwards, so only the configuration file provides them.
+func unsetEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+// The function writes a configuration file with the given name and contents to a temporary directory
+// and points `CONFIG_FILE` at it.
+func useConfigFile(t *testing.T, name string, contents string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+}
+
+func TestLoad(t *testing.T) {
+	keys := []string{"REQUEST_TIMEOUT", "APP_NAME", "OTP_CHANNELS", "COMPRESS_ENABLED", "NOTE_MAX_LENGTH"}
+
+	t.Run("yaml file only", func(t *testing.T) {
+		unsetEnv(t, keys...)
+		useConfigFile(t, "config.yaml", "REQUEST_TIMEOUT: 5s\nAPP_NAME: sigmacoder-file\nOTP_CHANNELS: [sms, whatsapp]\nCOMPRESS_ENABLED: false\nNOTE_MAX_LENGTH: 200\n")
+		config, err := Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.RequestTimeout != 5*time.Second || config.AppName != "sigmacoder-file" || config.CompressEnabled || config.NoteMaxLength != 200 {
+			t.Errorf("config = %+v, want the file's values", config)
+		}
+		if !reflect.DeepEqual(config.OTPChannels, []string{"sms", "whatsapp"}) {
+			t.Errorf("OTPChannels = %v, want [sms whatsapp]", config.OTPChannels)
+		}
+	})
+
+	t.Run("json file only", func(t *testing.T) {
+		unsetEnv(t, keys...)
+		useConfigFile(t, "config.json", `{"REQUEST_TIMEOUT": "7s", "NOTE_MAX_LENGTH": 300, "OTP_CHANNELS": ["call"]}`)
+		config, err := Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.RequestTimeout != 7*time.Second || config.NoteMaxLength != 300 || !reflect.DeepEqual(config.OTPChannels, []string{"call"}) {
+			t.Errorf("config = %+v, want the file's values", config)
+		}
+	})
+
+	t.Run("environment only", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", "")
+		t.Setenv("REQUEST_TIMEOUT", "3s")
+		t.Setenv("APP_NAME", "sigmacoder-env")
+		config, err := Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(config, FromEnv()) {
+			t.Errorf("Load() = %+v, want the same as FromEnv()", config)
+		}
+		if config.RequestTimeout != 3*time.Second || config.AppName != "sigmacoder-env" {
+			t.Errorf("config = %+v, want the environment's values", config)
+		}
+	})
+
+	t.Run("environment overrides the file", func(t *testing.T) {
+		unsetEnv(t, keys...)
+		t.Setenv("APP_NAME", "sigmacoder-env")
+		useConfigFile(t, "config.yml", "REQUEST_TIMEOUT: 5s\nAPP_NAME: sigmacoder-file\n")
+		config, err := Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.AppName != "sigmacoder-env" {
+			t.Errorf("AppName = %q, want the environment's value", config.AppName)
+		}
+		if config.RequestTimeout != 5*time.Second {
+			t.Errorf("RequestTimeout = %s, want the file's value for the variable that isn't set", config.RequestTimeout)
+		}
+	})
+
+	for _, tt := range []struct{ name, file, contents string }{
+		{name: "unsupported extension", file: "config.toml", contents: "APP_NAME = \"sigmacoder\""},
+		{name: "malformed yaml", file: "config.yaml", contents: "APP_NAME: [sigmacoder"},
+		{name: "nested mapping", file: "config.json", contents: `{"APP_NAME": {"value": "sigmacoder"}}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			unsetEnv(t, keys...)
+			useConfigFile(t, tt.file, tt.contents)
+			if _, err := Load(); err == nil {
+				t.Error("Load() = nil error, want the file rejected")
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+		if _, err := Load(); err == nil {
+			t.Error("Load() = nil error, want the missing file reported")
+		}
+	})
+}
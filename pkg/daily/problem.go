@@ -0,0 +1,36 @@
+package daily
+
+import (
+	"sigmacoder/pkg/allquestions"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// `dayLayout` is the format of the days problems are selected for. Days are taken in UTC, so every user
+// sees the same problem at the same moment.
+const dayLayout = "2006-01-02"
+
+// The Problem type is the question selected as the problem of a day. There is exactly one per day.
+// @property {string} Day - The UTC day the problem is for, as YYYY-MM-DD. It is the document's ID,
+// which is what keeps two concurrent selections from storing different problems for the same day.
+// @property QuestionID - The ObjectID of the selected question.
+// @property SelectedAt - When the question was selected.
+type Problem struct {
+	Day        string             `json:"day" bson:"_id"`
+	QuestionID primitive.ObjectID `json:"questionId" bson:"questionid"`
+	SelectedAt time.Time          `json:"selectedAt" bson:"selectedAt"`
+}
+
+// The Today type is the problem of the day as it is returned to users.
+// @property {string} Day - The day in the user's timezone, as YYYY-MM-DD.
+// @property Question - The selected question, without its hints.
+type Today struct {
+	Day      string                   `json:"day"`
+	Question allquestions.AllQuestion `json:"question"`
+}
+
+// The function returns the day `now` falls on in `loc`.
+func dayOf(now time.Time, loc *time.Location) string {
+	return now.In(loc).Format(dayLayout)
+}
@@ -0,0 +1,75 @@
+package daily
+
+import (
+	"context"
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the Problem entity.
+type Repository interface {
+	Read(day string) (Problem, error)
+	Latest() (Problem, error)
+	Insert(problem Problem) (Problem, error)
+}
+
+// Repo is the struct that implements the Repository interface on top of the `daily_problems`
+// collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `Read` function is a method of the `Repo` struct that implements the `Repository` interface. It
+// returns the problem of the given day, or `mongo.ErrNoDocuments` when none was selected yet.
+func (s *Repo) Read(day string) (Problem, error) {
+	var problem Problem
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, bson.M{"_id": day}).Decode(&problem)
+	})
+	return problem, err
+}
+
+// The `Latest` function is a method of the `Repo` struct that implements the `Repository` interface. It
+// returns the most recent problem, or `mongo.ErrNoDocuments` when none was ever selected.
+func (s *Repo) Latest() (Problem, error) {
+	var problem Problem
+	opts := options.FindOne().SetSort(bson.M{"_id": -1})
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, bson.M{}, opts).Decode(&problem)
+	})
+	return problem, err
+}
+
+// The `Insert` function is a method of the `Repo` struct that implements the `Repository` interface. It
+// stores the problem of a day unless one is already stored, and returns the problem that ends up
+// stored for that day, so the first selection wins when several race.
+func (s *Repo) Insert(problem Problem) (Problem, error) {
+	err := retry.Default.Do(s.context, func() error {
+		_, err := s.db.InsertOne(s.context, problem)
+		return err
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return s.Read(problem.Day)
+	}
+	return problem, err
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `daily_problems` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("daily_problems", opts...), context: ctx}
+}
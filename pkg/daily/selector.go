@@ -0,0 +1,56 @@
+package daily
+
+import (
+	"sigmacoder/pkg/allquestions"
+)
+
+// The selection strategies for the problem of the day.
+const (
+	StrategyRandom     = "random"
+	StrategySequential = "sequential"
+)
+
+// The Selector interface picks the problem of a new day.
+// @property Select - Select returns the question to use, given the previous day's problem, or nil
+// when no problem was ever selected. It returns `mongo.ErrNoDocuments` when there is no published
+// question to pick.
+type Selector interface {
+	Select(questions allquestions.Repository, previous *Problem) (allquestions.AllQuestion, error)
+}
+
+// The RandomSelector type picks a random published question every day. The same question can come up
+// again on a later day.
+type RandomSelector struct{}
+
+// The `Select` function is a method of the `RandomSelector` struct that implements the `Selector`
+// interface.
+func (RandomSelector) Select(questions allquestions.Repository, previous *Problem) (allquestions.AllQuestion, error) {
+	return questions.RandomPublished()
+}
+
+// The SequentialSelector type walks through the published questions in `Id` order, one per day, and
+// starts over after the last one.
+type SequentialSelector struct{}
+
+// The `Select` function is a method of the `SequentialSelector` struct that implements the `Selector`
+// interface.
+func (SequentialSelector) Select(questions allquestions.Repository, previous *Problem) (allquestions.AllQuestion, error) {
+	after := 0
+	if previous != nil {
+		if question, err := questions.ReadByID(previous.QuestionID.Hex()); err == nil {
+			after = question.Id
+		}
+	}
+	return questions.NextPublished(after)
+}
+
+// The function returns the selector for a strategy, and false when the strategy is unknown.
+func NewSelector(strategy string) (Selector, bool) {
+	switch strategy {
+	case StrategyRandom:
+		return RandomSelector{}, true
+	case StrategySequential:
+		return SequentialSelector{}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,110 @@
+package daily
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/logging"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The Service interface defines the problem of the day operations.
+// @property Today - Today returns the problem of the day `now` falls on in `loc`, selecting it first
+// when that hasn't happened yet.
+// @property WithContext - WithContext returns a copy of the service whose repository calls run with the
+// given context.
+type Service interface {
+	Today(now time.Time, loc *time.Location) (Today, error)
+	WithContext(ctx context.Context) Service
+}
+
+// The type Svc implements the Service interface.
+// @property repo - The repository the selected problems are stored in.
+// @property questions - The question repository the problems are picked from.
+// @property selector - The strategy that picks the problem of a new day.
+type Svc struct {
+	repo      *Repo
+	questions allquestions.Repository
+	selector  Selector
+}
+
+// The function returns the problem stored for the day, selecting and storing it when there is none
+// yet. Concurrent callers end up with the same problem, since only the first insert for a day is
+// kept.
+func (s *Svc) selectFor(day string) (Problem, error) {
+	problem, err := s.repo.Read(day)
+	if err == nil || !errors.Is(err, mongo.ErrNoDocuments) {
+		return problem, err
+	}
+	var previous *Problem
+	if latest, err := s.repo.Latest(); err == nil {
+		previous = &latest
+	}
+	question, err := s.selector.Select(s.questions, previous)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return problem, pkg.ErrQuestionNotFound
+	}
+	if err != nil {
+		return problem, err
+	}
+	return s.repo.Insert(Problem{Day: day, QuestionID: question.ID, SelectedAt: pkg.NowUTC()})
+}
+
+// The `Today` function is a method of the `Svc` struct that implements the `Today` method of the
+// `Service` interface. Users in timezones ahead of UTC reach a new day first and select its problem. It
+// returns `pkg.ErrQuestionNotFound` when there is no published question to select, or the selected
+// question no longer exists.
+func (s *Svc) Today(now time.Time, loc *time.Location) (Today, error) {
+	day := dayOf(now, loc)
+	problem, err := s.selectFor(day)
+	if err != nil {
+		return Today{}, err
+	}
+	question, err := s.questions.ReadByID(problem.QuestionID.Hex())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Today{}, pkg.ErrQuestionNotFound
+	}
+	if err != nil {
+		return Today{}, err
+	}
+	question.Hints = nil
+	return Today{Day: day, Question: question}, nil
+}
+
+// The `WithContext` function is a method of the `Svc` struct that implements the `WithContext` method
+// of the `Service` interface. It returns a copy of the service bound to the given context.
+func (s *Svc) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.repo = s.repo.WithContext(ctx)
+	clone.questions = s.questions.WithContext(ctx)
+	return &clone
+}
+
+// The function selects the problem of the current day every `interval`, in the background, so it is
+// usually in place before the first request of the day. Requests still select it themselves when it
+// isn't. It returns immediately.
+func (s *Svc) StartScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.selectFor(dayOf(time.Now(), time.UTC)); err != nil {
+				logging.Errorf("daily: could not select the problem of the day: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// The function creates a new instance of the problem of the day service, picking new problems with the
+// given selector.
+func NewDailyService(repo *Repo, questions allquestions.Repository, selector Selector) Service {
+	return &Svc{
+		repo:      repo,
+		questions: questions,
+		selector:  selector,
+	}
+}
@@ -0,0 +1,124 @@
+package daily
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The type countingSelector picks `question`, or fails with `err`, and counts how often it is asked.
+type countingSelector struct {
+	question allquestions.AllQuestion
+	err      error
+	previous []*Problem
+}
+
+func (s *countingSelector) Select(questions allquestions.Repository, previous *Problem) (allquestions.AllQuestion, error) {
+	s.previous = append(s.previous, previous)
+	return s.question, s.err
+}
+
+// The function returns a problem of the day service, picking with `selector`, whose repositories talk
+// to the mocked deployment.
+func newService(mt *mtest.T, selector Selector) Service {
+	return NewDailyService(NewRepo(mt.DB).(*Repo), allquestions.NewRepo(mt.DB), selector)
+}
+
+func TestToday(t *testing.T) {
+	now := time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC)
+	question := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "Two Sum", Id: 1, Hints: []string{"use a map"}}
+	yesterday := Problem{Day: "2024-02-29", QuestionID: primitive.NewObjectID()}
+
+	dbtest.Run(t, "selected lazily", func(mt *mtest.T) {
+		selector := &countingSelector{question: question}
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt, yesterday), dbtest.Written(1), dbtest.Cursor(mt, question))
+		today, err := newService(mt, selector).Today(now, time.UTC)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if today.Day != "2024-03-01" || today.Question.ID != question.ID || len(today.Question.Hints) != 0 {
+			mt.Errorf("Today() = %+v, want the selected question without hints", today)
+		}
+		if len(selector.previous) != 1 || selector.previous[0] == nil || selector.previous[0].Day != yesterday.Day {
+			mt.Errorf("selector was given %v, want yesterday's problem once", selector.previous)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": "2024-03-01"})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "sort", bson.M{"_id": -1})
+		inserted := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+		if inserted["_id"] != "2024-03-01" || inserted["questionid"] != question.ID {
+			mt.Errorf("inserted %v, want the selected question for the day", inserted)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": question.ID})
+	})
+
+	dbtest.Run(t, "already selected", func(mt *mtest.T) {
+		selector := &countingSelector{question: allquestions.AllQuestion{ID: primitive.NewObjectID()}}
+		mt.AddMockResponses(dbtest.Cursor(mt, Problem{Day: "2024-03-01", QuestionID: question.ID}), dbtest.Cursor(mt, question))
+		today, err := newService(mt, selector).Today(now, time.UTC)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if today.Question.ID != question.ID {
+			mt.Errorf("Today() = %+v, want the stored problem", today)
+		}
+		if len(selector.previous) != 0 {
+			mt.Errorf("selector was asked %d times, want the stored problem reused", len(selector.previous))
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "selected concurrently", func(mt *mtest.T) {
+		other := allquestions.AllQuestion{ID: primitive.NewObjectID(), Name: "3Sum", Id: 3}
+		selector := &countingSelector{question: other}
+		mt.AddMockResponses(
+			dbtest.Cursor(mt),
+			dbtest.Cursor(mt),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}),
+			dbtest.Cursor(mt, Problem{Day: "2024-03-01", QuestionID: question.ID}),
+			dbtest.Cursor(mt, question),
+		)
+		today, err := newService(mt, selector).Today(now, time.UTC)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if today.Question.ID != question.ID {
+			mt.Errorf("Today() = %+v, want the problem the first selection stored", today)
+		}
+		if selector.previous[0] != nil {
+			mt.Errorf("selector was given %v, want nil when no problem was ever selected", selector.previous[0])
+		}
+	})
+
+	dbtest.Run(t, "day in the user's timezone", func(mt *mtest.T) {
+		tokyo := time.FixedZone("JST", 9*60*60)
+		mt.AddMockResponses(dbtest.Cursor(mt, Problem{Day: "2024-03-02", QuestionID: question.ID}), dbtest.Cursor(mt, question))
+		today, err := newService(mt, &countingSelector{}).Today(now, tokyo)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if today.Day != "2024-03-02" {
+			mt.Errorf("Today().Day = %q, want the next day in Tokyo", today.Day)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": "2024-03-02"})
+	})
+
+	dbtest.Run(t, "no published question", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt))
+		if _, err := newService(mt, &countingSelector{err: mongo.ErrNoDocuments}).Today(now, time.UTC); !errors.Is(err, pkg.ErrQuestionNotFound) {
+			mt.Fatalf("Today() = %v, want %v", err, pkg.ErrQuestionNotFound)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
@@ -0,0 +1,113 @@
+// Package dbtest runs repositories against a mocked MongoDB deployment in tests. The deployment answers
+// each command the repository sends with the next response the test queued, in order, and records the
+// commands so the test can check the filters, updates and pipelines that were sent.
+package dbtest
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// `Namespace` is the namespace the queued cursors claim to come from. The driver doesn't check it.
+const Namespace = "sigmacoder.test"
+
+// The function runs `test` as a subtest with a client connected to a mocked deployment. Repositories
+// are created from `mt.DB`.
+func Run(t *testing.T, name string, test func(mt *mtest.T)) {
+	t.Helper()
+	mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock)).Run(name, test)
+}
+
+// The function returns the response to a find or aggregate command that returns the documents in a
+// single batch.
+func Cursor(mt *mtest.T, documents ...interface{}) bson.D {
+	mt.Helper()
+	batch := make([]bson.D, len(documents))
+	for i, document := range documents {
+		batch[i] = Document(mt, document)
+	}
+	return mtest.CreateCursorResponse(0, Namespace, mtest.FirstBatch, batch...)
+}
+
+// The function returns the response to a findAndModify command, as sent by `FindOneAndUpdate` and
+// friends, that returns the document. A nil document is the response when nothing matched.
+func Value(mt *mtest.T, document interface{}) bson.D {
+	mt.Helper()
+	if document == nil {
+		return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil})
+	}
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: Document(mt, document)})
+}
+
+// The function returns the response to a write command, such as an update or a delete, that affected
+// `n` documents.
+func Written(n int) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+}
+
+// The function returns the response to a command that failed with the given server error code.
+func Failed(code int, message string) bson.D {
+	return mtest.CreateCommandErrorResponse(mtest.CommandError{Code: int32(code), Message: message})
+}
+
+// The function converts a value to the document it is stored as.
+func Document(mt *mtest.T, value interface{}) bson.D {
+	mt.Helper()
+	data, err := bson.Marshal(value)
+	if err != nil {
+		mt.Fatalf("marshalling %v: %v", value, err)
+	}
+	var document bson.D
+	if err := bson.Unmarshal(data, &document); err != nil {
+		mt.Fatalf("unmarshalling %v: %v", value, err)
+	}
+	return document
+}
+
+// The function decodes a value the way documents are compared in the tests, so values built in Go
+// compare equal to the ones sent on the wire. Field order isn't kept.
+func Normalized(mt *mtest.T, value interface{}) bson.M {
+	mt.Helper()
+	data, err := bson.Marshal(value)
+	if err != nil {
+		mt.Fatalf("marshalling %v: %v", value, err)
+	}
+	var document bson.M
+	if err := bson.Unmarshal(data, &document); err != nil {
+		mt.Fatalf("unmarshalling %v: %v", value, err)
+	}
+	return document
+}
+
+// The function returns the next command the repository sent, checking that it is `name`, such as
+// "find" or "aggregate".
+func NextCommand(mt *mtest.T, name string) bson.M {
+	mt.Helper()
+	started := mt.GetStartedEvent()
+	if started == nil {
+		mt.Fatalf("no %s command was sent", name)
+	}
+	if started.CommandName != name {
+		mt.Fatalf("sent %s, want %s", started.CommandName, name)
+	}
+	return Normalized(mt, started.Command)
+}
+
+// The function checks that the field of a command, such as the filter of a find, equals `want`.
+func ExpectField(mt *mtest.T, command bson.M, field string, want interface{}) {
+	mt.Helper()
+	if want := Normalized(mt, bson.M{field: want})[field]; !reflect.DeepEqual(command[field], want) {
+		mt.Errorf("%s = %v, want %v", field, command[field], want)
+	}
+}
+
+// The function checks that the repository sent no further commands.
+func ExpectNoCommand(mt *mtest.T) {
+	mt.Helper()
+	if started := mt.GetStartedEvent(); started != nil {
+		mt.Errorf("unexpected %s command: %v", started.CommandName, started.Command)
+	}
+}
@@ -0,0 +1,42 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// The function builds the collection options a repository is created with from a read preference
+// mode, such as "primary" or "secondaryPreferred", and a write concern, either "majority" or the
+// number of nodes that have to acknowledge a write. Empty values default to "primary" and
+// "majority".
+func CollectionOptions(readPreference string, writeConcern string) (*options.CollectionOptions, error) {
+	if readPreference == "" {
+		readPreference = "primary"
+	}
+	if writeConcern == "" {
+		writeConcern = "majority"
+	}
+	mode, err := readpref.ModeFromString(readPreference)
+	if err != nil {
+		return nil, err
+	}
+	pref, err := readpref.New(mode)
+	if err != nil {
+		return nil, err
+	}
+	var concern *writeconcern.WriteConcern
+	if writeConcern == "majority" {
+		concern = writeconcern.New(writeconcern.WMajority())
+	} else {
+		nodes, err := strconv.Atoi(writeConcern)
+		if err != nil || nodes < 0 {
+			return nil, fmt.Errorf("write concern must be \"majority\" or a number of nodes, got %q", writeConcern)
+		}
+		concern = writeconcern.New(writeconcern.W(nodes))
+	}
+	return options.Collection().SetReadPreference(pref).SetWriteConcern(concern), nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func TestCollectionOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		readPreference string
+		writeConcern   string
+		mode           readpref.Mode
+		w              interface{}
+	}{
+		{name: "defaults", mode: readpref.PrimaryMode, w: "majority"},
+		{name: "secondary preferred", readPreference: "secondaryPreferred", writeConcern: "majority", mode: readpref.SecondaryPreferredMode, w: "majority"},
+		{name: "number of nodes", readPreference: "nearest", writeConcern: "2", mode: readpref.NearestMode, w: 2},
+		{name: "unacknowledged", readPreference: "primary", writeConcern: "0", mode: readpref.PrimaryMode, w: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := CollectionOptions(tt.readPreference, tt.writeConcern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mode := opts.ReadPreference.Mode(); mode != tt.mode {
+				t.Errorf("read preference = %v, want %v", mode, tt.mode)
+			}
+			if w := opts.WriteConcern.GetW(); w != tt.w {
+				t.Errorf("write concern w = %v, want %v", w, tt.w)
+			}
+		})
+	}
+
+	for _, tt := range []struct{ name, readPreference, writeConcern string }{
+		{name: "unknown read preference", readPreference: "fastest"},
+		{name: "write concern not a number", writeConcern: "all"},
+		{name: "negative write concern", writeConcern: "-1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CollectionOptions(tt.readPreference, tt.writeConcern); err == nil {
+				t.Error("CollectionOptions() = nil error, want the value rejected")
+			}
+		})
+	}
+}
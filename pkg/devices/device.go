@@ -0,0 +1,42 @@
+package devices
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The platforms a device can be registered for.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWeb     = "web"
+)
+
+// `Platforms` lists every platform a device can be registered for.
+var Platforms = []string{PlatformIOS, PlatformAndroid, PlatformWeb}
+
+// The Device type is a device a user receives push notifications on. Each push token is registered at
+// most once.
+// @property ID - The ObjectID of the device.
+// @property {string} UserID - The ID of the user the device belongs to.
+// @property {string} Platform - The platform of the device: "ios", "android" or "web".
+// @property {string} Token - The push token the notification provider issued for the device.
+// @property CreatedAt - When the token was first registered.
+// @property UpdatedAt - When the token was last registered.
+type Device struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    string             `json:"userId" bson:"userid"`
+	Platform  string             `json:"platform" bson:"platform"`
+	Token     string             `json:"token" bson:"token"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// The InDevice type is the request body for registering a device.
+// @property {string} Platform - The platform of the device: "ios", "android" or "web".
+// @property {string} Token - The push token of the device.
+type InDevice struct {
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+}
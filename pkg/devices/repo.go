@@ -0,0 +1,105 @@
+package devices
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the Device entity.
+type Repository interface {
+	Register(userID string, platform string, token string) (Device, error)
+	Delete(userID string, id primitive.ObjectID) error
+	ListByUser(userID string) ([]Device, error)
+	EnsureIndexes() error
+}
+
+// Repo is the struct that implements the Repository interface on top of the `devices` collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `Register` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It stores the push token for the user and returns the stored device. Tokens are unique: registering
+// a token that is already known updates that device instead of adding another one, and moves it to
+// the given user, since a token identifies the app installation rather than the account that was
+// logged in on it.
+func (s *Repo) Register(userID string, platform string, token string) (Device, error) {
+	var device Device
+	now := pkg.NowUTC()
+	update := bson.M{
+		"$set":         bson.M{"userid": userID, "platform": platform, "updatedAt": now},
+		"$setOnInsert": bson.M{"createdAt": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOneAndUpdate(s.context, bson.M{"token": token}, update, opts).Decode(&device)
+	})
+	return device, err
+}
+
+// The `Delete` function is a method of the `Repo` struct that implements the `Repository` interface. It
+// removes the user's device with the given ID, and returns `pkg.ErrDeviceNotFound` when the user has no
+// such device.
+func (s *Repo) Delete(userID string, id primitive.ObjectID) error {
+	var result *mongo.DeleteResult
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		result, err = s.db.DeleteOne(s.context, bson.M{"_id": id, "userid": userID})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return pkg.ErrDeviceNotFound
+	}
+	return nil
+}
+
+// The `ListByUser` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the devices a user's notifications should be sent to.
+func (s *Repo) ListByUser(userID string) ([]Device, error) {
+	list := []Device{}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, bson.M{"userid": userID})
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &list)
+	})
+	return list, err
+}
+
+// The `EnsureIndexes` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It creates the unique index on the token, which keeps concurrent registrations of the
+// same token from creating two devices, and the index on the user that the listing relies on.
+func (s *Repo) EnsureIndexes() error {
+	_, err := s.db.Indexes().CreateMany(s.context, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userid", Value: 1}}},
+	})
+	return err
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `devices` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("devices", opts...), context: ctx}
+}
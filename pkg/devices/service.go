@@ -0,0 +1,72 @@
+package devices
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Service interface defines the device operations available to the HTTP handlers.
+// @property Register - Register records a push token for the user. It fails with
+// `pkg.ErrInvalidDevice` when the platform is unknown or the token is empty.
+// @property Remove - Remove unregisters one of the user's devices, or fails with
+// `pkg.ErrDeviceNotFound`.
+// @property WithContext - WithContext returns a copy of the service whose repository calls run with the
+// given context.
+type Service interface {
+	Register(userID string, in InDevice) (Device, error)
+	Remove(userID string, deviceID string) error
+	WithContext(ctx context.Context) Service
+}
+
+// The type Svc implements the Service interface.
+// @property repo - The repository devices are stored in.
+type Svc struct {
+	repo *Repo
+}
+
+// The `Register` function is a method of the `Svc` struct that implements the `Register` method of the
+// `Service` interface.
+func (s *Svc) Register(userID string, in InDevice) (Device, error) {
+	platform := strings.ToLower(strings.TrimSpace(in.Platform))
+	token := strings.TrimSpace(in.Token)
+	if !validPlatform(platform) || token == "" {
+		return Device{}, pkg.ErrInvalidDevice
+	}
+	return s.repo.Register(userID, platform, token)
+}
+
+// The function reports whether the platform is one of `Platforms`.
+func validPlatform(platform string) bool {
+	for _, known := range Platforms {
+		if platform == known {
+			return true
+		}
+	}
+	return false
+}
+
+// The `Remove` function is a method of the `Svc` struct that implements the `Remove` method of the
+// `Service` interface.
+func (s *Svc) Remove(userID string, deviceID string) error {
+	oid, err := primitive.ObjectIDFromHex(deviceID)
+	if err != nil {
+		return pkg.ErrDeviceNotFound
+	}
+	return s.repo.Delete(userID, oid)
+}
+
+// The `WithContext` function is a method of the `Svc` struct that implements the `WithContext` method
+// of the `Service` interface. It returns a copy of the service bound to the given context.
+func (s *Svc) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.repo = s.repo.WithContext(ctx)
+	return &clone
+}
+
+// The function creates a new instance of the devices service with a given repository.
+func NewDevicesService(repo *Repo) Service {
+	return &Svc{repo: repo}
+}
@@ -0,0 +1,94 @@
+package devices
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns a devices service whose repository talks to the mocked deployment.
+func newService(mt *mtest.T) Service {
+	return NewDevicesService(NewRepo(mt.DB).(*Repo))
+}
+
+func TestRegister(t *testing.T) {
+	dbtest.Run(t, "new token", func(mt *mtest.T) {
+		stored := Device{ID: primitive.NewObjectID(), UserID: "user-1", Platform: PlatformIOS, Token: "apns-token", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		mt.AddMockResponses(dbtest.Value(mt, stored))
+		device, err := newService(mt).Register("user-1", InDevice{Platform: " iOS ", Token: " apns-token "})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if device.ID != stored.ID {
+			mt.Errorf("Register() = %+v, want the stored device", device)
+		}
+		command := dbtest.NextCommand(mt, "findAndModify")
+		dbtest.ExpectField(mt, command, "query", bson.M{"token": "apns-token"})
+		dbtest.ExpectField(mt, command, "upsert", true)
+		update := command["update"].(bson.M)
+		if set := update["$set"].(bson.M); set["userid"] != "user-1" || set["platform"] != PlatformIOS {
+			mt.Errorf("$set = %v, want the user and the normalized platform", set)
+		}
+		if _, ok := update["$setOnInsert"].(bson.M)["createdAt"]; !ok {
+			mt.Errorf("update = %v, want createdAt only set on insert", update)
+		}
+	})
+
+	// A token registered again, even by another user, is an upsert keyed on the token, so it updates the
+	// existing device instead of adding a second one.
+	dbtest.Run(t, "known token", func(mt *mtest.T) {
+		existing := Device{ID: primitive.NewObjectID(), UserID: "user-2", Platform: PlatformAndroid, Token: "fcm-token"}
+		mt.AddMockResponses(dbtest.Value(mt, existing))
+		device, err := newService(mt).Register("user-2", InDevice{Platform: "android", Token: "fcm-token"})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if device.ID != existing.ID {
+			mt.Errorf("Register() = %+v, want the existing device", device)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "findAndModify"), "query", bson.M{"token": "fcm-token"})
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	for _, in := range []InDevice{{Platform: "blackberry", Token: "token"}, {Platform: PlatformWeb, Token: "  "}} {
+		dbtest.Run(t, "invalid "+in.Platform, func(mt *mtest.T) {
+			if _, err := newService(mt).Register("user-1", in); !errors.Is(err, pkg.ErrInvalidDevice) {
+				mt.Fatalf("Register() = %v, want %v", err, pkg.ErrInvalidDevice)
+			}
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	id := primitive.NewObjectID()
+
+	dbtest.Run(t, "own device", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Written(1))
+		if err := newService(mt).Remove("user-1", id.Hex()); err != nil {
+			mt.Fatal(err)
+		}
+		deletes := dbtest.NextCommand(mt, "delete")["deletes"].(bson.A)
+		dbtest.ExpectField(mt, deletes[0].(bson.M), "q", bson.M{"_id": id, "userid": "user-1"})
+	})
+
+	dbtest.Run(t, "another user's device", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Written(0))
+		if err := newService(mt).Remove("user-2", id.Hex()); !errors.Is(err, pkg.ErrDeviceNotFound) {
+			mt.Fatalf("Remove() = %v, want %v", err, pkg.ErrDeviceNotFound)
+		}
+	})
+
+	dbtest.Run(t, "invalid id", func(mt *mtest.T) {
+		if err := newService(mt).Remove("user-1", "not-an-id"); !errors.Is(err, pkg.ErrDeviceNotFound) {
+			mt.Fatalf("Remove() = %v, want %v", err, pkg.ErrDeviceNotFound)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
@@ -0,0 +1,102 @@
+package difficulty
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the Vote entity.
+type Repository interface {
+	Upsert(userID string, questionID primitive.ObjectID, level string) error
+	Breakdown(questionID primitive.ObjectID) (Breakdown, error)
+	EnsureIndexes() error
+}
+
+// Repo is the struct that implements the Repository interface on top of the `difficulty_votes`
+// collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `Upsert` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It records the user's vote on the question, replacing their previous vote if they already voted.
+func (s *Repo) Upsert(userID string, questionID primitive.ObjectID, level string) error {
+	filter := bson.M{"userid": userID, "questionid": questionID}
+	update := bson.M{"$set": bson.M{"level": level, "votedAt": pkg.NowUTC()}}
+	return retry.Default.Do(s.context, func() error {
+		_, err := s.db.UpdateOne(s.context, filter, update, options.Update().SetUpsert(true))
+		return err
+	})
+}
+
+// The `Breakdown` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It counts the votes on the question per level with a `$group`. A question nobody voted on
+// yields a breakdown of zeros.
+func (s *Repo) Breakdown(questionID primitive.ObjectID) (Breakdown, error) {
+	var breakdown Breakdown
+	var counts []struct {
+		Level string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"questionid": questionID}}},
+		{{Key: "$group", Value: bson.M{"_id": "$level", "count": bson.M{"$sum": 1}}}},
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &counts)
+	})
+	if err != nil {
+		return breakdown, err
+	}
+	for _, count := range counts {
+		switch count.Level {
+		case allquestions.LevelEasy:
+			breakdown.Easy = count.Count
+		case allquestions.LevelMedium:
+			breakdown.Medium = count.Count
+		case allquestions.LevelHard:
+			breakdown.Hard = count.Count
+		}
+		breakdown.Total += count.Count
+	}
+	return breakdown, nil
+}
+
+// The `EnsureIndexes` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It creates the unique index on the user and question, which keeps each user to one vote
+// per question and backs the per-question aggregation.
+func (s *Repo) EnsureIndexes() error {
+	_, err := s.db.Indexes().CreateOne(s.context, mongo.IndexModel{
+		Keys:    bson.D{{Key: "questionid", Value: 1}, {Key: "userid", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `difficulty_votes` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("difficulty_votes", opts...), context: ctx}
+}
@@ -0,0 +1,92 @@
+package difficulty
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Service interface defines the difficulty vote operations available to the HTTP handlers.
+// @property Vote - Vote records the user's vote on a question and returns the updated breakdown.
+// @property Breakdown - Breakdown returns the community's votes on a question.
+// @property WithContext - WithContext returns a copy of the service whose repository calls run with the
+// given context.
+type Service interface {
+	Vote(userID string, questionID string, level string) (Breakdown, error)
+	Breakdown(questionID string) (Breakdown, error)
+	WithContext(ctx context.Context) Service
+}
+
+// The type Svc implements the Service interface.
+// @property repo - The repository votes are stored in.
+// @property questions - The question repository, used to reject votes on questions that don't exist.
+type Svc struct {
+	repo      *Repo
+	questions allquestions.Repository
+}
+
+// The function returns the level of `allquestions.LevelEnum` a vote is for, so "hard" and "Hard" are
+// the same vote, and false when it isn't a level.
+func normalizeLevel(level string) (string, bool) {
+	for _, known := range allquestions.LevelEnum {
+		if strings.EqualFold(strings.TrimSpace(level), known) {
+			return known, true
+		}
+	}
+	return "", false
+}
+
+// The `Vote` function is a method of the `Svc` struct that implements the `Vote` method of the
+// `Service` interface. It fails with `pkg.ErrInvalidDifficulty` for anything but easy, medium or hard,
+// and with `pkg.ErrQuestionNotFound` when the question doesn't exist.
+func (s *Svc) Vote(userID string, questionID string, level string) (Breakdown, error) {
+	oid, err := primitive.ObjectIDFromHex(questionID)
+	if err != nil {
+		return Breakdown{}, pkg.ErrInvalidQuestionID
+	}
+	level, ok := normalizeLevel(level)
+	if !ok {
+		return Breakdown{}, pkg.ErrInvalidDifficulty
+	}
+	existing, err := s.questions.ExistingIDs([]primitive.ObjectID{oid})
+	if err != nil {
+		return Breakdown{}, err
+	}
+	if len(existing) == 0 {
+		return Breakdown{}, pkg.ErrQuestionNotFound
+	}
+	if err := s.repo.Upsert(userID, oid, level); err != nil {
+		return Breakdown{}, err
+	}
+	return s.repo.Breakdown(oid)
+}
+
+// The `Breakdown` function is a method of the `Svc` struct that implements the `Breakdown` method of
+// the `Service` interface.
+func (s *Svc) Breakdown(questionID string) (Breakdown, error) {
+	oid, err := primitive.ObjectIDFromHex(questionID)
+	if err != nil {
+		return Breakdown{}, pkg.ErrInvalidQuestionID
+	}
+	return s.repo.Breakdown(oid)
+}
+
+// The `WithContext` function is a method of the `Svc` struct that implements the `WithContext` method
+// of the `Service` interface. It returns a copy of the service bound to the given context.
+func (s *Svc) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.repo = s.repo.WithContext(ctx)
+	clone.questions = s.questions.WithContext(ctx)
+	return &clone
+}
+
+// The function creates a new instance of the difficulty vote service.
+func NewDifficultyService(repo *Repo, questions allquestions.Repository) Service {
+	return &Svc{
+		repo:      repo,
+		questions: questions,
+	}
+}
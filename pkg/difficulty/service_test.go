@@ -0,0 +1,115 @@
+package difficulty
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns a difficulty vote service whose repositories talk to the mocked deployment.
+func newService(mt *mtest.T) Service {
+	return NewDifficultyService(NewRepo(mt.DB).(*Repo), allquestions.NewRepo(mt.DB))
+}
+
+// The function returns the response to the aggregation of the votes on a question, one count per level.
+func counts(mt *mtest.T, easy, medium, hard int) bson.D {
+	var groups []interface{}
+	for level, count := range map[string]int{allquestions.LevelEasy: easy, allquestions.LevelMedium: medium, allquestions.LevelHard: hard} {
+		if count > 0 {
+			groups = append(groups, bson.M{"_id": level, "count": count})
+		}
+	}
+	return dbtest.Cursor(mt, groups...)
+}
+
+func TestVote(t *testing.T) {
+	questionID := primitive.NewObjectID()
+
+	// Voting again is the same upsert keyed on the user and the question, so a second vote replaces the
+	// first instead of adding another.
+	for _, test := range []struct{ name, level, stored string }{
+		{name: "first vote", level: "hard", stored: allquestions.LevelHard},
+		{name: "vote again", level: " Easy ", stored: allquestions.LevelEasy},
+	} {
+		dbtest.Run(t, test.name, func(mt *mtest.T) {
+			mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": questionID}), dbtest.Written(1), counts(mt, 2, 1, 3))
+			breakdown, err := newService(mt).Vote("user-1", questionID.Hex(), test.level)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if want := (Breakdown{Easy: 2, Medium: 1, Hard: 3, Total: 6}); breakdown != want {
+				mt.Errorf("Vote() = %+v, want %+v", breakdown, want)
+			}
+			dbtest.NextCommand(mt, "find")
+			update := dbtest.NextCommand(mt, "update")["updates"].(bson.A)[0].(bson.M)
+			dbtest.ExpectField(mt, update, "q", bson.M{"userid": "user-1", "questionid": questionID})
+			dbtest.ExpectField(mt, update, "upsert", true)
+			if level := update["u"].(bson.M)["$set"].(bson.M)["level"]; level != test.stored {
+				mt.Errorf("stored level = %v, want %s", level, test.stored)
+			}
+			dbtest.NextCommand(mt, "aggregate")
+		})
+	}
+
+	tests := []struct {
+		name       string
+		questionID string
+		level      string
+		found      []interface{}
+		want       error
+	}{
+		{name: "invalid question id", questionID: "not-an-id", level: "easy", want: pkg.ErrInvalidQuestionID},
+		{name: "not a level", questionID: questionID.Hex(), level: "trivial", want: pkg.ErrInvalidDifficulty},
+		{name: "unknown question", questionID: questionID.Hex(), level: "easy", found: []interface{}{}, want: pkg.ErrQuestionNotFound},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			if tt.found != nil {
+				mt.AddMockResponses(dbtest.Cursor(mt, tt.found...))
+			}
+			if _, err := newService(mt).Vote("user-1", tt.questionID, tt.level); !errors.Is(err, tt.want) {
+				mt.Fatalf("Vote() = %v, want %v", err, tt.want)
+			}
+			if tt.found != nil {
+				dbtest.NextCommand(mt, "find")
+			}
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+}
+
+func TestBreakdown(t *testing.T) {
+	questionID := primitive.NewObjectID()
+
+	dbtest.Run(t, "grouped per level", func(mt *mtest.T) {
+		mt.AddMockResponses(counts(mt, 4, 0, 1))
+		breakdown, err := newService(mt).Breakdown(questionID.Hex())
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if want := (Breakdown{Easy: 4, Hard: 1, Total: 5}); breakdown != want {
+			mt.Errorf("Breakdown() = %+v, want %+v", breakdown, want)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$match": bson.M{"questionid": questionID}},
+			bson.M{"$group": bson.M{"_id": "$level", "count": bson.M{"$sum": 1}}},
+		})
+	})
+
+	dbtest.Run(t, "no votes", func(mt *mtest.T) {
+		mt.AddMockResponses(counts(mt, 0, 0, 0))
+		breakdown, err := newService(mt).Breakdown(questionID.Hex())
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if breakdown != (Breakdown{}) {
+			mt.Errorf("Breakdown() = %+v, want zeros", breakdown)
+		}
+	})
+}
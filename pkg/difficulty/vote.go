@@ -0,0 +1,40 @@
+package difficulty
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Vote type is a user's opinion of how difficult a question is. Each user has at most one vote per
+// question; voting again replaces it.
+// @property ID - The ObjectID of the vote.
+// @property {string} UserID - The ID of the user that voted.
+// @property QuestionID - The ObjectID of the question voted on.
+// @property {string} Level - The level the user voted for, one of `allquestions.LevelEnum`.
+// @property VotedAt - When the user last voted.
+type Vote struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID     string             `json:"userId" bson:"userid"`
+	QuestionID primitive.ObjectID `json:"questionId" bson:"questionid"`
+	Level      string             `json:"level" bson:"level"`
+	VotedAt    time.Time          `json:"votedAt" bson:"votedAt"`
+}
+
+// The InVote type is the request body for voting on the difficulty of a question.
+// @property {string} Level - "easy", "medium" or "hard", in any case.
+type InVote struct {
+	Level string `json:"level"`
+}
+
+// The Breakdown type is the community's opinion of how difficult a question is.
+// @property {int64} Easy - The number of users that voted easy.
+// @property {int64} Medium - The number of users that voted medium.
+// @property {int64} Hard - The number of users that voted hard.
+// @property {int64} Total - The number of users that voted.
+type Breakdown struct {
+	Easy   int64 `json:"easy"`
+	Medium int64 `json:"medium"`
+	Hard   int64 `json:"hard"`
+	Total  int64 `json:"total"`
+}
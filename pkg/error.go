@@ -6,7 +6,143 @@ import "errors"
 
 // Declaring a variable `ErrUserNotFound` and assigning it a new error instance with the message "user
 // not found" using the `errors.New()` function from the `errors` package. This variable can be used to
-// represent the specific error of a user not being found in the program.
+// represent the specific error of a user not being found in the program. The other variables represent
+// the errors shared by the remaining packages in the same way.
 var (
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrInvalidQuestionID    = errors.New("invalid question id")
+	ErrNoteNotFound         = errors.New("note not found")
+	ErrNoteTooLong          = errors.New("note is too long")
+	ErrEmptyBatch           = errors.New("no question ids given")
+	ErrBatchTooLarge        = errors.New("too many question ids in one batch")
+	ErrLastAdmin            = errors.New("cannot remove the last admin")
+	ErrQuestionNotFound     = errors.New("question not found")
+	ErrInvalidSolution      = errors.New("a solution needs a language and code")
+	ErrSolutionNotFound     = errors.New("solution not found")
+	ErrAlreadyUpvoted       = errors.New("solution already upvoted")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrSessionRevoked       = errors.New("session has been revoked or has expired")
+	ErrUnauthorized         = errors.New("missing or invalid token")
+	ErrForbidden            = errors.New("insufficient permissions")
+	ErrInvalidCredentials   = errors.New("invalid email or password")
+	ErrOTPNotApproved       = errors.New("otp code was not approved")
+	ErrRequestTimeout       = errors.New("request timed out")
+	ErrUnknownTimezone      = errors.New("unknown timezone")
+	ErrMaintenance          = errors.New("the service is under maintenance, please retry later")
+	ErrInvalidEmail         = errors.New("invalid email address")
+	ErrEmailTaken           = errors.New("email address is already in use")
+	ErrUsernameTaken        = errors.New("username is already in use")
+	ErrInvalidEmailToken    = errors.New("email verification link is invalid or has expired")
+	ErrInvalidBody          = errors.New("invalid request body")
+	ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+	ErrCommentNotFound      = errors.New("comment not found")
+	ErrCommentEmpty         = errors.New("comment must not be empty")
+	ErrCommentTooLong       = errors.New("comment is too long")
+	ErrCommentProfanity     = errors.New("comment contains inappropriate language")
+	ErrInvalidParentComment = errors.New("replies must answer a top-level comment on the same question")
+	ErrRateLimited          = errors.New("too many requests, slow down")
+	ErrInvalidSubmission    = errors.New("a submission needs a language and code")
+	ErrSubmissionNotFound   = errors.New("submission not found")
+	ErrInvalidShareToken    = errors.New("share link is invalid")
+	ErrShareLinkExpired     = errors.New("share link has expired")
+	ErrInvalidDevice        = errors.New("a device needs a platform of ios, android or web and a push token")
+	ErrDeviceNotFound       = errors.New("device not found")
+	ErrInvalidPassword      = errors.New("new password must not be empty")
+	ErrInvalidDifficulty    = errors.New("difficulty must be easy, medium or hard")
+	ErrInternal             = errors.New("internal server error")
+	ErrOTPDailyLimit        = errors.New("too many codes sent to this phone number today")
+	ErrReportNotFound       = errors.New("report not found")
+	ErrDuplicateReport      = errors.New("you already have an open report on this question")
+	ErrInvalidReportStatus  = errors.New("report status must be open or resolved")
+	ErrInvalidPhone         = errors.New("phone number must be in international format, such as +14155552671")
+	ErrPasswordExpired      = errors.New("password has expired and must be changed")
+	ErrCaptchaFailed        = errors.New("captcha verification failed")
+	ErrInvalidEmbedding     = errors.New("embedding must be a non-empty list of numbers")
+	ErrInvalidCSV           = errors.New("invalid CSV file")
+	ErrInvalidDuration      = errors.New("invalid session duration")
+	ErrInvalidIdemKey       = errors.New("idempotency key must be 1 to 255 printable characters")
+	ErrIdemKeyReused        = errors.New("idempotency key was already used for a different request")
+	ErrQuestionIDImmutable  = errors.New("the id of a question can't be changed")
+	ErrUnknownFlag          = errors.New("unknown feature flag")
+	ErrFeatureDisabled      = errors.New("this feature is currently disabled")
+	ErrInvalidRole          = errors.New("unknown role")
+	ErrOTPChannel           = errors.New("otp channel is not allowed")
 )
+
+// `errorCodes` maps each sentinel error to the machine-readable code sent in the `code` field of error
+// responses. Clients should match on these codes rather than on the messages, which are meant for
+// display and may change. Codes, once published, must not change.
+var errorCodes = []struct {
+	Err  error
+	Code string
+}{
+	{ErrUserNotFound, "USER_NOT_FOUND"},
+	{ErrInvalidQuestionID, "INVALID_QUESTION_ID"},
+	{ErrNoteNotFound, "NOTE_NOT_FOUND"},
+	{ErrNoteTooLong, "NOTE_TOO_LONG"},
+	{ErrEmptyBatch, "EMPTY_BATCH"},
+	{ErrBatchTooLarge, "BATCH_TOO_LARGE"},
+	{ErrLastAdmin, "LAST_ADMIN"},
+	{ErrQuestionNotFound, "QUESTION_NOT_FOUND"},
+	{ErrInvalidSolution, "INVALID_SOLUTION"},
+	{ErrSolutionNotFound, "SOLUTION_NOT_FOUND"},
+	{ErrAlreadyUpvoted, "ALREADY_UPVOTED"},
+	{ErrSessionNotFound, "SESSION_NOT_FOUND"},
+	{ErrSessionRevoked, "SESSION_REVOKED"},
+	{ErrUnauthorized, "UNAUTHORIZED"},
+	{ErrForbidden, "FORBIDDEN"},
+	{ErrInvalidCredentials, "INVALID_CREDENTIALS"},
+	{ErrOTPNotApproved, "OTP_NOT_APPROVED"},
+	{ErrRequestTimeout, "REQUEST_TIMEOUT"},
+	{ErrUnknownTimezone, "UNKNOWN_TIMEZONE"},
+	{ErrMaintenance, "MAINTENANCE"},
+	{ErrInvalidEmail, "INVALID_EMAIL"},
+	{ErrEmailTaken, "EMAIL_TAKEN"},
+	{ErrUsernameTaken, "USERNAME_TAKEN"},
+	{ErrInvalidEmailToken, "INVALID_EMAIL_TOKEN"},
+	{ErrInvalidBody, "INVALID_BODY"},
+	{ErrDeletionNotScheduled, "DELETION_NOT_SCHEDULED"},
+	{ErrCommentNotFound, "COMMENT_NOT_FOUND"},
+	{ErrCommentEmpty, "COMMENT_EMPTY"},
+	{ErrCommentTooLong, "COMMENT_TOO_LONG"},
+	{ErrCommentProfanity, "COMMENT_PROFANITY"},
+	{ErrInvalidParentComment, "INVALID_PARENT_COMMENT"},
+	{ErrRateLimited, "RATE_LIMITED"},
+	{ErrInvalidSubmission, "INVALID_SUBMISSION"},
+	{ErrSubmissionNotFound, "SUBMISSION_NOT_FOUND"},
+	{ErrInvalidShareToken, "INVALID_SHARE_TOKEN"},
+	{ErrShareLinkExpired, "SHARE_LINK_EXPIRED"},
+	{ErrInvalidDevice, "INVALID_DEVICE"},
+	{ErrDeviceNotFound, "DEVICE_NOT_FOUND"},
+	{ErrInvalidPassword, "INVALID_PASSWORD"},
+	{ErrInvalidDifficulty, "INVALID_DIFFICULTY"},
+	{ErrInternal, "INTERNAL_ERROR"},
+	{ErrOTPDailyLimit, "OTP_DAILY_LIMIT"},
+	{ErrReportNotFound, "REPORT_NOT_FOUND"},
+	{ErrDuplicateReport, "DUPLICATE_REPORT"},
+	{ErrInvalidReportStatus, "INVALID_REPORT_STATUS"},
+	{ErrInvalidPhone, "INVALID_PHONE"},
+	{ErrPasswordExpired, "PASSWORD_EXPIRED"},
+	{ErrCaptchaFailed, "CAPTCHA_FAILED"},
+	{ErrInvalidEmbedding, "INVALID_EMBEDDING"},
+	{ErrInvalidCSV, "INVALID_CSV"},
+	{ErrInvalidDuration, "INVALID_DURATION"},
+	{ErrInvalidIdemKey, "INVALID_IDEMPOTENCY_KEY"},
+	{ErrIdemKeyReused, "IDEMPOTENCY_KEY_REUSED"},
+	{ErrQuestionIDImmutable, "QUESTION_ID_IMMUTABLE"},
+	{ErrUnknownFlag, "UNKNOWN_FLAG"},
+	{ErrFeatureDisabled, "FEATURE_DISABLED"},
+	{ErrInvalidRole, "INVALID_ROLE"},
+	{ErrOTPChannel, "OTP_CHANNEL_NOT_ALLOWED"},
+}
+
+// The function returns the code of the sentinel error that `err` is or wraps, and an empty string for
+// any other error.
+func Code(err error) string {
+	for _, entry := range errorCodes {
+		if errors.Is(err, entry.Err) {
+			return entry.Code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCode(t *testing.T) {
+	// The codes are part of the API, so they are spelled out here rather than read from `errorCodes`.
+	documented := map[error]string{
+		ErrUserNotFound:         "USER_NOT_FOUND",
+		ErrInvalidQuestionID:    "INVALID_QUESTION_ID",
+		ErrNoteNotFound:         "NOTE_NOT_FOUND",
+		ErrNoteTooLong:          "NOTE_TOO_LONG",
+		ErrEmptyBatch:           "EMPTY_BATCH",
+		ErrBatchTooLarge:        "BATCH_TOO_LARGE",
+		ErrLastAdmin:            "LAST_ADMIN",
+		ErrQuestionNotFound:     "QUESTION_NOT_FOUND",
+		ErrInvalidSolution:      "INVALID_SOLUTION",
+		ErrSolutionNotFound:     "SOLUTION_NOT_FOUND",
+		ErrAlreadyUpvoted:       "ALREADY_UPVOTED",
+		ErrSessionNotFound:      "SESSION_NOT_FOUND",
+		ErrSessionRevoked:       "SESSION_REVOKED",
+		ErrUnauthorized:         "UNAUTHORIZED",
+		ErrForbidden:            "FORBIDDEN",
+		ErrInvalidCredentials:   "INVALID_CREDENTIALS",
+		ErrOTPNotApproved:       "OTP_NOT_APPROVED",
+		ErrRequestTimeout:       "REQUEST_TIMEOUT",
+		ErrUnknownTimezone:      "UNKNOWN_TIMEZONE",
+		ErrMaintenance:          "MAINTENANCE",
+		ErrInvalidEmail:         "INVALID_EMAIL",
+		ErrEmailTaken:           "EMAIL_TAKEN",
+		ErrUsernameTaken:        "USERNAME_TAKEN",
+		ErrInvalidEmailToken:    "INVALID_EMAIL_TOKEN",
+		ErrInvalidBody:          "INVALID_BODY",
+		ErrDeletionNotScheduled: "DELETION_NOT_SCHEDULED",
+		ErrCommentNotFound:      "COMMENT_NOT_FOUND",
+		ErrCommentEmpty:         "COMMENT_EMPTY",
+		ErrCommentTooLong:       "COMMENT_TOO_LONG",
+		ErrCommentProfanity:     "COMMENT_PROFANITY",
+		ErrInvalidParentComment: "INVALID_PARENT_COMMENT",
+		ErrRateLimited:          "RATE_LIMITED",
+		ErrInvalidSubmission:    "INVALID_SUBMISSION",
+		ErrSubmissionNotFound:   "SUBMISSION_NOT_FOUND",
+		ErrInvalidShareToken:    "INVALID_SHARE_TOKEN",
+		ErrShareLinkExpired:     "SHARE_LINK_EXPIRED",
+		ErrInvalidDevice:        "INVALID_DEVICE",
+		ErrDeviceNotFound:       "DEVICE_NOT_FOUND",
+		ErrInvalidPassword:      "INVALID_PASSWORD",
+		ErrInvalidDifficulty:    "INVALID_DIFFICULTY",
+		ErrInternal:             "INTERNAL_ERROR",
+		ErrOTPDailyLimit:        "OTP_DAILY_LIMIT",
+		ErrReportNotFound:       "REPORT_NOT_FOUND",
+		ErrDuplicateReport:      "DUPLICATE_REPORT",
+		ErrInvalidReportStatus:  "INVALID_REPORT_STATUS",
+		ErrInvalidPhone:         "INVALID_PHONE",
+		ErrPasswordExpired:      "PASSWORD_EXPIRED",
+		ErrCaptchaFailed:        "CAPTCHA_FAILED",
+		ErrInvalidEmbedding:     "INVALID_EMBEDDING",
+		ErrInvalidCSV:           "INVALID_CSV",
+		ErrInvalidDuration:      "INVALID_DURATION",
+		ErrInvalidIdemKey:       "INVALID_IDEMPOTENCY_KEY",
+		ErrIdemKeyReused:        "IDEMPOTENCY_KEY_REUSED",
+		ErrQuestionIDImmutable:  "QUESTION_ID_IMMUTABLE",
+		ErrUnknownFlag:          "UNKNOWN_FLAG",
+		ErrFeatureDisabled:      "FEATURE_DISABLED",
+		ErrInvalidRole:          "INVALID_ROLE",
+		ErrOTPChannel:           "OTP_CHANNEL_NOT_ALLOWED",
+	}
+	for err, want := range documented {
+		if got := Code(err); got != want {
+			t.Errorf("Code(%q) = %q, want %q", err, got, want)
+		}
+		if got := Code(fmt.Errorf("saving: %w", err)); got != want {
+			t.Errorf("Code of wrapped %q = %q, want %q", err, got, want)
+		}
+	}
+	if len(errorCodes) != len(documented) {
+		t.Errorf("%d sentinels have a code, but %d are documented here", len(errorCodes), len(documented))
+	}
+
+	seen := map[string]bool{}
+	for _, entry := range errorCodes {
+		if seen[entry.Code] {
+			t.Errorf("the code %s is used by more than one error", entry.Code)
+		}
+		seen[entry.Code] = true
+	}
+
+	if got := Code(errors.New("user not found")); got != "" {
+		t.Errorf("Code of an error that only has the same message = %q, want none", got)
+	}
+}
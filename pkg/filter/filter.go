@@ -0,0 +1,124 @@
+package filter
+
+import (
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The Field type is the name of a field in a stored document. Repositories declare a constant for
+// every field they filter on, so a misspelled field name is a compile error rather than a filter that
+// silently matches nothing.
+type Field string
+
+// The Builder type composes the conditions of a MongoDB query filter. Conditions on different fields
+// are combined with an implicit AND, and operator conditions on the same field, such as both bounds of
+// a range, are merged. The methods return the builder so conditions can be chained.
+// @property conditions - The filter built so far.
+type Builder struct {
+	conditions bson.M
+}
+
+// The function returns a builder for a filter without conditions, which matches every document.
+func New() *Builder {
+	return &Builder{conditions: bson.M{}}
+}
+
+// The function adds the operator condition `op` on the field, next to any other operator condition
+// on it. It replaces an equality condition on the field.
+func (b *Builder) operator(field Field, op string, value interface{}) *Builder {
+	existing, ok := b.conditions[string(field)].(bson.M)
+	if !ok {
+		existing = bson.M{}
+		b.conditions[string(field)] = existing
+	}
+	existing[op] = value
+	return b
+}
+
+// The function requires the field to equal the value. For array fields, it requires the array to
+// contain the value.
+func (b *Builder) Eq(field Field, value interface{}) *Builder {
+	b.conditions[string(field)] = value
+	return b
+}
+
+// The function requires the field to equal the value, unless the value is empty, in which case it
+// adds no condition. It suits optional filters read from requests.
+func (b *Builder) EqIfSet(field Field, value string) *Builder {
+	if value == "" {
+		return b
+	}
+	return b.Eq(field, value)
+}
+
+// The function requires the field to differ from the value.
+func (b *Builder) Ne(field Field, value interface{}) *Builder {
+	return b.operator(field, "$ne", value)
+}
+
+// The function requires the field to equal one of the values, given as a slice.
+func (b *Builder) In(field Field, values interface{}) *Builder {
+	return b.operator(field, "$in", values)
+}
+
+// The function requires the field to equal none of the values, given as a slice.
+func (b *Builder) Nin(field Field, values interface{}) *Builder {
+	return b.operator(field, "$nin", values)
+}
+
+// The function requires the array field to contain every one of the values, given as a slice.
+func (b *Builder) All(field Field, values interface{}) *Builder {
+	return b.operator(field, "$all", values)
+}
+
+// The function requires the field to match the regular expression, with the given regex options such
+// as "i".
+func (b *Builder) Regex(field Field, pattern string, options string) *Builder {
+	b.operator(field, "$regex", pattern)
+	if options != "" {
+		b.operator(field, "$options", options)
+	}
+	return b
+}
+
+// The function requires the field to contain the text, ignoring case. The text is matched literally,
+// not as a regular expression, and empty text adds no condition.
+func (b *Builder) Contains(field Field, text string) *Builder {
+	if text == "" {
+		return b
+	}
+	return b.Regex(field, regexp.QuoteMeta(text), "i")
+}
+
+// The function requires the field to be present, or absent when `exists` is false.
+func (b *Builder) Exists(field Field, exists bool) *Builder {
+	return b.operator(field, "$exists", exists)
+}
+
+// The function requires the field to be greater than the value.
+func (b *Builder) Gt(field Field, value interface{}) *Builder {
+	return b.operator(field, "$gt", value)
+}
+
+// The function requires the field to be less than the value.
+func (b *Builder) Lt(field Field, value interface{}) *Builder {
+	return b.operator(field, "$lt", value)
+}
+
+// The function requires the field to be between `min` and `max`, both included. A nil bound leaves
+// that side of the range open.
+func (b *Builder) Range(field Field, min interface{}, max interface{}) *Builder {
+	if min != nil {
+		b.operator(field, "$gte", min)
+	}
+	if max != nil {
+		b.operator(field, "$lte", max)
+	}
+	return b
+}
+
+// The function returns the filter, ready to be passed to the driver.
+func (b *Builder) Build() bson.M {
+	return b.conditions
+}
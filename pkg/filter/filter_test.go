@@ -0,0 +1,95 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestBuilder(t *testing.T) {
+	const (
+		name   Field = "name"
+		status Field = "status"
+		number Field = "id"
+		tags   Field = "tags"
+	)
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    bson.M
+	}{
+		{
+			name:    "no conditions",
+			builder: New(),
+			want:    bson.M{},
+		},
+		{
+			name:    "equality",
+			builder: New().Eq(name, "Two Sum").Eq(number, 1),
+			want:    bson.M{"name": "Two Sum", "id": 1},
+		},
+		{
+			name:    "optional equality",
+			builder: New().EqIfSet(name, "").EqIfSet(status, "published"),
+			want:    bson.M{"status": "published"},
+		},
+		{
+			name:    "set membership",
+			builder: New().In(status, bson.A{"published", nil}).Nin(tags, []string{"draft"}).All(name, []string{"a"}),
+			want:    bson.M{"status": bson.M{"$in": bson.A{"published", nil}}, "tags": bson.M{"$nin": []string{"draft"}}, "name": bson.M{"$all": []string{"a"}}},
+		},
+		{
+			name:    "operators on one field are merged",
+			builder: New().Gt(number, 10).Lt(number, 20).Ne(number, 15),
+			want:    bson.M{"id": bson.M{"$gt": 10, "$lt": 20, "$ne": 15}},
+		},
+		{
+			name:    "closed range",
+			builder: New().Range(number, 1, 5),
+			want:    bson.M{"id": bson.M{"$gte": 1, "$lte": 5}},
+		},
+		{
+			name:    "open range",
+			builder: New().Range(number, nil, 5),
+			want:    bson.M{"id": bson.M{"$lte": 5}},
+		},
+		{
+			name:    "regex",
+			builder: New().Regex(name, "^two", "").Regex(tags, "dp", "i"),
+			want:    bson.M{"name": bson.M{"$regex": "^two"}, "tags": bson.M{"$regex": "dp", "$options": "i"}},
+		},
+		{
+			name:    "contains is literal",
+			builder: New().Contains(name, "a.b*"),
+			want:    bson.M{"name": bson.M{"$regex": `a\.b\*`, "$options": "i"}},
+		},
+		{
+			name:    "contains nothing",
+			builder: New().Contains(name, ""),
+			want:    bson.M{},
+		},
+		{
+			name:    "exists",
+			builder: New().Exists(status, false),
+			want:    bson.M{"status": bson.M{"$exists": false}},
+		},
+		{
+			name:    "operator replaces equality",
+			builder: New().Eq(number, 3).Gt(number, 1),
+			want:    bson.M{"id": bson.M{"$gt": 1}},
+		},
+		{
+			name:    "equality replaces operators",
+			builder: New().Gt(number, 1).Eq(number, 3),
+			want:    bson.M{"id": 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.builder.Build(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Build() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
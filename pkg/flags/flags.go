@@ -0,0 +1,217 @@
+package flags
+
+import (
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/logging"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The names of the feature flags.
+const (
+	// Signup is whether new users can register with an email and password.
+	Signup = "signup"
+	// PhoneOTP is whether users can request and verify phone OTPs.
+	PhoneOTP = "phone_otp"
+	// WelcomeEmail is whether new users are sent a welcome email.
+	WelcomeEmail = "welcome_email"
+	// ReadOnly is whether the API is in read-only maintenance, refusing every request that changes data.
+	ReadOnly = "read_only"
+	// Maintenance is whether the API is in full maintenance, refusing every request.
+	Maintenance = "maintenance"
+)
+
+// `Defaults` holds every known flag with the value it has when the configuration doesn't set it.
+var Defaults = map[string]bool{
+	Signup:       true,
+	PhoneOTP:     true,
+	WelcomeEmail: true,
+	ReadOnly:     false,
+	Maintenance:  false,
+}
+
+// The sources a flag's value can come from, in increasing order of precedence.
+const (
+	SourceDefault  = "default"
+	SourceConfig   = "config"
+	SourceOverride = "override"
+)
+
+// The Override type is a value an admin set for a flag at runtime. It is stored in the `feature_flags`
+// collection and beats the configuration on every instance.
+// @property {string} Name - The name of the flag.
+// @property {bool} Enabled - The value of the flag.
+// @property UpdatedAt - When the override was set.
+// @property {string} UpdatedBy - The ID of the admin that set it.
+type Override struct {
+	Name      string    `json:"name" bson:"_id"`
+	Enabled   bool      `json:"enabled" bson:"enabled"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+	UpdatedBy string    `json:"updatedBy" bson:"updatedBy"`
+}
+
+// The State type is the resolved value of a flag and where it came from.
+// @property {string} Name - The name of the flag.
+// @property {bool} Enabled - The value in effect.
+// @property {string} Source - `SourceOverride`, `SourceConfig` or `SourceDefault`.
+// @property Override - The override in effect, if there is one.
+type State struct {
+	Name     string    `json:"name"`
+	Enabled  bool      `json:"enabled"`
+	Source   string    `json:"source"`
+	Override *Override `json:"override,omitempty"`
+}
+
+// The FeatureFlags type resolves the feature flags. A flag's value is, in order of precedence, the
+// override stored by an admin, the value from the configuration, or its entry in `Defaults`. Overrides
+// are read from the repository at most once per `refresh`, so a change made on another instance is
+// picked up within that interval; until the first successful read, and whenever reading fails, the
+// last known overrides are used. It is safe for concurrent use.
+// @property config - The values from the configuration, keyed by flag name.
+// @property repo - Where the overrides are stored. When nil, only the configuration is used.
+// @property refresh - How long the overrides read from `repo` are used before they are read again.
+// @property now - Returns the current time.
+// @property mu - Guards `overrides` and `loadedAt`.
+// @property overrides - The last overrides read from `repo`, keyed by flag name.
+// @property loadedAt - When `overrides` were read.
+type FeatureFlags struct {
+	config    map[string]bool
+	repo      Repository
+	refresh   time.Duration
+	now       func() time.Time
+	mu        sync.Mutex
+	overrides map[string]Override
+	loadedAt  time.Time
+}
+
+// The function returns the overrides, reading them from the repository again when they are older than
+// `refresh`.
+func (f *FeatureFlags) currentOverrides() map[string]Override {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.repo == nil || (!f.loadedAt.IsZero() && f.now().Sub(f.loadedAt) < f.refresh) {
+		return f.overrides
+	}
+	// The read time is set even when the read fails, so a database outage isn't hit on every request.
+	f.loadedAt = f.now()
+	list, err := f.repo.List()
+	if err != nil {
+		logging.Warnf("flags: could not read the overrides, using the last known ones: %v", err)
+		return f.overrides
+	}
+	overrides := make(map[string]Override, len(list))
+	for _, override := range list {
+		overrides[override.Name] = override
+	}
+	f.overrides = overrides
+	return f.overrides
+}
+
+// The function resolves the flag from the given overrides.
+func (f *FeatureFlags) resolve(name string, overrides map[string]Override) State {
+	if override, ok := overrides[name]; ok {
+		return State{Name: name, Enabled: override.Enabled, Source: SourceOverride, Override: &override}
+	}
+	if enabled, ok := f.config[name]; ok {
+		return State{Name: name, Enabled: enabled, Source: SourceConfig}
+	}
+	return State{Name: name, Enabled: Defaults[name], Source: SourceDefault}
+}
+
+// The function reports whether the flag is on. Unknown flags are off, even when an override of them
+// is left in the repository.
+func (f *FeatureFlags) Enabled(name string) bool {
+	if _, ok := Defaults[name]; !ok {
+		return false
+	}
+	return f.resolve(name, f.currentOverrides()).Enabled
+}
+
+// The function returns the state of the flag, or `pkg.ErrUnknownFlag` when there is no such flag.
+func (f *FeatureFlags) State(name string) (State, error) {
+	if _, ok := Defaults[name]; !ok {
+		return State{}, pkg.ErrUnknownFlag
+	}
+	return f.resolve(name, f.currentOverrides()), nil
+}
+
+// The function returns the state of every known flag, sorted by name.
+func (f *FeatureFlags) States() []State {
+	overrides := f.currentOverrides()
+	states := make([]State, 0, len(Defaults))
+	for name := range Defaults {
+		states = append(states, f.resolve(name, overrides))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// The function stores an override of the flag, set by the admin with the given ID, and returns the
+// flag's new state. It takes effect on this instance right away and on the others within their
+// refresh interval. It fails with `pkg.ErrUnknownFlag` when there is no such flag.
+func (f *FeatureFlags) Set(name string, enabled bool, actorID string) (State, error) {
+	if _, ok := Defaults[name]; !ok {
+		return State{}, pkg.ErrUnknownFlag
+	}
+	override := Override{Name: name, Enabled: enabled, UpdatedAt: f.now().UTC(), UpdatedBy: actorID}
+	if f.repo != nil {
+		if err := f.repo.Set(override); err != nil {
+			return State{}, err
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	overrides := make(map[string]Override, len(f.overrides)+1)
+	for key, value := range f.overrides {
+		overrides[key] = value
+	}
+	overrides[name] = override
+	f.overrides = overrides
+	return f.resolve(name, f.overrides), nil
+}
+
+// The function removes the override of the flag, so it falls back to the configuration, and returns
+// the flag's new state. It fails with `pkg.ErrUnknownFlag` when there is no such flag.
+func (f *FeatureFlags) Clear(name string) (State, error) {
+	if _, ok := Defaults[name]; !ok {
+		return State{}, pkg.ErrUnknownFlag
+	}
+	if f.repo != nil {
+		if _, err := f.repo.Delete(name); err != nil {
+			return State{}, err
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	overrides := make(map[string]Override, len(f.overrides))
+	for key, value := range f.overrides {
+		if key != name {
+			overrides[key] = value
+		}
+	}
+	f.overrides = overrides
+	return f.resolve(name, f.overrides), nil
+}
+
+// The function reports whether new users can register with an email and password.
+func (f *FeatureFlags) Signup() bool { return f.Enabled(Signup) }
+
+// The function reports whether users can request and verify phone OTPs.
+func (f *FeatureFlags) PhoneOTP() bool { return f.Enabled(PhoneOTP) }
+
+// The function reports whether new users are sent a welcome email.
+func (f *FeatureFlags) WelcomeEmail() bool { return f.Enabled(WelcomeEmail) }
+
+// The function reports whether the API refuses every request that changes data.
+func (f *FeatureFlags) ReadOnly() bool { return f.Enabled(ReadOnly) }
+
+// The function reports whether the API refuses every request.
+func (f *FeatureFlags) Maintenance() bool { return f.Enabled(Maintenance) }
+
+// The function creates the feature flags from the values in the configuration, keyed by flag name, and
+// the repository overrides are stored in, read again every `refresh`. Passing a nil repository turns
+// the overrides off.
+func New(config map[string]bool, repo Repository, refresh time.Duration) *FeatureFlags {
+	return &FeatureFlags{config: config, repo: repo, refresh: refresh, now: time.Now, overrides: map[string]Override{}}
+}
@@ -0,0 +1,172 @@
+package flags
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"testing"
+	"time"
+)
+
+// The fakeRepo type is an in-memory `Repository` counting its reads. `err` is returned by every call.
+type fakeRepo struct {
+	overrides map[string]Override
+	reads     int
+	err       error
+}
+
+func (r *fakeRepo) List() ([]Override, error) {
+	r.reads++
+	if r.err != nil {
+		return nil, r.err
+	}
+	list := make([]Override, 0, len(r.overrides))
+	for _, override := range r.overrides {
+		list = append(list, override)
+	}
+	return list, nil
+}
+
+func (r *fakeRepo) Set(override Override) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.overrides[override.Name] = override
+	return nil
+}
+
+func (r *fakeRepo) Delete(name string) (bool, error) {
+	if r.err != nil {
+		return false, r.err
+	}
+	_, ok := r.overrides[name]
+	delete(r.overrides, name)
+	return ok, nil
+}
+
+// The function returns feature flags over the given configuration and overrides, refreshed every
+// minute of the returned clock.
+func newFlags(config map[string]bool, overrides ...Override) (*FeatureFlags, *fakeRepo, *time.Time) {
+	repo := &fakeRepo{overrides: map[string]Override{}}
+	for _, override := range overrides {
+		repo.overrides[override.Name] = override
+	}
+	clock := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	f := New(config, repo, time.Minute)
+	f.now = func() time.Time { return clock }
+	return f, repo, &clock
+}
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]bool
+		override *Override
+		want     bool
+		source   string
+	}{
+		{name: "default", want: true, source: SourceDefault},
+		{name: "config beats the default", config: map[string]bool{Signup: false}, want: false, source: SourceConfig},
+		{name: "override beats the config", config: map[string]bool{Signup: false}, override: &Override{Name: Signup, Enabled: true}, want: true, source: SourceOverride},
+		{name: "override beats the default", override: &Override{Name: Signup, Enabled: false}, want: false, source: SourceOverride},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var overrides []Override
+			if tt.override != nil {
+				overrides = append(overrides, *tt.override)
+			}
+			f, _, _ := newFlags(tt.config, overrides...)
+			state, err := f.State(Signup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.Enabled != tt.want || state.Source != tt.source || f.Signup() != tt.want {
+				t.Errorf("State() = %+v, want %v from %s", state, tt.want, tt.source)
+			}
+		})
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	f := New(nil, nil, time.Minute)
+	for name, want := range Defaults {
+		if got := f.Enabled(name); got != want {
+			t.Errorf("Enabled(%q) = %v, want its default %v", name, got, want)
+		}
+	}
+	if f.ReadOnly() || f.Maintenance() {
+		t.Error("the API starts in maintenance by default")
+	}
+	if states := f.States(); len(states) != len(Defaults) || states[0].Name > states[1].Name {
+		t.Errorf("States() = %+v, want every flag sorted by name", states)
+	}
+}
+
+func TestUnknownFlag(t *testing.T) {
+	f, _, _ := newFlags(nil, Override{Name: "dark_mode", Enabled: true})
+	if f.Enabled("dark_mode") {
+		t.Error("Enabled() of an unknown flag = true, want false")
+	}
+	if _, err := f.State("dark_mode"); !errors.Is(err, pkg.ErrUnknownFlag) {
+		t.Errorf("State() = %v, want %v", err, pkg.ErrUnknownFlag)
+	}
+	if _, err := f.Set("dark_mode", true, "admin-1"); !errors.Is(err, pkg.ErrUnknownFlag) {
+		t.Errorf("Set() = %v, want %v", err, pkg.ErrUnknownFlag)
+	}
+	if _, err := f.Clear("dark_mode"); !errors.Is(err, pkg.ErrUnknownFlag) {
+		t.Errorf("Clear() = %v, want %v", err, pkg.ErrUnknownFlag)
+	}
+}
+
+func TestSetAndClear(t *testing.T) {
+	f, repo, _ := newFlags(map[string]bool{PhoneOTP: true})
+	state, err := f.Set(PhoneOTP, false, "admin-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Enabled || state.Source != SourceOverride || state.Override.UpdatedBy != "admin-1" || f.PhoneOTP() {
+		t.Errorf("Set() = %+v, want the override in effect right away", state)
+	}
+	if stored, ok := repo.overrides[PhoneOTP]; !ok || stored.Enabled {
+		t.Errorf("stored %+v, want the override saved", repo.overrides)
+	}
+
+	state, err = f.Clear(PhoneOTP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Enabled || state.Source != SourceConfig || !f.PhoneOTP() {
+		t.Errorf("Clear() = %+v, want the configuration back in effect", state)
+	}
+	if len(repo.overrides) != 0 {
+		t.Errorf("stored %+v, want the override removed", repo.overrides)
+	}
+
+	repo.err = errors.New("connection reset")
+	if _, err := f.Set(PhoneOTP, false, "admin-1"); err == nil || !f.PhoneOTP() {
+		t.Errorf("Set() = %v, want the error and the flag unchanged", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	f, repo, clock := newFlags(map[string]bool{Signup: true})
+	if !f.Signup() {
+		t.Fatal("Signup() = false, want the configuration")
+	}
+
+	// Another instance overrides the flag.
+	repo.overrides[Signup] = Override{Name: Signup, Enabled: false}
+	if !f.Signup() || repo.reads != 1 {
+		t.Errorf("Signup() = %v after %d reads, want the cached overrides used within the interval", f.Signup(), repo.reads)
+	}
+	*clock = clock.Add(time.Minute)
+	if f.Signup() || repo.reads != 2 {
+		t.Errorf("Signup() = %v after %d reads, want the override picked up after the interval", f.Signup(), repo.reads)
+	}
+
+	repo.err = errors.New("connection reset")
+	*clock = clock.Add(time.Minute)
+	if f.Signup() || f.Signup() || repo.reads != 3 {
+		t.Errorf("Signup() after %d reads, want the last known override kept and one read per interval", repo.reads)
+	}
+}
@@ -0,0 +1,77 @@
+package flags
+
+import (
+	"context"
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the Override entity.
+type Repository interface {
+	List() ([]Override, error)
+	Set(override Override) error
+	Delete(name string) (bool, error)
+}
+
+// Repo is the struct that implements the Repository interface on top of the `feature_flags` collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `List` function is a method of the `Repo` struct that implements the `Repository` interface. It
+// returns every stored override.
+func (s *Repo) List() ([]Override, error) {
+	overrides := []Override{}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, bson.M{})
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &overrides)
+	})
+	return overrides, err
+}
+
+// The `Set` function is a method of the `Repo` struct that implements the `Repository` interface. It
+// stores the override, replacing any previous one of the same flag.
+func (s *Repo) Set(override Override) error {
+	return retry.Default.Do(s.context, func() error {
+		_, err := s.db.ReplaceOne(s.context, bson.M{"_id": override.Name}, override, options.Replace().SetUpsert(true))
+		return err
+	})
+}
+
+// The `Delete` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It removes the override of the flag and reports whether there was one.
+func (s *Repo) Delete(name string) (bool, error) {
+	var result *mongo.DeleteResult
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		result, err = s.db.DeleteOne(s.context, bson.M{"_id": name})
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount == 1, nil
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `feature_flags` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("feature_flags", opts...), context: ctx}
+}
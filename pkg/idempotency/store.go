@@ -0,0 +1,107 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg/retry"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The Record type is the stored outcome of a request sent with an `Idempotency-Key` header. A retry
+// with the same key is answered with the stored response instead of being processed again.
+// @property {string} ID - The scope of the request, such as "register", and the key, joined by "|".
+// @property {string} UserID - The ID of the user the request created or acted on.
+// @property {string} Fingerprint - A digest of the request that identifies it independently of the key,
+// so a key reused for a different request can be told apart from a retry.
+// @property {int} Status - The HTTP status of the original response.
+// @property Body - The body of the original response, when it can be replayed as it is. Sign ups leave
+// it empty, since their response holds a live token; their retries are issued a fresh one instead.
+// @property CreatedAt - When the original request was processed.
+// @property ExpiresAt - When the record is removed and the key may be used again.
+type Record struct {
+	ID          string    `bson:"_id"`
+	UserID      string    `bson:"userid"`
+	Fingerprint string    `bson:"fingerprint"`
+	Status      int       `bson:"status"`
+	Body        []byte    `bson:"body"`
+	CreatedAt   time.Time `bson:"createdat"`
+	ExpiresAt   time.Time `bson:"expiresat"`
+}
+
+// The Store type keeps the outcome of requests sent with an idempotency key in the `idempotency_keys`
+// collection, so every instance answers a retry the same way. Records expire after `ttl`.
+// @property db - The `idempotency_keys` collection.
+// @property ttl - How long a key is remembered.
+// @property context - The context the database calls run with.
+type Store struct {
+	db      *mongo.Collection
+	ttl     time.Duration
+	context context.Context
+}
+
+// The function returns the ID of the record of `key` in `scope`. Scoping keeps a key sent to one
+// endpoint from replaying the response of another.
+func recordID(scope string, key string) string {
+	return scope + "|" + key
+}
+
+// The function returns the record of `key` in `scope`, and whether there is one. Records past their
+// expiry that the TTL monitor hasn't removed yet are ignored.
+func (s *Store) Find(scope string, key string, now time.Time) (Record, bool, error) {
+	var record Record
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, bson.M{"_id": recordID(scope, key), "expiresat": bson.M{"$gt": now}}).Decode(&record)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return record, false, nil
+	}
+	if err != nil {
+		return record, false, err
+	}
+	return record, true, nil
+}
+
+// The function stores the outcome of the request sent with `key` in `scope`, to be replayed until the
+// store's TTL has passed. When a record for the key already exists, which happens when two attempts
+// of the same request raced, the first one is kept.
+func (s *Store) Save(scope string, key string, record Record, now time.Time) error {
+	record.ID = recordID(scope, key)
+	record.CreatedAt = now
+	record.ExpiresAt = now.Add(s.ttl)
+	err := retry.Default.Do(s.context, func() error {
+		_, err := s.db.InsertOne(s.context, record)
+		return err
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// The function creates the TTL index that removes the records once they expire.
+func (s *Store) EnsureIndexes() error {
+	_, err := s.db.Indexes().CreateOne(s.context, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresat", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// The `WithContext` function returns a copy of the store whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Store) WithContext(ctx context.Context) *Store {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function creates a store that remembers idempotency keys for `ttl`, backed by the
+// `idempotency_keys` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewStore(db *mongo.Database, ttl time.Duration, opts ...*options.CollectionOptions) *Store {
+	return &Store{db: db.Collection("idempotency_keys", opts...), ttl: ttl, context: context.TODO()}
+}
@@ -0,0 +1,50 @@
+package idempotency
+
+import (
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	dbtest.Run(t, "saved with an expiry", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Written(1))
+		if err := NewStore(mt.DB, time.Hour).Save("register", "key-1", Record{UserID: "user-1", Status: 200, Body: []byte(`{}`)}, now); err != nil {
+			mt.Fatal(err)
+		}
+		record := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+		if record["_id"] != "register|key-1" || record["createdat"] != primitive.NewDateTimeFromTime(now) || record["expiresat"] != primitive.NewDateTimeFromTime(now.Add(time.Hour)) {
+			mt.Errorf("inserted %v, want the key in its scope, expiring an hour later", record)
+		}
+	})
+
+	dbtest.Run(t, "racing attempts keep the first record", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}))
+		if err := NewStore(mt.DB, time.Hour).Save("register", "key-1", Record{}, now); err != nil {
+			mt.Errorf("Save() = %v, want the duplicate ignored", err)
+		}
+	})
+
+	dbtest.Run(t, "found until it expires", func(mt *mtest.T) {
+		stored := Record{ID: "register|key-1", UserID: "user-1", Status: 200, Body: []byte(`{"token":"t"}`), ExpiresAt: now.Add(time.Minute)}
+		mt.AddMockResponses(dbtest.Cursor(mt, stored))
+		record, found, err := NewStore(mt.DB, time.Hour).Find("register", "key-1", now)
+		if err != nil || !found || record.UserID != "user-1" || string(record.Body) != `{"token":"t"}` {
+			mt.Errorf("Find() = %+v, %v, %v, want the stored record", record, found, err)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": "register|key-1", "expiresat": bson.M{"$gt": now}})
+	})
+
+	dbtest.Run(t, "unknown key", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if _, found, err := NewStore(mt.DB, time.Hour).Find("register", "key-2", now); err != nil || found {
+			mt.Errorf("Find() = %v, %v, want nothing found", found, err)
+		}
+	})
+}
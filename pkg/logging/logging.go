@@ -0,0 +1,168 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The Level type is the severity of a log record. Records below the level of a logger are dropped.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// `levelNames` are the names of the levels, as accepted by `ParseLevel` and written in the records.
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// The function returns the name of the level, such as "info".
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// The function returns the level with the given name: "debug", "info", "warn" or "error", ignoring case.
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(strings.TrimSpace(name), levelName) {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("LOG_LEVEL must be %q, %q, %q or %q, got %q", "debug", "info", "warn", "error", name)
+}
+
+// The formats records can be written in: one JSON object per line, for log collectors, or a plain line
+// for reading in a terminal.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// The type record is a log record in the JSON format.
+type record struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Message string `json:"msg"`
+}
+
+// The Logger type writes leveled log records. It is safe for concurrent use.
+// @property mu - Serializes the writes, so records from different goroutines don't interleave.
+// @property out - Where the records are written.
+// @property {Level} level - The lowest level that is written.
+// @property {string} format - `FormatJSON` or `FormatConsole`.
+// @property now - Returns the time records are stamped with.
+type Logger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	level  Level
+	format string
+	now    func() time.Time
+}
+
+// The function creates a logger that writes the records of `level` and above to `out`, in `format`.
+func New(out io.Writer, level Level, format string) (*Logger, error) {
+	if format != FormatJSON && format != FormatConsole {
+		return nil, fmt.Errorf("LOG_FORMAT must be %q or %q, got %q", FormatJSON, FormatConsole, format)
+	}
+	return &Logger{out: out, level: level, format: format, now: time.Now}, nil
+}
+
+// The function reports whether records of the level are written. It lets callers skip building
+// expensive messages that would be dropped.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// The function writes a record of the level, with the message formatted like `fmt.Sprintf`, unless
+// the level is below the logger's. Sensitive values in the message are masked by `Redact`.
+func (l *Logger) Logf(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	message := Redact(fmt.Sprintf(format, args...))
+	now := l.now().UTC().Format(time.RFC3339Nano)
+	var line []byte
+	if l.format == FormatJSON {
+		line, _ = json.Marshal(record{Time: now, Level: level.String(), Message: message})
+	} else {
+		line = []byte(fmt.Sprintf("%s %-5s %s", now, strings.ToUpper(level.String()), message))
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(append(line, '\n'))
+}
+
+// The function writes a debug record.
+func (l *Logger) Debugf(format string, args ...interface{}) { l.Logf(LevelDebug, format, args...) }
+
+// The function writes an info record.
+func (l *Logger) Infof(format string, args ...interface{}) { l.Logf(LevelInfo, format, args...) }
+
+// The function writes a warn record.
+func (l *Logger) Warnf(format string, args ...interface{}) { l.Logf(LevelWarn, format, args...) }
+
+// The function writes an error record.
+func (l *Logger) Errorf(format string, args ...interface{}) { l.Logf(LevelError, format, args...) }
+
+// The type levelWriter is an io.Writer that writes every line it is given as a record of one level.
+type levelWriter struct {
+	logger *Logger
+	level  Level
+}
+
+// The `Write` function is a method of the `levelWriter` struct that implements the `io.Writer`
+// interface.
+func (w levelWriter) Write(p []byte) (int, error) {
+	w.logger.Logf(w.level, "%s", strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// The function returns a writer that writes each line as a record of the level. Passing it to
+// `log.SetOutput` routes the standard logger, as used by libraries, through this logger.
+func (l *Logger) Writer(level Level) io.Writer {
+	return levelWriter{logger: l, level: level}
+}
+
+// `std` is the logger used by the package-level functions. Until `SetDefault` is called it writes info
+// records and above to standard error in the console format, which covers failures while loading the
+// configuration.
+var std = &Logger{out: os.Stderr, level: LevelInfo, format: FormatConsole, now: time.Now}
+
+// The function makes `logger` the one used by the package-level functions.
+func SetDefault(logger *Logger) {
+	std = logger
+}
+
+// The function reports whether the default logger writes records of the level.
+func Enabled(level Level) bool { return std.Enabled(level) }
+
+// The function writes a debug record with the default logger.
+func Debugf(format string, args ...interface{}) { std.Debugf(format, args...) }
+
+// The function writes an info record with the default logger.
+func Infof(format string, args ...interface{}) { std.Infof(format, args...) }
+
+// The function writes a warn record with the default logger.
+func Warnf(format string, args ...interface{}) { std.Warnf(format, args...) }
+
+// The function writes an error record with the default logger.
+func Errorf(format string, args ...interface{}) { std.Errorf(format, args...) }
+
+// The function writes an error record with the default logger and exits with status 1. It is meant
+// for configuration mistakes found at startup.
+func Fatalf(format string, args ...interface{}) {
+	std.Errorf(format, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The function returns a logger writing to a buffer, with a fixed clock.
+func bufferLogger(t *testing.T, level Level, format string) (*Logger, *bytes.Buffer) {
+	t.Helper()
+	var out bytes.Buffer
+	logger, err := New(&out, level, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return logger, &out
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]Level{"debug": LevelDebug, "INFO": LevelInfo, " warn ": LevelWarn, "Error": LevelError} {
+		if got, err := ParseLevel(name); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"", "trace", "fatal"} {
+		if _, err := ParseLevel(name); err == nil {
+			t.Errorf("ParseLevel(%q) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, LevelInfo, "text"); err == nil {
+		t.Error("New() with an unknown format succeeded, want an error")
+	}
+}
+
+func TestLevels(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatConsole} {
+		t.Run("debug suppressed at info in "+format, func(t *testing.T) {
+			logger, out := bufferLogger(t, LevelInfo, format)
+			logger.Debugf("cache %s missed", "questions")
+			if out.Len() != 0 {
+				t.Fatalf("logged %q, want the debug message suppressed", out.String())
+			}
+			if logger.Enabled(LevelDebug) {
+				t.Error("Enabled(LevelDebug) = true at info level")
+			}
+			logger.Infof("listening on %d", 8080)
+			logger.Errorf("database unreachable")
+			if lines := strings.Count(out.String(), "\n"); lines != 2 {
+				t.Errorf("logged %q, want the info and error records", out.String())
+			}
+		})
+
+		t.Run("debug written at debug in "+format, func(t *testing.T) {
+			logger, out := bufferLogger(t, LevelDebug, format)
+			logger.Debugf("cache %s missed", "questions")
+			if !strings.Contains(out.String(), "cache questions missed") {
+				t.Errorf("logged %q, want the debug message", out.String())
+			}
+		})
+	}
+}
+
+func TestFormats(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		logger, out := bufferLogger(t, LevelInfo, FormatJSON)
+		logger.Warnf("slow query: %dms", 250)
+		var got record
+		if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+			t.Fatalf("logged %q, want a JSON record: %v", out.String(), err)
+		}
+		if want := (record{Time: "2024-01-02T03:04:05Z", Level: "warn", Message: "slow query: 250ms"}); got != want {
+			t.Errorf("record = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("console", func(t *testing.T) {
+		logger, out := bufferLogger(t, LevelInfo, FormatConsole)
+		logger.Warnf("slow query: %dms", 250)
+		if want := "2024-01-02T03:04:05Z WARN  slow query: 250ms\n"; out.String() != want {
+			t.Errorf("logged %q, want %q", out.String(), want)
+		}
+	})
+}
+
+func TestSensitiveValuesAtEveryLevel(t *testing.T) {
+	AddSecrets("AC0123456789abcdef")
+	t.Cleanup(func() { secrets = nil })
+	for _, level := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError} {
+		t.Run(level.String(), func(t *testing.T) {
+			logger, out := bufferLogger(t, LevelDebug, FormatJSON)
+			logger.Logf(level, `body: {"email":"ada@example.com","password":"hunter22"} token=abc.def twilio AC0123456789abcdef`)
+			for _, secret := range []string{"hunter22", "abc.def", "AC0123456789abcdef"} {
+				if strings.Contains(out.String(), secret) {
+					t.Errorf("logged %q, want %q masked", out.String(), secret)
+				}
+			}
+			if !strings.Contains(out.String(), "ada@example.com") {
+				t.Errorf("logged %q, want the email kept", out.String())
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	logger, out := bufferLogger(t, LevelWarn, FormatConsole)
+	logger.Writer(LevelInfo).Write([]byte("dropped\n"))
+	logger.Writer(LevelError).Write([]byte("kept\n"))
+	if got := out.String(); strings.Contains(got, "dropped") || !strings.Contains(got, "ERROR kept\n") {
+		t.Errorf("logged %q, want only the error line", got)
+	}
+}
@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// `Mask` replaces the redacted values.
+const Mask = "[REDACTED]"
+
+// `keyValuePattern` matches a key and its value in JSON (`"password":"..."`), form and query strings
+// (`password=...`) and error messages (`password: ...`). The value is either a quoted string or runs
+// to the next separator; values that start with a bracket, such as an array or an already masked value,
+// aren't matched.
+var keyValuePattern = regexp.MustCompile(`("?)([A-Za-z][A-Za-z0-9_.-]*)("?[ \t]*[:=][ \t]*)("(?:[^"\\]|\\.)*"|[^\s&,;}\]"\[][^\s&,;}\]"]*)`)
+
+// `bearerPattern` matches the credentials of an `Authorization: Bearer ...` header.
+var bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/-]+=*`)
+
+// `sensitiveKeys` are the keys whose value is always redacted, once lowercased and stripped of
+// separators. `sensitiveKeyParts` are redacted wherever they appear in a key, such as in
+// `newPassword`, `refreshToken` or `TWILIO_AUTHTOKEN`.
+var (
+	sensitiveKeys     = []string{"code", "authorization"}
+	sensitiveKeyParts = []string{"password", "token", "secret", "pepper", "accountsid", "servicesid"}
+)
+
+// `secrets` are the configured credentials, such as the Twilio auth token, which are redacted wherever
+// they appear, whatever they are called.
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// The function reports whether the value of the key must be redacted.
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(strings.NewReplacer("_", "", "-", "", ".", "").Replace(key))
+	for _, sensitive := range sensitiveKeys {
+		if key == sensitive {
+			return true
+		}
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// The function registers credentials, such as the Twilio account SID and auth token, to be redacted
+// wherever they appear. Empty values are ignored.
+func AddSecrets(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, value := range values {
+		if value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+}
+
+// The function returns the text with sensitive values replaced by `Mask`: the values of passwords,
+// OTP codes, tokens, secrets and Twilio credentials, whether written as JSON, form or query fields or
+// `key: value` pairs in an error message, bearer tokens, and the credentials registered with
+// `AddSecrets`. It is applied to every log record and every error message sent to clients, so request
+// bodies and errors that echo them can be logged safely.
+func Redact(text string) string {
+	secretsMu.RLock()
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, Mask)
+	}
+	secretsMu.RUnlock()
+	text = bearerPattern.ReplaceAllString(text, "${1}"+Mask)
+	return keyValuePattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := keyValuePattern.FindStringSubmatch(match)
+		if !sensitiveKey(parts[2]) {
+			return match
+		}
+		value := Mask
+		if strings.HasPrefix(parts[4], `"`) {
+			value = `"` + Mask + `"`
+		}
+		return parts[1] + parts[2] + parts[3] + value
+	})
+}
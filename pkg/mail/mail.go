@@ -0,0 +1,32 @@
+package mail
+
+import "sigmacoder/pkg/logging"
+
+// The Message type is a plain text email.
+// @property {string} To - The address the email is sent to.
+// @property {string} Subject - The subject line.
+// @property {string} Body - The plain text body.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// The Sender interface delivers emails. Implementations decide how, so the services that send email
+// don't depend on a particular provider.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// The LogSender type is a Sender that writes every email to the log instead of delivering it. It is
+// meant for local development, where links in emails can be copied from the server output. Emails carry
+// tokens, such as the link confirming an email change, so their bodies are only logged at debug level,
+// with the tokens masked.
+type LogSender struct{}
+
+// The `Send` method logs the message.
+func (LogSender) Send(msg Message) error {
+	logging.Infof("mail: to=%s subject=%q", msg.To, msg.Subject)
+	logging.Debugf("mail: body of the email to %s:\n%s", msg.To, msg.Body)
+	return nil
+}
@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+// The SMTPSender type is a Sender that delivers emails through an SMTP server.
+// @property {string} addr - The host and port of the server.
+// @property auth - The credentials for the server, or nil when it doesn't require any.
+// @property {string} from - The address the emails are sent from.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// The `Send` method delivers the message as a plain text email.
+func (s *SMTPSender) Send(msg Message) error {
+	var body strings.Builder
+	body.WriteString("From: " + s.from + "\r\n")
+	body.WriteString("To: " + msg.To + "\r\n")
+	body.WriteString("Subject: " + msg.Subject + "\r\n")
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, []byte(body.String()))
+}
+
+// The function creates a sender for the SMTP server at `host` and `port`. The server is logged into
+// with `username` and `password` when a username is given.
+func NewSMTPSender(host string, port int, username string, password string, from string) *SMTPSender {
+	sender := &SMTPSender{addr: net.JoinHostPort(host, strconv.Itoa(port)), from: from}
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sender
+}
@@ -0,0 +1,23 @@
+package notes
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Note type is a user's private note on a question. Each user has at most one note per question.
+// @property ID - The ObjectID of the note.
+// @property {string} UserID - The ID of the user that owns the note.
+// @property QuestionID - The ObjectID of the question the note is about.
+// @property {string} Content - The text of the note.
+// @property CreatedAt - When the note was first saved.
+// @property UpdatedAt - When the note was last changed.
+type Note struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID     string             `json:"userId" bson:"userid"`
+	QuestionID primitive.ObjectID `json:"questionId" bson:"questionid"`
+	Content    string             `json:"content" bson:"content"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
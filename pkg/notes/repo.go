@@ -0,0 +1,94 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the Note entity.
+type Repository interface {
+	Upsert(userID string, questionID primitive.ObjectID, content string) (Note, error)
+	Read(userID string, questionID primitive.ObjectID) (Note, error)
+	ListByUser(userID string) ([]Note, error)
+}
+
+// Repo is the struct that implements the Repository interface on top of the `notes` collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `Upsert` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It stores the user's note on the question, creating it on the first save and replacing its content
+// afterwards, and returns the stored note.
+func (s *Repo) Upsert(userID string, questionID primitive.ObjectID, content string) (Note, error) {
+	var note Note
+	now := pkg.NowUTC()
+	filter := bson.M{"userid": userID, "questionid": questionID}
+	update := bson.M{
+		"$set":         bson.M{"content": content, "updatedAt": now},
+		"$setOnInsert": bson.M{"createdAt": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOneAndUpdate(s.context, filter, update, opts).Decode(&note)
+	})
+	if err != nil {
+		return note, err
+	}
+	return note, nil
+}
+
+// The `Read` function is a method of the `Repo` struct that implements the `Repository` interface. It
+// returns the user's note on the question, or `pkg.ErrNoteNotFound` when there is none.
+func (s *Repo) Read(userID string, questionID primitive.ObjectID) (Note, error) {
+	var note Note
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, bson.M{"userid": userID, "questionid": questionID}).Decode(&note)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return note, pkg.ErrNoteNotFound
+	}
+	if err != nil {
+		return note, err
+	}
+	return note, nil
+}
+
+// The `ListByUser` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns all of the user's notes, most recently updated first.
+func (s *Repo) ListByUser(userID string) ([]Note, error) {
+	list := []Note{}
+	opts := options.Find().SetSort(bson.M{"updatedAt": -1})
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, bson.M{"userid": userID}, opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &list)
+	})
+	return list, err
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `notes` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("notes", opts...), context: ctx}
+}
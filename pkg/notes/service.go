@@ -0,0 +1,69 @@
+package notes
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Service interface defines the note operations available to the HTTP handlers.
+// @property Save - Save stores the user's note on a question, replacing any previous one. It fails with
+// `pkg.ErrNoteTooLong` when the content exceeds the configured maximum length.
+// @property Get - Get returns the user's note on a question, or `pkg.ErrNoteNotFound`.
+// @property WithContext - WithContext returns a copy of the service whose repository calls run with the
+// given context.
+type Service interface {
+	Save(userID string, questionID string, content string) (Note, error)
+	Get(userID string, questionID string) (Note, error)
+	WithContext(ctx context.Context) Service
+}
+
+// The type Svc implements the Service interface.
+// @property repo - The repository notes are stored in.
+// @property maxLength - The maximum number of characters a note may contain.
+type Svc struct {
+	repo      *Repo
+	maxLength int
+}
+
+// The `Save` function is a method of the `Svc` struct that implements the `Save` method of the
+// `Service` interface.
+func (s *Svc) Save(userID string, questionID string, content string) (Note, error) {
+	oid, err := primitive.ObjectIDFromHex(questionID)
+	if err != nil {
+		return Note{}, pkg.ErrInvalidQuestionID
+	}
+	if utf8.RuneCountInString(content) > s.maxLength {
+		return Note{}, pkg.ErrNoteTooLong
+	}
+	return s.repo.Upsert(userID, oid, content)
+}
+
+// The `Get` function is a method of the `Svc` struct that implements the `Get` method of the `Service`
+// interface.
+func (s *Svc) Get(userID string, questionID string) (Note, error) {
+	oid, err := primitive.ObjectIDFromHex(questionID)
+	if err != nil {
+		return Note{}, pkg.ErrInvalidQuestionID
+	}
+	return s.repo.Read(userID, oid)
+}
+
+// The `WithContext` function is a method of the `Svc` struct that implements the `WithContext` method
+// of the `Service` interface. It returns a copy of the service bound to the given context.
+func (s *Svc) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.repo = s.repo.WithContext(ctx)
+	return &clone
+}
+
+// The function creates a new instance of the notes service with a given repository and maximum note
+// length in characters.
+func NewNotesService(repo *Repo, maxLength int) Service {
+	return &Svc{
+		repo:      repo,
+		maxLength: maxLength,
+	}
+}
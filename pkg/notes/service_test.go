@@ -0,0 +1,94 @@
+package notes
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns a notes service, capping notes at `maxLength` characters, whose repository
+// talks to the mocked deployment.
+func newService(mt *mtest.T, maxLength int) Service {
+	return NewNotesService(NewRepo(mt.DB).(*Repo), maxLength)
+}
+
+func TestSave(t *testing.T) {
+	questionID := primitive.NewObjectID()
+
+	for _, test := range []struct{ name, content string }{{"create", "first try: two pointers"}, {"update", "use a hash map instead"}} {
+		dbtest.Run(t, test.name, func(mt *mtest.T) {
+			stored := Note{ID: primitive.NewObjectID(), UserID: "user-1", QuestionID: questionID, Content: test.content, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+			mt.AddMockResponses(dbtest.Value(mt, stored))
+			note, err := newService(mt, 20000).Save("user-1", questionID.Hex(), test.content)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if note.Content != test.content || note.UserID != "user-1" {
+				mt.Errorf("Save() = %+v, want the stored note", note)
+			}
+			// Both the first save and later ones are a single upsert keyed on the user and the question, so
+			// a user never gets two notes on the same question.
+			command := dbtest.NextCommand(mt, "findAndModify")
+			dbtest.ExpectField(mt, command, "query", bson.M{"userid": "user-1", "questionid": questionID})
+			dbtest.ExpectField(mt, command, "upsert", true)
+			dbtest.ExpectField(mt, command, "new", true)
+			update := command["update"].(bson.M)
+			if set := update["$set"].(bson.M); set["content"] != test.content {
+				mt.Errorf("$set = %v, want the content", set)
+			}
+			if _, ok := update["$setOnInsert"].(bson.M)["createdAt"]; !ok {
+				mt.Errorf("update = %v, want createdAt only set on insert", update)
+			}
+		})
+	}
+
+	dbtest.Run(t, "at the length cap", func(mt *mtest.T) {
+		content := strings.Repeat("é", 10)
+		mt.AddMockResponses(dbtest.Value(mt, Note{Content: content}))
+		if _, err := newService(mt, 10).Save("user-1", questionID.Hex(), content); err != nil {
+			mt.Errorf("Save() of a note of exactly the maximum length = %v, want no error", err)
+		}
+	})
+
+	dbtest.Run(t, "over the length cap", func(mt *mtest.T) {
+		_, err := newService(mt, 10).Save("user-1", questionID.Hex(), strings.Repeat("a", 11))
+		if !errors.Is(err, pkg.ErrNoteTooLong) {
+			mt.Errorf("Save() = %v, want %v", err, pkg.ErrNoteTooLong)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "invalid question ID", func(mt *mtest.T) {
+		if _, err := newService(mt, 10).Save("user-1", "not-an-id", "note"); !errors.Is(err, pkg.ErrInvalidQuestionID) {
+			mt.Errorf("Save() = %v, want %v", err, pkg.ErrInvalidQuestionID)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestGet(t *testing.T) {
+	questionID := primitive.NewObjectID()
+
+	dbtest.Run(t, "own note", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, Note{UserID: "user-1", QuestionID: questionID, Content: "note"}))
+		note, err := newService(mt, 10).Get("user-1", questionID.Hex())
+		if err != nil || note.Content != "note" {
+			mt.Errorf("Get() = %+v, %v, want the note", note, err)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"userid": "user-1", "questionid": questionID})
+	})
+
+	dbtest.Run(t, "no note", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if _, err := newService(mt, 10).Get("user-2", questionID.Hex()); !errors.Is(err, pkg.ErrNoteNotFound) {
+			mt.Errorf("Get() = %v, want %v", err, pkg.ErrNoteNotFound)
+		}
+	})
+}
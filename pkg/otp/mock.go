@@ -0,0 +1,99 @@
+package otp
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/logging"
+	"strings"
+	"sync"
+)
+
+// `defaultMockCodeLength` is the number of digits of the mock codes when no length is configured.
+const defaultMockCodeLength = 6
+
+// The type `mockCode` is a code the mock provider "sent" and hasn't been verified yet.
+type mockCode struct {
+	code    string
+	channel string
+}
+
+// The MockProvider type stands in for a real provider during local development. It doesn't deliver
+// anything: it writes each code to the log at debug level instead, and checks codes against the ones
+// it logged. Codes are kept in memory and can be verified once.
+// @property mu - Guards `codes`.
+// @property codes - The codes waiting to be verified, keyed by phone number.
+// @property {int} length - The number of digits of the codes.
+// @property {string} fixed - The code every number is "sent", instead of a random one, when it is set.
+type MockProvider struct {
+	mu     sync.Mutex
+	codes  map[string]mockCode
+	length int
+	fixed  string
+}
+
+// The function returns the fixed code when there is one, and otherwise a random code of the configured
+// number of digits.
+func (p *MockProvider) newCode() (string, error) {
+	if p.fixed != "" {
+		return p.fixed, nil
+	}
+	var code strings.Builder
+	for i := 0; i < p.length; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code.WriteString(digit.String())
+	}
+	return code.String(), nil
+}
+
+// The `Send` function is a method of the `MockProvider` struct that implements the `Provider`
+// interface. A new code replaces the one sent to the same number before.
+func (p *MockProvider) Send(ctx context.Context, phoneNumber string, channel string, deviceIP string) error {
+	code, err := p.newCode()
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.codes[phoneNumber] = mockCode{code: code, channel: channel}
+	p.mu.Unlock()
+	logging.Debugf("otp: mock %s code for %s is %s", channel, phoneNumber, code)
+	return nil
+}
+
+// The `Verify` function is a method of the `MockProvider` struct that implements the `Provider`
+// interface.
+func (p *MockProvider) Verify(ctx context.Context, phoneNumber string, code string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sent, ok := p.codes[phoneNumber]
+	if !ok {
+		return UnknownChannel, pkg.ErrOTPNotApproved
+	}
+	if sent.code != code {
+		return sent.channel, pkg.ErrOTPNotApproved
+	}
+	delete(p.codes, phoneNumber)
+	return sent.channel, nil
+}
+
+// The function creates a mock provider whose codes have `length` digits, or 6 when `length` is 0.
+func NewMockProvider(length int) *MockProvider {
+	if length <= 0 {
+		length = defaultMockCodeLength
+	}
+	return &MockProvider{codes: map[string]mockCode{}, length: length}
+}
+
+// The function creates a mock provider that "sends" the same code to every number, so automated tests
+// know it in advance. The code must have between 4 and 10 digits, like the ones Twilio sends.
+func NewFixedCodeProvider(code string) (*MockProvider, error) {
+	if len(code) < minCodeLength || len(code) > maxCodeLength || strings.Trim(code, "0123456789") != "" {
+		return nil, fmt.Errorf("otp test code must have between %d and %d digits", minCodeLength, maxCodeLength)
+	}
+	return &MockProvider{codes: map[string]mockCode{}, length: len(code), fixed: code}, nil
+}
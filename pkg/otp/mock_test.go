@@ -0,0 +1,70 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"strings"
+	"testing"
+)
+
+func TestMockProvider(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("random code", func(t *testing.T) {
+		provider := NewMockProvider(8)
+		if err := provider.Send(ctx, "+15551234567", "whatsapp", ""); err != nil {
+			t.Fatal(err)
+		}
+		code := provider.codes["+15551234567"].code
+		if len(code) != 8 || strings.Trim(code, "0123456789") != "" {
+			t.Fatalf("code = %q, want 8 digits", code)
+		}
+		if channel, err := provider.Verify(ctx, "+15551234567", code); err != nil || channel != "whatsapp" {
+			t.Errorf("Verify() = %q, %v, want the channel the code was sent over", channel, err)
+		}
+	})
+
+	t.Run("default length", func(t *testing.T) {
+		provider := NewMockProvider(0)
+		if err := provider.Send(ctx, "+15551234567", "sms", ""); err != nil {
+			t.Fatal(err)
+		}
+		if code := provider.codes["+15551234567"].code; len(code) != defaultMockCodeLength {
+			t.Errorf("code = %q, want %d digits", code, defaultMockCodeLength)
+		}
+	})
+
+	t.Run("codes are checked and used once", func(t *testing.T) {
+		provider, err := NewFixedCodeProvider("424242")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := provider.Verify(ctx, "+15551234567", "424242"); !errors.Is(err, pkg.ErrOTPNotApproved) {
+			t.Errorf("Verify() before a send = %v, want %v", err, pkg.ErrOTPNotApproved)
+		}
+		if err := provider.Send(ctx, "+15551234567", "sms", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := provider.Verify(ctx, "+15551234567", "000000"); !errors.Is(err, pkg.ErrOTPNotApproved) {
+			t.Errorf("Verify() of a wrong code = %v, want %v", err, pkg.ErrOTPNotApproved)
+		}
+		if _, err := provider.Verify(ctx, "+15557654321", "424242"); !errors.Is(err, pkg.ErrOTPNotApproved) {
+			t.Errorf("Verify() for another number = %v, want %v", err, pkg.ErrOTPNotApproved)
+		}
+		if _, err := provider.Verify(ctx, "+15551234567", "424242"); err != nil {
+			t.Errorf("Verify() of the code after a wrong attempt = %v, want it accepted", err)
+		}
+		if _, err := provider.Verify(ctx, "+15551234567", "424242"); !errors.Is(err, pkg.ErrOTPNotApproved) {
+			t.Errorf("Verify() of a used code = %v, want %v", err, pkg.ErrOTPNotApproved)
+		}
+	})
+
+	for _, code := range []string{"123", "12345678901", "12ab56", ""} {
+		t.Run("invalid fixed code "+code, func(t *testing.T) {
+			if _, err := NewFixedCodeProvider(code); err == nil {
+				t.Errorf("NewFixedCodeProvider(%q) = nil error, want one", code)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package otp
+
+import "context"
+
+// `UnknownChannel` labels verifications whose channel the provider didn't report, such as the ones
+// that failed before reaching it.
+const UnknownChannel = "unknown"
+
+// The Provider interface delivers and checks one-time passwords sent to phone numbers. Both methods
+// give up once `ctx` is done, returning its error, so a slow provider can't hold a request past its
+// deadline.
+// @property Send - Send delivers a new code to the phone number over the channel, such as "sms".
+// `deviceIP` is the client's IP, which providers may use for fraud checks; it may be empty.
+// @property Verify - Verify checks a code the user entered and returns the channel it was sent over,
+// or `UnknownChannel`. It returns `pkg.ErrOTPNotApproved` when the code is wrong or has expired.
+type Provider interface {
+	Send(ctx context.Context, phoneNumber string, channel string, deviceIP string) error
+	Verify(ctx context.Context, phoneNumber string, code string) (string, error)
+}
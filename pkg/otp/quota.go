@@ -0,0 +1,81 @@
+package otp
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/retry"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The DailyQuota type caps how many codes are sent to a phone number per UTC day, to bound the
+// provider's bill. The counts are stored in the `otp_sends` collection, one document per number and
+// day, so every instance shares them. The documents expire at the end of their day.
+// @property db - The `otp_sends` collection.
+// @property limit - The number of codes a phone number may be sent per day. 0 disables the cap.
+// @property context - The context the database calls run with.
+type DailyQuota struct {
+	db      *mongo.Collection
+	limit   int
+	context context.Context
+}
+
+// The function returns the start of the UTC day after the one `now` falls on, when the quota resets.
+func nextReset(now time.Time) time.Time {
+	year, month, day := now.UTC().Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+}
+
+// The function counts a code sent to the phone number at `now` and returns when its quota resets. When
+// the number has already been sent `limit` codes that day, it returns `pkg.ErrOTPDailyLimit` and the
+// code must not be sent. Every call counts, so refused requests can't be retried until the next day.
+func (q *DailyQuota) Take(phoneNumber string, now time.Time) (time.Time, error) {
+	resetAt := nextReset(now)
+	if q.limit <= 0 {
+		return resetAt, nil
+	}
+	var result struct {
+		Count int `bson:"count"`
+	}
+	err := retry.Default.Do(q.context, func() error {
+		return q.db.FindOneAndUpdate(q.context,
+			bson.M{"_id": phoneNumber + "|" + now.UTC().Format("2006-01-02")},
+			bson.M{"$inc": bson.M{"count": 1}, "$setOnInsert": bson.M{"expiresat": resetAt}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&result)
+	})
+	if err != nil {
+		return resetAt, err
+	}
+	if result.Count > q.limit {
+		return resetAt, pkg.ErrOTPDailyLimit
+	}
+	return resetAt, nil
+}
+
+// The function creates the TTL index that removes the counts of past days.
+func (q *DailyQuota) EnsureIndexes() error {
+	_, err := q.db.Indexes().CreateOne(q.context, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresat", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// The `WithContext` function returns a copy of the quota whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (q *DailyQuota) WithContext(ctx context.Context) *DailyQuota {
+	clone := *q
+	clone.context = ctx
+	return &clone
+}
+
+// The function creates a quota allowing `limit` codes per phone number and UTC day, backed by the
+// `otp_sends` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewDailyQuota(db *mongo.Database, limit int, opts ...*options.CollectionOptions) *DailyQuota {
+	return &DailyQuota{db: db.Collection("otp_sends", opts...), limit: limit, context: context.TODO()}
+}
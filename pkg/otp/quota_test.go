@@ -0,0 +1,61 @@
+package otp
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestDailyQuota(t *testing.T) {
+	lateEvening := time.Date(2024, 3, 1, 23, 59, 59, 0, time.UTC)
+	midnight := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		now     time.Time
+		count   int
+		wantErr error
+		day     string
+		resetAt time.Time
+	}{
+		{name: "below the limit", now: lateEvening, count: 2, day: "2024-03-01", resetAt: midnight},
+		{name: "at the limit", now: lateEvening, count: 3, day: "2024-03-01", resetAt: midnight},
+		{name: "over the limit", now: lateEvening, count: 4, wantErr: pkg.ErrOTPDailyLimit, day: "2024-03-01", resetAt: midnight},
+		{name: "after the reset", now: midnight, count: 1, day: "2024-03-02", resetAt: midnight.AddDate(0, 0, 1)},
+		{
+			name:    "days are UTC days",
+			now:     time.Date(2024, 3, 2, 1, 30, 0, 0, time.FixedZone("IST", 5*3600+1800)),
+			count:   1,
+			day:     "2024-03-01",
+			resetAt: midnight,
+		},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			quota := NewDailyQuota(mt.DB, 3)
+			mt.AddMockResponses(dbtest.Value(mt, bson.M{"_id": "+15551234567|" + tt.day, "count": tt.count}))
+			resetAt, err := quota.Take("+15551234567", tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				mt.Errorf("Take() = %v, want %v", err, tt.wantErr)
+			}
+			if !resetAt.Equal(tt.resetAt) {
+				mt.Errorf("reset at %s, want %s", resetAt, tt.resetAt)
+			}
+			command := dbtest.NextCommand(mt, "findAndModify")
+			dbtest.ExpectField(mt, command, "query", bson.M{"_id": "+15551234567|" + tt.day})
+			dbtest.ExpectField(mt, command, "update", bson.M{"$inc": bson.M{"count": 1}, "$setOnInsert": bson.M{"expiresat": tt.resetAt}})
+			dbtest.ExpectField(mt, command, "upsert", true)
+		})
+	}
+
+	dbtest.Run(t, "no limit", func(mt *mtest.T) {
+		if _, err := NewDailyQuota(mt.DB, 0).Take("+15551234567", lateEvening); err != nil {
+			mt.Errorf("Take() = %v, want no limit", err)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
@@ -0,0 +1,67 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// The TestNumberProvider type lets automated end-to-end tests complete the phone OTP flow without
+// receiving an SMS. Phone numbers that start with `prefix` are handled by a provider with a fixed,
+// known code and never reach the real provider; every other number goes to the real provider as
+// usual.
+// @property {string} prefix - The start of the test numbers, in international format such as "+1555".
+// It is never empty, so real numbers keep reaching the real provider.
+// @property test - The provider test numbers are sent the fixed code by.
+// @property real - The provider every other number is handled by.
+type TestNumberProvider struct {
+	prefix string
+	test   Provider
+	real   Provider
+}
+
+// The function returns the provider that handles the phone number.
+func (p *TestNumberProvider) route(phoneNumber string) Provider {
+	if strings.HasPrefix(phoneNumber, p.prefix) {
+		return p.test
+	}
+	return p.real
+}
+
+// The `Send` function is a method of the `TestNumberProvider` struct that implements the `Provider`
+// interface.
+func (p *TestNumberProvider) Send(ctx context.Context, phoneNumber string, channel string, deviceIP string) error {
+	return p.route(phoneNumber).Send(ctx, phoneNumber, channel, deviceIP)
+}
+
+// The `Verify` function is a method of the `TestNumberProvider` struct that implements the `Provider`
+// interface.
+func (p *TestNumberProvider) Verify(ctx context.Context, phoneNumber string, code string) (string, error) {
+	return p.route(phoneNumber).Verify(ctx, phoneNumber, code)
+}
+
+// The function creates a provider that sends `code` to the numbers starting with `prefix`, and hands
+// every other number to `real`. It fails when the prefix is empty, which would give every number the
+// fixed code, or when the code isn't made of 4 to 10 digits.
+func NewTestNumberProvider(prefix string, code string, real Provider) (*TestNumberProvider, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return nil, errors.New("otp test phone prefix must not be empty")
+	}
+	test, err := NewFixedCodeProvider(code)
+	if err != nil {
+		return nil, err
+	}
+	return &TestNumberProvider{prefix: prefix, test: test, real: real}, nil
+}
+
+// The function reports whether the fixed test code may be used in the environment named by `APP_ENV`.
+// Only "development" and "test" allow it, ignoring case and surrounding spaces, so any other value,
+// including a misspelled one, is treated like production.
+func FixedCodeAllowed(appEnv string) bool {
+	switch strings.ToLower(strings.TrimSpace(appEnv)) {
+	case "development", "test":
+		return true
+	}
+	return false
+}
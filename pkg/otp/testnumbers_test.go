@@ -0,0 +1,83 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"testing"
+)
+
+func TestTestNumberProvider(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("test numbers get the fixed code", func(t *testing.T) {
+		upstream := NewMockProvider(6)
+		provider, err := NewTestNumberProvider(" +1555 ", "424242", upstream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := provider.Send(ctx, "+15550001111", "sms", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := upstream.codes["+15550001111"]; ok {
+			t.Error("the real provider was sent a code for a test number")
+		}
+		if channel, err := provider.Verify(ctx, "+15550001111", "424242"); err != nil || channel != "sms" {
+			t.Errorf("Verify() = %q, %v, want the fixed code accepted", channel, err)
+		}
+	})
+
+	t.Run("other numbers reach the real provider", func(t *testing.T) {
+		upstream, err := NewFixedCodeProvider("111111")
+		if err != nil {
+			t.Fatal(err)
+		}
+		provider, err := NewTestNumberProvider("+1555", "424242", upstream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := provider.Send(ctx, "+919876543210", "sms", ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := upstream.codes["+919876543210"]; !ok {
+			t.Fatal("the real provider wasn't sent a code for a real number")
+		}
+		if _, err := provider.Verify(ctx, "+919876543210", "424242"); !errors.Is(err, pkg.ErrOTPNotApproved) {
+			t.Errorf("Verify() of the test code for a real number = %v, want %v", err, pkg.ErrOTPNotApproved)
+		}
+		if _, err := provider.Verify(ctx, "+919876543210", "111111"); err != nil {
+			t.Errorf("Verify() of the code sent by the real provider = %v, want it accepted", err)
+		}
+	})
+
+	for _, prefix := range []string{"", "  "} {
+		t.Run("empty prefix "+prefix, func(t *testing.T) {
+			if _, err := NewTestNumberProvider(prefix, "424242", NewMockProvider(6)); err == nil {
+				t.Errorf("NewTestNumberProvider(%q) = nil error, want one", prefix)
+			}
+		})
+	}
+
+	t.Run("invalid code", func(t *testing.T) {
+		if _, err := NewTestNumberProvider("+1555", "42", NewMockProvider(6)); err == nil {
+			t.Error("NewTestNumberProvider() with a 2 digit code = nil error, want one")
+		}
+	})
+}
+
+func TestFixedCodeAllowed(t *testing.T) {
+	for appEnv, want := range map[string]bool{
+		"development": true,
+		"test":        true,
+		" Test ":      true,
+		"production":  false,
+		"Production":  false,
+		"staging":     false,
+		"prod":        false,
+		"":            false,
+	} {
+		if got := FixedCodeAllowed(appEnv); got != want {
+			t.Errorf("FixedCodeAllowed(%q) = %v, want %v", appEnv, got, want)
+		}
+	}
+}
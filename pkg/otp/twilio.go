@@ -0,0 +1,106 @@
+package otp
+
+import (
+	"context"
+	"fmt"
+	"sigmacoder/pkg"
+
+	"github.com/twilio/twilio-go"
+	twilioApi "github.com/twilio/twilio-go/rest/verify/v2"
+)
+
+// `minCodeLength` and `maxCodeLength` are the code lengths supported by Twilio Verify.
+const (
+	minCodeLength = 4
+	maxCodeLength = 10
+)
+
+// The TwilioProvider type sends and checks codes with a Twilio Verify service.
+// @property client - The Twilio API client.
+// @property {string} serviceSID - The ID of the Verify service the codes are sent from.
+type TwilioProvider struct {
+	client     *twilio.RestClient
+	serviceSID string
+}
+
+// The function sets the number of digits in the codes the Twilio Verify service sends. Twilio only
+// supports the code length as a setting of the whole service rather than per verification, so it is
+// applied once at startup. Codes stay valid for 10 minutes whatever their length.
+func (p *TwilioProvider) SetCodeLength(length int) error {
+	if length < minCodeLength || length > maxCodeLength {
+		return fmt.Errorf("otp code length must be between %d and %d, got %d", minCodeLength, maxCodeLength, length)
+	}
+	params := &twilioApi.UpdateServiceParams{}
+	params.SetCodeLength(length)
+	_, err := p.client.VerifyV2.UpdateService(p.serviceSID, params)
+	return err
+}
+
+// The function runs `call`, a request to Twilio, and waits for it to finish or for `ctx` to be done,
+// whichever comes first. The Twilio client doesn't take a context, so a request still running when
+// `ctx` is done is abandoned rather than cancelled: its result is dropped and the client's own timeout
+// ends it.
+func await(ctx context.Context, call func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- call()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// The `Send` function is a method of the `TwilioProvider` struct that implements the `Provider`
+// interface. The requesting client's IP is forwarded to Twilio so its fraud checks see the real device
+// rather than our proxy.
+func (p *TwilioProvider) Send(ctx context.Context, phoneNumber string, channel string, deviceIP string) error {
+	params := &twilioApi.CreateVerificationParams{}
+	params.SetTo(phoneNumber)
+	params.SetChannel(channel)
+	if deviceIP != "" {
+		params.SetDeviceIp(deviceIP)
+	}
+	return await(ctx, func() error {
+		_, err := p.client.VerifyV2.CreateVerification(p.serviceSID, params)
+		return err
+	})
+}
+
+// The `Verify` function is a method of the `TwilioProvider` struct that implements the `Provider`
+// interface.
+func (p *TwilioProvider) Verify(ctx context.Context, phoneNumber string, code string) (string, error) {
+	params := &twilioApi.CreateVerificationCheckParams{}
+	params.SetTo(phoneNumber)
+	params.SetCode(code)
+
+	var resp *twilioApi.VerifyV2VerificationCheck
+	err := await(ctx, func() error {
+		var err error
+		resp, err = p.client.VerifyV2.CreateVerificationCheck(p.serviceSID, params)
+		return err
+	})
+	if err != nil {
+		return UnknownChannel, err
+	}
+	channel := UnknownChannel
+	if resp.Channel != nil {
+		channel = *resp.Channel
+	}
+	if resp.Status != nil && *resp.Status == "approved" {
+		return channel, nil
+	}
+	return channel, pkg.ErrOTPNotApproved
+}
+
+// The function creates a provider for the Twilio Verify service with the given ID, authenticating with
+// the account's credentials.
+func NewTwilioProvider(accountSID string, authToken string, serviceSID string) *TwilioProvider {
+	client := twilio.NewRestClientWithParams(twilio.ClientParams{
+		Username: accountSID,
+		Password: authToken,
+	})
+	return &TwilioProvider{client: client, serviceSID: serviceSID}
+}
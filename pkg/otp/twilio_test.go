@@ -0,0 +1,103 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/twilio/twilio-go"
+)
+
+// The type fakeTwilio stands in for the HTTP client of the Twilio SDK. It records the requests it is
+// sent and answers each with `response`.
+type fakeTwilio struct {
+	response string
+	requests []fakeRequest
+}
+
+// The type fakeRequest is a request the SDK sent to the fake client.
+type fakeRequest struct {
+	method string
+	url    string
+	data   url.Values
+}
+
+func (f *fakeTwilio) AccountSid() string {
+	return "AC123"
+}
+
+func (f *fakeTwilio) SetTimeout(timeout time.Duration) {}
+
+func (f *fakeTwilio) SendRequest(method string, rawURL string, data url.Values, headers map[string]interface{}) (*http.Response, error) {
+	f.requests = append(f.requests, fakeRequest{method: method, url: rawURL, data: data})
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(f.response))}, nil
+}
+
+// The function returns a provider for the Verify service "VA123" that talks to the fake client.
+func fakeTwilioProvider(fake *fakeTwilio) *TwilioProvider {
+	return &TwilioProvider{client: twilio.NewRestClientWithParams(twilio.ClientParams{Client: fake}), serviceSID: "VA123"}
+}
+
+func TestAwait(t *testing.T) {
+	t.Run("call finishes first", func(t *testing.T) {
+		want := errors.New("twilio error")
+		if err := await(context.Background(), func() error { return want }); err != want {
+			t.Errorf("await() = %v, want %v", err, want)
+		}
+	})
+
+	t.Run("context done first", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		release := make(chan struct{})
+		defer close(release)
+		started := time.Now()
+		err := await(ctx, func() error {
+			<-release
+			return nil
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("await() = %v, want %v", err, context.DeadlineExceeded)
+		}
+		if elapsed := time.Since(started); elapsed > time.Second {
+			t.Errorf("await() returned after %s, want it to return at the deadline", elapsed)
+		}
+	})
+}
+
+func TestSetCodeLength(t *testing.T) {
+	t.Run("supported length", func(t *testing.T) {
+		fake := &fakeTwilio{response: `{"sid": "VA123", "code_length": 8}`}
+		if err := fakeTwilioProvider(fake).SetCodeLength(8); err != nil {
+			t.Fatal(err)
+		}
+		if len(fake.requests) != 1 {
+			t.Fatalf("sent %d requests, want 1", len(fake.requests))
+		}
+		request := fake.requests[0]
+		if request.method != http.MethodPost || !strings.HasSuffix(request.url, "/Services/VA123") {
+			t.Errorf("sent %s %s, want an update of the VA123 service", request.method, request.url)
+		}
+		if length := request.data.Get("CodeLength"); length != "8" {
+			t.Errorf("CodeLength = %q, want 8", length)
+		}
+	})
+
+	for _, length := range []int{minCodeLength - 1, maxCodeLength + 1} {
+		t.Run(fmt.Sprintf("unsupported length %d", length), func(t *testing.T) {
+			fake := &fakeTwilio{}
+			if err := fakeTwilioProvider(fake).SetCodeLength(length); err == nil {
+				t.Error("SetCodeLength() = nil, want an error")
+			}
+			if len(fake.requests) != 0 {
+				t.Errorf("sent %v, want the length refused before calling Twilio", fake.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package progress
+
+import (
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The SolveCount type is the number of questions a user has solved, as aggregated from the progress
+// collection.
+// @property {string} UserID - The ID of the user.
+// @property {int64} Solved - The number of questions the user has solved.
+type SolveCount struct {
+	UserID string `bson:"_id"`
+	Solved int64  `bson:"solved"`
+}
+
+// The LeaderboardEntry type is a single position on the leaderboard.
+// @property {int64} Rank - The position of the user. Users with the same solve count share a rank, and
+// the next rank skips the shared positions, so two users tied at 1 are followed by 3.
+// @property User - The public profile of the user.
+// @property {int64} Solved - The number of questions the user has solved.
+type LeaderboardEntry struct {
+	Rank   int64           `json:"rank"`
+	User   auth.PublicUser `json:"user"`
+	Solved int64           `json:"solved"`
+}
+
+// The Rank type is a user's position on the leaderboard.
+// @property {int64} Rank - One more than the number of users that solved more questions, so users with
+// the same solve count share a rank. Users that haven't solved anything rank after everyone who has.
+// @property {int64} Solved - The number of questions the user has solved.
+// @property {int64} Ranked - The number of users that have solved at least one question.
+type Rank struct {
+	Rank   int64 `json:"rank"`
+	Solved int64 `json:"solved"`
+	Ranked int64 `json:"ranked"`
+}
+
+// `solveCountStage` groups the solve entries by user into their solve counts.
+var solveCountStage = bson.D{{Key: "$group", Value: bson.M{"_id": "$userid", "solved": bson.M{"$sum": 1}}}}
+
+// The `TopSolvers` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the `limit` users that solved the most questions, most first. Ties are ordered
+// by user ID so the order is stable between requests.
+func (s *Repo) TopSolvers(limit int) ([]SolveCount, error) {
+	counts := []SolveCount{}
+	pipeline := mongo.Pipeline{
+		solveCountStage,
+		{{Key: "$sort", Value: bson.D{{Key: "solved", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &counts)
+	})
+	return counts, err
+}
+
+// The `CountSolved` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the number of questions the user has solved.
+func (s *Repo) CountSolved(userID string) (int64, error) {
+	var count int64
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		count, err = s.db.CountDocuments(s.context, bson.M{"userid": userID})
+		return err
+	})
+	return count, err
+}
+
+// The `RankCounts` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the number of users that solved more than `solved` questions and the number
+// of users that solved any, from a single aggregation.
+func (s *Repo) RankCounts(solved int64) (int64, int64, error) {
+	pipeline := mongo.Pipeline{
+		solveCountStage,
+		{{Key: "$facet", Value: bson.M{
+			"above":  bson.A{bson.M{"$match": bson.M{"solved": bson.M{"$gt": solved}}}, bson.M{"$count": "count"}},
+			"ranked": bson.A{bson.M{"$count": "count"}},
+		}}},
+	}
+	type count struct {
+		Count int64 `bson:"count"`
+	}
+	var result []struct {
+		Above  []count `bson:"above"`
+		Ranked []count `bson:"ranked"`
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &result)
+	})
+	if err != nil || len(result) == 0 {
+		return 0, 0, err
+	}
+	var above, ranked int64
+	if len(result[0].Above) > 0 {
+		above = result[0].Above[0].Count
+	}
+	if len(result[0].Ranked) > 0 {
+		ranked = result[0].Ranked[0].Count
+	}
+	return above, ranked, nil
+}
+
+// The `Rank` function is a method of the `Svc` struct that implements the `Rank` method of the
+// `Service` interface.
+func (s *Svc) Rank(userID string) (Rank, error) {
+	solved, err := s.repo.CountSolved(userID)
+	if err != nil {
+		return Rank{}, err
+	}
+	above, ranked, err := s.repo.RankCounts(solved)
+	if err != nil {
+		return Rank{}, err
+	}
+	return Rank{Rank: above + 1, Solved: solved, Ranked: ranked}, nil
+}
+
+// The `Leaderboard` function is a method of the `Svc` struct that implements the `Leaderboard` method
+// of the `Service` interface. The profiles of the listed users are looked up in a single query.
+func (s *Svc) Leaderboard(limit int) ([]LeaderboardEntry, error) {
+	entries := []LeaderboardEntry{}
+	top, err := s.repo.TopSolvers(limit)
+	if err != nil || len(top) == 0 {
+		return entries, err
+	}
+	userIDs := make([]string, 0, len(top))
+	for _, count := range top {
+		userIDs = append(userIDs, count.UserID)
+	}
+	users, err := s.users.ReadMany(userIDs)
+	if err != nil {
+		return entries, err
+	}
+	byID := make(map[string]auth.PublicUser, len(users))
+	for _, user := range users {
+		byID[user.ID] = user.ToPublicUser()
+	}
+	for i, count := range top {
+		rank := int64(i + 1)
+		if i > 0 && count.Solved == top[i-1].Solved {
+			rank = entries[i-1].Rank
+		}
+		entries = append(entries, LeaderboardEntry{Rank: rank, User: byID[count.UserID], Solved: count.Solved})
+	}
+	return entries, nil
+}
@@ -0,0 +1,106 @@
+package progress
+
+import (
+	"reflect"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns a progress service that also looks up user profiles in the mocked deployment.
+func newLeaderboardService(mt *mtest.T) *Svc {
+	return NewProgressService(NewRepo(mt.DB).(*Repo), allquestions.NewRepo(mt.DB), auth.NewRepo(mt.DB).(*auth.Repo), nil, 10).(*Svc)
+}
+
+func TestLeaderboard(t *testing.T) {
+	dbtest.Run(t, "ties share a rank", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, SolveCount{UserID: "ada", Solved: 9}, SolveCount{UserID: "grace", Solved: 9}, SolveCount{UserID: "alan", Solved: 4}, SolveCount{UserID: "edsger", Solved: 1}),
+			dbtest.Cursor(mt, auth.User{ID: "alan", Username: "alan"}, auth.User{ID: "ada", Username: "ada"}, auth.User{ID: "edsger", Username: "edsger"}, auth.User{ID: "grace", Username: "grace"}),
+		)
+		entries, err := newLeaderboardService(mt).Leaderboard(10)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		var ranks []int64
+		var usernames []string
+		for _, entry := range entries {
+			ranks = append(ranks, entry.Rank)
+			usernames = append(usernames, entry.User.Username)
+		}
+		if !reflect.DeepEqual(ranks, []int64{1, 1, 3, 4}) {
+			mt.Errorf("ranks = %v, want [1 1 3 4]", ranks)
+		}
+		if !reflect.DeepEqual(usernames, []string{"ada", "grace", "alan", "edsger"}) {
+			mt.Errorf("usernames = %v, want the profiles in solve count order", usernames)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$group": bson.M{"_id": "$userid", "solved": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "solved", Value: -1}, {Key: "_id", Value: 1}}},
+			bson.M{"$limit": 10},
+		})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": bson.M{"$in": bson.A{"ada", "grace", "alan", "edsger"}}})
+	})
+
+	dbtest.Run(t, "nothing solved", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		entries, err := newLeaderboardService(mt).Leaderboard(10)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if entries == nil || len(entries) != 0 {
+			mt.Errorf("Leaderboard() = %#v, want an empty slice", entries)
+		}
+		dbtest.NextCommand(mt, "aggregate")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		name   string
+		solved int
+		facet  bson.M
+		want   Rank
+	}{
+		{
+			name:   "behind two users",
+			solved: 4,
+			facet:  bson.M{"above": bson.A{bson.M{"count": 2}}, "ranked": bson.A{bson.M{"count": 4}}},
+			want:   Rank{Rank: 3, Solved: 4, Ranked: 4},
+		},
+		{
+			name:   "tied for first",
+			solved: 9,
+			facet:  bson.M{"above": bson.A{}, "ranked": bson.A{bson.M{"count": 4}}},
+			want:   Rank{Rank: 1, Solved: 9, Ranked: 4},
+		},
+		{
+			name:   "nothing solved",
+			solved: 0,
+			facet:  bson.M{"above": bson.A{bson.M{"count": 4}}, "ranked": bson.A{bson.M{"count": 4}}},
+			want:   Rank{Rank: 5, Solved: 0, Ranked: 4},
+		},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"n": tt.solved}), dbtest.Cursor(mt, tt.facet))
+			rank, err := newLeaderboardService(mt).Rank("alan")
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if rank != tt.want {
+				mt.Errorf("Rank() = %+v, want %+v", rank, tt.want)
+			}
+			dbtest.NextCommand(mt, "aggregate")
+			facet := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)[1].(bson.M)["$facet"].(bson.M)
+			if match := facet["above"].(bson.A)[0].(bson.M)["$match"]; !reflect.DeepEqual(match, bson.M{"solved": bson.M{"$gt": int64(tt.solved)}}) {
+				mt.Errorf("above $match = %v, want the users with more than %d solved", match, tt.solved)
+			}
+		})
+	}
+}
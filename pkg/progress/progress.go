@@ -0,0 +1,57 @@
+package progress
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Progress type is a single solve entry: a user having solved a question. There is at most one
+// entry per user and question.
+// @property ID - The ObjectID of the entry.
+// @property {string} UserID - The ID of the user that solved the question.
+// @property QuestionID - The ObjectID of the solved question.
+// @property SolvedAt - When the question was marked solved.
+// @property {int64} TimeSpent - The seconds the user logged working on the question, summed over their
+// sessions. It isn't stored with the entry but filled in from the time logs when listing, and left
+// out when no time was logged.
+type Progress struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID     string             `json:"userId" bson:"userid"`
+	QuestionID primitive.ObjectID `json:"questionId" bson:"questionid"`
+	SolvedAt   time.Time          `json:"solvedAt" bson:"solvedAt"`
+	TimeSpent  int64              `json:"timeSpentSeconds,omitempty" bson:"-"`
+}
+
+// The BatchResult type reports what happened to each ID of a batch "mark solved" request.
+// @property {int} Requested - The number of IDs in the request.
+// @property {int} Recorded - The number of new solve entries created.
+// @property {int} Duplicates - The number of IDs that were already marked solved or were repeated in
+// the request.
+// @property {int} Unknown - The number of well-formed IDs that don't belong to any question.
+// @property {int} Invalid - The number of IDs that aren't valid ObjectIDs.
+type BatchResult struct {
+	Requested  int `json:"requested"`
+	Recorded   int `json:"recorded"`
+	Duplicates int `json:"duplicates"`
+	Unknown    int `json:"unknown"`
+	Invalid    int `json:"invalid"`
+}
+
+// The SolvedState type is whether a user has solved a question, as returned after toggling it.
+// @property QuestionID - The ObjectID of the question.
+// @property {bool} Solved - Whether the question is now marked solved.
+type SolvedState struct {
+	QuestionID primitive.ObjectID `json:"questionId"`
+	Solved     bool               `json:"solved"`
+}
+
+// The Page type is a page of a user's solve entries together with the number of entries matching the
+// listing's filter, and the seconds the user logged on all questions, solved or not.
+type Page struct {
+	Progress  []Progress `json:"progress"`
+	Total     int64      `json:"total"`
+	Limit     int        `json:"limit"`
+	Offset    int        `json:"offset"`
+	TimeSpent int64      `json:"timeSpentSeconds"`
+}
@@ -0,0 +1,70 @@
+package progress
+
+import (
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The Recount type is the result of recounting the questions a user has solved.
+// @property {string} UserID - The ID of the user.
+// @property {int64} Solved - The number of questions the user has solved, now stored on the user.
+type Recount struct {
+	UserID string `json:"userId"`
+	Solved int64  `json:"solved"`
+}
+
+// The RecountAllResult type is the result of recounting the solved questions of every user.
+// @property {int} Solvers - The number of users that have solved at least one question.
+// @property {int64} Corrected - The number of users whose stored count was wrong and has been fixed.
+type RecountAllResult struct {
+	Solvers   int   `json:"solvers"`
+	Corrected int64 `json:"corrected"`
+}
+
+// The `SolveCounts` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the solve count of every user that has solved at least one question.
+func (s *Repo) SolveCounts() ([]SolveCount, error) {
+	counts := []SolveCount{}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, mongo.Pipeline{solveCountStage})
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &counts)
+	})
+	return counts, err
+}
+
+// The `Recount` function is a method of the `Svc` struct that implements the `Recount` method of the
+// `Service` interface. The progress collection is the authoritative source; the count stored on the
+// user is overwritten with it.
+func (s *Svc) Recount(userID string) (Recount, error) {
+	solved, err := s.repo.CountSolved(userID)
+	if err != nil {
+		return Recount{}, err
+	}
+	if err := s.users.SetSolvedCount(userID, solved); err != nil {
+		return Recount{}, err
+	}
+	return Recount{UserID: userID, Solved: solved}, nil
+}
+
+// The `RecountAll` function is a method of the `Svc` struct that implements the `RecountAll` method of
+// the `Service` interface. Every user's count is computed in a single aggregation and written back in
+// a single bulk write. Counts of users that no longer exist are dropped by the write.
+func (s *Svc) RecountAll() (RecountAllResult, error) {
+	counts, err := s.repo.SolveCounts()
+	if err != nil {
+		return RecountAllResult{}, err
+	}
+	byUser := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		byUser[count.UserID] = count.Solved
+	}
+	corrected, err := s.users.SetSolvedCounts(byUser)
+	if err != nil {
+		return RecountAllResult{}, err
+	}
+	return RecountAllResult{Solvers: len(counts), Corrected: corrected}, nil
+}
@@ -0,0 +1,99 @@
+package progress
+
+import (
+	"errors"
+	"reflect"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/database/dbtest"
+	"sort"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRecount(t *testing.T) {
+	dbtest.Run(t, "stores the count of solve entries", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"n": 7}), dbtest.Written(1))
+		recount, err := newLeaderboardService(mt).Recount("ada")
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if recount != (Recount{UserID: "ada", Solved: 7}) {
+			mt.Errorf("Recount() = %+v, want 7 solved", recount)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$match": bson.M{"userid": "ada"}},
+			bson.M{"$group": bson.M{"_id": 1, "n": bson.M{"$sum": 1}}},
+		})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "update"), "updates", bson.A{
+			bson.M{"q": bson.M{"_id": "ada"}, "u": bson.M{"$set": bson.M{"solvedcount": int64(7)}}},
+		})
+	})
+
+	dbtest.Run(t, "unknown user", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Written(0))
+		if _, err := newLeaderboardService(mt).Recount("nobody"); !errors.Is(err, pkg.ErrUserNotFound) {
+			mt.Fatalf("Recount() = %v, want %v", err, pkg.ErrUserNotFound)
+		}
+		dbtest.NextCommand(mt, "aggregate")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "update"), "updates", bson.A{
+			bson.M{"q": bson.M{"_id": "nobody"}, "u": bson.M{"$set": bson.M{"solvedcount": int64(0)}}},
+		})
+	})
+}
+
+func TestRecountAll(t *testing.T) {
+	dbtest.Run(t, "every user matches the progress collection", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, SolveCount{UserID: "ada", Solved: 9}, SolveCount{UserID: "alan", Solved: 4}),
+			dbtest.Written(1),
+			dbtest.Written(2),
+		)
+		result, err := newLeaderboardService(mt).RecountAll()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if result != (RecountAllResult{Solvers: 2, Corrected: 3}) {
+			mt.Errorf("RecountAll() = %+v, want 2 solvers and 3 corrected", result)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$group": bson.M{"_id": "$userid", "solved": bson.M{"$sum": 1}}},
+		})
+		updates := bson.M{}
+		for _, update := range dbtest.NextCommand(mt, "update")["updates"].(bson.A) {
+			update := update.(bson.M)
+			updates[update["q"].(bson.M)["_id"].(string)] = update
+		}
+		dbtest.ExpectField(mt, bson.M{"updates": updates}, "updates", bson.M{
+			"ada":  bson.M{"q": bson.M{"_id": "ada", "solvedcount": bson.M{"$ne": int64(9)}}, "u": bson.M{"$set": bson.M{"solvedcount": int64(9)}}},
+			"alan": bson.M{"q": bson.M{"_id": "alan", "solvedcount": bson.M{"$ne": int64(4)}}, "u": bson.M{"$set": bson.M{"solvedcount": int64(4)}}},
+		})
+		reset := dbtest.NextCommand(mt, "update")["updates"].(bson.A)[0].(bson.M)
+		var ids []string
+		for _, id := range reset["q"].(bson.M)["_id"].(bson.M)["$nin"].(bson.A) {
+			ids = append(ids, id.(string))
+		}
+		sort.Strings(ids)
+		if !reflect.DeepEqual(ids, []string{"ada", "alan"}) {
+			mt.Errorf("reset excludes %v, want the users with solve entries", ids)
+		}
+		dbtest.ExpectField(mt, reset, "u", bson.M{"$set": bson.M{"solvedcount": 0}})
+		dbtest.ExpectField(mt, reset, "multi", true)
+	})
+
+	dbtest.Run(t, "nothing solved", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Written(3))
+		result, err := newLeaderboardService(mt).RecountAll()
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if result != (RecountAllResult{Solvers: 0, Corrected: 3}) {
+			mt.Errorf("RecountAll() = %+v, want every stored count reset", result)
+		}
+		dbtest.NextCommand(mt, "aggregate")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "update"), "updates", bson.A{
+			bson.M{"q": bson.M{"_id": bson.M{"$nin": bson.A{}}, "solvedcount": bson.M{"$ne": 0}}, "u": bson.M{"$set": bson.M{"solvedcount": 0}}, "multi": true},
+		})
+	})
+}
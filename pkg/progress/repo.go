@@ -0,0 +1,220 @@
+package progress
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/retry"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the Progress entity.
+type Repository interface {
+	MarkSolvedMany(userID string, questionIDs []primitive.ObjectID) (int64, error)
+	SolvedTimes(userID string) ([]time.Time, error)
+	ListByUser(userID string) ([]Progress, error)
+	ListPage(userID string, level string, ascending bool, limit int, offset int) (Page, error)
+	Toggle(userID string, questionID primitive.ObjectID) (bool, error)
+	TopSolvers(limit int) ([]SolveCount, error)
+	CountSolved(userID string) (int64, error)
+	RankCounts(solved int64) (int64, int64, error)
+	SolveCounts() ([]SolveCount, error)
+	UserSolves(userID string) (map[string]int64, []time.Time, error)
+	EnsureIndexes() error
+}
+
+// `questionsCollection` is the collection holding the questions, whose `_id` the solve entries
+// reference in their `questionid` field.
+const questionsCollection = "AllQuestion"
+
+// Repo is the struct that implements the Repository interface on top of the `progress` collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `MarkSolvedMany` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It records a solve entry for each of the given questions in a single bulk write. Entries
+// are upserted on the user and question, so questions the user already solved keep their original
+// `solvedAt`. It returns the number of entries that were newly created.
+func (s *Repo) MarkSolvedMany(userID string, questionIDs []primitive.ObjectID) (int64, error) {
+	if len(questionIDs) == 0 {
+		return 0, nil
+	}
+	now := pkg.NowUTC()
+	models := make([]mongo.WriteModel, 0, len(questionIDs))
+	for _, questionID := range questionIDs {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"userid": userID, "questionid": questionID}).
+			SetUpdate(bson.M{"$setOnInsert": bson.M{"solvedAt": now}}).
+			SetUpsert(true))
+	}
+	var result *mongo.BulkWriteResult
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		result, err = s.db.BulkWrite(s.context, models, options.BulkWrite().SetOrdered(false))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.UpsertedCount, nil
+}
+
+// The `SolvedTimes` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns when the user solved each of their solved questions.
+func (s *Repo) SolvedTimes(userID string) ([]time.Time, error) {
+	times := []time.Time{}
+	opts := options.Find().SetProjection(bson.M{"solvedAt": 1})
+	var entries []Progress
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, bson.M{"userid": userID}, opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &entries)
+	})
+	if err != nil {
+		return times, err
+	}
+	for _, entry := range entries {
+		times = append(times, entry.SolvedAt)
+	}
+	return times, nil
+}
+
+// The `ListByUser` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns all of the user's solve entries, most recent first.
+func (s *Repo) ListByUser(userID string) ([]Progress, error) {
+	entries := []Progress{}
+	opts := options.Find().SetSort(bson.M{"solvedAt": -1})
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, bson.M{"userid": userID}, opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &entries)
+	})
+	return entries, err
+}
+
+// The `ListPage` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns a page of the user's solve entries ordered by `solvedAt`, most recent first
+// unless `ascending` is set, together with the number of entries matching. A non-empty `level`
+// restricts the listing to questions of that level, which is looked up on the questions themselves so
+// it stays right when a question's level changes.
+func (s *Repo) ListPage(userID string, level string, ascending bool, limit int, offset int) (Page, error) {
+	page := Page{Progress: []Progress{}, Limit: limit, Offset: offset}
+	order := -1
+	if ascending {
+		order = 1
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userid": userID}}},
+	}
+	if level != "" {
+		pipeline = append(pipeline,
+			bson.D{{Key: "$lookup", Value: bson.M{
+				"from":         questionsCollection,
+				"localField":   "questionid",
+				"foreignField": "_id",
+				"as":           "question",
+			}}},
+			bson.D{{Key: "$match", Value: bson.M{"question.level": level}}},
+			bson.D{{Key: "$project", Value: bson.M{"question": 0}}},
+		)
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.M{
+		"total": bson.A{bson.M{"$count": "count"}},
+		"progress": bson.A{
+			bson.M{"$sort": bson.D{{Key: "solvedAt", Value: order}, {Key: "_id", Value: order}}},
+			bson.M{"$skip": offset},
+			bson.M{"$limit": limit},
+		},
+	}}})
+	var result []struct {
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Progress []Progress `bson:"progress"`
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &result)
+	})
+	if err != nil || len(result) == 0 {
+		return page, err
+	}
+	if len(result[0].Total) > 0 {
+		page.Total = result[0].Total[0].Count
+	}
+	page.Progress = append(page.Progress, result[0].Progress...)
+	return page, nil
+}
+
+// The `Toggle` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It deletes the user's solve entry for the question if there is one and creates it otherwise, and
+// returns whether the question is solved afterwards. Both steps are keyed on the user and question, so
+// when two toggles of an unsolved question race, the unique index lets only one of them create the
+// entry and both report it as solved instead of recording it twice.
+func (s *Repo) Toggle(userID string, questionID primitive.ObjectID) (bool, error) {
+	filter := bson.M{"userid": userID, "questionid": questionID}
+	var deleted *mongo.DeleteResult
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		deleted, err = s.db.DeleteOne(s.context, filter)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	if deleted.DeletedCount == 1 {
+		return false, nil
+	}
+	err = retry.Default.Do(s.context, func() error {
+		_, err := s.db.UpdateOne(s.context, filter, bson.M{"$setOnInsert": bson.M{"solvedAt": pkg.NowUTC()}}, options.Update().SetUpsert(true))
+		return err
+	})
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// The `EnsureIndexes` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It creates the unique index on the user and question, which keeps concurrent "mark
+// solved" requests from recording the same solve twice, and the index on the user and `solvedAt` that
+// the paginated listing relies on.
+func (s *Repo) EnsureIndexes() error {
+	_, err := s.db.Indexes().CreateMany(s.context, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "userid", Value: 1}, {Key: "questionid", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "userid", Value: 1}, {Key: "solvedAt", Value: -1}}},
+	})
+	return err
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `progress` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("progress", opts...), context: ctx}
+}
@@ -0,0 +1,99 @@
+package progress
+
+import (
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns the response to the paging aggregation: `total` matching entries, of which
+// `entries` are on the page.
+func progressPage(mt *mtest.T, total int, entries ...Progress) bson.D {
+	counts := bson.A{}
+	if total > 0 {
+		counts = append(counts, bson.M{"count": total})
+	}
+	page := bson.A{}
+	for _, entry := range entries {
+		page = append(page, dbtest.Document(mt, entry))
+	}
+	return dbtest.Cursor(mt, bson.M{"total": counts, "progress": page})
+}
+
+func TestListPage(t *testing.T) {
+	newest := Progress{ID: primitive.NewObjectID(), UserID: "user-1", QuestionID: primitive.NewObjectID(), SolvedAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)}
+	older := Progress{ID: primitive.NewObjectID(), UserID: "user-1", QuestionID: primitive.NewObjectID(), SolvedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}
+
+	dbtest.Run(t, "second page, newest first", func(mt *mtest.T) {
+		mt.AddMockResponses(progressPage(mt, 7, newest, older))
+		page, err := NewRepo(mt.DB).(*Repo).ListPage("user-1", "", false, 2, 2)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if page.Total != 7 || page.Limit != 2 || page.Offset != 2 || len(page.Progress) != 2 || page.Progress[0].ID != newest.ID {
+			mt.Errorf("ListPage() = %+v, want 2 of 7 entries from offset 2", page)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$match": bson.M{"userid": "user-1"}},
+			bson.M{"$facet": bson.M{
+				"total": bson.A{bson.M{"$count": "count"}},
+				"progress": bson.A{
+					bson.M{"$sort": bson.D{{Key: "solvedAt", Value: -1}, {Key: "_id", Value: -1}}},
+					bson.M{"$skip": 2},
+					bson.M{"$limit": 2},
+				},
+			}},
+		})
+	})
+
+	dbtest.Run(t, "level filter, oldest first", func(mt *mtest.T) {
+		mt.AddMockResponses(progressPage(mt, 1, older))
+		page, err := NewRepo(mt.DB).(*Repo).ListPage("user-1", "Hard", true, 10, 0)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if page.Total != 1 || len(page.Progress) != 1 {
+			mt.Errorf("ListPage() = %+v, want the one hard entry", page)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$match": bson.M{"userid": "user-1"}},
+			bson.M{"$lookup": bson.M{"from": questionsCollection, "localField": "questionid", "foreignField": "_id", "as": "question"}},
+			bson.M{"$match": bson.M{"question.level": "Hard"}},
+			bson.M{"$project": bson.M{"question": 0}},
+			bson.M{"$facet": bson.M{
+				"total": bson.A{bson.M{"$count": "count"}},
+				"progress": bson.A{
+					bson.M{"$sort": bson.D{{Key: "solvedAt", Value: 1}, {Key: "_id", Value: 1}}},
+					bson.M{"$skip": 0},
+					bson.M{"$limit": 10},
+				},
+			}},
+		})
+	})
+
+	dbtest.Run(t, "past the last page", func(mt *mtest.T) {
+		mt.AddMockResponses(progressPage(mt, 3))
+		page, err := NewRepo(mt.DB).(*Repo).ListPage("user-1", "", false, 10, 20)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if page.Total != 3 || page.Progress == nil || len(page.Progress) != 0 {
+			mt.Errorf("ListPage() = %#v, want the total and an empty page", page)
+		}
+	})
+
+	dbtest.Run(t, "nothing solved", func(mt *mtest.T) {
+		mt.AddMockResponses(progressPage(mt, 0))
+		page, err := NewRepo(mt.DB).(*Repo).ListPage("user-1", "", false, 10, 0)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if page.Total != 0 || page.Progress == nil || len(page.Progress) != 0 {
+			mt.Errorf("ListPage() = %#v, want an empty page", page)
+		}
+	})
+}
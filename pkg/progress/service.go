@@ -0,0 +1,177 @@
+package progress
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/timelogs"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Service interface defines the progress operations available to the HTTP handlers.
+// @property MarkSolvedBatch - MarkSolvedBatch marks every valid, existing question in the list as
+// solved by the user and reports what happened to the rest.
+// @property Streak - Streak computes the user's current and longest daily solving streak, with days
+// taken in the given timezone.
+// @property ToggleSolved - ToggleSolved flips whether the user has solved a question.
+// @property List - List returns a page of the user's solve entries, optionally only for questions of
+// one level, with the time the user spent on each question and on all of them.
+// @property Rank - Rank returns the user's position on the leaderboard.
+// @property Leaderboard - Leaderboard returns the users that solved the most questions.
+// @property Recount - Recount recomputes the number of questions a user has solved and stores it on the
+// user.
+// @property RecountAll - RecountAll does the same for every user.
+// @property Stats - Stats summarizes the user's progress: the solved questions per level, the streak
+// with days taken in the given timezone, and the rank.
+// @property WithContext - WithContext returns a copy of the service whose repository calls run with the
+// given context.
+type Service interface {
+	MarkSolvedBatch(userID string, questionIDs []string) (BatchResult, error)
+	Streak(userID string, loc *time.Location) (Streak, error)
+	ToggleSolved(userID string, questionID string) (SolvedState, error)
+	List(userID string, level string, ascending bool, limit int, offset int) (Page, error)
+	Rank(userID string) (Rank, error)
+	Leaderboard(limit int) ([]LeaderboardEntry, error)
+	Recount(userID string) (Recount, error)
+	RecountAll() (RecountAllResult, error)
+	Stats(userID string, loc *time.Location) (Stats, error)
+	WithContext(ctx context.Context) Service
+}
+
+// The type Svc implements the Service interface.
+// @property repo - The repository solve entries are stored in.
+// @property questions - The question repository, used to skip IDs that don't belong to a question.
+// @property users - The user repository, used to show the public profiles on the leaderboard and to
+// store the recounted solve counts.
+// @property timeLogs - The time log repository, used to add the time spent to the listed entries.
+// @property maxBatch - The maximum number of IDs accepted in one batch.
+type Svc struct {
+	repo      *Repo
+	questions allquestions.Repository
+	users     *auth.Repo
+	timeLogs  *timelogs.Repo
+	maxBatch  int
+}
+
+// The `MarkSolvedBatch` function is a method of the `Svc` struct that implements the `MarkSolvedBatch`
+// method of the `Service` interface. Malformed IDs, IDs repeated within the request and IDs of
+// questions that don't exist are skipped and counted rather than failing the whole batch.
+func (s *Svc) MarkSolvedBatch(userID string, questionIDs []string) (BatchResult, error) {
+	result := BatchResult{Requested: len(questionIDs)}
+	if len(questionIDs) == 0 {
+		return result, pkg.ErrEmptyBatch
+	}
+	if len(questionIDs) > s.maxBatch {
+		return result, pkg.ErrBatchTooLarge
+	}
+	seen := make(map[primitive.ObjectID]bool, len(questionIDs))
+	var candidates []primitive.ObjectID
+	for _, id := range questionIDs {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			result.Invalid++
+			continue
+		}
+		if seen[oid] {
+			result.Duplicates++
+			continue
+		}
+		seen[oid] = true
+		candidates = append(candidates, oid)
+	}
+	if len(candidates) == 0 {
+		return result, nil
+	}
+	existing, err := s.questions.ExistingIDs(candidates)
+	if err != nil {
+		return result, err
+	}
+	result.Unknown = len(candidates) - len(existing)
+	recorded, err := s.repo.MarkSolvedMany(userID, existing)
+	if err != nil {
+		return result, err
+	}
+	result.Recorded = int(recorded)
+	result.Duplicates += len(existing) - int(recorded)
+	return result, nil
+}
+
+// The `Streak` function is a method of the `Svc` struct that implements the `Streak` method of the
+// `Service` interface.
+func (s *Svc) Streak(userID string, loc *time.Location) (Streak, error) {
+	solvedAt, err := s.repo.SolvedTimes(userID)
+	if err != nil {
+		return Streak{}, err
+	}
+	return computeStreak(solvedAt, loc, time.Now()), nil
+}
+
+// The `ToggleSolved` function is a method of the `Svc` struct that implements the `ToggleSolved`
+// method of the `Service` interface. It marks the question solved if the user hasn't solved it yet and
+// unsolved otherwise.
+func (s *Svc) ToggleSolved(userID string, questionID string) (SolvedState, error) {
+	oid, err := primitive.ObjectIDFromHex(questionID)
+	if err != nil {
+		return SolvedState{}, pkg.ErrInvalidQuestionID
+	}
+	existing, err := s.questions.ExistingIDs([]primitive.ObjectID{oid})
+	if err != nil {
+		return SolvedState{}, err
+	}
+	if len(existing) == 0 {
+		return SolvedState{}, pkg.ErrQuestionNotFound
+	}
+	solved, err := s.repo.Toggle(userID, oid)
+	if err != nil {
+		return SolvedState{}, err
+	}
+	return SolvedState{QuestionID: oid, Solved: solved}, nil
+}
+
+// The `List` function is a method of the `Svc` struct that implements the `List` method of the
+// `Service` interface.
+func (s *Svc) List(userID string, level string, ascending bool, limit int, offset int) (Page, error) {
+	page, err := s.repo.ListPage(userID, level, ascending, limit, offset)
+	if err != nil {
+		return page, err
+	}
+	questionIDs := make([]primitive.ObjectID, 0, len(page.Progress))
+	for _, entry := range page.Progress {
+		questionIDs = append(questionIDs, entry.QuestionID)
+	}
+	totals, err := s.timeLogs.Totals(userID, questionIDs)
+	if err != nil {
+		return page, err
+	}
+	for i, entry := range page.Progress {
+		page.Progress[i].TimeSpent = totals[entry.QuestionID].Seconds
+	}
+	page.TimeSpent, err = s.timeLogs.UserSeconds(userID)
+	return page, err
+}
+
+// The `WithContext` function is a method of the `Svc` struct that implements the `WithContext` method
+// of the `Service` interface. It returns a copy of the service bound to the given context.
+func (s *Svc) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.repo = s.repo.WithContext(ctx)
+	clone.questions = s.questions.WithContext(ctx)
+	clone.users = s.users.WithContext(ctx)
+	clone.timeLogs = s.timeLogs.WithContext(ctx)
+	return &clone
+}
+
+// The function creates a new instance of the progress service. `maxBatch` caps how many question IDs a
+// single batch request may contain.
+func NewProgressService(repo *Repo, questions allquestions.Repository, users *auth.Repo, timeLogs *timelogs.Repo, maxBatch int) Service {
+	return &Svc{
+		repo:      repo,
+		questions: questions,
+		users:     users,
+		timeLogs:  timeLogs,
+		maxBatch:  maxBatch,
+	}
+}
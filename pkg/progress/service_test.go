@@ -0,0 +1,152 @@
+package progress
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns a progress service, accepting batches of up to `maxBatch` IDs, whose
+// repositories talk to the mocked deployment.
+func newService(mt *mtest.T, maxBatch int) *Svc {
+	return NewProgressService(NewRepo(mt.DB).(*Repo), allquestions.NewRepo(mt.DB), nil, nil, maxBatch).(*Svc)
+}
+
+// The function returns the response to a bulk write that upserted the entries at the given indexes.
+func upserted(indexes ...int) bson.D {
+	entries := bson.A{}
+	for _, index := range indexes {
+		entries = append(entries, bson.D{{Key: "index", Value: index}, {Key: "_id", Value: primitive.NewObjectID()}})
+	}
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: len(indexes)}, bson.E{Key: "nModified", Value: 0}, bson.E{Key: "upserted", Value: entries})
+}
+
+func TestMarkSolvedBatch(t *testing.T) {
+	solved, unsolved, unknown := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	dbtest.Run(t, "duplicates and unknown IDs", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, bson.M{"_id": solved}, bson.M{"_id": unsolved}),
+			upserted(1),
+		)
+		ids := []string{solved.Hex(), unsolved.Hex(), unsolved.Hex(), unknown.Hex(), "not-an-id"}
+		result, err := newService(mt, 10).MarkSolvedBatch("user-1", ids)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		// `solved` was already solved, so only `unsolved` gets a new entry, and its repetition in the
+		// request counts as a duplicate like `solved` does.
+		want := BatchResult{Requested: 5, Recorded: 1, Duplicates: 2, Unknown: 1, Invalid: 1}
+		if result != want {
+			mt.Errorf("MarkSolvedBatch() = %+v, want %+v", result, want)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": bson.M{"$in": bson.A{solved, unsolved, unknown}}})
+		update := dbtest.NextCommand(mt, "update")
+		dbtest.ExpectField(mt, update, "ordered", false)
+		if updates := update["updates"].(bson.A); len(updates) != 2 || updates[0].(bson.M)["upsert"] != true {
+			mt.Errorf("updates = %v, want an upsert for each existing question", updates)
+		}
+	})
+
+	dbtest.Run(t, "only unknown IDs", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		result, err := newService(mt, 10).MarkSolvedBatch("user-1", []string{unknown.Hex()})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if want := (BatchResult{Requested: 1, Unknown: 1}); result != want {
+			mt.Errorf("MarkSolvedBatch() = %+v, want %+v", result, want)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "only invalid IDs", func(mt *mtest.T) {
+		result, err := newService(mt, 10).MarkSolvedBatch("user-1", []string{"1", "two"})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if want := (BatchResult{Requested: 2, Invalid: 2}); result != want {
+			mt.Errorf("MarkSolvedBatch() = %+v, want %+v", result, want)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "empty and oversized batches", func(mt *mtest.T) {
+		svc := newService(mt, 2)
+		if _, err := svc.MarkSolvedBatch("user-1", nil); !errors.Is(err, pkg.ErrEmptyBatch) {
+			mt.Errorf("MarkSolvedBatch() of no IDs = %v, want %v", err, pkg.ErrEmptyBatch)
+		}
+		if _, err := svc.MarkSolvedBatch("user-1", []string{solved.Hex(), unsolved.Hex(), unknown.Hex()}); !errors.Is(err, pkg.ErrBatchTooLarge) {
+			mt.Errorf("MarkSolvedBatch() of 3 IDs = %v, want %v", err, pkg.ErrBatchTooLarge)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestToggleSolved(t *testing.T) {
+	questionID := primitive.NewObjectID()
+	key := bson.M{"userid": "user-1", "questionid": questionID}
+
+	dbtest.Run(t, "unsolved to solved", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": questionID}), dbtest.Written(0), dbtest.Written(1))
+		state, err := newService(mt, 10).ToggleSolved("user-1", questionID.Hex())
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if !state.Solved || state.QuestionID != questionID {
+			mt.Errorf("ToggleSolved() = %+v, want the question solved", state)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "delete")["deletes"].(bson.A)[0].(bson.M), "q", key)
+		update := dbtest.NextCommand(mt, "update")["updates"].(bson.A)[0].(bson.M)
+		dbtest.ExpectField(mt, update, "q", key)
+		dbtest.ExpectField(mt, update, "upsert", true)
+	})
+
+	dbtest.Run(t, "solved to unsolved", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": questionID}), dbtest.Written(1))
+		state, err := newService(mt, 10).ToggleSolved("user-1", questionID.Hex())
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if state.Solved {
+			mt.Errorf("ToggleSolved() = %+v, want the question unsolved", state)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "delete")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "concurrent toggle already inserted the entry", func(mt *mtest.T) {
+		// A double click can make both requests find nothing to delete; the second upsert then hits the
+		// unique index and still reports the question solved.
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, bson.M{"_id": questionID}),
+			dbtest.Written(0),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}),
+		)
+		state, err := newService(mt, 10).ToggleSolved("user-1", questionID.Hex())
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if !state.Solved {
+			mt.Errorf("ToggleSolved() = %+v, want the question solved", state)
+		}
+	})
+
+	dbtest.Run(t, "unknown question", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if _, err := newService(mt, 10).ToggleSolved("user-1", questionID.Hex()); !errors.Is(err, pkg.ErrQuestionNotFound) {
+			mt.Errorf("ToggleSolved() = %v, want %v", err, pkg.ErrQuestionNotFound)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
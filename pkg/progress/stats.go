@@ -0,0 +1,98 @@
+package progress
+
+import (
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/retry"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The Stats type summarizes a user's progress for their profile page.
+// @property {int64} Solved - The number of questions the user has solved.
+// @property ByLevel - The number of solved questions per difficulty level. Every level is present,
+// with 0 when the user hasn't solved any question of it. Solved questions that have since been deleted
+// only count towards `Solved`.
+// @property Streak - The user's daily solving streak.
+// @property Rank - The user's position on the leaderboard.
+type Stats struct {
+	Solved  int64            `json:"solved"`
+	ByLevel map[string]int64 `json:"byLevel"`
+	Streak  Streak           `json:"streak"`
+	Rank    Rank             `json:"rank"`
+}
+
+// The type `userSolves` is what `UserSolves` reads from the user's solve entries in one aggregation.
+type userSolves struct {
+	ByLevel []struct {
+		Level string `bson:"_id"`
+		Count int64  `bson:"count"`
+	} `bson:"byLevel"`
+	SolvedAt []struct {
+		SolvedAt time.Time `bson:"solvedAt"`
+	} `bson:"solvedAt"`
+}
+
+// The `UserSolves` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the number of questions the user solved per level together with when they
+// solved each one, reading the user's solve entries once and splitting them with a `$facet`.
+func (s *Repo) UserSolves(userID string) (map[string]int64, []time.Time, error) {
+	byLevel := map[string]int64{}
+	times := []time.Time{}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userid": userID}}},
+		{{Key: "$facet", Value: bson.M{
+			"byLevel": bson.A{
+				bson.M{"$lookup": bson.M{
+					"from":         questionsCollection,
+					"localField":   "questionid",
+					"foreignField": "_id",
+					"as":           "question",
+				}},
+				bson.M{"$unwind": "$question"},
+				bson.M{"$group": bson.M{"_id": "$question.level", "count": bson.M{"$sum": 1}}},
+			},
+			"solvedAt": bson.A{bson.M{"$project": bson.M{"_id": 0, "solvedAt": 1}}},
+		}}},
+	}
+	var result []userSolves
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &result)
+	})
+	if err != nil || len(result) == 0 {
+		return byLevel, times, err
+	}
+	for _, level := range result[0].ByLevel {
+		byLevel[level.Level] = level.Count
+	}
+	for _, entry := range result[0].SolvedAt {
+		times = append(times, entry.SolvedAt)
+	}
+	return byLevel, times, nil
+}
+
+// The `Stats` function is a method of the `Svc` struct that implements the `Stats` method of the
+// `Service` interface. It takes two aggregations: one over the user's own solve entries for the level
+// breakdown and the streak, and one over everyone's for the rank.
+func (s *Svc) Stats(userID string, loc *time.Location) (Stats, error) {
+	byLevel, solvedAt, err := s.repo.UserSolves(userID)
+	if err != nil {
+		return Stats{}, err
+	}
+	stats := Stats{Solved: int64(len(solvedAt)), ByLevel: make(map[string]int64, len(allquestions.LevelEnum))}
+	for _, level := range allquestions.LevelEnum {
+		stats.ByLevel[level] = byLevel[level]
+	}
+	stats.Streak = computeStreak(solvedAt, loc, time.Now())
+	above, ranked, err := s.repo.RankCounts(stats.Solved)
+	if err != nil {
+		return Stats{}, err
+	}
+	stats.Rank = Rank{Rank: above + 1, Solved: stats.Solved, Ranked: ranked}
+	return stats, nil
+}
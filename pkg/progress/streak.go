@@ -0,0 +1,57 @@
+package progress
+
+import "time"
+
+// The Streak type summarizes how consistently a user has been solving questions.
+// @property {int} Current - The number of consecutive days, ending today or yesterday, on which the
+// user solved at least one question. A streak is still current when today hasn't been solved yet.
+// @property {int} Longest - The longest run of consecutive solving days ever.
+// @property {bool} SolvedToday - Whether the user has already solved a question today.
+// @property {string} Timezone - The timezone the calendar days were computed in.
+type Streak struct {
+	Current     int    `json:"current"`
+	Longest     int    `json:"longest"`
+	SolvedToday bool   `json:"solvedToday"`
+	Timezone    string `json:"timezone"`
+}
+
+// The function computes the streak for the given solve times. Times are grouped into calendar days in
+// `loc`, and `now` decides what "today" is. If today has no solves yet but yesterday does, the current
+// streak is counted from yesterday, since the user can still extend it; any older gap breaks it.
+func computeStreak(solvedAt []time.Time, loc *time.Location, now time.Time) Streak {
+	streak := Streak{Timezone: loc.String()}
+	days := make(map[time.Time]bool, len(solvedAt))
+	for _, t := range solvedAt {
+		days[calendarDay(t, loc)] = true
+	}
+	for day := range days {
+		if days[day.AddDate(0, 0, -1)] {
+			continue
+		}
+		length := 1
+		for days[day.AddDate(0, 0, length)] {
+			length++
+		}
+		if length > streak.Longest {
+			streak.Longest = length
+		}
+	}
+	today := calendarDay(now, loc)
+	streak.SolvedToday = days[today]
+	day := today
+	if !streak.SolvedToday {
+		day = today.AddDate(0, 0, -1)
+	}
+	for days[day] {
+		streak.Current++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
+// The function returns the calendar day `t` falls on in `loc`, as midnight UTC of that date so days
+// can be compared and stepped through without daylight saving surprises.
+func calendarDay(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
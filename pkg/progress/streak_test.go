@@ -0,0 +1,80 @@
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeStreak(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 18:00 UTC on 10 March is 23:30 on 10 March in Kolkata.
+	now := time.Date(2024, 3, 10, 18, 0, 0, 0, time.UTC)
+	day := func(d, hour int) time.Time { return time.Date(2024, 3, d, hour, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name     string
+		solvedAt []time.Time
+		loc      *time.Location
+		want     Streak
+	}{
+		{
+			name: "no solves",
+			loc:  time.UTC,
+			want: Streak{Timezone: "UTC"},
+		},
+		{
+			name:     "solved today",
+			solvedAt: []time.Time{day(8, 9), day(9, 9), day(10, 9), day(10, 12)},
+			loc:      time.UTC,
+			want:     Streak{Current: 3, Longest: 3, SolvedToday: true, Timezone: "UTC"},
+		},
+		{
+			name:     "today not solved yet",
+			solvedAt: []time.Time{day(8, 9), day(9, 9)},
+			loc:      time.UTC,
+			want:     Streak{Current: 2, Longest: 2, Timezone: "UTC"},
+		},
+		{
+			name:     "streak broken",
+			solvedAt: []time.Time{day(4, 9), day(5, 9), day(6, 9), day(8, 9)},
+			loc:      time.UTC,
+			want:     Streak{Longest: 3, Timezone: "UTC"},
+		},
+		{
+			name:     "longest run in the past",
+			solvedAt: []time.Time{day(1, 9), day(2, 9), day(3, 9), day(4, 9), day(9, 9), day(10, 9)},
+			loc:      time.UTC,
+			want:     Streak{Current: 2, Longest: 4, SolvedToday: true, Timezone: "UTC"},
+		},
+		{
+			// 20:00 UTC on 8 March is already 9 March in Kolkata, and `now` is 10 March there, so the
+			// solves fall on consecutive local days although they are two UTC days apart.
+			name:     "days in the user's timezone",
+			solvedAt: []time.Time{day(8, 20), day(10, 17)},
+			loc:      kolkata,
+			want:     Streak{Current: 2, Longest: 2, SolvedToday: true, Timezone: "Asia/Kolkata"},
+		},
+		{
+			name:     "same solves in UTC",
+			solvedAt: []time.Time{day(8, 20), day(10, 17)},
+			loc:      time.UTC,
+			want:     Streak{Current: 1, Longest: 1, SolvedToday: true, Timezone: "UTC"},
+		},
+		{
+			name:     "across a month boundary",
+			solvedAt: []time.Time{time.Date(2024, 2, 28, 9, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC), day(1, 9)},
+			loc:      time.UTC,
+			want:     Streak{Longest: 3, Timezone: "UTC"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := computeStreak(tt.solvedAt, tt.loc, now); got != tt.want {
+				t.Errorf("computeStreak() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the Report entity.
+type Repository interface {
+	Create(report Report) (Report, error)
+	List(status string, limit int, offset int) (Page, error)
+	SetStatus(id primitive.ObjectID, status string, adminID string) (Report, error)
+	EnsureIndexes() error
+}
+
+// Repo is the struct that implements the Repository interface on top of the `reports` collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `Create` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It stores a new report and returns it with its assigned ID. It fails with `pkg.ErrDuplicateReport`
+// when the user already has an open report on the question.
+func (s *Repo) Create(report Report) (Report, error) {
+	report.ID = primitive.NewObjectID()
+	err := retry.Default.Do(s.context, func() error {
+		_, err := s.db.InsertOne(s.context, report)
+		return err
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return report, pkg.ErrDuplicateReport
+	}
+	return report, err
+}
+
+// The `List` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It returns a page of the reports with the given status, oldest first so the ones waiting longest
+// are handled first, together with the number of reports with that status.
+func (s *Repo) List(status string, limit int, offset int) (Page, error) {
+	page := Page{Reports: []Report{}, Limit: limit, Offset: offset}
+	filter := bson.M{"status": status}
+	opts := options.Find().SetSort(bson.M{"createdAt": 1}).SetSkip(int64(offset)).SetLimit(int64(limit))
+	err := retry.Default.Do(s.context, func() error {
+		total, err := s.db.CountDocuments(s.context, filter)
+		if err != nil {
+			return err
+		}
+		page.Total = total
+		cursor, err := s.db.Find(s.context, filter, opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &page.Reports)
+	})
+	return page, err
+}
+
+// The `SetStatus` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It moves the report to the given status and returns it updated. Resolving records when and by which
+// admin; reopening clears both. It fails with `pkg.ErrReportNotFound` when there is no such report, and
+// with `pkg.ErrDuplicateReport` when reopening it would give its author two open reports on the
+// question.
+func (s *Repo) SetStatus(id primitive.ObjectID, status string, adminID string) (Report, error) {
+	update := bson.M{"$set": bson.M{"status": status}, "$unset": bson.M{"resolvedAt": "", "resolvedBy": ""}}
+	if status == StatusResolved {
+		update = bson.M{"$set": bson.M{"status": status, "resolvedAt": pkg.NowUTC(), "resolvedBy": adminID}}
+	}
+	var report Report
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOneAndUpdate(s.context, bson.M{"_id": id}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&report)
+	})
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return report, pkg.ErrReportNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return report, pkg.ErrDuplicateReport
+	}
+	return report, err
+}
+
+// The `EnsureIndexes` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It creates the unique index on the question and user of the open reports, which keeps
+// each user to one open report per question, and the index behind the listing by status.
+func (s *Repo) EnsureIndexes() error {
+	_, err := s.db.Indexes().CreateMany(s.context, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "questionid", Value: 1}, {Key: "userid", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": StatusOpen}),
+		},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "createdAt", Value: 1}}},
+	})
+	return err
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `reports` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("reports", opts...), context: ctx}
+}
@@ -0,0 +1,55 @@
+package reports
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The statuses a report can have. Reports are open until an admin resolves them, and can be reopened.
+const (
+	StatusOpen     = "open"
+	StatusResolved = "resolved"
+)
+
+// The Report type is a user's flag on a question that is broken, such as a dead link or an incorrect
+// problem statement. A user can only have one open report per question.
+// @property ID - The ObjectID of the report.
+// @property QuestionID - The ObjectID of the question reported.
+// @property {string} UserID - The ID of the user that reported it.
+// @property {string} Reason - What is wrong with the question, in the user's words.
+// @property {string} Status - `StatusOpen` or `StatusResolved`.
+// @property CreatedAt - When the question was reported.
+// @property ResolvedAt - When an admin resolved the report, if they did.
+// @property {string} ResolvedBy - The ID of the admin that resolved the report, if one did.
+type Report struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	QuestionID primitive.ObjectID `json:"questionId" bson:"questionid"`
+	UserID     string             `json:"userId" bson:"userid"`
+	Reason     string             `json:"reason" bson:"reason"`
+	Status     string             `json:"status" bson:"status"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	ResolvedAt *time.Time         `json:"resolvedAt,omitempty" bson:"resolvedAt,omitempty"`
+	ResolvedBy string             `json:"resolvedBy,omitempty" bson:"resolvedBy,omitempty"`
+}
+
+// The InReport type is the request body for reporting a question.
+// @property {string} Reason - What is wrong with the question.
+type InReport struct {
+	Reason string `json:"reason" validate:"required,max=1000"`
+}
+
+// The InStatus type is the request body for moving a report to another status.
+// @property {string} Status - "open" or "resolved".
+type InStatus struct {
+	Status string `json:"status" validate:"required"`
+}
+
+// The Page type is a page of reports together with the total number of reports with the requested
+// status.
+type Page struct {
+	Reports []Report `json:"reports"`
+	Total   int64    `json:"total"`
+	Limit   int      `json:"limit"`
+	Offset  int      `json:"offset"`
+}
@@ -0,0 +1,100 @@
+package reports
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Service interface defines the report operations available to the HTTP handlers.
+// @property Report - Report records a user's report on a question.
+// @property List - List returns a page of the reports with a status.
+// @property SetStatus - SetStatus moves a report to another status on behalf of an admin.
+// @property WithContext - WithContext returns a copy of the service whose repository calls run with the
+// given context.
+type Service interface {
+	Report(userID string, questionID string, reason string) (Report, error)
+	List(status string, limit int, offset int) (Page, error)
+	SetStatus(id string, status string, adminID string) (Report, error)
+	WithContext(ctx context.Context) Service
+}
+
+// The type Svc implements the Service interface.
+// @property repo - The repository reports are stored in.
+// @property questions - The question repository, used to reject reports on questions that don't exist.
+type Svc struct {
+	repo      *Repo
+	questions allquestions.Repository
+}
+
+// The function reports whether the status is one a report can have.
+func validStatus(status string) bool {
+	return status == StatusOpen || status == StatusResolved
+}
+
+// The `Report` function is a method of the `Svc` struct that implements the `Report` method of the
+// `Service` interface. It fails with `pkg.ErrQuestionNotFound` when the question doesn't exist and
+// with `pkg.ErrDuplicateReport` when the user already has an open report on it.
+func (s *Svc) Report(userID string, questionID string, reason string) (Report, error) {
+	oid, err := primitive.ObjectIDFromHex(questionID)
+	if err != nil {
+		return Report{}, pkg.ErrInvalidQuestionID
+	}
+	existing, err := s.questions.ExistingIDs([]primitive.ObjectID{oid})
+	if err != nil {
+		return Report{}, err
+	}
+	if len(existing) == 0 {
+		return Report{}, pkg.ErrQuestionNotFound
+	}
+	return s.repo.Create(Report{
+		QuestionID: oid,
+		UserID:     userID,
+		Reason:     strings.TrimSpace(reason),
+		Status:     StatusOpen,
+		CreatedAt:  pkg.NowUTC(),
+	})
+}
+
+// The `List` function is a method of the `Svc` struct that implements the `List` method of the
+// `Service` interface. It fails with `pkg.ErrInvalidReportStatus` for an unknown status.
+func (s *Svc) List(status string, limit int, offset int) (Page, error) {
+	if !validStatus(status) {
+		return Page{}, pkg.ErrInvalidReportStatus
+	}
+	return s.repo.List(status, limit, offset)
+}
+
+// The `SetStatus` function is a method of the `Svc` struct that implements the `SetStatus` method of
+// the `Service` interface. It fails with `pkg.ErrInvalidReportStatus` for an unknown status.
+func (s *Svc) SetStatus(id string, status string, adminID string) (Report, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Report{}, pkg.ErrReportNotFound
+	}
+	status = strings.ToLower(strings.TrimSpace(status))
+	if !validStatus(status) {
+		return Report{}, pkg.ErrInvalidReportStatus
+	}
+	return s.repo.SetStatus(oid, status, adminID)
+}
+
+// The `WithContext` function is a method of the `Svc` struct that implements the `WithContext` method
+// of the `Service` interface. It returns a copy of the service bound to the given context.
+func (s *Svc) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.repo = s.repo.WithContext(ctx)
+	clone.questions = s.questions.WithContext(ctx)
+	return &clone
+}
+
+// The function creates a new instance of the question report service.
+func NewReportsService(repo *Repo, questions allquestions.Repository) Service {
+	return &Svc{
+		repo:      repo,
+		questions: questions,
+	}
+}
@@ -0,0 +1,83 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The Policy type describes how often and how patiently a failed operation is retried.
+// @property {int} Attempts - The total number of times the operation is tried, including the first.
+// @property Backoff - The wait before the first retry. It doubles after every further attempt.
+type Policy struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// `Default` is the policy used by the repositories. `main` replaces it with the values from
+// `MONGO_RETRY_ATTEMPTS` and `MONGO_RETRY_BACKOFF` at startup.
+var Default = Policy{Attempts: 3, Backoff: 100 * time.Millisecond}
+
+// `retryableCodes` are the MongoDB server error codes that indicate a transient condition, such as a
+// primary stepping down or a node shutting down, after which the same operation is expected to
+// succeed.
+var retryableCodes = []int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	262,   // ExceededTimeLimit
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// The function runs `op` and retries it with exponential backoff while it fails with a retryable
+// error, up to the policy's number of attempts. It stops early when `ctx` is done. The error of the
+// last attempt is returned.
+func (p Policy) Do(ctx context.Context, op func() error) error {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= p.Attempts || !Retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// The function reports whether an error returned by the MongoDB driver is transient. Missing documents,
+// duplicate keys and cancelled or expired contexts are never retryable, since trying again can't
+// change the outcome.
+func Retryable(err error) bool {
+	if errors.Is(err, mongo.ErrNoDocuments) || mongo.IsDuplicateKeyError(err) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) {
+		for _, code := range retryableCodes {
+			if serverErr.HasErrorCode(code) {
+				return true
+			}
+		}
+		return serverErr.HasErrorLabel("RetryableWriteError") || serverErr.HasErrorLabel("TransientTransactionError")
+	}
+	return false
+}
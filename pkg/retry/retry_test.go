@@ -0,0 +1,103 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The type flakyRepo stands in for a repository whose first calls fail with `err`.
+// @property {int} failures - The number of calls that fail before the calls succeed.
+// @property {int} calls - The number of calls made so far.
+type flakyRepo struct {
+	err      error
+	failures int
+	calls    int
+}
+
+func (r *flakyRepo) Read() error {
+	r.calls++
+	if r.calls <= r.failures {
+		return r.err
+	}
+	return nil
+}
+
+// `stepdown` is the error a read fails with while the primary steps down.
+var stepdown = mongo.CommandError{Code: 11600, Message: "interrupted at shutdown"}
+
+func TestDo(t *testing.T) {
+	policy := Policy{Attempts: 3, Backoff: time.Millisecond}
+	tests := []struct {
+		name    string
+		repo    *flakyRepo
+		wantErr error
+		calls   int
+	}{
+		{name: "fails twice then succeeds", repo: &flakyRepo{err: stepdown, failures: 2}, calls: 3},
+		{name: "network error", repo: &flakyRepo{err: mongo.CommandError{Labels: []string{"NetworkError"}}, failures: 1}, calls: 2},
+		{name: "attempts exhausted", repo: &flakyRepo{err: stepdown, failures: 5}, wantErr: stepdown, calls: 3},
+		{name: "not found", repo: &flakyRepo{err: mongo.ErrNoDocuments, failures: 1}, wantErr: mongo.ErrNoDocuments, calls: 1},
+		{
+			name:    "duplicate key",
+			repo:    &flakyRepo{err: mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, failures: 1},
+			wantErr: mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}},
+			calls:   1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Do(context.Background(), tt.repo.Read)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Do() = %v, want nil", err)
+			}
+			if tt.wantErr != nil && err == nil {
+				t.Errorf("Do() = nil, want %v", tt.wantErr)
+			}
+			if tt.repo.calls != tt.calls {
+				t.Errorf("the operation ran %d times, want %d", tt.repo.calls, tt.calls)
+			}
+		})
+	}
+
+	t.Run("context done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		repo := &flakyRepo{err: stepdown, failures: 2}
+		err := (Policy{Attempts: 3, Backoff: time.Hour}).Do(ctx, repo.Read)
+		var serverErr mongo.CommandError
+		if !errors.As(err, &serverErr) || serverErr.Code != stepdown.Code {
+			t.Errorf("Do() = %v, want the error of the first attempt", err)
+		}
+		if repo.calls != 1 {
+			t.Errorf("the operation ran %d times, want 1", repo.calls)
+		}
+	})
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "interrupted at shutdown", err: stepdown, want: true},
+		{name: "primary stepped down", err: mongo.CommandError{Code: 189}, want: true},
+		{name: "retryable write label", err: mongo.CommandError{Code: 1, Labels: []string{"RetryableWriteError"}}, want: true},
+		{name: "other server error", err: mongo.CommandError{Code: 2}, want: false},
+		{name: "not found", err: mongo.ErrNoDocuments, want: false},
+		{name: "duplicate key", err: mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, want: false},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, want: false},
+		{name: "plain error", err: errors.New("boom"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.want {
+				t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
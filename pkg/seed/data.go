@@ -0,0 +1,45 @@
+package seed
+
+import (
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+)
+
+// `users` are the sample accounts created by the seed command. They are meant for local development
+// only; every account shares the password "sigmacoder123".
+var users = []auth.InUser{
+	{
+		Name:        "Sigma Admin",
+		Password:    "sigmacoder123",
+		PhoneNumber: "+15550000001",
+		Email:       "admin@sigmacoder.dev",
+		Username:    "admin",
+		DateOfBirth: "1995-01-01",
+		Gender:      "other",
+		UserType:    "admin",
+	},
+	{
+		Name:        "Sigma Learner",
+		Password:    "sigmacoder123",
+		PhoneNumber: "+15550000002",
+		Email:       "learner@sigmacoder.dev",
+		Username:    "learner",
+		DateOfBirth: "2000-06-15",
+		Gender:      "female",
+		UserType:    "user",
+	},
+}
+
+// `questions` are the sample problems created by the seed command, identified by their numeric `Id`.
+var questions = []allquestions.AllQuestion{
+	{Id: 1, Name: "Two Sum", Category: "Arrays", Level: "Easy", Link: "https://leetcode.com/problems/two-sum/", Videourl: "https://www.youtube.com/watch?v=KLlXCFG5TnA"},
+	{Id: 2, Name: "Best Time to Buy and Sell Stock", Category: "Arrays", Level: "Easy", Link: "https://leetcode.com/problems/best-time-to-buy-and-sell-stock/"},
+	{Id: 3, Name: "Product of Array Except Self", Category: "Arrays", Level: "Medium", Link: "https://leetcode.com/problems/product-of-array-except-self/"},
+	{Id: 4, Name: "Valid Anagram", Category: "Strings", Level: "Easy", Link: "https://leetcode.com/problems/valid-anagram/"},
+	{Id: 5, Name: "Longest Substring Without Repeating Characters", Category: "Strings", Level: "Medium", Link: "https://leetcode.com/problems/longest-substring-without-repeating-characters/"},
+	{Id: 6, Name: "Reverse Linked List", Category: "Linked List", Level: "Easy", Link: "https://leetcode.com/problems/reverse-linked-list/"},
+	{Id: 7, Name: "Merge k Sorted Lists", Category: "Linked List", Level: "Hard", Link: "https://leetcode.com/problems/merge-k-sorted-lists/"},
+	{Id: 8, Name: "Climbing Stairs", Category: "Dynamic Programming", Level: "Easy", Link: "https://leetcode.com/problems/climbing-stairs/"},
+	{Id: 9, Name: "Coin Change", Category: "Dynamic Programming", Level: "Medium", Link: "https://leetcode.com/problems/coin-change/"},
+	{Id: 10, Name: "Number of Islands", Category: "Graphs", Level: "Medium", Link: "https://leetcode.com/problems/number-of-islands/"},
+}
@@ -0,0 +1,61 @@
+package seed
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// The Result type reports what a seed run did.
+// @property {int} UsersCreated - The number of sample users inserted by this run.
+// @property {int} UsersSkipped - The number of sample users that already existed.
+// @property {int} QuestionsCreated - The number of sample questions inserted by this run.
+// @property {int} QuestionsSkipped - The number of sample questions that already existed.
+type Result struct {
+	UsersCreated     int
+	UsersSkipped     int
+	QuestionsCreated int
+	QuestionsSkipped int
+}
+
+// The function populates a fresh database with the sample users and questions from `data.go`.
+//
+// Seeding is tracked per record rather than with a global marker: a sample user counts as seeded when
+// a user with the same email exists, and a sample question when a question with the same numeric `Id`
+// exists. Records that are already present are left untouched, so running the command again only
+// fills in whatever is missing and is always safe.
+func Run(userRepo *auth.Repo, questionRepo allquestions.Repository) (Result, error) {
+	var result Result
+	for _, in := range users {
+		_, err := userRepo.ReadByEmail(in.Email)
+		if err == nil {
+			result.UsersSkipped++
+			continue
+		}
+		if !errors.Is(err, pkg.ErrUserNotFound) {
+			return result, err
+		}
+		if _, err := userRepo.Create(in); err != nil {
+			return result, err
+		}
+		result.UsersCreated++
+	}
+	for _, question := range questions {
+		_, err := questionRepo.ReadByNumber(question.Id)
+		if err == nil {
+			result.QuestionsSkipped++
+			continue
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return result, err
+		}
+		if _, err := questionRepo.Create(question); err != nil {
+			return result, err
+		}
+		result.QuestionsCreated++
+	}
+	return result, nil
+}
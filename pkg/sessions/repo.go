@@ -0,0 +1,149 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/retry"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// `touchInterval` is how stale `LastUsedAt` has to be before an authenticated request refreshes it.
+const touchInterval = time.Minute
+
+// Repository is an interface that defines the operations that can be performed on the Session entity.
+type Repository interface {
+	Create(userID string, device Device, expiresAt time.Time) (Session, error)
+	ListActive(userID string) ([]Session, error)
+	Revoke(userID string, id string) error
+	RevokeAll(userID string) (int64, error)
+	Touch(id string) error
+}
+
+// Repo is the struct that implements the Repository interface on top of the `sessions` collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `Create` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It records a new session for the user and returns it.
+func (s *Repo) Create(userID string, device Device, expiresAt time.Time) (Session, error) {
+	now := pkg.NowUTC()
+	session := Session{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		UserAgent:  device.UserAgent,
+		IP:         device.IP,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	err := retry.Default.Do(s.context, func() error {
+		_, err := s.db.InsertOne(s.context, session)
+		return err
+	})
+	return session, err
+}
+
+// The `ListActive` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the user's sessions that are neither revoked nor expired, most recently used
+// first.
+func (s *Repo) ListActive(userID string) ([]Session, error) {
+	list := []Session{}
+	filter := bson.M{"userid": userID, "revokedAt": bson.M{"$exists": false}, "expiresAt": bson.M{"$gt": time.Now()}}
+	opts := options.Find().SetSort(bson.M{"lastUsedAt": -1})
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, filter, opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &list)
+	})
+	return list, err
+}
+
+// The `Revoke` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It revokes one of the user's active sessions, after which its token is rejected. Sessions of other
+// users are reported as `pkg.ErrSessionNotFound`, the same as sessions that don't exist.
+func (s *Repo) Revoke(userID string, id string) error {
+	filter := bson.M{"_id": id, "userid": userID, "revokedAt": bson.M{"$exists": false}}
+	var result *mongo.UpdateResult
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		result, err = s.db.UpdateOne(s.context, filter, bson.M{"$set": bson.M{"revokedAt": pkg.NowUTC()}})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return pkg.ErrSessionNotFound
+	}
+	return nil
+}
+
+// The `RevokeAll` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It revokes every session of the user that isn't revoked yet, logging them out everywhere,
+// and returns the number of sessions revoked.
+func (s *Repo) RevokeAll(userID string) (int64, error) {
+	filter := bson.M{"userid": userID, "revokedAt": bson.M{"$exists": false}}
+	var result *mongo.UpdateResult
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		result, err = s.db.UpdateMany(s.context, filter, bson.M{"$set": bson.M{"revokedAt": pkg.NowUTC()}})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// The `Touch` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It checks that the session is still active and refreshes its `LastUsedAt`. It returns
+// `pkg.ErrSessionRevoked` for sessions that were revoked, expired or never existed.
+func (s *Repo) Touch(id string) error {
+	var session Session
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, bson.M{"_id": id}).Decode(&session)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return pkg.ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+	now := pkg.NowUTC()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return pkg.ErrSessionRevoked
+	}
+	if now.Sub(session.LastUsedAt) < touchInterval {
+		return nil
+	}
+	return retry.Default.Do(s.context, func() error {
+		_, err := s.db.UpdateOne(s.context, bson.M{"_id": id}, bson.M{"$set": bson.M{"lastUsedAt": now}})
+		return err
+	})
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `sessions` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("sessions", opts...), context: ctx}
+}
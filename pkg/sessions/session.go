@@ -0,0 +1,33 @@
+package sessions
+
+import "time"
+
+// The Session type is a single logged in device. Every token issued at sign up or login carries the ID
+// of its session in the `sid` claim, so the session can be revoked before the token expires.
+// @property {string} ID - A UUID identifying the session.
+// @property {string} UserID - The ID of the user the session belongs to.
+// @property {string} UserAgent - The `User-Agent` header of the request that created the session.
+// @property {string} IP - The client IP address the session was created from.
+// @property CreatedAt - When the session was created.
+// @property LastUsedAt - When a request last authenticated with the session's token. It is refreshed at
+// most once per `touchInterval` to keep authenticated requests from writing on every call.
+// @property ExpiresAt - When the session's token expires.
+// @property RevokedAt - When the session was revoked, or nil while it is active.
+type Session struct {
+	ID         string     `json:"id" bson:"_id"`
+	UserID     string     `json:"-" bson:"userid"`
+	UserAgent  string     `json:"userAgent" bson:"userAgent"`
+	IP         string     `json:"ip" bson:"ip"`
+	CreatedAt  time.Time  `json:"createdAt" bson:"createdAt"`
+	LastUsedAt time.Time  `json:"lastUsedAt" bson:"lastUsedAt"`
+	ExpiresAt  time.Time  `json:"expiresAt" bson:"expiresAt"`
+	RevokedAt  *time.Time `json:"-" bson:"revokedAt,omitempty"`
+}
+
+// The Device type describes the client a session is created for.
+// @property {string} UserAgent - The `User-Agent` header of the request.
+// @property {string} IP - The client IP address of the request.
+type Device struct {
+	UserAgent string
+	IP        string
+}
@@ -0,0 +1,98 @@
+package solutions
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the Solution entity.
+type Repository interface {
+	Create(solution Solution) (Solution, error)
+	ListByQuestion(questionID primitive.ObjectID) ([]Solution, error)
+	Upvote(id primitive.ObjectID, userID string) error
+}
+
+// Repo is the struct that implements the Repository interface on top of the `solutions` collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `Create` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It stores a new solution and returns it with its assigned ID.
+func (s *Repo) Create(solution Solution) (Solution, error) {
+	solution.ID = primitive.NewObjectID()
+	solution.Upvoters = []string{}
+	err := retry.Default.Do(s.context, func() error {
+		_, err := s.db.InsertOne(s.context, solution)
+		return err
+	})
+	return solution, err
+}
+
+// The `ListByQuestion` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the solutions of a question, most upvoted first and newest first among equals.
+func (s *Repo) ListByQuestion(questionID primitive.ObjectID) ([]Solution, error) {
+	list := []Solution{}
+	opts := options.Find().SetSort(bson.D{{Key: "upvotes", Value: -1}, {Key: "createdAt", Value: -1}})
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Find(s.context, bson.M{"questionid": questionID}, opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &list)
+	})
+	return list, err
+}
+
+// The `Upvote` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It records the user's upvote in a single conditional update that only matches when the user isn't
+// among the upvoters yet, so concurrent or repeated votes can never count twice. It returns
+// `pkg.ErrAlreadyUpvoted` for a repeated vote and `pkg.ErrSolutionNotFound` for an unknown solution.
+func (s *Repo) Upvote(id primitive.ObjectID, userID string) error {
+	filter := bson.M{"_id": id, "upvoters": bson.M{"$ne": userID}}
+	update := bson.M{"$addToSet": bson.M{"upvoters": userID}, "$inc": bson.M{"upvotes": 1}}
+	var result *mongo.UpdateResult
+	err := retry.Default.Do(s.context, func() error {
+		var err error
+		result, err = s.db.UpdateOne(s.context, filter, update)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 1 {
+		return nil
+	}
+	err = s.db.FindOne(s.context, bson.M{"_id": id}).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return pkg.ErrSolutionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return pkg.ErrAlreadyUpvoted
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `solutions` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("solutions", opts...), context: ctx}
+}
@@ -0,0 +1,130 @@
+package solutions
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Service interface defines the solution operations available to the HTTP handlers.
+// @property Submit - Submit shares a user's solution to a question.
+// @property List - List returns the solutions of a question together with their authors' public
+// profiles.
+// @property Upvote - Upvote records a user's upvote on a solution, at most once per user.
+// @property WithContext - WithContext returns a copy of the service whose repository calls run with the
+// given context.
+type Service interface {
+	Submit(userID string, questionID string, in InSolution) (Solution, error)
+	List(questionID string) ([]SolutionView, error)
+	Upvote(userID string, solutionID string) error
+	WithContext(ctx context.Context) Service
+}
+
+// The type Svc implements the Service interface.
+// @property repo - The repository solutions are stored in.
+// @property questions - The question repository, used to check that a question exists.
+// @property users - The user repository, used to look up the authors of solutions.
+type Svc struct {
+	repo      *Repo
+	questions allquestions.Repository
+	users     *auth.Repo
+}
+
+// The `Submit` function is a method of the `Svc` struct that implements the `Submit` method of the
+// `Service` interface.
+func (s *Svc) Submit(userID string, questionID string, in InSolution) (Solution, error) {
+	oid, err := s.questionID(questionID)
+	if err != nil {
+		return Solution{}, err
+	}
+	if strings.TrimSpace(in.Language) == "" || strings.TrimSpace(in.Code) == "" {
+		return Solution{}, pkg.ErrInvalidSolution
+	}
+	return s.repo.Create(Solution{
+		QuestionID:  oid,
+		UserID:      userID,
+		Language:    strings.TrimSpace(in.Language),
+		Code:        in.Code,
+		Explanation: in.Explanation,
+		CreatedAt:   pkg.NowUTC(),
+	})
+}
+
+// The `List` function is a method of the `Svc` struct that implements the `List` method of the
+// `Service` interface. Authors are looked up in a single query for the whole list.
+func (s *Svc) List(questionID string) ([]SolutionView, error) {
+	views := []SolutionView{}
+	oid, err := s.questionID(questionID)
+	if err != nil {
+		return views, err
+	}
+	list, err := s.repo.ListByQuestion(oid)
+	if err != nil || len(list) == 0 {
+		return views, err
+	}
+	var authorIDs []string
+	for _, solution := range list {
+		authorIDs = append(authorIDs, solution.UserID)
+	}
+	authors, err := s.users.ReadMany(authorIDs)
+	if err != nil {
+		return views, err
+	}
+	byID := make(map[string]auth.PublicUser, len(authors))
+	for _, author := range authors {
+		byID[author.ID] = author.ToPublicUser()
+	}
+	for _, solution := range list {
+		views = append(views, SolutionView{Solution: solution, Author: byID[solution.UserID]})
+	}
+	return views, nil
+}
+
+// The `Upvote` function is a method of the `Svc` struct that implements the `Upvote` method of the
+// `Service` interface.
+func (s *Svc) Upvote(userID string, solutionID string) error {
+	oid, err := primitive.ObjectIDFromHex(solutionID)
+	if err != nil {
+		return pkg.ErrSolutionNotFound
+	}
+	return s.repo.Upvote(oid, userID)
+}
+
+// The function parses a question ID and checks that the question exists.
+func (s *Svc) questionID(questionID string) (primitive.ObjectID, error) {
+	oid, err := primitive.ObjectIDFromHex(questionID)
+	if err != nil {
+		return oid, pkg.ErrInvalidQuestionID
+	}
+	existing, err := s.questions.ExistingIDs([]primitive.ObjectID{oid})
+	if err != nil {
+		return oid, err
+	}
+	if len(existing) == 0 {
+		return oid, pkg.ErrQuestionNotFound
+	}
+	return oid, nil
+}
+
+// The `WithContext` function is a method of the `Svc` struct that implements the `WithContext` method
+// of the `Service` interface. It returns a copy of the service bound to the given context.
+func (s *Svc) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.repo = s.repo.WithContext(ctx)
+	clone.questions = s.questions.WithContext(ctx)
+	clone.users = s.users.WithContext(ctx)
+	return &clone
+}
+
+// The function creates a new instance of the solutions service.
+func NewSolutionsService(repo *Repo, questions allquestions.Repository, users *auth.Repo) Service {
+	return &Svc{
+		repo:      repo,
+		questions: questions,
+		users:     users,
+	}
+}
@@ -0,0 +1,132 @@
+package solutions
+
+import (
+	"encoding/json"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/auth"
+	"sigmacoder/pkg/database/dbtest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns a solutions service whose repositories talk to the mocked deployment.
+func newService(mt *mtest.T) Service {
+	return NewSolutionsService(NewRepo(mt.DB).(*Repo), allquestions.NewRepo(mt.DB), auth.NewRepo(mt.DB).(*auth.Repo))
+}
+
+func TestSubmit(t *testing.T) {
+	question := primitive.NewObjectID()
+
+	dbtest.Run(t, "stored for the author", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": question}), dbtest.Written(1))
+		solution, err := newService(mt).Submit("user-1", question.Hex(), InSolution{Language: " go ", Code: "return nil", Explanation: "nothing to do"})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if solution.ID.IsZero() || solution.UserID != "user-1" || solution.Language != "go" {
+			mt.Errorf("Submit() = %+v, want a new solution by user-1 in go", solution)
+		}
+		dbtest.NextCommand(mt, "find")
+		insert := dbtest.NextCommand(mt, "insert")
+		dbtest.ExpectField(mt, insert, "documents", bson.A{solution})
+	})
+
+	dbtest.Run(t, "without code", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": question}))
+		if _, err := newService(mt).Submit("user-1", question.Hex(), InSolution{Language: "go", Code: "  "}); !errors.Is(err, pkg.ErrInvalidSolution) {
+			mt.Errorf("Submit() = %v, want %v", err, pkg.ErrInvalidSolution)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "unknown question", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if _, err := newService(mt).Submit("user-1", question.Hex(), InSolution{Language: "go", Code: "return nil"}); !errors.Is(err, pkg.ErrQuestionNotFound) {
+			mt.Errorf("Submit() = %v, want %v", err, pkg.ErrQuestionNotFound)
+		}
+	})
+
+	dbtest.Run(t, "invalid question ID", func(mt *mtest.T) {
+		if _, err := newService(mt).Submit("user-1", "nope", InSolution{Language: "go", Code: "return nil"}); !errors.Is(err, pkg.ErrInvalidQuestionID) {
+			mt.Errorf("Submit() = %v, want %v", err, pkg.ErrInvalidQuestionID)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestList(t *testing.T) {
+	question := primitive.NewObjectID()
+	author := auth.User{ID: "user-1", Name: "Ada", Username: "ada", Email: "ada@example.com", PhoneNumber: "+15551234567", Password: "hash"}
+	solution := Solution{ID: primitive.NewObjectID(), QuestionID: question, UserID: author.ID, Language: "go", Code: "return nil", Upvotes: 2, Upvoters: []string{"user-2", "user-3"}}
+
+	dbtest.Run(t, "authors without PII", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": question}), dbtest.Cursor(mt, solution), dbtest.Cursor(mt, author))
+		views, err := newService(mt).List(question.Hex())
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if len(views) != 1 || views[0].Upvotes != 2 || views[0].Author.Username != "ada" {
+			mt.Fatalf("List() = %+v, want the solution with its upvotes and author", views)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "sort", bson.D{{Key: "upvotes", Value: -1}, {Key: "createdAt", Value: -1}})
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": bson.M{"$in": bson.A{author.ID}}})
+
+		data, err := json.Marshal(views)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		for _, private := range []string{author.ID, author.Email, author.PhoneNumber, author.Password, "user-2"} {
+			if strings.Contains(string(data), private) {
+				mt.Errorf("the listing contains %q: %s", private, data)
+			}
+		}
+	})
+
+	dbtest.Run(t, "no solutions", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": question}), dbtest.Cursor(mt))
+		views, err := newService(mt).List(question.Hex())
+		if err != nil || len(views) != 0 {
+			mt.Errorf("List() = %v, %v, want no solutions", views, err)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestUpvote(t *testing.T) {
+	solution := primitive.NewObjectID()
+
+	dbtest.Run(t, "first vote", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Written(1))
+		if err := newService(mt).Upvote("user-2", solution.Hex()); err != nil {
+			mt.Fatal(err)
+		}
+		update := dbtest.NextCommand(mt, "update")["updates"].(bson.A)[0].(bson.M)
+		dbtest.ExpectField(mt, update, "q", bson.M{"_id": solution, "upvoters": bson.M{"$ne": "user-2"}})
+		dbtest.ExpectField(mt, update, "u", bson.M{"$addToSet": bson.M{"upvoters": "user-2"}, "$inc": bson.M{"upvotes": 1}})
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "duplicate vote", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Written(0), dbtest.Cursor(mt, bson.M{"_id": solution}))
+		if err := newService(mt).Upvote("user-2", solution.Hex()); !errors.Is(err, pkg.ErrAlreadyUpvoted) {
+			mt.Errorf("Upvote() = %v, want %v", err, pkg.ErrAlreadyUpvoted)
+		}
+	})
+
+	dbtest.Run(t, "unknown solution", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Written(0), dbtest.Cursor(mt))
+		if err := newService(mt).Upvote("user-2", solution.Hex()); !errors.Is(err, pkg.ErrSolutionNotFound) {
+			mt.Errorf("Upvote() = %v, want %v", err, pkg.ErrSolutionNotFound)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package solutions
+
+import (
+	"sigmacoder/pkg/auth"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Solution type is a solution to a question shared by a user.
+// @property ID - The ObjectID of the solution.
+// @property QuestionID - The ObjectID of the question it solves.
+// @property {string} UserID - The ID of the author. It is not exposed directly; listings carry the
+// author's public profile instead.
+// @property {string} Language - The programming language of the code.
+// @property {string} Code - The source code of the solution.
+// @property {string} Explanation - The author's explanation of the approach.
+// @property {int64} Upvotes - The number of users that upvoted the solution.
+// @property {[]string} Upvoters - The IDs of the users that upvoted it, which is what limits every user
+// to a single vote.
+// @property CreatedAt - When the solution was submitted.
+type Solution struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	QuestionID  primitive.ObjectID `json:"questionId" bson:"questionid"`
+	UserID      string             `json:"-" bson:"userid"`
+	Language    string             `json:"language" bson:"language"`
+	Code        string             `json:"code" bson:"code"`
+	Explanation string             `json:"explanation" bson:"explanation"`
+	Upvotes     int64              `json:"upvotes" bson:"upvotes"`
+	Upvoters    []string           `json:"-" bson:"upvoters"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// The InSolution type is the data a user submits to share a solution.
+type InSolution struct {
+	Language    string `json:"language"`
+	Code        string `json:"code"`
+	Explanation string `json:"explanation"`
+}
+
+// The SolutionView type is a solution as it is listed to other users, together with the public profile
+// of its author.
+type SolutionView struct {
+	Solution
+	Author auth.PublicUser `json:"author"`
+}
@@ -0,0 +1,111 @@
+package submissions
+
+import (
+	"math"
+	"sigmacoder/pkg/retry"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// `rateUnavailable` is the acceptance rate of a question nobody's code has been judged for yet.
+const rateUnavailable = "N/A"
+
+// The Acceptance type is how often the code submitted for a question is accepted. Only judged
+// submissions count; pending ones and the ones the judge failed to run don't.
+// @property {int64} Accepted - The number of accepted submissions.
+// @property {int64} Total - The number of accepted and rejected submissions.
+// @property Rate - `Accepted` divided by `Total`, rounded to three decimals, or "N/A" when `Total` is 0.
+type Acceptance struct {
+	Accepted int64       `json:"accepted"`
+	Total    int64       `json:"total"`
+	Rate     interface{} `json:"rate"`
+}
+
+// The function returns the acceptance of `accepted` out of `total` judged submissions.
+func newAcceptance(accepted int64, total int64) Acceptance {
+	acceptance := Acceptance{Accepted: accepted, Total: total, Rate: rateUnavailable}
+	if total > 0 {
+		acceptance.Rate = math.Round(float64(accepted)/float64(total)*1000) / 1000
+	}
+	return acceptance
+}
+
+// The `Acceptance` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It counts the accepted and rejected submissions for the question in a single aggregation.
+func (s *Repo) Acceptance(questionID primitive.ObjectID) (Acceptance, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"questionid": questionID, "status": bson.M{"$in": bson.A{StatusAccepted, StatusRejected}}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      nil,
+			"accepted": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", StatusAccepted}}, 1, 0}}},
+			"total":    bson.M{"$sum": 1},
+		}}},
+	}
+	var result []struct {
+		Accepted int64 `bson:"accepted"`
+		Total    int64 `bson:"total"`
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &result)
+	})
+	if err != nil || len(result) == 0 {
+		return newAcceptance(0, 0), err
+	}
+	return newAcceptance(result[0].Accepted, result[0].Total), nil
+}
+
+// The type `cachedAcceptance` is an acceptance together with when it has to be recomputed.
+type cachedAcceptance struct {
+	acceptance Acceptance
+	expiresAt  time.Time
+}
+
+// The AcceptanceCache type keeps the acceptance of every question for a short while, so showing a
+// popular question doesn't aggregate its submissions on every request.
+// @property mu - Guards `entries`.
+// @property entries - The cached acceptances, keyed by question.
+// @property ttl - How long an acceptance is reused before it is recomputed. 0 turns the cache off.
+// @property repo - The repository acceptances are computed from.
+type AcceptanceCache struct {
+	mu      sync.Mutex
+	entries map[primitive.ObjectID]cachedAcceptance
+	ttl     time.Duration
+	repo    *Repo
+}
+
+// The function returns the acceptance of the question, computing it when it isn't cached or the cached
+// one has expired. Expired entries are only replaced, never swept, which is fine for a bounded number
+// of questions.
+func (a *AcceptanceCache) Get(questionID primitive.ObjectID) (Acceptance, error) {
+	now := time.Now()
+	a.mu.Lock()
+	entry, ok := a.entries[questionID]
+	a.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.acceptance, nil
+	}
+	acceptance, err := a.repo.Acceptance(questionID)
+	if err != nil {
+		return acceptance, err
+	}
+	if a.ttl > 0 {
+		a.mu.Lock()
+		a.entries[questionID] = cachedAcceptance{acceptance: acceptance, expiresAt: now.Add(a.ttl)}
+		a.mu.Unlock()
+	}
+	return acceptance, nil
+}
+
+// The function creates an acceptance cache that computes acceptances from the given repository and
+// keeps them for `ttl`.
+func NewAcceptanceCache(repo *Repo, ttl time.Duration) *AcceptanceCache {
+	return &AcceptanceCache{entries: map[primitive.ObjectID]cachedAcceptance{}, ttl: ttl, repo: repo}
+}
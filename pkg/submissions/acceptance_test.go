@@ -0,0 +1,104 @@
+package submissions
+
+import (
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function answers the acceptance aggregation the way a real deployment would for the seeded
+// submissions: only the judged ones of the question count.
+func acceptanceOf(mt *mtest.T, questionID primitive.ObjectID, seeded []Submission) bson.D {
+	var accepted, total int
+	for _, submission := range seeded {
+		if submission.QuestionID != questionID || (submission.Status != StatusAccepted && submission.Status != StatusRejected) {
+			continue
+		}
+		total++
+		if submission.Status == StatusAccepted {
+			accepted++
+		}
+	}
+	if total == 0 {
+		return dbtest.Cursor(mt)
+	}
+	return dbtest.Cursor(mt, bson.M{"_id": nil, "accepted": accepted, "total": total})
+}
+
+func TestAcceptance(t *testing.T) {
+	questionID, otherID := primitive.NewObjectID(), primitive.NewObjectID()
+	seeded := []Submission{
+		{QuestionID: questionID, Status: StatusAccepted},
+		{QuestionID: questionID, Status: StatusRejected},
+		{QuestionID: questionID, Status: StatusAccepted},
+		{QuestionID: questionID, Status: StatusPending},
+		{QuestionID: questionID, Status: StatusRunning},
+		{QuestionID: questionID, Status: StatusError},
+		{QuestionID: otherID, Status: StatusRejected},
+	}
+	tests := []struct {
+		name     string
+		question primitive.ObjectID
+		seeded   []Submission
+		want     Acceptance
+	}{
+		{name: "mixed outcomes", question: questionID, seeded: seeded, want: Acceptance{Accepted: 2, Total: 3, Rate: 0.667}},
+		{name: "only rejected", question: otherID, seeded: seeded, want: Acceptance{Accepted: 0, Total: 1, Rate: 0.0}},
+		{name: "nothing judged", question: questionID, seeded: seeded[3:6], want: Acceptance{Rate: "N/A"}},
+		{name: "no submissions", question: primitive.NewObjectID(), want: Acceptance{Rate: "N/A"}},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			mt.AddMockResponses(acceptanceOf(mt, tt.question, tt.seeded))
+			acceptance, err := NewRepo(mt.DB).(*Repo).Acceptance(tt.question)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if acceptance != tt.want {
+				mt.Errorf("Acceptance() = %+v, want %+v", acceptance, tt.want)
+			}
+			pipeline := dbtest.NextCommand(mt, "aggregate")["pipeline"].(bson.A)
+			dbtest.ExpectField(mt, pipeline[0].(bson.M), "$match", bson.M{"questionid": tt.question, "status": bson.M{"$in": bson.A{StatusAccepted, StatusRejected}}})
+		})
+	}
+}
+
+func TestAcceptanceCache(t *testing.T) {
+	questionID := primitive.NewObjectID()
+
+	dbtest.Run(t, "reused until it expires", func(mt *mtest.T) {
+		cache := NewAcceptanceCache(NewRepo(mt.DB).(*Repo), time.Minute)
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"accepted": 1, "total": 4}))
+		for i := 0; i < 3; i++ {
+			acceptance, err := cache.Get(questionID)
+			if err != nil {
+				mt.Fatal(err)
+			}
+			if acceptance.Rate != 0.25 {
+				mt.Errorf("rate = %v, want 0.25", acceptance.Rate)
+			}
+		}
+		dbtest.NextCommand(mt, "aggregate")
+		dbtest.ExpectNoCommand(mt)
+
+		cache.entries[questionID] = cachedAcceptance{acceptance: cache.entries[questionID].acceptance, expiresAt: time.Now().Add(-time.Second)}
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"accepted": 2, "total": 4}))
+		if acceptance, err := cache.Get(questionID); err != nil || acceptance.Rate != 0.5 {
+			mt.Errorf("Get() after expiry = %+v, %v, want it recomputed", acceptance, err)
+		}
+		dbtest.NextCommand(mt, "aggregate")
+	})
+
+	dbtest.Run(t, "caching off", func(mt *mtest.T) {
+		cache := NewAcceptanceCache(NewRepo(mt.DB).(*Repo), 0)
+		mt.AddMockResponses(dbtest.Cursor(mt), dbtest.Cursor(mt))
+		cache.Get(questionID)
+		cache.Get(questionID)
+		dbtest.NextCommand(mt, "aggregate")
+		dbtest.NextCommand(mt, "aggregate")
+	})
+}
@@ -0,0 +1,23 @@
+package submissions
+
+import (
+	"strings"
+	"time"
+)
+
+// The FakeJudge type is a Judge that doesn't run anything. It accepts every submission after `Delay`
+// unless its code contains "FAIL", which makes it easy to walk a submission through every status
+// locally before a real judge is wired in. It must never be used in production.
+// @property Delay - How long judging pretends to take.
+type FakeJudge struct {
+	Delay time.Duration
+}
+
+// The `Submit` function is a method of the `FakeJudge` struct that implements the `Judge` interface.
+func (j FakeJudge) Submit(language string, code string, questionID string) (Result, error) {
+	time.Sleep(j.Delay)
+	if strings.Contains(code, "FAIL") {
+		return Result{Passed: false, Message: "fake judge: the code asked to fail"}, nil
+	}
+	return Result{Passed: true, Message: "fake judge: accepted without running", RuntimeMillis: j.Delay.Milliseconds()}, nil
+}
@@ -0,0 +1,92 @@
+package submissions
+
+import (
+	"context"
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the Submission
+// entity.
+type Repository interface {
+	Create(submission Submission) (Submission, error)
+	Read(id primitive.ObjectID) (Submission, error)
+	SetStatus(id primitive.ObjectID, status string, result *Result) error
+	Acceptance(questionID primitive.ObjectID) (Acceptance, error)
+	EnsureIndexes() error
+}
+
+// Repo is the struct that implements the Repository interface on top of the `submissions` collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `Create` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It stores a new submission and returns it with its assigned ID.
+func (s *Repo) Create(submission Submission) (Submission, error) {
+	submission.ID = primitive.NewObjectID()
+	err := retry.Default.Do(s.context, func() error {
+		_, err := s.db.InsertOne(s.context, submission)
+		return err
+	})
+	return submission, err
+}
+
+// The `Read` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It returns the submission with the given ID, or `pkg.ErrSubmissionNotFound`.
+func (s *Repo) Read(id primitive.ObjectID) (Submission, error) {
+	var submission Submission
+	err := retry.Default.Do(s.context, func() error {
+		return s.db.FindOne(s.context, bson.M{"_id": id}).Decode(&submission)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return submission, pkg.ErrSubmissionNotFound
+	}
+	return submission, err
+}
+
+// The `SetStatus` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It moves a submission to the given status, storing the judge's result along with it when
+// there is one.
+func (s *Repo) SetStatus(id primitive.ObjectID, status string, result *Result) error {
+	set := bson.M{"status": status, "updatedAt": pkg.NowUTC()}
+	if result != nil {
+		set["result"] = result
+	}
+	return retry.Default.Do(s.context, func() error {
+		_, err := s.db.UpdateOne(s.context, bson.M{"_id": id}, bson.M{"$set": set})
+		return err
+	})
+}
+
+// The `EnsureIndexes` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It creates the index behind counting a question's submissions by status.
+func (s *Repo) EnsureIndexes() error {
+	_, err := s.db.Indexes().CreateOne(s.context, mongo.IndexModel{
+		Keys: bson.D{{Key: "questionid", Value: 1}, {Key: "status", Value: 1}},
+	})
+	return err
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `submissions` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("submissions", opts...), context: ctx}
+}
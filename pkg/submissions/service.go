@@ -0,0 +1,130 @@
+package submissions
+
+import (
+	"context"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/logging"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Service interface defines the code submission operations available to the HTTP handlers.
+// @property Submit - Submit stores a user's code as a pending submission and hands it to the judge in
+// the background.
+// @property Get - Get returns one of the user's submissions, so its status can be polled.
+// @property WithContext - WithContext returns a copy of the service whose repository calls run with the
+// given context.
+type Service interface {
+	Submit(userID string, questionID string, in InSubmission) (Submission, error)
+	Get(userID string, submissionID string) (Submission, error)
+	WithContext(ctx context.Context) Service
+}
+
+// The type Svc implements the Service interface.
+// @property repo - The repository submissions are stored in.
+// @property questions - The question repository, used to check that a question exists.
+// @property judge - The judge that runs the submitted code. When it is nil, submissions stay pending
+// until one is configured.
+type Svc struct {
+	repo      *Repo
+	questions allquestions.Repository
+	judge     Judge
+}
+
+// The `Submit` function is a method of the `Svc` struct that implements the `Submit` method of the
+// `Service` interface. It returns as soon as the submission is stored; judging happens in the
+// background, since running code can take far longer than a request may.
+func (s *Svc) Submit(userID string, questionID string, in InSubmission) (Submission, error) {
+	oid, err := primitive.ObjectIDFromHex(questionID)
+	if err != nil {
+		return Submission{}, pkg.ErrInvalidQuestionID
+	}
+	existing, err := s.questions.ExistingIDs([]primitive.ObjectID{oid})
+	if err != nil {
+		return Submission{}, err
+	}
+	if len(existing) == 0 {
+		return Submission{}, pkg.ErrQuestionNotFound
+	}
+	language := strings.ToLower(strings.TrimSpace(in.Language))
+	if language == "" || strings.TrimSpace(in.Code) == "" {
+		return Submission{}, pkg.ErrInvalidSubmission
+	}
+	now := pkg.NowUTC()
+	submission, err := s.repo.Create(Submission{
+		QuestionID: oid,
+		UserID:     userID,
+		Language:   language,
+		Code:       in.Code,
+		Status:     StatusPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+	if err != nil {
+		return submission, err
+	}
+	if s.judge != nil {
+		go s.run(submission)
+	}
+	return submission, nil
+}
+
+// The function runs a submission through the judge and records the outcome. It runs detached from the
+// request that created the submission, so it uses a context of its own.
+func (s *Svc) run(submission Submission) {
+	repo := s.repo.WithContext(context.Background())
+	if err := repo.SetStatus(submission.ID, StatusRunning, nil); err != nil {
+		logging.Errorf("submissions: could not mark %s running: %v", submission.ID.Hex(), err)
+		return
+	}
+	result, err := s.judge.Submit(submission.Language, submission.Code, submission.QuestionID.Hex())
+	status := StatusRejected
+	switch {
+	case err != nil:
+		status = StatusError
+		result = Result{Message: err.Error()}
+	case result.Passed:
+		status = StatusAccepted
+	}
+	if err := repo.SetStatus(submission.ID, status, &result); err != nil {
+		logging.Errorf("submissions: could not record the result of %s: %v", submission.ID.Hex(), err)
+	}
+}
+
+// The `Get` function is a method of the `Svc` struct that implements the `Get` method of the
+// `Service` interface. Users can only see their own submissions; anybody else's yield
+// `pkg.ErrSubmissionNotFound`, so their existence isn't revealed.
+func (s *Svc) Get(userID string, submissionID string) (Submission, error) {
+	oid, err := primitive.ObjectIDFromHex(submissionID)
+	if err != nil {
+		return Submission{}, pkg.ErrSubmissionNotFound
+	}
+	submission, err := s.repo.Read(oid)
+	if err != nil {
+		return Submission{}, err
+	}
+	if submission.UserID != userID {
+		return Submission{}, pkg.ErrSubmissionNotFound
+	}
+	return submission, nil
+}
+
+// The `WithContext` function is a method of the `Svc` struct that implements the `WithContext` method
+// of the `Service` interface. It returns a copy of the service bound to the given context.
+func (s *Svc) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.repo = s.repo.WithContext(ctx)
+	clone.questions = s.questions.WithContext(ctx)
+	return &clone
+}
+
+// The function creates a new instance of the submissions service that runs code with the given judge.
+func NewSubmissionsService(repo *Repo, questions allquestions.Repository, judge Judge) Service {
+	return &Svc{
+		repo:      repo,
+		questions: questions,
+		judge:     judge,
+	}
+}
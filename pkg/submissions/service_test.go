@@ -0,0 +1,159 @@
+package submissions
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The type scriptedJudge is a judge that answers every submission with `result` and `err`, and
+// remembers the code it was given.
+type scriptedJudge struct {
+	result Result
+	err    error
+	code   []string
+}
+
+func (j *scriptedJudge) Submit(language string, code string, questionID string) (Result, error) {
+	j.code = append(j.code, code)
+	return j.result, j.err
+}
+
+// The function returns a submissions service that runs code with `judge` and whose repositories talk
+// to the mocked deployment.
+func newService(mt *mtest.T, judge Judge) *Svc {
+	return NewSubmissionsService(NewRepo(mt.DB).(*Repo), allquestions.NewRepo(mt.DB), judge).(*Svc)
+}
+
+// The function pops the next update and returns the fields it sets.
+func nextStatusUpdate(mt *mtest.T, id primitive.ObjectID) bson.M {
+	mt.Helper()
+	update := dbtest.NextCommand(mt, "update")["updates"].(bson.A)[0].(bson.M)
+	dbtest.ExpectField(mt, update, "q", bson.M{"_id": id})
+	return update["u"].(bson.M)["$set"].(bson.M)
+}
+
+func TestSubmit(t *testing.T) {
+	questionID := primitive.NewObjectID()
+
+	dbtest.Run(t, "stored as pending", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": questionID}), dbtest.Written(1))
+		submission, err := newService(mt, nil).Submit("user-1", questionID.Hex(), InSubmission{Language: " Go ", Code: "package main"})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if submission.Status != StatusPending || submission.Language != "go" || submission.ID.IsZero() {
+			mt.Errorf("Submit() = %+v, want a pending go submission with an ID", submission)
+		}
+		dbtest.NextCommand(mt, "find")
+		stored := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+		if stored["status"] != StatusPending || stored["userid"] != "user-1" {
+			mt.Errorf("stored %v, want a pending submission of user-1", stored)
+		}
+	})
+
+	for _, test := range []struct {
+		name string
+		in   InSubmission
+	}{
+		{name: "no language", in: InSubmission{Code: "package main"}},
+		{name: "blank code", in: InSubmission{Language: "go", Code: " \n "}},
+	} {
+		dbtest.Run(t, test.name, func(mt *mtest.T) {
+			mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": questionID}))
+			if _, err := newService(mt, nil).Submit("user-1", questionID.Hex(), test.in); !errors.Is(err, pkg.ErrInvalidSubmission) {
+				mt.Fatalf("Submit() = %v, want %v", err, pkg.ErrInvalidSubmission)
+			}
+			dbtest.NextCommand(mt, "find")
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+
+	dbtest.Run(t, "unknown question", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if _, err := newService(mt, nil).Submit("user-1", questionID.Hex(), InSubmission{Language: "go", Code: "package main"}); !errors.Is(err, pkg.ErrQuestionNotFound) {
+			mt.Fatalf("Submit() = %v, want %v", err, pkg.ErrQuestionNotFound)
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		judge  *scriptedJudge
+		status string
+	}{
+		{name: "accepted", judge: &scriptedJudge{result: Result{Passed: true, RuntimeMillis: 12}}, status: StatusAccepted},
+		{name: "rejected", judge: &scriptedJudge{result: Result{Passed: false, Message: "wrong answer"}}, status: StatusRejected},
+		{name: "judge failed", judge: &scriptedJudge{err: errors.New("judge unavailable")}, status: StatusError},
+	}
+	for _, tt := range tests {
+		dbtest.Run(t, tt.name, func(mt *mtest.T) {
+			submission := Submission{ID: primitive.NewObjectID(), QuestionID: primitive.NewObjectID(), Language: "go", Code: "package main", Status: StatusPending}
+			mt.AddMockResponses(dbtest.Written(1), dbtest.Written(1))
+			newService(mt, tt.judge).run(submission)
+
+			if len(tt.judge.code) != 1 || tt.judge.code[0] != submission.Code {
+				mt.Errorf("judged %v, want the submitted code once", tt.judge.code)
+			}
+			running := nextStatusUpdate(mt, submission.ID)
+			if _, ok := running["result"]; ok || running["status"] != StatusRunning {
+				mt.Errorf("first update = %v, want the status running without a result", running)
+			}
+			done := nextStatusUpdate(mt, submission.ID)
+			if done["status"] != tt.status {
+				mt.Errorf("final status = %v, want %s", done["status"], tt.status)
+			}
+			if _, ok := done["result"]; !ok {
+				mt.Errorf("final update = %v, want the result stored", done)
+			}
+			dbtest.ExpectNoCommand(mt)
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	submission := Submission{ID: primitive.NewObjectID(), UserID: "user-1", Status: StatusRunning}
+
+	dbtest.Run(t, "own submission", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, submission))
+		got, err := newService(mt, nil).Get("user-1", submission.ID.Hex())
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if got.ID != submission.ID || got.Status != StatusRunning {
+			mt.Errorf("Get() = %+v, want the running submission", got)
+		}
+	})
+
+	dbtest.Run(t, "somebody else's submission", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, submission))
+		if _, err := newService(mt, nil).Get("user-2", submission.ID.Hex()); !errors.Is(err, pkg.ErrSubmissionNotFound) {
+			mt.Errorf("Get() = %v, want %v", err, pkg.ErrSubmissionNotFound)
+		}
+	})
+
+	dbtest.Run(t, "malformed id", func(mt *mtest.T) {
+		if _, err := newService(mt, nil).Get("user-1", "not-an-id"); !errors.Is(err, pkg.ErrSubmissionNotFound) {
+			mt.Errorf("Get() = %v, want %v", err, pkg.ErrSubmissionNotFound)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestFakeJudge(t *testing.T) {
+	judge := FakeJudge{Delay: time.Millisecond}
+	if result, err := judge.Submit("go", "package main", "q"); err != nil || !result.Passed {
+		t.Errorf("Submit() = %+v, %v, want the code accepted", result, err)
+	}
+	if result, err := judge.Submit("go", "// FAIL", "q"); err != nil || result.Passed {
+		t.Errorf("Submit() = %+v, %v, want the code rejected", result, err)
+	}
+}
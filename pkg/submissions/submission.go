@@ -0,0 +1,64 @@
+package submissions
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The statuses a submission goes through. It starts out `pending`, is `running` while the judge works
+// on it and ends in one of the other statuses.
+const (
+	StatusPending  = "pending"
+	StatusRunning  = "running"
+	StatusAccepted = "accepted"
+	StatusRejected = "rejected"
+	StatusError    = "error"
+)
+
+// The Result type is the outcome of judging a submission.
+// @property {bool} Passed - Whether the code passed all of the question's tests.
+// @property {string} Output - What the code printed, or the compiler's output if it didn't compile.
+// @property {string} Message - An explanation of the outcome from the judge, such as the first failing
+// test.
+// @property {int64} RuntimeMillis - How long the code ran for, in milliseconds.
+type Result struct {
+	Passed        bool   `json:"passed" bson:"passed"`
+	Output        string `json:"output,omitempty" bson:"output,omitempty"`
+	Message       string `json:"message,omitempty" bson:"message,omitempty"`
+	RuntimeMillis int64  `json:"runtimeMillis" bson:"runtimemillis"`
+}
+
+// The Submission type is a user's code submitted to be run against a question's tests.
+// @property ID - The ObjectID of the submission.
+// @property QuestionID - The ObjectID of the question the code answers.
+// @property {string} UserID - The ID of the user that submitted it.
+// @property {string} Language - The programming language of the code.
+// @property {string} Code - The submitted source code.
+// @property {string} Status - Where the submission is at, one of the `Status*` constants.
+// @property Result - The judge's verdict, once there is one.
+// @property CreatedAt - When the code was submitted.
+// @property UpdatedAt - When the status last changed.
+type Submission struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	QuestionID primitive.ObjectID `json:"questionId" bson:"questionid"`
+	UserID     string             `json:"-" bson:"userid"`
+	Language   string             `json:"language" bson:"language"`
+	Code       string             `json:"code" bson:"code"`
+	Status     string             `json:"status" bson:"status"`
+	Result     *Result            `json:"result,omitempty" bson:"result,omitempty"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// The InSubmission type is the code a user submits to be run.
+type InSubmission struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+// The Judge interface runs submitted code against a question's tests. Implementations wrap a code
+// execution service such as Judge0, and may block for as long as running the code takes.
+type Judge interface {
+	Submit(language string, code string, questionID string) (Result, error)
+}
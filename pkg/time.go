@@ -0,0 +1,10 @@
+package pkg
+
+import "time"
+
+// The function returns the current time in UTC. Every timestamp that is stored or returned is created
+// with it, so they don't depend on the timezone of the host the server happens to run on. The Mongo
+// driver decodes times as UTC as well, so they stay in UTC after a round trip through the database.
+func NowUTC() time.Time {
+	return time.Now().UTC()
+}
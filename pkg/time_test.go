@@ -0,0 +1,40 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNowUTC(t *testing.T) {
+	// The host's timezone is far from UTC, so a local time would show up in the checks below.
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = time.FixedZone("UTC+5:30", 5*60*60+30*60)
+
+	now := NowUTC()
+	if now.Location() != time.UTC {
+		t.Fatalf("NowUTC() is in %s, want UTC", now.Location())
+	}
+	if drift := time.Since(now); drift < 0 || drift > time.Minute {
+		t.Errorf("NowUTC() = %s, want the current time", now)
+	}
+
+	type stored struct {
+		CreatedAt time.Time `bson:"createdAt"`
+	}
+	data, err := bson.Marshal(stored{CreatedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded stored
+	if err := bson.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.CreatedAt.Location() != time.UTC {
+		t.Errorf("decoded time is in %s, want UTC", decoded.CreatedAt.Location())
+	}
+	if !decoded.CreatedAt.Equal(now.Truncate(time.Millisecond)) {
+		t.Errorf("decoded %s, want %s", decoded.CreatedAt, now.Truncate(time.Millisecond))
+	}
+}
@@ -0,0 +1,120 @@
+package timelogs
+
+import (
+	"context"
+	"sigmacoder/pkg/retry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Repository is an interface that defines the operations that can be performed on the TimeLog entity.
+type Repository interface {
+	Create(log TimeLog) (TimeLog, error)
+	Totals(userID string, questionIDs []primitive.ObjectID) (map[primitive.ObjectID]Total, error)
+	UserSeconds(userID string) (int64, error)
+	EnsureIndexes() error
+}
+
+// Repo is the struct that implements the Repository interface on top of the `time_logs` collection.
+type Repo struct {
+	db      *mongo.Collection
+	context context.Context
+}
+
+// The `Create` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It stores a session and returns it with its ID set.
+func (s *Repo) Create(log TimeLog) (TimeLog, error) {
+	err := retry.Default.Do(s.context, func() error {
+		result, err := s.db.InsertOne(s.context, log)
+		if err != nil {
+			return err
+		}
+		log.ID = result.InsertedID.(primitive.ObjectID)
+		return nil
+	})
+	return log, err
+}
+
+// The `Totals` function is a method of the `Repo` struct that implements the `Repository` interface.
+// It returns the time the user spent on each of the given questions, summed over their sessions.
+// Questions without any session are left out of the map.
+func (s *Repo) Totals(userID string, questionIDs []primitive.ObjectID) (map[primitive.ObjectID]Total, error) {
+	totals := map[primitive.ObjectID]Total{}
+	if len(questionIDs) == 0 {
+		return totals, nil
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userid": userID, "questionid": bson.M{"$in": questionIDs}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$questionid",
+			"seconds":  bson.M{"$sum": "$seconds"},
+			"sessions": bson.M{"$sum": 1},
+		}}},
+	}
+	var results []Total
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &results)
+	})
+	if err != nil {
+		return totals, err
+	}
+	for _, total := range results {
+		totals[total.QuestionID] = total
+	}
+	return totals, nil
+}
+
+// The `UserSeconds` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It returns the time the user spent on all questions together, in seconds.
+func (s *Repo) UserSeconds(userID string) (int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"userid": userID}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "seconds": bson.M{"$sum": "$seconds"}}}},
+	}
+	var results []struct {
+		Seconds int64 `bson:"seconds"`
+	}
+	err := retry.Default.Do(s.context, func() error {
+		cursor, err := s.db.Aggregate(s.context, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.context, &results)
+	})
+	if err != nil || len(results) == 0 {
+		return 0, err
+	}
+	return results[0].Seconds, nil
+}
+
+// The `EnsureIndexes` function is a method of the `Repo` struct that implements the `Repository`
+// interface. It creates the index on the user and question that the totals are computed with.
+func (s *Repo) EnsureIndexes() error {
+	_, err := s.db.Indexes().CreateOne(s.context, mongo.IndexModel{
+		Keys: bson.D{{Key: "userid", Value: 1}, {Key: "questionid", Value: 1}},
+	})
+	return err
+}
+
+// The `WithContext` function returns a copy of the repo whose operations run with the given context,
+// so a request's deadline and cancellation reach the database calls.
+func (s *Repo) WithContext(ctx context.Context) *Repo {
+	clone := *s
+	clone.context = ctx
+	return &clone
+}
+
+// The function returns a new instance of a Repository interface implementation backed by the
+// `time_logs` collection. The collection is opened with the given options, which set its read
+// preference and write concern.
+func NewRepo(db *mongo.Database, opts ...*options.CollectionOptions) Repository {
+	ctx := context.TODO()
+	return &Repo{db: db.Collection("time_logs", opts...), context: ctx}
+}
@@ -0,0 +1,108 @@
+package timelogs
+
+import (
+	"context"
+	"fmt"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The Service interface defines the time tracking operations available to the HTTP handlers.
+// @property Log - Log records a session the user spent on a question and returns the time spent on it
+// so far. It fails with `pkg.ErrInvalidDuration` when the session isn't positive or is longer than the
+// configured maximum.
+// @property WithContext - WithContext returns a copy of the service whose repository calls run with the
+// given context.
+type Service interface {
+	Log(userID string, questionID string, session Session) (Logged, error)
+	WithContext(ctx context.Context) Service
+}
+
+// The type Svc implements the Service interface.
+// @property repo - The repository sessions are stored in.
+// @property questions - The question repository, used to reject IDs that don't belong to a question.
+// @property maxSession - The longest session that is accepted.
+type Svc struct {
+	repo       *Repo
+	questions  allquestions.Repository
+	maxSession time.Duration
+}
+
+// The function returns the length of the session in seconds. Exactly one of `Seconds` and the
+// `StartedAt` and `EndedAt` pair must be given, and the length must be positive and at most
+// `maxSession`.
+func (s *Svc) duration(session Session) (int64, error) {
+	seconds := session.Seconds
+	hasTimes := session.StartedAt != nil || session.EndedAt != nil
+	switch {
+	case seconds != 0 && hasTimes:
+		return 0, fmt.Errorf("%w: give either seconds or startedAt and endedAt, not both", pkg.ErrInvalidDuration)
+	case hasTimes && (session.StartedAt == nil || session.EndedAt == nil):
+		return 0, fmt.Errorf("%w: startedAt and endedAt must be given together", pkg.ErrInvalidDuration)
+	case hasTimes:
+		seconds = int64(session.EndedAt.Sub(*session.StartedAt) / time.Second)
+	}
+	if seconds <= 0 {
+		return 0, fmt.Errorf("%w: the session must last at least one second", pkg.ErrInvalidDuration)
+	}
+	if seconds > int64(s.maxSession/time.Second) {
+		return 0, fmt.Errorf("%w: the session must not last longer than %s", pkg.ErrInvalidDuration, s.maxSession)
+	}
+	return seconds, nil
+}
+
+// The `Log` function is a method of the `Svc` struct that implements the `Log` method of the `Service`
+// interface.
+func (s *Svc) Log(userID string, questionID string, session Session) (Logged, error) {
+	oid, err := primitive.ObjectIDFromHex(questionID)
+	if err != nil {
+		return Logged{}, pkg.ErrInvalidQuestionID
+	}
+	seconds, err := s.duration(session)
+	if err != nil {
+		return Logged{}, err
+	}
+	existing, err := s.questions.ExistingIDs([]primitive.ObjectID{oid})
+	if err != nil {
+		return Logged{}, err
+	}
+	if len(existing) == 0 {
+		return Logged{}, pkg.ErrQuestionNotFound
+	}
+	log := TimeLog{UserID: userID, QuestionID: oid, Seconds: seconds, LoggedAt: pkg.NowUTC()}
+	if session.StartedAt != nil {
+		startedAt, endedAt := session.StartedAt.UTC(), session.EndedAt.UTC()
+		log.StartedAt, log.EndedAt = &startedAt, &endedAt
+	}
+	log, err = s.repo.Create(log)
+	if err != nil {
+		return Logged{}, err
+	}
+	totals, err := s.repo.Totals(userID, []primitive.ObjectID{oid})
+	if err != nil {
+		return Logged{}, err
+	}
+	return Logged{Session: log, Total: totals[oid]}, nil
+}
+
+// The `WithContext` function is a method of the `Svc` struct that implements the `WithContext` method
+// of the `Service` interface. It returns a copy of the service bound to the given context.
+func (s *Svc) WithContext(ctx context.Context) Service {
+	clone := *s
+	clone.repo = s.repo.WithContext(ctx)
+	clone.questions = s.questions.WithContext(ctx)
+	return &clone
+}
+
+// The function creates a new instance of the time tracking service. `maxSession` is the longest
+// session accepted, which keeps a timer left running overnight from inflating the totals.
+func NewTimeLogsService(repo *Repo, questions allquestions.Repository, maxSession time.Duration) Service {
+	return &Svc{
+		repo:       repo,
+		questions:  questions,
+		maxSession: maxSession,
+	}
+}
@@ -0,0 +1,153 @@
+package timelogs
+
+import (
+	"errors"
+	"sigmacoder/pkg"
+	"sigmacoder/pkg/allquestions"
+	"sigmacoder/pkg/database/dbtest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// The function returns a time tracking service, accepting sessions of up to four hours, whose
+// repositories talk to the mocked deployment.
+func newService(mt *mtest.T) *Svc {
+	return NewTimeLogsService(NewRepo(mt.DB).(*Repo), allquestions.NewRepo(mt.DB), 4*time.Hour).(*Svc)
+}
+
+func TestDuration(t *testing.T) {
+	svc := &Svc{maxSession: 4 * time.Hour}
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		moment := start.Add(d)
+		return &moment
+	}
+	tests := []struct {
+		name    string
+		session Session
+		want    int64
+	}{
+		{name: "seconds", session: Session{Seconds: 90}, want: 90},
+		{name: "start and stop", session: Session{StartedAt: at(0), EndedAt: at(25 * time.Minute)}, want: 1500},
+		{name: "longest session", session: Session{Seconds: 4 * 3600}, want: 4 * 3600},
+		{name: "nothing"},
+		{name: "negative", session: Session{Seconds: -5}},
+		{name: "too long", session: Session{Seconds: 4*3600 + 1}},
+		{name: "stopped before it started", session: Session{StartedAt: at(time.Minute), EndedAt: at(0)}},
+		{name: "start without a stop", session: Session{StartedAt: at(0)}},
+		{name: "seconds and times", session: Session{Seconds: 60, StartedAt: at(0), EndedAt: at(time.Minute)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.duration(tt.session)
+			if tt.want == 0 {
+				if !errors.Is(err, pkg.ErrInvalidDuration) {
+					t.Errorf("duration() = %d, %v, want %v", got, err, pkg.ErrInvalidDuration)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("duration() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLog(t *testing.T) {
+	question := primitive.NewObjectID()
+
+	dbtest.Run(t, "sessions accumulate", func(mt *mtest.T) {
+		svc := newService(mt)
+		mt.AddMockResponses(
+			dbtest.Cursor(mt, bson.M{"_id": question}),
+			dbtest.Written(1),
+			dbtest.Cursor(mt, Total{QuestionID: question, Seconds: 300, Sessions: 1}),
+			dbtest.Cursor(mt, bson.M{"_id": question}),
+			dbtest.Written(1),
+			dbtest.Cursor(mt, Total{QuestionID: question, Seconds: 420, Sessions: 2}),
+		)
+		first, err := svc.Log("user-1", question.Hex(), Session{Seconds: 300})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if want := (Total{QuestionID: question, Seconds: 300, Sessions: 1}); first.Total != want || first.Session.Seconds != 300 {
+			mt.Errorf("first Log() = %+v, want a 300 second session totalling %+v", first, want)
+		}
+		second, err := svc.Log("user-1", question.Hex(), Session{Seconds: 120})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if want := (Total{QuestionID: question, Seconds: 420, Sessions: 2}); second.Total != want || second.Session.Seconds != 120 {
+			mt.Errorf("second Log() = %+v, want a 120 second session totalling %+v", second, want)
+		}
+
+		for _, seconds := range []int64{300, 120} {
+			dbtest.ExpectField(mt, dbtest.NextCommand(mt, "find"), "filter", bson.M{"_id": bson.M{"$in": bson.A{question}}})
+			logged := dbtest.NextCommand(mt, "insert")["documents"].(bson.A)[0].(bson.M)
+			if logged["userid"] != "user-1" || logged["questionid"] != question || logged["seconds"] != seconds {
+				mt.Errorf("inserted %v, want a %d second session of user-1", logged, seconds)
+			}
+			dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+				bson.M{"$match": bson.M{"userid": "user-1", "questionid": bson.M{"$in": bson.A{question}}}},
+				bson.M{"$group": bson.M{"_id": "$questionid", "seconds": bson.M{"$sum": "$seconds"}, "sessions": bson.M{"$sum": int32(1)}}},
+			})
+		}
+	})
+
+	dbtest.Run(t, "start and stop times", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": question}), dbtest.Written(1), dbtest.Cursor(mt, Total{QuestionID: question, Seconds: 600, Sessions: 1}))
+		start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.FixedZone("IST", 5*3600+1800))
+		end := start.Add(10 * time.Minute)
+		logged, err := newService(mt).Log("user-1", question.Hex(), Session{StartedAt: &start, EndedAt: &end})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if logged.Session.Seconds != 600 || logged.Session.StartedAt.Location() != time.UTC || !logged.Session.StartedAt.Equal(start) {
+			mt.Errorf("Log() = %+v, want a 600 second session stored in UTC", logged.Session)
+		}
+	})
+
+	dbtest.Run(t, "unknown question", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if _, err := newService(mt).Log("user-1", question.Hex(), Session{Seconds: 60}); !errors.Is(err, pkg.ErrQuestionNotFound) {
+			mt.Errorf("Log() = %v, want %v", err, pkg.ErrQuestionNotFound)
+		}
+		dbtest.NextCommand(mt, "find")
+		dbtest.ExpectNoCommand(mt)
+	})
+
+	dbtest.Run(t, "invalid input", func(mt *mtest.T) {
+		if _, err := newService(mt).Log("user-1", "not-an-id", Session{Seconds: 60}); !errors.Is(err, pkg.ErrInvalidQuestionID) {
+			mt.Errorf("Log() = %v, want %v", err, pkg.ErrInvalidQuestionID)
+		}
+		if _, err := newService(mt).Log("user-1", question.Hex(), Session{Seconds: 5 * 3600}); !errors.Is(err, pkg.ErrInvalidDuration) {
+			mt.Errorf("Log() = %v, want %v", err, pkg.ErrInvalidDuration)
+		}
+		dbtest.ExpectNoCommand(mt)
+	})
+}
+
+func TestUserSeconds(t *testing.T) {
+	dbtest.Run(t, "summed over every question", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt, bson.M{"_id": nil, "seconds": 3600}))
+		seconds, err := NewRepo(mt.DB).(*Repo).UserSeconds("user-1")
+		if err != nil || seconds != 3600 {
+			mt.Errorf("UserSeconds() = %d, %v, want 3600", seconds, err)
+		}
+		dbtest.ExpectField(mt, dbtest.NextCommand(mt, "aggregate"), "pipeline", bson.A{
+			bson.M{"$match": bson.M{"userid": "user-1"}},
+			bson.M{"$group": bson.M{"_id": nil, "seconds": bson.M{"$sum": "$seconds"}}},
+		})
+	})
+
+	dbtest.Run(t, "nothing logged", func(mt *mtest.T) {
+		mt.AddMockResponses(dbtest.Cursor(mt))
+		if seconds, err := NewRepo(mt.DB).(*Repo).UserSeconds("user-1"); err != nil || seconds != 0 {
+			mt.Errorf("UserSeconds() = %d, %v, want 0", seconds, err)
+		}
+	})
+}
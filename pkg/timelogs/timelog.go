@@ -0,0 +1,52 @@
+package timelogs
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The TimeLog type is one session a user spent working on a question. A user may log any number of
+// sessions per question; the time spent on it is their sum.
+// @property ID - The ObjectID of the session.
+// @property {string} UserID - The ID of the user that worked on the question.
+// @property QuestionID - The ObjectID of the question.
+// @property {int64} Seconds - How long the session lasted, in seconds.
+// @property StartedAt - When the session started, if the client sent start and stop times.
+// @property EndedAt - When the session ended, if the client sent start and stop times.
+// @property LoggedAt - When the session was recorded.
+type TimeLog struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID     string             `json:"userId" bson:"userid"`
+	QuestionID primitive.ObjectID `json:"questionId" bson:"questionid"`
+	Seconds    int64              `json:"seconds" bson:"seconds"`
+	StartedAt  *time.Time         `json:"startedAt,omitempty" bson:"startedAt,omitempty"`
+	EndedAt    *time.Time         `json:"endedAt,omitempty" bson:"endedAt,omitempty"`
+	LoggedAt   time.Time          `json:"loggedAt" bson:"loggedAt"`
+}
+
+// The Session type is the time a client reports for one session: either its length in `Seconds`, or
+// the `StartedAt` and `EndedAt` times the client's timer was started and stopped at.
+type Session struct {
+	Seconds   int64      `json:"seconds,omitempty"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+}
+
+// The Total type is the time a user spent on a question, summed over their sessions.
+// @property QuestionID - The ObjectID of the question.
+// @property {int64} Seconds - The length of all sessions together, in seconds.
+// @property {int64} Sessions - The number of sessions logged.
+type Total struct {
+	QuestionID primitive.ObjectID `json:"questionId" bson:"_id"`
+	Seconds    int64              `json:"seconds" bson:"seconds"`
+	Sessions   int64              `json:"sessions" bson:"sessions"`
+}
+
+// The Logged type is returned after logging a session.
+// @property Session - The session that was recorded.
+// @property Total - The time spent on the question so far, including the session.
+type Logged struct {
+	Session TimeLog `json:"session"`
+	Total   Total   `json:"total"`
+}
@@ -0,0 +1,243 @@
+package token
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// The signing algorithms tokens can be signed with. HS256 signs and verifies with one shared secret;
+// RS256 signs with a private key and verifies with its public key, so services that only check tokens
+// can't mint them.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+)
+
+// The Keys type signs the tokens the API issues and checks the ones it receives, with a single
+// algorithm.
+// @property method - The signing method, HS256 or RS256.
+// @property signKey - The key tokens are signed with: the shared secret or the RSA private key.
+// @property verifyKey - The key tokens are checked with: the shared secret or the RSA public key.
+// @property {string} keyID - The `kid` header of RS256 tokens, derived from the public key so a
+// rotated key gets a new ID. It is empty for HS256.
+// @property previous - The public keys of RS256 keys that were rotated out, keyed by their `kid`.
+// Tokens they signed are still accepted until they expire.
+// @property {string} issuer - The `iss` claim stamped on issued tokens and required on received ones.
+// When it is empty, the claim is neither stamped nor checked.
+// @property {string} audience - The `aud` claim stamped on issued tokens and required on received ones.
+// When it is empty, the claim is neither stamped nor checked.
+type Keys struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	keyID     string
+	previous  map[string]*rsa.PublicKey
+	issuer    string
+	audience  string
+}
+
+// The JWK type is the public half of a signing key in JSON Web Key form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// The JWKSet type is the body of the JWKS endpoint. It is empty under HS256, since a shared secret
+// can't be published.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// The function returns the algorithm tokens are signed with.
+func (k *Keys) Algorithm() string {
+	return k.method.Alg()
+}
+
+// The function sets the `iss` and `aud` claims issued tokens carry and received tokens must carry. An
+// empty value leaves its claim out, which is what tokens issued before the claims existed look like.
+func (k *Keys) SetClaims(issuer string, audience string) {
+	k.issuer = issuer
+	k.audience = audience
+}
+
+// The function signs a token with the given claims, stamped with the configured issuer and audience.
+func (k *Keys) Sign(claims jwt.MapClaims) (string, error) {
+	if k.issuer != "" {
+		claims["iss"] = k.issuer
+	}
+	if k.audience != "" {
+		claims["aud"] = k.audience
+	}
+	token := jwt.NewWithClaims(k.method, claims)
+	if k.keyID != "" {
+		token.Header["kid"] = k.keyID
+	}
+	return token.SignedString(k.signKey)
+}
+
+// The function is the `jwt.Keyfunc` that checks received tokens. Tokens signed with any other
+// algorithm than the configured one are refused, so an RS256 public key can't be passed off as an
+// HS256 secret. Tokens whose issuer or audience isn't the configured one are refused as well. RS256
+// tokens are checked with the key their `kid` header names, which may be a previous one.
+func (k *Keys) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing algorithm %q", token.Method.Alg())
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("unexpected claims type %T", token.Claims)
+	}
+	if k.issuer != "" && !claims.VerifyIssuer(k.issuer, true) {
+		return nil, fmt.Errorf("unexpected issuer")
+	}
+	if k.audience != "" && !claims.VerifyAudience(k.audience, true) {
+		return nil, fmt.Errorf("unexpected audience")
+	}
+	if kid, _ := token.Header["kid"].(string); kid != "" && kid != k.keyID {
+		if public, ok := k.previous[kid]; ok {
+			return public, nil
+		}
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return k.verifyKey, nil
+}
+
+// The function parses a token and checks its signature and expiry.
+func (k *Keys) Parse(raw string) (*jwt.Token, error) {
+	return jwt.Parse(raw, k.Keyfunc)
+}
+
+// The function returns the public keys tokens can be verified with: the current one first, then the
+// previous ones.
+func (k *Keys) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	public, ok := k.verifyKey.(*rsa.PublicKey)
+	if !ok {
+		return set
+	}
+	set.Keys = append(set.Keys, rsaJWK(k.keyID, public))
+	kids := make([]string, 0, len(k.previous))
+	for kid := range k.previous {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	for _, kid := range kids {
+		set.Keys = append(set.Keys, rsaJWK(kid, k.previous[kid]))
+	}
+	return set
+}
+
+// The function returns the JWK of an RSA public key.
+func rsaJWK(kid string, public *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: RS256,
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+	}
+}
+
+// The function derives the `kid` of an RSA public key from the SHA-256 of its DER encoding.
+func keyIDOf(public *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:])[:16], nil
+}
+
+// The function parses a PEM encoded RSA key, either a private key or only its public half, and returns
+// the public key.
+func parsePublicKey(keyPEM []byte) (*rsa.PublicKey, error) {
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(keyPEM); err == nil {
+		return &private.PublicKey, nil
+	}
+	return jwt.ParseRSAPublicKeyFromPEM(keyPEM)
+}
+
+// The function adds the key of a rotated out RS256 key pair, so the tokens it signed keep verifying and
+// it stays in the JWKS until they have expired. The PEM may hold the private or the public key.
+func (k *Keys) AddPrevious(keyPEM []byte) error {
+	if k.method != jwt.SigningMethodRS256 {
+		return fmt.Errorf("previous keys are only supported with %s", RS256)
+	}
+	public, err := parsePublicKey(keyPEM)
+	if err != nil {
+		return err
+	}
+	keyID, err := keyIDOf(public)
+	if err != nil {
+		return err
+	}
+	if keyID != k.keyID {
+		k.previous[keyID] = public
+	}
+	return nil
+}
+
+// The function creates keys that sign and verify tokens with HS256 and the shared secret.
+func NewHS256(secret string) *Keys {
+	return &Keys{method: jwt.SigningMethodHS256, signKey: []byte(secret), verifyKey: []byte(secret)}
+}
+
+// The function creates keys that sign tokens with RS256 and the PEM encoded RSA private key, and
+// verify them with its public key.
+func NewRS256(privateKeyPEM []byte) (*Keys, error) {
+	private, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := keyIDOf(&private.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Keys{method: jwt.SigningMethodRS256, signKey: private, verifyKey: &private.PublicKey, keyID: keyID, previous: map[string]*rsa.PublicKey{}}, nil
+}
+
+// The function creates the keys for the configured algorithm: HS256 with `secret`, or RS256 with the
+// private key read from `privateKeyFile` and the rotated out keys read from `previousKeyFiles`. The
+// issuer and audience are set separately, with `SetClaims`.
+func Load(algorithm string, secret string, privateKeyFile string, previousKeyFiles []string) (*Keys, error) {
+	switch algorithm {
+	case HS256:
+		return NewHS256(secret), nil
+	case RS256:
+		if privateKeyFile == "" {
+			return nil, fmt.Errorf("%s needs a private key file", RS256)
+		}
+		pem, err := os.ReadFile(privateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := NewRS256(pem)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range previousKeyFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if err := keys.AddPrevious(pem); err != nil {
+				return nil, fmt.Errorf("%s: %v", file, err)
+			}
+		}
+		return keys, nil
+	}
+	return nil, fmt.Errorf("signing algorithm must be %q or %q, got %q", HS256, RS256, algorithm)
+}
@@ -0,0 +1,249 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// The function returns a new PEM encoded RSA private key.
+func rsaKeyPEM(t *testing.T) []byte {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)})
+}
+
+// The function returns the claims of a token for "user-1" that expires in an hour.
+func userClaims() jwt.MapClaims {
+	return jwt.MapClaims{"userid": "user-1", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestSignAndParse(t *testing.T) {
+	rs256, err := NewRS256(rsaKeyPEM(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRS256, err := NewRS256(rsaKeyPEM(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		keys   *Keys
+		other  *Keys
+		hasKID bool
+	}{
+		{name: HS256, keys: NewHS256("secret"), other: NewHS256("other secret")},
+		{name: RS256, keys: rs256, other: otherRS256, hasKID: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := tt.keys.Sign(userClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := tt.keys.Parse(raw)
+			if err != nil {
+				t.Fatalf("Parse() = %v, want the token accepted", err)
+			}
+			if parsed.Method.Alg() != tt.name || tt.keys.Algorithm() != tt.name {
+				t.Errorf("signed with %s, want %s", parsed.Method.Alg(), tt.name)
+			}
+			if userID := parsed.Claims.(jwt.MapClaims)["userid"]; userID != "user-1" {
+				t.Errorf("userid = %v, want user-1", userID)
+			}
+			if _, ok := parsed.Header["kid"]; ok != tt.hasKID {
+				t.Errorf("kid header = %v, want present: %v", parsed.Header["kid"], tt.hasKID)
+			}
+			if _, err := tt.other.Parse(raw); err == nil {
+				t.Error("Parse() with another key = nil, want an error")
+			}
+			expired := userClaims()
+			expired["exp"] = time.Now().Add(-time.Minute).Unix()
+			raw, err = tt.keys.Sign(expired)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tt.keys.Parse(raw); err == nil {
+				t.Error("Parse() of an expired token = nil, want an error")
+			}
+		})
+	}
+
+	t.Run("algorithm mismatch", func(t *testing.T) {
+		hs256, err := NewHS256("secret").Sign(userClaims())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rs256.Parse(hs256); err == nil {
+			t.Error("RS256 keys accepted an HS256 token")
+		}
+		signed, err := rs256.Sign(userClaims())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewHS256("secret").Parse(signed); err == nil {
+			t.Error("HS256 keys accepted an RS256 token")
+		}
+	})
+
+	t.Run("public key used as a secret", func(t *testing.T) {
+		der, err := x509.MarshalPKIXPublicKey(rs256.verifyKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		public := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+		forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, userClaims()).SignedString(public)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rs256.Parse(forged); err == nil {
+			t.Error("RS256 keys accepted a token signed with the public key as an HS256 secret")
+		}
+	})
+
+}
+
+func TestIssuerAndAudience(t *testing.T) {
+	// The function returns HS256 keys with the given issuer and audience.
+	keysWith := func(issuer string, audience string) *Keys {
+		keys := NewHS256("secret")
+		keys.SetClaims(issuer, audience)
+		return keys
+	}
+	configured := keysWith("sigmacoder", "sigmacoder-web")
+
+	t.Run("stamped at signing", func(t *testing.T) {
+		raw, err := configured.Sign(userClaims())
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := configured.Parse(raw)
+		if err != nil {
+			t.Fatalf("Parse() = %v, want the token accepted", err)
+		}
+		claims := parsed.Claims.(jwt.MapClaims)
+		if claims["iss"] != "sigmacoder" || claims["aud"] != "sigmacoder-web" {
+			t.Errorf("iss = %v, aud = %v, want sigmacoder and sigmacoder-web", claims["iss"], claims["aud"])
+		}
+	})
+
+	tests := []struct {
+		name     string
+		signer   *Keys
+		verifier *Keys
+		accepted bool
+	}{
+		{name: "matching claims", signer: configured, verifier: configured, accepted: true},
+		{name: "other issuer", signer: keysWith("someone-else", "sigmacoder-web"), verifier: configured},
+		{name: "other audience", signer: keysWith("sigmacoder", "another-app"), verifier: configured},
+		{name: "no claims", signer: keysWith("", ""), verifier: configured},
+		{name: "issuer only checked", signer: keysWith("sigmacoder", "another-app"), verifier: keysWith("sigmacoder", ""), accepted: true},
+		{name: "unchecked when unset", signer: configured, verifier: keysWith("", ""), accepted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := tt.signer.Sign(userClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = tt.verifier.Parse(raw)
+			if tt.accepted && err != nil {
+				t.Errorf("Parse() = %v, want the token accepted", err)
+			}
+			if !tt.accepted && err == nil {
+				t.Error("Parse() = nil, want the token refused")
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "private.pem")
+	if err := os.WriteFile(keyFile, rsaKeyPEM(t), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys, err := Load("", "secret", "", nil); err == nil || keys != nil {
+		t.Errorf("Load() without an algorithm = %v, want an error", err)
+	}
+	if keys, err := Load(HS256, "secret", "", nil); err != nil || keys.Algorithm() != HS256 {
+		t.Errorf("Load(%s) = %v, want HS256 keys", HS256, err)
+	}
+	if keys, err := Load(RS256, "", keyFile, nil); err != nil || keys.Algorithm() != RS256 {
+		t.Errorf("Load(%s) = %v, want RS256 keys", RS256, err)
+	}
+	if _, err := Load(RS256, "", "", nil); err == nil {
+		t.Errorf("Load(%s) without a key file = nil, want an error", RS256)
+	}
+	if _, err := Load(RS256, "", filepath.Join(dir, "missing.pem"), nil); err == nil {
+		t.Errorf("Load(%s) with a missing key file = nil, want an error", RS256)
+	}
+	if _, err := Load("ES256", "secret", keyFile, nil); err == nil {
+		t.Error("Load(ES256) = nil, want an error")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	oldPEM := rsaKeyPEM(t)
+	old, err := NewRS256(oldPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := NewRS256(rsaKeyPEM(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := current.AddPrevious(oldPEM); err != nil {
+		t.Fatal(err)
+	}
+	if current.keyID == old.keyID {
+		t.Fatalf("both keys have the kid %q, want one per key", current.keyID)
+	}
+
+	signedBefore, err := old.Sign(userClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := current.Parse(signedBefore); err != nil {
+		t.Errorf("Parse() of a token signed before the rotation = %v, want it accepted", err)
+	}
+	signedAfter, err := current.Sign(userClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Parse(signedAfter); err == nil {
+		t.Error("the old keys accepted a token signed with the new key")
+	}
+
+	set := current.JWKS()
+	if len(set.Keys) != 2 || set.Keys[0].Kid != current.keyID || set.Keys[1].Kid != old.keyID {
+		t.Errorf("JWKS() = %+v, want the current key, then the previous one", set)
+	}
+
+	unknown, err := NewRS256(rsaKeyPEM(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedElsewhere, err := unknown.Sign(userClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := current.Parse(signedElsewhere); err == nil {
+		t.Error("Parse() of a token with an unknown kid = nil, want an error")
+	}
+	if err := NewHS256("secret").AddPrevious(oldPEM); err == nil {
+		t.Error("AddPrevious() on HS256 keys = nil, want an error")
+	}
+}
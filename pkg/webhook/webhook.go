@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sigmacoder/pkg/logging"
+	"time"
+)
+
+// `SignatureHeader` is the header carrying the HMAC-SHA256 signature of the request body, formatted
+// as "sha256=<hex digest>". Receivers recompute it over the raw body with the shared secret and
+// compare the two in constant time.
+const SignatureHeader = "X-Sigmacoder-Signature"
+
+// The Notifier type delivers signed JSON events to a single outbound webhook URL.
+// @property {string} url - The URL events are POSTed to.
+// @property {[]byte} secret - The shared secret the payloads are signed with.
+// @property client - The HTTP client used for deliveries. Its timeout bounds each attempt.
+// @property {int} attempts - How many times a delivery is tried in total before it is given up.
+// @property {time.Duration} backoff - The wait before the first retry, doubled after each further
+// attempt.
+type Notifier struct {
+	url      string
+	secret   []byte
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+// The function signs the body with HMAC-SHA256 and returns the value of the signature header.
+func Sign(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// The `Send` method delivers the event in the background and returns immediately, so the caller's
+// latency doesn't depend on the receiver. Failed deliveries are retried with exponential backoff and
+// logged once every attempt has failed. A nil Notifier, as returned when no URL is configured, sends
+// nothing.
+func (n *Notifier) Send(event interface{}) {
+	if n == nil {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		logging.Errorf("webhook: could not encode event: %v", err)
+		return
+	}
+	go func() {
+		if err := n.deliver(body); err != nil {
+			logging.Errorf("webhook: giving up on delivery to %s: %v", n.url, err)
+		}
+	}()
+}
+
+// The function POSTs the signed body to the webhook URL until it is accepted with a 2xx status or the
+// attempts run out.
+func (n *Notifier) deliver(body []byte) error {
+	var err error
+	wait := n.backoff
+	for attempt := 1; attempt <= n.attempts; attempt++ {
+		if err = n.post(body); err == nil {
+			return nil
+		}
+		if attempt < n.attempts {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+	return err
+}
+
+// The function makes a single delivery attempt.
+func (n *Notifier) post(body []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(n.secret, body))
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// The function creates a Notifier for the given URL and secret. It returns nil when the URL is empty,
+// which turns the webhook off.
+func NewNotifier(url string, secret string, attempts int, backoff time.Duration) *Notifier {
+	if url == "" {
+		return nil
+	}
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &Notifier{
+		url:      url,
+		secret:   []byte(secret),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		attempts: attempts,
+		backoff:  backoff,
+	}
+}
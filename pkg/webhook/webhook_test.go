@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// The type receiver is a fake webhook endpoint that rejects the first `failures` deliveries and
+// reports the accepted ones on `accepted`.
+type receiver struct {
+	t        *testing.T
+	secret   []byte
+	failures int32
+	calls    int32
+	accepted chan []byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		r.t.Errorf("reading the body: %v", err)
+	}
+	if want := Sign(r.secret, body); !hmac.Equal([]byte(req.Header.Get(SignatureHeader)), []byte(want)) {
+		r.t.Errorf("%s = %q, want %q", SignatureHeader, req.Header.Get(SignatureHeader), want)
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "application/json" {
+		r.t.Errorf("Content-Type = %q, want application/json", contentType)
+	}
+	if atomic.AddInt32(&r.calls, 1) <= r.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	r.accepted <- body
+}
+
+// The type signup is the event the tests send.
+type signup struct {
+	UserID    string    `json:"userId"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func TestSend(t *testing.T) {
+	event := signup{UserID: "user-1", Email: "ada@example.com", CreatedAt: time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)}
+
+	t.Run("retried until accepted", func(t *testing.T) {
+		fake := &receiver{t: t, secret: []byte("shared"), failures: 2, accepted: make(chan []byte, 1)}
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		NewNotifier(server.URL, "shared", 3, time.Millisecond).Send(event)
+		select {
+		case body := <-fake.accepted:
+			var got signup
+			if err := json.Unmarshal(body, &got); err != nil || got != event {
+				t.Errorf("payload = %s, want %+v", body, event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the event was never delivered")
+		}
+		if calls := atomic.LoadInt32(&fake.calls); calls != 3 {
+			t.Errorf("the receiver was called %d times, want 3", calls)
+		}
+	})
+
+	t.Run("given up after the attempts", func(t *testing.T) {
+		fake := &receiver{t: t, secret: []byte("shared"), failures: 100, accepted: make(chan []byte, 1)}
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		notifier := NewNotifier(server.URL, "shared", 2, time.Millisecond)
+		body, _ := json.Marshal(event)
+		if err := notifier.deliver(body); err == nil {
+			t.Error("deliver() = nil, want the last attempt's error")
+		}
+		if calls := atomic.LoadInt32(&fake.calls); calls != 2 {
+			t.Errorf("the receiver was called %d times, want 2", calls)
+		}
+	})
+
+	t.Run("doesn't wait for the receiver", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		started := time.Now()
+		NewNotifier(server.URL, "shared", 1, time.Millisecond).Send(event)
+		if elapsed := time.Since(started); elapsed > 100*time.Millisecond {
+			t.Errorf("Send() took %s, want it to return right away", elapsed)
+		}
+	})
+
+	t.Run("no URL", func(t *testing.T) {
+		notifier := NewNotifier("", "shared", 3, time.Millisecond)
+		if notifier != nil {
+			t.Fatalf("NewNotifier() = %v, want nil", notifier)
+		}
+		notifier.Send(event)
+	})
+}